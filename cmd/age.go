@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/timeshift"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// AgeCommand shifts every date/timestamp column of a scenario revision by
+// a fixed interval and saves the result as a new revision. Calendar-bound
+// fixtures (trials, subscriptions, expiries) drift into the past as real
+// time moves on; aging slides them forward without hand-editing CSVs.
+//
+//	seedmancer age billing/pro --shift 90d
+func AgeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "age",
+		Usage:     "Shift every date/timestamp in a scenario forward (or back) by an interval",
+		ArgsUsage: "<scenario>",
+		Description: "Reads the chosen revision (latest by default), moves every value in\n" +
+			"a date or timestamp column by --shift, and saves the result as a new\n" +
+			"rNNN revision. Every value moves by the same amount, so the relative\n" +
+			"order of events is preserved.\n\n" +
+			"Column types come from the revision's schema.json. NULLs and @env\n" +
+			"markers are left untouched.\n\n" +
+			"Examples:\n" +
+			"  seedmancer age billing/pro --shift 90d\n" +
+			"  seedmancer age billing/pro --shift -2w --revision r003",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "shift",
+				Usage:    "Interval to add, e.g. 90d, 2w, -36h",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "revision",
+				Usage: "Revision to age (defaults to latest)",
+			},
+			&cli.StringFlag{
+				Name:  "description",
+				Usage: "Optional description stored on the new revision manifest",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			out, err := RunAge(context.Background(), AgeInput{
+				Scenario:    scenarioArg,
+				Revision:    strings.TrimSpace(c.String("revision")),
+				Shift:       strings.TrimSpace(c.String("shift")),
+				Description: strings.TrimSpace(c.String("description")),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+			ui.Success("Aged %s @ %s → %s", out.Scenario, out.BaseRevision, out.Revision)
+			ui.KeyValue("Shift: ", out.Shift)
+			ui.KeyValue("Values shifted: ", fmt.Sprintf("%d", out.ValuesShifted))
+			if len(out.Columns) > 0 {
+				ui.KeyValue("Columns: ", strings.Join(out.Columns, ", "))
+			}
+			ui.KeyValue("Run: ", fmt.Sprintf("seedmancer seed %s", out.Scenario))
+			return nil
+		},
+	}
+}
+
+// AgeInput selects the revision to age and the interval to shift by.
+type AgeInput struct {
+	Scenario    string `json:"scenario" jsonschema:"Scenario path to age"`
+	Revision    string `json:"revision,omitempty" jsonschema:"Revision to age (defaults to latest)"`
+	Shift       string `json:"shift" jsonschema:"Interval added to every date/timestamp value, e.g. 90d, 2w, -36h"`
+	Description string `json:"description,omitempty" jsonschema:"Optional description stored on the new revision manifest"`
+}
+
+// AgeOutput reports the new revision and which columns moved.
+type AgeOutput struct {
+	Scenario      string   `json:"scenario"`
+	BaseRevision  string   `json:"baseRevision"`
+	Revision      string   `json:"revision"`
+	Shift         string   `json:"shift"`
+	Columns       []string `json:"columns"`
+	ValuesShifted int      `json:"valuesShifted"`
+	Path          string   `json:"path"`
+}
+
+// RunAge shifts every temporal column of a revision and commits the result
+// as a new revision of the same scenario.
+func RunAge(_ context.Context, in AgeInput) (AgeOutput, error) {
+	shift, err := timeshift.ParseShift(in.Shift)
+	if err != nil {
+		return AgeOutput{}, err
+	}
+
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return AgeOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return AgeOutput{}, err
+	}
+	scenarioPath, err := scenario.Normalize(in.Scenario)
+	if err != nil {
+		return AgeOutput{}, err
+	}
+	base, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+	if err != nil {
+		return AgeOutput{}, err
+	}
+
+	types := loadColumnTypes(projectRoot, cfg.StoragePath, base.Manifest.SchemaFingerprint)
+	if len(types) == 0 {
+		return AgeOutput{}, fmt.Errorf(
+			"no schema.json found for %s @ %s — column types are needed to find date columns",
+			scenarioPath, base.RevID,
+		)
+	}
+
+	var columns []string
+	shifted := 0
+	manifest, err := deriveRevision(projectRoot, cfg, base, "age", in.Description, func(table string, records [][]string) ([][]string, error) {
+		out, n, err := timeshift.ShiftRecords(records, types[table], shift)
+		if err != nil {
+			return nil, err
+		}
+		shifted += n
+		if len(records) > 0 {
+			for _, col := range records[0] {
+				if timeshift.IsTemporalType(types[table][col]) {
+					columns = append(columns, table+"."+col)
+				}
+			}
+		}
+		return out, nil
+	})
+	if err != nil {
+		return AgeOutput{}, err
+	}
+	sort.Strings(columns)
+
+	return AgeOutput{
+		Scenario:      scenarioPath,
+		BaseRevision:  base.RevID,
+		Revision:      manifest.Revision,
+		Shift:         in.Shift,
+		Columns:       columns,
+		ValuesShifted: shifted,
+		Path:          filepath.Join(scenario.RevisionDir(projectRoot, cfg.StoragePath, scenarioPath, manifest.Revision), "data"),
+	}, nil
+}
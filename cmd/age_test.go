@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// stageRevision builds a project in a temp dir (and chdirs into it) with
+// one scenario at r001: the given CSVs under data/ and schemaJSON in the
+// content-addressed schema store. Returns the project root.
+func stageRevision(t *testing.T, scenarioPath, schemaJSON string, csvs map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	prev, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Setenv("HOME", dir)
+	writeFile(t, filepath.Join(dir, "seedmancer.yaml"), "storage_path: .seedmancer\n")
+
+	fp := strings.Repeat("ab", 32)
+	writeFile(t, scenario.SchemaJSONPath(dir, ".seedmancer", utils.FingerprintShort(fp)), schemaJSON)
+
+	scDir := scenario.ScenarioDir(dir, ".seedmancer", scenarioPath)
+	revDir := scenario.RevisionDir(dir, ".seedmancer", scenarioPath, "r001")
+	var tables []string
+	for name, body := range csvs {
+		writeFile(t, filepath.Join(revDir, "data", name+".csv"), body)
+		tables = append(tables, name)
+	}
+	now := time.Now().UTC()
+	if err := scenario.WriteManifest(scDir, scenario.Manifest{
+		Scenario: scenarioPath, CreatedAt: now, UpdatedAt: now, Latest: "r001",
+	}); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if err := scenario.WriteRevisionManifest(revDir, scenario.RevisionManifest{
+		Scenario:          scenarioPath,
+		Revision:          "r001",
+		SchemaFingerprint: fp,
+		CreatedAt:         now,
+		Source:            "export",
+		Tables:            tables,
+		Services:          []string{"postgres"},
+	}); err != nil {
+		t.Fatalf("write revision manifest: %v", err)
+	}
+	return dir
+}
+
+// shardTable replaces table's CSV in scenarioPath @ r001, staged in dir by
+// stageRevision, with the given shards, each starting with the header.
+func shardTable(t *testing.T, dir, scenarioPath, table string, shards ...string) {
+	t.Helper()
+	dataDir := filepath.Join(scenario.RevisionDir(dir, ".seedmancer", scenarioPath, "r001"), "data")
+	if err := os.Remove(filepath.Join(dataDir, table+".csv")); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	for i, body := range shards {
+		writeFile(t, filepath.Join(dataDir, csvshard.Name(table, i+1)), body)
+	}
+}
+
+func TestRunAge_shiftsTemporalColumnsIntoNewRevision(t *testing.T) {
+	const schema = `{"tables":[{"name":"subscriptions","columns":[
+	  {"name":"id","type":"integer"},
+	  {"name":"plan","type":"text"},
+	  {"name":"starts_on","type":"date"},
+	  {"name":"expires_at","type":"timestamp with time zone","nullable":true}
+	]}]}`
+	dir := stageRevision(t, "billing/pro", schema, map[string]string{
+		"subscriptions": "id,plan,starts_on,expires_at\n" +
+			"1,2024-01-01,2024-01-01,2024-02-01 00:00:00 +0000 UTC\n" +
+			"2,pro,2024-03-01,NULL\n",
+	})
+
+	out, err := RunAge(context.Background(), AgeInput{Scenario: "billing/pro", Shift: "90d"})
+	if err != nil {
+		t.Fatalf("RunAge: %v", err)
+	}
+	if out.BaseRevision != "r001" || out.Revision != "r002" {
+		t.Fatalf("revisions = %s → %s, want r001 → r002", out.BaseRevision, out.Revision)
+	}
+	if out.ValuesShifted != 3 {
+		t.Fatalf("ValuesShifted = %d, want 3", out.ValuesShifted)
+	}
+
+	got, err := os.ReadFile(filepath.Join(out.Path, "subscriptions.csv"))
+	if err != nil {
+		t.Fatalf("reading aged CSV: %v", err)
+	}
+	want := "id,plan,starts_on,expires_at\n" +
+		"1,2024-01-01,2024-03-31,2024-05-01 00:00:00 +0000 UTC\n" +
+		"2,pro,2024-05-30,NULL\n"
+	if string(got) != want {
+		t.Fatalf("aged CSV:\n%s\nwant:\n%s", got, want)
+	}
+
+	m, err := scenario.ReadManifest(scenario.ScenarioDir(dir, ".seedmancer", "billing/pro"))
+	if err != nil || m.Latest != "r002" {
+		t.Fatalf("latest = %q (err %v), want r002", m.Latest, err)
+	}
+	rm, err := scenario.ReadRevisionManifest(scenario.RevisionDir(dir, ".seedmancer", "billing/pro", "r002"))
+	if err != nil || rm.Source != "age" || rm.SchemaFingerprint != strings.Repeat("ab", 32) {
+		t.Fatalf("revision manifest = %+v (err %v)", rm, err)
+	}
+}
+
+func TestRunAge_rejectsBadShift(t *testing.T) {
+	if _, err := RunAge(context.Background(), AgeInput{Scenario: "x", Shift: "3 months"}); err == nil {
+		t.Fatal("expected error for unparseable shift")
+	}
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/bundle"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// BundleCommand writes a standalone seeder binary for one revision: a
+// copy of this executable with the revision's CSVs and schema appended.
+// The result needs no seedmancer.yaml, local storage or API access — only
+// a database URL — which makes it easy to hand a fixture to another team.
+//
+//	seedmancer bundle billing/pro --revision r003 -o seeder
+//	./seeder --db-url postgres://localhost:5432/app
+func BundleCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "bundle",
+		Usage:     "Build a standalone seeder binary for one scenario revision",
+		ArgsUsage: "<scenario>",
+		Description: "Copies the seedmancer executable to --output and appends the\n" +
+			"revision (latest by default): its CSVs, schema.json and schema\n" +
+			"sidecars. The resulting binary only seeds that revision:\n\n" +
+			"  ./seeder --db-url postgres://localhost:5432/app\n\n" +
+			"It verifies the bundled files' checksums before restoring. The\n" +
+			"binary targets the OS/architecture seedmancer itself runs on.\n\n" +
+			"Examples:\n" +
+			"  seedmancer bundle billing/pro -o seeder\n" +
+			"  seedmancer bundle billing/pro --revision r003 -o dist/billing-seeder",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "revision",
+				Aliases: []string{"r"},
+				Usage:   "Revision to bundle (defaults to latest)",
+			},
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "Path of the seeder binary to write",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			out, err := RunBundle(context.Background(), BundleInput{
+				Scenario: scenarioArg,
+				Revision: strings.TrimSpace(c.String("revision")),
+				Output:   strings.TrimSpace(c.String("output")),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+			ui.Success("Bundled %s @ %s into %s", out.Scenario, out.Revision, out.Path)
+			ui.KeyValue("Tables: ", fmt.Sprintf("%d", len(out.Tables)))
+			ui.KeyValue("Size: ", fmt.Sprintf("%.1f MB", float64(out.Bytes)/(1<<20)))
+			ui.KeyValue("Run: ", fmt.Sprintf("%s --db-url <url>", out.Path))
+			return nil
+		},
+	}
+}
+
+// BundleInput selects the revision to bundle and where the binary goes.
+type BundleInput struct {
+	Scenario string `json:"scenario" jsonschema:"Scenario path"`
+	Revision string `json:"revision,omitempty" jsonschema:"Revision to bundle (defaults to latest)"`
+	Output   string `json:"output" jsonschema:"Path of the seeder binary to write"`
+}
+
+// BundleOutput reports the written seeder binary.
+type BundleOutput struct {
+	Scenario string   `json:"scenario"`
+	Revision string   `json:"revision"`
+	Tables   []string `json:"tables"`
+	Path     string   `json:"path"`
+	Bytes    int64    `json:"bytes"`
+}
+
+// RunBundle writes a seeder binary carrying one revision.
+func RunBundle(_ context.Context, in BundleInput) (BundleOutput, error) {
+	if in.Output == "" {
+		return BundleOutput{}, fmt.Errorf("output cannot be empty")
+	}
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return BundleOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return BundleOutput{}, err
+	}
+	scenarioPath, err := scenario.Normalize(in.Scenario)
+	if err != nil {
+		return BundleOutput{}, err
+	}
+	rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+	if err != nil {
+		return BundleOutput{}, err
+	}
+	if err := verifyRevisionChecksum(rev); err != nil {
+		return BundleOutput{}, err
+	}
+
+	schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	files, cleanupFiles, err := joinedRevisionFiles(schemaDir, rev.DataDir)
+	if err != nil {
+		return BundleOutput{}, err
+	}
+	defer cleanupFiles()
+	sumsPath, cleanupSums, err := writeBundleChecksums(files)
+	if err != nil {
+		return BundleOutput{}, err
+	}
+	defer cleanupSums()
+	archive, err := compressFiles(append(files, sumsPath))
+	if err != nil {
+		return BundleOutput{}, err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return BundleOutput{}, fmt.Errorf("locating seedmancer executable: %w", err)
+	}
+	if err := bundle.Write(exe, in.Output, archive.Bytes(), bundle.Manifest{
+		Scenario:          scenarioPath,
+		Revision:          rev.RevID,
+		SchemaFingerprint: rev.Manifest.SchemaFingerprint,
+		DatabaseType:      rev.Manifest.DatabaseType,
+		Tables:            rev.Manifest.Tables,
+		SeedmancerVersion: utils.CLIVersion(),
+	}); err != nil {
+		return BundleOutput{}, err
+	}
+	info, err := os.Stat(in.Output)
+	if err != nil {
+		return BundleOutput{}, err
+	}
+	return BundleOutput{
+		Scenario: scenarioPath,
+		Revision: rev.RevID,
+		Tables:   rev.Manifest.Tables,
+		Path:     in.Output,
+		Bytes:    info.Size(),
+	}, nil
+}
+
+// BundledSeederApp is the whole CLI of a binary written by `bundle`: it
+// seeds the revision in b into one database and does nothing else.
+func BundledSeederApp(b *bundle.Bundle) *cli.App {
+	m := b.Manifest
+	return &cli.App{
+		Name:      filepath.Base(os.Args[0]),
+		Usage:     fmt.Sprintf("Seed %s @ %s into a database", m.Scenario, m.Revision),
+		ArgsUsage: " ",
+		Description: fmt.Sprintf("Standalone seeder built with `seedmancer bundle` (seedmancer %s).\n"+
+			"Restores scenario %s @ %s (schema %s, %d table(s)) into\n"+
+			"--db-url, replacing the data in those tables.",
+			m.SeedmancerVersion, m.Scenario, m.Revision, utils.FingerprintShort(m.SchemaFingerprint), len(m.Tables)),
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "db-url",
+				Usage:    "Database URL to seed",
+				EnvVars:  []string{"SEEDMANCER_DATABASE_URL"},
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Skip the confirmation prompt",
+			},
+			&cli.DurationFlag{
+				Name:  "wait-for-db",
+				Usage: "Wait up to this long (e.g. 120s) for the database to accept connections and finish recovery",
+			},
+			&cli.StringFlag{
+				Name:  "target-schema",
+				Usage: "PostgreSQL: restore into this schema (created if missing) instead of public",
+			},
+			&cli.StringFlag{
+				Name:  "target-database",
+				Usage: "MySQL: restore into this database (created if missing) instead of the DSN's",
+			},
+			&cli.BoolFlag{
+				Name:    "debug",
+				Usage:   "Show detailed debug output",
+				EnvVars: []string{"SEEDMANCER_DEBUG"},
+			},
+		},
+		Action: func(c *cli.Context) error {
+			ui.SetDebug(c.Bool("debug"))
+			target := utils.NamedEnv{Name: adHocEnvName, EnvConfig: utils.EnvConfig{DatabaseURL: strings.TrimSpace(c.String("db-url"))}}
+			return runBundledSeed(b, target, c.Bool("yes"), c.Duration("wait-for-db"), c.String("target-schema"), c.String("target-database"))
+		},
+	}
+}
+
+// runBundledSeed extracts b, checks it, and restores it into target.
+func runBundledSeed(b *bundle.Bundle, target utils.NamedEnv, yes bool, waitFor time.Duration, targetSchema, targetDatabase string) error {
+	m := b.Manifest
+	var opts db.RestoreOptions
+	if err := applySandboxTarget(&opts, targetSchema, targetDatabase); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "seedmancer-bundle-*")
+	if err != nil {
+		return fmt.Errorf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := b.Extract(dir); err != nil {
+		return err
+	}
+	if err := verifyExtractedChecksums(dir, nil); err != nil {
+		return fmt.Errorf("bundled files failed verification: %w", err)
+	}
+
+	ui.Step("seed %s @ %s (schema %s) → %s", m.Scenario, m.Revision,
+		utils.FingerprintShort(m.SchemaFingerprint), targetDisplay(target))
+	if waitFor > 0 {
+		ui.Step("Waiting up to %s for %s to be ready...", waitFor, targetDisplay(target))
+		if err := db.WaitForReady(context.Background(), target.DatabaseURL, waitFor, func(reason string) {
+			ui.Debug("not ready: %s", reason)
+		}); err != nil {
+			return err
+		}
+	}
+	res := seedOneEnv(target, dir, m.Revision, m.Scenario, yes, opts)
+	if res.Skipped {
+		ui.Info("Skipped.")
+	}
+	return res.Err
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/bundle"
+)
+
+func TestRunBundle_joinsShards(t *testing.T) {
+	dir := stageRevision(t, "smoke", `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer"}]}]}`, map[string]string{"users": ""})
+	shardTable(t, dir, "smoke", "users", "id\n1\n2\n", "id\n3\n")
+
+	output := filepath.Join(dir, "seeder")
+	if _, err := RunBundle(context.Background(), BundleInput{Scenario: "smoke", Output: output}); err != nil {
+		t.Fatalf("RunBundle: %v", err)
+	}
+	b, err := bundle.Open(output)
+	if err != nil {
+		t.Fatalf("bundle.Open: %v", err)
+	}
+	extracted := t.TempDir()
+	if err := b.Extract(extracted); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyExtractedChecksums(extracted, nil); err != nil {
+		t.Fatalf("bundled checksums: %v", err)
+	}
+	users, err := os.ReadFile(filepath.Join(extracted, "users.csv"))
+	if err != nil || string(users) != "id\n1\n2\n3\n" {
+		t.Fatalf("users.csv = %q (err %v)", users, err)
+	}
+	if fileExists(filepath.Join(extracted, "users.csv.001")) {
+		t.Fatal("shards bundled next to the joined CSV")
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/chaos"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// chaosReport is what `seed --chaos-report` writes: enough to reproduce
+// the run (Seed, Rate) and to find every planted value afterwards.
+type chaosReport struct {
+	Seed   int64         `json:"seed"`
+	Rate   float64       `json:"rate"`
+	Faults []chaos.Fault `json:"faults"`
+}
+
+// materializeChaosDir stages a copy of restoreDir whose table CSVs carry
+// faults planted by the chaos package. Everything else is linked in
+// unchanged. The returned cleanup removes the temp dir.
+func materializeChaosDir(restoreDir string, rate float64, seed int64) (string, []chaos.Fault, func(), error) {
+	schema, err := utils.ReadSchemaJSON(filepath.Join(restoreDir, "schema.json"))
+	if err != nil {
+		return "", nil, func() {}, err
+	}
+
+	tmp, err := os.MkdirTemp("", "seedmancer-chaos-*")
+	if err != nil {
+		return "", nil, func() {}, fmt.Errorf("creating temp dir: %v", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(tmp) }
+
+	entries, err := os.ReadDir(restoreDir)
+	if err != nil {
+		cleanup()
+		return "", nil, func() {}, fmt.Errorf("reading restore dir: %v", err)
+	}
+	// CSVs are linked only after chaos.Apply has written its copies, so
+	// Apply never writes through a link into the revision itself.
+	for _, e := range entries {
+		if e.IsDir() || strings.HasSuffix(strings.ToLower(e.Name()), ".csv") {
+			continue
+		}
+		if err := linkOrCopy(filepath.Join(restoreDir, e.Name()), filepath.Join(tmp, e.Name())); err != nil {
+			cleanup()
+			return "", nil, func() {}, fmt.Errorf("staging %s: %v", e.Name(), err)
+		}
+	}
+	faults, err := chaos.Apply(restoreDir, tmp, schema, rate, seed)
+	if err != nil {
+		cleanup()
+		return "", nil, func() {}, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(strings.ToLower(e.Name()), ".csv") {
+			continue
+		}
+		dst := filepath.Join(tmp, e.Name())
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+		if err := linkOrCopy(filepath.Join(restoreDir, e.Name()), dst); err != nil {
+			cleanup()
+			return "", nil, func() {}, fmt.Errorf("staging %s: %v", e.Name(), err)
+		}
+	}
+	return tmp, faults, cleanup, nil
+}
+
+// printChaosSummary reports how many faults of each kind landed in each
+// table.
+func printChaosSummary(report chaosReport) {
+	if len(report.Faults) == 0 {
+		ui.Warn("--chaos planted no faults (no eligible columns, or too few rows for %.4g%%)", report.Rate*100)
+		return
+	}
+	byTable := map[string]map[string]int{}
+	for _, f := range report.Faults {
+		if byTable[f.Table] == nil {
+			byTable[f.Table] = map[string]int{}
+		}
+		byTable[f.Table][f.Kind]++
+	}
+	tables := make([]string, 0, len(byTable))
+	for t := range byTable {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	ui.Info("Planted %d fault(s) in %d table(s) (chaos seed %d):", len(report.Faults), len(tables), report.Seed)
+	for _, t := range tables {
+		kinds := make([]string, 0, len(byTable[t]))
+		for k, n := range byTable[t] {
+			kinds = append(kinds, fmt.Sprintf("%d %s", n, k))
+		}
+		sort.Strings(kinds)
+		ui.Info("  %-20s %s", t, strings.Join(kinds, ", "))
+	}
+}
+
+// writeChaosReport writes report as indented JSON to path.
+func writeChaosReport(path string, report chaosReport) error {
+	if report.Faults == nil {
+		report.Faults = []chaos.Fault{}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing chaos report: %v", err)
+	}
+	return nil
+}
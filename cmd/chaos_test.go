@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaterializeChaosDir(t *testing.T) {
+	src := t.TempDir()
+	schema := `{"tables":[{"name":"users","columns":[
+		{"name":"id","type":"integer","isPrimary":true},
+		{"name":"bio","type":"text","nullable":true}]}]}`
+	users := "id,bio\n1,hi\n2,hello\n"
+	writeFile(t, filepath.Join(src, "schema.json"), schema)
+	writeFile(t, filepath.Join(src, "users.csv"), users)
+	writeFile(t, filepath.Join(src, "notes.csv"), "a\n1\n")
+
+	dir, faults, cleanup, err := materializeChaosDir(src, 1, 7)
+	if err != nil {
+		t.Fatalf("materializeChaosDir: %v", err)
+	}
+	defer cleanup()
+	if len(faults) != 2 {
+		t.Fatalf("got %d faults at rate 1, want 2", len(faults))
+	}
+	raw, err := os.ReadFile(filepath.Join(src, "users.csv"))
+	if err != nil || string(raw) != users {
+		t.Fatalf("source users.csv changed: %q", raw)
+	}
+	for _, name := range []string{"schema.json", "users.csv", "notes.csv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not staged: %v", name, err)
+		}
+	}
+}
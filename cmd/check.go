@@ -24,6 +24,7 @@ import (
 func CheckCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "check",
+		Aliases:   []string{"diff"},
 		Usage:     "Compare a scenario revision schema with the current database",
 		ArgsUsage: "<scenario>",
 		Description: "Loads the schema fingerprint stored on the chosen revision and\n" +
@@ -47,14 +48,19 @@ func CheckCommand() *cli.Command {
 			},
 			&cli.BoolFlag{
 				Name:  "json",
-				Usage: "Emit JSON for CI/CD pipelines",
+				Usage: "Same as --output json",
 			},
+			outputFlag(),
 		},
 		Action: func(c *cli.Context) error {
 			scenarioArg := strings.TrimSpace(c.Args().First())
 			if scenarioArg == "" {
 				return usageError(c, "missing required argument: <scenario>")
 			}
+			asJSON, err := jsonRequested(c)
+			if err != nil {
+				return err
+			}
 			out, err := RunCheck(c.Context, CheckInput{
 				Scenario: scenarioArg,
 				Revision: c.String("revision"),
@@ -64,7 +70,7 @@ func CheckCommand() *cli.Command {
 			if err != nil {
 				return err
 			}
-			if c.Bool("json") {
+			if asJSON {
 				return outputJSON(out)
 			}
 			ui.Title(fmt.Sprintf("%s @ %s", out.Scenario, out.Revision))
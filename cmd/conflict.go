@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/patch"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// What a push does when the cloud copy of a scenario changed since it was
+// last pulled or pushed here (push --on-conflict).
+const (
+	// conflictOverwrite pushes the local revision over the cloud copy.
+	conflictOverwrite = "overwrite"
+	// conflictRename copies the local revision to the next free revision
+	// label and pushes that, so the cloud keeps its revision too.
+	conflictRename = "rename"
+	// conflictDiff pulls the cloud copy as a new revision and reports how
+	// the local one differs from it, pushing nothing.
+	conflictDiff = "diff"
+)
+
+// conflictChoices is the order the interactive prompt offers them in.
+var conflictChoices = []string{conflictOverwrite, conflictRename, conflictDiff}
+
+// parseConflictChoice validates an --on-conflict value. Empty means ask,
+// or fail where there is no terminal to ask on.
+func parseConflictChoice(s string) (string, error) {
+	switch choice := strings.ToLower(strings.TrimSpace(s)); choice {
+	case "", conflictOverwrite, conflictRename, conflictDiff:
+		return choice, nil
+	default:
+		return "", fmt.Errorf("unknown --on-conflict %q (supported: overwrite, rename, diff)", s)
+	}
+}
+
+// cloudDatasetFor finds the cloud copy of scenarioPath among cloud, as
+// listRemoteDatasets indexes it: by stable scenario id first, so a rename
+// in the dashboard doesn't hide it, then by name.
+func cloudDatasetFor(cloud map[string]datasetAPI, scenarioPath, remoteScenarioID string) (datasetAPI, bool) {
+	if remoteScenarioID != "" {
+		for _, ds := range cloud {
+			if ds.ScenarioID == remoteScenarioID {
+				return ds, true
+			}
+		}
+	}
+	ds, ok := cloud[scenarioPath]
+	return ds, ok
+}
+
+// lastSynced returns the manifest of the newest revision in scenarioDir
+// stamped with the cloud revision it mirrored when pulled or pushed, or a
+// zero manifest when there is none.
+func lastSynced(scenarioDir string) scenario.RevisionManifest {
+	revs, _ := scenario.ListRevisions(scenarioDir)
+	for i := len(revs) - 1; i >= 0; i-- {
+		rm, err := scenario.ReadRevisionManifest(filepath.Join(scenarioDir, "revisions", revs[i].ID))
+		if err == nil && rm.RemoteID != "" {
+			return rm
+		}
+	}
+	return scenario.RevisionManifest{}
+}
+
+// remoteChanged reports whether cloud, the cloud copy of the scenario in
+// scenarioDir, moved on since the scenario last synced with it, so that a
+// push would overwrite changes never pulled here. A cloud copy it never
+// synced with counts: someone else pushed it.
+func remoteChanged(scenarioDir string, cloud datasetAPI) bool {
+	return !isPushUpToDate(lastSynced(scenarioDir), cloud)
+}
+
+// cloudDiff is what --on-conflict diff found.
+type cloudDiff struct {
+	// Pulled is the revision the cloud copy was pulled as.
+	Pulled string
+	// Changes are the local revision's rows against the cloud copy's;
+	// nil when their schemas differ.
+	Changes []patch.TableChange
+}
+
+// settleConflict decides what pushing rev does about cloud having changed
+// underneath it: choice, or when empty the user's pick. It returns the
+// revision to push, which is rev or its copy under a new label, or the
+// diff when nothing is to be pushed.
+func settleConflict(ctx context.Context, projectRoot string, cfg utils.Config, rev resolvedRevision, cloud datasetAPI, choice, token string) (resolvedRevision, *cloudDiff, error) {
+	if choice == "" {
+		if !ui.CanPrompt() {
+			return rev, nil, fmt.Errorf("the cloud copy of %s changed since it was last pulled or pushed here (updated %s); pass --on-conflict overwrite, rename or diff",
+				rev.Scenario, cloud.UpdatedAt)
+		}
+		ui.Warn("The cloud copy of %s changed since it was last pulled or pushed here (updated %s)", rev.Scenario, cloud.UpdatedAt)
+		i, err := ui.Select("Push anyway?", []string{
+			fmt.Sprintf("overwrite  push %s over the cloud copy", rev.RevID),
+			fmt.Sprintf("rename     push %s as a new revision, keeping the cloud's", rev.RevID),
+			"diff       pull the cloud copy and compare, pushing nothing",
+		})
+		if errors.Is(err, ui.ErrCanceled) {
+			return rev, nil, fmt.Errorf("push canceled")
+		}
+		if err != nil {
+			return rev, nil, err
+		}
+		choice = conflictChoices[i]
+	}
+
+	switch choice {
+	case conflictRename:
+		m, err := deriveRevision(projectRoot, cfg, rev, "copy", "copy of "+rev.RevID+", pushed alongside the cloud's changes",
+			func(_ string, records [][]string) ([][]string, error) { return records, nil })
+		if err != nil {
+			return rev, nil, fmt.Errorf("copying %s: %w", rev.RevID, err)
+		}
+		ui.Info("Copied %s to %s", rev.RevID, m.Revision)
+		renamed, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, rev.Scenario, m.Revision)
+		return renamed, nil, err
+	case conflictDiff:
+		d, err := diffWithCloud(ctx, projectRoot, cfg, rev, token)
+		return rev, d, err
+	default:
+		return rev, nil, nil
+	}
+}
+
+// diffWithCloud pulls the cloud copy of rev's scenario as a new revision
+// and prints how rev differs from it. latest stays on rev, so nothing
+// local is lost, and the pulled revision's stamp lets the next push go
+// ahead: the cloud's changes have been seen.
+func diffWithCloud(ctx context.Context, projectRoot string, cfg utils.Config, rev resolvedRevision, token string) (*cloudDiff, error) {
+	out, err := RunFetch(ctx, FetchInput{Scenario: rev.Scenario, Token: token})
+	if err != nil {
+		return nil, fmt.Errorf("pulling the cloud copy: %w", err)
+	}
+	scenarioDir := scenario.ScenarioDir(projectRoot, cfg.StoragePath, out.Scenario)
+	sm, err := scenario.ReadManifest(scenarioDir)
+	if err != nil {
+		return nil, err
+	}
+	sm.Latest = rev.RevID
+	if err := scenario.WriteManifest(scenarioDir, sm); err != nil {
+		return nil, err
+	}
+	d := &cloudDiff{Pulled: out.Revision}
+	ui.Info("Pulled the cloud copy of %s as %s; latest stays %s", out.Scenario, out.Revision, rev.RevID)
+
+	if out.SchemaFingerprint != rev.Manifest.SchemaFingerprint {
+		ui.Warn("The schemas differ (%s locally, %s in the cloud); rows can't be compared",
+			utils.FingerprintShort(rev.Manifest.SchemaFingerprint), out.SchemaShort)
+	} else {
+		schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, out.SchemaShort)
+		schema, err := utils.ReadSchemaJSON(filepath.Join(schemaDir, "schema.json"))
+		if err != nil {
+			return nil, err
+		}
+		// Shards are joined into plain CSVs, which is what Diff reads.
+		local, cleanupLocal, err := materializeRestoreDir(schemaDir, rev.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanupLocal()
+		remote, cleanupRemote, err := materializeRestoreDir(schemaDir, out.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanupRemote()
+		staged, err := os.MkdirTemp("", "seedmancer-conflict-*")
+		if err != nil {
+			return nil, fmt.Errorf("creating temp dir: %v", err)
+		}
+		defer os.RemoveAll(staged)
+		d.Changes, err = patch.Diff(remote, local, staged, schema, cfg.DiffIgnoreFunc())
+		if err != nil {
+			return nil, err
+		}
+		if len(d.Changes) == 0 {
+			ui.Info("%s holds the same rows as the cloud copy", rev.RevID)
+		} else {
+			ui.Info("%s against the cloud copy (%s):", rev.RevID, out.Revision)
+			printPatchChanges(d.Changes)
+		}
+	}
+	ui.Info("Push %s over it with `seedmancer push %s`, or seed the cloud's with `seedmancer seed %s --revision %s`",
+		rev.RevID, rev.Scenario, rev.Scenario, out.Revision)
+	return d, nil
+}
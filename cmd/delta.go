@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Incremental transfer. Push and pull both ship a revision as one zip
+// whose checksums.sha256 lists every file in it, and both compare that
+// listing with what the other side already holds:
+//
+//   - pull reads the remote archive piecewise with HTTP Range requests —
+//     its central directory, its checksums.sha256, then only the files
+//     the local latest revision doesn't already hold byte for byte;
+//     those are copied from disk instead.
+//   - push sends the listing when it asks for an upload URL; a server
+//     that already holds some of the files in the scenario's latest cloud
+//     revision names them in the reply, and the zip leaves them out.
+//
+// Either side falls back to the whole archive when the other can't take
+// part: storage that ignores Range, a server that names no files.
+
+// errRangeUnsupported is returned by openRangeArchive when the archive is
+// served whole whatever Range asks for.
+var errRangeUnsupported = errors.New("the archive is not served in ranges")
+
+// rangeBlock is how much a rangeArchive reads per request; archive/zip
+// reads in small pieces, which would otherwise each be a round trip.
+const rangeBlock = 1 << 20
+
+// rangeArchive is an io.ReaderAt over a remote archive, fetched with HTTP
+// Range requests as it is read and cached a block at a time.
+type rangeArchive struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+	token  string // sent only when url is the API itself
+	size   int64
+	// fetched counts the bytes transferred.
+	fetched  int64
+	block    []byte
+	blockOff int64
+}
+
+// openRangeArchive resolves where ds's archive is downloaded from, as
+// downloadDatasetArchive does, and checks that it is served in ranges.
+func openRangeArchive(ctx context.Context, baseURL, token string, ds datasetAPI) (*rangeArchive, error) {
+	a := &rangeArchive{ctx: ctx, client: http.DefaultClient, url: fmt.Sprintf("%s/v1.0/datasets/%s/download", baseURL, ds.ID), token: token}
+	resp, err := a.get(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK && isJSONPointer(resp) {
+		// The API answered with where to fetch the archive from.
+		var pointer struct {
+			URL string `json:"url"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&pointer)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing download response: %v", err)
+		}
+		if pointer.URL == "" {
+			return nil, fmt.Errorf("server returned empty download URL")
+		}
+		a.url, a.token = pointer.URL, ""
+		if resp, err = a.get(0, 0); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, utils.ErrInvalidAPIToken
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, errRangeUnsupported
+	}
+	_, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || size <= 0 {
+		return nil, errRangeUnsupported
+	}
+	// Later requests go straight to wherever redirects led.
+	if final := resp.Request.URL.String(); final != a.url {
+		a.url, a.token = final, ""
+	}
+	a.size = size
+	return a, nil
+}
+
+// get requests bytes first through last of the archive.
+func (a *rangeArchive) get(first, last int64) (*http.Response, error) {
+	ui.Debug("GET %s (bytes %d-%d)", a.url, first, last)
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, a.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %v", err)
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", utils.BearerAPIToken(a.token))
+		utils.ApplyProjectHeader(req, "")
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", first, last))
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading: %v", err)
+	}
+	return resp, nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (a *rangeArchive) ReadAt(p []byte, off int64) (int, error) {
+	if off >= a.size {
+		return 0, io.EOF
+	}
+	n := 0
+	for n < len(p) && off < a.size {
+		if off < a.blockOff || off >= a.blockOff+int64(len(a.block)) {
+			if err := a.fill(off, len(p)-n); err != nil {
+				return n, err
+			}
+		}
+		c := copy(p[n:], a.block[off-a.blockOff:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fill replaces the cached block with the one starting at off, at least
+// want bytes long unless the archive ends first.
+func (a *rangeArchive) fill(off int64, want int) error {
+	end := off + int64(max(want, rangeBlock))
+	if end > a.size {
+		end = a.size
+	}
+	resp, err := a.get(off, end-1)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return httpStatusError(resp, resp.Request)
+	}
+	block := make([]byte, end-off)
+	if _, err := io.ReadFull(resp.Body, block); err != nil {
+		return fmt.Errorf("downloading: %v", err)
+	}
+	a.fetched += int64(len(block))
+	a.block, a.blockOff = block, off
+	return nil
+}
+
+// openIncrementalPull opens ds's archive for a pull that reuses the files
+// of the local revision in revDir the archive holds unchanged. A nil
+// reader means the pull has to download the whole archive: the archive
+// isn't served in ranges, carries no checksums, or shares no file with
+// revDir. Only a rejected token is an error.
+func openIncrementalPull(ctx context.Context, baseURL, token string, ds datasetAPI, revDir, schemaDir string) (*zip.Reader, *rangeArchive, map[string]string, error) {
+	ranged, err := openRangeArchive(ctx, baseURL, token, ds)
+	var zr *zip.Reader
+	if err == nil {
+		zr, err = zip.NewReader(ranged, ranged.size)
+	}
+	var reuse map[string]string
+	if err == nil {
+		reuse, err = reusableFiles(zr, pullBaseFiles(revDir, schemaDir))
+	}
+	if errors.Is(err, utils.ErrInvalidAPIToken) {
+		return nil, nil, nil, err
+	}
+	if err != nil || len(reuse) == 0 {
+		ui.Debug("Downloading %s whole (incremental pull unavailable: %v, %d file(s) reusable)", ds.Name, err, len(reuse))
+		return nil, nil, nil, nil
+	}
+	ui.Debug("Reusing %d unchanged file(s) of %s", len(reuse), revDir)
+	return zr, ranged, reuse, nil
+}
+
+// pullBaseFiles lists the local files a pull may reuse, by in-archive
+// name: the files of the scenario's latest revision (revDir), its
+// dataset.sql and the sidecars of the schema the cloud copy uses
+// (schemaDir).
+func pullBaseFiles(revDir, schemaDir string) map[string]string {
+	files := map[string]string{}
+	for _, dir := range []string{schemaDir, filepath.Join(revDir, "data")} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() || e.Type()&os.ModeSymlink != 0 {
+				files[e.Name()] = filepath.Join(dir, e.Name())
+			}
+		}
+	}
+	if sqlPath := DatasetSQLPath(revDir); fileExists(sqlPath) {
+		files[filepath.Base(sqlPath)] = sqlPath
+	}
+	return files
+}
+
+// reusableFiles maps each file of the archive zr that a file in local
+// already holds byte for byte, going by the archive's checksums.sha256,
+// to that local file. An archive without the listing reuses nothing.
+func reusableFiles(zr *zip.Reader, local map[string]string) (map[string]string, error) {
+	var listing *zip.File
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) == scenario.ChecksumsFileName {
+			listing = f
+		}
+	}
+	if listing == nil {
+		return nil, nil
+	}
+	rc, err := listing.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening file in zip: %v", err)
+	}
+	sums, err := scenario.ParseChecksums(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	reuse := map[string]string{}
+	for name, sum := range sums {
+		path, ok := local[name]
+		if !ok {
+			continue
+		}
+		if got, err := scenario.FileSHA256(path); err == nil && got == sum {
+			reuse[name] = path
+		}
+	}
+	return reuse, nil
+}
+
+// openEntry opens file for extraction: the local copy reuse names for it,
+// if any, otherwise the archive entry itself.
+func openEntry(file *zip.File, reuse map[string]string) (io.ReadCloser, error) {
+	if path, ok := reuse[filepath.Base(file.Name)]; ok {
+		return os.Open(path)
+	}
+	return file.Open()
+}
+
+// uploadEntries drops from entries the files the server said it already
+// holds (reuse, by in-archive name). The checksums listing always goes:
+// it is what tells the server which files the revision has.
+func uploadEntries(entries, reuse []string) []string {
+	if len(reuse) == 0 {
+		return entries
+	}
+	held := make(map[string]bool, len(reuse))
+	for _, name := range reuse {
+		held[name] = true
+	}
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if name := filepath.Base(e); !held[name] || name == scenario.ChecksumsFileName {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withChecksums adds the checksums.sha256 push bundles to files.
+func withChecksums(files map[string]string) map[string]string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sums strings.Builder
+	for _, name := range names {
+		sum := sha256.Sum256([]byte(files[name]))
+		sums.WriteString(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	}
+	files["checksums.sha256"] = sums.String()
+	return files
+}
+
+// TestRunFetch_incremental pulls a cloud copy sharing all but one file with
+// the local latest revision and expects only that file to be read from
+// the archive, the rest copied from disk.
+func TestRunFetch_incremental(t *testing.T) {
+	const schema = `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer"}]},{"name":"orders","columns":[{"name":"id","type":"integer"}]}]}`
+	// Random enough not to compress below the few MiB that make reading
+	// around it worthwhile.
+	var b strings.Builder
+	b.WriteString("id\n")
+	for i := 0; b.Len() < 4<<20; i++ {
+		sum := sha256.Sum256([]byte{byte(i), byte(i >> 8), byte(i >> 16)})
+		b.WriteString(hex.EncodeToString(sum[:]) + "\n")
+	}
+	users := b.String()
+	dir := stageRevision(t, "bench/x", schema, map[string]string{
+		"users":  users,
+		"orders": "id\n1\n",
+	})
+
+	zipBytes, err := compressTestZip(withChecksums(map[string]string{
+		"schema.json": schema,
+		"users.csv":   users,
+		"orders.csv":  "id\n1\n2\n",
+	}))
+	if err != nil {
+		t.Fatalf("build zip: %v", err)
+	}
+	var ranged int
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.0/datasets":
+			_ = json.NewEncoder(w).Encode(datasetListResponse{Datasets: []datasetAPI{{
+				ID:     "rev_2",
+				Name:   "bench/x",
+				Schema: &schemaRefShort{ID: "s1", Fingerprint: strings.Repeat("ab", 32), FingerprintShort: "abababababab"},
+			}}})
+		case "/v1.0/datasets/rev_2/download":
+			_ = json.NewEncoder(w).Encode(map[string]string{"url": srvURL + "/blob.zip"})
+		case "/blob.zip":
+			if r.Header.Get("Range") != "" {
+				ranged++
+			}
+			http.ServeContent(w, r, "blob.zip", time.Time{}, bytes.NewReader(zipBytes))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	t.Setenv("SEEDMANCER_API_URL", srv.URL)
+
+	out, err := RunFetch(t.Context(), FetchInput{Scenario: "bench/x", Token: "tok"})
+	if err != nil {
+		t.Fatalf("RunFetch: %v", err)
+	}
+	if out.Revision != "r002" || out.Reused != 2 {
+		t.Fatalf("out = %+v, want r002 reusing schema.json and users.csv", out)
+	}
+	if ranged == 0 || out.BytesDownloaded >= int64(len(zipBytes)) {
+		t.Fatalf("read %d of %d bytes in %d ranged request(s); want part of the archive", out.BytesDownloaded, len(zipBytes), ranged)
+	}
+	for name, want := range map[string]string{"users.csv": users, "orders.csv": "id\n1\n2\n"} {
+		got, err := os.ReadFile(filepath.Join(out.Path, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %d bytes (err %v), want the cloud copy", name, len(got), err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".seedmancer", "schemas", "abababababab", "schema.json")); err != nil {
+		t.Errorf("schema.json not in the schema store: %v", err)
+	}
+}
+
+// TestSyncUploadPresigned_skipsFilesTheServerHolds has the server name one
+// file as already held and expects the uploaded zip to leave it out.
+func TestSyncUploadPresigned_skipsFilesTheServerHolds(t *testing.T) {
+	dir := t.TempDir()
+	var entries []string
+	for name, body := range map[string]string{"schema.json": "{}", "users.csv": "id\n1\n", "orders.csv": "id\n2\n"} {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, body)
+		entries = append(entries, path)
+	}
+	sumsPath, cleanup, err := writeBundleChecksums(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	entries = append(entries, sumsPath)
+
+	var listed map[string]string
+	var uploaded []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1.0/datasets/sync/upload-url":
+			var body struct {
+				Files map[string]string `json:"files"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			listed = body.Files
+			_ = json.NewEncoder(w).Encode(uploadURLResponse{
+				UploadURL: server.URL + "/blob",
+				Path:      "staging/x.zip",
+				Reuse:     []string{"users.csv", "schema.json"},
+			})
+		case r.URL.Path == "/blob":
+			data, _ := io.ReadAll(r.Body)
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Errorf("uploaded zip: %v", err)
+			}
+			for _, f := range zr.File {
+				uploaded = append(uploaded, f.Name)
+			}
+		case r.URL.Path == "/v1.0/datasets/sync/confirm":
+			_ = json.NewEncoder(w).Encode(syncUploadResult{ID: "ds_1"})
+		}
+	}))
+	defer server.Close()
+
+	if _, err := syncUploadPresigned(t.Context(), "tok", server.URL, "bench/x", "r001", "", entries); err != nil {
+		t.Fatalf("syncUploadPresigned: %v", err)
+	}
+	if len(listed) != 3 || listed["users.csv"] == "" {
+		t.Fatalf("upload-url listed %v, want every file's checksum", listed)
+	}
+	sort.Strings(uploaded)
+	if strings.Join(uploaded, ",") != "checksums.sha256,orders.csv" {
+		t.Fatalf("uploaded %v, want only orders.csv and the listing", uploaded)
+	}
+}
@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// downloadOptions tunes resumableDownload. The zero value retries five
+// times starting at one second.
+type downloadOptions struct {
+	// Attempts is how many times the download is tried in total.
+	Attempts int
+	// Backoff is the wait before the first retry; it doubles on every
+	// retry up to MaxBackoff. A Retry-After header takes precedence.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Version identifies the remote content. A partial file left by a
+	// download of another version is discarded instead of resumed.
+	Version string
+	// Progress is called as bytes arrive with the bytes on disk so far
+	// and the total size, or -1 while the size is unknown.
+	Progress func(done, total int64)
+	// OnRetry is called before waiting for the next attempt.
+	OnRetry func(attempt int, wait time.Duration, err error)
+}
+
+// downloadState is kept next to the partial file (<path>.part.json) so a
+// later run can resume it with a conditional range request.
+type downloadState struct {
+	Version      string `json:"version"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Total        int64  `json:"total"`
+}
+
+// permanentError marks a failure retrying can't fix, such as a 404 or a
+// rejected token.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// retryAfterError carries the wait a 429 or 503 response asked for.
+type retryAfterError struct {
+	err  error
+	wait time.Duration
+}
+
+func (e retryAfterError) Error() string { return e.err.Error() }
+func (e retryAfterError) Unwrap() error { return e.err }
+
+// resumableDownload downloads to path what newRequest points at, writing
+// to <path>.part as it goes. An interrupted transfer is retried with
+// exponential backoff and picks up where it stopped with an HTTP Range
+// request, validated by If-Range so a changed file is fetched afresh; the
+// partial file survives a failed run, so the next run resumes it too.
+//
+// newRequest is called for every attempt. Redirects are followed by the
+// client, and a JSON reply of the form {"url": "..."} — how the
+// Seedmancer API hands out presigned storage URLs — is followed as well,
+// so a URL that expired between attempts is simply issued again.
+//
+// It returns the number of bytes transferred by this call.
+func resumableDownload(ctx context.Context, client *http.Client, newRequest func(context.Context) (*http.Request, error), path string, opts downloadOptions) (int64, error) {
+	if opts.Attempts <= 0 {
+		opts.Attempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	part, statePath := path+".part", path+".part.json"
+
+	state := readDownloadState(statePath)
+	if state.Version != opts.Version {
+		_ = os.Remove(part)
+		state = downloadState{Version: opts.Version}
+	}
+
+	var transferred int64
+	for attempt := 1; ; attempt++ {
+		n, err := downloadAttempt(ctx, client, newRequest, part, statePath, &state, opts.Progress)
+		transferred += n
+		if err == nil {
+			if err := os.Rename(part, path); err != nil {
+				return transferred, err
+			}
+			_ = os.Remove(statePath)
+			return transferred, nil
+		}
+		var perm permanentError
+		if errors.As(err, &perm) || ctx.Err() != nil {
+			return transferred, err
+		}
+		if attempt >= opts.Attempts {
+			return transferred, fmt.Errorf("download failed after %d attempts: %w (the partial download is kept; run the command again to resume)", attempt, err)
+		}
+		wait := opts.Backoff << (attempt - 1)
+		if wait > opts.MaxBackoff || wait <= 0 {
+			wait = opts.MaxBackoff
+		}
+		var ra retryAfterError
+		if errors.As(err, &ra) && ra.wait > 0 {
+			wait = ra.wait
+		}
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt, wait, err)
+		}
+		select {
+		case <-ctx.Done():
+			return transferred, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// downloadAttempt makes one request, appending to part from its current
+// size. A nil error means part holds the whole file.
+func downloadAttempt(ctx context.Context, client *http.Client, newRequest func(context.Context) (*http.Request, error), part, statePath string, state *downloadState, progress func(done, total int64)) (int64, error) {
+	var offset int64
+	if fi, err := os.Stat(part); err == nil {
+		offset = fi.Size()
+	}
+	if offset > 0 && state.Total > 0 && offset == state.Total {
+		return 0, nil // a previous run finished the transfer but not the rename
+	}
+
+	req, err := newRequest(ctx)
+	if err != nil {
+		return 0, permanentError{err}
+	}
+	resp, err := doRanged(client, req, offset, *state)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode == http.StatusOK && isJSONPointer(resp) {
+		// The API answered with where to fetch the archive from.
+		var pointer struct {
+			URL string `json:"url"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&pointer)
+		resp.Body.Close()
+		if err != nil {
+			return 0, fmt.Errorf("parsing download response: %v", err)
+		}
+		if pointer.URL == "" {
+			return 0, permanentError{errors.New("server returned empty download URL")}
+		}
+		next, err := http.NewRequestWithContext(ctx, http.MethodGet, pointer.URL, nil)
+		if err != nil {
+			return 0, permanentError{fmt.Errorf("invalid download URL: %v", err)}
+		}
+		if resp, err = doRanged(client, next, offset, *state); err != nil {
+			return 0, err
+		}
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			_ = os.Remove(part)
+			return 0, fmt.Errorf("server resumed at the wrong offset (%q); starting over", resp.Header.Get("Content-Range"))
+		}
+		total = size
+	case http.StatusOK:
+		// No range support, or the file changed: start from scratch.
+		offset = 0
+		if err := os.Remove(part); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		_ = os.Remove(part)
+		return 0, errors.New("server rejected the resume range; starting over")
+	default:
+		return 0, httpStatusError(resp, req)
+	}
+
+	state.ETag = resp.Header.Get("ETag")
+	state.LastModified = resp.Header.Get("Last-Modified")
+	state.Total = total
+	if err := writeDownloadState(statePath, *state); err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, permanentError{fmt.Errorf("creating %s: %v", part, err)}
+	}
+	w := &progressWriter{w: f, done: offset, total: total, report: progress}
+	if progress != nil {
+		progress(offset, total)
+	}
+	n, copyErr := io.Copy(w, resp.Body)
+	if err := f.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return n, fmt.Errorf("connection lost after %s: %v", formatBytes(offset+n), copyErr)
+	}
+	if total >= 0 && offset+n != total {
+		return n, fmt.Errorf("connection closed after %s of %s", formatBytes(offset+n), formatBytes(total))
+	}
+	return n, nil
+}
+
+// doRanged sends req asking for the bytes from offset on, provided the
+// file still matches state.
+func doRanged(client *http.Client, req *http.Request, offset int64, state downloadState) (*http.Response, error) {
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if state.ETag != "" {
+			req.Header.Set("If-Range", state.ETag)
+		} else if state.LastModified != "" {
+			req.Header.Set("If-Range", state.LastModified)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading: %v", err)
+	}
+	return resp, nil
+}
+
+// httpStatusError classifies a failed response: server errors, throttling
+// and timeouts are worth retrying, as is a 403 from storage (an expired
+// presigned URL is issued again on the next attempt). Other client
+// errors are permanent.
+func httpStatusError(resp *http.Response, req *http.Request) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err := fmt.Errorf("download failed (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		return retryAfterError{err: err, wait: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout:
+		return err
+	case resp.StatusCode == http.StatusForbidden && resp.Request != nil && resp.Request.URL.Host != req.URL.Host:
+		return err
+	}
+	return permanentError{err}
+}
+
+// isJSONPointer reports whether resp is a JSON document rather than the
+// file itself, going by Content-Type or, when the server didn't set one,
+// by the first byte of the body.
+func isJSONPointer(resp *http.Response) bool {
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mt == "application/json" {
+		return true
+	}
+	if mt != "" && mt != "text/plain" {
+		return false
+	}
+	br := bufio.NewReader(resp.Body)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, resp.Body}
+	first, err := br.Peek(1)
+	return err == nil && first[0] == '{'
+}
+
+// parseContentRange parses "bytes 100-199/200"; size is -1 for "/*".
+func parseContentRange(h string) (start, size int64, ok bool) {
+	rest, found := strings.CutPrefix(h, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, sz, found := strings.Cut(rest, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if sz == "*" {
+		return start, -1, true
+	}
+	size, err = strconv.ParseInt(sz, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds, capped at
+// a minute. HTTP dates are ignored.
+func parseRetryAfter(h string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(h))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	if secs > 60 {
+		secs = 60
+	}
+	return time.Duration(secs) * time.Second
+}
+
+func readDownloadState(path string) downloadState {
+	var s downloadState
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	_ = json.Unmarshal(raw, &s)
+	return s
+}
+
+func writeDownloadState(path string, s downloadState) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// progressWriter reports every write to report.
+type progressWriter struct {
+	w      io.Writer
+	done   int64
+	total  int64
+	report func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.report != nil {
+		p.report(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testBlob = []byte(strings.Repeat("0123456789", 1000))
+
+func fastRetries() downloadOptions {
+	return downloadOptions{Attempts: 4, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+}
+
+func getRequest(url string) func(context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+}
+
+// flakyBlob serves testBlob honouring Range, but the first response is
+// cut off after half the bytes.
+func flakyBlob(t *testing.T, ranges *[]string) http.HandlerFunc {
+	var calls int32
+	return func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		start := 0
+		if rng := r.Header.Get("Range"); rng != "" {
+			if r.Header.Get("If-Range") != `"v1"` {
+				t.Errorf("If-Range = %q", r.Header.Get("If-Range"))
+			}
+			start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(testBlob)-1)+"/"+strconv.Itoa(len(testBlob)))
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Length", strconv.Itoa(len(testBlob)-start))
+		if start > 0 {
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			_, _ = w.Write(testBlob[:len(testBlob)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		_, _ = w.Write(testBlob[start:])
+	}
+}
+
+func TestResumableDownload_resumesAfterDrop(t *testing.T) {
+	var ranges []string
+	srv := httptest.NewServer(flakyBlob(t, &ranges))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "a.zip")
+	retries := 0
+	opts := fastRetries()
+	opts.OnRetry = func(int, time.Duration, error) { retries++ }
+	var last int64
+	opts.Progress = func(done, total int64) { last = done }
+	n, err := resumableDownload(t.Context(), srv.Client(), getRequest(srv.URL), path, opts)
+	if err != nil {
+		t.Fatalf("resumableDownload: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != string(testBlob) {
+		t.Fatalf("downloaded %d bytes that differ from the blob", len(got))
+	}
+	if n != int64(len(testBlob)) || last != int64(len(testBlob)) || retries != 1 {
+		t.Errorf("n=%d last progress=%d retries=%d", n, last, retries)
+	}
+	if len(ranges) != 2 || ranges[0] != "" || ranges[1] != "bytes="+strconv.Itoa(len(testBlob)/2)+"-" {
+		t.Errorf("ranges = %q", ranges)
+	}
+	if _, err := os.Stat(path + ".part.json"); !os.IsNotExist(err) {
+		t.Errorf("state file left behind: %v", err)
+	}
+}
+
+func TestResumableDownload_resumesAcrossRuns(t *testing.T) {
+	var ranges []string
+	srv := httptest.NewServer(flakyBlob(t, &ranges))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "a.zip")
+	opts := fastRetries()
+	opts.Attempts = 1
+	opts.Version = "ds@1"
+	if _, err := resumableDownload(t.Context(), srv.Client(), getRequest(srv.URL), path, opts); err == nil ||
+		!strings.Contains(err.Error(), "run the command again to resume") {
+		t.Fatalf("first run err = %v", err)
+	}
+	n, err := resumableDownload(t.Context(), srv.Client(), getRequest(srv.URL), path, opts)
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if n != int64(len(testBlob)/2) {
+		t.Errorf("second run transferred %d bytes, want %d", n, len(testBlob)/2)
+	}
+
+	// A partial file of another version is not resumed.
+	ranges = nil
+	if err := os.WriteFile(path+".part", []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = writeDownloadState(path+".part.json", downloadState{Version: "ds@0", ETag: `"v1"`})
+	opts.Version = "ds@2"
+	opts.Attempts = 2
+	if _, err := resumableDownload(t.Context(), srv.Client(), getRequest(srv.URL), path, opts); err != nil {
+		t.Fatal(err)
+	}
+	if ranges[0] != "" {
+		t.Errorf("resumed a stale partial file: ranges = %q", ranges)
+	}
+}
+
+func TestResumableDownload_followsJSONPointerAndRetries5xx(t *testing.T) {
+	var apiCalls, blobCalls int32
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download":
+			atomic.AddInt32(&apiCalls, 1)
+			_ = json.NewEncoder(w).Encode(map[string]string{"url": srvURL + "/blob"})
+		case "/blob":
+			if atomic.AddInt32(&blobCalls, 1) == 1 {
+				http.Error(w, "busy", http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write(testBlob)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	path := filepath.Join(t.TempDir(), "a.zip")
+	if _, err := resumableDownload(t.Context(), srv.Client(), getRequest(srv.URL+"/download"), path, fastRetries()); err != nil {
+		t.Fatalf("resumableDownload: %v", err)
+	}
+	if apiCalls != 2 || blobCalls != 2 {
+		t.Errorf("api calls %d, blob calls %d; want a fresh URL per attempt", apiCalls, blobCalls)
+	}
+}
+
+func TestResumableDownload_doesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "no such dataset", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := resumableDownload(t.Context(), srv.Client(), getRequest(srv.URL), filepath.Join(t.TempDir(), "a.zip"), fastRetries())
+	if err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Fatalf("err = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("retried a 404: %d calls", calls)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	for h, want := range map[string][2]int64{
+		"bytes 100-199/200": {100, 200},
+		"bytes 0-9/*":       {0, -1},
+	} {
+		start, size, ok := parseContentRange(h)
+		if !ok || start != want[0] || size != want[1] {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", h, start, size, ok)
+		}
+	}
+	if _, _, ok := parseContentRange("items 1-2/3"); ok {
+		t.Error("accepted a non-byte range")
+	}
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/embedgen"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// EmbedCommand writes a Go package that go:embeds one revision and
+// exposes Seed(*sql.DB), so tests seed from their own compiled binary.
+//
+//	seedmancer embed billing/pro --revision r003 --package fixtures -o internal/fixtures
+func EmbedCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "embed",
+		Usage:     "Generate a Go package that embeds a scenario revision for tests",
+		ArgsUsage: "<scenario>",
+		Description: "Copies the revision (latest by default) — its CSVs, schema.json and\n" +
+			"schema sidecars — into <output>/" + embedgen.DataDir + "/ and writes\n" +
+			"<output>/" + embedgen.FileName + " with a go:embed of those files and\n\n" +
+			"  func Seed(conn *sql.DB) error\n\n" +
+			"which restores them through seedmancer's own restore code. The\n" +
+			"generated package imports github.com/KazanKK/seedmancer/database,\n" +
+			"so add the module to your go.mod. Re-running replaces both.\n\n" +
+			"--output defaults to a directory named after --package. Go ignores\n" +
+			"directories called testdata, so pick another name for an\n" +
+			"importable package.\n\n" +
+			"Examples:\n" +
+			"  seedmancer embed billing/pro --package fixtures -o internal/fixtures\n" +
+			"  seedmancer embed billing/pro -r r003 -p fixtures",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "revision",
+				Aliases: []string{"r"},
+				Usage:   "Revision to embed (defaults to latest)",
+			},
+			&cli.StringFlag{
+				Name:     "package",
+				Aliases:  []string{"p"},
+				Usage:    "Go package name of the generated file",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Directory to write the package into (defaults to the package name)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			out, err := RunEmbed(context.Background(), EmbedInput{
+				Scenario: scenarioArg,
+				Revision: strings.TrimSpace(c.String("revision")),
+				Package:  strings.TrimSpace(c.String("package")),
+				Output:   strings.TrimSpace(c.String("output")),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+			ui.Success("Embedded %s @ %s into package %s", out.Scenario, out.Revision, out.Package)
+			ui.KeyValue("Dir: ", out.Dir)
+			ui.KeyValue("Files: ", fmt.Sprintf("%d", out.Files))
+			if filepath.Base(out.Dir) == "testdata" {
+				ui.Warn("Go ignores directories named testdata; the package can't be imported from there")
+			}
+			return nil
+		},
+	}
+}
+
+// EmbedInput selects the revision to embed and the package to write.
+type EmbedInput struct {
+	Scenario string `json:"scenario" jsonschema:"Scenario path"`
+	Revision string `json:"revision,omitempty" jsonschema:"Revision to embed (defaults to latest)"`
+	Package  string `json:"package" jsonschema:"Go package name of the generated file"`
+	Output   string `json:"output,omitempty" jsonschema:"Directory to write the package into (defaults to the package name)"`
+}
+
+// EmbedOutput reports the generated package.
+type EmbedOutput struct {
+	Scenario string `json:"scenario"`
+	Revision string `json:"revision"`
+	Package  string `json:"package"`
+	Dir      string `json:"dir"`
+	Files    int    `json:"files"`
+}
+
+// RunEmbed writes the Go package for one revision.
+func RunEmbed(_ context.Context, in EmbedInput) (EmbedOutput, error) {
+	if in.Output == "" {
+		in.Output = in.Package
+	}
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return EmbedOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return EmbedOutput{}, err
+	}
+	scenarioPath, err := scenario.Normalize(in.Scenario)
+	if err != nil {
+		return EmbedOutput{}, err
+	}
+	rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+	if err != nil {
+		return EmbedOutput{}, err
+	}
+	if err := verifyRevisionChecksum(rev); err != nil {
+		return EmbedOutput{}, err
+	}
+
+	// Render first so a bad package name fails before anything is written.
+	src, err := embedgen.Render(embedgen.Params{
+		Package:           in.Package,
+		Scenario:          scenarioPath,
+		Revision:          rev.RevID,
+		SchemaFingerprint: rev.Manifest.SchemaFingerprint,
+		DatabaseType:      rev.Manifest.DatabaseType,
+		Tables:            rev.Manifest.Tables,
+		SeedmancerVersion: utils.CLIVersion(),
+	})
+	if err != nil {
+		return EmbedOutput{}, err
+	}
+
+	schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	files, cleanupFiles, err := joinedRevisionFiles(schemaDir, rev.DataDir)
+	if err != nil {
+		return EmbedOutput{}, err
+	}
+	defer cleanupFiles()
+
+	dataDir := filepath.Join(in.Output, embedgen.DataDir)
+	if err := os.RemoveAll(dataDir); err != nil {
+		return EmbedOutput{}, fmt.Errorf("clearing %s: %v", dataDir, err)
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return EmbedOutput{}, fmt.Errorf("creating %s: %v", dataDir, err)
+	}
+	for _, f := range files {
+		if err := copyFile(f, filepath.Join(dataDir, filepath.Base(f))); err != nil {
+			return EmbedOutput{}, fmt.Errorf("copying %s: %v", filepath.Base(f), err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(in.Output, embedgen.FileName), src, 0644); err != nil {
+		return EmbedOutput{}, fmt.Errorf("writing %s: %v", embedgen.FileName, err)
+	}
+
+	return EmbedOutput{
+		Scenario: scenarioPath,
+		Revision: rev.RevID,
+		Package:  in.Package,
+		Dir:      in.Output,
+		Files:    len(files),
+	}, nil
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/embedgen"
+)
+
+func TestRunEmbed_joinsShards(t *testing.T) {
+	dir := stageRevision(t, "smoke", `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer"}]}]}`, map[string]string{"users": ""})
+	shardTable(t, dir, "smoke", "users", "id\n1\n2\n", "id\n3\n")
+
+	out, err := RunEmbed(context.Background(), EmbedInput{Scenario: "smoke", Package: "fixtures", Output: filepath.Join(dir, "fixtures")})
+	if err != nil {
+		t.Fatalf("RunEmbed: %v", err)
+	}
+	dataDir := filepath.Join(out.Dir, embedgen.DataDir)
+	users, err := os.ReadFile(filepath.Join(dataDir, "users.csv"))
+	if err != nil || string(users) != "id\n1\n2\n3\n" {
+		t.Fatalf("users.csv = %q (err %v)", users, err)
+	}
+	if fileExists(filepath.Join(dataDir, "users.csv.001")) {
+		t.Fatal("shards embedded next to the joined CSV")
+	}
+	if !fileExists(filepath.Join(dataDir, "schema.json")) {
+		t.Fatal("schema.json not embedded")
+	}
+}
@@ -31,7 +31,11 @@ func EnvCommand() *cli.Command {
 		Description: "An environment is a named database target. The same dataset can be\n" +
 			"seeded into many of them via `seedmancer seed --env local,staging`.\n" +
 			"The default environment is used when --env is omitted; change it with\n" +
-			"`seedmancer env use <name>`.",
+			"`seedmancer env use <name>`.\n\n" +
+			"Database URLs may reference environment variables as ${NAME} or\n" +
+			"${NAME:-fallback}, e.g. --db-url 'postgres://app:${PGPASSWORD}@db/app',\n" +
+			"so seedmancer.yaml can be committed and credentials stay out of\n" +
+			"shell history. They are expanded each time the env is used.",
 		Subcommands: []*cli.Command{
 			envListCommand(),
 			envCurrentCommand(),
@@ -332,6 +336,26 @@ func loadConfigForEnvCmd() (string, utils.Config, error) {
 	return path, cfg, nil
 }
 
+// loadConfigOrAdHoc is loadConfigForEnvCmd for commands that only need
+// a database URL: when no seedmancer.yaml is found but dbURL (or
+// SEEDMANCER_DATABASE_URL) names the database, it returns the zero
+// config and an empty path instead of failing, so one-off runs and
+// scripts work without `seedmancer init`.
+func loadConfigOrAdHoc(dbURL string) (string, utils.Config, error) {
+	path, err := utils.FindConfigFile()
+	if err != nil {
+		if strings.TrimSpace(dbURL) != "" || strings.TrimSpace(os.Getenv("SEEDMANCER_DATABASE_URL")) != "" {
+			return "", utils.Config{}, nil
+		}
+		return "", utils.Config{}, fmt.Errorf("%v — run `seedmancer init` first, or pass --db-url", err)
+	}
+	cfg, err := utils.LoadConfig(path)
+	if err != nil {
+		return "", utils.Config{}, err
+	}
+	return path, cfg, nil
+}
+
 // validateEnvName enforces a sane character set for env names so they can
 // show up in filenames, shell arguments, and URLs without needing to be
 // quoted. Matches what users type for git branches / docker tags today.
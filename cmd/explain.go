@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/explain"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// explainRowsShown caps the child rows printed per foreign key; --output
+// json has them all.
+const explainRowsShown = 10
+
+// ExplainCommand prints one row of a scenario revision with the rows it
+// references and the rows referencing it, read straight from the CSVs.
+// Reviewers get to see what a fixture entity is made of without seeding
+// it anywhere.
+//
+//	seedmancer explain billing/pro --table users --row id=42
+func ExplainCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "explain",
+		Usage:     "Show one row of a scenario with its foreign key parents and children",
+		ArgsUsage: "<scenario>",
+		Description: "Finds the row of --table that --row selects in the chosen revision\n" +
+			"(latest by default) and prints it along with its immediate\n" +
+			"neighbours: the parent rows its foreign keys name, and the child\n" +
+			"rows whose foreign keys name it. Nothing is written and no database\n" +
+			"is needed.\n\n" +
+			"--row takes column=value pairs, comma-separated for a composite key,\n" +
+			"and must select exactly one row. Tables without a CSV are skipped;\n" +
+			"a parent that should be there but isn't is reported as missing.\n\n" +
+			"Examples:\n" +
+			"  seedmancer explain billing/pro --table users --row id=42\n" +
+			"  seedmancer explain billing/pro --table members --row org_id=1,user_id=42 --revision r003",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "table",
+				Usage:    "Table holding the row",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "row",
+				Usage:    "Row to explain, as column=value (comma-separated for several columns)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "revision",
+				Usage: "Revision to read (defaults to latest)",
+			},
+			outputFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			asJSON, err := jsonRequested(c)
+			if err != nil {
+				return err
+			}
+			out, err := RunExplain(context.Background(), ExplainInput{
+				Scenario: scenarioArg,
+				Revision: strings.TrimSpace(c.String("revision")),
+				Table:    strings.TrimSpace(c.String("table")),
+				Row:      c.String("row"),
+			})
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return outputJSON(out)
+			}
+
+			ui.Title(fmt.Sprintf("%s @ %s: %s %s", out.Scenario, out.Revision, out.Table, out.Row))
+			for i, col := range out.Header {
+				ui.KeyValue(col+":", out.Values[i])
+			}
+			fmt.Println()
+			if len(out.Parents) == 0 {
+				ui.Info("Parents: none")
+			} else {
+				ui.Info("Parents:")
+			}
+			for _, rel := range out.Parents {
+				ui.Info("  %s", rel.Via)
+				if len(rel.Rows) == 0 {
+					ui.Warn("    missing: %s has no row with %s", rel.Table, rel.Value)
+				}
+				printExplainRows(rel)
+			}
+			fmt.Println()
+			if len(out.Children) == 0 {
+				ui.Info("Children: none")
+			} else {
+				ui.Info("Children:")
+			}
+			for _, rel := range out.Children {
+				ui.Info("  %s (%d row(s))", rel.Via, len(rel.Rows))
+				printExplainRows(rel)
+			}
+			return nil
+		},
+	}
+}
+
+// printExplainRows prints rel's rows as column=value lists, up to
+// explainRowsShown of them.
+func printExplainRows(rel explain.Related) {
+	for i, rec := range rel.Rows {
+		if i == explainRowsShown {
+			ui.Info("    … and %d more (see --output json)", len(rel.Rows)-i)
+			return
+		}
+		cells := make([]string, 0, len(rec))
+		for j, v := range rec {
+			if j < len(rel.Header) {
+				cells = append(cells, rel.Header[j]+"="+v)
+			}
+		}
+		ui.Info("    %s", strings.Join(cells, " "))
+	}
+}
+
+// ExplainInput selects the revision and the row to explain.
+type ExplainInput struct {
+	Scenario string `json:"scenario" jsonschema:"Scenario path"`
+	Revision string `json:"revision,omitempty" jsonschema:"Revision to read (defaults to latest)"`
+	Table    string `json:"table" jsonschema:"Table holding the row"`
+	Row      string `json:"row" jsonschema:"Row to explain as column=value, comma-separated for several columns, e.g. id=42"`
+}
+
+// ExplainOutput is the row with its immediate foreign key neighbours.
+type ExplainOutput struct {
+	Scenario string            `json:"scenario"`
+	Revision string            `json:"revision"`
+	Table    string            `json:"table"`
+	Row      string            `json:"row"`
+	Header   []string          `json:"header"`
+	Values   []string          `json:"values"`
+	Parents  []explain.Related `json:"parents"`
+	Children []explain.Related `json:"children"`
+}
+
+// RunExplain looks up one row of a revision and its parents and children
+// in the revision's CSVs.
+func RunExplain(_ context.Context, in ExplainInput) (ExplainOutput, error) {
+	if in.Table == "" {
+		return ExplainOutput{}, fmt.Errorf("table is required")
+	}
+	match, err := explain.ParseMatch(in.Row)
+	if err != nil {
+		return ExplainOutput{}, err
+	}
+
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return ExplainOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return ExplainOutput{}, err
+	}
+	scenarioPath, err := scenario.Normalize(in.Scenario)
+	if err != nil {
+		return ExplainOutput{}, err
+	}
+	base, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+	if err != nil {
+		return ExplainOutput{}, err
+	}
+	schema, err := loadRevisionSchema(projectRoot, cfg.StoragePath, base)
+	if err != nil {
+		return ExplainOutput{}, err
+	}
+
+	tableNames, _, err := listCSVTablesAndRowCounts(base.DataDir)
+	if err != nil {
+		return ExplainOutput{}, err
+	}
+	tables := make(map[string][][]string, len(tableNames))
+	for _, t := range tableNames {
+		if tables[t], err = utils.ReadCSVRecords(filepath.Join(base.DataDir, t+".csv")); err != nil {
+			return ExplainOutput{}, err
+		}
+	}
+
+	entity, err := explain.Explain(schema, tables, in.Table, match)
+	if err != nil {
+		return ExplainOutput{}, fmt.Errorf("%s @ %s: %w", scenarioPath, base.RevID, err)
+	}
+	return ExplainOutput{
+		Scenario: scenarioPath,
+		Revision: base.RevID,
+		Table:    entity.Table,
+		Row:      match.String(),
+		Header:   entity.Header,
+		Values:   entity.Row,
+		Parents:  entity.Parents,
+		Children: entity.Children,
+	}, nil
+}
@@ -5,9 +5,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/KazanKK/seedmancer/internal/utils"
 
 	"github.com/urfave/cli/v2"
 )
@@ -44,7 +47,55 @@ func ExportCommand() *cli.Command {
 			"Each export creates a new revision (r001, r002, ...) under\n" +
 			"<storagePath>/scenarios/<scenario>/revisions/. Previous revisions\n" +
 			"are never overwritten; the scenario's `latest` pointer always\n" +
-			"points to the most recent export.",
+			"points to the most recent export.\n\n" +
+			"Credentials that can't SELECT every table make the export fail;\n" +
+			"--skip-unreadable skips those tables with a warning instead and\n" +
+			"records them in the revision manifest, so seed knows the revision\n" +
+			"is partial and says which tables it has no data for.\n\n" +
+			"--tables exports the rows of the listed tables only (the schema is\n" +
+			"always whole). --interactive lists the database's tables with\n" +
+			"checkboxes to pick from instead, then prints the --tables command\n" +
+			"that makes the same choice in a script.\n\n" +
+			"With --output-dir the dump is written to that directory instead, as\n" +
+			"schema.json, function/trigger .sql files and one <table>.csv per\n" +
+			"table (the layout `validate --dir` reads); --schema-only leaves the\n" +
+			"CSVs out. No scenario is needed, and with --db-url no seedmancer.yaml\n" +
+			"either:\n\n" +
+			"  seedmancer export --db-url postgres://localhost/app --output-dir ./out\n" +
+			"  seedmancer export --db-url postgres://localhost/app --output-dir ./schema --schema-only\n\n" +
+			"--filter keeps only the rows of a table matching an SQL predicate, so a\n" +
+			"snapshot can hold recent or relevant rows only. It is repeatable, one\n" +
+			"table each, and recorded in the revision manifest. Foreign keys are not\n" +
+			"followed: filter child tables too, or their rows will point at parents\n" +
+			"left out:\n\n" +
+			"  seedmancer export recent --filter \"users: created_at > now() - interval '30 days'\" \\\n" +
+			"      --filter \"orders: user_id IN (SELECT id FROM users WHERE created_at > now() - interval '30 days')\"\n\n" +
+			"Columns listed under exclude_columns in seedmancer.yaml (large blobs,\n" +
+			"secrets) are left out of the CSVs and marked excluded in schema.json.\n" +
+			"Seed leaves them to the database default or NULL, and makes up values\n" +
+			"for NOT NULL ones without a default:\n\n" +
+			"  exclude_columns: [users.password_hash, documents.body, api_token]\n\n" +
+			"Tables with more rows than csv_shard_rows in seedmancer.yaml are\n" +
+			"written as ordered shards (orders.csv.001, orders.csv.002, …), each\n" +
+			"with the header; the revision manifest lists them in load order and\n" +
+			"seed joins them back into one table:\n\n" +
+			"  csv_shard_rows: 1000000\n\n" +
+			"While tables are read, a status line shows the tables done, rows so\n" +
+			"far, rows/s and an ETA (from the planner's row estimates) for the\n" +
+			"table in flight and the whole export. Off a terminal, as in CI, the\n" +
+			"same status is logged every 10s instead. The global --progress-json\n" +
+			"emits it as line-delimited JSON events on stderr for wrappers.\n\n" +
+			"Against production, --read-only has the server itself refuse writes:\n" +
+			"the connection opens with default_transaction_read_only on\n" +
+			"(PostgreSQL, CockroachDB) or transaction_read_only (MySQL, MariaDB),\n" +
+			"and the export stops before reading anything if the session turns\n" +
+			"out not to be read-only, as behind a pooler dropping startup options.\n\n" +
+			"With --watch the export repeats every --interval (default 1h) until\n" +
+			"interrupted. A run whose schema and data match the latest revision\n" +
+			"adds none, so revisions only pile up when something changed; --push\n" +
+			"uploads each new revision (and the latest one, if the cloud doesn't\n" +
+			"have it yet). A failed run is reported and retried at the next tick:\n\n" +
+			"  seedmancer export staging/nightly --env staging --watch --interval 24h --push",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "env",
@@ -59,19 +110,106 @@ func ExportCommand() *cli.Command {
 				Name:  "description",
 				Usage: "Optional human-readable description stored in the revision manifest",
 			},
+			&cli.StringFlag{
+				Name:  "output-dir",
+				Usage: "Write schema.json and table CSVs to this empty directory instead of a scenario revision",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-unreadable",
+				Usage: "Skip tables the credentials can't SELECT from instead of failing; the revision is marked partial",
+			},
+			&cli.BoolFlag{
+				Name:  "schema-only",
+				Usage: "With --output-dir: write the schema files only, no data",
+			},
+			&cli.StringFlag{
+				Name:  "tables",
+				Usage: "Comma-separated tables whose rows to export (default: every table)",
+			},
+			&cli.BoolFlag{
+				Name:  "interactive",
+				Usage: "Pick the tables to export from a checkbox list, then print the equivalent --tables",
+			},
+			&cli.GenericFlag{
+				Name:  "filter",
+				Value: &rawValues{},
+				Usage: "table: predicate — export only the table's rows matching the SQL predicate (repeatable)",
+			},
+			&cli.BoolFlag{
+				Name:  "read-only",
+				Usage: "Connect read-only (default_transaction_read_only on PostgreSQL) and refuse to export if the session isn't",
+			},
+			&cli.BoolFlag{
+				Name:  "watch",
+				Usage: "Keep exporting every --interval, adding a revision only when something changed",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Value: time.Hour,
+				Usage: "With --watch: time between exports",
+			},
+			&cli.BoolFlag{
+				Name:  "push",
+				Usage: "With --watch: upload each new revision to the cloud",
+			},
+			&cli.StringFlag{
+				Name:  "token",
+				Usage: "With --push: API token (falls back to SEEDMANCER_API_TOKEN env var, then ~/.seedmancer/credentials)",
+			},
 		},
 		Action: func(c *cli.Context) error {
+			if !c.Bool("watch") {
+				for _, name := range []string{"interval", "push", "token"} {
+					if c.IsSet(name) {
+						return usageError(c, "--%s only applies with --watch", name)
+					}
+				}
+			}
+			filters, err := parseExportFilters(*c.Generic("filter").(*rawValues))
+			if err != nil {
+				return usageError(c, "--filter: %v", err)
+			}
+			tables := splitCSVList(c.String("tables"))
+			if c.Bool("interactive") {
+				if c.Bool("watch") {
+					return usageError(c, "--interactive can't be combined with --watch")
+				}
+				all, err := liveTables(c.String("env"), c.String("db-url"))
+				if err != nil {
+					return err
+				}
+				if tables, err = pickTables("export", all, tables); err != nil {
+					return err
+				}
+			}
+			if c.IsSet("output-dir") {
+				if c.Bool("watch") {
+					return usageError(c, "--watch exports into a scenario and can't be combined with --output-dir")
+				}
+				return exportDirAction(c, filters, tables)
+			}
+			if c.IsSet("schema-only") {
+				return usageError(c, "--schema-only only applies with --output-dir")
+			}
 			scenarioArg := strings.TrimSpace(c.Args().First())
 			if scenarioArg == "" {
-				return usageError(c, "missing required argument: <scenario>")
+				return usageError(c, "missing required argument: <scenario> (or --output-dir <path>)")
 			}
 
-			out, err := RunExport(c.Context, ExportInput{
-				Scenario:    scenarioArg,
-				Env:         c.String("env"),
-				DBURL:       c.String("db-url"),
-				Description: c.String("description"),
-			})
+			in := ExportInput{
+				Scenario:       scenarioArg,
+				Env:            c.String("env"),
+				DBURL:          c.String("db-url"),
+				Description:    c.String("description"),
+				SkipUnreadable: c.Bool("skip-unreadable"),
+				Filters:        filters,
+				Tables:         tables,
+				ReadOnly:       c.Bool("read-only"),
+			}
+			if c.Bool("watch") {
+				return exportWatch(c, in)
+			}
+			out, err := RunExport(c.Context, in)
 			if err != nil {
 				return err
 			}
@@ -87,35 +225,203 @@ func ExportCommand() *cli.Command {
 				}
 				ui.KeyValue("Tables: ", strings.Join(parts, ", "))
 			}
+			filtered := make([]string, 0, len(filters))
+			for table, pred := range filters {
+				filtered = append(filtered, table+": "+pred)
+			}
+			sort.Strings(filtered)
+			for _, f := range filtered {
+				ui.KeyValue("Filtered: ", f)
+			}
+			if len(out.Excluded) > 0 {
+				ui.KeyValue("Excluded columns: ", strings.Join(out.Excluded, ", "))
+			}
 			ui.KeyValue("Latest now points to: ", out.Revision)
+			if len(out.RemovedSidecars) > 0 {
+				ui.Info("Removed stale schema sidecar(s): %s", strings.Join(out.RemovedSidecars, ", "))
+			}
+			if len(out.DroppedTables) > 0 {
+				ui.Warn("Not carried over from %s (no longer in the database): %s", out.PreviousRevision, strings.Join(out.DroppedTables, ", "))
+			}
+			if len(out.EmptyNewTables) > 0 {
+				ui.Warn("New table(s) since %s exported without data: %s", out.PreviousRevision, strings.Join(out.EmptyNewTables, ", "))
+			}
+			if len(out.SkippedTables) > 0 {
+				ui.Warn("%s is partial — skipped unreadable table(s): %s", out.Revision, strings.Join(out.SkippedTables, ", "))
+			}
 			return nil
 		},
 	}
 }
 
+// exportDirAction is export --output-dir: dump into a plain directory
+// rather than a scenario revision.
+func exportDirAction(c *cli.Context, filters map[string]string, tables []string) error {
+	if c.Args().Present() {
+		return usageError(c, "--output-dir writes a plain dump and takes no <scenario>")
+	}
+	if c.IsSet("description") {
+		return usageError(c, "--description can't be combined with --output-dir")
+	}
+	outDir := strings.TrimSpace(c.String("output-dir"))
+	if outDir == "" {
+		return usageError(c, "--output-dir needs a path")
+	}
+	if len(filters) > 0 && c.Bool("schema-only") {
+		return usageError(c, "--filter picks rows and can't be combined with --schema-only")
+	}
+	if len(tables) > 0 && c.Bool("schema-only") {
+		return usageError(c, "--tables picks rows and can't be combined with --schema-only")
+	}
+	out, err := RunExport(c.Context, ExportInput{
+		Env:            c.String("env"),
+		DBURL:          c.String("db-url"),
+		OutputDir:      outDir,
+		SchemaOnly:     c.Bool("schema-only"),
+		SkipUnreadable: c.Bool("skip-unreadable"),
+		Filters:        filters,
+		Tables:         tables,
+		ReadOnly:       c.Bool("read-only"),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	ui.Success("Exported to %s", out.Path)
+	ui.KeyValue("Schema fingerprint: ", out.SchemaShort)
+	if len(out.Tables) > 0 {
+		parts := make([]string, 0, len(out.Tables))
+		for _, t := range out.Tables {
+			parts = append(parts, fmt.Sprintf("%s(%d)", t, out.RowCounts[t]))
+		}
+		ui.KeyValue("Tables: ", strings.Join(parts, ", "))
+		ui.KeyValue("Check with: ", "seedmancer validate --dir "+out.Path)
+	}
+	if len(out.Excluded) > 0 {
+		ui.KeyValue("Excluded columns: ", strings.Join(out.Excluded, ", "))
+	}
+	if len(out.SkippedTables) > 0 {
+		ui.Warn("Skipped unreadable table(s): %s", strings.Join(out.SkippedTables, ", "))
+	}
+	return nil
+}
+
+// rawValues collects every value of a repeatable flag as given. Unlike
+// cli.StringSliceFlag it doesn't split values at commas, which SQL needs.
+type rawValues []string
+
+func (v *rawValues) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
+
+func (v *rawValues) String() string { return strings.Join(*v, "; ") }
+
+// parseExportFilters turns --filter values of the form "table: predicate"
+// into a table → predicate map. The table ends at the first colon, so
+// the predicate may hold casts like '30 days'::interval.
+func parseExportFilters(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	filters := make(map[string]string, len(values))
+	for _, v := range values {
+		table, pred, ok := strings.Cut(v, ":")
+		table, pred = strings.TrimSpace(table), strings.TrimSpace(pred)
+		if !ok || table == "" || pred == "" {
+			return nil, fmt.Errorf("%q: want \"table: predicate\"", v)
+		}
+		if _, dup := filters[table]; dup {
+			return nil, fmt.Errorf("%s is filtered twice; join the predicates with AND", table)
+		}
+		filters[table] = pred
+	}
+	return filters, nil
+}
+
+// checkStaticFilters rejects filters on tables marked `static: true`:
+// reference tables are exported in full, so a restore never reloads a
+// partial copy of one.
+func checkStaticFilters(cfg utils.Config, filters map[string]string) error {
+	for _, table := range cfg.StaticTables() {
+		if _, ok := filters[table]; ok {
+			return fmt.Errorf("%s is a static table (tables: %s: static: true) and is always exported in full; drop its filter", table, table)
+		}
+	}
+	return nil
+}
+
 // refreshSchemaFolder copies schema.json (plus any *_func.sql / *_trigger.sql
 // sidecars) from the temp dump into the canonical schema folder. Existing
-// files are overwritten so a fresh export always wins over stale sidecars.
-func refreshSchemaFolder(src, dst string) error {
+// files are overwritten so a fresh export always wins over stale sidecars,
+// and sidecars the dump no longer has — functions or triggers dropped since
+// the folder was last written — are removed so seeds stop restoring them.
+// The removed file names are returned.
+func refreshSchemaFolder(src, dst string) ([]string, error) {
 	entries, err := os.ReadDir(src)
 	if err != nil {
-		return fmt.Errorf("reading temp schema dir: %v", err)
+		return nil, fmt.Errorf("reading temp schema dir: %v", err)
 	}
+	fresh := map[string]bool{}
 	for _, e := range entries {
-		if e.IsDir() {
+		if e.IsDir() || !isSchemaFile(e.Name()) {
 			continue
 		}
 		name := e.Name()
-		if name != "schema.json" &&
-			!strings.HasSuffix(name, "_func.sql") &&
-			!strings.HasSuffix(name, "_trigger.sql") {
+		fresh[name] = true
+		if err := copyFile(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+			return nil, fmt.Errorf("copying %s: %v", name, err)
+		}
+	}
+
+	existing, err := os.ReadDir(dst)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema dir: %v", err)
+	}
+	var removed []string
+	for _, e := range existing {
+		name := e.Name()
+		if e.IsDir() || fresh[name] || !isSchemaFile(name) {
 			continue
 		}
-		if err := copyFile(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
-			return fmt.Errorf("copying %s: %v", name, err)
+		if err := os.Remove(filepath.Join(dst, name)); err != nil {
+			return removed, fmt.Errorf("removing stale %s: %v", name, err)
 		}
+		removed = append(removed, name)
 	}
-	return nil
+	return removed, nil
+}
+
+func isSchemaFile(name string) bool {
+	return name == "schema.json" ||
+		strings.HasSuffix(name, "_func.sql") ||
+		strings.HasSuffix(name, "_trigger.sql")
+}
+
+// reconcileTables compares a fresh export with the scenario's previous
+// latest revision. dropped lists tables the previous revision had that the
+// database no longer does (they are not carried over); emptyNew lists
+// tables new since then that exported without rows, which usually means
+// the fixture needs data for them.
+func reconcileTables(previous, tables []string, rowCounts map[string]int) (dropped, emptyNew []string) {
+	prev := map[string]bool{}
+	for _, t := range previous {
+		prev[t] = true
+	}
+	now := map[string]bool{}
+	for _, t := range tables {
+		now[t] = true
+		if !prev[t] && rowCounts[t] == 0 {
+			emptyNew = append(emptyNew, t)
+		}
+	}
+	for _, t := range previous {
+		if !now[t] {
+			dropped = append(dropped, t)
+		}
+	}
+	return dropped, emptyNew
 }
 
 func copyFile(src, dst string) error {
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRefreshSchemaFolder_removesStaleSidecars(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	for _, name := range []string{"schema.json", "touch_updated_at_func.sql"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte("new"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"schema.json", "legacy_func.sql", "users_audit_trigger.sql", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dst, name), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := refreshSchemaFolder(src, dst)
+	if err != nil {
+		t.Fatalf("refreshSchemaFolder: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"legacy_func.sql", "users_audit_trigger.sql"}) {
+		t.Errorf("removed = %q", removed)
+	}
+	entries, _ := os.ReadDir(dst)
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	if !reflect.DeepEqual(left, []string{"notes.txt", "schema.json", "touch_updated_at_func.sql"}) {
+		t.Errorf("left = %q", left)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "schema.json")); string(data) != "new" {
+		t.Errorf("schema.json not overwritten: %q", data)
+	}
+}
+
+func TestReconcileTables(t *testing.T) {
+	dropped, emptyNew := reconcileTables(
+		[]string{"users", "legacy_events", "orders"},
+		[]string{"users", "orders", "invoices", "coupons"},
+		map[string]int{"users": 3, "orders": 0, "invoices": 0, "coupons": 2},
+	)
+	if !reflect.DeepEqual(dropped, []string{"legacy_events"}) {
+		t.Errorf("dropped = %q", dropped)
+	}
+	if !reflect.DeepEqual(emptyNew, []string{"invoices"}) {
+		t.Errorf("emptyNew = %q", emptyNew)
+	}
+}
+
+func TestLoadConfigOrAdHoc_withoutConfig(t *testing.T) {
+	dir := t.TempDir()
+	prev, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Setenv("HOME", dir)
+	t.Setenv("SEEDMANCER_DATABASE_URL", "")
+
+	path, cfg, err := loadConfigOrAdHoc("postgres://localhost/app")
+	if err != nil || path != "" || len(cfg.EffectiveEnvs()) != 0 {
+		t.Fatalf("with a URL: got %q, %+v, %v", path, cfg, err)
+	}
+	if _, _, err := loadConfigOrAdHoc(""); err == nil || !strings.Contains(err.Error(), "--db-url") {
+		t.Fatalf("without a URL: want a hint at --db-url, got %v", err)
+	}
+	t.Setenv("SEEDMANCER_DATABASE_URL", "postgres://localhost/app")
+	if _, _, err := loadConfigOrAdHoc(""); err != nil {
+		t.Fatalf("with SEEDMANCER_DATABASE_URL: %v", err)
+	}
+}
+
+func TestRunExport_outputDir(t *testing.T) {
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "users.csv"), []byte("id\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := RunExport(context.Background(), ExportInput{DBURL: "postgres://localhost/app", OutputDir: out})
+	if err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Fatalf("non-empty output dir: got %v", err)
+	}
+	_, err = RunExport(context.Background(), ExportInput{Scenario: "basic", SchemaOnly: true})
+	if err == nil || !strings.Contains(err.Error(), "outputDir") {
+		t.Fatalf("schemaOnly without outputDir: got %v", err)
+	}
+}
+
+func TestWatchLoop(t *testing.T) {
+	if err := watchLoop(context.Background(), time.Millisecond, func(context.Context) error {
+		return errors.New("no such env")
+	}); err == nil || err.Error() != "no such env" {
+		t.Fatalf("first cycle error: got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runs := 0
+	err := watchLoop(ctx, time.Millisecond, func(context.Context) error {
+		runs++
+		if runs == 3 {
+			cancel()
+		}
+		if runs == 2 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil || runs != 3 {
+		t.Fatalf("got %v after %d run(s); want nil after 3", err, runs)
+	}
+}
+
+func TestParseExportFilters(t *testing.T) {
+	got, err := parseExportFilters([]string{
+		"users: created_at > now() - '30 days'::interval",
+		" orders :status IN ('paid', 'shipped')",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"users":  "created_at > now() - '30 days'::interval",
+		"orders": "status IN ('paid', 'shipped')",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, bad := range [][]string{{"users"}, {": id > 1"}, {"users:"}, {"users: a", "users: b"}} {
+		if _, err := parseExportFilters(bad); err == nil {
+			t.Errorf("%q: want an error", bad)
+		}
+	}
+}
+
+func TestRunExport_rejectsFilterOnStaticTable(t *testing.T) {
+	dir := stageRevision(t, "basic", `{"tables":[{"name":"countries","columns":[{"name":"code","type":"text"}]}]}`, nil)
+	writeFile(t, filepath.Join(dir, "seedmancer.yaml"), "storage_path: .seedmancer\ntables:\n  countries:\n    static: true\n")
+
+	_, err := RunExport(context.Background(), ExportInput{
+		Scenario: "basic",
+		DBURL:    "postgres://localhost/app",
+		Filters:  map[string]string{"countries": "code = 'DE'"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "countries is a static table") {
+		t.Fatalf("err = %v, want the static table's filter rejected", err)
+	}
+}
@@ -2,6 +2,10 @@ package cmd
 
 import (
 	"archive/zip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +16,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+	"github.com/KazanKK/seedmancer/internal/mask"
 	"github.com/KazanKK/seedmancer/internal/scenario"
 	"github.com/KazanKK/seedmancer/internal/ui"
 	utils "github.com/KazanKK/seedmancer/internal/utils"
@@ -58,7 +64,22 @@ func PullCommand() *cli.Command {
 			"cloud and writes it as a new local revision. Pointers.latest advances\n" +
 			"so `seedmancer seed <scenario>` picks it up immediately.\n\n" +
 			"When called without arguments, every locally-known scenario is pulled.\n" +
-			"Scenarios whose local latest already matches the cloud are skipped.",
+			"Scenarios whose local latest already matches the cloud are skipped.\n\n" +
+			"Downloads show a progress bar, are retried with backoff when the\n" +
+			"connection drops, and continue from where they stopped rather than\n" +
+			"from zero — also across runs, so rerunning a failed pull resumes it.\n\n" +
+			"Pulls are incremental: when the scenario has a local revision and the\n" +
+			"archive's storage serves byte ranges, only the files that differ from\n" +
+			"it (by SHA-256) are downloaded; the rest are copied from disk.\n\n" +
+			"Masking: when seedmancer.yaml has pull_masking, every pull on a\n" +
+			"machine where CI isn't set rewrites the listed columns as the\n" +
+			"archive is unpacked, so their raw values never reach the revision:\n\n" +
+			"  pull_masking:\n" +
+			"    users.full_name: name     # one column of one table\n" +
+			"    email: email              # a column of that name in every table\n\n" +
+			"Strategies are hash, email, name, phone and null. Equal values mask\n" +
+			"alike within a pull, so unique columns stay unique. Keys can't be\n" +
+			"masked, and the downloaded archive is deleted once it is unpacked.",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:  "token",
@@ -139,8 +160,14 @@ func PullCommand() *cli.Command {
 			}
 			ui.Success("Pulled %s @ %s (%s in %s)",
 				out.Scenario, out.Revision, formatBytes(out.BytesDownloaded), formatDuration(elapsed))
+			if out.Reused > 0 {
+				ui.KeyValue("Unchanged: ", fmt.Sprintf("%d file(s) reused from the previous revision", out.Reused))
+			}
 			ui.KeyValue("Schema: ", out.SchemaShort)
 			ui.KeyValue("Files: ", fmt.Sprintf("%d", len(out.Files)))
+			if len(out.Masked) > 0 {
+				ui.KeyValue("Masked: ", strings.Join(out.Masked, ", "))
+			}
 			ui.KeyValue("Path: ", out.Path)
 			return nil
 		},
@@ -333,38 +360,54 @@ func listRemoteDatasets(baseURL, token string) (map[string]datasetAPI, error) {
 	return out, nil
 }
 
-func getDownloadURL(baseURL, token, datasetID string) (string, error) {
-	reqURL := fmt.Sprintf("%s/v1.0/datasets/%s/download", baseURL, datasetID)
-	ui.Debug("GET %s", reqURL)
-
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("creating request: %v", err)
+// downloadDatasetArchive downloads datasetID's zip archive to path. The
+// transfer is retried and resumed as resumableDownload describes, with
+// partial files kept beside path, and drawn as a progress bar.
+func downloadDatasetArchive(ctx context.Context, baseURL, token string, ds datasetAPI, path, label string) (int64, error) {
+	reqURL := fmt.Sprintf("%s/v1.0/datasets/%s/download", baseURL, ds.ID)
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		ui.Debug("GET %s", reqURL)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %v", err)
+		}
+		req.Header.Set("Authorization", utils.BearerAPIToken(token))
+		utils.ApplyProjectHeader(req, "")
+		return req, nil
 	}
-	req.Header.Set("Authorization", utils.BearerAPIToken(token))
-	utils.ApplyProjectHeader(req, "")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("making request: %v", err)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("creating download dir: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("download request failed (HTTP %d): %s", resp.StatusCode, string(body))
+	progress := ui.StartProgress(ui.PhaseDownload, label)
+	n, err := resumableDownload(ctx, http.DefaultClient, newRequest, path, downloadOptions{
+		Version:  ds.ID + "@" + ds.UpdatedAt,
+		Progress: progress.Update,
+		OnRetry: func(attempt int, wait time.Duration, err error) {
+			progress.Clear()
+			ui.Warn("%v — retrying in %s", err, formatDuration(wait))
+		},
+	})
+	if err != nil {
+		progress.Stop(false, "Download failed")
+		return n, err
 	}
+	progress.Stop(true, fmt.Sprintf("Downloaded %s", formatBytes(n)))
+	return n, nil
+}
 
-	var result struct {
-		URL string `json:"url"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("parsing download response: %v", err)
+// datasetDownloadPath is where the archive of ds is downloaded to. It
+// lives under ~/.seedmancer so an interrupted pull can be resumed from
+// any checkout, or under --tmp-dir / tmp_dir when one is set.
+func datasetDownloadPath(ds datasetAPI) (string, error) {
+	if dir := utils.TempDirOverride(); dir != "" {
+		return filepath.Join(dir, "seedmancer-downloads", ds.ID+".zip"), nil
 	}
-	if result.URL == "" {
-		return "", fmt.Errorf("server returned empty download URL")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding home directory: %v", err)
 	}
-	return result.URL, nil
+	return filepath.Join(home, ".seedmancer", "downloads", ds.ID+".zip"), nil
 }
 
 // liftSchemaSidecars moves schema-level files (schema.json plus any
@@ -405,72 +448,195 @@ func liftSchemaSidecars(dataDir, schemaDir string) (int, error) {
 	return moved, nil
 }
 
-func downloadAndExtractZip(downloadURL, outputDir string) ([]string, int64, error) {
-	ui.Debug("Downloading zip...")
-
-	resp, err := http.Get(downloadURL)
+// verifyExtractedChecksums checks a freshly-extracted archive against the
+// checksums.sha256 push bundled into it, then removes the listing so it
+// never lands in the revision. Archives pushed by older CLIs carry no
+// listing and are accepted as-is. raw, when not nil, holds the files'
+// checksums as they were in the archive, for files that were masked on
+// the way to disk; otherwise the files in dataDir are hashed.
+func verifyExtractedChecksums(dataDir string, raw map[string]string) error {
+	path := filepath.Join(dataDir, scenario.ChecksumsFileName)
+	sums, err := scenario.ReadChecksumsFile(path)
+	if os.IsNotExist(err) {
+		ui.Debug("archive has no %s; skipping verification", scenario.ChecksumsFileName)
+		return nil
+	}
 	if err != nil {
-		return nil, 0, fmt.Errorf("downloading zip: %v", err)
+		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, 0, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	if err := os.Remove(path); err != nil {
+		return err
 	}
+	if raw != nil {
+		delete(raw, scenario.ChecksumsFileName)
+		return scenario.CompareChecksums(raw, sums)
+	}
+	return scenario.VerifyChecksums(dataDir, sums)
+}
 
-	tmpFile, err := os.CreateTemp("", "seedmancer-*.zip")
+// takePushedManifest removes the manifest.json push bundled with the
+// revision from dataDir and returns it, or nil for an archive pushed
+// without one.
+func takePushedManifest(dataDir string) (*scenario.RevisionManifest, error) {
+	m, err := scenario.ReadRevisionManifest(dataDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, 0, fmt.Errorf("creating temporary file: %v", err)
+		return nil, err
+	}
+	if err := os.Remove(scenario.RevisionManifestPath(dataDir)); err != nil {
+		return nil, err
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
+	return &m, nil
+}
 
-	downloadedBytes, err := io.Copy(tmpFile, resp.Body)
-	if err != nil {
-		return nil, 0, fmt.Errorf("saving zip file: %v", err)
+// adoptPushedManifest checks the data m was stamped from against the
+// checksums pushed recorded, then carries over what the pushed manifest
+// says about that data. Masked data no longer matches by design and is
+// not checked. pushed may be nil.
+func adoptPushedManifest(m *scenario.RevisionManifest, pushed *scenario.RevisionManifest) error {
+	if pushed == nil {
+		return nil
+	}
+	if len(pushed.Files) > 0 && len(m.Masked) == 0 {
+		if err := scenario.CompareChecksums(m.Files, pushed.Files); err != nil {
+			return fmt.Errorf("data doesn't match the pushed manifest: %w", err)
+		}
+	}
+	m.Description = pushed.Description
+	m.SkippedTables = pushed.SkippedTables
+	m.Filters = pushed.Filters
+	m.Excluded = pushed.Excluded
+	m.Shards = pushed.Shards
+	return nil
+}
+
+// removeName returns names without any entry equal to name.
+func removeName(names []string, name string) []string {
+	out := names[:0]
+	for _, n := range names {
+		if n != name {
+			out = append(out, n)
+		}
 	}
+	return out
+}
 
-	zipReader, err := zip.OpenReader(tmpFile.Name())
+// extractZip writes the files of the zip at zipPath flat into outputDir
+// and returns their names.
+func extractZip(zipPath, outputDir string) ([]string, error) {
+	zipReader, err := zip.OpenReader(zipPath)
 	if err != nil {
-		return nil, 0, fmt.Errorf("opening zip file: %v", err)
+		return nil, fmt.Errorf("opening zip file: %v", err)
 	}
 	defer zipReader.Close()
+	extracted, _, err := extractZipReader(&zipReader.Reader, outputDir, nil, nil)
+	return extracted, err
+}
 
+// extractedSize is the total size of zr's files once extracted.
+func extractedSize(zr *zip.Reader) int64 {
+	var n int64
+	for _, f := range zr.File {
+		n += int64(f.UncompressedSize64)
+	}
+	return n
+}
+
+// extractZipReader is extractZip for an open archive. The CSVs of tables
+// masker masks pass through it on the way to disk, so their raw rows are
+// never written. Files reuse names (see reusableFiles) are copied from
+// disk rather than read from the archive. It also returns the SHA-256 of
+// every file as it was in the archive, for verifyExtractedChecksums.
+func extractZipReader(zipReader *zip.Reader, outputDir string, masker *mask.Masker, reuse map[string]string) ([]string, map[string]string, error) {
 	var extracted []string
+	sums := map[string]string{}
 	for _, file := range zipReader.File {
 		if file.FileInfo().IsDir() {
 			continue
 		}
+		name := filepath.Base(file.Name)
 
-		rc, err := file.Open()
+		rc, err := openEntry(file, reuse)
 		if err != nil {
-			return nil, 0, fmt.Errorf("opening file in zip: %v", err)
+			return nil, nil, fmt.Errorf("opening file in zip: %v", err)
 		}
 
-		destPath := filepath.Join(outputDir, filepath.Base(file.Name))
+		destPath := filepath.Join(outputDir, name)
 		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 			rc.Close()
-			return nil, 0, fmt.Errorf("creating directories: %v", err)
+			return nil, nil, fmt.Errorf("creating directories: %v", err)
 		}
 
 		outFile, err := os.Create(destPath)
 		if err != nil {
 			rc.Close()
-			return nil, 0, fmt.Errorf("creating output file: %v", err)
+			return nil, nil, fmt.Errorf("creating output file: %v", err)
 		}
 
-		if _, err := io.Copy(outFile, rc); err != nil {
+		h := sha256.New()
+		src := io.TeeReader(rc, h)
+		table, isCSV := strings.CutSuffix(name, ".csv")
+		if t, _, ok := csvshard.Parse(name); ok {
+			// Every shard has the header, so it masks on its own.
+			table, isCSV = t, true
+		}
+		if masker != nil && isCSV && masker.Masks(table) {
+			_, err = masker.MaskCSV(table, src, outFile)
+		} else {
+			_, err = io.Copy(outFile, src)
+		}
+		if err != nil {
 			outFile.Close()
 			rc.Close()
-			return nil, 0, fmt.Errorf("extracting file: %v", err)
+			return nil, nil, fmt.Errorf("extracting file: %v", err)
 		}
 
 		outFile.Close()
 		rc.Close()
 
-		extracted = append(extracted, filepath.Base(file.Name))
-		ui.Debug("Extracted: %s", filepath.Base(file.Name))
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+		extracted = append(extracted, name)
+		ui.Debug("Extracted: %s", name)
 	}
 
-	return extracted, downloadedBytes, nil
+	return extracted, sums, nil
+}
+
+// onCI reports whether this process runs in CI, going by the CI
+// variable most CI services set.
+func onCI() bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("CI")))
+	return v != "" && v != "false" && v != "0"
+}
+
+// pullMasker returns the masker pull applies to the archive zr under
+// cfg's pull_masking, or nil when there is nothing to mask or the pull
+// runs in CI. Masked values are hashed with a key drawn for this pull
+// alone.
+func pullMasker(cfg utils.Config, zr *zip.Reader) (*mask.Masker, error) {
+	if len(cfg.PullMasking) == 0 || onCI() {
+		return nil, nil
+	}
+	var schema utils.SchemaJSON
+	for _, file := range zr.File {
+		if filepath.Base(file.Name) != "schema.json" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("opening file in zip: %v", err)
+		}
+		err = json.NewDecoder(rc).Decode(&schema)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing schema.json: %v", err)
+		}
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return mask.New(cfg.PullMasking, schema, key)
 }
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
@@ -13,6 +16,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/KazanKK/seedmancer/internal/scenario"
 	utils "github.com/KazanKK/seedmancer/internal/utils"
 )
 
@@ -208,14 +212,21 @@ func TestDownloadAndExtractZip(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	outDir := t.TempDir()
-	extracted, downloaded, err := downloadAndExtractZip(srv.URL, outDir)
+	zipPath := filepath.Join(t.TempDir(), "archive.zip")
+	downloaded, err := resumableDownload(t.Context(), srv.Client(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	}, zipPath, downloadOptions{})
 	if err != nil {
-		t.Fatalf("downloadAndExtractZip: %v", err)
+		t.Fatalf("resumableDownload: %v", err)
 	}
 	if downloaded != int64(len(buf)) {
 		t.Fatalf("downloaded bytes = %d, want %d", downloaded, len(buf))
 	}
+	outDir := t.TempDir()
+	extracted, err := extractZip(zipPath, outDir)
+	if err != nil {
+		t.Fatalf("extractZip: %v", err)
+	}
 	sort.Strings(extracted)
 	want := []string{"schema.json", "users.csv"}
 	if len(extracted) != len(want) {
@@ -256,3 +267,269 @@ func (bb *buffer) Write(p []byte) (int, error) {
 	bb.b = append(bb.b, p...)
 	return len(p), nil
 }
+
+// TestRunFetch_rejectsArchiveFailingChecksums serves an archive whose
+// bundled checksums.sha256 doesn't match a truncated CSV and expects the
+// pull to fail without leaving a revision behind.
+func TestRunFetch_rejectsArchiveFailingChecksums(t *testing.T) {
+	dir := t.TempDir()
+	prev, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Setenv("HOME", dir)
+	writeFile(t, filepath.Join(dir, "seedmancer.yaml"), "storage_path: .seedmancer\n")
+
+	zipBytes, err := compressTestZip(map[string]string{
+		"users.csv": "id\n1\n",
+		// Hash of "id\n1\n2\n" — the CSV above lost its last row.
+		"checksums.sha256": "8e3c3e2c0e8d5ac3c3fda0b1a2e4e2d3f3f4a0c1b2c3d4e5f60718293a4b5c6d  users.csv\n",
+	})
+	if err != nil {
+		t.Fatalf("build zip: %v", err)
+	}
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.0/datasets":
+			_ = json.NewEncoder(w).Encode(datasetListResponse{Datasets: []datasetAPI{{
+				ID:     "rev_1",
+				Name:   "bench/x",
+				Schema: &schemaRefShort{ID: "s1", Fingerprint: "abc", FingerprintShort: "abc"},
+			}}})
+		case "/v1.0/datasets/rev_1/download":
+			_ = json.NewEncoder(w).Encode(map[string]string{"url": srvURL + "/blob.zip"})
+		case "/blob.zip":
+			_, _ = w.Write(zipBytes)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	t.Setenv("SEEDMANCER_API_URL", srv.URL)
+
+	_, err = RunFetch(t.Context(), FetchInput{Scenario: "bench/x", Token: "tok"})
+	if err == nil || !strings.Contains(err.Error(), "modified: users.csv") {
+		t.Fatalf("RunFetch err = %v, want checksum mismatch naming users.csv", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, ".seedmancer", "scenarios", "bench", "x", "revisions", "r001")); !os.IsNotExist(statErr) {
+		t.Fatalf("failed pull left r001 behind (stat err=%v)", statErr)
+	}
+}
+
+// TestRunFetch_checksPushedManifest serves archives carrying the
+// manifest.json push bundles: one whose checksums match the data, whose
+// description the pulled revision keeps, and one whose don't.
+func TestRunFetch_checksPushedManifest(t *testing.T) {
+	dir := t.TempDir()
+	prev, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Setenv("HOME", dir)
+	writeFile(t, filepath.Join(dir, "seedmancer.yaml"), "storage_path: .seedmancer\n")
+
+	users := "id\n1\n"
+	sum := sha256.Sum256([]byte(users))
+	var zipBytes []byte
+	archive := func(usersSum string) {
+		t.Helper()
+		manifest, _ := json.Marshal(scenario.RevisionManifest{
+			Description: "nightly",
+			Files:       map[string]string{"users.csv": usersSum},
+		})
+		var err error
+		if zipBytes, err = compressTestZip(map[string]string{"users.csv": users, "manifest.json": string(manifest)}); err != nil {
+			t.Fatalf("build zip: %v", err)
+		}
+	}
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.0/datasets":
+			_ = json.NewEncoder(w).Encode(datasetListResponse{Datasets: []datasetAPI{{
+				ID:     "rev_1",
+				Name:   "bench/x",
+				Schema: &schemaRefShort{ID: "s1", Fingerprint: "abc", FingerprintShort: "abc"},
+			}}})
+		case "/v1.0/datasets/rev_1/download":
+			_ = json.NewEncoder(w).Encode(map[string]string{"url": srvURL + "/blob.zip"})
+		case "/blob.zip":
+			_, _ = w.Write(zipBytes)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	t.Setenv("SEEDMANCER_API_URL", srv.URL)
+
+	archive(hex.EncodeToString(sum[:]))
+	out, err := RunFetch(t.Context(), FetchInput{Scenario: "bench/x", Token: "tok"})
+	if err != nil {
+		t.Fatalf("RunFetch: %v", err)
+	}
+	if fileExists(filepath.Join(out.Path, "manifest.json")) {
+		t.Error("the pushed manifest.json was left among the data files")
+	}
+	m, err := scenario.ReadRevisionManifest(filepath.Dir(out.Path))
+	if err != nil || m.Description != "nightly" || m.Source != "pull" {
+		t.Errorf("pulled manifest = %+v, %v; want the pushed description on a pull revision", m, err)
+	}
+
+	// A fresh project, so the pull starts from nothing again.
+	fresh := t.TempDir()
+	if err := os.Chdir(fresh); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	writeFile(t, filepath.Join(fresh, "seedmancer.yaml"), "storage_path: .seedmancer\n")
+	archive(strings.Repeat("0", 64))
+	if _, err := RunFetch(t.Context(), FetchInput{Scenario: "bench/x", Token: "tok"}); err == nil || !strings.Contains(err.Error(), "modified: users.csv") {
+		t.Fatalf("RunFetch err = %v, want a mismatch with the pushed manifest naming users.csv", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(fresh, ".seedmancer", "scenarios", "bench", "x", "revisions", "r001")); !os.IsNotExist(statErr) {
+		t.Fatalf("failed pull left r001 behind (stat err=%v)", statErr)
+	}
+}
+
+// TestRunFetch_masksUnderPullMasking checks that pull_masking rewrites
+// the listed columns before the revision is written, while the archive's
+// checksums still verify against the raw rows.
+func TestRunFetch_masksUnderPullMasking(t *testing.T) {
+	dir := t.TempDir()
+	prev, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Setenv("HOME", dir)
+	t.Setenv("CI", "")
+	writeFile(t, filepath.Join(dir, "seedmancer.yaml"), "storage_path: .seedmancer\npull_masking:\n  users.email: email\n")
+
+	files := map[string]string{
+		"schema.json": `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"email","type":"text"}]}]}`,
+		"users.csv":   "id,email\n1,ada@corp.io\n",
+	}
+	var sums strings.Builder
+	for _, name := range []string{"schema.json", "users.csv"} {
+		sum := sha256.Sum256([]byte(files[name]))
+		sums.WriteString(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	}
+	files["checksums.sha256"] = sums.String()
+	zipBytes, err := compressTestZip(files)
+	if err != nil {
+		t.Fatalf("build zip: %v", err)
+	}
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.0/datasets":
+			_ = json.NewEncoder(w).Encode(datasetListResponse{Datasets: []datasetAPI{{
+				ID:     "rev_1",
+				Name:   "bench/x",
+				Schema: &schemaRefShort{ID: "s1", Fingerprint: "abc", FingerprintShort: "abc"},
+			}}})
+		case "/v1.0/datasets/rev_1/download":
+			_ = json.NewEncoder(w).Encode(map[string]string{"url": srvURL + "/blob.zip"})
+		case "/blob.zip":
+			_, _ = w.Write(zipBytes)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	t.Setenv("SEEDMANCER_API_URL", srv.URL)
+
+	out, err := RunFetch(t.Context(), FetchInput{Scenario: "bench/x", Token: "tok"})
+	if err != nil {
+		t.Fatalf("RunFetch: %v", err)
+	}
+	if len(out.Masked) != 1 || out.Masked[0] != "users.email" {
+		t.Fatalf("Masked = %v, want [users.email]", out.Masked)
+	}
+	got, err := os.ReadFile(filepath.Join(out.Path, "users.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "ada@corp.io") || !strings.Contains(string(got), "@example.com") {
+		t.Fatalf("users.csv not masked:\n%s", got)
+	}
+
+	// CI pulls the rows as they are.
+	t.Setenv("CI", "true")
+	if m, err := pullMasker(utils.Config{PullMasking: map[string]string{"users.email": "email"}}, nil); m != nil || err != nil {
+		t.Fatalf("pullMasker on CI = %v, %v; want nil", m, err)
+	}
+}
+
+// TestRunSeed_remote seeds straight from the cloud in a directory with no
+// seedmancer.yaml, as a stateless CI runner would.
+func TestRunSeed_remote(t *testing.T) {
+	dir := t.TempDir()
+	prev, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPDIR", filepath.Join(dir, "tmp"))
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	zipBytes, err := compressTestZip(map[string]string{
+		"schema.json": `{"databaseType":"postgres","tables":[{"name":"users","columns":[{"name":"id","type":"integer"}]}]}`,
+		"users.csv":   "id\n1\n",
+	})
+	if err != nil {
+		t.Fatalf("build zip: %v", err)
+	}
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.0/datasets":
+			_ = json.NewEncoder(w).Encode(datasetListResponse{Datasets: []datasetAPI{{
+				ID:     "rev_1",
+				Name:   "bench/x",
+				Schema: &schemaRefShort{ID: "s1", Fingerprint: strings.Repeat("ab", 32), FingerprintShort: "abababababab"},
+			}}})
+		case "/v1.0/datasets/rev_1/download":
+			_ = json.NewEncoder(w).Encode(map[string]string{"url": srvURL + "/blob.zip"})
+		case "/blob.zip":
+			_, _ = w.Write(zipBytes)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	t.Setenv("SEEDMANCER_API_URL", srv.URL)
+
+	out, err := RunSeed(t.Context(), SeedInput{
+		Scenario: "bench/x",
+		Remote:   true,
+		Token:    "tok",
+		DBURL:    "postgres://u:p@127.0.0.1:1/none",
+		DryRun:   true,
+	})
+	if err != nil {
+		t.Fatalf("RunSeed: %v", err)
+	}
+	if out.Revision != "rev_1" || out.Schema != "abababababab" {
+		t.Fatalf("out = %+v, want rev_1 with schema abababababab", out)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(entries) != 0 {
+		t.Fatalf("remote seed left %d temp entries behind", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".seedmancer")); !os.IsNotExist(err) {
+		t.Fatalf("remote seed wrote into the project (stat err=%v)", err)
+	}
+
+	if _, err := RunSeed(t.Context(), SeedInput{Scenario: "bench/x", Remote: true, Revision: "r001", DryRun: true}); err == nil {
+		t.Fatal("remote with a revision: want an error")
+	}
+}
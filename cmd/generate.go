@@ -1,13 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/generators"
+	"github.com/KazanKK/seedmancer/internal/chaos"
+	"github.com/KazanKK/seedmancer/internal/rowtemplate"
 	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/KazanKK/seedmancer/internal/utils"
 
 	"github.com/urfave/cli/v2"
 )
@@ -39,6 +46,8 @@ type generateColumn struct {
 	ForeignKey    *generateForeignKey `json:"foreignKey,omitempty"`
 	Enum          string              `json:"enum,omitempty"`
 	AllowedValues []string            `json:"allowedValues,omitempty"`
+	// UniqueIgnoreCase asks for values that stay unique lower-cased.
+	UniqueIgnoreCase bool `json:"uniqueIgnoreCase,omitempty"`
 }
 
 type generateForeignKey struct {
@@ -66,6 +75,46 @@ func GenerateCommand() *cli.Command {
 			"  seedmancer generate qa/smoke --inherit baseline --prompt 'add two orders'\n\n" +
 			"Requires a Pro plan (https://seedmancer.dev/#pricing). Local generation\n" +
 			"through the MCP server stays free.\n\n" +
+			"Quick local data:\n" +
+			"  With --rows N no scenario is needed: made-up rows are inserted straight\n" +
+			"  into the database in batches, N per table, parents first so foreign\n" +
+			"  keys resolve. Nothing is written to disk and no API call is made. Values\n" +
+			"  follow column types, enums, CHECK lists and names (email, phone, ...),\n" +
+			"  and agree within a row: email matches first_name/last_name, city,\n" +
+			"  country and postal_code are one place, end_date is after start_date.\n" +
+			"  Every label of an enum or CHECK-list column gets a row before any\n" +
+			"  repeats, so rare statuses are covered; enum_weights in seedmancer.yaml\n" +
+			"  (keyed like generators) sets how the rest are spread, e.g.\n" +
+			"  orders.status: {paid: 80, pending: 15, refunded: 5}.\n" +
+			"  Foreign keys pick their parent uniformly; fk_picks (keyed the same\n" +
+			"  way) makes a column zipfian, so a few parents own most children\n" +
+			"  (orders.user_id: zipfian), or one-to-one, one child per parent.\n" +
+			"  --locale (en_US, en_GB, de_DE, fr_FR, es_ES, pt_BR, ja_JP, zh_CN) draws\n" +
+			"  names, addresses, phone numbers and text from that locale, non-ASCII\n" +
+			"  included; email addresses and usernames stay ASCII.\n" +
+			"  Columns listed under generators: in seedmancer.yaml (\"table.column\"\n" +
+			"  or a bare column name) take their values from a custom generator\n" +
+			"  instead, registered by a Go plugin listed under generator_plugins\n" +
+			"  (see the generators package). quickstart honours them too. Unique\n" +
+			"  columns that ignore case (citext, a _ci collation, a unique index on\n" +
+			"  lower(column)) never get two values differing only in case.\n" +
+			"  --edge-cases 5% gives that share of rows one edge-case value: NULL in\n" +
+			"  a nullable foreign key (an orphan), a string at its length limit, an\n" +
+			"  integer at its type's limits, an empty JSON object, an extreme date.\n" +
+			"  Key, unique, enum and custom columns are left alone. --clean does the\n" +
+			"  opposite: no random NULLs, so every nullable foreign key has a parent.\n\n" +
+			"  seedmancer generate --db-url postgres://localhost/app --rows 200\n" +
+			"  seedmancer generate --rows 50 --tables orders,order_items --seed 7\n" +
+			"  seedmancer generate --rows 20 --locale de_DE\n" +
+			"  seedmancer generate --rows 500 --edge-cases 5%\n\n" +
+			"  With --db-url, --rows needs no seedmancer.yaml.\n\n" +
+			"  --direct makes the rows up in memory from the database's schema and\n" +
+			"  prints them as JSON instead of inserting them — one object per table,\n" +
+			"  parents first, with its columns and rows — for pipelines that load or\n" +
+			"  transform the data themselves. The database is only read. Serial and\n" +
+			"  identity keys are numbered from 1, and foreign keys point at rows of\n" +
+			"  the same output, so --tables can't leave out a required parent.\n\n" +
+			"  seedmancer generate --rows 100 --direct > rows.json\n\n" +
 			"NOTE: this overwrites data in the configured local env.",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
@@ -94,8 +143,49 @@ func GenerateCommand() *cli.Command {
 				Name:  "token",
 				Usage: "API token (falls back to SEEDMANCER_API_TOKEN env var, then ~/.seedmancer/credentials)",
 			},
+			&cli.IntFlag{
+				Name:  "rows",
+				Usage: "Insert this many made-up rows per table straight into the database, without a scenario",
+			},
+			&cli.StringFlag{
+				Name:  "tables",
+				Usage: "With --rows: comma-separated tables to fill; their foreign keys use existing rows",
+			},
+			&cli.Int64Flag{
+				Name:  "seed",
+				Usage: "With --rows: random seed, for repeatable values (default: random)",
+			},
+			&cli.StringFlag{
+				Name:  "locale",
+				Usage: "With --rows: locale of names, addresses and text, e.g. de_DE or ja_JP",
+			},
+			&cli.StringFlag{
+				Name:  "edge-cases",
+				Usage: "With --rows: share of rows that get an edge-case value (NULL reference, max-length string, integer limit, {}), e.g. 5%",
+			},
+			&cli.BoolFlag{
+				Name:  "clean",
+				Usage: "With --rows: no random NULLs, so every nullable foreign key points at a parent row",
+			},
+			&cli.BoolFlag{
+				Name:  "direct",
+				Usage: "With --rows: print the made-up rows as JSON instead of inserting them",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Skip the confirmation prompt for prod-like envs",
+			},
 		},
 		Action: func(c *cli.Context) error {
+			if c.IsSet("rows") {
+				return generateFakeAction(c)
+			}
+			for _, name := range []string{"locale", "edge-cases", "clean", "direct"} {
+				if c.IsSet(name) {
+					return usageError(c, "--%s only applies with --rows", name)
+				}
+			}
 			scenarioArg := strings.TrimSpace(c.Args().First())
 			if scenarioArg == "" {
 				return usageError(c, "missing required argument: <scenario>")
@@ -125,6 +215,228 @@ func GenerateCommand() *cli.Command {
 	}
 }
 
+// generateFakeAction is generate --rows: fill the target database with
+// made-up rows directly, skipping the API and the revision on disk.
+func generateFakeAction(c *cli.Context) error {
+	if c.Args().Present() {
+		return usageError(c, "--rows inserts into the database directly and takes no <scenario>")
+	}
+	for _, name := range []string{"prompt", "inherit", "description"} {
+		if c.IsSet(name) {
+			return usageError(c, "--%s can't be combined with --rows", name)
+		}
+	}
+	in := GenerateFakeInput{
+		Env:    c.String("env"),
+		DBURL:  c.String("db-url"),
+		Rows:   c.Int("rows"),
+		Tables: splitCSVList(c.String("tables")),
+		Seed:   c.Int64("seed"),
+		Locale: strings.TrimSpace(c.String("locale")),
+		Clean:  c.Bool("clean"),
+		Direct: c.Bool("direct"),
+		Yes:    true,
+	}
+	if in.Rows <= 0 {
+		return usageError(c, "--rows must be a positive number")
+	}
+	if !c.IsSet("seed") {
+		in.Seed = time.Now().UnixNano()
+	}
+	if _, err := rowtemplate.Lookup(in.Locale); err != nil {
+		return usageError(c, "%v", err)
+	}
+	if c.IsSet("edge-cases") {
+		if in.Clean {
+			return usageError(c, "--edge-cases can't be combined with --clean")
+		}
+		in.EdgeCases = c.String("edge-cases")
+		if _, err := chaos.ParseRate(in.EdgeCases); err != nil {
+			return usageError(c, "--edge-cases: %v", err)
+		}
+	}
+
+	if in.Direct {
+		out, err := RunGenerateFake(c.Context, in)
+		if err != nil {
+			return err
+		}
+		return outputJSON(out.Data)
+	}
+
+	_, cfg, err := loadConfigOrAdHoc(in.DBURL)
+	if err != nil {
+		return err
+	}
+	target, err := pickExportTarget(cfg, in.Env, in.DBURL)
+	if err != nil {
+		return err
+	}
+	dest := targetDisplay(target)
+	if isProdLike(target.Name) && !c.Bool("yes") {
+		ui.Title(fmt.Sprintf("→ %s", dest))
+		if !ui.Confirm(fmt.Sprintf("Insert %d made-up rows per table into %q?", in.Rows, dest), false) {
+			ui.Info("Skipped.")
+			return nil
+		}
+	}
+
+	spinner := ui.StartSpinner(fmt.Sprintf("Inserting fake rows into %s…", dest))
+	out, err := RunGenerateFake(c.Context, in)
+	if err != nil {
+		spinner.Stop(false, "")
+		return err
+	}
+	total := 0
+	for _, t := range out.Tables {
+		total += t.Rows
+	}
+	spinner.Stop(true, fmt.Sprintf("Inserted %d row(s) into %d table(s) of %s", total, len(out.Tables), out.Env))
+	for _, t := range out.Tables {
+		if t.EdgeCases > 0 {
+			ui.KeyValue("  "+t.Table+": ", fmt.Sprintf("%d (%d with an edge case)", t.Rows, t.EdgeCases))
+			continue
+		}
+		ui.KeyValue("  "+t.Table+": ", fmt.Sprintf("%d", t.Rows))
+	}
+	ui.KeyValue("Seed:    ", fmt.Sprintf("%d", out.Seed))
+	return nil
+}
+
+// GenerateFakeInput is the structured argument set for RunGenerateFake.
+type GenerateFakeInput struct {
+	Env    string   `json:"env,omitempty" jsonschema:"Named env to insert into (defaults to default_env)"`
+	DBURL  string   `json:"dbUrl,omitempty" jsonschema:"Ad-hoc target URL (mutually exclusive with env)"`
+	Rows   int      `json:"rows" jsonschema:"Made-up rows to insert into each table"`
+	Tables []string `json:"tables,omitempty" jsonschema:"Only fill these tables; their foreign keys point at existing rows"`
+	Seed   int64    `json:"seed,omitempty" jsonschema:"Random seed for repeatable values"`
+	Locale string   `json:"locale,omitempty" jsonschema:"Locale of names, addresses and text, e.g. de_DE or ja_JP"`
+	// EdgeCases is a share of rows such as "5%"; Clean rules it out.
+	EdgeCases string `json:"edgeCases,omitempty" jsonschema:"Give this share of rows one edge-case value (NULL reference, max-length string, integer limit, empty JSON), e.g. 5%"`
+	Clean     bool   `json:"clean,omitempty" jsonschema:"No random NULLs, so every nullable foreign key points at a parent row"`
+	// Direct returns the rows in Data instead of inserting them; the
+	// database is only read for its schema.
+	Direct bool `json:"direct,omitempty" jsonschema:"Return the made-up rows instead of inserting them; the database is only read"`
+	Yes    bool `json:"yes,omitempty" jsonschema:"Confirm inserting into a prod-like env"`
+}
+
+// GenerateFakeOutput reports the rows RunGenerateFake inserted.
+type GenerateFakeOutput struct {
+	Env    string               `json:"env"`
+	Seed   int64                `json:"seed"`
+	Tables []db.FakeTableResult `json:"tables"`
+	// Data holds the rows themselves when Direct was set.
+	Data []db.FakeTable `json:"data,omitempty"`
+}
+
+// RunGenerateFake inserts in.Rows made-up rows into every table (or
+// in.Tables) of the target database, in one transaction, or with
+// in.Direct returns them without inserting. Tables listed in the
+// config's exclude_tables are left alone.
+func RunGenerateFake(ctx context.Context, in GenerateFakeInput) (GenerateFakeOutput, error) {
+	pack, err := rowtemplate.Lookup(in.Locale)
+	if err != nil {
+		return GenerateFakeOutput{}, err
+	}
+	var edgeCases float64
+	if in.EdgeCases != "" {
+		if in.Clean {
+			return GenerateFakeOutput{}, fmt.Errorf("edgeCases can't be combined with clean")
+		}
+		if edgeCases, err = chaos.ParseRate(in.EdgeCases); err != nil {
+			return GenerateFakeOutput{}, fmt.Errorf("edgeCases: %v", err)
+		}
+	}
+	configPath, cfg, err := loadConfigOrAdHoc(in.DBURL)
+	if err != nil {
+		return GenerateFakeOutput{}, err
+	}
+	target, err := pickExportTarget(cfg, in.Env, in.DBURL)
+	if err != nil {
+		return GenerateFakeOutput{}, err
+	}
+	dest := targetDisplay(target)
+	if !in.Direct && !in.Yes && isProdLike(target.Name) {
+		return GenerateFakeOutput{}, fmt.Errorf("confirmation required to insert fake rows into %q — set yes:true to confirm", dest)
+	}
+
+	manager, normalizedURL, err := db.NewManager(target.DatabaseURL)
+	if err != nil {
+		return GenerateFakeOutput{}, err
+	}
+	if err := manager.ConnectWithDSN(normalizedURL); err != nil {
+		return GenerateFakeOutput{}, fmt.Errorf("connecting to database: %v", err)
+	}
+	var schema *db.Schema
+	if in.Direct || len(cfg.ExcludeTables) > 0 {
+		if schema, err = manager.(db.SchemaExtractor).ExtractSchema(); err != nil {
+			return GenerateFakeOutput{}, fmt.Errorf("reading schema: %v", err)
+		}
+	}
+	tables := in.Tables
+	if len(tables) == 0 && len(cfg.ExcludeTables) > 0 {
+		excluded := make(map[string]bool, len(cfg.ExcludeTables))
+		for _, name := range cfg.ExcludeTables {
+			excluded[name] = true
+		}
+		for _, t := range schema.Tables {
+			if !excluded[t.Name] {
+				tables = append(tables, t.Name)
+			}
+		}
+	}
+	custom, err := customGenerators(cfg, filepath.Dir(configPath))
+	if err != nil {
+		return GenerateFakeOutput{}, err
+	}
+	weights, err := cfg.EnumWeightsFunc()
+	if err != nil {
+		return GenerateFakeOutput{}, err
+	}
+	picks, err := cfg.FKPickFunc()
+	if err != nil {
+		return GenerateFakeOutput{}, err
+	}
+	opts := db.FakeOptions{
+		Rows: in.Rows, Tables: tables, Seed: in.Seed, Pack: pack, Custom: custom,
+		EnumWeights: weights, FKPick: picks, EdgeCases: edgeCases, Clean: in.Clean,
+	}
+	if in.Direct {
+		data, err := db.GenerateFakeDataInMemory(schema, opts)
+		if err != nil {
+			return GenerateFakeOutput{}, err
+		}
+		out := GenerateFakeOutput{Env: dest, Seed: in.Seed, Data: data}
+		for _, t := range data {
+			out.Tables = append(out.Tables, db.FakeTableResult{Table: t.Table, Rows: len(t.Rows), EdgeCases: t.EdgeCases})
+		}
+		return out, nil
+	}
+	results, err := manager.GenerateFake(opts)
+	if err != nil {
+		return GenerateFakeOutput{}, err
+	}
+	return GenerateFakeOutput{Env: dest, Seed: in.Seed, Tables: results}, nil
+}
+
+// customGenerators loads the generator_plugins listed in seedmancer.yaml
+// (paths relative to configDir) and resolves its generators: map. It
+// returns nil when no column has a custom generator.
+func customGenerators(cfg utils.Config, configDir string) (func(table, column string) generators.Func, error) {
+	for _, p := range cfg.GeneratorPlugins {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(configDir, p)
+		}
+		if err := generators.LoadPlugin(p); err != nil {
+			return nil, fmt.Errorf("generator_plugins: %v", err)
+		}
+	}
+	if len(cfg.Generators) == 0 {
+		return nil, nil
+	}
+	return generators.ForColumns(cfg.Generators)
+}
+
 // ─── Schema conversion ────────────────────────────────────────────────────────
 
 func buildAPISchema(schemaJSON []byte, excludeTables []string) (generateSchema, error) {
@@ -154,13 +466,14 @@ func buildAPISchema(schemaJSON []byte, excludeTables []string) (generateSchema,
 		var cols []generateColumn
 		for _, c := range t.Columns {
 			col := generateColumn{
-				Name:          c.Name,
-				Type:          c.Type,
-				Nullable:      c.Nullable,
-				IsPrimary:     c.IsPrimary,
-				IsUnique:      c.IsUnique,
-				Enum:          c.Enum,
-				AllowedValues: c.AllowedValues,
+				Name:             c.Name,
+				Type:             c.Type,
+				Nullable:         c.Nullable,
+				IsPrimary:        c.IsPrimary,
+				IsUnique:         c.IsUnique,
+				Enum:             c.Enum,
+				AllowedValues:    c.AllowedValues,
+				UniqueIgnoreCase: c.UniqueIgnoreCase,
 			}
 			if c.Default != nil {
 				col.Default = fmt.Sprintf("%v", c.Default)
@@ -290,4 +603,4 @@ func splitStatements(sql string) []string {
 		stmts = append(stmts, cur.String())
 	}
 	return stmts
-}
\ No newline at end of file
+}
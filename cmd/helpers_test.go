@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"flag"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -64,6 +65,18 @@ func TestResolveSingleDB_precedence(t *testing.T) {
 			t.Fatalf("got %+v err=%v", ne, err)
 		}
 	})
+	t.Run("profile database_url beats default env but not --env", func(t *testing.T) {
+		utils.SetActiveProfile("side", utils.Profile{DatabaseURL: "postgres://profile"})
+		t.Cleanup(func() { utils.SetActiveProfile("", utils.Profile{}) })
+		ne, err := resolveSingleDB(newTestContext(t, nil), cfg)
+		if err != nil || ne.DatabaseURL != "postgres://profile" || ne.Name != adHocEnvName {
+			t.Fatalf("got %+v err=%v", ne, err)
+		}
+		ne, err = resolveSingleDB(newTestContext(t, []string{"--env", "staging"}), cfg)
+		if err != nil || ne.Name != "staging" {
+			t.Fatalf("got %+v err=%v", ne, err)
+		}
+	})
 	t.Run("env var used when no environments configured", func(t *testing.T) {
 		t.Setenv("SEEDMANCER_DATABASE_URL", "postgres://envvar")
 		c := newTestContext(t, nil)
@@ -107,6 +120,41 @@ func TestResolveSeedTargets_dbURLMutuallyExclusive(t *testing.T) {
 	}
 }
 
+func TestAddDBURLFileFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dsn")
+	writeFile(t, path, "postgres://app:secret@db/app\n")
+
+	var got string
+	commands := []*cli.Command{{
+		Name: "snapshot",
+		Subcommands: []*cli.Command{{
+			Name:   "take",
+			Flags:  []cli.Flag{&cli.StringFlag{Name: "db-url"}},
+			Action: func(c *cli.Context) error { got = c.String("db-url"); return nil },
+		}},
+	}}
+	AddDBURLFileFlags(commands)
+	app := &cli.App{Commands: commands}
+
+	if err := app.Run([]string{"seedmancer", "snapshot", "take", "--db-url-file", path}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got != "postgres://app:secret@db/app" {
+		t.Errorf("--db-url = %q, want the file's contents", got)
+	}
+	err := app.Run([]string{"seedmancer", "snapshot", "take", "--db-url", "x", "--db-url-file", path})
+	if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("both flags err = %v, want mutual exclusion", err)
+	}
+
+	if dsn, err := readDBURLFile("-", strings.NewReader("  postgres://stdin/app\n")); err != nil || dsn != "postgres://stdin/app" {
+		t.Errorf("stdin = %q, %v", dsn, err)
+	}
+	if _, err := readDBURLFile("-", strings.NewReader("\n")); err == nil {
+		t.Error("empty input accepted")
+	}
+}
+
 func TestIsProdLike(t *testing.T) {
 	hits := []string{"prod", "Production", "LIVE", " prod "}
 	for _, n := range hits {
@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+
+	"github.com/urfave/cli/v2"
+)
+
+// IntrospectCommand describes the project as editor integrations see it:
+// environments, scenarios with their revisions, schemas down to the
+// column, and the commands the CLI takes.
+func IntrospectCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "introspect",
+		Usage:     "Describe environments, scenarios, schemas and commands for editor integrations",
+		ArgsUsage: " ",
+		Description: "Reads local storage only, without connecting to a database or the\n" +
+			"cloud, and describes:\n\n" +
+			"  environments  name, engine and URL (password masked)\n" +
+			"  scenarios     every revision with its schema, tables, row counts\n" +
+			"                and the folder its CSVs are in\n" +
+			"  schemas       tables, columns (type, nullability, keys, defaults,\n" +
+			"                foreign keys) and enums\n" +
+			"  commands      every command with its arguments and flags\n\n" +
+			"--json prints it all to stdout for an editor extension to offer\n" +
+			"completion while fixtures are edited and to run seed from; without\n" +
+			"it a summary is printed.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Same as --output json",
+			},
+			outputFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			asJSON, err := jsonRequested(c)
+			if err != nil {
+				return err
+			}
+			out, err := RunIntrospect(c.Context, IntrospectInput{})
+			if err != nil {
+				return err
+			}
+			out.GlobalFlags = describeFlags(c.App.VisibleFlags())
+			out.Commands = describeCommands(c.App.VisibleCommands(), "")
+			if asJSON {
+				return outputJSON(out)
+			}
+			ui.Title("Project")
+			ui.KeyValue("Root", out.ProjectRoot)
+			ui.KeyValue("Environments", fmt.Sprintf("%d (default %s)", len(out.Environments), defaultDash(out.DefaultEnv)))
+			revisions := 0
+			for _, s := range out.Scenarios {
+				revisions += len(s.Revisions)
+			}
+			ui.KeyValue("Scenarios", fmt.Sprintf("%d, %d revision(s)", len(out.Scenarios), revisions))
+			ui.KeyValue("Schemas", fmt.Sprintf("%d", len(out.Schemas)))
+			ui.KeyValue("Commands", fmt.Sprintf("%d", len(out.Commands)))
+			for path, msg := range out.BadPaths {
+				ui.Warn("%s is unreadable: %s", path, msg)
+			}
+			ui.Info("Pass --json for the full description.")
+			return nil
+		},
+	}
+}
+
+// ─── introspect ───────────────────────────────────────────────────────────────
+
+type IntrospectInput struct{}
+
+// IntrospectEnv is an environment as introspect reports it.
+type IntrospectEnv struct {
+	EnvEntry
+	// Engine is postgres, mysql, cockroach or mariadb, from the URL.
+	Engine string `json:"engine,omitempty"`
+}
+
+// IntrospectRevision is one revision of an IntrospectScenario.
+type IntrospectRevision struct {
+	Revision    string `json:"revision"`
+	Schema      string `json:"schema,omitempty"`
+	Created     string `json:"created,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Description string `json:"description,omitempty"`
+	Engine      string `json:"engine,omitempty"`
+	// DataDir holds the revision's CSVs: <table>.csv, or the files Shards
+	// lists for tables split into shards.
+	DataDir   string              `json:"dataDir"`
+	Tables    []string            `json:"tables"`
+	RowCounts map[string]int      `json:"rowCounts,omitempty"`
+	Shards    map[string][]string `json:"shards,omitempty"`
+}
+
+// IntrospectScenario is a scenario and its revisions, oldest first.
+type IntrospectScenario struct {
+	Scenario  string               `json:"scenario"`
+	Latest    string               `json:"latest,omitempty"`
+	Prompt    string               `json:"prompt,omitempty"`
+	Revisions []IntrospectRevision `json:"revisions"`
+}
+
+// IntrospectSchema is a local schema with its tables and enums.
+type IntrospectSchema struct {
+	Fingerprint      string         `json:"fingerprint"`
+	FingerprintShort string         `json:"fingerprintShort"`
+	DisplayName      string         `json:"displayName,omitempty"`
+	DatabaseType     string         `json:"databaseType,omitempty"`
+	Path             string         `json:"path"`
+	Tables           []SchemaTable  `json:"tables"`
+	Enums            []generateEnum `json:"enums,omitempty"`
+}
+
+// IntrospectedFlag is a flag of the CLI or of one of its commands.
+type IntrospectedFlag struct {
+	Name       string   `json:"name"`
+	Aliases    []string `json:"aliases,omitempty"`
+	Usage      string   `json:"usage,omitempty"`
+	TakesValue bool     `json:"takesValue"`
+	Default    string   `json:"default,omitempty"`
+	EnvVars    []string `json:"envVars,omitempty"`
+}
+
+// IntrospectedCommand is a command of the CLI; Name is the full path of a
+// subcommand, e.g. "schemas list".
+type IntrospectedCommand struct {
+	Name      string             `json:"name"`
+	Aliases   []string           `json:"aliases,omitempty"`
+	Usage     string             `json:"usage,omitempty"`
+	ArgsUsage string             `json:"argsUsage,omitempty"`
+	Flags     []IntrospectedFlag `json:"flags,omitempty"`
+}
+
+type IntrospectOutput struct {
+	Version      string               `json:"version"`
+	ProjectRoot  string               `json:"projectRoot"`
+	StoragePath  string               `json:"storagePath"`
+	DefaultEnv   string               `json:"defaultEnv,omitempty"`
+	Environments []IntrospectEnv      `json:"environments"`
+	Scenarios    []IntrospectScenario `json:"scenarios"`
+	Schemas      []IntrospectSchema   `json:"schemas"`
+	// GlobalFlags and Commands are filled in by the CLI only.
+	GlobalFlags []IntrospectedFlag    `json:"globalFlags,omitempty"`
+	Commands    []IntrospectedCommand `json:"commands,omitempty"`
+	// BadPaths are scenarios, revisions and schemas whose manifest or
+	// schema.json couldn't be read, with why; they are left out above.
+	BadPaths map[string]string `json:"badPaths,omitempty"`
+}
+
+// RunIntrospect describes the project from local storage: environments,
+// scenarios and schemas. Nothing is connected to.
+func RunIntrospect(_ context.Context, _ IntrospectInput) (IntrospectOutput, error) {
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return IntrospectOutput{}, fmt.Errorf("%v — run `seedmancer init` first", err)
+	}
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return IntrospectOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	out := IntrospectOutput{
+		Version:      utils.CLIVersion(),
+		ProjectRoot:  projectRoot,
+		StoragePath:  cfg.StoragePath,
+		DefaultEnv:   cfg.ActiveEnvName(),
+		Environments: []IntrospectEnv{},
+		Scenarios:    []IntrospectScenario{},
+		Schemas:      []IntrospectSchema{},
+	}
+	bad := map[string]string{}
+
+	envs := cfg.EffectiveEnvs()
+	for _, name := range cfg.SortedEnvNames() {
+		e := IntrospectEnv{EnvEntry: EnvEntry{
+			Name:        name,
+			DatabaseURL: maskDatabaseURL(envs[name].DatabaseURL),
+			IsDefault:   name == out.DefaultEnv,
+		}}
+		if engine, err := db.DatabaseTypeOf(envs[name].DatabaseURL); err == nil {
+			e.Engine = string(engine)
+		}
+		out.Environments = append(out.Environments, e)
+	}
+
+	paths, badManifests, err := scenario.WalkScenarios(projectRoot, cfg.StoragePath)
+	if err != nil {
+		return out, err
+	}
+	for path, err := range badManifests {
+		bad[path] = err.Error()
+	}
+	for _, p := range paths {
+		sc, err := introspectScenario(projectRoot, cfg.StoragePath, p, bad)
+		if err != nil {
+			bad[p] = err.Error()
+			continue
+		}
+		out.Scenarios = append(out.Scenarios, sc)
+	}
+	sort.Slice(out.Scenarios, func(i, j int) bool { return out.Scenarios[i].Scenario < out.Scenarios[j].Scenario })
+
+	schemas, err := utils.ListLocalSchemas(projectRoot, cfg.StoragePath)
+	if err != nil {
+		return out, err
+	}
+	for _, s := range schemas {
+		data, err := os.ReadFile(s.SchemaJSONPath)
+		if err != nil {
+			bad[s.SchemaJSONPath] = err.Error()
+			continue
+		}
+		var raw struct {
+			DatabaseType string          `json:"databaseType"`
+			Tables       []generateTable `json:"tables"`
+			Enums        []generateEnum  `json:"enums"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			bad[s.SchemaJSONPath] = err.Error()
+			continue
+		}
+		out.Schemas = append(out.Schemas, IntrospectSchema{
+			Fingerprint:      s.Fingerprint,
+			FingerprintShort: s.FingerprintShort,
+			DisplayName:      s.DisplayName,
+			DatabaseType:     raw.DatabaseType,
+			Path:             s.Path,
+			Tables:           schemaTables(raw.Tables),
+			Enums:            raw.Enums,
+		})
+	}
+
+	if len(bad) > 0 {
+		out.BadPaths = bad
+	}
+	return out, nil
+}
+
+// introspectScenario describes the scenario at scenarioPath, recording
+// revisions whose manifest is unreadable in bad.
+func introspectScenario(projectRoot, storagePath, scenarioPath string, bad map[string]string) (IntrospectScenario, error) {
+	scenarioDir := scenario.ScenarioDir(projectRoot, storagePath, scenarioPath)
+	manifest, err := scenario.ReadManifest(scenarioDir)
+	if err != nil {
+		return IntrospectScenario{}, err
+	}
+	revs, err := scenario.ListRevisions(scenarioDir)
+	if err != nil {
+		return IntrospectScenario{}, err
+	}
+	sc := IntrospectScenario{
+		Scenario:  scenarioPath,
+		Latest:    manifest.Latest,
+		Prompt:    manifest.Prompt,
+		Revisions: []IntrospectRevision{},
+	}
+	for _, r := range revs {
+		revDir := scenario.RevisionDir(projectRoot, storagePath, scenarioPath, r.ID)
+		rm, err := scenario.ReadRevisionManifest(revDir)
+		if err != nil {
+			bad[scenarioPath+"@"+r.ID] = err.Error()
+			continue
+		}
+		rev := IntrospectRevision{
+			Revision:    r.ID,
+			Schema:      utils.FingerprintShort(rm.SchemaFingerprint),
+			Source:      rm.Source,
+			Description: rm.Description,
+			Engine:      rm.DatabaseType,
+			DataDir:     filepath.Join(revDir, "data"),
+			Tables:      rm.Tables,
+			RowCounts:   rm.RowCounts,
+			Shards:      rm.Shards,
+		}
+		if !rm.CreatedAt.IsZero() {
+			rev.Created = rm.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		if rev.Tables == nil {
+			rev.Tables = []string{}
+		}
+		sc.Revisions = append(sc.Revisions, rev)
+	}
+	return sc, nil
+}
+
+// describeCommands lists commands and, depth first, their subcommands,
+// hidden ones aside. prefix is the path of the parent command.
+func describeCommands(commands []*cli.Command, prefix string) []IntrospectedCommand {
+	var out []IntrospectedCommand
+	for _, c := range commands {
+		if c.Hidden {
+			continue
+		}
+		name := c.Name
+		if prefix != "" {
+			name = prefix + " " + name
+		}
+		out = append(out, IntrospectedCommand{
+			Name:      name,
+			Aliases:   c.Aliases,
+			Usage:     c.Usage,
+			ArgsUsage: c.ArgsUsage,
+			Flags:     describeFlags(c.VisibleFlags()),
+		})
+		out = append(out, describeCommands(c.Subcommands, name)...)
+	}
+	return out
+}
+
+// describeFlags describes flags, help aside.
+func describeFlags(flags []cli.Flag) []IntrospectedFlag {
+	var out []IntrospectedFlag
+	for _, f := range flags {
+		names := f.Names()
+		if len(names) == 0 || names[0] == "help" {
+			continue
+		}
+		flag := IntrospectedFlag{Name: names[0], Aliases: names[1:]}
+		if d, ok := f.(cli.DocGenerationFlag); ok {
+			flag.Usage = d.GetUsage()
+			flag.TakesValue = d.TakesValue()
+			flag.Default = d.GetDefaultText()
+			flag.EnvVars = d.GetEnvVars()
+		}
+		out = append(out, flag)
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/patch"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// layerStack splits the scenarios of a layered seed into the base and the
+// layers stacked on it: the base is scenarioArg, or the first of layers
+// when no <scenario> was given.
+func layerStack(scenarioArg string, layers []string) (string, []string) {
+	if scenarioArg == "" && len(layers) > 0 {
+		return layers[0], layers[1:]
+	}
+	return scenarioArg, layers
+}
+
+// appliedLayer is one layer stacked on a seed's base revision.
+type appliedLayer struct {
+	Rev     resolvedRevision
+	Changes []patch.TableChange
+}
+
+// applyLayers overlays the latest revision of each scenario in names, in
+// order, on the table CSVs in restoreDir: rows with a primary key already
+// there are replaced, the rest appended. Every layer must have base's
+// schema, since its rows are loaded with it.
+func applyLayers(projectRoot, storagePath string, names []string, restoreDir string, base resolvedRevision) ([]appliedLayer, error) {
+	schema, err := utils.ReadSchemaJSON(filepath.Join(restoreDir, "schema.json"))
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %v", err)
+	}
+	var applied []appliedLayer
+	for _, name := range names {
+		scenarioPath, err := scenario.Normalize(name)
+		if err != nil {
+			return nil, err
+		}
+		rev, err := resolveScenarioRevision(projectRoot, storagePath, scenarioPath, "")
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", name, err)
+		}
+		if rev.Manifest.SchemaFingerprint != base.Manifest.SchemaFingerprint {
+			return nil, fmt.Errorf("layer %s @ %s has schema %s but %s @ %s has schema %s — export the layer again against the current schema",
+				rev.Scenario, rev.RevID, utils.FingerprintShort(rev.Manifest.SchemaFingerprint),
+				base.Scenario, base.RevID, utils.FingerprintShort(base.Manifest.SchemaFingerprint))
+		}
+		if err := verifyRevisionChecksum(rev); err != nil {
+			return nil, err
+		}
+		changes, err := patch.Overlay(rev.DataDir, restoreDir, schema)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", rev.Scenario, err)
+		}
+		applied = append(applied, appliedLayer{Rev: rev, Changes: changes})
+	}
+	return applied, nil
+}
+
+// layeredSeedRecord names a layered seed in the history table: scenarios
+// and revisions joined with "+", base first. It has no checksum, since no
+// single revision holds the data that was loaded.
+func layeredSeedRecord(base resolvedRevision, layers []appliedLayer) *db.SeedRecord {
+	scenarios := []string{base.Scenario}
+	revisions := []string{base.RevID}
+	for _, l := range layers {
+		scenarios = append(scenarios, l.Rev.Scenario)
+		revisions = append(revisions, l.Rev.RevID)
+	}
+	return &db.SeedRecord{
+		Scenario: strings.Join(scenarios, "+"),
+		Revision: strings.Join(revisions, "+"),
+		Operator: seedOperator(),
+	}
+}
+
+// seedLayers reports layers for SeedOutput.
+func seedLayers(layers []appliedLayer) []SeedLayer {
+	var out []SeedLayer
+	for _, l := range layers {
+		sl := SeedLayer{Scenario: l.Rev.Scenario, Revision: l.Rev.RevID}
+		for _, c := range l.Changes {
+			sl.Replaced += c.Updated
+			sl.Added += c.Inserted
+		}
+		out = append(out, sl)
+	}
+	return out
+}
+
+// layerSummary is "plus-billing @ r002 (3 replaced, 12 added)".
+func layerSummary(l SeedLayer) string {
+	return fmt.Sprintf("%s @ %s (%d replaced, %d added)", l.Scenario, l.Revision, l.Replaced, l.Added)
+}
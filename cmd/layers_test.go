@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// addLayerScenario adds scenarioPath at r001 to the project stageRevision
+// built, with the given CSVs and schema fingerprint.
+func addLayerScenario(t *testing.T, dir, scenarioPath, fp string, csvs map[string]string) {
+	t.Helper()
+	revDir := scenario.RevisionDir(dir, ".seedmancer", scenarioPath, "r001")
+	for name, body := range csvs {
+		writeFile(t, filepath.Join(revDir, "data", name+".csv"), body)
+	}
+	now := time.Now().UTC()
+	if err := scenario.WriteManifest(scenario.ScenarioDir(dir, ".seedmancer", scenarioPath), scenario.Manifest{
+		Scenario: scenarioPath, CreatedAt: now, UpdatedAt: now, Latest: "r001",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := scenario.WriteRevisionManifest(revDir, scenario.RevisionManifest{
+		Scenario: scenarioPath, Revision: "r001", SchemaFingerprint: fp, CreatedAt: now, Source: "export",
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyLayers(t *testing.T) {
+	const schema = `{"tables":[
+	  {"name":"plans","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"name","type":"text"}]},
+	  {"name":"flags","columns":[{"name":"key","type":"text","isPrimary":true},{"name":"on","type":"boolean"}]}
+	]}`
+	dir := stageRevision(t, "base", schema, map[string]string{
+		"plans": "id,name\n1,free\n2,pro\n",
+		"flags": "key,on\nbeta,false\n",
+	})
+	fp := strings.Repeat("ab", 32)
+	addLayerScenario(t, dir, "plus-billing", fp, map[string]string{"plans": "id,name\n2,pro-annual\n3,team\n"})
+	addLayerScenario(t, dir, "plus-flags", fp, map[string]string{"flags": "key,on\nbeta,true\n"})
+
+	base, err := resolveScenarioRevision(dir, ".seedmancer", "base", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, cleanup, err := materializeRestoreDir(scenario.SchemaStoreDir(dir, ".seedmancer", utils.FingerprintShort(fp)), base.DataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	layered, err := applyLayers(dir, ".seedmancer", []string{"plus-billing", "plus-flags"}, merged, base)
+	if err != nil {
+		t.Fatalf("applyLayers: %v", err)
+	}
+	summary := seedLayers(layered)
+	if len(summary) != 2 || summary[0].Replaced != 1 || summary[0].Added != 1 || summary[1].Replaced != 1 {
+		t.Fatalf("layers = %+v", summary)
+	}
+	plans, _ := os.ReadFile(filepath.Join(merged, "plans.csv"))
+	if string(plans) != "id,name\n1,free\n2,pro-annual\n3,team\n" {
+		t.Errorf("plans.csv = %q", plans)
+	}
+	flags, _ := os.ReadFile(filepath.Join(merged, "flags.csv"))
+	if string(flags) != "key,on\nbeta,true\n" {
+		t.Errorf("flags.csv = %q", flags)
+	}
+	base2, _ := os.ReadFile(filepath.Join(base.DataDir, "plans.csv"))
+	if string(base2) != "id,name\n1,free\n2,pro\n" {
+		t.Errorf("the base revision was modified: %q", base2)
+	}
+	if rec := layeredSeedRecord(base, layered); rec.Scenario != "base+plus-billing+plus-flags" || rec.Revision != "r001+r001+r001" {
+		t.Errorf("record = %+v", rec)
+	}
+
+	addLayerScenario(t, dir, "old-schema", strings.Repeat("cd", 32), map[string]string{"plans": "id,name\n9,legacy\n"})
+	if _, err := applyLayers(dir, ".seedmancer", []string{"old-schema"}, merged, base); err == nil || !strings.Contains(err.Error(), "schema") {
+		t.Errorf("a layer on another schema should fail, got %v", err)
+	}
+}
+
+func TestLayerStack(t *testing.T) {
+	if base, rest := layerStack("", []string{"base", "plus-billing"}); base != "base" || len(rest) != 1 {
+		t.Errorf("layerStack without <scenario> = %q, %v", base, rest)
+	}
+	if base, rest := layerStack("base", []string{"plus-billing"}); base != "base" || len(rest) != 1 || rest[0] != "plus-billing" {
+		t.Errorf("layerStack with <scenario> = %q, %v", base, rest)
+	}
+}
@@ -37,6 +37,12 @@ type listEntry struct {
 	// this state; LastUsed is a humanized "time ago" of the most recent run.
 	UsedBy   int    `json:"usedBy,omitempty"`
 	LastUsed string `json:"lastUsed,omitempty"`
+	// Engine / SeedmancerVersion come from the latest revision manifest.
+	// Checksum is "ok" or "mismatch" when the manifest records one, and
+	// empty for revisions written before checksums existed.
+	Engine            string `json:"engine,omitempty"`
+	SeedmancerVersion string `json:"seedmancerVersion,omitempty"`
+	Checksum          string `json:"checksum,omitempty"`
 }
 
 // ListCommand prints every scenario known on disk, grouped by name with
@@ -53,8 +59,9 @@ func ListCommand() *cli.Command {
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
 				Name:  "json",
-				Usage: "Emit JSON for CI/CD pipelines",
+				Usage: "Same as --output json",
 			},
+			outputFlag(),
 			&cli.StringFlag{
 				Name:    "env",
 				Aliases: []string{"e"},
@@ -70,6 +77,10 @@ func ListCommand() *cli.Command {
 			},
 		},
 		Action: func(c *cli.Context) error {
+			asJSON, err := jsonRequested(c)
+			if err != nil {
+				return err
+			}
 			entries, badManifests, err := collectListEntries()
 			if err != nil {
 				return err
@@ -84,7 +95,7 @@ func ListCommand() *cli.Command {
 				entries, _ = annotateEntriesWithUsage(entries)
 			}
 
-			if c.Bool("json") {
+			if asJSON {
 				return outputJSON(struct {
 					Scenarios []listEntry            `json:"scenarios"`
 					BadPaths  map[string]string      `json:"badPaths,omitempty"`
@@ -103,6 +114,11 @@ func ListCommand() *cli.Command {
 			for path, err := range badManifests {
 				ui.Warn("scenario %q has a corrupt manifest: %v", path, err)
 			}
+			for _, e := range entries {
+				if e.Checksum == "mismatch" {
+					ui.Warn("%s @ %s: data/ was modified after the revision was written (checksum mismatch)", e.Scenario, e.Latest)
+				}
+			}
 			return nil
 		},
 	}
@@ -154,6 +170,19 @@ func buildListEntry(projectRoot, storagePath, scenarioPath string) (listEntry, e
 		if rev, err := scenario.ReadRevisionManifest(revDir); err == nil {
 			entry.Schema = utils.FingerprintShort(rev.SchemaFingerprint)
 			entry.Services = strings.Join(rev.Services, ",")
+			entry.Engine = rev.DatabaseType
+			entry.SeedmancerVersion = rev.SeedmancerVersion
+			if rev.Checksum != "" {
+				entry.Checksum = "ok"
+				if err := verifyRevisionChecksum(resolvedRevision{
+					Scenario: scenarioPath,
+					RevID:    manifest.Latest,
+					DataDir:  filepath.Join(revDir, "data"),
+					Manifest: rev,
+				}); err != nil {
+					entry.Checksum = "mismatch"
+				}
+			}
 		}
 	}
 	return entry, nil
@@ -174,9 +203,9 @@ func renderScenarioTable(entries []listEntry) {
 	table := tablewriter.NewWriter(os.Stdout)
 	var headers []string
 	if showDB {
-		headers = []string{"Scenario", "Schema Status", "Drift", "Updated"}
+		headers = []string{"Scenario", "Schema Status", "Drift", "Engine", "Updated"}
 	} else {
-		headers = []string{"Scenario", "Schema", "Updated"}
+		headers = []string{"Scenario", "Schema", "Engine", "Updated"}
 	}
 	table.SetHeader(headers)
 	table.SetBorder(false)
@@ -196,12 +225,14 @@ func renderScenarioTable(entries []listEntry) {
 				e.Scenario,
 				dbCell,
 				defaultDash(e.Drift),
+				defaultDash(e.Engine),
 				defaultDash(e.Updated),
 			})
 		} else {
 			table.Append([]string{
 				e.Scenario,
 				defaultDash(e.Schema),
+				defaultDash(e.Engine),
 				defaultDash(e.Updated),
 			})
 		}
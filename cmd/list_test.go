@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"os"
@@ -107,3 +108,34 @@ func TestListLocalEntries_scenarioLayout(t *testing.T) {
 		t.Errorf("Latest = %q, want %q", entries[0].Latest, "r001")
 	}
 }
+
+// TestBuildListEntry_flagsEditedRevisionData derives a revision (which
+// records a checksum) and checks that list reports it ok until a CSV is
+// edited in place.
+func TestBuildListEntry_flagsEditedRevisionData(t *testing.T) {
+	const schema = `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer"},{"name":"plan","type":"text"}]}]}`
+	dir := stageRevision(t, "billing/pro", schema, map[string]string{
+		"users": "id,plan\n1,free\n",
+	})
+	out, err := RunRewrite(context.Background(), RewriteInput{Scenario: "billing/pro", Set: []string{"users.plan=pro"}})
+	if err != nil {
+		t.Fatalf("RunRewrite: %v", err)
+	}
+
+	entry, err := buildListEntry(dir, ".seedmancer", "billing/pro")
+	if err != nil {
+		t.Fatalf("buildListEntry: %v", err)
+	}
+	if entry.Latest != out.Revision || entry.Checksum != "ok" || entry.SeedmancerVersion == "" {
+		t.Fatalf("entry = %+v, want %s with checksum ok and a seedmancer version", entry, out.Revision)
+	}
+
+	writeFile(t, filepath.Join(out.Path, "users.csv"), "id,plan\n1,enterprise\n")
+	entry, err = buildListEntry(dir, ".seedmancer", "billing/pro")
+	if err != nil {
+		t.Fatalf("buildListEntry: %v", err)
+	}
+	if entry.Checksum != "mismatch" {
+		t.Fatalf("Checksum = %q after editing data, want mismatch", entry.Checksum)
+	}
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/lockfile"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// LockCommand pins a scenario revision in seedmancer.lock so a service
+// can declare, in its own repo, the fixture its code expects.
+//
+//	seedmancer lock billing/pro --revision r003
+func LockCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "lock",
+		Usage:     "Pin a scenario revision in " + lockfile.FileName,
+		ArgsUsage: "<scenario>",
+		Description: "Writes " + lockfile.FileName + " next to seedmancer.yaml with the scenario,\n" +
+			"the revision (latest by default) and the checksum of its data.\n" +
+			"Commit it with the code that depends on that data. Then:\n\n" +
+			"  seedmancer seed --from-lock           seeds exactly that revision\n" +
+			"  seedmancer verify-lock --db-url ...   fails unless a database holds it\n\n" +
+			"Re-running lock replaces the file.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "revision",
+				Aliases: []string{"r"},
+				Usage:   "Revision to pin (defaults to latest)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			configPath, err := utils.FindConfigFile()
+			if err != nil {
+				return err
+			}
+			projectRoot := filepath.Dir(configPath)
+			cfg, err := utils.LoadConfig(configPath)
+			if err != nil {
+				return err
+			}
+			scenarioPath, err := scenario.Normalize(scenarioArg)
+			if err != nil {
+				return err
+			}
+			rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, c.String("revision"))
+			if err != nil {
+				return err
+			}
+			if err := verifyRevisionChecksum(rev); err != nil {
+				return err
+			}
+			sum, err := revisionChecksum(rev)
+			if err != nil {
+				return err
+			}
+			l := lockfile.Lock{
+				Scenario:          rev.Scenario,
+				Revision:          rev.RevID,
+				Checksum:          sum,
+				SchemaFingerprint: rev.Manifest.SchemaFingerprint,
+			}
+			if err := lockfile.Write(projectRoot, l); err != nil {
+				return fmt.Errorf("writing %s: %v", lockfile.FileName, err)
+			}
+			ui.Success("Locked %s @ %s", l.Scenario, l.Revision)
+			ui.KeyValue("File: ", lockfile.Path(projectRoot))
+			return nil
+		},
+	}
+}
+
+// verifyLockReport is the JSON shape of `seedmancer verify-lock --json`.
+type verifyLockReport struct {
+	Lock    lockfile.Lock `json:"lock"`
+	Matches bool          `json:"matches"`
+	Drift   *statusDrift  `json:"drift"`
+}
+
+// VerifyLockCommand checks that a live database holds exactly the
+// revision pinned in seedmancer.lock, for CI gates and service startup.
+func VerifyLockCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "verify-lock",
+		Usage: "Check that a database holds the revision pinned in " + lockfile.FileName,
+		Description: "Reads " + lockfile.FileName + ", makes sure the pinned revision exists\n" +
+			"locally with the locked checksum, then compares it with the target\n" +
+			"database: the schema fingerprint must match and every table must\n" +
+			"hold exactly the fixture rows. Exits non-zero otherwise. Nothing\n" +
+			"is written.\n\n" +
+			"Tables whose CSVs hold @env markers never match, since markers are\n" +
+			"resolved only at seed time.\n\n" +
+			"Examples:\n" +
+			"  seedmancer verify-lock --db-url postgres://localhost:5432/app\n" +
+			"  seedmancer verify-lock --env staging --json",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "env",
+				Aliases: []string{"e"},
+				Usage:   "Named environment to verify (defaults to default_env)",
+			},
+			&cli.StringFlag{
+				Name:  "db-url",
+				Usage: "Ad-hoc database URL to verify (takes precedence over env)",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Emit the result as JSON",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			configPath, err := utils.FindConfigFile()
+			if err != nil {
+				return err
+			}
+			projectRoot := filepath.Dir(configPath)
+			cfg, err := utils.LoadConfig(configPath)
+			if err != nil {
+				return err
+			}
+			l, err := loadProjectLock(projectRoot)
+			if err != nil {
+				return err
+			}
+			if _, err := resolveLockedRevision(projectRoot, cfg.StoragePath, *l); err != nil {
+				return err
+			}
+			drift, err := buildStatusDrift(l.Scenario, l.Revision, c.String("env"), c.String("db-url"))
+			if err != nil {
+				return err
+			}
+			report := verifyLockReport{
+				Lock:    *l,
+				Matches: drift.SchemaStatus == "ok" && drift.Create+drift.Replace == 0,
+				Drift:   drift,
+			}
+
+			if c.Bool("json") {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return err
+				}
+			} else if !report.Matches {
+				renderStatusDrift(*drift)
+			}
+			if !report.Matches {
+				return fmt.Errorf("%s does not hold %s @ %s pinned in %s — run `seedmancer seed --from-lock`",
+					drift.Target, l.Scenario, l.Revision, lockfile.FileName)
+			}
+			if !c.Bool("json") {
+				ui.Success("%s holds %s @ %s", drift.Target, l.Scenario, l.Revision)
+			}
+			return nil
+		},
+	}
+}
+
+// loadProjectLock reads seedmancer.lock from projectRoot; a missing file
+// is an error here since every caller needs a lock.
+func loadProjectLock(projectRoot string) (*lockfile.Lock, error) {
+	l, ok, err := lockfile.Load(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no %s in %s — run `seedmancer lock <scenario>` first", lockfile.FileName, projectRoot)
+	}
+	return l, nil
+}
+
+// resolveLockedRevision resolves the revision pinned by l and makes sure
+// the local copy is the one that was locked: same data checksum, and
+// data/ still matching its own manifest.
+func resolveLockedRevision(projectRoot, storagePath string, l lockfile.Lock) (resolvedRevision, error) {
+	scenarioPath, err := scenario.Normalize(l.Scenario)
+	if err != nil {
+		return resolvedRevision{}, fmt.Errorf("%s: %w", lockfile.FileName, err)
+	}
+	rev, err := resolveScenarioRevision(projectRoot, storagePath, scenarioPath, l.Revision)
+	if err != nil {
+		return resolvedRevision{}, fmt.Errorf("%s pins %s @ %s: %w", lockfile.FileName, l.Scenario, l.Revision, err)
+	}
+	if err := verifyRevisionChecksum(rev); err != nil {
+		return resolvedRevision{}, err
+	}
+	sum, err := revisionChecksum(rev)
+	if err != nil {
+		return resolvedRevision{}, err
+	}
+	if sum != l.Checksum {
+		return resolvedRevision{}, fmt.Errorf(
+			"%s @ %s: local data checksum %s does not match %s in %s — pull the revision again or re-run `seedmancer lock %s`",
+			rev.Scenario, rev.RevID, shortChecksum(sum), shortChecksum(l.Checksum), lockfile.FileName, rev.Scenario)
+	}
+	return rev, nil
+}
+
+// revisionChecksum is the data checksum recorded on rev's manifest, or,
+// for revisions written before checksums existed, computed from data/.
+func revisionChecksum(rev resolvedRevision) (string, error) {
+	if rev.Manifest.Checksum != "" {
+		return rev.Manifest.Checksum, nil
+	}
+	sum, err := scenario.DataChecksum(rev.DataDir)
+	if err != nil {
+		return "", fmt.Errorf("checksumming %s @ %s: %w", rev.Scenario, rev.RevID, err)
+	}
+	return sum, nil
+}
+
+func shortChecksum(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/lockfile"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+)
+
+func TestResolveSeedRevision_fromLock(t *testing.T) {
+	dir := stageRevision(t, "billing/pro", `{"tables":[]}`, map[string]string{"users": "id\n1\n"})
+	revDir := scenario.RevisionDir(dir, ".seedmancer", "billing/pro", "r001")
+	sum, err := scenario.DataChecksum(filepath.Join(revDir, "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := resolveSeedRevision(dir, ".seedmancer", "", "", true); err == nil || !strings.Contains(err.Error(), "no "+lockfile.FileName) {
+		t.Fatalf("err = %v, want missing lock", err)
+	}
+
+	if err := lockfile.Write(dir, lockfile.Lock{Scenario: "billing/pro", Revision: "r001", Checksum: sum}); err != nil {
+		t.Fatal(err)
+	}
+	rev, err := resolveSeedRevision(dir, ".seedmancer", "", "", true)
+	if err != nil || rev.Scenario != "billing/pro" || rev.RevID != "r001" {
+		t.Fatalf("got %+v err=%v", rev, err)
+	}
+	if _, err := resolveSeedRevision(dir, ".seedmancer", "billing/free", "", true); err == nil {
+		t.Fatal("expected an error for a scenario other than the locked one")
+	}
+
+	if err := lockfile.Write(dir, lockfile.Lock{Scenario: "billing/pro", Revision: "r001", Checksum: "deadbeef"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveSeedRevision(dir, ".seedmancer", "", "", true); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("err = %v, want checksum mismatch", err)
+	}
+}
@@ -192,6 +192,11 @@ func resolveDashboardURL(flag string) string {
 	if v := strings.TrimSpace(flag); v != "" {
 		return strings.TrimRight(v, "/")
 	}
+	if _, p, ok := utils.ActiveProfile(); ok {
+		if v := strings.TrimSpace(p.DashboardURL); v != "" {
+			return strings.TrimRight(v, "/")
+		}
+	}
 	// When the resolved API origin is not production, treat it as the dashboard
 	// host too (local monorepo dev). Otherwise use the public marketing site.
 	if v := strings.TrimSpace(utils.GetBaseURL()); v != "" && !strings.Contains(v, "api.seedmancer.dev") {
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/migrations"
+	"github.com/KazanKK/seedmancer/internal/ui"
+)
+
+// loadSeedMigrations reads the column mapping a seed applies: path when
+// given (it must exist), otherwise migrations.yaml in the project root if
+// there is one. A nil mapping means there is nothing to apply.
+func loadSeedMigrations(projectRoot, path string, disabled bool) (*migrations.File, error) {
+	if disabled {
+		return nil, nil
+	}
+	explicit := strings.TrimSpace(path) != ""
+	if !explicit {
+		path = migrations.Path(projectRoot)
+	}
+	m, ok, err := migrations.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if explicit {
+			return nil, fmt.Errorf("migrations file %s not found", path)
+		}
+		return nil, nil
+	}
+	return m, nil
+}
+
+// materializeMigrationsDir stages restoreDir with m applied into a fresh
+// temp dir: rewritten CSVs and schema.json are written there and every
+// other file is linked through. With no rule firing it returns
+// restoreDir itself and no changes.
+func materializeMigrationsDir(restoreDir string, m migrations.File) (string, []migrations.Change, func(), error) {
+	tmp, err := os.MkdirTemp("", "seedmancer-migrate-*")
+	if err != nil {
+		return "", nil, func() {}, fmt.Errorf("creating temp dir: %v", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(tmp) }
+
+	changes, err := migrations.Apply(restoreDir, tmp, m)
+	if err != nil {
+		cleanup()
+		return "", nil, func() {}, err
+	}
+	if len(changes) == 0 {
+		cleanup()
+		return restoreDir, nil, func() {}, nil
+	}
+	entries, err := os.ReadDir(restoreDir)
+	if err != nil {
+		cleanup()
+		return "", nil, func() {}, fmt.Errorf("reading restore dir: %v", err)
+	}
+	for _, e := range entries {
+		dst := filepath.Join(tmp, e.Name())
+		if e.IsDir() {
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+		if err := linkOrCopy(filepath.Join(restoreDir, e.Name()), dst); err != nil {
+			cleanup()
+			return "", nil, func() {}, fmt.Errorf("staging %s: %v", e.Name(), err)
+		}
+	}
+	return tmp, changes, cleanup, nil
+}
+
+// printMigrationChanges lists the mapping rules a seed applied.
+func printMigrationChanges(changes []migrations.Change) {
+	ui.Info("Applied %d column mapping(s) from %s; the schema guard is skipped:", len(changes), migrations.FileName)
+	for _, c := range changes {
+		ui.KeyValue("  ", c.String())
+	}
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/lockfile"
+	"github.com/KazanKK/seedmancer/internal/orchestrate"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// OrchestrateCommand seeds every locked service of a monorepo in
+// dependency order.
+//
+//	seedmancer orchestrate
+//	seedmancer orchestrate services --env ci --yes
+func OrchestrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "orchestrate",
+		Usage:     "Seed every service in a monorepo from its seedmancer.lock, in dependency order",
+		ArgsUsage: "[dir]",
+		Description: "Finds every directory under [dir] (default: the current one) that\n" +
+			"holds a seedmancer.yaml and seeds each service's database with the\n" +
+			"revision pinned in its " + lockfile.FileName + ". Services without a lock\n" +
+			"are listed and left alone.\n\n" +
+			"When one service's fixtures reference rows owned by another, declare\n" +
+			"it in the dependent service's seedmancer.yaml so the owner is seeded\n" +
+			"first:\n\n" +
+			"  # services/orders/seedmancer.yaml\n" +
+			"  depends_on:\n" +
+			"    - ../users\n\n" +
+			"Services seed one at a time. If one fails, the services depending\n" +
+			"on it are skipped and the rest carry on with --continue-on-error;\n" +
+			"without it the run stops. A summary of every service comes last.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "env",
+				Aliases: []string{"e"},
+				Usage:   "Environment to seed in every service (defaults to each service's default_env)",
+			},
+			&cli.BoolFlag{
+				Name:    "force",
+				Aliases: []string{"f"},
+				Usage:   "Seed even when a database's schema fingerprint differs",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Skip the confirmation prompt",
+			},
+			&cli.BoolFlag{
+				Name:  "continue-on-error",
+				Usage: "Keep seeding services that don't depend on a failed one (default: stop)",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Print the seeding order and targets without seeding",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Emit the per-service results as JSON",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			root := strings.TrimSpace(c.Args().First())
+			if root == "" {
+				root = "."
+			}
+			services, err := orchestrate.Discover(root)
+			if err != nil {
+				return err
+			}
+			if len(services) == 0 {
+				return fmt.Errorf("no seedmancer.yaml found under %s", root)
+			}
+			ordered, err := orchestrate.Order(services)
+			if err != nil {
+				return err
+			}
+			plan, err := planOrchestration(ordered, c.String("env"))
+			if err != nil {
+				return err
+			}
+
+			ui.Title("Seeding order")
+			n := 0
+			for _, p := range plan {
+				if p.Lock == nil {
+					ui.Info("  -  %-20s no %s, not seeded", p.Name, lockfile.FileName)
+					continue
+				}
+				n++
+				ui.Info("  %d. %-20s %s @ %s → %s", n, p.Name, p.Lock.Scenario, p.Lock.Revision, targetDisplay(p.Target))
+			}
+			if c.Bool("dry-run") {
+				return nil
+			}
+
+			if !c.Bool("yes") {
+				for _, p := range plan {
+					if p.Lock != nil && isProdLike(p.Target.Name) {
+						ui.Warn("%s seeds into %s", p.Name, targetDisplay(p.Target))
+					}
+				}
+				if !ui.Confirm(fmt.Sprintf("Seed %d service(s)?", countLocked(plan)), false) {
+					ui.Info("Skipped.")
+					return nil
+				}
+			}
+
+			results := runOrchestration(plan, c.Bool("force"), c.Bool("continue-on-error"))
+
+			if c.Bool("json") {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(results); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintln(os.Stderr)
+				printOrchestrateSummary(results)
+			}
+			for _, r := range results {
+				if r.Status == orchestrateFailed {
+					return fmt.Errorf("one or more services failed to seed")
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// orchestrateStep is one service in the run: its lock (nil when it has
+// none) and the env its database comes from.
+type orchestrateStep struct {
+	orchestrate.Service
+	Target utils.NamedEnv
+}
+
+// planOrchestration resolves each locked service's target env up front so
+// a typo in one service's config fails the run before anything is seeded.
+// A locked service may not depend on an unlocked one: its fixtures would
+// reference rows nobody loads.
+func planOrchestration(ordered []orchestrate.Service, envName string) ([]orchestrateStep, error) {
+	locked := make(map[string]bool, len(ordered))
+	for _, s := range ordered {
+		locked[s.Name] = s.Lock != nil
+	}
+	plan := make([]orchestrateStep, 0, len(ordered))
+	for _, s := range ordered {
+		step := orchestrateStep{Service: s}
+		if s.Lock == nil {
+			plan = append(plan, step)
+			continue
+		}
+		for _, dep := range s.DependsOn {
+			if !locked[dep] {
+				return nil, fmt.Errorf("%s depends on %s, which has no %s — run `seedmancer lock <scenario>` there", s.Name, dep, lockfile.FileName)
+			}
+		}
+		target, err := s.Config.ResolveEnv(envName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		step.Target = target
+		plan = append(plan, step)
+	}
+	return plan, nil
+}
+
+func countLocked(plan []orchestrateStep) int {
+	n := 0
+	for _, p := range plan {
+		if p.Lock != nil {
+			n++
+		}
+	}
+	return n
+}
+
+const (
+	orchestrateOK       = "ok"
+	orchestrateFailed   = "failed"
+	orchestrateSkipped  = "skipped"
+	orchestrateUnlocked = "unlocked"
+)
+
+// orchestrateResult is one service's line in the consolidated report.
+type orchestrateResult struct {
+	Service    string `json:"service"`
+	Scenario   string `json:"scenario,omitempty"`
+	Revision   string `json:"revision,omitempty"`
+	Env        string `json:"env,omitempty"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// runOrchestration seeds plan in order. A service whose dependency did
+// not seed is skipped; after the first failure everything left is
+// skipped too unless continueOnError.
+func runOrchestration(plan []orchestrateStep, force, continueOnError bool) []orchestrateResult {
+	results := make([]orchestrateResult, 0, len(plan))
+	status := make(map[string]string, len(plan))
+	stopped := false
+	for _, p := range plan {
+		r := orchestrateResult{Service: p.Name, Status: orchestrateUnlocked}
+		if p.Lock == nil {
+			results = append(results, r)
+			continue
+		}
+		r.Scenario, r.Revision, r.Env = p.Lock.Scenario, p.Lock.Revision, targetDisplay(p.Target)
+
+		blocked := ""
+		for _, dep := range p.DependsOn {
+			if status[dep] != orchestrateOK {
+				blocked = dep
+				break
+			}
+		}
+		switch {
+		case stopped:
+			r.Status = orchestrateSkipped
+		case blocked != "":
+			r.Status = orchestrateSkipped
+			r.Error = fmt.Sprintf("dependency %s did not seed", blocked)
+		default:
+			fmt.Fprintln(os.Stderr)
+			ui.Step("%s: seed %s @ %s", p.Name, p.Lock.Scenario, p.Lock.Revision)
+			start := time.Now()
+			if err := seedService(p, force); err != nil {
+				r.Status = orchestrateFailed
+				r.Error = err.Error()
+				stopped = !continueOnError
+			} else {
+				r.Status = orchestrateOK
+			}
+			r.DurationMS = time.Since(start).Milliseconds()
+		}
+		status[p.Name] = r.Status
+		results = append(results, r)
+	}
+	return results
+}
+
+// seedService seeds one service's target with its locked revision, the
+// way `seed --from-lock --yes` would from inside the service directory.
+func seedService(p orchestrateStep, force bool) error {
+	rev, err := resolveLockedRevision(p.Dir, p.Config.StoragePath, *p.Lock)
+	if err != nil {
+		ui.Error("%v", err)
+		return err
+	}
+	schemaDir := scenario.SchemaStoreDir(p.Dir, p.Config.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	merged, cleanup, err := materializeRestoreDir(schemaDir, rev.DataDir)
+	if err != nil {
+		ui.Error("%v", err)
+		return err
+	}
+	defer cleanup()
+	if err := refuseProtectedTargets(p.Config, []utils.NamedEnv{p.Target}); err != nil {
+		ui.Error("%v", err)
+		return err
+	}
+	if err := checkSeedTarget(p.Target, rev, force, 0); err != nil {
+		ui.Error("%v", err)
+		return err
+	}
+	warnings, err := checkRevisionEngine(rev, revisionDatabaseType(p.Dir, p.Config.StoragePath, rev), []utils.NamedEnv{p.Target}, false)
+	if err != nil {
+		ui.Error("%v", err)
+		return err
+	}
+	if w := partialRevisionWarning(rev); w != "" {
+		warnings = append(warnings, w)
+	}
+	for _, w := range warnings {
+		ui.Warn("%s", w)
+	}
+	// seedOneEnv prints its own failures.
+	opts := restoreOptionsFromConfig(p.Config)
+	opts.Record = seedRecordFor(rev)
+	return seedOneEnv(p.Target, merged, rev.RevID, rev.Scenario, true, opts).Err
+}
+
+// printOrchestrateSummary renders one line per service, in seeding order.
+func printOrchestrateSummary(results []orchestrateResult) {
+	ui.Title("Summary")
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		label := fmt.Sprintf("  %-20s", r.Service)
+		switch r.Status {
+		case orchestrateOK:
+			ui.KeyValue(label, fmt.Sprintf("✓ ok  %s @ %s → %s (%s)", r.Scenario, r.Revision, r.Env, time.Duration(r.DurationMS)*time.Millisecond))
+		case orchestrateFailed:
+			ui.KeyValue(label, fmt.Sprintf("✗ failed  — %s", r.Error))
+		case orchestrateSkipped:
+			if r.Error != "" {
+				ui.KeyValue(label, fmt.Sprintf("— skipped (%s)", r.Error))
+			} else {
+				ui.KeyValue(label, "— skipped")
+			}
+		case orchestrateUnlocked:
+			ui.KeyValue(label, fmt.Sprintf("— no %s", lockfile.FileName))
+		}
+	}
+	ui.Info("%d ok, %d failed, %d skipped, %d without a lock",
+		counts[orchestrateOK], counts[orchestrateFailed], counts[orchestrateSkipped], counts[orchestrateUnlocked])
+}
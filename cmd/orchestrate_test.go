@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/lockfile"
+	"github.com/KazanKK/seedmancer/internal/orchestrate"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func TestPlanOrchestration_dependencyWithoutLock(t *testing.T) {
+	cfg := utils.Config{Environments: map[string]utils.EnvConfig{"local": {DatabaseURL: "postgres://localhost/x"}}, DefaultEnv: "local"}
+	ordered := []orchestrate.Service{
+		{Name: "users", Config: cfg},
+		{Name: "orders", Config: cfg, DependsOn: []string{"users"}, Lock: &lockfile.Lock{Scenario: "base", Revision: "r001", Checksum: "abc"}},
+	}
+	if _, err := planOrchestration(ordered, ""); err == nil || !strings.Contains(err.Error(), "orders depends on users") {
+		t.Fatalf("err = %v, want unlocked dependency", err)
+	}
+}
+
+func TestRunOrchestration_skipsDependentsOfFailures(t *testing.T) {
+	lock := &lockfile.Lock{Scenario: "base", Revision: "r001", Checksum: "abc"}
+	plan := []orchestrateStep{
+		// No revision on disk, so seeding fails before touching a database.
+		{Service: orchestrate.Service{Name: "users", Dir: t.TempDir(), Lock: lock}},
+		{Service: orchestrate.Service{Name: "docs"}},
+		{Service: orchestrate.Service{Name: "orders", Dir: t.TempDir(), Lock: lock, DependsOn: []string{"users"}}},
+		{Service: orchestrate.Service{Name: "catalog", Dir: t.TempDir(), Lock: lock}},
+	}
+
+	results := runOrchestration(plan, false, true)
+	got := make([]string, len(results))
+	for i, r := range results {
+		got[i] = r.Service + "=" + r.Status
+	}
+	want := "users=failed docs=unlocked orders=skipped catalog=failed"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("results = %v, want %s", got, want)
+	}
+	if !strings.Contains(results[2].Error, "users") {
+		t.Fatalf("orders error = %q, want it to name users", results[2].Error)
+	}
+
+	results = runOrchestration(plan, false, false)
+	if results[3].Status != orchestrateSkipped || results[3].Error != "" {
+		t.Fatalf("catalog = %+v, want skipped after the first failure", results[3])
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Output formats accepted by --output.
+const (
+	outputText       = "text"
+	outputJSONFormat = "json"
+)
+
+// outputFlag is the --output flag of commands whose result CI pipelines
+// parse. JSON goes to stdout; progress and prompts stay on stderr, so
+// `seedmancer seed … --output json > result.json` captures only the
+// result.
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "output",
+		Usage: "Output format: text or json (json is written to stdout)",
+		Value: outputText,
+	}
+}
+
+// jsonRequested reports whether c asked for JSON, through --output json
+// or the older --json switch where the command has one.
+func jsonRequested(c *cli.Context) (bool, error) {
+	switch format := strings.ToLower(strings.TrimSpace(c.String("output"))); format {
+	case "", outputText:
+		return c.Bool("json"), nil
+	case outputJSONFormat:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown --output %q (use text or json)", format)
+	}
+}
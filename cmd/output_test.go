@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestJSONRequested(t *testing.T) {
+	cases := []struct {
+		argv    []string
+		want    bool
+		wantErr bool
+	}{
+		{argv: nil, want: false},
+		{argv: []string{"--json"}, want: true},
+		{argv: []string{"--output", "json"}, want: true},
+		{argv: []string{"--output", "JSON"}, want: true},
+		{argv: []string{"--output", "text"}, want: false},
+		{argv: []string{"--output", "yaml"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Bool("json", false, "")
+		fs.String("output", outputText, "")
+		if err := fs.Parse(tc.argv); err != nil {
+			t.Fatalf("flag parse: %v", err)
+		}
+		got, err := jsonRequested(cli.NewContext(cli.NewApp(), fs, nil))
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("%v: got %v, %v; want %v (err %v)", tc.argv, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
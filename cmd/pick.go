@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/ui"
+)
+
+// pickTables lets the user check tables off the list for command (export
+// or seed) --interactive, starting from preselected, and prints the
+// command line that makes the same choice without a prompt.
+func pickTables(command string, tables, preselected []string) ([]string, error) {
+	if !ui.CanPrompt() {
+		return nil, fmt.Errorf("--interactive needs a terminal; pass --tables instead")
+	}
+	picked, err := ui.MultiSelect(fmt.Sprintf("Tables to %s", command), tables, preselected)
+	if errors.Is(err, ui.ErrCanceled) {
+		return nil, fmt.Errorf("%s canceled", command)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(picked) == 0 {
+		return nil, fmt.Errorf("no tables picked")
+	}
+	ui.Info("Same choice without the prompt:")
+	ui.Info("  %s", equivalentCommand(os.Args, command, picked))
+	return picked, nil
+}
+
+// equivalentCommand rewrites args, an --interactive invocation of
+// command, into the one taking tables as --tables instead.
+func equivalentCommand(args []string, command string, tables []string) string {
+	out := []string{"seedmancer"}
+	at := -1 // where --tables goes
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--interactive" || a == "-interactive" || strings.HasPrefix(a, "--interactive="):
+			continue
+		case a == "--tables" || a == "-tables":
+			i++ // and its value
+			continue
+		case strings.HasPrefix(a, "--tables=") || strings.HasPrefix(a, "-tables="):
+			continue
+		}
+		out = append(out, shellQuote(a))
+		if a == command && at < 0 {
+			at = len(out)
+		}
+	}
+	// Flags after the first positional argument would be taken as more
+	// arguments, so --tables goes right after the subcommand.
+	if at < 0 {
+		at = len(out)
+	}
+	flag := []string{"--tables", shellQuote(strings.Join(tables, ","))}
+	return strings.Join(append(out[:at], append(flag, out[at:]...)...), " ")
+}
+
+// shellQuote single-quotes s for a POSIX shell when it holds anything
+// beyond the characters that are safe bare.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=.,/:@%+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// liveTables lists the tables of the database export would read from,
+// as export's schema.json would describe them.
+func liveTables(envName, dbURL string) ([]string, error) {
+	_, cfg, err := loadConfigOrAdHoc(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	target, err := pickExportTarget(cfg, envName, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	manager, normalizedURL, err := db.NewManager(target.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := manager.ConnectWithDSN(normalizedURL); err != nil {
+		return nil, fmt.Errorf("connecting to database: %v", err)
+	}
+	tmp, err := os.MkdirTemp("", "seedmancer-schema-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := manager.ExportSchema(tmp); err != nil {
+		return nil, fmt.Errorf("exporting schema: %v", err)
+	}
+	schema, err := db.ReadSchemaFile(filepath.Join(tmp, "schema.json"))
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]string, 0, len(schema.Tables))
+	for _, t := range schema.Tables {
+		tables = append(tables, t.Name)
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
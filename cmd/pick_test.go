@@ -0,0 +1,46 @@
+package cmd
+
+import "testing"
+
+func TestEquivalentCommand(t *testing.T) {
+	cases := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{
+			"export",
+			[]string{"/usr/local/bin/seedmancer", "export", "--interactive", "nightly"},
+			"seedmancer export --tables orders,users nightly",
+		},
+		{
+			"seed",
+			[]string{"seedmancer", "--debug", "seed", "--env", "staging", "--tables", "orders", "--interactive=true", "billing/pro"},
+			"seedmancer --debug seed --tables orders,users --env staging billing/pro",
+		},
+		{
+			"export",
+			[]string{"seedmancer", "export", "--description", "before the migration", "--interactive", "-tables=x", "nightly"},
+			"seedmancer export --tables orders,users --description 'before the migration' nightly",
+		},
+	}
+	for _, c := range cases {
+		if got := equivalentCommand(c.args, c.command, []string{"orders", "users"}); got != c.want {
+			t.Errorf("equivalentCommand(%q)\n got %s\nwant %s", c.args, got, c.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"billing/pro": "billing/pro",
+		"it's":        `'it'\''s'`,
+		"a b":         "'a b'",
+		"":            "''",
+		"users.id=42": "users.id=42",
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// releaseBuildTags are the tags release binaries are built with (see
+// .goreleaser.yml): the pure Go resolver and user lookup, so a static
+// binary behaves the same on glibc, musl and macOS, and an embedded time
+// zone database for hosts without tzdata.
+var releaseBuildTags = []string{"netgo", "osusergo", "timetzdata"}
+
+// PlatformCheckCommand checks that the compiled-in database drivers work
+// on this machine: the build, time zones, DNS, the CA store and, given a
+// database, a real connection with its collation.
+func PlatformCheckCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "platform-check",
+		Usage: "Check that the database drivers work on this machine",
+		Description: "Runs the checks that tell a platform problem apart from a fixture\n" +
+			"or database one — the kind that otherwise surface as a generic\n" +
+			"driver error on an ARM or Alpine CI runner:\n\n" +
+			"  build       OS, architecture, Go version, cgo and build tags\n" +
+			"  rosetta     an x86_64 binary running translated on Apple Silicon\n" +
+			"  libc        glibc or musl (Linux), which matters only with cgo\n" +
+			"  time zones  whether named zones load, for timestamptz values\n" +
+			"  dns         the resolver in use and a lookup of the database host\n" +
+			"  tls         whether the system CA store can be read\n" +
+			"  driver      a connection to the database, its version and\n" +
+			"              collation, and PostgreSQL collations whose library\n" +
+			"              version changed since they were created\n\n" +
+			"The database is picked like export's (--db-url, --env, then the\n" +
+			"default env); without one the driver check is skipped. The command\n" +
+			"fails when any check fails.\n\n" +
+			"Release binaries are static (CGO_ENABLED=0) and built with\n" +
+			"-tags " + strings.Join(releaseBuildTags, ",") + " for linux, macOS and Windows\n" +
+			"on x86_64 and arm64, plus linux/armv7. To build the same locally:\n\n" +
+			"  CGO_ENABLED=0 go build -trimpath -tags " + strings.Join(releaseBuildTags, ",") + " .",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "env",
+				Aliases: []string{"e"},
+				Usage:   "Named environment whose database to check",
+			},
+			&cli.StringFlag{
+				Name:  "db-url",
+				Usage: "Database URL to check (takes precedence over --env)",
+			},
+			outputFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			asJSON, err := jsonRequested(c)
+			if err != nil {
+				return err
+			}
+			dsn, err := platformCheckDSN(c)
+			if err != nil {
+				return err
+			}
+			checks := runPlatformChecks(c.Context, dsn)
+			failed := 0
+			for _, ch := range checks {
+				if ch.Status == checkFail {
+					failed++
+				}
+			}
+			if asJSON {
+				if err := outputJSON(checks); err != nil {
+					return err
+				}
+			} else {
+				for _, ch := range checks {
+					line := fmt.Sprintf("%-11s %s", ch.Name, ch.Detail)
+					switch ch.Status {
+					case checkOK:
+						ui.Success("%s", line)
+					case checkWarn:
+						ui.Warn("%s", line)
+					case checkFail:
+						ui.Error("%s", line)
+					default:
+						ui.Info("%s", line)
+					}
+					if ch.Hint != "" {
+						ui.Info("            %s", ch.Hint)
+					}
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d platform check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+}
+
+// Statuses of a platformCheck.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// platformCheck is one line of platform-check's report.
+type platformCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// platformCheckDSN is the database platform-check connects to, or ""
+// when none is configured. A missing seedmancer.yaml is fine; an --env
+// that doesn't resolve is not.
+func platformCheckDSN(c *cli.Context) (string, error) {
+	var cfg utils.Config
+	if path, err := utils.FindConfigFile(); err == nil {
+		if cfg, err = utils.LoadConfig(path); err != nil {
+			return "", err
+		}
+	}
+	target, err := resolveSingleDB(c, cfg)
+	if err != nil {
+		if c.IsSet("env") {
+			return "", err
+		}
+		return "", nil
+	}
+	return strings.TrimSpace(target.DatabaseURL), nil
+}
+
+// runPlatformChecks runs every check, connecting to dsn when it isn't "".
+func runPlatformChecks(ctx context.Context, dsn string) []platformCheck {
+	info, _ := debug.ReadBuildInfo()
+	checks := []platformCheck{buildCheck(info)}
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "amd64" {
+		checks = append(checks, rosettaCheck())
+	}
+	if runtime.GOOS == "linux" {
+		checks = append(checks, libcCheck(info))
+	}
+	checks = append(checks, tzCheck(), dnsCheck(ctx, info, dsn), tlsCheck())
+	return append(checks, driverCheck(ctx, dsn))
+}
+
+// buildSetting returns the value of key in info's build settings.
+func buildSetting(info *debug.BuildInfo, key string) string {
+	if info == nil {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+func buildCheck(info *debug.BuildInfo) platformCheck {
+	detail := fmt.Sprintf("%s/%s, %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
+	if cgo := buildSetting(info, "CGO_ENABLED"); cgo != "" {
+		detail += ", CGO_ENABLED=" + cgo
+	}
+	tags := buildSetting(info, "-tags")
+	if tags != "" {
+		detail += ", tags " + tags
+	}
+	ch := platformCheck{Name: "build", Status: checkOK, Detail: detail}
+	have := map[string]bool{}
+	for _, t := range strings.Split(tags, ",") {
+		have[strings.TrimSpace(t)] = true
+	}
+	var missing []string
+	for _, t := range releaseBuildTags {
+		if !have[t] {
+			missing = append(missing, t)
+		}
+	}
+	if len(missing) > 0 {
+		ch.Status = checkWarn
+		ch.Hint = "not a release build (no " + strings.Join(missing, ", ") + "); behaviour may differ from the published binaries"
+	}
+	return ch
+}
+
+func rosettaCheck() platformCheck {
+	out, err := exec.Command("sysctl", "-n", "sysctl.proc_translated").Output()
+	if err == nil && strings.TrimSpace(string(out)) == "1" {
+		return platformCheck{Name: "rosetta", Status: checkWarn,
+			Detail: "x86_64 binary running under Rosetta on Apple Silicon",
+			Hint:   "install the arm64 build; translated binaries are slower and hide native driver issues"}
+	}
+	return platformCheck{Name: "rosetta", Status: checkOK, Detail: "native"}
+}
+
+func libcCheck(info *debug.BuildInfo) platformCheck {
+	libc := "glibc"
+	if musl, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(musl) > 0 {
+		libc = "musl"
+	} else if glibc, _ := filepath.Glob("/lib*/ld-linux*.so.*"); len(glibc) == 0 {
+		libc = "none found"
+	}
+	if buildSetting(info, "CGO_ENABLED") == "1" {
+		return platformCheck{Name: "libc", Status: checkWarn, Detail: libc + ", binary linked against it (cgo)",
+			Hint: "a cgo build only runs on the libc it was built for; the static release build runs on any"}
+	}
+	return platformCheck{Name: "libc", Status: checkOK, Detail: libc + ", not used (static binary)"}
+}
+
+func tzCheck() platformCheck {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		return platformCheck{Name: "time zones", Status: checkFail, Detail: err.Error(),
+			Hint: "install tzdata, or use a release build, which embeds the zone database"}
+	}
+	return platformCheck{Name: "time zones", Status: checkOK, Detail: "named zones load"}
+}
+
+// dsnHost is the host dsn connects to, or "".
+func dsnHost(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func dnsCheck(ctx context.Context, info *debug.BuildInfo, dsn string) platformCheck {
+	resolver := "Go resolver"
+	if buildSetting(info, "CGO_ENABLED") == "1" && !strings.Contains(buildSetting(info, "-tags"), "netgo") {
+		resolver = "system resolver (cgo)"
+	}
+	ch := platformCheck{Name: "dns", Status: checkOK}
+	if runtime.GOOS != "windows" {
+		if _, err := os.Stat("/etc/resolv.conf"); err != nil {
+			ch.Status = checkWarn
+			ch.Hint = "no /etc/resolv.conf; the Go resolver falls back to a DNS server on localhost"
+		}
+	}
+	host := dsnHost(dsn)
+	if host == "" {
+		ch.Detail = resolver + "; no database host to look up"
+		return ch
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		ch.Detail = resolver + "; " + host + " is an address"
+		return ch
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(lookupCtx, host)
+	if err != nil {
+		return platformCheck{Name: "dns", Status: checkFail, Detail: resolver + "; " + err.Error(),
+			Hint: "check /etc/resolv.conf and /etc/hosts; names a container network provides need its DNS server"}
+	}
+	ch.Detail = fmt.Sprintf("%s; %s → %s (%s)", resolver, host, strings.Join(addrs, ", "), time.Since(start).Round(time.Millisecond))
+	return ch
+}
+
+func tlsCheck() platformCheck {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return platformCheck{Name: "tls", Status: checkFail, Detail: "system CA store unreadable: " + err.Error(),
+			Hint: "install ca-certificates; sslmode=verify-full and TLS MySQL connections need it"}
+	}
+	if runtime.GOOS != "windows" && pool.Equal(x509.NewCertPool()) {
+		return platformCheck{Name: "tls", Status: checkFail, Detail: "system CA store is empty",
+			Hint: "install ca-certificates, or point SSL_CERT_FILE at a CA bundle"}
+	}
+	return platformCheck{Name: "tls", Status: checkOK, Detail: "system CA store loaded"}
+}
+
+func driverCheck(ctx context.Context, dsn string) platformCheck {
+	if dsn == "" {
+		return platformCheck{Name: "driver", Status: checkSkip, Detail: "no database configured; pass --db-url or --env"}
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	probe, err := db.ProbeDriver(probeCtx, dsn)
+	if err != nil {
+		return platformCheck{Name: "driver", Status: checkFail, Detail: err.Error(), Hint: driverHint(err)}
+	}
+	detail := fmt.Sprintf("%s: %s", probe.Engine, firstLine(probe.Version))
+	if probe.Collation != "" {
+		detail += ", collation " + probe.Collation
+	}
+	ch := platformCheck{Name: "driver", Status: checkOK, Detail: detail}
+	if len(probe.StaleCollations) > 0 {
+		ch.Status = checkWarn
+		ch.Hint = fmt.Sprintf("collation version changed for %s; REINDEX text indexes using them and ALTER COLLATION ... REFRESH VERSION",
+			strings.Join(probe.StaleCollations, ", "))
+	}
+	return ch
+}
+
+// driverHint names the likely platform cause of a driver error, or "".
+func driverHint(err error) string {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "x509"), strings.Contains(msg, "certificate"):
+		return "the server's certificate isn't trusted here: install ca-certificates or set sslrootcert; sslmode=require skips verification"
+	case strings.Contains(msg, "no such host"), strings.Contains(msg, "server misbehaving"):
+		return "the database host doesn't resolve on this machine; see the dns check"
+	case strings.Contains(msg, "unknown collation"):
+		return "the server doesn't know the driver's default collation; add ?collation=utf8mb4_general_ci to the URL"
+	case strings.Contains(msg, "ssl is not enabled"):
+		return "the server doesn't do TLS; add sslmode=disable to the URL"
+	case strings.Contains(msg, "connection refused"):
+		return "nothing listens on that host and port; is the database running and published to this network?"
+	case strings.Contains(msg, "i/o timeout"), strings.Contains(msg, "deadline exceeded"):
+		return "the connection timed out; a firewall or the wrong network (e.g. a container's localhost) is likely"
+	}
+	return ""
+}
+
+// firstLine is s up to its first newline.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
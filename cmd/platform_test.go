@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"errors"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestBuildCheck_flagsNonReleaseBuilds(t *testing.T) {
+	release := &debug.BuildInfo{Settings: []debug.BuildSetting{
+		{Key: "CGO_ENABLED", Value: "0"},
+		{Key: "-tags", Value: "netgo,osusergo,timetzdata"},
+	}}
+	if ch := buildCheck(release); ch.Status != checkOK {
+		t.Errorf("release build: %+v", ch)
+	}
+	dev := &debug.BuildInfo{Settings: []debug.BuildSetting{{Key: "CGO_ENABLED", Value: "1"}}}
+	ch := buildCheck(dev)
+	if ch.Status != checkWarn || !strings.Contains(ch.Hint, "netgo, osusergo, timetzdata") {
+		t.Errorf("dev build: %+v", ch)
+	}
+}
+
+func TestDriverHint(t *testing.T) {
+	cases := map[string]string{
+		"tls: failed to verify certificate: x509: certificate signed by unknown authority": "ca-certificates",
+		"dial tcp: lookup db.internal on 127.0.0.11:53: no such host":                      "dns check",
+		"Error 1273 (HY000): Unknown collation: 'utf8mb4_0900_ai_ci'":                      "collation=",
+		"dial tcp 127.0.0.1:5432: connect: connection refused":                             "nothing listens",
+		"pq: SSL is not enabled on the server":                                             "sslmode=disable",
+	}
+	for msg, want := range cases {
+		if got := driverHint(errors.New(msg)); !strings.Contains(got, want) {
+			t.Errorf("driverHint(%q) = %q, want it to mention %q", msg, got, want)
+		}
+	}
+	if got := driverHint(errors.New("pq: password authentication failed")); got != "" {
+		t.Errorf("auth failure got a platform hint: %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// ProfileCommand lists and selects the named remote profiles defined under
+// `profiles:` in seedmancer.yaml or ~/.seedmancer/config.yaml. Profiles
+// themselves are edited in YAML; this command only shows what --profile
+// can pick and sets default_profile, the same way `env use` sets
+// default_env.
+func ProfileCommand() *cli.Command {
+	return &cli.Command{
+		Name:            "profile",
+		Usage:           "List and select named API profiles (self-hosted vs. hosted, per-org tokens)",
+		HideHelpCommand: true,
+		Description: "A profile bundles an API URL, dashboard URL, token variable and default\n" +
+			"project/env under a name, so one machine can talk to a self-hosted\n" +
+			"Seedmancer API for some projects and the hosted one for others:\n\n" +
+			"  profiles:\n" +
+			"    prod-api:\n" +
+			"      api_url: https://seedmancer.internal.example.com\n" +
+			"      dashboard_url: https://seedmancer.internal.example.com\n" +
+			"      token_env: SEEDMANCER_PROD_TOKEN\n" +
+			"    staging-api:\n" +
+			"      api_url: https://api.seedmancer.dev\n\n" +
+			"Pick one per command with `seedmancer --profile prod-api <command>`\n" +
+			"(or SEEDMANCER_PROFILE), or make it the default with\n" +
+			"`seedmancer profile use prod-api`. `seedmancer --profile <name> login`\n" +
+			"saves a token for that profile only.",
+		Subcommands: []*cli.Command{
+			profileListCommand(),
+			profileUseCommand(),
+		},
+	}
+}
+
+func profileListCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "list",
+		Usage:     "List the profiles --profile can select",
+		ArgsUsage: " ",
+		Action: func(c *cli.Context) error {
+			entries, defaultName := utils.ListProfiles()
+			if len(entries) == 0 {
+				ui.Info("No profiles configured. Add a `profiles:` section to seedmancer.yaml")
+				ui.Info("or ~/.seedmancer/config.yaml (see `seedmancer profile --help`).")
+				return nil
+			}
+			active, _, _ := utils.ActiveProfile()
+
+			fmt.Fprintln(os.Stderr)
+			for _, e := range entries {
+				marker := "  "
+				if e.Name == active {
+					marker = " *"
+				}
+				fmt.Fprintf(os.Stderr, "%s %-14s %s  token: %s\n",
+					marker,
+					e.Name,
+					profileAPIURL(e.Profile),
+					profileTokenSource(e.Name, e.Profile),
+				)
+				ui.Debug("profile %s defined in %s", e.Name, e.Source)
+			}
+			fmt.Fprintln(os.Stderr)
+			if defaultName != "" {
+				ui.Info("Default: %s  (change with `seedmancer profile use <name>`)", defaultName)
+			}
+			return nil
+		},
+	}
+}
+
+func profileUseCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "use",
+		Usage:     "Set default_profile in seedmancer.yaml",
+		ArgsUsage: "<name>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return usageError(c, "expected exactly one argument: <name>")
+			}
+			name := strings.TrimSpace(c.Args().First())
+			if err := utils.ValidateProfileName(name); err != nil {
+				return err
+			}
+			path, cfg, err := loadConfigForEnvCmd()
+			if err != nil {
+				return err
+			}
+			entries, _ := utils.ListProfiles()
+			found := false
+			var known []string
+			for _, e := range entries {
+				known = append(known, e.Name)
+				if e.Name == name {
+					found = true
+				}
+			}
+			if !found {
+				if len(known) == 0 {
+					return fmt.Errorf("unknown profile %q: no profiles are defined in seedmancer.yaml or ~/.seedmancer/config.yaml", name)
+				}
+				return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(known, ", "))
+			}
+			cfg.DefaultProfile = name
+			if err := utils.SaveConfig(path, cfg); err != nil {
+				return err
+			}
+			ui.Success("Default profile set to %q", name)
+			return nil
+		},
+	}
+}
+
+// profileAPIURL is the API origin commands reach under p.
+func profileAPIURL(p utils.Profile) string {
+	if v := strings.TrimSpace(p.APIURL); v != "" {
+		return strings.TrimRight(v, "/")
+	}
+	return "(default API)"
+}
+
+// profileTokenSource describes where a token comes from under the named
+// profile once --token and SEEDMANCER_API_TOKEN are out of the picture.
+func profileTokenSource(name string, p utils.Profile) string {
+	creds := "~/.seedmancer/credentials-" + name
+	if v := strings.TrimSpace(p.TokenEnv); v != "" {
+		if os.Getenv(v) != "" {
+			return v + " (set)"
+		}
+		return v + " (unset), then " + creds
+	}
+	return creds
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/subset"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// PruneCommand derives a slim variant of a scenario: only the kept tables
+// in full, plus the parent rows they reference through foreign keys. The
+// result lands as a new revision of the --as scenario, so re-running prune
+// after the full scenario changes keeps the slim one up to date.
+//
+//	seedmancer prune full --keep users,orders,order_items --as slim
+func PruneCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "prune",
+		Usage:     "Derive a slim scenario holding only some tables (plus FK parents)",
+		ArgsUsage: "<scenario>",
+		Description: "Copies the --keep tables of a revision (latest by default) into a new\n" +
+			"revision of the --as scenario. Parent tables reached through foreign\n" +
+			"keys are added automatically, filtered to the rows the kept tables\n" +
+			"reference, so the slim variant seeds without FK violations. Tables\n" +
+			"marked static: true in seedmancer.yaml are always kept in full.\n\n" +
+			"The schema is unchanged; tables without rows in the slim variant are\n" +
+			"simply left empty at seed time.\n\n" +
+			"Examples:\n" +
+			"  seedmancer prune full --keep users,orders,order_items --as slim\n" +
+			"  seedmancer prune full --keep invoices --as billing/slim --revision r004",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "keep",
+				Usage:    "Comma-separated tables to keep in full",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "as",
+				Usage:    "Scenario that receives the slim revision",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "revision",
+				Usage: "Revision of <scenario> to prune (defaults to latest)",
+			},
+			&cli.StringFlag{
+				Name:  "description",
+				Usage: "Optional description stored on the new revision manifest",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			out, err := RunPrune(context.Background(), PruneInput{
+				Scenario:    scenarioArg,
+				Revision:    strings.TrimSpace(c.String("revision")),
+				Keep:        c.String("keep"),
+				As:          strings.TrimSpace(c.String("as")),
+				Description: strings.TrimSpace(c.String("description")),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+			ui.Success("Pruned %s @ %s → %s @ %s", out.From, out.FromRevision, out.Scenario, out.Revision)
+			parts := make([]string, 0, len(out.Kept))
+			for _, t := range out.Kept {
+				parts = append(parts, fmt.Sprintf("%s(%d)", t, out.RowCounts[t]))
+			}
+			ui.KeyValue("Kept: ", strings.Join(parts, ", "))
+			if len(out.Parents) > 0 {
+				parts = parts[:0]
+				for _, t := range out.Parents {
+					parts = append(parts, fmt.Sprintf("%s(%d)", t, out.RowCounts[t]))
+				}
+				ui.KeyValue("FK parents: ", strings.Join(parts, ", "))
+			}
+			ui.KeyValue("Run: ", fmt.Sprintf("seedmancer seed %s", out.Scenario))
+			return nil
+		},
+	}
+}
+
+// PruneInput selects the source revision, the tables to keep, and the
+// scenario the slim revision is written to.
+type PruneInput struct {
+	Scenario    string `json:"scenario" jsonschema:"Source scenario path"`
+	Revision    string `json:"revision,omitempty" jsonschema:"Source revision (defaults to latest)"`
+	Keep        string `json:"keep" jsonschema:"Comma-separated tables to keep in full; FK parent rows are added automatically"`
+	As          string `json:"as" jsonschema:"Scenario path that receives the slim revision"`
+	Description string `json:"description,omitempty" jsonschema:"Optional description stored on the new revision manifest"`
+}
+
+// PruneOutput reports the slim revision and what went into it.
+type PruneOutput struct {
+	From         string         `json:"from"`
+	FromRevision string         `json:"fromRevision"`
+	Scenario     string         `json:"scenario"`
+	Revision     string         `json:"revision"`
+	Kept         []string       `json:"kept"`
+	Parents      []string       `json:"parents,omitempty"`
+	RowCounts    map[string]int `json:"rowCounts"`
+	Path         string         `json:"path"`
+}
+
+// RunPrune writes the FK-closed subset of a revision as a new revision of
+// the target scenario.
+func RunPrune(_ context.Context, in PruneInput) (PruneOutput, error) {
+	keep := splitCSVList(in.Keep)
+	if len(keep) == 0 {
+		return PruneOutput{}, fmt.Errorf("keep cannot be empty")
+	}
+
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return PruneOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return PruneOutput{}, err
+	}
+	fromPath, err := scenario.Normalize(in.Scenario)
+	if err != nil {
+		return PruneOutput{}, err
+	}
+	toPath, err := scenario.Normalize(in.As)
+	if err != nil {
+		return PruneOutput{}, fmt.Errorf("invalid --as scenario: %w", err)
+	}
+	if fromPath == toPath {
+		return PruneOutput{}, fmt.Errorf("--as must name a different scenario than %s", fromPath)
+	}
+
+	src, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, fromPath, in.Revision)
+	if err != nil {
+		return PruneOutput{}, err
+	}
+	schema, err := loadRevisionSchema(projectRoot, cfg.StoragePath, src)
+	if err != nil {
+		return PruneOutput{}, err
+	}
+	// Static reference tables are kept whole whatever --keep names.
+	for _, t := range schema.Tables {
+		if cfg.Tables[t.Name].Static && !slices.Contains(keep, t.Name) {
+			keep = append(keep, t.Name)
+		}
+	}
+	plan, err := subset.Closure(schema, keep)
+	if err != nil {
+		return PruneOutput{}, err
+	}
+
+	staging, err := newStagingRevision(projectRoot, cfg.StoragePath, toPath, "prune")
+	if err != nil {
+		return PruneOutput{}, err
+	}
+	defer os.RemoveAll(staging)
+
+	dataDir := filepath.Join(staging, "data")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return PruneOutput{}, err
+	}
+	if _, err := subset.WriteSubset(src.DataDir, dataDir, schema, plan); err != nil {
+		return PruneOutput{}, err
+	}
+	tables, rowCounts, err := listCSVTablesAndRowCounts(dataDir)
+	if err != nil {
+		return PruneOutput{}, err
+	}
+
+	description := strings.TrimSpace(in.Description)
+	if description == "" {
+		description = fmt.Sprintf("pruned from %s @ %s (%s)", fromPath, src.RevID, strings.Join(plan.Selected, ", "))
+	}
+	manifest, err := commitStagedRevision(projectRoot, cfg.StoragePath, toPath, staging, scenario.RevisionManifest{
+		SchemaFingerprint: src.Manifest.SchemaFingerprint,
+		Source:            "prune",
+		Tables:            tables,
+		Services:          src.Manifest.Services,
+		RowCounts:         rowCounts,
+		Description:       description,
+		DatabaseType:      src.Manifest.DatabaseType,
+		SkippedTables:     src.Manifest.SkippedTables,
+		Filters:           src.Manifest.Filters,
+		Excluded:          src.Manifest.Excluded,
+	}, src.ScenarioManifest.Prompt)
+	if err != nil {
+		return PruneOutput{}, err
+	}
+
+	return PruneOutput{
+		From:         fromPath,
+		FromRevision: src.RevID,
+		Scenario:     toPath,
+		Revision:     manifest.Revision,
+		Kept:         plan.Selected,
+		Parents:      plan.Parents,
+		RowCounts:    rowCounts,
+		Path:         scenario.RevisionDataDir(projectRoot, cfg.StoragePath, toPath, manifest.Revision),
+	}, nil
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+)
+
+func TestRunPrune_keepsTablesAndReferencedParents(t *testing.T) {
+	const schema = `{"tables":[
+	  {"name":"users","columns":[{"name":"id","type":"integer"}]},
+	  {"name":"orders","columns":[{"name":"id","type":"integer"},{"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}}]},
+	  {"name":"audit_log","columns":[{"name":"id","type":"integer"}]}
+	]}`
+	dir := stageRevision(t, "full", schema, map[string]string{
+		"users":     "id\n1\n2\n3\n",
+		"orders":    "id,user_id\n10,1\n11,3\n",
+		"audit_log": "id\n1\n2\n",
+	})
+
+	out, err := RunPrune(context.Background(), PruneInput{Scenario: "full", Keep: "orders", As: "slim"})
+	if err != nil {
+		t.Fatalf("RunPrune: %v", err)
+	}
+	if out.Scenario != "slim" || out.Revision != "r001" {
+		t.Fatalf("out = %+v", out)
+	}
+	if strings.Join(out.Kept, ",") != "orders" || strings.Join(out.Parents, ",") != "users" {
+		t.Fatalf("kept=%v parents=%v", out.Kept, out.Parents)
+	}
+	users, err := os.ReadFile(filepath.Join(out.Path, "users.csv"))
+	if err != nil || string(users) != "id\n1\n3\n" {
+		t.Fatalf("users.csv = %q (err %v)", users, err)
+	}
+	if fileExists(filepath.Join(out.Path, "audit_log.csv")) {
+		t.Fatal("audit_log.csv should not be in the slim revision")
+	}
+	rm, err := scenario.ReadRevisionManifest(scenario.RevisionDir(dir, ".seedmancer", "slim", "r001"))
+	if err != nil || rm.Source != "prune" || rm.SchemaFingerprint != strings.Repeat("ab", 32) {
+		t.Fatalf("revision manifest = %+v (err %v)", rm, err)
+	}
+
+	// Re-running refreshes the slim scenario with a new revision.
+	out, err = RunPrune(context.Background(), PruneInput{Scenario: "full", Keep: "orders", As: "slim"})
+	if err != nil || out.Revision != "r002" {
+		t.Fatalf("second prune = %+v (err %v), want r002", out, err)
+	}
+}
+
+func TestRunPrune_readsShardedTables(t *testing.T) {
+	const schema = `{"tables":[
+	  {"name":"users","columns":[{"name":"id","type":"integer"}]},
+	  {"name":"orders","columns":[{"name":"id","type":"integer"},{"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}}]}
+	]}`
+	dir := stageRevision(t, "full", schema, map[string]string{"users": "", "orders": ""})
+	shardTable(t, dir, "full", "users", "id\n1\n2\n", "id\n3\n")
+	shardTable(t, dir, "full", "orders", "id,user_id\n10,1\n", "id,user_id\n11,3\n")
+
+	out, err := RunPrune(context.Background(), PruneInput{Scenario: "full", Keep: "orders", As: "slim"})
+	if err != nil {
+		t.Fatalf("RunPrune: %v", err)
+	}
+	orders, err := os.ReadFile(filepath.Join(out.Path, "orders.csv"))
+	if err != nil || string(orders) != "id,user_id\n10,1\n11,3\n" {
+		t.Fatalf("orders.csv = %q (err %v)", orders, err)
+	}
+	users, err := os.ReadFile(filepath.Join(out.Path, "users.csv"))
+	if err != nil || string(users) != "id\n1\n3\n" {
+		t.Fatalf("users.csv = %q (err %v)", users, err)
+	}
+}
+
+func TestRunPrune_keepsStaticTablesWhole(t *testing.T) {
+	const schema = `{"tables":[
+	  {"name":"countries","columns":[{"name":"code","type":"text"}]},
+	  {"name":"users","columns":[{"name":"id","type":"integer"},{"name":"country","type":"text","foreignKey":{"table":"countries","column":"code"}}]},
+	  {"name":"orders","columns":[{"name":"id","type":"integer"},{"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}}]},
+	  {"name":"currencies","columns":[{"name":"code","type":"text"}]}
+	]}`
+	dir := stageRevision(t, "full", schema, map[string]string{
+		"countries":  "code\nDE\nFR\nJP\n",
+		"users":      "id,country\n1,DE\n2,FR\n",
+		"orders":     "id,user_id\n10,1\n",
+		"currencies": "code\nEUR\nJPY\n",
+	})
+	writeFile(t, filepath.Join(dir, "seedmancer.yaml"), "storage_path: .seedmancer\ntables:\n  countries:\n    static: true\n  currencies:\n    static: true\n")
+
+	out, err := RunPrune(context.Background(), PruneInput{Scenario: "full", Keep: "orders", As: "slim"})
+	if err != nil {
+		t.Fatalf("RunPrune: %v", err)
+	}
+	for table, want := range map[string]string{
+		"countries":  "code\nDE\nFR\nJP\n",
+		"currencies": "code\nEUR\nJPY\n",
+		"users":      "id,country\n1,DE\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(out.Path, table+".csv"))
+		if err != nil || string(got) != want {
+			t.Errorf("%s.csv = %q (err %v), want %q", table, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,512 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/patch"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// recordingFile holds a recording's metadata inside its recording dir.
+const recordingFile = "recording.json"
+
+// recording is what `record start` leaves behind for `record stop`.
+type recording struct {
+	Name              string    `json:"name"`
+	Env               string    `json:"env"`
+	Scenario          string    `json:"scenario,omitempty"`
+	Revision          string    `json:"revision,omitempty"`
+	Description       string    `json:"description,omitempty"`
+	SchemaFingerprint string    `json:"schemaFingerprint"`
+	StartedAt         time.Time `json:"startedAt"`
+}
+
+// RecordCommand captures the writes of a manual test session as a named
+// patch that `seed --patch` replays on top of a revision.
+//
+//	seedmancer record start checkout-refund --scenario billing/pro
+//	... click through the app ...
+//	seedmancer record stop checkout-refund
+//	seedmancer seed billing/pro --patch checkout-refund
+func RecordCommand() *cli.Command {
+	return &cli.Command{
+		Name:            "record",
+		Usage:           "Record what a test session writes to the database as a reusable patch",
+		HideHelpCommand: true,
+		Description: "`record start <patch>` exports the target database as it is now.\n" +
+			"Use the application against it — sign up, place orders, whatever the\n" +
+			"session needs — then run `record stop <patch>`: the database is\n" +
+			"exported again and the rows that were inserted, updated or deleted\n" +
+			"are saved as a patch under <storagePath>/patches/<patch>/.\n\n" +
+			"`seed <scenario> --patch <patch>` loads the scenario with the patch\n" +
+			"applied on top, so the state an exploratory session ended in becomes\n" +
+			"a fixture anyone can reproduce:\n\n" +
+			"  seedmancer seed billing/pro\n" +
+			"  seedmancer record start refund-flow --scenario billing/pro\n" +
+			"  ... manual testing ...\n" +
+			"  seedmancer record stop refund-flow\n" +
+			"  seedmancer seed billing/pro --patch refund-flow\n\n" +
+			"Rows are matched on their primary key, or on every column for tables\n" +
+			"without one. The schema must not change during a recording.\n\n" +
+			"Columns listed under diff_ignore_columns in seedmancer.yaml (volatile\n" +
+			"ones such as updated_at or etags) don't count as a change, so a row\n" +
+			"only they differ in stays out of the patch. Listing an auto-increment\n" +
+			"id matches rows on their other columns instead:\n\n" +
+			"  diff_ignore_columns: [updated_at, etag, audit_log.id]",
+		Subcommands: []*cli.Command{
+			recordStartCommand(),
+			recordStopCommand(),
+			recordDiscardCommand(),
+			recordListCommand(),
+		},
+	}
+}
+
+func recordTargetFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "env",
+			Aliases: []string{"e"},
+			Usage:   "Named environment to record (defaults to default_env)",
+		},
+		&cli.StringFlag{
+			Name:  "db-url",
+			Usage: "Ad-hoc database URL (takes precedence over --env)",
+		},
+	}
+}
+
+func recordStartCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "start",
+		Usage:     "Export the database as the starting point of a recording",
+		ArgsUsage: "<patch>",
+		Flags: append(recordTargetFlags(),
+			&cli.StringFlag{
+				Name:  "scenario",
+				Usage: "Scenario the database currently holds, noted on the patch",
+			},
+			&cli.StringFlag{
+				Name:    "revision",
+				Aliases: []string{"r"},
+				Usage:   "Revision of --scenario the database holds (default: latest)",
+			},
+			&cli.StringFlag{
+				Name:    "description",
+				Aliases: []string{"m"},
+				Usage:   "What the session is meant to capture",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Overwrite an existing patch or unfinished recording of the same name",
+			},
+		),
+		Action: func(c *cli.Context) error {
+			name := strings.TrimSpace(c.Args().First())
+			if name == "" {
+				return usageError(c, "missing required argument: <patch>")
+			}
+			if err := patch.ValidateName(name); err != nil {
+				return err
+			}
+			projectRoot, cfg, err := loadProject()
+			if err != nil {
+				return err
+			}
+			recDir := patch.RecordingDir(projectRoot, cfg.StoragePath, name)
+			if !c.Bool("force") {
+				if _, err := os.Stat(recDir); err == nil {
+					return fmt.Errorf("a recording of %q is already running — stop it with `seedmancer record stop %s` or pass --force", name, name)
+				}
+				if _, err := os.Stat(patch.Dir(projectRoot, cfg.StoragePath, name)); err == nil {
+					return fmt.Errorf("patch %q already exists — pass --force to record it again", name)
+				}
+			}
+
+			rec := recording{Name: name, Description: strings.TrimSpace(c.String("description")), StartedAt: time.Now().UTC()}
+			if s := strings.TrimSpace(c.String("scenario")); s != "" {
+				rev, err := resolveSeedRevision(projectRoot, cfg.StoragePath, s, c.String("revision"), false)
+				if err != nil {
+					return err
+				}
+				rec.Scenario, rec.Revision = rev.Scenario, rev.RevID
+			}
+			target, err := resolveSingleDB(c, cfg)
+			if err != nil {
+				return err
+			}
+			rec.Env = target.Name
+
+			if err := os.RemoveAll(recDir); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(recDir, 0755); err != nil {
+				return fmt.Errorf("creating recording directory: %v", err)
+			}
+			ui.Step("Exporting %s...", targetDisplay(target))
+			fp, err := exportForPatch(target, recDir)
+			if err != nil {
+				_ = os.RemoveAll(recDir)
+				return err
+			}
+			rec.SchemaFingerprint = fp
+			if err := writeRecording(recDir, rec); err != nil {
+				_ = os.RemoveAll(recDir)
+				return err
+			}
+			ui.Success("Recording %q on %s", name, targetDisplay(target))
+			ui.Info("Run your test session, then: seedmancer record stop %s", name)
+			return nil
+		},
+	}
+}
+
+func recordStopCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "stop",
+		Usage:     "Export the database again and save the difference as a patch",
+		ArgsUsage: "[patch]",
+		Flags:     recordTargetFlags(),
+		Action: func(c *cli.Context) error {
+			projectRoot, cfg, err := loadProject()
+			if err != nil {
+				return err
+			}
+			name, err := pickRecording(projectRoot, cfg.StoragePath, strings.TrimSpace(c.Args().First()))
+			if err != nil {
+				return err
+			}
+			recDir := patch.RecordingDir(projectRoot, cfg.StoragePath, name)
+			rec, err := readRecording(recDir)
+			if err != nil {
+				return err
+			}
+			if !c.IsSet("env") && !c.IsSet("db-url") && rec.Env != adHocEnvName {
+				if err := c.Set("env", rec.Env); err != nil {
+					return err
+				}
+			}
+			target, err := resolveSingleDB(c, cfg)
+			if err != nil {
+				return err
+			}
+
+			after, err := os.MkdirTemp("", "seedmancer-record-*")
+			if err != nil {
+				return fmt.Errorf("creating temp dir: %v", err)
+			}
+			defer os.RemoveAll(after)
+			ui.Step("Exporting %s...", targetDisplay(target))
+			fp, err := exportForPatch(target, after)
+			if err != nil {
+				return err
+			}
+			if fp != rec.SchemaFingerprint {
+				return fmt.Errorf("the schema of %s changed during the recording (%s → %s); a patch can only hold row changes",
+					targetDisplay(target), utils.FingerprintShort(rec.SchemaFingerprint), utils.FingerprintShort(fp))
+			}
+
+			schema, err := utils.ReadSchemaJSON(filepath.Join(after, "schema.json"))
+			if err != nil {
+				return err
+			}
+			staged, err := os.MkdirTemp(filepath.Join(projectRoot, cfg.StoragePath), ".patch-*")
+			if err != nil {
+				return fmt.Errorf("creating patch directory: %v", err)
+			}
+			defer os.RemoveAll(staged)
+			changes, err := patch.Diff(recDir, after, staged, schema, cfg.DiffIgnoreFunc())
+			if err != nil {
+				return err
+			}
+			if changes == nil {
+				changes = []patch.TableChange{}
+			}
+			m := patch.Manifest{
+				Name:              name,
+				Scenario:          rec.Scenario,
+				Revision:          rec.Revision,
+				SchemaFingerprint: fp,
+				CreatedAt:         time.Now().UTC(),
+				Description:       rec.Description,
+				Tables:            changes,
+			}
+			if err := patch.WriteManifest(staged, m); err != nil {
+				return err
+			}
+			dir := patch.Dir(projectRoot, cfg.StoragePath, name)
+			if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+				return err
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+			if err := os.Rename(staged, dir); err != nil {
+				return fmt.Errorf("saving patch: %v", err)
+			}
+			if err := os.RemoveAll(recDir); err != nil {
+				ui.Warn("Could not remove the recording: %v", err)
+			}
+
+			if len(changes) == 0 {
+				ui.Warn("No rows changed during the recording; saved an empty patch %q", name)
+				return nil
+			}
+			ui.Success("Saved patch %q", name)
+			printPatchChanges(changes)
+			if rec.Scenario != "" {
+				ui.Info("Replay it with: seedmancer seed %s --patch %s", rec.Scenario, name)
+			} else {
+				ui.Info("Replay it with: seedmancer seed <scenario> --patch %s", name)
+			}
+			return nil
+		},
+	}
+}
+
+func recordDiscardCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "discard",
+		Usage:     "Abandon a recording without saving a patch",
+		ArgsUsage: "[patch]",
+		Action: func(c *cli.Context) error {
+			projectRoot, cfg, err := loadProject()
+			if err != nil {
+				return err
+			}
+			name, err := pickRecording(projectRoot, cfg.StoragePath, strings.TrimSpace(c.Args().First()))
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(patch.RecordingDir(projectRoot, cfg.StoragePath, name)); err != nil {
+				return err
+			}
+			ui.Success("Discarded the recording of %q", name)
+			return nil
+		},
+	}
+}
+
+func recordListCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "list",
+		Usage:     "List saved patches and running recordings",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Emit result as JSON",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			projectRoot, cfg, err := loadProject()
+			if err != nil {
+				return err
+			}
+			patches, err := listPatches(projectRoot, cfg.StoragePath)
+			if err != nil {
+				return err
+			}
+			running, err := listDirNames(filepath.Join(projectRoot, cfg.StoragePath, "recordings"))
+			if err != nil {
+				return err
+			}
+			if c.Bool("json") {
+				return outputJSON(struct {
+					Patches    []patch.Manifest `json:"patches"`
+					Recordings []string         `json:"recordings"`
+				}{Patches: patches, Recordings: running})
+			}
+			if len(patches) == 0 && len(running) == 0 {
+				ui.Info("No patches yet. Record one with: seedmancer record start <patch>")
+				return nil
+			}
+			if len(patches) > 0 {
+				ui.Title("Patches")
+				for _, m := range patches {
+					base := "-"
+					if m.Scenario != "" {
+						base = m.Scenario + " @ " + m.Revision
+					}
+					ins, upd, del := 0, 0, 0
+					for _, t := range m.Tables {
+						ins, upd, del = ins+t.Inserted, upd+t.Updated, del+t.Deleted
+					}
+					ui.Info("  %-24s %-24s +%d ~%d -%d  %s", m.Name, base, ins, upd, del, m.CreatedAt.Local().Format("2006-01-02 15:04"))
+				}
+			}
+			if len(running) > 0 {
+				ui.Title("Recording")
+				for _, n := range running {
+					ui.Info("  %s", n)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// applyPatches applies the named patches, in order, to the table CSVs in
+// restoreDir (a staged copy of a revision). A patch recorded against a
+// different schema is applied anyway, with a warning.
+func applyPatches(projectRoot, storagePath string, names []string, restoreDir string, rev resolvedRevision) error {
+	for _, name := range names {
+		if err := patch.ValidateName(name); err != nil {
+			return err
+		}
+		dir := patch.Dir(projectRoot, storagePath, name)
+		m, err := patch.ReadManifest(dir)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no patch %q — record one with `seedmancer record start %s`", name, name)
+		}
+		if err != nil {
+			return fmt.Errorf("patch %s: %w", name, err)
+		}
+		if m.SchemaFingerprint != rev.Manifest.SchemaFingerprint {
+			ui.Warn("Patch %s was recorded on schema %s but %s @ %s has schema %s",
+				name, utils.FingerprintShort(m.SchemaFingerprint), rev.Scenario, rev.RevID,
+				utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+		}
+		if err := patch.Apply(dir, m, restoreDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportForPatch writes target's schema.json and table CSVs into dir and
+// returns the schema fingerprint.
+func exportForPatch(target utils.NamedEnv, dir string) (string, error) {
+	manager, normalizedURL, err := db.NewManager(target.DatabaseURL)
+	if err != nil {
+		return "", err
+	}
+	if err := manager.ConnectWithDSN(normalizedURL); err != nil {
+		return "", fmt.Errorf("connecting to database: %v", err)
+	}
+	if err := manager.ExportSchema(dir); err != nil {
+		return "", fmt.Errorf("exporting schema: %v", err)
+	}
+	if err := manager.ExportToCSV(dir); err != nil {
+		return "", fmt.Errorf("exporting data: %v", err)
+	}
+	fp, err := utils.FingerprintSchemaFile(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		return "", fmt.Errorf("fingerprinting schema: %v", err)
+	}
+	return fp, nil
+}
+
+// pickRecording returns name, or when it's empty the only running
+// recording.
+func pickRecording(projectRoot, storagePath, name string) (string, error) {
+	if name != "" {
+		if err := patch.ValidateName(name); err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(patch.RecordingDir(projectRoot, storagePath, name)); err != nil {
+			return "", fmt.Errorf("no recording of %q is running", name)
+		}
+		return name, nil
+	}
+	running, err := listDirNames(filepath.Join(projectRoot, storagePath, "recordings"))
+	if err != nil {
+		return "", err
+	}
+	switch len(running) {
+	case 0:
+		return "", fmt.Errorf("no recording is running — start one with `seedmancer record start <patch>`")
+	case 1:
+		return running[0], nil
+	default:
+		return "", fmt.Errorf("several recordings are running (%s) — name one", strings.Join(running, ", "))
+	}
+}
+
+func listPatches(projectRoot, storagePath string) ([]patch.Manifest, error) {
+	names, err := listDirNames(filepath.Join(projectRoot, storagePath, "patches"))
+	if err != nil {
+		return nil, err
+	}
+	out := []patch.Manifest{}
+	for _, n := range names {
+		m, err := patch.ReadManifest(patch.Dir(projectRoot, storagePath, n))
+		if err != nil {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// listDirNames returns the sorted names of dir's visible subdirectories;
+// a missing dir has none.
+func listDirNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func printPatchChanges(changes []patch.TableChange) {
+	for _, t := range changes {
+		line := fmt.Sprintf("  %-24s +%d inserted  ~%d updated  -%d deleted", t.Table, t.Inserted, t.Updated, t.Deleted)
+		if len(t.Ignored) > 0 {
+			line += "  (ignoring " + strings.Join(t.Ignored, ", ") + ")"
+		}
+		ui.Info("%s", line)
+	}
+}
+
+func writeRecording(dir string, rec recording) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, recordingFile), append(data, '\n'), 0644)
+}
+
+func readRecording(dir string) (recording, error) {
+	var rec recording
+	raw, err := os.ReadFile(filepath.Join(dir, recordingFile))
+	if err != nil {
+		return rec, fmt.Errorf("reading %s: %v", recordingFile, err)
+	}
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return rec, fmt.Errorf("parsing %s: %v", recordingFile, err)
+	}
+	return rec, nil
+}
+
+// loadProject finds seedmancer.yaml and returns its directory and config.
+func loadProject() (string, utils.Config, error) {
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return "", utils.Config{}, err
+	}
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return "", utils.Config{}, err
+	}
+	return filepath.Dir(configPath), cfg, nil
+}
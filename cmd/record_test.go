@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/patch"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func TestApplyPatches(t *testing.T) {
+	stageRevision(t, "billing", `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"name","type":"text"}]}]}`,
+		map[string]string{"users": "id,name\n1,ann\n"})
+	root, _ := os.Getwd()
+	rev, err := resolveSeedRevision(root, ".seedmancer", "billing", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := patch.Dir(root, ".seedmancer", "signup")
+	writeFile(t, filepath.Join(dir, "users.upsert.csv"), "id,name\n2,bob\n")
+	if err := patch.WriteManifest(dir, patch.Manifest{
+		Name: "signup", SchemaFingerprint: rev.Manifest.SchemaFingerprint, CreatedAt: time.Now(),
+		Tables: []patch.TableChange{{Table: "users", Key: []string{"id"}, Inserted: 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	schemaDir := scenario.SchemaStoreDir(root, ".seedmancer", utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	merged, cleanup, err := materializeRestoreDir(schemaDir, rev.DataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if err := applyPatches(root, ".seedmancer", []string{"signup"}, merged, rev); err != nil {
+		t.Fatalf("applyPatches: %v", err)
+	}
+	raw, _ := os.ReadFile(filepath.Join(merged, "users.csv"))
+	if string(raw) != "id,name\n1,ann\n2,bob\n" {
+		t.Errorf("patched users.csv = %q", raw)
+	}
+	raw, _ = os.ReadFile(filepath.Join(rev.DataDir, "users.csv"))
+	if string(raw) != "id,name\n1,ann\n" {
+		t.Errorf("revision changed: %q", raw)
+	}
+
+	err = applyPatches(root, ".seedmancer", []string{"missing"}, merged, rev)
+	if err == nil || !strings.Contains(err.Error(), "no patch") {
+		t.Errorf("missing patch: err = %v", err)
+	}
+}
+
+func TestPickRecording(t *testing.T) {
+	root := t.TempDir()
+	if _, err := pickRecording(root, ".seedmancer", ""); err == nil {
+		t.Fatal("expected an error with no recordings")
+	}
+	writeFile(t, filepath.Join(patch.RecordingDir(root, ".seedmancer", "a"), recordingFile), "{}")
+	if name, err := pickRecording(root, ".seedmancer", ""); err != nil || name != "a" {
+		t.Fatalf("pickRecording = %q, %v", name, err)
+	}
+	writeFile(t, filepath.Join(patch.RecordingDir(root, ".seedmancer", "b"), recordingFile), "{}")
+	if _, err := pickRecording(root, ".seedmancer", ""); err == nil {
+		t.Fatal("expected an error with two recordings")
+	}
+	if name, err := pickRecording(root, ".seedmancer", "b"); err != nil || name != "b" {
+		t.Fatalf("pickRecording(b) = %q, %v", name, err)
+	}
+}
@@ -690,6 +690,9 @@ func runApplyGeneratedSQL(ctx context.Context, r generateRefreshResult) (ApplyAI
 		RowCounts:         rowCounts,
 		Services:          []string{"postgres"},
 	}
+	if err := stampRevisionMetadata(&revManifest, newDataDir, r.target.DatabaseURL); err != nil {
+		return ApplyAIRefreshOutput{}, err
+	}
 	if err := scenario.WriteRevisionManifest(newRevDir, revManifest); err != nil {
 		return ApplyAIRefreshOutput{}, err
 	}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// seedProject finds the project a seed runs in. A remote seed needs
+// none, so a stateless CI runner can use one without seedmancer.yaml; it
+// then runs from the current directory with default settings.
+func seedProject(remote bool) (string, utils.Config, error) {
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		if !remote {
+			return "", utils.Config{}, err
+		}
+		wd, wdErr := os.Getwd()
+		return wd, utils.Config{}, wdErr
+	}
+	cfg, err := utils.LoadConfig(configPath)
+	return filepath.Dir(configPath), cfg, err
+}
+
+// remoteRevision downloads the cloud copy of scenarioPath into memory and
+// unpacks it into a temp dir laid out like a revision — data/ with the
+// CSVs, schema/ with schema.json and its sidecars — so seed --remote can
+// restore it without pulling a revision into the project. It returns the
+// revision, its schema dir and a cleanup that removes the temp dir. The
+// revision's RevID is the cloud id; pull_masking applies as it does to a
+// pull.
+func remoteRevision(ctx context.Context, cfg utils.Config, scenarioPath, token string) (resolvedRevision, string, func(), error) {
+	noop := func() {}
+	baseURL := utils.GetBaseURL()
+	utils.SetGlobalProjectSlug(utils.ResolveProjectSlug("", cfg))
+	match, err := findRemoteDataset(baseURL, token, scenarioPath, "")
+	if err != nil {
+		return resolvedRevision{}, "", noop, err
+	}
+	if match.Schema == nil || match.Schema.Fingerprint == "" {
+		return resolvedRevision{}, "", noop, fmt.Errorf("remote dataset %q is missing schema metadata", scenarioPath)
+	}
+	archive, err := readDatasetArchive(ctx, baseURL, token, match)
+	if err != nil {
+		return resolvedRevision{}, "", noop, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return resolvedRevision{}, "", noop, fmt.Errorf("opening zip file: %v", err)
+	}
+	masker, err := pullMasker(cfg, zr)
+	if err != nil {
+		return resolvedRevision{}, "", noop, err
+	}
+
+	tmp, err := os.MkdirTemp("", "seedmancer-remote-*")
+	if err != nil {
+		return resolvedRevision{}, "", noop, fmt.Errorf("creating temp dir: %v", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(tmp) }
+	fail := func(err error) (resolvedRevision, string, func(), error) {
+		cleanup()
+		return resolvedRevision{}, "", noop, err
+	}
+	dataDir, schemaDir := filepath.Join(tmp, "data"), filepath.Join(tmp, "schema")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fail(fmt.Errorf("creating temp dir: %v", err))
+	}
+	_, raw, err := extractZipReader(zr, dataDir, masker, nil)
+	if err != nil {
+		return fail(err)
+	}
+	manifest := scenario.RevisionManifest{
+		Scenario:          scenarioPath,
+		Revision:          match.ID,
+		SchemaFingerprint: match.Schema.Fingerprint,
+		Source:            "remote",
+		RemoteID:          match.ID,
+		RemoteUpdatedAt:   match.UpdatedAt,
+	}
+	if masker != nil {
+		manifest.Masked = masker.Columns()
+	} else {
+		raw = nil
+	}
+	if err := verifyExtractedChecksums(dataDir, raw); err != nil {
+		return fail(fmt.Errorf("remote archive for %s failed verification: %w", scenarioPath, err))
+	}
+	pushed, err := takePushedManifest(dataDir)
+	if err != nil {
+		return fail(fmt.Errorf("reading the pushed manifest of %s: %w", scenarioPath, err))
+	}
+	if _, err := liftSchemaSidecars(dataDir, schemaDir); err != nil {
+		return fail(fmt.Errorf("placing schema files: %v", err))
+	}
+	if err := liftDatasetSQL(dataDir, tmp); err != nil {
+		return fail(fmt.Errorf("placing dataset.sql: %v", err))
+	}
+	if schema, err := utils.ReadSchemaJSON(filepath.Join(schemaDir, "schema.json")); err == nil {
+		manifest.DatabaseType = schema.DatabaseType
+	}
+	if err := stampRevisionMetadata(&manifest, dataDir, ""); err != nil {
+		return fail(err)
+	}
+	if err := adoptPushedManifest(&manifest, pushed); err != nil {
+		return fail(fmt.Errorf("remote archive for %s failed verification: %w", scenarioPath, err))
+	}
+	ui.Debug("Unpacked %s from the cloud into %s", scenarioPath, tmp)
+	return resolvedRevision{
+		Scenario: scenarioPath,
+		RevID:    match.ID,
+		RevDir:   tmp,
+		DataDir:  dataDir,
+		Manifest: manifest,
+	}, schemaDir, cleanup, nil
+}
+
+// readDatasetArchive downloads ds's zip archive into memory. Unlike
+// downloadDatasetArchive it keeps no partial file to resume from, since
+// nothing may be left on disk.
+func readDatasetArchive(ctx context.Context, baseURL, token string, ds datasetAPI) ([]byte, error) {
+	reqURL := fmt.Sprintf("%s/v1.0/datasets/%s/download", baseURL, ds.ID)
+	ui.Debug("GET %s", reqURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %v", err)
+	}
+	req.Header.Set("Authorization", utils.BearerAPIToken(token))
+	utils.ApplyProjectHeader(req, "")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading: %v", err)
+	}
+	if resp.StatusCode == http.StatusOK && isJSONPointer(resp) {
+		// The API answered with where to fetch the archive from.
+		var pointer struct {
+			URL string `json:"url"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&pointer)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing download response: %v", err)
+		}
+		if pointer.URL == "" {
+			return nil, fmt.Errorf("server returned empty download URL")
+		}
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, pointer.URL, nil); err != nil {
+			return nil, fmt.Errorf("invalid download URL: %v", err)
+		}
+		if resp, err = http.DefaultClient.Do(req); err != nil {
+			return nil, fmt.Errorf("downloading: %v", err)
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, utils.ErrInvalidAPIToken
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpStatusError(resp, req)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("downloading: %v", err)
+	}
+	return body, nil
+}
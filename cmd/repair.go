@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/repair"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// RepairCommand fixes foreign key references that point at rows missing
+// from a revision's CSVs and saves the result as a new revision.
+// Hand-edited fixtures drift into that state easily; without repair it
+// only surfaces as a constraint error at seed time.
+//
+//	seedmancer repair billing/pro --strategy delete
+func RepairCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "repair",
+		Usage:     "Fix orphaned foreign key references in a scenario revision",
+		ArgsUsage: "<scenario>",
+		Description: "Finds foreign key cells in the chosen revision (latest by default)\n" +
+			"that name a parent row its CSVs don't have, fixes them with\n" +
+			"--strategy and saves the result as a new rNNN revision:\n\n" +
+			"  delete              drop the rows holding an orphan, and the rows\n" +
+			"                      that referenced those in turn\n" +
+			"  nullify             set the orphaned cells to NULL (nullable\n" +
+			"                      columns only)\n" +
+			"  synthesize-parent   add the missing parent rows, with made-up\n" +
+			"                      values for their other columns\n\n" +
+			"References to a table without a CSV aren't checked, and NULLs, @env\n" +
+			"markers and SQL expression cells are never orphans. Without\n" +
+			"--strategy the orphans are listed and nothing is written.\n\n" +
+			"Examples:\n" +
+			"  seedmancer repair billing/pro\n" +
+			"  seedmancer repair billing/pro --revision r003 --strategy synthesize-parent",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "strategy",
+				Usage: "How to fix orphans: delete, nullify or synthesize-parent (omit to only list them)",
+			},
+			&cli.StringFlag{
+				Name:  "revision",
+				Usage: "Revision to repair (defaults to latest)",
+			},
+			&cli.StringFlag{
+				Name:  "description",
+				Usage: "Optional description stored on the new revision manifest",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			out, err := RunRepair(context.Background(), RepairInput{
+				Scenario:    scenarioArg,
+				Revision:    strings.TrimSpace(c.String("revision")),
+				Strategy:    strings.TrimSpace(c.String("strategy")),
+				Description: strings.TrimSpace(c.String("description")),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+			if len(out.Orphans) == 0 {
+				ui.Success("%s @ %s has no orphaned references", out.Scenario, out.BaseRevision)
+				return nil
+			}
+			if out.Revision == "" {
+				ui.Warn("%s @ %s has orphaned references", out.Scenario, out.BaseRevision)
+			} else {
+				ui.Success("Repaired %s @ %s → %s", out.Scenario, out.BaseRevision, out.Revision)
+			}
+			for _, o := range out.Orphans {
+				ui.KeyValue(fmt.Sprintf("%s.%s → %s: ", o.Table, o.Column, o.Parent), fmt.Sprintf("%d orphan(s)", o.Count))
+			}
+			if out.Revision == "" {
+				ui.KeyValue("Fix with: ", fmt.Sprintf("seedmancer repair %s --strategy delete|nullify|synthesize-parent", out.Scenario))
+				return nil
+			}
+			if len(out.Deleted) > 0 {
+				ui.KeyValue("Rows deleted: ", countsByTable(out.Deleted))
+			}
+			if len(out.Added) > 0 {
+				ui.KeyValue("Parent rows added: ", countsByTable(out.Added))
+			}
+			ui.KeyValue("Run: ", fmt.Sprintf("seedmancer seed %s", out.Scenario))
+			return nil
+		},
+	}
+}
+
+// countsByTable renders per-table counts as "a(1), b(2)".
+func countsByTable(counts map[string]int) string {
+	parts := make([]string, 0, len(counts))
+	for table, n := range counts {
+		parts = append(parts, fmt.Sprintf("%s(%d)", table, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// RepairInput selects the revision to repair and how. An empty Strategy
+// only reports the orphans.
+type RepairInput struct {
+	Scenario    string `json:"scenario" jsonschema:"Scenario path to repair"`
+	Revision    string `json:"revision,omitempty" jsonschema:"Revision to repair (defaults to latest)"`
+	Strategy    string `json:"strategy,omitempty" jsonschema:"delete, nullify or synthesize-parent; omit to only list the orphans"`
+	Description string `json:"description,omitempty" jsonschema:"Optional description stored on the new revision manifest"`
+}
+
+// RepairOutput reports the orphans found and, when a strategy was given
+// and there were any, the new revision.
+type RepairOutput struct {
+	Scenario     string           `json:"scenario"`
+	BaseRevision string           `json:"baseRevision"`
+	Revision     string           `json:"revision,omitempty"`
+	Strategy     string           `json:"strategy,omitempty"`
+	Orphans      []repair.Orphans `json:"orphans"`
+	Deleted      map[string]int   `json:"deleted,omitempty"`
+	Added        map[string]int   `json:"added,omitempty"`
+	Path         string           `json:"path,omitempty"`
+}
+
+// RunRepair finds the orphaned foreign key references of a revision and,
+// with a strategy, commits the repaired CSVs as a new revision. A revision
+// without orphans is left alone.
+func RunRepair(_ context.Context, in RepairInput) (RepairOutput, error) {
+	var strategy repair.Strategy
+	if in.Strategy != "" {
+		var err error
+		if strategy, err = repair.ParseStrategy(in.Strategy); err != nil {
+			return RepairOutput{}, err
+		}
+	}
+
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return RepairOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return RepairOutput{}, err
+	}
+	scenarioPath, err := scenario.Normalize(in.Scenario)
+	if err != nil {
+		return RepairOutput{}, err
+	}
+	base, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+	if err != nil {
+		return RepairOutput{}, err
+	}
+	schema, err := loadRevisionSchema(projectRoot, cfg.StoragePath, base)
+	if err != nil {
+		return RepairOutput{}, err
+	}
+
+	tableNames, _, err := listCSVTablesAndRowCounts(base.DataDir)
+	if err != nil {
+		return RepairOutput{}, err
+	}
+	tables := make(map[string][][]string, len(tableNames))
+	for _, t := range tableNames {
+		if tables[t], err = utils.ReadCSVRecords(filepath.Join(base.DataDir, t+".csv")); err != nil {
+			return RepairOutput{}, err
+		}
+	}
+
+	out := RepairOutput{Scenario: scenarioPath, BaseRevision: base.RevID, Strategy: in.Strategy}
+	if strategy == "" {
+		out.Orphans = repair.Find(schema, tables)
+		return out, nil
+	}
+	report, err := repair.Repair(schema, tables, strategy)
+	if err != nil {
+		return RepairOutput{}, err
+	}
+	out.Orphans = report.Orphans
+	if len(report.Orphans) == 0 {
+		return out, nil
+	}
+
+	manifest, err := deriveRevision(projectRoot, cfg, base, "repair", in.Description, func(table string, _ [][]string) ([][]string, error) {
+		return tables[table], nil
+	})
+	if err != nil {
+		return RepairOutput{}, err
+	}
+	out.Revision = manifest.Revision
+	out.Deleted = report.Deleted
+	out.Added = report.Added
+	out.Path = filepath.Join(scenario.RevisionDir(projectRoot, cfg.StoragePath, scenarioPath, manifest.Revision), "data")
+	return out, nil
+}
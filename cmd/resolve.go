@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"strings"
@@ -36,8 +37,10 @@ func targetDisplay(t utils.NamedEnv) string {
 // single DB (export, status). Precedence, highest first:
 //
 //  1. --db-url flag (explicit ad-hoc override)
-//  2. --env <name> or cfg.DefaultEnv (named env from seedmancer.yaml)
-//  3. $SEEDMANCER_DATABASE_URL — only used when no environments are
+//  2. --env <name>
+//  3. database_url of the active profile
+//  4. the profile's default_env or cfg.DefaultEnv (named env from seedmancer.yaml)
+//  5. $SEEDMANCER_DATABASE_URL — only used when no environments are
 //     configured (bare CI / no seedmancer.yaml scenario)
 //
 // $SEEDMANCER_DATABASE_URL is intentionally last so that a project with
@@ -50,6 +53,12 @@ func resolveSingleDB(c *cli.Context, cfg utils.Config) (utils.NamedEnv, error) {
 			EnvConfig: utils.EnvConfig{DatabaseURL: adhoc},
 		}, nil
 	}
+	if url := profileDatabaseURL(); url != "" && !c.IsSet("env") {
+		return utils.NamedEnv{
+			Name:      adHocEnvName,
+			EnvConfig: utils.EnvConfig{DatabaseURL: url},
+		}, nil
+	}
 	if len(cfg.EffectiveEnvs()) == 0 {
 		if v := strings.TrimSpace(os.Getenv("SEEDMANCER_DATABASE_URL")); v != "" {
 			return utils.NamedEnv{
@@ -69,8 +78,9 @@ func resolveSingleDB(c *cli.Context, cfg utils.Config) (utils.NamedEnv, error) {
 //     is ambiguous).
 //   - $SEEDMANCER_DATABASE_URL is only used when no environments are configured
 //     (bare CI scenario), so a project with named envs always resolves cleanly.
-//   - An empty --env falls back to the active default env, so `seedmancer
-//     seed snap1` keeps working for users who never adopt named envs.
+//   - An empty --env falls back to the active profile's database_url, then
+//     to the active default env, so `seedmancer seed snap1` keeps working
+//     for users who never adopt named envs.
 func resolveSeedTargets(c *cli.Context, cfg utils.Config) ([]utils.NamedEnv, error) {
 	if adhoc := strings.TrimSpace(c.String("db-url")); adhoc != "" {
 		if c.IsSet("env") {
@@ -81,6 +91,12 @@ func resolveSeedTargets(c *cli.Context, cfg utils.Config) ([]utils.NamedEnv, err
 			EnvConfig: utils.EnvConfig{DatabaseURL: adhoc},
 		}}, nil
 	}
+	if url := profileDatabaseURL(); url != "" && !c.IsSet("env") {
+		return []utils.NamedEnv{{
+			Name:      adHocEnvName,
+			EnvConfig: utils.EnvConfig{DatabaseURL: url},
+		}}, nil
+	}
 	if len(cfg.EffectiveEnvs()) == 0 && !c.IsSet("env") {
 		if v := strings.TrimSpace(os.Getenv("SEEDMANCER_DATABASE_URL")); v != "" {
 			return []utils.NamedEnv{{
@@ -92,6 +108,12 @@ func resolveSeedTargets(c *cli.Context, cfg utils.Config) ([]utils.NamedEnv, err
 	return cfg.ResolveEnvs(c.String("env"))
 }
 
+// profileDatabaseURL is the active profile's database_url, or "".
+func profileDatabaseURL() string {
+	_, p, _ := utils.ActiveProfile()
+	return strings.TrimSpace(p.DatabaseURL)
+}
+
 // isProdLike decides whether a "you're about to touch prod" confirmation
 // should fire. Matching is case-insensitive and covers the names real
 // teams actually use (`prod`, `production`, `live`). When users pass
@@ -103,3 +125,72 @@ func isProdLike(envName string) bool {
 	}
 	return false
 }
+
+// AddDBURLFileFlags gives every command under commands that takes
+// --db-url a --db-url-file too, so a DSN with a password can be read
+// from a file, or from stdin with "-", instead of sitting in argv and
+// shell history. The file's contents become --db-url before the command
+// runs, so nothing downstream needs to know about it.
+func AddDBURLFileFlags(commands []*cli.Command) {
+	for _, command := range commands {
+		AddDBURLFileFlags(command.Subcommands)
+		if !hasFlag(command.Flags, "db-url") || hasFlag(command.Flags, "db-url-file") {
+			continue
+		}
+		command.Flags = append(command.Flags, &cli.StringFlag{
+			Name:  "db-url-file",
+			Usage: "Read --db-url from this file (\"-\" for stdin) so the credentials stay out of argv and shell history",
+		})
+		before := command.Before
+		command.Before = func(c *cli.Context) error {
+			if path := c.String("db-url-file"); path != "" {
+				if c.IsSet("db-url") {
+					return fmt.Errorf("--db-url and --db-url-file are mutually exclusive")
+				}
+				dsn, err := readDBURLFile(path, os.Stdin)
+				if err != nil {
+					return err
+				}
+				if err := c.Set("db-url", dsn); err != nil {
+					return err
+				}
+			}
+			if before != nil {
+				return before(c)
+			}
+			return nil
+		}
+	}
+}
+
+// hasFlag reports whether flags has one named name.
+func hasFlag(flags []cli.Flag, name string) bool {
+	for _, f := range flags {
+		for _, n := range f.Names() {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readDBURLFile is the trimmed contents of path, or of stdin when path
+// is "-".
+func readDBURLFile(path string, stdin io.Reader) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading --db-url-file: %v", err)
+	}
+	dsn := strings.TrimSpace(string(data))
+	if dsn == "" {
+		return "", fmt.Errorf("--db-url-file %s is empty", path)
+	}
+	return dsn, nil
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+	"github.com/KazanKK/seedmancer/internal/rewrite"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// RewriteCommand batch-edits column values across a revision's CSVs and
+// saves the result as a new revision. Every rule is type-checked against
+// the revision's schema.json first, which makes it a safe replacement for
+// sed on quoted CSVs.
+//
+//	seedmancer rewrite billing/pro --set users.environment=staging --map orders.currency:USD=EUR
+func RewriteCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "rewrite",
+		Usage:     "Batch-edit column values in a scenario revision",
+		ArgsUsage: "<scenario>",
+		Description: "Applies --set and --map rules to the chosen revision (latest by\n" +
+			"default) and saves the result as a new rNNN revision.\n\n" +
+			"  --set table.column=value       replace every value in the column\n" +
+			"  --map table.column:from=to     replace only cells equal to <from>\n\n" +
+			"Both flags repeat. Use NULL for SQL NULL. Rules are checked against\n" +
+			"schema.json before any file is written: unknown columns, values the\n" +
+			"column type can't hold, enum values outside the enum, NULL in a NOT\n" +
+			"NULL column, and --set on a primary key / unique column are errors.\n\n" +
+			"Examples:\n" +
+			"  seedmancer rewrite billing/pro --set users.environment=staging\n" +
+			"  seedmancer rewrite billing/pro --map orders.currency:USD=EUR --map orders.currency:GBP=EUR",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "set",
+				Usage: "table.column=value — set every value in the column (repeatable)",
+			},
+			&cli.StringSliceFlag{
+				Name:  "map",
+				Usage: "table.column:from=to — replace matching values (repeatable)",
+			},
+			&cli.StringFlag{
+				Name:  "revision",
+				Usage: "Revision to rewrite (defaults to latest)",
+			},
+			&cli.StringFlag{
+				Name:  "description",
+				Usage: "Optional description stored on the new revision manifest",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			if len(c.StringSlice("set")) == 0 && len(c.StringSlice("map")) == 0 {
+				return usageError(c, "pass at least one --set or --map")
+			}
+			out, err := RunRewrite(context.Background(), RewriteInput{
+				Scenario:    scenarioArg,
+				Revision:    strings.TrimSpace(c.String("revision")),
+				Set:         c.StringSlice("set"),
+				Map:         c.StringSlice("map"),
+				Description: strings.TrimSpace(c.String("description")),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+			ui.Success("Rewrote %s @ %s → %s", out.Scenario, out.BaseRevision, out.Revision)
+			for _, r := range out.Rules {
+				ui.KeyValue(r.Rule+": ", fmt.Sprintf("%d value(s) changed", r.Changed))
+			}
+			ui.KeyValue("Run: ", fmt.Sprintf("seedmancer seed %s", out.Scenario))
+			return nil
+		},
+	}
+}
+
+// RewriteInput lists the rules to apply. Set entries use
+// "table.column=value", Map entries "table.column:from=to".
+type RewriteInput struct {
+	Scenario    string   `json:"scenario" jsonschema:"Scenario path to rewrite"`
+	Revision    string   `json:"revision,omitempty" jsonschema:"Revision to rewrite (defaults to latest)"`
+	Set         []string `json:"set,omitempty" jsonschema:"Rules of the form table.column=value; every value in the column is replaced"`
+	Map         []string `json:"map,omitempty" jsonschema:"Rules of the form table.column:from=to; only cells equal to from are replaced"`
+	Description string   `json:"description,omitempty" jsonschema:"Optional description stored on the new revision manifest"`
+}
+
+// RewriteRuleResult reports how many cells one rule changed.
+type RewriteRuleResult struct {
+	Rule    string `json:"rule"`
+	Changed int    `json:"changed"`
+}
+
+// RewriteOutput reports the new revision and per-rule change counts.
+type RewriteOutput struct {
+	Scenario     string              `json:"scenario"`
+	BaseRevision string              `json:"baseRevision"`
+	Revision     string              `json:"revision"`
+	Rules        []RewriteRuleResult `json:"rules"`
+	Path         string              `json:"path"`
+}
+
+// RunRewrite validates the rules against the revision's schema, applies
+// them to every CSV, and commits the result as a new revision.
+func RunRewrite(_ context.Context, in RewriteInput) (RewriteOutput, error) {
+	var rules []rewrite.Rule
+	for _, s := range in.Set {
+		r, err := rewrite.ParseSet(s)
+		if err != nil {
+			return RewriteOutput{}, err
+		}
+		rules = append(rules, r)
+	}
+	for _, s := range in.Map {
+		r, err := rewrite.ParseMap(s)
+		if err != nil {
+			return RewriteOutput{}, err
+		}
+		rules = append(rules, r)
+	}
+	if len(rules) == 0 {
+		return RewriteOutput{}, fmt.Errorf("no rewrite rules given")
+	}
+
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return RewriteOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return RewriteOutput{}, err
+	}
+	scenarioPath, err := scenario.Normalize(in.Scenario)
+	if err != nil {
+		return RewriteOutput{}, err
+	}
+	base, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+	if err != nil {
+		return RewriteOutput{}, err
+	}
+
+	schema, err := loadRevisionSchema(projectRoot, cfg.StoragePath, base)
+	if err != nil {
+		return RewriteOutput{}, err
+	}
+	if err := rewrite.Check(schema, rules); err != nil {
+		return RewriteOutput{}, err
+	}
+	for _, r := range rules {
+		if !csvshard.Exists(filepath.Join(base.DataDir, r.Table+".csv")) {
+			return RewriteOutput{}, fmt.Errorf("%s: %s @ %s has no %s.csv", r, scenarioPath, base.RevID, r.Table)
+		}
+	}
+
+	changed := make([]int, len(rules))
+	manifest, err := deriveRevision(projectRoot, cfg, base, "rewrite", in.Description, func(table string, records [][]string) ([][]string, error) {
+		out, counts, err := rewrite.Apply(records, table, rules)
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range counts {
+			changed[i] += n
+		}
+		return out, nil
+	})
+	if err != nil {
+		return RewriteOutput{}, err
+	}
+
+	results := make([]RewriteRuleResult, len(rules))
+	for i, r := range rules {
+		results[i] = RewriteRuleResult{Rule: r.String(), Changed: changed[i]}
+	}
+	return RewriteOutput{
+		Scenario:     scenarioPath,
+		BaseRevision: base.RevID,
+		Revision:     manifest.Revision,
+		Rules:        results,
+		Path:         filepath.Join(scenario.RevisionDir(projectRoot, cfg.StoragePath, scenarioPath, manifest.Revision), "data"),
+	}, nil
+}
+
+// loadRevisionSchema reads and parses the schema.json a revision is pinned
+// to from the local schema store.
+func loadRevisionSchema(projectRoot, storagePath string, rev resolvedRevision) (utils.SchemaJSON, error) {
+	path := scenario.SchemaJSONPath(projectRoot, storagePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return utils.SchemaJSON{}, fmt.Errorf("reading schema.json for %s @ %s: %w", rev.Scenario, rev.RevID, err)
+	}
+	schema, err := utils.ParseSchemaJSON(data)
+	if err != nil {
+		return utils.SchemaJSON{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return schema, nil
+}
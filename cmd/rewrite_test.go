@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const rewriteSchema = `{"tables":[
+  {"name":"users","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"environment","type":"text"}]},
+  {"name":"orders","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"total","type":"numeric"},{"name":"currency","type":"text"}]}
+]}`
+
+func TestRunRewrite_appliesRulesIntoNewRevision(t *testing.T) {
+	stageRevision(t, "billing/pro", rewriteSchema, map[string]string{
+		"users":  "id,environment\n1,local\n2,local\n",
+		"orders": "id,total,currency\n1,\"1,000.50\",USD\n2,3,GBP\n",
+	})
+
+	out, err := RunRewrite(context.Background(), RewriteInput{
+		Scenario: "billing/pro",
+		Set:      []string{"users.environment=staging"},
+		Map:      []string{"orders.currency:USD=EUR"},
+	})
+	if err != nil {
+		t.Fatalf("RunRewrite: %v", err)
+	}
+	if out.Revision != "r002" {
+		t.Fatalf("revision = %s, want r002", out.Revision)
+	}
+	if out.Rules[0].Changed != 2 || out.Rules[1].Changed != 1 {
+		t.Fatalf("rules = %+v", out.Rules)
+	}
+	orders, err := os.ReadFile(filepath.Join(out.Path, "orders.csv"))
+	if err != nil {
+		t.Fatalf("reading orders.csv: %v", err)
+	}
+	if want := "id,total,currency\n1,\"1,000.50\",EUR\n2,3,GBP\n"; string(orders) != want {
+		t.Fatalf("orders.csv = %q, want %q", orders, want)
+	}
+}
+
+func TestRunRewrite_typeErrorWritesNothing(t *testing.T) {
+	dir := stageRevision(t, "billing/pro", rewriteSchema, map[string]string{
+		"orders": "id,total,currency\n1,3,USD\n",
+	})
+	_, err := RunRewrite(context.Background(), RewriteInput{
+		Scenario: "billing/pro",
+		Set:      []string{"orders.total=free"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "not a number") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, ".seedmancer", "scenarios", "billing", "pro", "revisions", "r002")); !os.IsNotExist(statErr) {
+		t.Fatal("a revision was written despite the type error")
+	}
+}
+
+func TestRunRewrite_shardedTable(t *testing.T) {
+	dir := stageRevision(t, "billing/pro", rewriteSchema, map[string]string{
+		"users":  "",
+		"orders": "id,total,currency\n1,3,USD\n",
+	})
+	shardTable(t, dir, "billing/pro", "users", "id,environment\n1,local\n", "id,environment\n2,local\n")
+
+	out, err := RunRewrite(context.Background(), RewriteInput{
+		Scenario: "billing/pro",
+		Set:      []string{"users.environment=staging"},
+	})
+	if err != nil {
+		t.Fatalf("RunRewrite: %v", err)
+	}
+	if out.Rules[0].Changed != 2 {
+		t.Fatalf("rules = %+v, want both shards' rows changed", out.Rules)
+	}
+	users, err := os.ReadFile(filepath.Join(out.Path, "users.csv"))
+	if err != nil || string(users) != "id,environment\n1,staging\n2,staging\n" {
+		t.Fatalf("users.csv = %q (err %v)", users, err)
+	}
+}
@@ -1,21 +1,31 @@
 package cmd
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/chaos"
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+	"github.com/KazanKK/seedmancer/internal/diskspace"
+	"github.com/KazanKK/seedmancer/internal/migrations"
+	"github.com/KazanKK/seedmancer/internal/patch"
 	"github.com/KazanKK/seedmancer/internal/scenario"
 	"github.com/KazanKK/seedmancer/internal/sqlcontract"
+	"github.com/KazanKK/seedmancer/internal/subset"
 	utils "github.com/KazanKK/seedmancer/internal/utils"
 )
 
@@ -402,6 +412,12 @@ type DescribeSchemaOutput struct {
 	DisplayName      string        `json:"displayName,omitempty"`
 	Path             string        `json:"path"`
 	Tables           []SchemaTable `json:"tables"`
+	// InsertOrder lists every table parents-first so generated SQL can
+	// INSERT in this order without tripping FK checks. CyclicTables are
+	// the ones caught in an FK cycle; they trail InsertOrder and need a
+	// nullable FK filled in with a follow-up UPDATE.
+	InsertOrder  []string `json:"insertOrder"`
+	CyclicTables []string `json:"cyclicTables,omitempty"`
 }
 
 // RunDescribeSchema loads schema.json from the local on-disk folder for
@@ -442,9 +458,22 @@ func RunDescribeSchema(_ context.Context, in DescribeSchemaInput) (DescribeSchem
 		FingerprintShort: schema.FingerprintShort,
 		DisplayName:      schema.DisplayName,
 		Path:             schema.Path,
-		Tables:           make([]SchemaTable, 0, len(raw.Tables)),
+		Tables:           schemaTables(raw.Tables),
 	}
-	for _, t := range raw.Tables {
+
+	fkSchema, err := utils.ParseSchemaJSON(data)
+	if err != nil {
+		return DescribeSchemaOutput{}, fmt.Errorf("parsing %s: %v", schemaJSONPath, err)
+	}
+	out.InsertOrder, out.CyclicTables = subset.InsertOrder(fkSchema)
+	return out, nil
+}
+
+// schemaTables is the structured view of schema.json's tables that
+// describe_schema and introspect return.
+func schemaTables(tables []generateTable) []SchemaTable {
+	out := make([]SchemaTable, 0, len(tables))
+	for _, t := range tables {
 		st := SchemaTable{Name: t.Name, Columns: make([]SchemaColumn, 0, len(t.Columns))}
 		for _, c := range t.Columns {
 			col := SchemaColumn{
@@ -461,9 +490,9 @@ func RunDescribeSchema(_ context.Context, in DescribeSchemaInput) (DescribeSchem
 			}
 			st.Columns = append(st.Columns, col)
 		}
-		out.Tables = append(out.Tables, st)
+		out = append(out, st)
 	}
-	return out, nil
+	return out
 }
 
 // ─── status ───────────────────────────────────────────────────────────────────
@@ -471,6 +500,10 @@ func RunDescribeSchema(_ context.Context, in DescribeSchemaInput) (DescribeSchem
 type StatusInput struct {
 	Offline   bool `json:"offline,omitempty" jsonschema:"Skip the API reachability probe"`
 	ShowDBURL bool `json:"showDbUrl,omitempty" jsonschema:"Return database URLs with credentials (default masks the password)"`
+	Scenario  string `json:"scenario,omitempty" jsonschema:"Scenario to compare with the live database; fills drift with what seed would change"`
+	Revision  string `json:"revision,omitempty" jsonschema:"Revision of scenario to compare (defaults to latest)"`
+	Env       string `json:"env,omitempty" jsonschema:"Named environment to compare scenario with (defaults to default_env)"`
+	DBURL     string `json:"dbUrl,omitempty" jsonschema:"Ad-hoc database URL to compare scenario with (takes precedence over env)"`
 }
 
 // StatusOutput is the same shape the CLI emits for `status --json`. We
@@ -487,6 +520,13 @@ func RunStatus(_ context.Context, in StatusInput) (StatusOutput, error) {
 			report.Auth.ReachableError = errMsg
 		}
 	}
+	if strings.TrimSpace(in.Scenario) != "" {
+		drift, err := buildStatusDrift(in.Scenario, in.Revision, in.Env, in.DBURL)
+		if err != nil {
+			return report, err
+		}
+		report.Drift = drift
+	}
 	return report, nil
 }
 
@@ -1028,7 +1068,63 @@ type SeedInput struct {
 	// so MCP handlers default to true.
 	Yes             bool `json:"yes,omitempty" jsonschema:"Skip the destructive-action prompt"`
 	ContinueOnError bool `json:"continueOnError,omitempty" jsonschema:"Keep seeding remaining envs after a failure"`
-	DryRun          bool `json:"dryRun,omitempty" jsonschema:"Resolve envs and return plan only; make no DB changes"`
+	DryRun          bool `json:"dryRun,omitempty" jsonschema:"Resolve envs and return the plan only, with the tables TRUNCATE … CASCADE would empty on each; make no DB changes"`
+	// AllowCascade lets a PostgreSQL restore's TRUNCATE … CASCADE empty
+	// tables outside the revision; without it such a seed is refused.
+	AllowCascade bool `json:"allowCascade,omitempty" jsonschema:"Let TRUNCATE … CASCADE empty tables outside the revision that reference the ones reloaded (PostgreSQL)"`
+	// Tables limits the seed to a comma-separated subset. Parent tables
+	// reached through foreign keys are added automatically, loading only
+	// the referenced rows (and only when missing).
+	Tables string `json:"tables,omitempty" jsonschema:"Comma-separated tables to seed; FK parent rows are included automatically"`
+	// WaitForDB polls each target until it is ready before seeding.
+	WaitForDB string `json:"waitForDb,omitempty" jsonschema:"Wait up to this long (Go duration, e.g. 120s) for each target to accept connections and finish recovery"`
+	// WaitForReplica polls each target's replica_url after seeding until
+	// its row counts match the primary's.
+	// Warmup runs ANALYZE on the seeded tables after the load, then the
+	// revision's warmup.sql when it has one.
+	Warmup         bool   `json:"warmup,omitempty" jsonschema:"After the load, ANALYZE the seeded tables and run the revision's warmup.sql if present"`
+	WaitForReplica string `json:"waitForReplica,omitempty" jsonschema:"After seeding, wait up to this long (Go duration, e.g. 60s) for each target's replica_url to reach the primary's row counts"`
+	// Vacuum is "run" to vacuum the seeded tables after the load, or
+	// "advise" to print the space there is to reclaim.
+	Vacuum string `json:"vacuum,omitempty" jsonschema:"After the load: run vacuums the seeded tables, advise prints the reclaimable space and the statements to run"`
+	// TargetSchema / TargetDatabase restore into a sandbox namespace that
+	// is created on the fly instead of the one the DSN points at.
+	TargetSchema   string `json:"targetSchema,omitempty" jsonschema:"PostgreSQL: restore into this schema (created if missing) instead of public"`
+	TargetDatabase string `json:"targetDatabase,omitempty" jsonschema:"MySQL: restore into this database (created if missing) instead of the DSN's"`
+	// Mode is "replace" (default), "upsert", which keeps existing rows and
+	// overwrites those whose primary key matches a fixture row, or
+	// "append", which inserts the fixture alongside with remapped keys.
+	Mode string `json:"mode,omitempty" jsonschema:"How to treat existing rows: replace (default, truncate first), upsert (insert or update by primary key) or append (insert alongside, remapping serial/uuid keys)"`
+	// Template resets each PostgreSQL target from a template database of
+	// the fixture, building the template on the first seed.
+	Template bool `json:"template,omitempty" jsonschema:"PostgreSQL: reset the target from a template database of this fixture (built on first use) instead of importing CSVs"`
+	// Chaos plants awkward-but-valid values in this share of rows (e.g.
+	// "5%"); ChaosSeed repeats an earlier run, 0 picks a random seed.
+	Chaos     string `json:"chaos,omitempty" jsonschema:"Plant awkward-but-valid values (NULLs, max-length strings, unicode, extreme dates, integer limits, empty JSON) in this share of rows, e.g. 5%"`
+	ChaosSeed int64  `json:"chaosSeed,omitempty" jsonschema:"Random seed for chaos, to repeat an earlier run; 0 picks one"`
+	// AllowEngineMismatch seeds targets whose engine family differs from
+	// the one the revision was captured from instead of refusing,
+	// converting the schema's types.
+	AllowEngineMismatch bool `json:"allowEngineMismatch,omitempty" jsonschema:"Seed even when the revision was captured from another database engine, converting its types"`
+	// Patches applies patches saved by `seedmancer record`, in order, on
+	// top of the revision.
+	Patches string `json:"patches,omitempty" jsonschema:"Comma-separated patches saved by seedmancer record to apply on top of the revision"`
+	// Layers stacks the latest revision of each scenario on top of the
+	// base, in order. With no Scenario the first layer is the base.
+	Layers string `json:"layers,omitempty" jsonschema:"Comma-separated scenarios to stack on the base, in order; later layers replace rows by primary key and add the rest"`
+	// CreateMissingOnly applies only additive DDL (missing tables, enums
+	// and columns) and loads no rows. The fingerprint guard is skipped.
+	CreateMissingOnly bool `json:"createMissingOnly,omitempty" jsonschema:"Only create missing tables, enums and columns; existing structures and rows are left alone"`
+	// Migrations names the column mapping file to apply (default
+	// migrations.yaml in the project root, if present); NoMigrations
+	// ignores it. An applied mapping skips the fingerprint guard.
+	Migrations   string `json:"migrations,omitempty" jsonschema:"Column mapping file for renamed, dropped and added columns (default migrations.yaml in the project root)"`
+	NoMigrations bool   `json:"noMigrations,omitempty" jsonschema:"Seed the revision as exported, ignoring migrations.yaml"`
+	// Remote seeds the cloud's copy of Scenario straight from the API,
+	// held in memory and a temp dir rather than pulled into the project;
+	// Token overrides the API token it uses.
+	Remote bool   `json:"remote,omitempty" jsonschema:"Seed the cloud's copy of the scenario without pulling it into the project; no seedmancer.yaml needed"`
+	Token  string `json:"token,omitempty" jsonschema:"API token override for remote"`
 }
 
 type SeedTargetResult struct {
@@ -1037,6 +1133,30 @@ type SeedTargetResult struct {
 	Skipped    bool   `json:"skipped"`
 	DurationMS int64  `json:"durationMs"`
 	Error      string `json:"error,omitempty"`
+	// Cascade lists the tables outside the revision that TRUNCATE …
+	// CASCADE empties on this target: on a dry run, the ones it would;
+	// on a seed refused with db.CascadeError, the ones it named.
+	Cascade []string `json:"cascade,omitempty"`
+}
+
+// seedTargetResult converts one target's outcome for SeedOutput.
+func seedTargetResult(res seedResult) SeedTargetResult {
+	r := SeedTargetResult{
+		Env:        res.Env,
+		DurationMS: res.Duration.Milliseconds(),
+		Skipped:    res.Skipped,
+		Cascade:    res.Cascade,
+	}
+	var cascadeErr *db.CascadeError
+	if errors.As(res.Err, &cascadeErr) {
+		r.Cascade = cascadeErr.Tables
+	}
+	if res.Err != nil {
+		r.Error = res.Err.Error()
+	} else if !res.Skipped {
+		r.Ok = true
+	}
+	return r
 }
 
 // SeedOutput is the structured result returned by RunSeed. Schema is the
@@ -1048,38 +1168,101 @@ type SeedOutput struct {
 	DryRun   bool               `json:"dryRun"`
 	Results  []SeedTargetResult `json:"results"`
 	AnyError bool               `json:"anyError"`
+	// Warnings lists targets whose engine differs from the one the
+	// revision was captured from, and tables a partial revision has no
+	// data for. They never block the seed.
+	Warnings []string `json:"warnings,omitempty"`
+	// Layers lists the scenarios stacked on the revision, in order.
+	Layers []SeedLayer `json:"layers,omitempty"`
+	// Chaos lists the values planted when the input asked for chaos.
+	Chaos *chaosReport `json:"chaos,omitempty"`
+	// Migrations lists the migrations.yaml rules that were applied.
+	Migrations []migrations.Change `json:"migrations,omitempty"`
+}
+
+// SeedLayer is one scenario revision stacked on a seed's base, with how
+// many of its rows replaced rows below and how many were added.
+type SeedLayer struct {
+	Scenario string `json:"scenario"`
+	Revision string `json:"revision"`
+	Replaced int    `json:"replaced"`
+	Added    int    `json:"added"`
 }
 
 // RunSeed is the structured entry point used by the MCP tool handler. It
 // mirrors SeedCommand's Action body without the stdout chatter, and never
 // prompts (the caller is responsible for setting `Yes: true` when the
 // flow is non-interactive).
-func RunSeed(_ context.Context, in SeedInput) (SeedOutput, error) {
-	configPath, err := utils.FindConfigFile()
+func RunSeed(ctx context.Context, in SeedInput) (SeedOutput, error) {
+	projectRoot, cfg, err := seedProject(in.Remote)
 	if err != nil {
 		return SeedOutput{}, err
 	}
-	projectRoot := filepath.Dir(configPath)
-	cfg, err := utils.LoadConfig(configPath)
+
+	base, layers := layerStack(strings.TrimSpace(in.Scenario), splitCSVList(in.Layers))
+	scenarioPath, err := scenario.Normalize(base)
 	if err != nil {
 		return SeedOutput{}, err
 	}
+	if len(layers) > 0 && in.Template {
+		return SeedOutput{}, fmt.Errorf("layers can't be combined with template")
+	}
+	if in.Remote && (in.Revision != "" || len(layers) > 0 || in.Patches != "") {
+		return SeedOutput{}, fmt.Errorf("remote seeds the cloud's copy of the scenario, so it can't be combined with revision, layers or patches")
+	}
 
-	scenarioPath, err := scenario.Normalize(in.Scenario)
+	targets, err := resolveSeedTargetsFromOpts(in.DBURL, in.Env, cfg)
 	if err != nil {
 		return SeedOutput{}, err
 	}
-
-	targets, err := resolveSeedTargetsFromOpts(in.DBURL, in.Env, cfg)
+	if err := refuseProtectedTargets(cfg, targets); err != nil {
+		return SeedOutput{}, err
+	}
+	var waitFor time.Duration
+	if w := strings.TrimSpace(in.WaitForDB); w != "" {
+		if waitFor, err = time.ParseDuration(w); err != nil {
+			return SeedOutput{}, fmt.Errorf("invalid waitForDb %q: %w", w, err)
+		}
+	}
+	var waitForReplicaTimeout time.Duration
+	if w := strings.TrimSpace(in.WaitForReplica); w != "" {
+		if waitForReplicaTimeout, err = time.ParseDuration(w); err != nil {
+			return SeedOutput{}, fmt.Errorf("invalid waitForReplica %q: %w", w, err)
+		}
+	}
+	mode, err := db.ParseRestoreMode(in.Mode)
 	if err != nil {
 		return SeedOutput{}, err
 	}
-
-	rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+	vacuum, err := db.ParseVacuumMode(in.Vacuum)
 	if err != nil {
 		return SeedOutput{}, err
 	}
 
+	var rev resolvedRevision
+	var schemaDir string
+	if in.Remote {
+		token, err := utils.ResolveAPIToken(in.Token)
+		if err != nil {
+			return SeedOutput{}, err
+		}
+		var cleanupRemote func()
+		rev, schemaDir, cleanupRemote, err = remoteRevision(ctx, cfg, scenarioPath, token)
+		if err != nil {
+			return SeedOutput{}, err
+		}
+		defer cleanupRemote()
+	} else {
+		rev, err = resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+		if err != nil {
+			return SeedOutput{}, err
+		}
+		schemaDir = scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	}
+	if err := verifyRevisionChecksum(rev); err != nil {
+		return SeedOutput{}, err
+	}
+
 	schemaShort := utils.FingerprintShort(rev.Manifest.SchemaFingerprint)
 	out := SeedOutput{
 		Scenario: scenarioPath,
@@ -1088,51 +1271,156 @@ func RunSeed(_ context.Context, in SeedInput) (SeedOutput, error) {
 		DryRun:   in.DryRun,
 		Results:  make([]SeedTargetResult, 0, len(targets)),
 	}
-
-	if in.DryRun {
-		for _, t := range targets {
-			out.Results = append(out.Results, SeedTargetResult{Env: t.Name, Ok: true})
-		}
-		return out, nil
+	warnings, err := checkRevisionEngine(rev, revisionDatabaseType(projectRoot, cfg.StoragePath, rev), targets, in.AllowEngineMismatch)
+	if err != nil {
+		return out, err
 	}
+	if w := partialRevisionWarning(rev); w != "" {
+		warnings = append(warnings, w)
+	}
+	out.Warnings = warnings
 
-	schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, schemaShort)
 	merged, cleanup, err := materializeRestoreDir(schemaDir, rev.DataDir)
 	if err != nil {
 		return out, err
 	}
 	defer cleanup()
+	layered, err := applyLayers(projectRoot, cfg.StoragePath, layers, merged, rev)
+	if err != nil {
+		return out, err
+	}
+	out.Layers = seedLayers(layered)
+
+	mapping, err := loadSeedMigrations(projectRoot, in.Migrations, in.NoMigrations)
+	if err != nil {
+		return out, err
+	}
+	if mapping != nil {
+		migratedDir, changes, cleanupMigrated, err := materializeMigrationsDir(merged, *mapping)
+		if err != nil {
+			return out, err
+		}
+		defer cleanupMigrated()
+		merged = migratedDir
+		out.Migrations = changes
+	}
+	migrated := len(out.Migrations) > 0
+
+	restoreOpts := restoreOptionsFromConfig(cfg)
+	restoreOpts.Mode = mode
+	restoreOpts.Vacuum = vacuum
+	restoreOpts.AllowCascade = in.AllowCascade
+	restoreOpts.Record = seedRecordFor(rev)
+	if len(layered) > 0 {
+		restoreOpts.Record = layeredSeedRecord(rev, layered)
+	}
+	if err := applySandboxTarget(&restoreOpts, in.TargetSchema, in.TargetDatabase); err != nil {
+		return out, err
+	}
+	if in.Warmup {
+		if err := applyWarmup(&restoreOpts, rev); err != nil {
+			return out, err
+		}
+	}
+	if patches := splitCSVList(in.Patches); len(patches) > 0 {
+		if in.Template {
+			return out, fmt.Errorf("patches can't be combined with template")
+		}
+		if err := applyPatches(projectRoot, cfg.StoragePath, patches, merged, rev); err != nil {
+			return out, err
+		}
+	}
+	if tables := splitCSVList(in.Tables); len(tables) > 0 {
+		subsetDir, plan, cleanupSubset, err := materializeSubsetDir(merged, tables)
+		if err != nil {
+			return out, err
+		}
+		defer cleanupSubset()
+		merged = subsetDir
+		restoreOpts.Tables = plan.Selected
+		restoreOpts.MergeTables = plan.Parents
+	}
+	if in.Template {
+		if err := validateTemplateSeed(restoreOpts); err != nil {
+			return out, err
+		}
+		if migrated {
+			return out, fmt.Errorf("template can't be used while %s rewrites the revision; set noMigrations or export a new revision", migrations.FileName)
+		}
+	}
+	if in.CreateMissingOnly {
+		if in.Template || strings.TrimSpace(in.Chaos) != "" || strings.TrimSpace(in.Patches) != "" {
+			return out, fmt.Errorf("createMissingOnly can't be combined with template, chaos or patches")
+		}
+		if err := validateCreateMissingOnly(restoreOpts); err != nil {
+			return out, err
+		}
+		restoreOpts.CreateMissingOnly = true
+	}
+	if strings.TrimSpace(in.Chaos) != "" {
+		if in.Template {
+			return out, fmt.Errorf("chaos can't be combined with template")
+		}
+		rate, err := chaos.ParseRate(in.Chaos)
+		if err != nil {
+			return out, err
+		}
+		report := &chaosReport{Seed: in.ChaosSeed, Rate: rate}
+		if report.Seed == 0 {
+			report.Seed = time.Now().UnixNano()
+		}
+		chaosDir, faults, cleanupChaos, err := materializeChaosDir(merged, rate, report.Seed)
+		if err != nil {
+			return out, err
+		}
+		defer cleanupChaos()
+		merged = chaosDir
+		report.Faults = faults
+		out.Chaos = report
+	}
+
+	// A dry run stops here, with every input staged and checked, and
+	// only reads each target to list what TRUNCATE … CASCADE would empty.
+	if in.DryRun {
+		for _, t := range targets {
+			res := seedTargetResult(previewSeed(t, merged, restoreOpts))
+			out.AnyError = out.AnyError || res.Error != ""
+			out.Results = append(out.Results, res)
+		}
+		return out, nil
+	}
 
 	for i, t := range targets {
-		if !in.Force {
-			if err := guardSchemaMatch(t, rev); err != nil {
-				out.Results = append(out.Results, SeedTargetResult{
-					Env:   t.Name,
-					Error: err.Error(),
-				})
-				out.AnyError = true
-				if !in.ContinueOnError {
-					for _, rest := range targets[i+1:] {
-						out.Results = append(out.Results, SeedTargetResult{Env: rest.Name, Skipped: true})
-					}
-					break
+		if err := checkSeedTarget(t, rev, in.Force || isSandboxRestore(restoreOpts) || in.Template || restoreOpts.CreateMissingOnly || migrated, waitFor); err != nil {
+			out.Results = append(out.Results, SeedTargetResult{
+				Env:   t.Name,
+				Error: err.Error(),
+			})
+			out.AnyError = true
+			if !in.ContinueOnError {
+				for _, rest := range targets[i+1:] {
+					out.Results = append(out.Results, SeedTargetResult{Env: rest.Name, Skipped: true})
 				}
-				continue
+				break
 			}
+			continue
 		}
-		res := seedOneEnvQuiet(t, merged, in.Yes, scenarioPath, rev.RevID)
-		r := SeedTargetResult{
-			Env:        res.Env,
-			DurationMS: res.Duration.Milliseconds(),
-			Skipped:    res.Skipped,
+		var res seedResult
+		if in.Template && (in.Yes || !isProdLike(t.Name)) {
+			res = seedViaTemplate(t, merged, scenarioPath, restoreOpts, func(target utils.NamedEnv, dir string) seedResult {
+				return seedOneEnvQuiet(target, dir, true, scenarioPath, rev.RevID, restoreOpts)
+			})
+		} else {
+			// Also the refusal path for a prod-like target without yes.
+			res = seedOneEnvQuiet(t, merged, in.Yes, scenarioPath, rev.RevID, restoreOpts)
+		}
+		if res.Err == nil && !res.Skipped && waitForReplicaTimeout > 0 && t.ReplicaURL != "" {
+			res.Err = waitForReplica(t, merged, waitForReplicaTimeout)
 		}
 		if res.Err != nil {
-			r.Error = res.Err.Error()
 			out.AnyError = true
-		} else if !res.Skipped {
-			r.Ok = true
 		}
-		out.Results = append(out.Results, r)
+		out.Results = append(out.Results, seedTargetResult(res))
 
 		if res.Err != nil && !in.ContinueOnError {
 			for _, rest := range targets[i+1:] {
@@ -1174,13 +1462,18 @@ func resolveSeedTargetsFromOpts(dbURL, envCSV string, cfg utils.Config) ([]utils
 // same prod guard (opt-out via `yes`), but without the spinner and
 // titles. MCP clients surface progress + errors from the structured
 // result; the CLI still has its pretty path via seedOneEnv.
-func seedOneEnvQuiet(target utils.NamedEnv, mergedDir string, yes bool, scenarioPath, revID string) seedResult {
+func seedOneEnvQuiet(target utils.NamedEnv, mergedDir string, yes bool, scenarioPath, revID string, opts db.RestoreOptions) seedResult {
 	start := time.Now()
 	dest := targetDisplay(target)
 	if !yes && isProdLike(target.Name) {
 		msg := fmt.Sprintf("confirmation required to seed %q @ %s into %q — set yes:true to confirm", scenarioPath, revID, dest)
 		return seedResult{Env: dest, Err: fmt.Errorf("%s", msg), Duration: time.Since(start)}
 	}
+	if len(target.Shards) > 0 {
+		return seedShards(target, mergedDir, func(shard utils.NamedEnv, dir string) seedResult {
+			return seedOneEnvQuiet(shard, dir, true, scenarioPath, revID, opts)
+		})
+	}
 
 	// Resolve @env:KEY markers per env without mutating the shared mergedDir.
 	restoreDir, cleanupResolved, err := resolveMarkersDir(mergedDir, target.Values, target.Name)
@@ -1196,7 +1489,9 @@ func seedOneEnvQuiet(target utils.NamedEnv, mergedDir string, yes bool, scenario
 	if err := manager.ConnectWithDSN(normalizedURL); err != nil {
 		return seedResult{Env: dest, Err: fmt.Errorf("connecting: %v", err), Duration: time.Since(start)}
 	}
-	if err := manager.RestoreFromCSV(restoreDir); err != nil {
+	opts.Role = target.Role
+	opts = stampSeedRecord(opts, restoreDir, start)
+	if err := manager.RestoreFromCSVWithOptions(restoreDir, opts); err != nil {
 		return seedResult{Env: dest, Err: err, Duration: time.Since(start)}
 	}
 	return seedResult{Env: dest, Duration: time.Since(start)}
@@ -1214,6 +1509,54 @@ type ExportInput struct {
 	// Description is stored verbatim on the new revision manifest so
 	// future `seedmancer history` output can describe what changed.
 	Description string `json:"description,omitempty" jsonschema:"Human-readable note saved on the new revision"`
+	// OutputDir writes a flat schema.json plus <table>.csv dump there
+	// instead of a scenario revision; Scenario is then not used. With
+	// DBURL set it needs no seedmancer.yaml.
+	OutputDir  string `json:"outputDir,omitempty" jsonschema:"Write schema.json and table CSVs to this empty directory instead of a scenario revision"`
+	SchemaOnly bool   `json:"schemaOnly,omitempty" jsonschema:"With outputDir: write schema.json and function/trigger files only, no data"`
+	// SkipUnreadable leaves out tables the credentials can't SELECT from
+	// instead of failing; the revision records them as skipped.
+	SkipUnreadable bool `json:"skipUnreadable,omitempty" jsonschema:"Skip tables the credentials can't read instead of failing; the revision is marked partial"`
+	// Filters maps a table to the SQL predicate its exported rows must
+	// satisfy; they are recorded on the revision manifest.
+	Filters map[string]string `json:"filters,omitempty" jsonschema:"Per-table SQL predicates rows must satisfy to be exported, e.g. {\"users\": \"created_at > now() - interval '30 days'\"}"`
+	// SkipUnchanged discards the new revision when its schema and data
+	// match the latest one, so a scheduled export only adds revisions
+	// when something changed.
+	SkipUnchanged bool `json:"skipUnchanged,omitempty" jsonschema:"Keep no new revision when schema and data match the latest one"`
+	// Tables limits the export to these tables' rows; the schema is
+	// always exported whole.
+	Tables []string `json:"tables,omitempty" jsonschema:"Export only these tables' rows (every table by default)"`
+	// ReadOnly opens the connection with the server holding every
+	// session to reads, and fails when it can't be made so.
+	ReadOnly bool `json:"readOnly,omitempty" jsonschema:"Connect read-only (default_transaction_read_only on PostgreSQL) and refuse to export when the session isn't"`
+}
+
+// connectExport opens manager on dsn for an export, read-only when
+// readOnly.
+func connectExport(manager db.DatabaseManager, dsn string, readOnly bool) error {
+	connect := manager.ConnectWithDSN
+	if readOnly {
+		connect = manager.ConnectReadOnly
+	}
+	if err := connect(dsn); err != nil {
+		return fmt.Errorf("connecting to database: %v", err)
+	}
+	return nil
+}
+
+// checkExportSpace refuses an export whose tables, by the database's own
+// estimate, won't fit in dir. Engines without an estimate pass.
+func checkExportSpace(manager db.DatabaseManager, dir string, opts db.ExportOptions) error {
+	estimator, ok := manager.(db.SizeEstimator)
+	if !ok {
+		return nil
+	}
+	need, err := estimator.EstimateExportBytes(opts)
+	if err != nil {
+		return nil
+	}
+	return diskspace.Check(dir, need, "the export")
 }
 
 // ExportOutput summarises the freshly created revision. Path points at
@@ -1227,13 +1570,34 @@ type ExportOutput struct {
 	Env               string         `json:"env"`
 	Tables            []string       `json:"tables"`
 	RowCounts         map[string]int `json:"rowCounts"`
+	// PreviousRevision is the latest revision before this export, if any.
+	// DroppedTables and EmptyNewTables compare the export against it.
+	PreviousRevision string   `json:"previousRevision,omitempty"`
+	DroppedTables    []string `json:"droppedTables,omitempty"`
+	EmptyNewTables   []string `json:"emptyNewTables,omitempty"`
+	// RemovedSidecars are function/trigger files deleted from the schema
+	// folder because the database no longer has them.
+	RemovedSidecars []string `json:"removedSidecars,omitempty"`
+	// SkippedTables are the unreadable tables SkipUnreadable left out.
+	SkippedTables []string `json:"skippedTables,omitempty"`
+	// Excluded are the columns exclude_columns left out of the CSVs.
+	Excluded []string `json:"excluded,omitempty"`
+	// Unchanged is set when SkipUnchanged found nothing new: Revision and
+	// Path are then the latest revision's, which is left as it was.
+	Unchanged bool `json:"unchanged,omitempty"`
 }
 
 // RunExport materialises a new revision under the requested scenario
 // and updates pointers.latest. Existing revisions are never touched —
 // the only mutation outside the new revision folder is the manifest
 // timestamps and the latest pointer.
-func RunExport(_ context.Context, in ExportInput) (ExportOutput, error) {
+func RunExport(ctx context.Context, in ExportInput) (ExportOutput, error) {
+	if strings.TrimSpace(in.OutputDir) != "" {
+		return runExportDir(ctx, in)
+	}
+	if in.SchemaOnly {
+		return ExportOutput{}, fmt.Errorf("schemaOnly needs outputDir: a scenario revision always carries data")
+	}
 	scenarioPath, err := scenario.Normalize(in.Scenario)
 	if err != nil {
 		return ExportOutput{}, err
@@ -1248,6 +1612,9 @@ func RunExport(_ context.Context, in ExportInput) (ExportOutput, error) {
 	if err != nil {
 		return ExportOutput{}, err
 	}
+	if err := checkStaticFilters(cfg, in.Filters); err != nil {
+		return ExportOutput{}, err
+	}
 
 	target, err := pickExportTarget(cfg, in.Env, in.DBURL)
 	if err != nil {
@@ -1258,8 +1625,8 @@ func RunExport(_ context.Context, in ExportInput) (ExportOutput, error) {
 	if err != nil {
 		return ExportOutput{}, err
 	}
-	if err := manager.ConnectWithDSN(normalizedURL); err != nil {
-		return ExportOutput{}, fmt.Errorf("connecting to database: %v", err)
+	if err := connectExport(manager, normalizedURL, in.ReadOnly); err != nil {
+		return ExportOutput{}, err
 	}
 
 	tmpSchema, err := os.MkdirTemp("", "seedmancer-schema-*")
@@ -1270,6 +1637,10 @@ func RunExport(_ context.Context, in ExportInput) (ExportOutput, error) {
 	if err := manager.ExportSchema(tmpSchema); err != nil {
 		return ExportOutput{}, fmt.Errorf("exporting schema: %v", err)
 	}
+	excluded, err := db.MarkExcludedColumns(tmpSchema, cfg.ExcludeColumns)
+	if err != nil {
+		return ExportOutput{}, err
+	}
 	fingerprint, err := utils.FingerprintSchemaFile(filepath.Join(tmpSchema, "schema.json"))
 	if err != nil {
 		return ExportOutput{}, fmt.Errorf("fingerprinting schema: %v", err)
@@ -1280,7 +1651,8 @@ func RunExport(_ context.Context, in ExportInput) (ExportOutput, error) {
 	if err := os.MkdirAll(schemaDir, 0755); err != nil {
 		return ExportOutput{}, fmt.Errorf("creating schema directory: %v", err)
 	}
-	if err := refreshSchemaFolder(tmpSchema, schemaDir); err != nil {
+	removedSidecars, err := refreshSchemaFolder(tmpSchema, schemaDir)
+	if err != nil {
 		return ExportOutput{}, err
 	}
 
@@ -1308,15 +1680,50 @@ func RunExport(_ context.Context, in ExportInput) (ExportOutput, error) {
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return ExportOutput{}, fmt.Errorf("creating revision data directory: %v", err)
 	}
-	if err := manager.ExportToCSV(dataDir); err != nil {
+	exportOpts := db.ExportOptions{SkipUnreadable: in.SkipUnreadable, Filters: in.Filters, ExcludedColumns: excluded, Tables: in.Tables, ShardRows: cfg.CSVShardRows}
+	if err := checkExportSpace(manager, dataDir, exportOpts); err != nil {
+		return ExportOutput{}, err
+	}
+	skipped, err := manager.ExportToCSVWithOptions(dataDir, exportOpts)
+	if err != nil {
 		return ExportOutput{}, fmt.Errorf("exporting data: %v", err)
 	}
+	shards, err := csvshard.Tables(dataDir)
+	if err != nil {
+		return ExportOutput{}, err
+	}
 
 	tables, rowCounts, err := listCSVTablesAndRowCounts(dataDir)
 	if err != nil {
 		return ExportOutput{}, err
 	}
 
+	scenarioManifest, err := scenario.ReadManifest(scenarioDir)
+	if err != nil && !os.IsNotExist(err) {
+		return ExportOutput{}, err
+	}
+	var dropped, emptyNew []string
+	var prev scenario.RevisionManifest
+	if prevID := scenarioManifest.Latest; prevID != "" {
+		prev, err = scenario.ReadRevisionManifest(scenario.RevisionDir(projectRoot, cfg.StoragePath, scenarioPath, prevID))
+		// A selective export (Tables) says nothing about the tables it
+		// left out, so none of them is reported dropped.
+		if err == nil && len(in.Tables) == 0 {
+			// A table skipped this time is still in the database.
+			skip := map[string]bool{}
+			for _, t := range skipped {
+				skip[t] = true
+			}
+			var kept []string
+			for _, t := range prev.Tables {
+				if !skip[t] {
+					kept = append(kept, t)
+				}
+			}
+			dropped, emptyNew = reconcileTables(kept, tables, rowCounts)
+		}
+	}
+
 	now := time.Now().UTC()
 	revManifest := scenario.RevisionManifest{
 		Scenario:          scenarioPath,
@@ -1328,15 +1735,39 @@ func RunExport(_ context.Context, in ExportInput) (ExportOutput, error) {
 		Services:          []string{"postgres"},
 		RowCounts:         rowCounts,
 		Description:       strings.TrimSpace(in.Description),
+		SkippedTables:     skipped,
+		Filters:           in.Filters,
+		Excluded:          excluded,
+		Shards:            shards,
 	}
-	if err := scenario.WriteRevisionManifest(revRoot, revManifest); err != nil {
+	if err := stampRevisionMetadata(&revManifest, dataDir, target.DatabaseURL); err != nil {
 		return ExportOutput{}, err
 	}
-
-	scenarioManifest, err := scenario.ReadManifest(scenarioDir)
-	if err != nil && !os.IsNotExist(err) {
+	if in.SkipUnchanged && prev.Checksum != "" && prev.Checksum == revManifest.Checksum &&
+		prev.SchemaFingerprint == fingerprint && maps.Equal(prev.Filters, in.Filters) &&
+		slices.Equal(prev.Excluded, excluded) {
+		// revRoot is removed on the way out, as on failure.
+		return ExportOutput{
+			Scenario:          scenarioPath,
+			Revision:          scenarioManifest.Latest,
+			SchemaFingerprint: fingerprint,
+			SchemaShort:       fpShort,
+			Path:              scenario.RevisionDataDir(projectRoot, cfg.StoragePath, scenarioPath, scenarioManifest.Latest),
+			Env:               target.Name,
+			Tables:            prev.Tables,
+			RowCounts:         prev.RowCounts,
+			PreviousRevision:  scenarioManifest.Latest,
+			RemovedSidecars:   removedSidecars,
+			SkippedTables:     skipped,
+			Excluded:          excluded,
+			Unchanged:         true,
+		}, nil
+	}
+	if err := scenario.WriteRevisionManifest(revRoot, revManifest); err != nil {
 		return ExportOutput{}, err
 	}
+
+	previousRevision := scenarioManifest.Latest
 	if scenarioManifest.Scenario == "" {
 		scenarioManifest = scenario.Manifest{Scenario: scenarioPath, CreatedAt: now}
 	}
@@ -1356,9 +1787,82 @@ func RunExport(_ context.Context, in ExportInput) (ExportOutput, error) {
 		Env:               target.Name,
 		Tables:            tables,
 		RowCounts:         rowCounts,
+		PreviousRevision:  previousRevision,
+		DroppedTables:     dropped,
+		EmptyNewTables:    emptyNew,
+		RemovedSidecars:   removedSidecars,
+		SkippedTables:     skipped,
+		Excluded:          excluded,
 	}, nil
 }
 
+// runExportDir is RunExport with OutputDir: the dump lands in a plain
+// directory in the layout `validate --dir` reads, and nothing under the
+// storage path is touched. The directory must be empty or missing, so
+// CSVs of tables dropped since an earlier dump can't linger in it.
+func runExportDir(_ context.Context, in ExportInput) (ExportOutput, error) {
+	outDir := strings.TrimSpace(in.OutputDir)
+	if entries, err := os.ReadDir(outDir); err == nil && len(entries) > 0 {
+		return ExportOutput{}, fmt.Errorf("%s is not empty — pick a new --output-dir or empty it first", outDir)
+	}
+	_, cfg, err := loadConfigOrAdHoc(in.DBURL)
+	if err != nil {
+		return ExportOutput{}, err
+	}
+	if err := checkStaticFilters(cfg, in.Filters); err != nil {
+		return ExportOutput{}, err
+	}
+	target, err := pickExportTarget(cfg, in.Env, in.DBURL)
+	if err != nil {
+		return ExportOutput{}, err
+	}
+	manager, normalizedURL, err := db.NewManager(target.DatabaseURL)
+	if err != nil {
+		return ExportOutput{}, err
+	}
+	if err := connectExport(manager, normalizedURL, in.ReadOnly); err != nil {
+		return ExportOutput{}, err
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return ExportOutput{}, fmt.Errorf("creating output directory: %v", err)
+	}
+	if err := manager.ExportSchema(outDir); err != nil {
+		return ExportOutput{}, fmt.Errorf("exporting schema: %v", err)
+	}
+	excluded, err := db.MarkExcludedColumns(outDir, cfg.ExcludeColumns)
+	if err != nil {
+		return ExportOutput{}, err
+	}
+	fingerprint, err := utils.FingerprintSchemaFile(filepath.Join(outDir, "schema.json"))
+	if err != nil {
+		return ExportOutput{}, fmt.Errorf("fingerprinting schema: %v", err)
+	}
+	out := ExportOutput{
+		SchemaFingerprint: fingerprint,
+		SchemaShort:       utils.FingerprintShort(fingerprint),
+		Path:              outDir,
+		Env:               target.Name,
+		Excluded:          excluded,
+	}
+	if in.SchemaOnly {
+		return out, nil
+	}
+	exportOpts := db.ExportOptions{SkipUnreadable: in.SkipUnreadable, Filters: in.Filters, ExcludedColumns: excluded, Tables: in.Tables}
+	if err := checkExportSpace(manager, outDir, exportOpts); err != nil {
+		return ExportOutput{}, err
+	}
+	out.SkippedTables, err = manager.ExportToCSVWithOptions(outDir, exportOpts)
+	if err != nil {
+		return ExportOutput{}, fmt.Errorf("exporting data: %v", err)
+	}
+	out.Tables, out.RowCounts, err = listCSVTablesAndRowCounts(outDir)
+	if err != nil {
+		return ExportOutput{}, err
+	}
+	return out, nil
+}
+
 // pickExportTarget mirrors resolveSingleDB but works from raw strings so
 // the MCP handler can call RunExport without a cli.Context.
 func pickExportTarget(cfg utils.Config, envName, dbURL string) (utils.NamedEnv, error) {
@@ -1808,6 +2312,9 @@ func RunGenerateLocal(ctx context.Context, in GenerateLocalInput) (GenerateLocal
 		RowCounts:         rowCounts,
 		Description:       strings.TrimSpace(in.Description),
 	}
+	if err := stampRevisionMetadata(&revManifest, dataDir, target.DatabaseURL); err != nil {
+		return GenerateLocalOutput{}, err
+	}
 	if err := scenario.WriteRevisionManifest(revDir, revManifest); err != nil {
 		return GenerateLocalOutput{}, err
 	}
@@ -1849,8 +2356,9 @@ func RunGenerateLocal(ctx context.Context, in GenerateLocalInput) (GenerateLocal
 // SyncInput uploads a scenario revision. The server stores an immutable
 // revision row (r001, r002, …) and advances the scenario's latest pointer.
 type SyncInput struct {
-	Scenario string `json:"scenario" jsonschema:"Scenario path whose latest revision should be uploaded"`
-	Token    string `json:"token,omitempty" jsonschema:"API token override"`
+	Scenario   string `json:"scenario" jsonschema:"Scenario path whose latest revision should be uploaded"`
+	Token      string `json:"token,omitempty" jsonschema:"API token override"`
+	OnConflict string `json:"onConflict,omitempty" jsonschema:"What to do when the cloud copy changed since the scenario was last pulled or pushed: overwrite it, rename (push a copy under the next revision label) or diff (pull the cloud copy and report the row differences, pushing nothing). Empty fails on such a conflict."`
 }
 
 type SyncOutput struct {
@@ -1858,6 +2366,11 @@ type SyncOutput struct {
 	Revision string `json:"revision"`
 	Schema   string `json:"schema"`
 	ID       string `json:"id,omitempty"`
+	// Pulled is the revision onConflict "diff" pulled the cloud copy as;
+	// nothing was pushed.
+	Pulled string `json:"pulled,omitempty"`
+	// Changes are the local revision's rows against the pulled cloud copy.
+	Changes []patch.TableChange `json:"changes,omitempty"`
 }
 
 func RunSync(ctx context.Context, in SyncInput) (SyncOutput, error) {
@@ -1878,39 +2391,52 @@ func RunSync(ctx context.Context, in SyncInput) (SyncOutput, error) {
 	if err != nil {
 		return SyncOutput{}, err
 	}
+	onConflict, err := parseConflictChoice(in.OnConflict)
+	if err != nil {
+		return SyncOutput{}, err
+	}
 	rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, "")
 	if err != nil {
 		return SyncOutput{}, err
 	}
-	fpShort := utils.FingerprintShort(rev.Manifest.SchemaFingerprint)
-	schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, fpShort)
+	if err := verifyRevisionChecksum(rev); err != nil {
+		return SyncOutput{}, err
+	}
 	baseURL := utils.GetBaseURL()
 
 	projectSlug := utils.ResolveProjectSlug("", cfg)
 	utils.SetGlobalProjectSlug(projectSlug)
 
-	schemaFiles, err := utils.SchemaFiles(schemaDir)
-	if err != nil {
-		return SyncOutput{}, err
-	}
-	dataFiles, err := utils.DatasetFiles(rev.DataDir)
+	cloudDatasets, err := listRemoteDatasets(baseURL, token)
 	if err != nil {
-		return SyncOutput{}, err
-	}
-	if len(dataFiles) == 0 {
-		return SyncOutput{}, fmt.Errorf("no CSV or JSON files in %s", rev.DataDir)
+		return SyncOutput{}, fmt.Errorf("listing cloud datasets: %w", err)
 	}
-	entries := append(append([]string{}, schemaFiles...), dataFiles...)
-	// Bundle the agent-written SQL sidecar (if present) so a round-trip
-	// pull preserves the source of truth, not just the materialised CSVs.
-	if sqlPath := DatasetSQLPath(rev.RevDir); fileExists(sqlPath) {
-		entries = append(entries, sqlPath)
+	if cloudDS, ok := cloudDatasetFor(cloudDatasets, scenarioPath, rev.ScenarioManifest.RemoteScenarioID); ok &&
+		!isPushUpToDate(rev.Manifest, cloudDS) && remoteChanged(scenario.ScenarioDir(projectRoot, cfg.StoragePath, scenarioPath), cloudDS) {
+		var diff *cloudDiff
+		rev, diff, err = settleConflict(ctx, projectRoot, cfg, rev, cloudDS, onConflict, token)
+		if err != nil {
+			return SyncOutput{}, err
+		}
+		if diff != nil {
+			return SyncOutput{
+				Scenario: scenarioPath,
+				Revision: rev.RevID,
+				Schema:   utils.FingerprintShort(rev.Manifest.SchemaFingerprint),
+				Pulled:   diff.Pulled,
+				Changes:  diff.Changes,
+			}, nil
+		}
 	}
-	zipData, err := compressFiles(entries)
+	fpShort := utils.FingerprintShort(rev.Manifest.SchemaFingerprint)
+	schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, fpShort)
+
+	entries, cleanupSums, err := pushEntries(schemaDir, rev.DataDir)
 	if err != nil {
-		return SyncOutput{}, fmt.Errorf("compressing files: %v", err)
+		return SyncOutput{}, err
 	}
-	result, err := syncUploadPresigned(ctx, token, baseURL, scenarioPath, rev.RevID, utils.ResolveProjectSlug("", cfg), zipData)
+	defer cleanupSums()
+	result, err := syncUploadPresigned(ctx, token, baseURL, scenarioPath, rev.RevID, utils.ResolveProjectSlug("", cfg), entries)
 	if err != nil {
 		return SyncOutput{}, err
 	}
@@ -1950,8 +2476,14 @@ type FetchOutput struct {
 	// UpToDate is true when the local latest revision already mirrors the
 	// cloud's latest revision, so no download happened.
 	UpToDate bool `json:"upToDate,omitempty"`
-	// BytesDownloaded is the size of the downloaded archive (0 when UpToDate).
+	// BytesDownloaded is the size of the downloaded archive (0 when UpToDate),
+	// or of the parts of it read when the pull was incremental.
 	BytesDownloaded int64 `json:"bytesDownloaded,omitempty"`
+	// Reused counts the files copied from the previous local revision
+	// because the cloud copy's were identical, instead of downloaded.
+	Reused int `json:"reused,omitempty"`
+	// Masked are the columns pull_masking masked, as "table.column".
+	Masked []string `json:"masked,omitempty"`
 }
 
 func RunFetch(ctx context.Context, in FetchInput) (FetchOutput, error) {
@@ -2021,8 +2553,9 @@ func RunFetch(ctx context.Context, in FetchInput) (FetchOutput, error) {
 	// cloud's latest revision (stamped by a previous pull or push). This
 	// keeps warm CI runs and repeated pulls fast and avoids piling up
 	// duplicate revisions.
+	var latestDir string
 	if m, mErr := scenario.ReadManifest(scenarioDir); mErr == nil && m.Latest != "" {
-		latestDir := scenario.RevisionDir(projectRoot, cfg.StoragePath, scenarioPath, m.Latest)
+		latestDir = scenario.RevisionDir(projectRoot, cfg.StoragePath, scenarioPath, m.Latest)
 		if rm, rErr := scenario.ReadRevisionManifest(latestDir); rErr == nil &&
 			rm.RemoteID != "" && rm.RemoteID == match.ID &&
 			rm.RemoteUpdatedAt != "" && rm.RemoteUpdatedAt == match.UpdatedAt {
@@ -2037,6 +2570,52 @@ func RunFetch(ctx context.Context, in FetchInput) (FetchOutput, error) {
 		}
 	}
 
+	// With a local revision to start from, only the files it doesn't
+	// already hold are downloaded, read out of the remote archive in
+	// ranges. Masked pulls rewrite files on the way in, so they never
+	// match and take the whole archive.
+	var (
+		zipReader       *zip.Reader
+		ranged          *rangeArchive
+		reuse           map[string]string
+		downloadedBytes int64
+	)
+	if latestDir != "" && (len(cfg.PullMasking) == 0 || onCI()) {
+		if zipReader, ranged, reuse, err = openIncrementalPull(ctx, baseURL, token, match, latestDir, schemaDir); err != nil {
+			return FetchOutput{}, err
+		}
+	}
+	if zipReader == nil {
+		// Download before allocating a revision, so a failed or interrupted
+		// pull leaves nothing behind but the partial archive it resumes from.
+		archivePath, err := datasetDownloadPath(match)
+		if err != nil {
+			return FetchOutput{}, err
+		}
+		if err := diskspace.Check(filepath.Dir(archivePath), match.TotalSize, "downloading "+scenarioPath); err != nil {
+			return FetchOutput{}, err
+		}
+		if downloadedBytes, err = downloadDatasetArchive(ctx, baseURL, token, match, archivePath, "Downloading "+scenarioPath); err != nil {
+			return FetchOutput{}, err
+		}
+		defer os.Remove(archivePath)
+		archive, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return FetchOutput{}, fmt.Errorf("opening zip file: %v", err)
+		}
+		defer archive.Close()
+		zipReader = &archive.Reader
+	}
+	// Under pull_masking, masked columns are rewritten as the archive is
+	// extracted, so their raw values never reach the revision.
+	masker, err := pullMasker(cfg, zipReader)
+	if err != nil {
+		return FetchOutput{}, err
+	}
+	if err := diskspace.Check(scenarioDir, extractedSize(zipReader), "extracting "+scenarioPath); err != nil {
+		return FetchOutput{}, err
+	}
+
 	if err := os.MkdirAll(scenarioDir, 0755); err != nil {
 		return FetchOutput{}, fmt.Errorf("creating scenario dir: %v", err)
 	}
@@ -2050,14 +2629,31 @@ func RunFetch(ctx context.Context, in FetchInput) (FetchOutput, error) {
 		return FetchOutput{}, fmt.Errorf("creating revision data dir: %v", err)
 	}
 
-	downloadURL, err := getDownloadURL(baseURL, token, match.ID)
+	extracted, raw, err := extractZipReader(zipReader, dataDir, masker, reuse)
 	if err != nil {
+		_ = os.RemoveAll(revDir)
 		return FetchOutput{}, err
 	}
-	extracted, downloadedBytes, err := downloadAndExtractZip(downloadURL, dataDir)
+	if ranged != nil {
+		downloadedBytes = ranged.fetched
+	}
+	var masked []string
+	if masker != nil {
+		masked = masker.Columns()
+	} else {
+		raw = nil
+	}
+	if err := verifyExtractedChecksums(dataDir, raw); err != nil {
+		_ = os.RemoveAll(revDir)
+		return FetchOutput{}, fmt.Errorf("pulled archive for %s failed verification: %w", scenarioPath, err)
+	}
+	extracted = removeName(extracted, scenario.ChecksumsFileName)
+	pushed, err := takePushedManifest(dataDir)
 	if err != nil {
-		return FetchOutput{}, err
+		_ = os.RemoveAll(revDir)
+		return FetchOutput{}, fmt.Errorf("reading the pushed manifest of %s: %w", scenarioPath, err)
 	}
+	extracted = removeName(extracted, "manifest.json")
 	if _, err := liftSchemaSidecars(dataDir, schemaDir); err != nil {
 		return FetchOutput{}, fmt.Errorf("placing schema files: %v", err)
 	}
@@ -2081,6 +2677,19 @@ func RunFetch(ctx context.Context, in FetchInput) (FetchOutput, error) {
 		RowCounts:         rowCounts,
 		RemoteID:          match.ID,
 		RemoteUpdatedAt:   match.UpdatedAt,
+		Masked:            masked,
+	}
+	// The engine travels in schema.json, so a pulled revision knows it as
+	// well as an exported one does.
+	if schema, err := utils.ReadSchemaJSON(scenario.SchemaJSONPath(projectRoot, cfg.StoragePath, fpShort)); err == nil {
+		revManifest.DatabaseType = schema.DatabaseType
+	}
+	if err := stampRevisionMetadata(&revManifest, dataDir, ""); err != nil {
+		return FetchOutput{}, err
+	}
+	if err := adoptPushedManifest(&revManifest, pushed); err != nil {
+		_ = os.RemoveAll(revDir)
+		return FetchOutput{}, fmt.Errorf("pulled archive for %s failed verification: %w", scenarioPath, err)
 	}
 	if err := scenario.WriteRevisionManifest(revDir, revManifest); err != nil {
 		return FetchOutput{}, err
@@ -2112,6 +2721,8 @@ func RunFetch(ctx context.Context, in FetchInput) (FetchOutput, error) {
 		Path:              dataDir,
 		Files:             extracted,
 		BytesDownloaded:   downloadedBytes,
+		Reused:            len(reuse),
+		Masked:            masked,
 	}, nil
 }
 
@@ -7,9 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
-	"strings"
 
+	"github.com/KazanKK/seedmancer/internal/scenario"
 	utils "github.com/KazanKK/seedmancer/internal/utils"
 )
 
@@ -36,6 +35,11 @@ type syncUploadResult struct {
 type uploadURLResponse struct {
 	UploadURL string `json:"uploadUrl"`
 	Path      string `json:"path"`
+	// Reuse names the files of the upload the server already holds, with
+	// the checksums the request listed, in the scenario's latest cloud
+	// revision; the zip leaves them out. Servers without incremental
+	// sync don't send it.
+	Reuse []string `json:"reuse,omitempty"`
 }
 
 // pushScenarioPrompt syncs the scenario's saved purpose to the cloud via
@@ -72,104 +76,31 @@ func pushScenarioPrompt(ctx context.Context, token, baseURL, datasetID, prompt,
 	return nil
 }
 
-// syncUploadPresigned uploads zipData via the three-step presigned URL flow:
-//  1. POST /v1.0/datasets/sync/upload-url  → receive { uploadUrl, path }
-//  2. PUT  uploadUrl                       → stream bytes directly to storage
+// syncUploadPresigned uploads the files in entries, the last of them the
+// checksums listing written by writeBundleChecksums, via the three-step
+// presigned URL flow:
+//  1. POST /v1.0/datasets/sync/upload-url  → receive { uploadUrl, path, reuse }
+//  2. PUT  uploadUrl                       → stream the zip directly to storage
 //  3. POST /v1.0/datasets/sync/confirm     → process ZIP + register dataset
 //
 // This bypasses the Vercel function body-size limit (≈4.5 MB) so datasets
-// of any size can be synced.
-func syncUploadPresigned(ctx context.Context, token, baseURL, datasetName, revisionLabel, projectSlug string, zipData *bytes.Buffer) (syncUploadResult, error) {
-	// Step 1: request a presigned upload URL.
-	q1 := url.Values{}
-	q1.Set("name", datasetName)
-	if strings.TrimSpace(revisionLabel) != "" {
-		q1.Set("revision", strings.TrimSpace(revisionLabel))
-	}
-	uploadURLEndpoint := fmt.Sprintf("%s/v1.0/datasets/sync/upload-url?%s", baseURL, q1.Encode())
-
-	req1, err := http.NewRequestWithContext(ctx, "POST", uploadURLEndpoint, nil)
-	if err != nil {
-		return syncUploadResult{}, fmt.Errorf("creating upload-url request: %v", err)
-	}
-	req1.Header.Set("Authorization", utils.BearerAPIToken(token))
-	utils.ApplyProjectHeader(req1, projectSlug)
-
-	resp1, err := http.DefaultClient.Do(req1)
-	if err != nil {
-		return syncUploadResult{}, fmt.Errorf("requesting upload URL: %v", err)
-	}
-	defer resp1.Body.Close()
-
-	body1, _ := io.ReadAll(resp1.Body)
-	if resp1.StatusCode == http.StatusUnauthorized {
-		return syncUploadResult{}, utils.ErrInvalidAPIToken
-	}
-	if resp1.StatusCode == http.StatusPaymentRequired {
-		return syncUploadResult{}, formatLimitError(body1)
-	}
-	if resp1.StatusCode != http.StatusOK {
-		return syncUploadResult{}, fmt.Errorf("server responded %s: %s", resp1.Status, string(body1))
-	}
-
-	var uploadURLResp uploadURLResponse
-	if err := json.Unmarshal(body1, &uploadURLResp); err != nil || uploadURLResp.UploadURL == "" {
-		return syncUploadResult{}, fmt.Errorf("parsing upload-url response: %v", err)
-	}
-
-	// Step 2: PUT the zip directly to storage (presigned URL — no auth header).
-	req2, err := http.NewRequestWithContext(ctx, "PUT", uploadURLResp.UploadURL, bytes.NewReader(zipData.Bytes()))
+// of any size can be synced. Files the server says it already holds are
+// left out of the zip.
+func syncUploadPresigned(ctx context.Context, token, baseURL, datasetName, revisionLabel, projectSlug string, entries []string) (syncUploadResult, error) {
+	sums, err := scenario.ReadChecksumsFile(entries[len(entries)-1])
 	if err != nil {
-		return syncUploadResult{}, fmt.Errorf("creating storage PUT request: %v", err)
+		return syncUploadResult{}, err
 	}
-	req2.Header.Set("Content-Type", "application/zip")
-	req2.ContentLength = int64(zipData.Len())
-
-	resp2, err := http.DefaultClient.Do(req2)
-	if err != nil {
-		return syncUploadResult{}, fmt.Errorf("uploading to storage: %v", err)
-	}
-	resp2.Body.Close()
-	if resp2.StatusCode != http.StatusOK && resp2.StatusCode != http.StatusCreated {
-		return syncUploadResult{}, fmt.Errorf("storage upload failed (HTTP %d)", resp2.StatusCode)
-	}
-
-	// Step 3: confirm — server parses the ZIP, resolves schema, and records the revision.
-	q3 := url.Values{}
-	q3.Set("name", datasetName)
-	q3.Set("path", uploadURLResp.Path)
-	if strings.TrimSpace(revisionLabel) != "" {
-		q3.Set("revision", strings.TrimSpace(revisionLabel))
-	}
-	confirmEndpoint := fmt.Sprintf("%s/v1.0/datasets/sync/confirm?%s", baseURL, q3.Encode())
-
-	req3, err := http.NewRequestWithContext(ctx, "POST", confirmEndpoint, nil)
+	uploadURLResp, err := requestUploadURL(ctx, token, baseURL, datasetName, revisionLabel, "", projectSlug, sums)
 	if err != nil {
-		return syncUploadResult{}, fmt.Errorf("creating confirm request: %v", err)
+		return syncUploadResult{}, err
 	}
-	req3.Header.Set("Authorization", utils.BearerAPIToken(token))
-	utils.ApplyProjectHeader(req3, projectSlug)
-
-	resp3, err := http.DefaultClient.Do(req3)
+	zipData, err := compressFiles(uploadEntries(entries, uploadURLResp.Reuse))
 	if err != nil {
-		return syncUploadResult{}, fmt.Errorf("confirming upload: %v", err)
-	}
-	defer resp3.Body.Close()
-
-	body3, _ := io.ReadAll(resp3.Body)
-	if resp3.StatusCode == http.StatusUnauthorized {
-		return syncUploadResult{}, utils.ErrInvalidAPIToken
-	}
-	if resp3.StatusCode == http.StatusPaymentRequired {
-		return syncUploadResult{}, formatLimitError(body3)
+		return syncUploadResult{}, fmt.Errorf("compressing files: %v", err)
 	}
-	if resp3.StatusCode != http.StatusOK && resp3.StatusCode != http.StatusCreated {
-		return syncUploadResult{}, fmt.Errorf("server responded %s: %s", resp3.Status, string(body3))
+	if err := putToStorage(ctx, uploadURLResp.UploadURL, zipData); err != nil {
+		return syncUploadResult{}, err
 	}
-
-	var result syncUploadResult
-	if err := json.Unmarshal(body3, &result); err != nil {
-		return syncUploadResult{}, fmt.Errorf("parsing confirm response: %v", err)
-	}
-	return result, nil
-}
\ No newline at end of file
+	return confirmUpload(ctx, token, baseURL, datasetName, uploadURLResp.Path, revisionLabel, "", projectSlug)
+}
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// SaveCommand promotes a revision of one scenario into another, named
+// scenario. The usual flow is to export or generate into a scratch
+// scenario, look at it, and then save the keeper under its real name:
+//
+//	seedmancer export scratch
+//	seedmancer save scratch billing/pro
+//
+// The copy is staged next to the target's revisions and renamed into place,
+// so a crash never leaves a half-written rNNN behind.
+func SaveCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "save",
+		Usage:     "Promote a revision of one scenario into a named scenario",
+		ArgsUsage: "<source-scenario> <target-scenario>",
+		Description: "Copies a revision (latest by default) of <source-scenario> — CSVs and\n" +
+			"dataset.sql — into a new revision of <target-scenario> and points\n" +
+			"the target's latest at it. The source revision must have its\n" +
+			"schema.json in the local schema store.\n\n" +
+			"Saving into a scenario that already has revisions requires --force;\n" +
+			"the copy is then added as its next revision (nothing is overwritten).\n\n" +
+			"Examples:\n" +
+			"  seedmancer save scratch billing/pro\n" +
+			"  seedmancer save scratch billing/pro --revision r002 --force",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "revision",
+				Usage: "Source revision to save (defaults to latest)",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Allow saving into a scenario that already has revisions",
+			},
+			&cli.StringFlag{
+				Name:  "description",
+				Usage: "Optional description stored on the new revision manifest",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			from := strings.TrimSpace(c.Args().Get(0))
+			to := strings.TrimSpace(c.Args().Get(1))
+			if from == "" || to == "" {
+				return usageError(c, "missing required arguments: <source-scenario> <target-scenario>")
+			}
+			out, err := RunSave(context.Background(), SaveInput{
+				From:        from,
+				To:          to,
+				Revision:    strings.TrimSpace(c.String("revision")),
+				Force:       c.Bool("force"),
+				Description: strings.TrimSpace(c.String("description")),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+			ui.Success("Saved %s @ %s as %s @ %s", out.From, out.FromRevision, out.Scenario, out.Revision)
+			ui.KeyValue("Schema: ", out.Schema)
+			ui.KeyValue("Tables: ", strings.Join(out.Tables, ", "))
+			ui.KeyValue("Run: ", fmt.Sprintf("seedmancer seed %s", out.Scenario))
+			return nil
+		},
+	}
+}
+
+// SaveInput names the revision to promote and where it should land.
+type SaveInput struct {
+	From        string `json:"from" jsonschema:"Source scenario path"`
+	To          string `json:"to" jsonschema:"Target scenario path"`
+	Revision    string `json:"revision,omitempty" jsonschema:"Source revision (defaults to latest)"`
+	Force       bool   `json:"force,omitempty" jsonschema:"Allow saving into a scenario that already has revisions"`
+	Description string `json:"description,omitempty" jsonschema:"Optional description stored on the new revision manifest"`
+}
+
+// SaveOutput reports the newly created target revision.
+type SaveOutput struct {
+	From         string   `json:"from"`
+	FromRevision string   `json:"fromRevision"`
+	Scenario     string   `json:"scenario"`
+	Revision     string   `json:"revision"`
+	Schema       string   `json:"schema"`
+	Tables       []string `json:"tables"`
+	Path         string   `json:"path"`
+}
+
+// RunSave copies a source revision into a fresh revision of the target
+// scenario and advances the target's latest pointer.
+func RunSave(_ context.Context, in SaveInput) (SaveOutput, error) {
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return SaveOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return SaveOutput{}, err
+	}
+
+	fromPath, err := scenario.Normalize(in.From)
+	if err != nil {
+		return SaveOutput{}, fmt.Errorf("invalid source scenario: %w", err)
+	}
+	toPath, err := scenario.Normalize(in.To)
+	if err != nil {
+		return SaveOutput{}, fmt.Errorf("invalid target scenario: %w", err)
+	}
+	if fromPath == toPath {
+		return SaveOutput{}, fmt.Errorf("source and target are the same scenario (%s)", fromPath)
+	}
+
+	src, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, fromPath, in.Revision)
+	if err != nil {
+		return SaveOutput{}, err
+	}
+	fpShort := utils.FingerprintShort(src.Manifest.SchemaFingerprint)
+	if src.Manifest.SchemaFingerprint == "" ||
+		!fileExists(scenario.SchemaJSONPath(projectRoot, cfg.StoragePath, fpShort)) {
+		return SaveOutput{}, fmt.Errorf(
+			"%s @ %s has no schema.json in the local schema store — re-export it before saving",
+			fromPath, src.RevID,
+		)
+	}
+
+	targetDir := scenario.ScenarioDir(projectRoot, cfg.StoragePath, toPath)
+	existing, err := scenario.ListRevisions(targetDir)
+	if err != nil {
+		return SaveOutput{}, err
+	}
+	if len(existing) > 0 && !in.Force {
+		return SaveOutput{}, fmt.Errorf(
+			"scenario %q already has %d revision(s) — pass --force to add this as its next revision",
+			toPath, len(existing),
+		)
+	}
+
+	staging, err := newStagingRevision(projectRoot, cfg.StoragePath, toPath, "save")
+	if err != nil {
+		return SaveOutput{}, err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := copyDir(src.DataDir, filepath.Join(staging, "data")); err != nil {
+		return SaveOutput{}, fmt.Errorf("copying data: %w", err)
+	}
+	if sqlPath := DatasetSQLPath(src.RevDir); fileExists(sqlPath) {
+		if err := copyFile(sqlPath, DatasetSQLPath(staging)); err != nil {
+			return SaveOutput{}, fmt.Errorf("copying %s: %w", datasetSQLName, err)
+		}
+	}
+
+	description := strings.TrimSpace(in.Description)
+	if description == "" {
+		description = fmt.Sprintf("saved from %s @ %s", fromPath, src.RevID)
+	}
+	revManifest := src.Manifest
+	revManifest.Source = "save"
+	revManifest.Description = description
+	revManifest.RemoteID = ""
+	revManifest.RemoteUpdatedAt = ""
+	revManifest, err = commitStagedRevision(projectRoot, cfg.StoragePath, toPath, staging, revManifest, src.ScenarioManifest.Prompt)
+	if err != nil {
+		return SaveOutput{}, err
+	}
+	revID := revManifest.Revision
+	revDir := scenario.RevisionDir(projectRoot, cfg.StoragePath, toPath, revID)
+
+	return SaveOutput{
+		From:         fromPath,
+		FromRevision: src.RevID,
+		Scenario:     toPath,
+		Revision:     revID,
+		Schema:       fpShort,
+		Tables:       revManifest.Tables,
+		Path:         filepath.Join(revDir, "data"),
+	}, nil
+}
+
+// newStagingRevision creates a hidden directory under the scenario's
+// revisions/ in which a new revision can be assembled. Hidden names never
+// match rNNN, so listings ignore it until commitStagedRevision renames it
+// into place. Callers remove it on failure.
+func newStagingRevision(projectRoot, storagePath, scenarioPath, kind string) (string, error) {
+	revisionsDir := scenario.RevisionsDir(projectRoot, storagePath, scenarioPath)
+	if err := os.MkdirAll(revisionsDir, 0755); err != nil {
+		return "", fmt.Errorf("creating scenario dir: %w", err)
+	}
+	staging, err := os.MkdirTemp(revisionsDir, "."+kind+"-*")
+	if err != nil {
+		return "", fmt.Errorf("creating staging dir: %w", err)
+	}
+	if err := os.Chmod(staging, 0755); err != nil {
+		_ = os.RemoveAll(staging)
+		return "", err
+	}
+	return staging, nil
+}
+
+// commitStagedRevision stamps m with the scenario, next revision id and
+// creation time, writes it into staging, renames staging to that rNNN
+// and advances latest. prompt seeds the scenario prompt when it has none.
+func commitStagedRevision(projectRoot, storagePath, scenarioPath, staging string, m scenario.RevisionManifest, prompt string) (scenario.RevisionManifest, error) {
+	scenarioDir := scenario.ScenarioDir(projectRoot, storagePath, scenarioPath)
+	revID, err := scenario.NextRevisionID(scenarioDir)
+	if err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+	now := time.Now().UTC()
+	m.Scenario = scenarioPath
+	m.Revision = revID
+	m.CreatedAt = now
+	if err := stampRevisionMetadata(&m, filepath.Join(staging, "data"), ""); err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+	if err := scenario.WriteRevisionManifest(staging, m); err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+	revDir := scenario.RevisionDir(projectRoot, storagePath, scenarioPath, revID)
+	if err := os.Rename(staging, revDir); err != nil {
+		return scenario.RevisionManifest{}, fmt.Errorf("moving revision into place: %w", err)
+	}
+
+	manifest, err := scenario.ReadManifest(scenarioDir)
+	if err != nil && !os.IsNotExist(err) {
+		return scenario.RevisionManifest{}, err
+	}
+	if manifest.Scenario == "" {
+		manifest = scenario.Manifest{Scenario: scenarioPath, CreatedAt: now}
+	}
+	manifest.UpdatedAt = now
+	manifest.Latest = revID
+	if manifest.Prompt == "" {
+		manifest.Prompt = prompt
+	}
+	if err := scenario.WriteManifest(scenarioDir, manifest); err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+	return m, nil
+}
+
+// copyDir copies the regular files of src (non-recursive) into dst.
+func copyDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if err := copyFile(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+			return fmt.Errorf("%s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
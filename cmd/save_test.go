@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+)
+
+func TestRunSave_promotesRevisionIntoNamedScenario(t *testing.T) {
+	dir := stageRevision(t, "scratch", `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer"}]}]}`,
+		map[string]string{"users": "id\n1\n2\n"})
+	writeFile(t, DatasetSQLPath(scenario.RevisionDir(dir, ".seedmancer", "scratch", "r001")), "TRUNCATE users;\n")
+
+	out, err := RunSave(context.Background(), SaveInput{From: "scratch", To: "billing/pro"})
+	if err != nil {
+		t.Fatalf("RunSave: %v", err)
+	}
+	if out.Scenario != "billing/pro" || out.Revision != "r001" || out.FromRevision != "r001" {
+		t.Fatalf("out = %+v", out)
+	}
+	got, err := os.ReadFile(filepath.Join(out.Path, "users.csv"))
+	if err != nil || string(got) != "id\n1\n2\n" {
+		t.Fatalf("users.csv = %q (err %v)", got, err)
+	}
+	revDir := scenario.RevisionDir(dir, ".seedmancer", "billing/pro", "r001")
+	if !fileExists(DatasetSQLPath(revDir)) {
+		t.Fatal("dataset.sql was not carried over")
+	}
+	rm, err := scenario.ReadRevisionManifest(revDir)
+	if err != nil || rm.Source != "save" || rm.Scenario != "billing/pro" {
+		t.Fatalf("revision manifest = %+v (err %v)", rm, err)
+	}
+	m, err := scenario.ReadManifest(scenario.ScenarioDir(dir, ".seedmancer", "billing/pro"))
+	if err != nil || m.Latest != "r001" {
+		t.Fatalf("latest = %q (err %v)", m.Latest, err)
+	}
+
+	// A second save into the same scenario needs --force and appends.
+	if _, err := RunSave(context.Background(), SaveInput{From: "scratch", To: "billing/pro"}); err == nil ||
+		!strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected --force error, got %v", err)
+	}
+	out, err = RunSave(context.Background(), SaveInput{From: "scratch", To: "billing/pro", Force: true})
+	if err != nil || out.Revision != "r002" {
+		t.Fatalf("forced save = %+v (err %v), want r002", out, err)
+	}
+}
+
+func TestRunSave_requiresSchemaJSON(t *testing.T) {
+	dir := stageRevision(t, "scratch", `{"tables":[]}`, map[string]string{"users": "id\n1\n"})
+	if err := os.RemoveAll(filepath.Join(dir, ".seedmancer", "schemas")); err != nil {
+		t.Fatalf("removing schema store: %v", err)
+	}
+	_, err := RunSave(context.Background(), SaveInput{From: "scratch", To: "keep"})
+	if err == nil || !strings.Contains(err.Error(), "schema.json") {
+		t.Fatalf("expected schema.json error, got %v", err)
+	}
+}
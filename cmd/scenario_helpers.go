@@ -13,6 +13,7 @@ import (
 	"time"
 
 	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/csvshard"
 	"github.com/KazanKK/seedmancer/internal/envmarker"
 	"github.com/KazanKK/seedmancer/internal/scenario"
 	"github.com/KazanKK/seedmancer/internal/schemahistory"
@@ -159,8 +160,8 @@ func tryUpdateSchemaHistory(projectRoot, storagePath, fingerprint string) {
 
 // listCSVTablesAndRowCounts walks dataDir, returns the sorted list of
 // tables (.csv basename) and their data-row counts (header excluded).
-// Files larger than the row scan threshold report rowCount=-1 so callers
-// can decide how to display.
+// A table split into shards (orders.csv.001, …) is listed once with the
+// rows of all its shards.
 func listCSVTablesAndRowCounts(dataDir string) (tables []string, rowCounts map[string]int, err error) {
 	entries, err := os.ReadDir(dataDir)
 	if err != nil {
@@ -172,16 +173,21 @@ func listCSVTablesAndRowCounts(dataDir string) (tables []string, rowCounts map[s
 			continue
 		}
 		name := e.Name()
-		if !strings.HasSuffix(strings.ToLower(name), ".csv") {
-			continue
+		table, _, sharded := csvshard.Parse(name)
+		if !sharded {
+			if !strings.HasSuffix(strings.ToLower(name), ".csv") {
+				continue
+			}
+			table = name[:len(name)-len(".csv")]
 		}
-		table := name[:len(name)-len(".csv")]
 		count, err := countCSVDataRows(filepath.Join(dataDir, name))
 		if err != nil {
 			return nil, nil, fmt.Errorf("counting rows in %s: %w", name, err)
 		}
-		tables = append(tables, table)
-		rowCounts[table] = count
+		if _, seen := rowCounts[table]; !seen {
+			tables = append(tables, table)
+		}
+		rowCounts[table] += count
 	}
 	sort.Strings(tables)
 	return tables, rowCounts, nil
@@ -277,14 +283,17 @@ func liftDatasetSQL(dataDir, revDir string) error {
 }
 
 // resolveMarkersDir returns a directory suitable for passing to RestoreFromCSV
-// after resolving all @env:KEY markers in CSV files.
+// after resolving all @env:KEY markers in CSV files and filling the
+// excluded columns (export's exclude_columns) the database can't default.
 //
-// Fast path — no markers anywhere in the CSV files: returns srcDir unchanged
-// with a no-op cleanup so callers pay zero overhead in the common case.
+// Fast path — no markers anywhere in the CSV files and no excluded columns
+// in schema.json: returns srcDir unchanged with a no-op cleanup so callers
+// pay zero overhead in the common case.
 //
-// Slow path — markers present: creates a sibling temp dir, copies every
-// non-CSV file (schema sidecars) via linkOrCopy, writes resolved CSV files
-// for every *.csv file, and returns the new dir with a cleanup func.
+// Slow path — markers or excluded columns present: creates a sibling temp
+// dir, copies every non-CSV file (schema sidecars) via linkOrCopy, writes
+// resolved CSV files for every *.csv file, and returns the new dir with a
+// cleanup func.
 //
 // envName is included in error messages when a key is missing.
 func resolveMarkersDir(srcDir string, values envmarker.EnvironmentValues, envName string) (string, func(), error) {
@@ -311,11 +320,15 @@ func resolveMarkersDir(srcDir string, values envmarker.EnvironmentValues, envNam
 		}
 	}
 
-	if !anyMarker {
+	excluded, err := excludedSchema(srcDir)
+	if err != nil {
+		return "", func() {}, err
+	}
+	if !anyMarker && excluded == nil {
 		return srcDir, func() {}, nil
 	}
 
-	// Markers present — build a per-env resolved copy.
+	// Markers or excluded columns present — build a per-env resolved copy.
 	tmp, err := os.MkdirTemp("", "seedmancer-env-*")
 	if err != nil {
 		return "", func() {}, fmt.Errorf("creating env temp dir: %w", err)
@@ -342,6 +355,13 @@ func resolveMarkersDir(srcDir string, values envmarker.EnvironmentValues, envNam
 			cleanup()
 			return "", func() {}, err
 		}
+		if excluded != nil {
+			table := strings.TrimSuffix(filepath.Base(csvPath), filepath.Ext(csvPath))
+			if records, err = db.FillExcluded(excluded, table, records); err != nil {
+				cleanup()
+				return "", func() {}, err
+			}
+		}
 		dst := filepath.Join(tmp, filepath.Base(csvPath))
 		if err := envmarker.WriteCSV(dst, records); err != nil {
 			cleanup()
@@ -351,3 +371,249 @@ func resolveMarkersDir(srcDir string, values envmarker.EnvironmentValues, envNam
 
 	return tmp, cleanup, nil
 }
+
+// excludedSchema returns the schema.json in dir when it marks any column
+// excluded, and nil when it marks none or dir has no schema.json.
+func excludedSchema(dir string) (*db.Schema, error) {
+	schema, err := db.ReadSchemaFile(filepath.Join(dir, "schema.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range schema.Tables {
+		for _, c := range t.Columns {
+			if c.Excluded {
+				return &schema, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// csvTransform rewrites the records (header included) of one table.
+// Returning the input slice unchanged is fine.
+type csvTransform func(table string, records [][]string) ([][]string, error)
+
+// deriveRevision writes a new revision of base.Scenario whose CSVs are
+// base's CSVs passed through transform, then advances latest to it. The
+// schema fingerprint and prompt carry over because the shape doesn't
+// change; dataset.sql does not, since it would no longer reproduce the
+// data. The half-written revision is removed on any error.
+func deriveRevision(projectRoot string, cfg utils.Config, base resolvedRevision, source, description string, transform csvTransform) (scenario.RevisionManifest, error) {
+	scenarioDir := scenario.ScenarioDir(projectRoot, cfg.StoragePath, base.Scenario)
+	revID, err := scenario.NextRevisionID(scenarioDir)
+	if err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+	revDir := scenario.RevisionDir(projectRoot, cfg.StoragePath, base.Scenario, revID)
+	dataDir := filepath.Join(revDir, "data")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return scenario.RevisionManifest{}, fmt.Errorf("creating revision data dir: %w", err)
+	}
+	success := false
+	defer func() {
+		if !success {
+			_ = os.RemoveAll(revDir)
+		}
+	}()
+
+	tables, _, err := listCSVTablesAndRowCounts(base.DataDir)
+	if err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+	for _, table := range tables {
+		records, err := utils.ReadCSVRecords(filepath.Join(base.DataDir, table+".csv"))
+		if err != nil {
+			return scenario.RevisionManifest{}, err
+		}
+		out, err := transform(table, records)
+		if err != nil {
+			return scenario.RevisionManifest{}, fmt.Errorf("%s: %w", table, err)
+		}
+		if err := envmarker.WriteCSV(filepath.Join(dataDir, table+".csv"), out); err != nil {
+			return scenario.RevisionManifest{}, fmt.Errorf("writing %s.csv: %w", table, err)
+		}
+	}
+
+	tables, rowCounts, err := listCSVTablesAndRowCounts(dataDir)
+	if err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+	now := time.Now().UTC()
+	manifest := scenario.RevisionManifest{
+		Scenario:          base.Scenario,
+		Revision:          revID,
+		SchemaFingerprint: base.Manifest.SchemaFingerprint,
+		CreatedAt:         now,
+		Source:            source,
+		Tables:            tables,
+		Services:          base.Manifest.Services,
+		RowCounts:         rowCounts,
+		Description:       strings.TrimSpace(description),
+		DatabaseType:      base.Manifest.DatabaseType,
+		SkippedTables:     base.Manifest.SkippedTables,
+		Filters:           base.Manifest.Filters,
+		Excluded:          base.Manifest.Excluded,
+	}
+	if err := stampRevisionMetadata(&manifest, dataDir, ""); err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+	if err := scenario.WriteRevisionManifest(revDir, manifest); err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+
+	scenarioManifest, err := scenario.ReadManifest(scenarioDir)
+	if err != nil && !os.IsNotExist(err) {
+		return scenario.RevisionManifest{}, err
+	}
+	if scenarioManifest.Scenario == "" {
+		scenarioManifest = scenario.Manifest{Scenario: base.Scenario, CreatedAt: now}
+	}
+	scenarioManifest.UpdatedAt = now
+	scenarioManifest.Latest = revID
+	if err := scenario.WriteManifest(scenarioDir, scenarioManifest); err != nil {
+		return scenario.RevisionManifest{}, err
+	}
+
+	success = true
+	return manifest, nil
+}
+
+// stampRevisionMetadata fills the provenance fields of a revision manifest
+// about to be written: the CLI version and the checksum of dataDir, plus
+// the engine and password-masked URL when sourceURL (the database the data
+// came from) is known. Fields already set by the caller are kept when
+// sourceURL is empty, so derived revisions inherit their base's engine.
+func stampRevisionMetadata(m *scenario.RevisionManifest, dataDir, sourceURL string) error {
+	m.SeedmancerVersion = utils.CLIVersion()
+	sum, err := scenario.DataChecksum(dataDir)
+	if err != nil {
+		return fmt.Errorf("checksumming revision data: %w", err)
+	}
+	files, err := scenario.FileChecksums(dataDir)
+	if err != nil {
+		return fmt.Errorf("checksumming revision data: %w", err)
+	}
+	m.Checksum = sum
+	m.Files = files
+	if sourceURL != "" {
+		m.SourceURL = maskDatabaseURL(sourceURL)
+		if dbType, err := db.DatabaseTypeOf(sourceURL); err == nil {
+			m.DatabaseType = string(dbType)
+		}
+	}
+	return nil
+}
+
+// verifyRevisionChecksum checks rev's data/ against the checksums recorded
+// in its manifest, per file when available so the error can name what was
+// truncated or edited. Revisions without recorded checksums (written by
+// older CLIs) always pass.
+func verifyRevisionChecksum(rev resolvedRevision) error {
+	const hint = "revisions are immutable; use rewrite/age or export a new revision instead of editing CSVs in place"
+	if len(rev.Manifest.Files) > 0 {
+		if err := scenario.VerifyChecksums(rev.DataDir, rev.Manifest.Files); err != nil {
+			return fmt.Errorf("%s @ %s: %w — %s", rev.Scenario, rev.RevID, err, hint)
+		}
+		return nil
+	}
+	if rev.Manifest.Checksum == "" {
+		return nil
+	}
+	got, err := scenario.DataChecksum(rev.DataDir)
+	if err != nil {
+		return fmt.Errorf("checksumming %s @ %s: %w", rev.Scenario, rev.RevID, err)
+	}
+	if got != rev.Manifest.Checksum {
+		return fmt.Errorf(
+			"%s @ %s: data/ no longer matches the checksum recorded when the revision was written — %s",
+			rev.Scenario, rev.RevID, hint,
+		)
+	}
+	return nil
+}
+
+// describeRevisionOrigin renders where a revision's data came from for
+// one-line display, e.g. "export from postgres://app:****@db/app, seedmancer v0.9.0".
+// Returns "" when the manifest predates provenance metadata.
+func describeRevisionOrigin(m scenario.RevisionManifest) string {
+	var parts []string
+	origin := m.Source
+	switch {
+	case m.SourceURL != "":
+		origin += " from " + m.SourceURL
+	case m.DatabaseType != "":
+		origin += " (" + m.DatabaseType + ")"
+	}
+	if m.SourceURL == "" && m.DatabaseType == "" && m.SeedmancerVersion == "" {
+		return ""
+	}
+	parts = append(parts, strings.TrimSpace(origin))
+	if m.SeedmancerVersion != "" {
+		parts = append(parts, "seedmancer "+m.SeedmancerVersion)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// revisionDatabaseType returns the engine rev was captured from: the
+// databaseType recorded in its schema.json, else the one in its manifest,
+// else "" for artifacts that record neither.
+func revisionDatabaseType(projectRoot, storagePath string, rev resolvedRevision) db.DatabaseType {
+	path := scenario.SchemaJSONPath(projectRoot, storagePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	if schema, err := utils.ReadSchemaJSON(path); err == nil && schema.DatabaseType != "" {
+		return db.DatabaseType(strings.ToLower(schema.DatabaseType))
+	}
+	return db.DatabaseType(strings.ToLower(rev.Manifest.DatabaseType))
+}
+
+// engineFamily maps an engine to the one whose restore path it shares:
+// CockroachDB restores like PostgreSQL, MariaDB like MySQL.
+func engineFamily(t db.DatabaseType) db.DatabaseType {
+	switch t {
+	case db.Cockroach:
+		return db.Postgres
+	case db.MariaDB:
+		return db.MySQL
+	}
+	return t
+}
+
+// partialRevisionWarning returns the warning seed shows for a revision
+// exported with --skip-unreadable, or "" for a complete one.
+func partialRevisionWarning(rev resolvedRevision) string {
+	if len(rev.Manifest.SkippedTables) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s @ %s is a partial export: %s couldn't be read when it was exported, so seed loads no rows into them",
+		rev.Scenario, rev.RevID, strings.Join(rev.Manifest.SkippedTables, ", "))
+}
+
+// checkRevisionEngine refuses to seed targets of a different engine
+// family than the one rev was captured from — a MySQL fixture's schema
+// is only loaded into PostgreSQL, and vice versa, by converting its
+// types, which the user has to ask for. Targets of the same family, such
+// as PostgreSQL and CockroachDB, only get a warning. allowMismatch turns
+// the refusal into a warning too.
+func checkRevisionEngine(rev resolvedRevision, engine db.DatabaseType, targets []utils.NamedEnv, allowMismatch bool) ([]string, error) {
+	if engine == "" {
+		return nil, nil
+	}
+	var warnings []string
+	for _, env := range targets {
+		for _, t := range shardTargets(env) {
+			dbType, err := db.DatabaseTypeOf(t.DatabaseURL)
+			if err != nil || dbType == engine {
+				continue
+			}
+			msg := fmt.Sprintf("%s @ %s was captured from %s but %s is %s", rev.Scenario, rev.RevID, engine, t.Name, dbType)
+			if engineFamily(dbType) != engineFamily(engine) && !allowMismatch {
+				return warnings, fmt.Errorf("%s — a %s fixture can't be loaded into %s as is; seed a %s database, re-export the scenario from %s, or pass --allow-engine-mismatch to convert its types",
+					msg, engine, dbType, engine, dbType)
+			}
+			warnings = append(warnings, msg)
+		}
+	}
+	return warnings, nil
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/KazanKK/seedmancer/internal/schemaspec"
 	"github.com/KazanKK/seedmancer/internal/ui"
 	utils "github.com/KazanKK/seedmancer/internal/utils"
 
@@ -68,7 +69,8 @@ func SchemasCommand() *cli.Command {
 			"in your Seedmancer cloud account. This command group lets you\n" +
 			"inspect them, give them human-friendly display names, or delete\n" +
 			"ones you no longer need. Flags --local / --remote scope each\n" +
-			"subcommand; by default they act on both sides when possible.",
+			"subcommand; by default they act on both sides when possible.\n\n" +
+			"`validate` and `json-schema` work offline, on schema.json files.",
 		Subcommands: []*cli.Command{
 			{
 				Name:  "list",
@@ -167,10 +169,94 @@ func SchemasCommand() *cli.Command {
 				},
 				Action: runSchemasRm,
 			},
+			{
+				Name:      "validate",
+				Usage:     "Check schema.json files against the published JSON Schema",
+				ArgsUsage: "<schema.json>...",
+				Description: "Validates hand-written or generated schema.json files before they\n" +
+					"are used. Each problem is reported with its location, e.g.\n\n" +
+					"  tables[3].columns[7].type: expected string, got number\n\n" +
+					"Beyond the JSON Schema (print it with `seedmancer schema json-schema`)\n" +
+					"it checks that table and column names are unique and that foreign\n" +
+					"keys and enum references point at something that exists. Exits\n" +
+					"non-zero when any file is invalid.",
+				Flags: []cli.Flag{
+					outputFlag(),
+				},
+				Action: runSchemasValidate,
+			},
+			{
+				Name:  "json-schema",
+				Usage: "Print the JSON Schema schema.json files follow",
+				Description: "Prints the JSON Schema (draft 2020-12) of the schema.json format to\n" +
+					"stdout, for editors and for tools that generate schema files:\n\n" +
+					"  seedmancer schema json-schema > seedmancer-schema.v1.json",
+				Action: func(c *cli.Context) error {
+					_, err := os.Stdout.Write(schemaspec.JSONSchema)
+					return err
+				},
+			},
 		},
 	}
 }
 
+// ─── validate ─────────────────────────────────────────────────────────────────
+
+// schemaValidation is the result for one file of `schema validate`.
+type schemaValidation struct {
+	File   string             `json:"file"`
+	Valid  bool               `json:"valid"`
+	Issues []schemaspec.Issue `json:"issues"`
+}
+
+func runSchemasValidate(c *cli.Context) error {
+	asJSON, err := jsonRequested(c)
+	if err != nil {
+		return err
+	}
+	files := c.Args().Slice()
+	if len(files) == 0 {
+		return usageError(c, "missing required argument: <schema.json>")
+	}
+	results := make([]schemaValidation, 0, len(files))
+	invalid := 0
+	for _, f := range files {
+		res := schemaValidation{File: f, Issues: []schemaspec.Issue{}}
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			res.Issues = append(res.Issues, schemaspec.Issue{Message: err.Error()})
+		} else if issues := schemaspec.Validate(raw); issues != nil {
+			res.Issues = issues
+		}
+		res.Valid = len(res.Issues) == 0
+		if !res.Valid {
+			invalid++
+		}
+		results = append(results, res)
+	}
+
+	if asJSON {
+		if err := outputJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, res := range results {
+			if res.Valid {
+				ui.Success("%s is valid", res.File)
+				continue
+			}
+			ui.Error("%s: %d problem(s)", res.File, len(res.Issues))
+			for _, issue := range res.Issues {
+				ui.Info("  %s", issue)
+			}
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d schema file(s) invalid", invalid, len(files))
+	}
+	return nil
+}
+
 // ─── list ─────────────────────────────────────────────────────────────────────
 
 func runSchemasList(c *cli.Context) error {
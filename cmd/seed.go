@@ -2,15 +2,23 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strings"
 	"time"
 
 	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/chaos"
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+	"github.com/KazanKK/seedmancer/internal/diskspace"
+	"github.com/KazanKK/seedmancer/internal/lockfile"
+	"github.com/KazanKK/seedmancer/internal/migrations"
 	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/subset"
 	"github.com/KazanKK/seedmancer/internal/ui"
 	utils "github.com/KazanKK/seedmancer/internal/utils"
 
@@ -21,8 +29,9 @@ import (
 // target databases.
 //
 // Revision resolution rules (highest priority first):
-//  1. --revision rNNN
-//  2. manifest.latest
+//  1. --from-lock (scenario and revision from seedmancer.lock)
+//  2. --revision rNNN
+//  3. manifest.latest
 func SeedCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "seed",
@@ -30,8 +39,11 @@ func SeedCommand() *cli.Command {
 		ArgsUsage: "<scenario>",
 		Description: "Loads a scenario's CSVs + schema sidecars into each target Postgres\n" +
 			"database. The chosen revision is resolved as follows:\n\n" +
+			"  --from-lock      → scenario + revision pinned in " + lockfile.FileName + "\n" +
 			"  --revision rNNN  → exact revision\n" +
 			"  (default)        → manifest.latest\n\n" +
+			"--from-lock also refuses a local revision whose data checksum\n" +
+			"differs from the one in the lock; <scenario> may be omitted.\n\n" +
 			"Targets:\n" +
 			"  --env local            single env\n" +
 			"  --env local,staging    many envs sequentially\n" +
@@ -39,7 +51,108 @@ func SeedCommand() *cli.Command {
 			"Schema safety: if the database's current schema fingerprint\n" +
 			"differs from the revision's, the seed is blocked unless\n" +
 			"--force is passed. Use `seedmancer check <scenario>` to see\n" +
-			"the diff.",
+			"the diff.\n\n" +
+			"Cascades: on PostgreSQL the reload empties tables with TRUNCATE …\n" +
+			"CASCADE, which also empties tables outside the revision that\n" +
+			"reference them. When any of those hold rows the seed is refused\n" +
+			"and names them; --allow-cascade accepts the loss. --dry-run lists\n" +
+			"them for each target without seeding.\n\n" +
+			"Partial seeds: --tables orders reloads only the listed tables.\n" +
+			"Parent tables they reference through foreign keys are included\n" +
+			"automatically, but only the referenced rows are inserted and only\n" +
+			"when missing — existing parent rows are left untouched.\n" +
+			"--interactive picks them from a checkbox list of the revision's\n" +
+			"tables and prints the matching --tables command for scripts.\n\n" +
+			"Hosts listed under protected_hosts in seedmancer.yaml, e.g.\n" +
+			"*.prod.example.com, are never seeded, --force or not.\n\n" +
+			"In CI, --wait-for-db 120s polls each target until it accepts\n" +
+			"connections and has finished recovery instead of failing while\n" +
+			"the database container is still starting. --wait-for-replica 60s\n" +
+			"then holds the command until each env's replica_url has the\n" +
+			"same row counts as the primary, so tests reading the replica\n" +
+			"don't race replication lag.\n\n" +
+			"Cold starts: --warmup runs ANALYZE on the seeded tables and then\n" +
+			"the revision's " + warmupFileName + " (revisions/<rev>/" + warmupFileName + "), e.g.\n" +
+			"REFRESH MATERIALIZED VIEW or the queries a test suite runs first,\n" +
+			"so the first test iteration isn't slower than the rest.\n\n" +
+			"Long-lived shared databases: repeated reloads leave dead rows and\n" +
+			"catalog bloat behind. --vacuum run vacuums the seeded tables after\n" +
+			"the load (OPTIMIZE TABLE on MySQL); --vacuum advise measures the\n" +
+			"space to reclaim and prints the statements to run, changing nothing.\n\n" +
+			"Non-destructive seeds: --mode upsert keeps existing rows. Fixture\n" +
+			"rows are inserted, and rows whose primary key already exists are\n" +
+			"overwritten; nothing is truncated or deleted. --mode append\n" +
+			"inserts the fixture next to existing rows instead: serial and UUID\n" +
+			"primary keys get fresh values and foreign keys pointing at them\n" +
+			"follow, so several fixtures can be loaded into one database.\n\n" +
+			"Fast resets (PostgreSQL): --template loads the CSVs once, copies the\n" +
+			"result into a template database on the same server, and from then\n" +
+			"on replaces the target with CREATE DATABASE … TEMPLATE — typically\n" +
+			"well under a second. Other sessions on the target are disconnected.\n" +
+			"A changed fixture gets a new template; the scenario's old ones are\n" +
+			"dropped. Only works with --mode replace and a whole-fixture seed.\n\n" +
+			"Sandboxes: --target-schema run_42 (Postgres) or --target-database\n" +
+			"run_42 (MySQL) restores into that namespace, creating it on the\n" +
+			"fly, and leaves whatever the DSN points at untouched. The schema\n" +
+			"fingerprint guard is skipped since the namespace is built from\n" +
+			"the revision's schema.\n\n" +
+			"Drifted databases: --create-missing-only applies only additive DDL.\n" +
+			"Enums, sequences and tables missing from the target are created\n" +
+			"and columns missing from existing tables are added; nothing that\n" +
+			"exists is altered and no rows are loaded or removed. A NOT NULL\n" +
+			"column without a default is added as nullable, since existing rows\n" +
+			"have no value for it. The fingerprint guard is skipped — drift is\n" +
+			"the point.\n\n" +
+			"Renamed columns: a " + migrations.FileName + " next to seedmancer.yaml maps an\n" +
+			"older revision onto the current schema while it is staged — column\n" +
+			"renames, dropped columns, and values for columns added since:\n\n" +
+			"  tables:\n" +
+			"    users:\n" +
+			"      rename: {full_name: display_name}\n" +
+			"      drop: [legacy_flag]\n" +
+			"      defaults: {timezone: UTC}\n\n" +
+			"A rule only applies while the revision still has the old column, so\n" +
+			"newer revisions pass through untouched. When one does apply, the\n" +
+			"fingerprint guard is skipped. --migrations picks another file and\n" +
+			"--no-migrations ignores it.\n\n" +
+			"Engines: the engine a revision was captured from is read from its\n" +
+			"schema.json. Seeding a target of another engine family (a MySQL\n" +
+			"fixture into PostgreSQL, say) is refused unless you pass\n" +
+			"--allow-engine-mismatch, which converts the schema's types on the\n" +
+			"way in: serial and AUTO_INCREMENT, jsonb and json, timestamptz and\n" +
+			"datetime, boolean and tinyint(1), uuid and char(36). PostgreSQL and\n" +
+			"CockroachDB, or MySQL and MariaDB, only warn.\n\n" +
+			"Messy data on purpose: --chaos 5% rewrites one value in about 5% of\n" +
+			"the rows with something awkward but valid — a NULL where allowed, a\n" +
+			"string at its declared maximum length, tricky unicode, a date at the\n" +
+			"edge of the engine's range, an integer at its limit, an empty JSON\n" +
+			"object. Keys, unique, foreign key and enum columns are left alone,\n" +
+			"and the revision on disk is never changed. The chaos seed is\n" +
+			"printed so a run can be repeated with --chaos-seed; --chaos-report\n" +
+			"faults.json lists every planted value.\n\n" +
+			"Layers: --layers base,plus-billing,plus-flags seeds base with the\n" +
+			"latest revision of each later scenario stacked on top, in order. A\n" +
+			"layer row whose primary key is already there replaces it, other rows\n" +
+			"are added, and tables a layer leaves out keep what is below, so small\n" +
+			"variations need no full copy of the fixture. Every layer must share\n" +
+			"the base's schema; --revision picks the base's revision. With a\n" +
+			"<scenario>, it is the base and --layers lists only what goes on top.\n\n" +
+			"Recorded sessions: --patch refund-flow applies a patch saved by\n" +
+			"`seedmancer record` on top of the revision (several apply in order:\n" +
+			"--patch a,b). Not available with --template.\n\n" +
+			"Remote: --remote seeds the cloud's copy of <scenario> without\n" +
+			"pulling it first, for stateless CI runners:\n\n" +
+			"  seedmancer seed billing/pro --remote --db-url postgres://...\n\n" +
+			"The archive is held in memory and its files only live in a temp dir\n" +
+			"for the length of the seed; no revision is written and no\n" +
+			"seedmancer.yaml is needed. The cloud keeps one copy per scenario, so\n" +
+			"--revision, --from-lock, --layers and --patch don't apply.\n\n" +
+			"While rows load, a status line shows the tables done, rows so far,\n" +
+			"rows/s and an ETA for the table in flight and the whole seed; off a\n" +
+			"terminal it is logged every 10s instead. The global --progress-json\n" +
+			"turns it into line-delimited JSON events on stderr for wrappers.\n\n" +
+			"CI: --output json prints the per-target results to stdout as JSON\n" +
+			"(the same shape the MCP seed tool returns); progress stays on stderr.",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "env",
@@ -55,11 +168,31 @@ func SeedCommand() *cli.Command {
 				Aliases: []string{"r"},
 				Usage:   "Specific revision id (e.g. r002); defaults to latest",
 			},
+			&cli.BoolFlag{
+				Name:  "from-lock",
+				Usage: "Seed the scenario revision pinned in " + lockfile.FileName + " (checksum-verified)",
+			},
 			&cli.BoolFlag{
 				Name:    "force",
 				Aliases: []string{"f"},
 				Usage:   "Seed even when the database schema fingerprint differs",
 			},
+			&cli.BoolFlag{
+				Name:  "create-missing-only",
+				Usage: "Only create missing tables, enums and columns; existing structures and rows are left alone",
+			},
+			&cli.StringFlag{
+				Name:  "migrations",
+				Usage: "Column mapping file to apply to the revision (default: " + migrations.FileName + " in the project root, if present)",
+			},
+			&cli.BoolFlag{
+				Name:  "no-migrations",
+				Usage: "Seed the revision as exported, ignoring " + migrations.FileName,
+			},
+			&cli.BoolFlag{
+				Name:  "allow-engine-mismatch",
+				Usage: "Seed even when the revision was captured from another engine (e.g. a MySQL fixture into PostgreSQL), converting its types",
+			},
 			&cli.BoolFlag{
 				Name:    "yes",
 				Aliases: []string{"y"},
@@ -69,24 +202,107 @@ func SeedCommand() *cli.Command {
 				Name:  "continue-on-error",
 				Usage: "Keep seeding remaining envs after a failure (default: stop)",
 			},
+			&cli.StringFlag{
+				Name:  "tables",
+				Usage: "Comma-separated tables to seed; referenced parent rows are added automatically",
+			},
+			&cli.BoolFlag{
+				Name:  "interactive",
+				Usage: "Pick the tables to seed from a checkbox list, then print the equivalent --tables",
+			},
+			&cli.StringFlag{
+				Name:  "target-schema",
+				Usage: "PostgreSQL: restore into this schema (created if missing) instead of public",
+			},
+			&cli.StringFlag{
+				Name:  "target-database",
+				Usage: "MySQL: restore into this database (created if missing) instead of the DSN's",
+			},
+			&cli.DurationFlag{
+				Name:  "wait-for-db",
+				Usage: "Wait up to this long (e.g. 120s) for each target to accept connections and finish recovery",
+			},
+			&cli.DurationFlag{
+				Name:  "wait-for-replica",
+				Usage: "After seeding, wait up to this long (e.g. 60s) for each target's replica_url to reach the primary's row counts",
+			},
+			&cli.BoolFlag{
+				Name:  "warmup",
+				Usage: "After the load, ANALYZE the seeded tables and run the revision's " + warmupFileName + " if it has one",
+			},
+			&cli.StringFlag{
+				Name:  "vacuum",
+				Usage: "After the load, vacuum the seeded tables (run) or print the space to reclaim and how (advise)",
+			},
+			&cli.BoolFlag{
+				Name:  "template",
+				Usage: "PostgreSQL: reset the target from a template database of this fixture, building it on first use",
+			},
+			&cli.BoolFlag{
+				Name:  "remote",
+				Usage: "Seed the cloud's copy of <scenario> straight from the API, without pulling it into the project",
+			},
+			&cli.StringFlag{
+				Name:  "token",
+				Usage: "API token for --remote (falls back to SEEDMANCER_API_TOKEN env var, then ~/.seedmancer/credentials)",
+			},
+			&cli.StringFlag{
+				Name:  "layers",
+				Usage: "Comma-separated scenarios to stack, base first; later layers replace rows by primary key and add the rest",
+			},
+			&cli.StringFlag{
+				Name:  "patch",
+				Usage: "Comma-separated patches from seedmancer record to apply on top of the revision",
+			},
+			&cli.StringFlag{
+				Name:  "chaos",
+				Usage: "Plant awkward-but-valid values in this share of rows (e.g. 5%) and report them",
+			},
+			&cli.Int64Flag{
+				Name:  "chaos-seed",
+				Usage: "Random seed for --chaos, to repeat an earlier run (default: random)",
+			},
+			&cli.StringFlag{
+				Name:  "chaos-report",
+				Usage: "Write every value --chaos planted to this JSON file",
+			},
+			&cli.StringFlag{
+				Name:  "mode",
+				Usage: "How to treat existing rows: replace (truncate first), upsert (insert or update by primary key) or append (insert alongside with remapped keys)",
+				Value: string(db.RestoreReplace),
+			},
+			&cli.BoolFlag{
+				Name:  "allow-cascade",
+				Usage: "Let PostgreSQL's TRUNCATE … CASCADE empty tables outside the revision that reference the ones it reloads",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Stage the seed and list, per target, the tables TRUNCATE … CASCADE would empty, without seeding",
+			},
+			outputFlag(),
 		},
 		Action: func(c *cli.Context) error {
-			scenarioArg := strings.TrimSpace(c.Args().First())
-			if scenarioArg == "" {
+			layers := splitCSVList(c.String("layers"))
+			scenarioArg, layers := layerStack(strings.TrimSpace(c.Args().First()), layers)
+			if scenarioArg == "" && !c.Bool("from-lock") {
 				return usageError(c, "missing required argument: <scenario>")
 			}
-
-			configPath, err := utils.FindConfigFile()
-			if err != nil {
-				return err
+			if c.Bool("from-lock") && c.IsSet("revision") {
+				return fmt.Errorf("--from-lock and --revision are mutually exclusive")
+			}
+			if c.IsSet("layers") && (c.Bool("from-lock") || c.Bool("template")) {
+				return fmt.Errorf("--layers can't be combined with --from-lock or --template")
 			}
-			projectRoot := filepath.Dir(configPath)
-			cfg, err := utils.LoadConfig(configPath)
+			remote := c.Bool("remote")
+			if remote && (c.Bool("from-lock") || c.IsSet("revision") || c.IsSet("layers") || c.IsSet("patch")) {
+				return fmt.Errorf("--remote seeds the cloud's copy of the scenario, so it can't be combined with --from-lock, --revision, --layers or --patch")
+			}
+			asJSON, err := jsonRequested(c)
 			if err != nil {
 				return err
 			}
 
-			scenarioPath, err := scenario.Normalize(scenarioArg)
+			projectRoot, cfg, err := seedProject(remote)
 			if err != nil {
 				return err
 			}
@@ -95,24 +311,226 @@ func SeedCommand() *cli.Command {
 			if err != nil {
 				return err
 			}
-
-			rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, c.String("revision"))
+			if err := refuseProtectedTargets(cfg, targets); err != nil {
+				return err
+			}
+			mode, err := db.ParseRestoreMode(c.String("mode"))
+			if err != nil {
+				return err
+			}
+			vacuum, err := db.ParseVacuumMode(c.String("vacuum"))
 			if err != nil {
 				return err
 			}
 
+			var rev resolvedRevision
+			var schemaDir string
+			if remote {
+				token, err := utils.ResolveAPIToken(c.String("token"))
+				if err != nil {
+					return err
+				}
+				scenarioPath, err := scenario.Normalize(scenarioArg)
+				if err != nil {
+					return err
+				}
+				var cleanupRemote func()
+				rev, schemaDir, cleanupRemote, err = remoteRevision(c.Context, cfg, scenarioPath, token)
+				if err != nil {
+					return err
+				}
+				defer cleanupRemote()
+			} else {
+				rev, err = resolveSeedRevision(projectRoot, cfg.StoragePath, scenarioArg, c.String("revision"), c.Bool("from-lock"))
+				if err != nil {
+					return err
+				}
+				schemaDir = scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+			}
+
 			ui.Step("seed %s @ %s (schema %s) → %s",
 				rev.Scenario, rev.RevID,
 				utils.FingerprintShort(rev.Manifest.SchemaFingerprint),
 				strings.Join(targetNames(targets), ", "))
+			if origin := describeRevisionOrigin(rev.Manifest); origin != "" {
+				ui.Info("Recorded by %s", origin)
+			}
+			if err := verifyRevisionChecksum(rev); err != nil {
+				return err
+			}
+			warnings, err := checkRevisionEngine(rev, revisionDatabaseType(projectRoot, cfg.StoragePath, rev), targets, c.Bool("allow-engine-mismatch"))
+			if err != nil {
+				return err
+			}
+			if w := partialRevisionWarning(rev); w != "" {
+				warnings = append(warnings, w)
+			}
+			for _, w := range warnings {
+				ui.Warn("%s", w)
+			}
 
-			schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
 			merged, cleanup, err := materializeRestoreDir(schemaDir, rev.DataDir)
 			if err != nil {
 				return err
 			}
 			defer cleanup()
 			ui.Debug("Merged restore dir: %s", merged)
+			layered, err := applyLayers(projectRoot, cfg.StoragePath, layers, merged, rev)
+			if err != nil {
+				return err
+			}
+			for _, l := range seedLayers(layered) {
+				ui.Info("Layered %s", layerSummary(l))
+			}
+
+			migrated := false
+			mapping, err := loadSeedMigrations(projectRoot, c.String("migrations"), c.Bool("no-migrations"))
+			if err != nil {
+				return err
+			}
+			if mapping != nil {
+				migratedDir, changes, cleanupMigrated, err := materializeMigrationsDir(merged, *mapping)
+				if err != nil {
+					return err
+				}
+				defer cleanupMigrated()
+				if len(changes) > 0 {
+					merged = migratedDir
+					migrated = true
+					printMigrationChanges(changes)
+				}
+			}
+
+			restoreOpts := restoreOptionsFromConfig(cfg)
+			restoreOpts.Mode = mode
+			restoreOpts.Vacuum = vacuum
+			restoreOpts.AllowCascade = c.Bool("allow-cascade")
+			restoreOpts.Record = seedRecordFor(rev)
+			if len(layered) > 0 {
+				restoreOpts.Record = layeredSeedRecord(rev, layered)
+			}
+			if err := applySandboxTarget(&restoreOpts, c.String("target-schema"), c.String("target-database")); err != nil {
+				return err
+			}
+			if c.Bool("warmup") {
+				if err := applyWarmup(&restoreOpts, rev); err != nil {
+					return err
+				}
+			}
+			if patches := splitCSVList(c.String("patch")); len(patches) > 0 {
+				if c.Bool("template") {
+					return fmt.Errorf("--patch can't be combined with --template")
+				}
+				if err := applyPatches(projectRoot, cfg.StoragePath, patches, merged, rev); err != nil {
+					return err
+				}
+				ui.Info("Applied patch(es): %s", strings.Join(patches, ", "))
+			}
+			tables := splitCSVList(c.String("tables"))
+			if c.Bool("interactive") {
+				all, _, err := listCSVTablesAndRowCounts(merged)
+				if err != nil {
+					return err
+				}
+				if tables, err = pickTables("seed", all, tables); err != nil {
+					return err
+				}
+			}
+			if len(tables) > 0 {
+				subsetDir, plan, cleanupSubset, err := materializeSubsetDir(merged, tables)
+				if err != nil {
+					return err
+				}
+				defer cleanupSubset()
+				merged = subsetDir
+				restoreOpts.Tables = plan.Selected
+				restoreOpts.MergeTables = plan.Parents
+				if len(plan.Parents) > 0 {
+					ui.Info("Including referenced rows from parent table(s): %s", strings.Join(plan.Parents, ", "))
+				}
+			}
+			useTemplate := c.Bool("template")
+			if useTemplate {
+				if err := validateTemplateSeed(restoreOpts); err != nil {
+					return err
+				}
+				if migrated {
+					return fmt.Errorf("--template can't be used while %s rewrites the revision; pass --no-migrations or export a new revision", migrations.FileName)
+				}
+			}
+			if c.Bool("create-missing-only") {
+				if useTemplate || c.IsSet("chaos") || c.IsSet("patch") {
+					return fmt.Errorf("--create-missing-only can't be combined with --template, --chaos or --patch")
+				}
+				if err := validateCreateMissingOnly(restoreOpts); err != nil {
+					return err
+				}
+				restoreOpts.CreateMissingOnly = true
+			}
+			if !c.IsSet("chaos") && (c.IsSet("chaos-seed") || c.IsSet("chaos-report")) {
+				return fmt.Errorf("--chaos-seed and --chaos-report need --chaos")
+			}
+			var chaosRun *chaosReport
+			if c.IsSet("chaos") {
+				if useTemplate {
+					return fmt.Errorf("--chaos can't be combined with --template")
+				}
+				rate, err := chaos.ParseRate(c.String("chaos"))
+				if err != nil {
+					return err
+				}
+				report := chaosReport{Seed: time.Now().UnixNano(), Rate: rate}
+				if c.IsSet("chaos-seed") {
+					report.Seed = c.Int64("chaos-seed")
+				}
+				chaosDir, faults, cleanupChaos, err := materializeChaosDir(merged, rate, report.Seed)
+				if err != nil {
+					return err
+				}
+				defer cleanupChaos()
+				merged = chaosDir
+				report.Faults = faults
+				printChaosSummary(report)
+				if path := strings.TrimSpace(c.String("chaos-report")); path != "" {
+					if err := writeChaosReport(path, report); err != nil {
+						return err
+					}
+					ui.Info("Chaos report written to %s", path)
+				}
+				chaosRun = &report
+			}
+
+			if c.Bool("dry-run") {
+				results := make([]seedResult, 0, len(targets))
+				for _, t := range targets {
+					results = append(results, previewSeed(t, merged, restoreOpts))
+				}
+				if asJSON {
+					out := SeedOutput{
+						Scenario: rev.Scenario,
+						Revision: rev.RevID,
+						Schema:   utils.FingerprintShort(rev.Manifest.SchemaFingerprint),
+						DryRun:   true,
+						Results:  make([]SeedTargetResult, len(results)),
+						AnyError: anyFailed(results),
+						Warnings: warnings,
+						Layers:   seedLayers(layered),
+						Chaos:    chaosRun,
+					}
+					for i, r := range results {
+						out.Results[i] = seedTargetResult(r)
+					}
+					if err := outputJSON(out); err != nil {
+						return err
+					}
+				} else {
+					printCascadePreview(results, restoreOpts.AllowCascade)
+				}
+				if anyFailed(results) {
+					return fmt.Errorf("one or more environments couldn't be checked")
+				}
+				return nil
+			}
 
 			// Fingerprint guard runs against each target separately so a
 			// matching local env can succeed even if a sibling drifts.
@@ -137,20 +555,53 @@ func SeedCommand() *cli.Command {
 				if i > 0 {
 					fmt.Fprintln(os.Stderr)
 				}
-				if !force {
-					if err := guardSchemaMatch(t, rev); err != nil {
-						ui.Error("%v", err)
-						results = append(results, seedResult{Env: targetDisplay(t), Err: err})
-						if !c.Bool("continue-on-error") {
-							for _, rest := range targets[i+1:] {
-								results = append(results, seedResult{Env: rest.Name, Skipped: true})
-							}
-							break
+				if waitFor := c.Duration("wait-for-db"); waitFor > 0 {
+					ui.Step("Waiting up to %s for %s to be ready...", waitFor, targetDisplay(t))
+				}
+				// A template reset replaces the whole database, so the live
+				// schema has no bearing on it; --create-missing-only exists
+				// for databases that have drifted, and an applied
+				// migrations.yaml declares the drift.
+				if err := checkSeedTarget(t, rev, force || isSandboxRestore(restoreOpts) || useTemplate || restoreOpts.CreateMissingOnly || migrated, c.Duration("wait-for-db")); err != nil {
+					ui.Error("%v", err)
+					results = append(results, seedResult{Env: targetDisplay(t), Err: err})
+					if !c.Bool("continue-on-error") {
+						for _, rest := range targets[i+1:] {
+							results = append(results, seedResult{Env: rest.Name, Skipped: true})
 						}
-						continue
+						break
+					}
+					continue
+				}
+				var res seedResult
+				if useTemplate {
+					// seedOneEnv reports its own load failures; only the
+					// template steps' errors still need printing.
+					var loadErr error
+					res = seedViaTemplate(t, merged, rev.Scenario, restoreOpts, func(target utils.NamedEnv, dir string) seedResult {
+						r := seedOneEnv(target, dir, rev.RevID, rev.Scenario, true, restoreOpts)
+						loadErr = r.Err
+						return r
+					})
+					switch {
+					case res.FromTemplate:
+						ui.Title(fmt.Sprintf("→ %s", targetDisplay(t)))
+						ui.Success("Reset %s from template (%s)", targetDisplay(t), res.Duration.Round(time.Millisecond))
+					case res.Err == nil && !res.Skipped:
+						ui.Info("Saved a template of %s @ %s; the next --template seed resets from it", rev.Scenario, rev.RevID)
+					case loadErr == nil || !errors.Is(res.Err, loadErr):
+						ui.Error("%v", res.Err)
+					}
+				} else {
+					res = seedOneEnv(t, merged, rev.RevID, rev.Scenario, true, restoreOpts)
+				}
+				if waitFor := c.Duration("wait-for-replica"); res.Err == nil && !res.Skipped && waitFor > 0 && t.ReplicaURL != "" {
+					ui.Step("Waiting up to %s for the %s replica to catch up...", waitFor, targetDisplay(t))
+					res.Err = waitForReplica(t, merged, waitFor)
+					if res.Err != nil {
+						ui.Error("%v", res.Err)
 					}
 				}
-				res := seedOneEnv(t, merged, rev.RevID, rev.Scenario, true)
 				results = append(results, res)
 				if res.Err != nil && !c.Bool("continue-on-error") {
 					for _, rest := range targets[i+1:] {
@@ -160,8 +611,27 @@ func SeedCommand() *cli.Command {
 				}
 			}
 
-			fmt.Fprintln(os.Stderr)
-			printSeedSummary(results)
+			if asJSON {
+				out := SeedOutput{
+					Scenario: rev.Scenario,
+					Revision: rev.RevID,
+					Schema:   utils.FingerprintShort(rev.Manifest.SchemaFingerprint),
+					Results:  make([]SeedTargetResult, len(results)),
+					AnyError: anyFailed(results),
+					Warnings: warnings,
+					Layers:   seedLayers(layered),
+					Chaos:    chaosRun,
+				}
+				for i, r := range results {
+					out.Results[i] = seedTargetResult(r)
+				}
+				if err := outputJSON(out); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintln(os.Stderr)
+				printSeedSummary(results)
+			}
 			if anyFailed(results) {
 				return fmt.Errorf("one or more environments failed to seed")
 			}
@@ -171,6 +641,94 @@ func SeedCommand() *cli.Command {
 	}
 }
 
+// resolveSeedRevision picks the revision to seed: the one pinned in
+// seedmancer.lock when fromLock is set (scenarioArg, if given, must name
+// the locked scenario), otherwise revID or the latest of scenarioArg.
+func resolveSeedRevision(projectRoot, storagePath, scenarioArg, revID string, fromLock bool) (resolvedRevision, error) {
+	var scenarioPath string
+	if scenarioArg != "" {
+		var err error
+		if scenarioPath, err = scenario.Normalize(scenarioArg); err != nil {
+			return resolvedRevision{}, err
+		}
+	}
+	if !fromLock {
+		return resolveScenarioRevision(projectRoot, storagePath, scenarioPath, revID)
+	}
+	l, err := loadProjectLock(projectRoot)
+	if err != nil {
+		return resolvedRevision{}, err
+	}
+	if scenarioPath != "" && scenarioPath != l.Scenario {
+		return resolvedRevision{}, fmt.Errorf("%s pins scenario %q, not %q", lockfile.FileName, l.Scenario, scenarioPath)
+	}
+	return resolveLockedRevision(projectRoot, storagePath, *l)
+}
+
+// refuseProtectedTargets errors on the first target, or shard of one,
+// whose host is listed under protected_hosts. --force doesn't override it:
+// the list is there to stop a seed nobody meant to run.
+func refuseProtectedTargets(cfg utils.Config, targets []utils.NamedEnv) error {
+	for _, t := range targets {
+		if len(t.Shards) > 0 {
+			if err := refuseProtectedTargets(cfg, shardTargets(t)); err != nil {
+				return err
+			}
+			continue
+		}
+		if entry, ok := cfg.ProtectedHost(t.DatabaseURL); ok {
+			return fmt.Errorf("refusing to seed %s: its host matches %q under protected_hosts in seedmancer.yaml", targetDisplay(t), entry)
+		}
+	}
+	return nil
+}
+
+// checkSeedTarget runs the per-target checks that precede a restore: the
+// optional readiness wait, then the schema fingerprint guard unless force.
+// A sharded env is checked shard by shard.
+func checkSeedTarget(t utils.NamedEnv, rev resolvedRevision, force bool, waitFor time.Duration) error {
+	if len(t.Shards) > 0 {
+		for _, shard := range shardTargets(t) {
+			if err := checkSeedTarget(shard, rev, force, waitFor); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if waitFor > 0 && strings.TrimSpace(t.DatabaseURL) != "" {
+		err := db.WaitForReady(context.Background(), t.DatabaseURL, waitFor, func(reason string) {
+			ui.Debug("%s not ready: %s", targetDisplay(t), reason)
+		})
+		if err != nil {
+			return fmt.Errorf("waiting for %s: %w", targetDisplay(t), err)
+		}
+	}
+	if force {
+		return nil
+	}
+	return guardSchemaMatch(t, rev)
+}
+
+// waitForReplica polls t's replica until every table in mergedDir's
+// schema.json has as many rows there as on the primary.
+func waitForReplica(t utils.NamedEnv, mergedDir string, timeout time.Duration) error {
+	schema, err := utils.ReadSchemaJSON(filepath.Join(mergedDir, "schema.json"))
+	if err != nil {
+		return err
+	}
+	tables := make([]string, len(schema.Tables))
+	for i, table := range schema.Tables {
+		tables[i] = table.Name
+	}
+	err = db.WaitForReplica(context.Background(), t.DatabaseURL, t.ReplicaURL, tables, timeout, func(reason string) {
+		ui.Debug("%s replica not caught up: %s", targetDisplay(t), reason)
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %s replica: %w", targetDisplay(t), err)
+	}
+	return nil
+}
+
 // guardSchemaMatch fingerprints the target database and compares with
 // the revision's stored fingerprint. Returns nil when they match (or
 // when the target's URL is empty, e.g. a misconfigured ad-hoc env).
@@ -227,10 +785,16 @@ type seedResult struct {
 	Err      error
 	Duration time.Duration
 	Skipped  bool
+	// FromTemplate is set when --template reset the target from an
+	// existing template database instead of loading the CSVs.
+	FromTemplate bool
+	// Cascade lists the tables outside the revision the reload's
+	// TRUNCATE … CASCADE empties (see db.PreviewCascade).
+	Cascade []string
 }
 
 // seedOneEnv applies merged into a single database URL.
-func seedOneEnv(target utils.NamedEnv, mergedDir, revID, scenarioPath string, skipConfirm bool) seedResult {
+func seedOneEnv(target utils.NamedEnv, mergedDir, revID, scenarioPath string, skipConfirm bool, opts db.RestoreOptions) seedResult {
 	start := time.Now()
 
 	ui.Title(fmt.Sprintf("→ %s", targetDisplay(target)))
@@ -242,6 +806,11 @@ func seedOneEnv(target utils.NamedEnv, mergedDir, revID, scenarioPath string, sk
 			return seedResult{Env: targetDisplay(target), Skipped: true, Duration: time.Since(start)}
 		}
 	}
+	if len(target.Shards) > 0 {
+		return seedShards(target, mergedDir, func(shard utils.NamedEnv, dir string) seedResult {
+			return seedOneEnv(shard, dir, revID, scenarioPath, true, opts)
+		})
+	}
 
 	// Resolve @env:KEY markers into a per-env temp dir so each env gets its
 	// own substituted copies without mutating the shared mergedDir or the
@@ -260,16 +829,84 @@ func seedOneEnv(target utils.NamedEnv, mergedDir, revID, scenarioPath string, sk
 		return seedResult{Env: targetDisplay(target), Err: fmt.Errorf("connecting: %v", err), Duration: time.Since(start)}
 	}
 
-	sp := ui.StartSpinner("Importing dataset...")
-	if err := manager.RestoreFromCSV(restoreDir); err != nil {
-		sp.Stop(false, fmt.Sprintf("Import failed (%s)", targetDisplay(target)))
+	opts.Role = target.Role
+	opts = stampSeedRecord(opts, restoreDir, start)
+	// No spinner: the restore reports its own steps and a live table
+	// progress line.
+	if err := manager.RestoreFromCSVWithOptions(restoreDir, opts); err != nil {
+		ui.Error("Import failed (%s)", targetDisplay(target))
 		ui.Error("%v", err)
 		return seedResult{Env: targetDisplay(target), Err: err, Duration: time.Since(start)}
 	}
-	sp.Stop(true, fmt.Sprintf("Seeded %s (%s)", targetDisplay(target), time.Since(start).Round(time.Millisecond)))
+	ui.Success("Seeded %s (%s)", targetDisplay(target), time.Since(start).Round(time.Millisecond))
 	return seedResult{Env: targetDisplay(target), Duration: time.Since(start)}
 }
 
+// previewSeed is the --dry-run stand-in for seedOneEnv: it connects to
+// target read-only and reports which tables outside the revision the
+// seed's TRUNCATE … CASCADE would empty, changing nothing. A sharded
+// target reports the tables any of its shards would lose.
+func previewSeed(target utils.NamedEnv, mergedDir string, opts db.RestoreOptions) seedResult {
+	start := time.Now()
+	dest := targetDisplay(target)
+	shards := []utils.NamedEnv{target}
+	if len(target.Shards) > 0 {
+		shards = shardTargets(target)
+	}
+	var cascade []string
+	seen := map[string]bool{}
+	for _, shard := range shards {
+		tables, err := previewCascade(shard, mergedDir, opts)
+		if err != nil {
+			return seedResult{Env: dest, Err: err, Duration: time.Since(start)}
+		}
+		for _, t := range tables {
+			if !seen[t] {
+				seen[t] = true
+				cascade = append(cascade, t)
+			}
+		}
+	}
+	return seedResult{Env: dest, Cascade: cascade, Duration: time.Since(start)}
+}
+
+// previewCascade runs db.PreviewCascade against one database URL.
+func previewCascade(target utils.NamedEnv, mergedDir string, opts db.RestoreOptions) ([]string, error) {
+	// Static tables are compared with the CSVs the seed would load, so
+	// the markers are resolved as they would be.
+	restoreDir, cleanupResolved, err := resolveMarkersDir(mergedDir, target.Values, target.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupResolved()
+
+	manager, normalizedURL, err := db.NewManager(target.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := manager.ConnectReadOnly(normalizedURL); err != nil {
+		return nil, fmt.Errorf("connecting: %v", err)
+	}
+	opts.Role = target.Role
+	return db.PreviewCascade(manager, restoreDir, opts)
+}
+
+// printCascadePreview reports previewSeed's results, one line per target.
+func printCascadePreview(results []seedResult, allowCascade bool) {
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			ui.Error("%s: %v", r.Env, r.Err)
+		case len(r.Cascade) == 0:
+			ui.Success("%s: TRUNCATE … CASCADE empties nothing outside the revision", r.Env)
+		case allowCascade:
+			ui.Warn("%s: TRUNCATE … CASCADE would also empty %s", r.Env, strings.Join(r.Cascade, ", "))
+		default:
+			ui.Warn("%s: the seed would be refused, TRUNCATE … CASCADE would also empty %s (pass --allow-cascade to accept)", r.Env, strings.Join(r.Cascade, ", "))
+		}
+	}
+}
+
 // printSeedSummary renders a table of target outcomes.
 func printSeedSummary(results []seedResult) {
 	if len(results) <= 1 {
@@ -293,6 +930,124 @@ func printSeedSummary(results []seedResult) {
 	ui.Info("%d ok, %d failed, %d skipped", ok, failed, skipped)
 }
 
+// applySandboxTarget validates the sandbox flags and records them on
+// opts. At most one of schema (Postgres) and database (MySQL) may be set.
+func applySandboxTarget(opts *db.RestoreOptions, schema, database string) error {
+	schema, database = strings.TrimSpace(schema), strings.TrimSpace(database)
+	if schema != "" && database != "" {
+		return fmt.Errorf("--target-schema and --target-database are mutually exclusive")
+	}
+	opts.TargetSchema = schema
+	opts.TargetDatabase = database
+	return nil
+}
+
+// warmupFileName is the SQL file, next to a revision's manifest, that
+// `seed --warmup` runs after the load.
+const warmupFileName = "warmup.sql"
+
+// applyWarmup switches on the post-load ANALYZE and loads rev's
+// warmup.sql into opts when the revision has one.
+func applyWarmup(opts *db.RestoreOptions, rev resolvedRevision) error {
+	opts.Analyze = true
+	raw, err := os.ReadFile(filepath.Join(rev.RevDir, warmupFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", warmupFileName, err)
+	}
+	opts.WarmupSQL = string(raw)
+	return nil
+}
+
+// validateCreateMissingOnly rejects the options that only matter when
+// rows are loaded, which a --create-missing-only seed never does.
+func validateCreateMissingOnly(opts db.RestoreOptions) error {
+	switch {
+	case opts.Mode != "" && opts.Mode != db.RestoreReplace:
+		return fmt.Errorf("--create-missing-only loads no rows, so --mode doesn't apply")
+	case len(opts.Tables) > 0:
+		return fmt.Errorf("--create-missing-only can't be combined with --tables")
+	case opts.Analyze:
+		return fmt.Errorf("--create-missing-only can't be combined with --warmup")
+	case opts.Vacuum != db.VacuumOff:
+		return fmt.Errorf("--create-missing-only can't be combined with --vacuum")
+	}
+	return nil
+}
+
+// isSandboxRestore reports whether opts restores into a separate
+// namespace, where the live schema fingerprint says nothing about it.
+func isSandboxRestore(opts db.RestoreOptions) bool {
+	return opts.TargetSchema != "" || opts.TargetDatabase != ""
+}
+
+// restoreOptionsFromConfig derives the per-restore tuning knobs from
+// seedmancer.yaml so the CLI and MCP seed paths stay in lockstep.
+func restoreOptionsFromConfig(cfg utils.Config) db.RestoreOptions {
+	return db.RestoreOptions{StaticTables: cfg.StaticTables()}
+}
+
+// seedRecordFor is the history entry (see db.HistoryTable) a seed of
+// rev leaves in each target; stampSeedRecord completes it per target.
+// Revisions written before checksums were recorded get theirs computed.
+func seedRecordFor(rev resolvedRevision) *db.SeedRecord {
+	checksum := rev.Manifest.Checksum
+	if checksum == "" {
+		checksum, _ = scenario.DataChecksum(rev.DataDir)
+	}
+	return &db.SeedRecord{
+		Scenario: rev.Scenario,
+		Revision: rev.RevID,
+		Checksum: checksum,
+		Operator: seedOperator(),
+	}
+}
+
+// seedOperator names whoever runs a seed: SEEDMANCER_OPERATOR when set
+// (CI jobs point it at the pipeline), user@host otherwise.
+func seedOperator() string {
+	if v := strings.TrimSpace(os.Getenv("SEEDMANCER_OPERATOR")); v != "" {
+		return v
+	}
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return name + "@" + host
+	}
+	return name
+}
+
+// stampSeedRecord gives opts its own copy of opts.Record, started at
+// start and counting the rows of the CSVs in restoreDir the restore
+// loads, so targets seeded from the same options don't share one.
+func stampSeedRecord(opts db.RestoreOptions, restoreDir string, start time.Time) db.RestoreOptions {
+	if opts.Record == nil {
+		return opts
+	}
+	rec := *opts.Record
+	rec.StartedAt = start
+	rec.Rows, rec.RowCounts = 0, map[string]int{}
+	if _, counts, err := listCSVTablesAndRowCounts(restoreDir); err == nil {
+		subset := map[string]bool{}
+		for _, t := range append(append([]string(nil), opts.Tables...), opts.MergeTables...) {
+			subset[t] = true
+		}
+		for table, n := range counts {
+			if len(opts.Tables) > 0 && !subset[table] {
+				continue
+			}
+			rec.RowCounts[table] = n
+			rec.Rows += n
+		}
+	}
+	opts.Record = &rec
+	return opts
+}
+
 func anyFailed(results []seedResult) bool {
 	for _, r := range results {
 		if r.Err != nil {
@@ -304,7 +1059,9 @@ func anyFailed(results []seedResult) bool {
 
 // materializeRestoreDir builds a single flat temp directory containing
 // the schema sidecars (schema.json + *.sql) symlinked in from
-// schemaDir and the CSV/JSON files from dataDir. The returned cleanup
+// schemaDir and the CSV/JSON files from dataDir. A table exported in
+// shards is joined back into one CSV, so everything downstream — and the
+// load itself — sees a single file per table. The returned cleanup
 // removes the temp dir. When symlinks fail (Windows, exotic
 // filesystems) we fall back to copying.
 func materializeRestoreDir(schemaDir, dataDir string) (string, func(), error) {
@@ -324,21 +1081,122 @@ func materializeRestoreDir(schemaDir, dataDir string) (string, func(), error) {
 		cleanup()
 		return "", func() {}, err
 	}
+	sharded, err := csvshard.Tables(dataDir)
+	if err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
 	if len(dataFiles) == 0 {
 		cleanup()
 		return "", func() {}, fmt.Errorf("no CSV or JSON files in %s", dataDir)
 	}
 
 	for _, src := range append(append([]string{}, schemaFiles...), dataFiles...) {
+		if _, _, shard := csvshard.Parse(filepath.Base(src)); shard {
+			continue // joined below
+		}
 		dst := filepath.Join(tmp, filepath.Base(src))
 		if err := linkOrCopy(src, dst); err != nil {
 			cleanup()
 			return "", func() {}, fmt.Errorf("staging %s: %v", src, err)
 		}
 	}
+	// Everything else is symlinked; the joined shards are real copies.
+	var joined int64
+	for _, shards := range sharded {
+		for _, name := range shards {
+			if info, err := os.Stat(filepath.Join(dataDir, name)); err == nil {
+				joined += info.Size()
+			}
+		}
+	}
+	if err := diskspace.Check(tmp, joined, "staging the revision's sharded tables"); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	for table := range sharded {
+		name := table + ".csv"
+		if err := csvshard.Join(filepath.Join(dataDir, name), filepath.Join(tmp, name)); err != nil {
+			cleanup()
+			return "", func() {}, fmt.Errorf("joining shards of %s: %v", table, err)
+		}
+	}
 	return tmp, cleanup, nil
 }
 
+// joinedRevisionFiles stages a revision's schema and data files as
+// materializeRestoreDir does and lists them, for artifacts that restore
+// outside seedmancer — bundles, embedded packages — and so need each
+// sharded table as one CSV. cleanup removes the staging dir.
+func joinedRevisionFiles(schemaDir, dataDir string) ([]string, func(), error) {
+	dir, cleanup, err := materializeRestoreDir(schemaDir, dataDir)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		cleanup()
+		return nil, func() {}, fmt.Errorf("reading %s: %w", dir, err)
+	}
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	return files, cleanup, nil
+}
+
+// materializeSubsetDir stages the FK closure of tables from restoreDir
+// into a fresh temp dir: schema sidecars are linked through unchanged,
+// the selected tables' CSVs are copied in full, and parent tables are
+// cut down to the rows the selected tables reference.
+func materializeSubsetDir(restoreDir string, tables []string) (string, subset.Plan, func(), error) {
+	schema, err := utils.ReadSchemaJSON(filepath.Join(restoreDir, "schema.json"))
+	if err != nil {
+		return "", subset.Plan{}, func() {}, err
+	}
+	plan, err := subset.Closure(schema, tables)
+	if err != nil {
+		return "", subset.Plan{}, func() {}, err
+	}
+
+	tmp, err := os.MkdirTemp("", "seedmancer-subset-*")
+	if err != nil {
+		return "", subset.Plan{}, func() {}, fmt.Errorf("creating temp dir: %v", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(tmp) }
+
+	entries, err := os.ReadDir(restoreDir)
+	if err != nil {
+		cleanup()
+		return "", subset.Plan{}, func() {}, fmt.Errorf("reading restore dir: %v", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasSuffix(strings.ToLower(e.Name()), ".csv") {
+			continue
+		}
+		if err := linkOrCopy(filepath.Join(restoreDir, e.Name()), filepath.Join(tmp, e.Name())); err != nil {
+			cleanup()
+			return "", subset.Plan{}, func() {}, fmt.Errorf("staging %s: %v", e.Name(), err)
+		}
+	}
+	if _, err := subset.WriteSubset(restoreDir, tmp, schema, plan); err != nil {
+		cleanup()
+		return "", subset.Plan{}, func() {}, err
+	}
+	return tmp, plan, cleanup, nil
+}
+
+// splitCSVList splits a comma-separated flag value, trimming blanks.
+func splitCSVList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if p := strings.TrimSpace(part); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
 func linkOrCopy(src, dst string) error {
 	if err := os.Symlink(src, dst); err == nil {
 		return nil
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	db "github.com/KazanKK/seedmancer/database"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
 )
 
 func writeFile(t *testing.T, path, content string) {
@@ -81,6 +88,39 @@ func TestMaterializeRestoreDir_emptyDatasetErrors(t *testing.T) {
 	}
 }
 
+// TestMaterializeRestoreDir_joinsShards checks that a table exported in
+// shards is staged as one CSV with a single header, and counted as one.
+func TestMaterializeRestoreDir_joinsShards(t *testing.T) {
+	root := t.TempDir()
+	schemaDir := filepath.Join(root, "schema")
+	datasetDir := filepath.Join(root, "dataset")
+
+	writeFile(t, filepath.Join(schemaDir, "schema.json"), `{"tables":[{"name":"orders","columns":[{"name":"id","type":"integer"}]}]}`)
+	writeFile(t, filepath.Join(datasetDir, "orders.csv.001"), "id\n1\n2\n")
+	writeFile(t, filepath.Join(datasetDir, "orders.csv.002"), "id\n3\n")
+
+	tables, counts, err := listCSVTablesAndRowCounts(datasetDir)
+	if err != nil || len(tables) != 1 || counts["orders"] != 3 {
+		t.Fatalf("listCSVTablesAndRowCounts = %v, %v, %v; want orders with 3 rows", tables, counts, err)
+	}
+
+	merged, cleanup, err := materializeRestoreDir(schemaDir, datasetDir)
+	if err != nil {
+		t.Fatalf("materializeRestoreDir: %v", err)
+	}
+	defer cleanup()
+	got, err := os.ReadFile(filepath.Join(merged, "orders.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "id\n1\n2\n3\n" {
+		t.Fatalf("orders.csv = %q", got)
+	}
+	if _, err := os.Lstat(filepath.Join(merged, "orders.csv.001")); !os.IsNotExist(err) {
+		t.Fatalf("shard staged next to the joined CSV: %v", err)
+	}
+}
+
 func TestLinkOrCopy(t *testing.T) {
 	root := t.TempDir()
 	src := filepath.Join(root, "src.txt")
@@ -98,3 +138,208 @@ func TestLinkOrCopy(t *testing.T) {
 		t.Fatalf("dst content = %q, want %q", got, "hello")
 	}
 }
+
+func TestMaterializeSubsetDir(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "schema.json"), `{"tables":[
+	  {"name":"users","columns":[{"name":"id","type":"integer"}]},
+	  {"name":"orders","columns":[{"name":"id","type":"integer"},{"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}}]},
+	  {"name":"audit","columns":[{"name":"id","type":"integer"}]}]}`)
+	writeFile(t, filepath.Join(src, "users.csv"), "id\n1\n2\n")
+	writeFile(t, filepath.Join(src, "orders.csv"), "id,user_id\n10,2\n")
+	writeFile(t, filepath.Join(src, "audit.csv"), "id\n1\n")
+
+	dir, plan, cleanup, err := materializeSubsetDir(src, []string{"orders"})
+	if err != nil {
+		t.Fatalf("materializeSubsetDir: %v", err)
+	}
+	defer cleanup()
+
+	if len(plan.Parents) != 1 || plan.Parents[0] != "users" {
+		t.Fatalf("parents = %v", plan.Parents)
+	}
+	if !fileExists(filepath.Join(dir, "schema.json")) {
+		t.Fatal("schema.json should be staged")
+	}
+	if fileExists(filepath.Join(dir, "audit.csv")) {
+		t.Fatal("unrelated tables should not be staged")
+	}
+	users, _ := os.ReadFile(filepath.Join(dir, "users.csv"))
+	if string(users) != "id\n2\n" {
+		t.Fatalf("users.csv = %q, want only the referenced row", users)
+	}
+}
+
+func TestRunSeed_refusesRevisionWithEditedData(t *testing.T) {
+	const schema = `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer"},{"name":"plan","type":"text"}]}]}`
+	stageRevision(t, "billing/pro", schema, map[string]string{"users": "id,plan\n1,free\n"})
+	out, err := RunRewrite(context.Background(), RewriteInput{Scenario: "billing/pro", Set: []string{"users.plan=pro"}})
+	if err != nil {
+		t.Fatalf("RunRewrite: %v", err)
+	}
+	writeFile(t, filepath.Join(out.Path, "users.csv"), "id,plan\n")
+
+	_, err = RunSeed(context.Background(), SeedInput{
+		Scenario: "billing/pro",
+		DBURL:    "postgres://u:p@127.0.0.1:1/none",
+		Yes:      true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "modified: users.csv") {
+		t.Fatalf("RunSeed err = %v, want checksum mismatch naming users.csv", err)
+	}
+}
+
+func TestRunSeed_refusesOtherEngineFamily(t *testing.T) {
+	const schema = `{"databaseType":"mysql","tables":[{"name":"users","columns":[{"name":"id","type":"int"}]}]}`
+	stageRevision(t, "billing/pro", schema, map[string]string{"users": "id\n1\n"})
+
+	_, err := RunSeed(context.Background(), SeedInput{
+		Scenario: "billing/pro",
+		DBURL:    "postgres://u:p@127.0.0.1:1/none",
+		Yes:      true,
+		DryRun:   true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "captured from mysql") {
+		t.Fatalf("RunSeed err = %v, want an engine mismatch", err)
+	}
+
+	out, err := RunSeed(context.Background(), SeedInput{
+		Scenario:            "billing/pro",
+		DBURL:               "postgres://u:p@127.0.0.1:1/none",
+		Yes:                 true,
+		DryRun:              true,
+		AllowEngineMismatch: true,
+	})
+	if err != nil || len(out.Warnings) != 1 {
+		t.Fatalf("with AllowEngineMismatch: %+v, %v", out.Warnings, err)
+	}
+}
+
+func TestCheckRevisionEngine(t *testing.T) {
+	rev := resolvedRevision{Scenario: "s", RevID: "r001"}
+	targets := []utils.NamedEnv{{Name: "crdb", EnvConfig: utils.EnvConfig{DatabaseURL: "cockroach://root@localhost:26257/app"}}}
+	warnings, err := checkRevisionEngine(rev, db.Postgres, targets, false)
+	if err != nil || len(warnings) != 1 {
+		t.Fatalf("same family: %v, %v; want one warning", warnings, err)
+	}
+	if warnings, err := checkRevisionEngine(rev, "", targets, false); err != nil || warnings != nil {
+		t.Fatalf("unknown engine: %v, %v; want no check", warnings, err)
+	}
+	sharded := []utils.NamedEnv{{Name: "app", EnvConfig: utils.EnvConfig{Shards: []string{"postgres://a/x", "mysql://b/x"}}}}
+	if _, err := checkRevisionEngine(rev, db.Postgres, sharded, false); err == nil || !strings.Contains(err.Error(), "app/shard1") {
+		t.Fatalf("sharded: err = %v, want shard1 refused", err)
+	}
+}
+
+func TestApplyWarmup(t *testing.T) {
+	rev := resolvedRevision{RevDir: t.TempDir()}
+	var opts db.RestoreOptions
+	if err := applyWarmup(&opts, rev); err != nil {
+		t.Fatalf("applyWarmup without file: %v", err)
+	}
+	if !opts.Analyze || opts.WarmupSQL != "" {
+		t.Fatalf("opts = %+v, want ANALYZE only", opts)
+	}
+
+	writeFile(t, filepath.Join(rev.RevDir, warmupFileName), "REFRESH MATERIALIZED VIEW daily_totals;\n")
+	if err := applyWarmup(&opts, rev); err != nil {
+		t.Fatalf("applyWarmup: %v", err)
+	}
+	if opts.WarmupSQL != "REFRESH MATERIALIZED VIEW daily_totals;\n" {
+		t.Fatalf("WarmupSQL = %q", opts.WarmupSQL)
+	}
+}
+
+func TestValidateCreateMissingOnly(t *testing.T) {
+	if err := validateCreateMissingOnly(db.RestoreOptions{Mode: db.RestoreReplace, TargetSchema: "run_1"}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	for name, opts := range map[string]db.RestoreOptions{
+		"upsert": {Mode: db.RestoreUpsert},
+		"tables": {Tables: []string{"users"}},
+		"warmup": {Analyze: true},
+		"vacuum": {Vacuum: db.VacuumRun},
+	} {
+		if err := validateCreateMissingOnly(opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestStampSeedRecord(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "users.csv"), "id\n1\n2\n")
+	writeFile(t, filepath.Join(dir, "orders.csv"), "id\n1\n")
+	writeFile(t, filepath.Join(dir, "schema.json"), "{}")
+
+	t.Setenv("SEEDMANCER_OPERATOR", "ci-nightly")
+	rev := resolvedRevision{Scenario: "billing/pro", RevID: "r003", DataDir: dir}
+	opts := db.RestoreOptions{Record: seedRecordFor(rev)}
+	if rec := opts.Record; rec.Operator != "ci-nightly" || rec.Checksum == "" {
+		t.Fatalf("record = %+v, want operator from env and a computed checksum", rec)
+	}
+
+	start := time.Now()
+	stamped := stampSeedRecord(opts, dir, start)
+	if stamped.Record == opts.Record {
+		t.Fatal("stampSeedRecord reused the shared record")
+	}
+	if rec := stamped.Record; rec.Rows != 3 || rec.RowCounts["users"] != 2 || !rec.StartedAt.Equal(start) {
+		t.Fatalf("record = %+v, want 3 rows from 2 tables", rec)
+	}
+
+	opts.Tables = []string{"orders"}
+	if rec := stampSeedRecord(opts, dir, start).Record; rec.Rows != 1 || len(rec.RowCounts) != 1 {
+		t.Fatalf("subset record = %+v, want orders only", rec)
+	}
+	if got := stampSeedRecord(db.RestoreOptions{}, dir, start); got.Record != nil {
+		t.Fatalf("record = %+v, want none without a record to stamp", got.Record)
+	}
+}
+
+func TestRunSeed_refusesProtectedHost(t *testing.T) {
+	const schema = `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer"}]}]}`
+	dir := stageRevision(t, "billing/pro", schema, map[string]string{"users": "id\n1\n"})
+	writeFile(t, filepath.Join(dir, "seedmancer.yaml"), "storage_path: .seedmancer\n"+
+		"protected_hosts:\n"+
+		"  - \"*.prod.example.com\"\n")
+
+	_, err := RunSeed(context.Background(), SeedInput{
+		Scenario: "billing/pro",
+		DBURL:    "postgres://u:p@eu.prod.example.com:5432/app",
+		Yes:      true,
+		Force:    true,
+		DryRun:   true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "protected_hosts") {
+		t.Fatalf("RunSeed err = %v, want a protected_hosts refusal", err)
+	}
+}
+
+func TestSeedTargetResult_cascade(t *testing.T) {
+	refused := seedTargetResult(seedResult{Env: "local", Err: fmt.Errorf("seeding: %w", &db.CascadeError{Tables: []string{"reviews"}})})
+	if refused.Ok || len(refused.Cascade) != 1 || refused.Cascade[0] != "reviews" {
+		t.Fatalf("refused = %+v, want the CascadeError's tables", refused)
+	}
+	preview := seedTargetResult(seedResult{Env: "local", Cascade: []string{"audit.events"}})
+	if !preview.Ok || len(preview.Cascade) != 1 || preview.Cascade[0] != "audit.events" {
+		t.Fatalf("preview = %+v, want ok with audit.events", preview)
+	}
+}
+
+func TestRunSeed_dryRunChecksTargets(t *testing.T) {
+	const schema = `{"tables":[{"name":"users","columns":[{"name":"id","type":"integer"}]}]}`
+	stageRevision(t, "billing/pro", schema, map[string]string{"users": "id\n1\n"})
+
+	out, err := RunSeed(context.Background(), SeedInput{
+		Scenario: "billing/pro",
+		DBURL:    "postgres://u:p@127.0.0.1:1/none",
+		DryRun:   true,
+	})
+	if err != nil {
+		t.Fatalf("RunSeed: %v", err)
+	}
+	if !out.DryRun || !out.AnyError || len(out.Results) != 1 || !strings.Contains(out.Results[0].Error, "connecting") {
+		t.Fatalf("out = %+v, want the unreachable target reported", out)
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// shardTargets expands a sharded env into one target per shard, named
+// <env>/shard<N>, so each can be checked and restored like a plain env.
+// Unsharded envs come back unchanged.
+func shardTargets(t utils.NamedEnv) []utils.NamedEnv {
+	if len(t.Shards) == 0 {
+		return []utils.NamedEnv{t}
+	}
+	out := make([]utils.NamedEnv, len(t.Shards))
+	for i, dsn := range t.Shards {
+		out[i] = utils.NamedEnv{
+			Name: fmt.Sprintf("%s/shard%d", t.Name, i),
+			EnvConfig: utils.EnvConfig{
+				DatabaseURL: dsn,
+				Values:      t.Values,
+				Role:        t.Role,
+			},
+		}
+	}
+	return out
+}
+
+// seedShards splits mergedDir across target's shards and hands each
+// shard its own restore dir via seed. It stops at the first failing
+// shard; the result covers the whole env.
+func seedShards(target utils.NamedEnv, mergedDir string, seed func(shard utils.NamedEnv, dir string) seedResult) seedResult {
+	start := time.Now()
+	dirs, cleanup, err := materializeShardDirs(mergedDir, len(target.Shards), target.ShardKeys)
+	if err != nil {
+		return seedResult{Env: targetDisplay(target), Err: err, Duration: time.Since(start)}
+	}
+	defer cleanup()
+
+	for i, shard := range shardTargets(target) {
+		res := seed(shard, dirs[i])
+		if res.Err != nil || res.Skipped {
+			res.Env = targetDisplay(target)
+			if res.Err != nil {
+				res.Err = fmt.Errorf("shard %d: %w", i, res.Err)
+			}
+			res.Duration = time.Since(start)
+			return res
+		}
+	}
+	return seedResult{Env: targetDisplay(target), Duration: time.Since(start)}
+}
+
+// materializeShardDirs stages restoreDir into n temp dirs, one per
+// shard. Tables named in keys have their CSV rows split by the key
+// column's value (see shardIndex); every other file — schema sidecars,
+// reference tables, JSON data — is linked into all n dirs unchanged.
+func materializeShardDirs(restoreDir string, n int, keys map[string]string) ([]string, func(), error) {
+	var dirs []string
+	cleanup := func() {
+		for _, d := range dirs {
+			_ = os.RemoveAll(d)
+		}
+	}
+	for i := 0; i < n; i++ {
+		tmp, err := os.MkdirTemp("", fmt.Sprintf("seedmancer-shard%d-*", i))
+		if err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("creating temp dir: %v", err)
+		}
+		dirs = append(dirs, tmp)
+	}
+
+	entries, err := os.ReadDir(restoreDir)
+	if err != nil {
+		cleanup()
+		return nil, func() {}, fmt.Errorf("reading restore dir: %v", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		src := filepath.Join(restoreDir, e.Name())
+		table := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		if key, ok := keys[table]; ok && strings.EqualFold(filepath.Ext(e.Name()), ".csv") {
+			if err := splitShardCSV(src, key, dirs); err != nil {
+				cleanup()
+				return nil, func() {}, fmt.Errorf("sharding %s: %v", table, err)
+			}
+			continue
+		}
+		for _, d := range dirs {
+			if err := linkOrCopy(src, filepath.Join(d, e.Name())); err != nil {
+				cleanup()
+				return nil, func() {}, fmt.Errorf("staging %s: %v", e.Name(), err)
+			}
+		}
+	}
+	return dirs, cleanup, nil
+}
+
+// splitShardCSV writes src's rows into one same-named CSV per dir, each
+// with the full header, routing every row by its key column.
+func splitShardCSV(src, key string, dirs []string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	r := csv.NewReader(in)
+	header, err := r.Read()
+	if err == io.EOF {
+		header = nil
+	} else if err != nil {
+		return err
+	}
+	keyIdx := -1
+	for i, h := range header {
+		if h == key {
+			keyIdx = i
+		}
+	}
+	if header != nil && keyIdx < 0 {
+		return fmt.Errorf("shard key column %q not in CSV header", key)
+	}
+
+	writers := make([]*csv.Writer, len(dirs))
+	for i, d := range dirs {
+		f, err := os.Create(filepath.Join(d, filepath.Base(src)))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		writers[i] = csv.NewWriter(f)
+		if header != nil {
+			if err := writers[i].Write(header); err != nil {
+				return err
+			}
+		}
+	}
+	for n := 1; header != nil; n++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if keyIdx >= len(record) || record[keyIdx] == "" || record[keyIdx] == "NULL" {
+			return fmt.Errorf("row %d has no value for shard key %q", n, key)
+		}
+		if err := writers[shardIndex(record[keyIdx], len(dirs))].Write(record); err != nil {
+			return err
+		}
+	}
+	for _, w := range writers {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardIndex picks the shard for a key value: integers go to value mod
+// n, so consecutive ids round-robin across shards; anything else (UUIDs,
+// slugs) by FNV-1a hash mod n.
+func shardIndex(value string, n int) int {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return int((v%int64(n) + int64(n)) % int64(n))
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(value))
+	return int(h.Sum32() % uint32(n))
+}
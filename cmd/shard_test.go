@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func TestMaterializeShardDirs(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "schema.json"), `{"tables":[]}`)
+	writeFile(t, filepath.Join(src, "tenants.csv"), "id,name\n1,a\n2,b\n3,c\n")
+	writeFile(t, filepath.Join(src, "orders.csv"), "id,tenant_id\n10,1\n11,2\n12,3\n13,1\n")
+	writeFile(t, filepath.Join(src, "countries.csv"), "code\nDE\nFR\n")
+
+	dirs, cleanup, err := materializeShardDirs(src, 2, map[string]string{"tenants": "id", "orders": "tenant_id"})
+	if err != nil {
+		t.Fatalf("materializeShardDirs: %v", err)
+	}
+	defer cleanup()
+
+	want := map[string][2]string{
+		"tenants.csv":   {"id,name\n2,b\n", "id,name\n1,a\n3,c\n"},
+		"orders.csv":    {"id,tenant_id\n11,2\n", "id,tenant_id\n10,1\n12,3\n13,1\n"},
+		"countries.csv": {"code\nDE\nFR\n", "code\nDE\nFR\n"},
+		"schema.json":   {`{"tables":[]}`, `{"tables":[]}`},
+	}
+	for name, perShard := range want {
+		for i, dir := range dirs {
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("shard %d %s: %v", i, name, err)
+			}
+			if string(got) != perShard[i] {
+				t.Errorf("shard %d %s = %q, want %q", i, name, got, perShard[i])
+			}
+		}
+	}
+}
+
+func TestMaterializeShardDirs_missingKeyColumn(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "orders.csv"), "id\n1\n")
+	if _, _, err := materializeShardDirs(src, 2, map[string]string{"orders": "tenant_id"}); err == nil {
+		t.Fatal("expected error for shard key missing from header")
+	}
+}
+
+func TestShardIndex(t *testing.T) {
+	if got := shardIndex("7", 3); got != 1 {
+		t.Errorf("shardIndex(7, 3) = %d, want 1", got)
+	}
+	if got := shardIndex("-1", 3); got != 2 {
+		t.Errorf("shardIndex(-1, 3) = %d, want 2", got)
+	}
+	uuid := "6f1c7c58-3c1e-4a8e-9d6a-2b7f0e5d1a11"
+	if a, b := shardIndex(uuid, 4), shardIndex(uuid, 4); a != b || a < 0 || a >= 4 {
+		t.Errorf("shardIndex(%q, 4) = %d then %d", uuid, a, b)
+	}
+}
+
+func TestShardTargets(t *testing.T) {
+	env := utils.NamedEnv{Name: "staging", EnvConfig: utils.EnvConfig{
+		DatabaseURL: "mysql://s0",
+		Shards:      []string{"mysql://s0", "mysql://s1"},
+		Role:        "seeder",
+	}}
+	got := shardTargets(env)
+	if len(got) != 2 || got[1].Name != "staging/shard1" || got[1].DatabaseURL != "mysql://s1" || got[1].Role != "seeder" || len(got[1].Shards) != 0 {
+		t.Fatalf("shardTargets = %+v", got)
+	}
+}
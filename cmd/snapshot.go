@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// SnapshotCommand manages database-level snapshots: server-side copies
+// of a whole database that restore far faster than re-seeding CSVs.
+//
+//	seedmancer snapshot create clean --env local
+//	seedmancer snapshot restore clean --env local
+func SnapshotCommand() *cli.Command {
+	return &cli.Command{
+		Name:            "snapshot",
+		Usage:           "Take and restore server-side copies of a whole database",
+		HideHelpCommand: true,
+		Description: "A snapshot is a copy of the target database kept on the same server,\n" +
+			"in a database named <db>__snap_<name>. Restoring one replaces the\n" +
+			"target with that copy without touching any scenario files, so it is\n" +
+			"the fast way back to a known state between test runs:\n\n" +
+			"  seedmancer seed billing/pro --env local\n" +
+			"  seedmancer snapshot create clean --env local\n" +
+			"  ... tests ...\n" +
+			"  seedmancer snapshot restore clean --env local --yes\n\n" +
+			"PostgreSQL copies with CREATE DATABASE … TEMPLATE and disconnects\n" +
+			"other sessions on the database first. MySQL and MariaDB copy every\n" +
+			"table and trigger; views and routines are left as they are.\n" +
+			"CockroachDB is not supported.",
+		Subcommands: []*cli.Command{
+			snapshotCreateCommand(),
+			snapshotRestoreCommand(),
+			snapshotListCommand(),
+			snapshotDropCommand(),
+		},
+	}
+}
+
+// snapshotTargetFlags are the target selectors every subcommand takes.
+func snapshotTargetFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "env",
+			Aliases: []string{"e"},
+			Usage:   "Named environment whose database to use (defaults to default_env)",
+		},
+		&cli.StringFlag{
+			Name:  "db-url",
+			Usage: "Ad-hoc database URL (takes precedence over --env)",
+		},
+	}
+}
+
+func snapshotCreateCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "create",
+		Usage:     "Copy the target database into a new snapshot",
+		ArgsUsage: "<name>",
+		Flags:     snapshotTargetFlags(),
+		Action: func(c *cli.Context) error {
+			name, target, err := snapshotArgs(c)
+			if err != nil {
+				return err
+			}
+			start := time.Now()
+			sp := ui.StartSpinner(fmt.Sprintf("Snapshotting %s...", targetDisplay(target)))
+			if err := db.CreateSnapshot(context.Background(), target.DatabaseURL, name); err != nil {
+				sp.Stop(false, "Snapshot failed")
+				return err
+			}
+			sp.Stop(true, fmt.Sprintf("Created snapshot %q of %s (%s)", name, targetDisplay(target), time.Since(start).Round(time.Millisecond)))
+			return nil
+		},
+	}
+}
+
+func snapshotRestoreCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "restore",
+		Usage:     "Replace the target database with a snapshot",
+		ArgsUsage: "<name>",
+		Flags: append(snapshotTargetFlags(), &cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Skip the confirmation prompt",
+		}),
+		Action: func(c *cli.Context) error {
+			name, target, err := snapshotArgs(c)
+			if err != nil {
+				return err
+			}
+			if !c.Bool("yes") {
+				if isProdLike(target.Name) {
+					ui.Title(fmt.Sprintf("→ %s", targetDisplay(target)))
+				}
+				if !ui.Confirm(fmt.Sprintf("Replace %q with snapshot %q?", targetDisplay(target), name), false) {
+					ui.Info("Skipped.")
+					return nil
+				}
+			}
+			start := time.Now()
+			sp := ui.StartSpinner(fmt.Sprintf("Restoring snapshot %q...", name))
+			if err := db.RestoreSnapshot(context.Background(), target.DatabaseURL, name); err != nil {
+				sp.Stop(false, "Restore failed")
+				return err
+			}
+			sp.Stop(true, fmt.Sprintf("Restored %s from snapshot %q (%s)", targetDisplay(target), name, time.Since(start).Round(time.Millisecond)))
+			return nil
+		},
+	}
+}
+
+func snapshotListCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "list",
+		Usage:     "List the target database's snapshots",
+		ArgsUsage: " ",
+		Flags: append(snapshotTargetFlags(), &cli.BoolFlag{
+			Name:  "json",
+			Usage: "Emit result as JSON",
+		}),
+		Action: func(c *cli.Context) error {
+			target, err := snapshotTarget(c)
+			if err != nil {
+				return err
+			}
+			snaps, err := db.ListSnapshots(context.Background(), target.DatabaseURL)
+			if err != nil {
+				return err
+			}
+			if c.Bool("json") {
+				if snaps == nil {
+					snaps = []db.Snapshot{}
+				}
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(snaps)
+			}
+			if len(snaps) == 0 {
+				ui.Info("No snapshots of %s. Take one with:", targetDisplay(target))
+				ui.Info("  seedmancer snapshot create <name>")
+				return nil
+			}
+			ui.Title(fmt.Sprintf("Snapshots of %s", targetDisplay(target)))
+			for _, s := range snaps {
+				created := "-"
+				if !s.CreatedAt.IsZero() {
+					created = s.CreatedAt.Local().Format("2006-01-02 15:04")
+				}
+				ui.Info("  %-20s %-16s %s", s.Name, created, formatBytes(s.SizeBytes))
+			}
+			return nil
+		},
+	}
+}
+
+func snapshotDropCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "drop",
+		Usage:     "Delete a snapshot",
+		ArgsUsage: "<name>",
+		Flags:     snapshotTargetFlags(),
+		Action: func(c *cli.Context) error {
+			name, target, err := snapshotArgs(c)
+			if err != nil {
+				return err
+			}
+			if err := db.DropSnapshot(context.Background(), target.DatabaseURL, name); err != nil {
+				return err
+			}
+			ui.Success("Dropped snapshot %q of %s", name, targetDisplay(target))
+			return nil
+		},
+	}
+}
+
+// snapshotArgs returns the required <name> argument and the target.
+func snapshotArgs(c *cli.Context) (string, utils.NamedEnv, error) {
+	name := strings.TrimSpace(c.Args().First())
+	if name == "" {
+		return "", utils.NamedEnv{}, usageError(c, "missing required argument: <name>")
+	}
+	target, err := snapshotTarget(c)
+	return name, target, err
+}
+
+// snapshotTarget resolves --db-url / --env. seedmancer.yaml is optional
+// here: snapshots never read scenario files, so --db-url alone is enough.
+func snapshotTarget(c *cli.Context) (utils.NamedEnv, error) {
+	cfg, _ := loadExistingConfig()
+	target, err := resolveSingleDB(c, cfg)
+	if err != nil {
+		return utils.NamedEnv{}, err
+	}
+	if strings.TrimSpace(target.DatabaseURL) == "" {
+		return utils.NamedEnv{}, fmt.Errorf("no database URL for %s", targetDisplay(target))
+	}
+	return target, nil
+}
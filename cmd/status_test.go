@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
 )
 
 func TestMaskDatabaseURL(t *testing.T) {
@@ -92,3 +95,47 @@ func TestHumanizeTokenSource(t *testing.T) {
 		}
 	}
 }
+
+func TestClassifyRemote(t *testing.T) {
+	cloud := datasetAPI{ID: "rev-2", UpdatedAt: "2026-01-02T00:00:00Z"}
+	cases := []struct {
+		name     string
+		hasLocal bool
+		latest   scenario.RevisionManifest
+		found    bool
+		want     string
+	}{
+		{"never pushed", true, scenario.RevisionManifest{}, false, remoteNotPushed},
+		{"nowhere", false, scenario.RevisionManifest{}, false, remoteUnknown},
+		{"only in cloud", false, scenario.RevisionManifest{}, true, remoteCloudNewer},
+		{"in sync", true, scenario.RevisionManifest{RemoteID: "rev-2", RemoteUpdatedAt: "2026-01-02T00:00:00Z"}, true, remoteInSync},
+		{"exported since", true, scenario.RevisionManifest{}, true, remoteLocalNewer},
+		{"pushed elsewhere", true, scenario.RevisionManifest{RemoteID: "rev-1", RemoteUpdatedAt: "2026-01-01T00:00:00Z"}, true, remoteCloudNewer},
+	}
+	for _, c := range cases {
+		if got := classifyRemote(c.hasLocal, c.latest, cloud, c.found); got != c.want {
+			t.Errorf("%s: classifyRemote = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFixtureNextSteps(t *testing.T) {
+	cases := []struct {
+		name string
+		f    statusFixture
+		want []string
+	}{
+		{"all agree", statusFixture{Scenario: "app", Local: "r002", Remote: remoteInSync, Seeded: "r002", Drift: "none"}, nil},
+		{"cloud newer", statusFixture{Scenario: "app", Local: "r002", Remote: remoteCloudNewer, Seeded: "r002", Drift: "none"},
+			[]string{"seedmancer pull app", "seedmancer seed app"}},
+		{"unpushed and stale db", statusFixture{Scenario: "app", Local: "r003", Remote: remoteLocalNewer, Seeded: "r002", Drift: "none"},
+			[]string{"seedmancer push app", "seedmancer seed app"}},
+		{"drifted", statusFixture{Scenario: "app", Local: "r002", Remote: remoteUnknown, Seeded: "r002", Drift: "detected"},
+			[]string{"seedmancer seed app"}},
+	}
+	for _, c := range cases {
+		if got := fixtureNextSteps(c.f); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: fixtureNextSteps = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
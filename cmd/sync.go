@@ -29,13 +29,26 @@ func PushCommand() *cli.Command {
 		Name:      "push",
 		Usage:     "Upload scenario revisions to the cloud",
 		ArgsUsage: "[scenario]",
-		Description: "Zips the schema sidecars + the chosen revision's CSVs and uploads\n" +
-			"them to your Seedmancer cloud account. The scenario path is the cloud name;\n" +
-			"the revision label (e.g. r002) is preserved on the server.\n\n" +
+		Description: "Zips the revision's schema.json and schema sidecars, its CSVs,\n" +
+			"dataset.sql and manifest.json and uploads them to your Seedmancer cloud\n" +
+			"account. The scenario path is the cloud name; the revision label (e.g.\n" +
+			"r002) is preserved on the server. Pull checks the data it downloads\n" +
+			"against the checksums in that manifest.\n\n" +
 			"With no argument, every local scenario is pushed: scenarios missing from\n" +
 			"the connected cloud API or whose local stamp no longer matches the cloud\n" +
 			"are uploaded (diff-only). Pass a scenario path to push just that one\n" +
-			"(re-pushes even if already in sync).",
+			"(re-pushes even if already in sync).\n\n" +
+			"Uploads are incremental where the cloud supports it: push lists each\n" +
+			"file's SHA-256 first and leaves out the files the cloud's latest\n" +
+			"revision already holds unchanged.\n\n" +
+			"When the cloud copy changed since this machine last pulled or pushed\n" +
+			"the scenario, push asks what to do instead of overwriting it:\n" +
+			"  overwrite  push the local revision over the cloud copy\n" +
+			"  rename     copy the local revision to the next revision label and\n" +
+			"             push that, so the cloud keeps its revision too\n" +
+			"  diff       pull the cloud copy as a new revision and show how the\n" +
+			"             local one differs, pushing nothing\n" +
+			"Without a terminal, pass the answer as --on-conflict.",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:  "token",
@@ -45,6 +58,10 @@ func PushCommand() *cli.Command {
 				Name:  "project",
 				Usage: "Cloud project slug (falls back to default_project in seedmancer.yaml, then server Default)",
 			},
+			&cli.StringFlag{
+				Name:  "on-conflict",
+				Usage: "When the cloud copy changed since the last pull or push: overwrite, rename or diff (default: ask)",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			configPath, err := utils.FindConfigFile()
@@ -60,6 +77,10 @@ func PushCommand() *cli.Command {
 			if err != nil {
 				return err
 			}
+			onConflict, err := parseConflictChoice(c.String("on-conflict"))
+			if err != nil {
+				return usageError(c, "%v", err)
+			}
 			projectSlug := utils.ResolveProjectSlug(c.String("project"), cfg)
 			utils.SetGlobalProjectSlug(projectSlug)
 
@@ -90,21 +111,26 @@ func PushCommand() *cli.Command {
 				}
 				// Also check by remoteScenarioID for renamed scenarios.
 				remoteScenarioID := rev.ScenarioManifest.RemoteScenarioID
-				cloudDS, foundByName := cloudDatasets[scenarioPath]
-				if !foundByName && remoteScenarioID != "" {
-					for _, ds := range cloudDatasets {
-						if ds.ScenarioID == remoteScenarioID {
-							cloudDS = ds
-							foundByName = true
-							break
-						}
-					}
-				}
+				cloudDS, foundByName := cloudDatasetFor(cloudDatasets, scenarioPath, remoteScenarioID)
 				if foundByName && isPushUpToDate(rev.Manifest, cloudDS) {
 					ui.Info("  skip  %s @ %s  (already in cloud)", scenarioPath, rev.RevID)
 					skipped++
 					continue
 				}
+				if err := verifyRevisionChecksum(rev); err != nil {
+					return fmt.Errorf("push %s: %w", scenarioPath, err)
+				}
+				if foundByName && remoteChanged(scenario.ScenarioDir(projectRoot, cfg.StoragePath, scenarioPath), cloudDS) {
+					var diff *cloudDiff
+					rev, diff, err = settleConflict(c.Context, projectRoot, cfg, rev, cloudDS, onConflict, token)
+					if err != nil {
+						return fmt.Errorf("push %s: %w", scenarioPath, err)
+					}
+					if diff != nil {
+						skipped++
+						continue
+					}
+				}
 				schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
 				ui.Step("%s @ %s  (schema %s)", scenarioPath, rev.RevID, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
 				if err := syncOne(schemaDir, rev.DataDir, scenarioPath, rev.RevID, baseURL, token, projectSlug, scenarioPrompt(projectRoot, cfg.StoragePath, scenarioPath), remoteScenarioID); err != nil {
@@ -112,7 +138,7 @@ func PushCommand() *cli.Command {
 				}
 				pushed++
 			}
-			ui.Info("pushed %d, skipped %d (already in cloud)", pushed, skipped)
+			ui.Info("pushed %d, skipped %d", pushed, skipped)
 			return nil
 			}
 
@@ -120,17 +146,39 @@ func PushCommand() *cli.Command {
 			if err != nil {
 				return err
 			}
-			rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, "")
-			if err != nil {
-				return err
-			}
-			schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
-			ui.Step("%s @ %s  (schema %s)", scenarioPath, rev.RevID, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
-			return syncOne(schemaDir, rev.DataDir, scenarioPath, rev.RevID, baseURL, token, projectSlug, scenarioPrompt(projectRoot, cfg.StoragePath, scenarioPath), rev.ScenarioManifest.RemoteScenarioID)
+			return pushScenario(c.Context, projectRoot, cfg, scenarioPath, baseURL, token, projectSlug, onConflict)
 		},
 	}
 }
 
+// pushScenario uploads the latest revision of scenarioPath, whether or
+// not the cloud already has it. When the cloud copy changed since it was
+// last synced, onConflict (or the user) settles what happens instead.
+func pushScenario(ctx context.Context, projectRoot string, cfg utils.Config, scenarioPath, baseURL, token, projectSlug, onConflict string) error {
+	rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, "")
+	if err != nil {
+		return err
+	}
+	if err := verifyRevisionChecksum(rev); err != nil {
+		return err
+	}
+	cloudDatasets, err := listRemoteDatasets(baseURL, token)
+	if err != nil {
+		return fmt.Errorf("listing cloud datasets: %w", err)
+	}
+	if cloudDS, ok := cloudDatasetFor(cloudDatasets, scenarioPath, rev.ScenarioManifest.RemoteScenarioID); ok &&
+		!isPushUpToDate(rev.Manifest, cloudDS) && remoteChanged(scenario.ScenarioDir(projectRoot, cfg.StoragePath, scenarioPath), cloudDS) {
+		var diff *cloudDiff
+		rev, diff, err = settleConflict(ctx, projectRoot, cfg, rev, cloudDS, onConflict, token)
+		if err != nil || diff != nil {
+			return err
+		}
+	}
+	schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	ui.Step("%s @ %s  (schema %s)", scenarioPath, rev.RevID, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	return syncOne(schemaDir, rev.DataDir, scenarioPath, rev.RevID, baseURL, token, projectSlug, scenarioPrompt(projectRoot, cfg.StoragePath, scenarioPath), rev.ScenarioManifest.RemoteScenarioID)
+}
+
 // isPushUpToDate reports whether the local revision stamp matches the cloud's
 // latest revision for the same scenario path. Mirrors the pull-side check in
 // RunFetch so push --all only skips scenarios the connected API confirms are
@@ -152,52 +200,90 @@ func scenarioPrompt(projectRoot, storagePath, scenarioPath string) string {
 	return strings.TrimSpace(m.Prompt)
 }
 
-// syncOne uploads schema sidecars + revision CSVs for a single scenario.
-// revisionID is sent as `revision=rNNN` so the cloud stores under that label.
-// prompt, when non-empty, is synced to the cloud scenario after the upload.
-// remoteScenarioID, when non-empty, is sent so the cloud resolves by stable id
-// (making a prior web rename transparent).
-func syncOne(schemaDir, dataDir, datasetName, revisionID, baseURL, token, projectSlug, prompt, remoteScenarioID string) error {
-	start := time.Now()
+// pushEntries lists the files a push uploads for the revision whose data
+// is in dataDir: schema.json and the sidecars in schemaDir, the dataset
+// files, dataset.sql when there is one, the revision's manifest.json, and
+// last the checksums listing of them all, which cleanup removes. Pull
+// checks the data it gets against the checksums in that manifest.
+//
+// A schema store without schema.json fails here, before anything is sent:
+// the cloud copy couldn't be restored without it.
+func pushEntries(schemaDir, dataDir string) ([]string, func(), error) {
+	if !fileExists(filepath.Join(schemaDir, "schema.json")) {
+		return nil, func() {}, fmt.Errorf(
+			"schema %s has no schema.json — the cloud copy couldn't be restored without it; export the scenario again to rewrite it",
+			schemaDir)
+	}
 	schemaFiles, err := utils.SchemaFiles(schemaDir)
 	if err != nil {
-		return err
+		return nil, func() {}, err
 	}
 	dataFiles, err := utils.DatasetFiles(dataDir)
 	if err != nil {
-		return err
+		return nil, func() {}, err
 	}
 	if len(dataFiles) == 0 {
-		return fmt.Errorf("no CSV or JSON files in %s", dataDir)
+		return nil, func() {}, fmt.Errorf("no CSV or JSON files in %s", dataDir)
 	}
 
-	entries := make([]string, 0, len(schemaFiles)+len(dataFiles)+1)
+	entries := make([]string, 0, len(schemaFiles)+len(dataFiles)+2)
 	entries = append(entries, schemaFiles...)
 	entries = append(entries, dataFiles...)
 	// Bundle the agent-written SQL sidecar (if present) so a round-trip
 	// pull preserves the source of truth, not just the materialised CSVs.
-	revDir := filepath.Dir(dataDir)
-	if sqlPath := DatasetSQLPath(revDir); fileExists(sqlPath) {
+	if sqlPath := DatasetSQLPath(filepath.Dir(dataDir)); fileExists(sqlPath) {
 		entries = append(entries, sqlPath)
 	}
+	if manifestPath := scenario.RevisionManifestPath(filepath.Dir(dataDir)); fileExists(manifestPath) {
+		entries = append(entries, manifestPath)
+	}
+	sumsPath, cleanup, err := writeBundleChecksums(entries)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return append(entries, sumsPath), cleanup, nil
+}
 
-	sp := ui.StartSpinner("Compressing...")
-	zipData, err := compressFiles(entries)
+// syncOne uploads schema sidecars + revision CSVs for a single scenario.
+// revisionID is sent as `revision=rNNN` so the cloud stores under that label.
+// prompt, when non-empty, is synced to the cloud scenario after the upload.
+// remoteScenarioID, when non-empty, is sent so the cloud resolves by stable id
+// (making a prior web rename transparent).
+func syncOne(schemaDir, dataDir, datasetName, revisionID, baseURL, token, projectSlug, prompt, remoteScenarioID string) error {
+	start := time.Now()
+	entries, cleanupSums, err := pushEntries(schemaDir, dataDir)
 	if err != nil {
-		sp.Stop(false, "Compression failed")
-		return fmt.Errorf("compressing files: %v", err)
+		return err
+	}
+	defer cleanupSums()
+	sums, err := scenario.ReadChecksumsFile(entries[len(entries)-1])
+	if err != nil {
+		return err
 	}
-	sp.Stop(true, fmt.Sprintf("Compressed (%s)", formatBytes(int64(zipData.Len()))))
 
 	ctx := context.Background()
 
-	sp = ui.StartSpinner("Uploading...")
+	// The server is told which files the revision has first, so the zip
+	// can leave out those it already holds.
 	ui.Debug("POST %s/v1.0/datasets/sync/upload-url?name=%s", baseURL, datasetName)
-	uploadURLResp, err := requestUploadURL(ctx, token, baseURL, datasetName, revisionID, remoteScenarioID, projectSlug)
+	uploadURLResp, err := requestUploadURL(ctx, token, baseURL, datasetName, revisionID, remoteScenarioID, projectSlug, sums)
 	if err != nil {
-		sp.Stop(false, "Upload failed")
 		return err
 	}
+	send := uploadEntries(entries, uploadURLResp.Reuse)
+	if skipped := len(entries) - len(send); skipped > 0 {
+		ui.Info("  %d unchanged file(s) already in the cloud; uploading %d", skipped, len(send)-1)
+	}
+
+	sp := ui.StartSpinner("Compressing...")
+	zipData, err := compressFiles(send)
+	if err != nil {
+		sp.Stop(false, "Compression failed")
+		return fmt.Errorf("compressing files: %v", err)
+	}
+	sp.Stop(true, fmt.Sprintf("Compressed (%s)", formatBytes(int64(zipData.Len()))))
+
+	sp = ui.StartSpinner("Uploading...")
 	if err := putToStorage(ctx, uploadURLResp.UploadURL, zipData); err != nil {
 		sp.Stop(false, "Upload failed")
 		return err
@@ -226,6 +312,7 @@ func syncOne(schemaDir, dataDir, datasetName, revisionID, baseURL, token, projec
 	if canonicalName == "" {
 		canonicalName = datasetName
 	}
+	revDir := filepath.Dir(dataDir)
 	if result.ScenarioID != "" {
 		scenarioDir := filepath.Dir(revDir)
 		if sm, smErr := scenario.ReadManifest(scenarioDir); smErr == nil {
@@ -302,8 +389,10 @@ func stampRemoteRevision(revDir, baseURL, token, datasetName, schemaPrefix strin
 }
 
 // requestUploadURL calls POST /v1.0/datasets/sync/upload-url and returns
-// the presigned storage URL and staging path.
-func requestUploadURL(ctx context.Context, token, baseURL, datasetName, revisionID, remoteScenarioID, projectSlug string) (uploadURLResponse, error) {
+// the presigned storage URL and staging path. files, the upload's
+// checksums by in-archive name, travel in the body so the server can
+// name the ones it already holds (uploadURLResponse.Reuse).
+func requestUploadURL(ctx context.Context, token, baseURL, datasetName, revisionID, remoteScenarioID, projectSlug string, files map[string]string) (uploadURLResponse, error) {
 	q := url.Values{}
 	q.Set("name", datasetName)
 	if strings.TrimSpace(revisionID) != "" {
@@ -314,10 +403,17 @@ func requestUploadURL(ctx context.Context, token, baseURL, datasetName, revision
 	}
 	endpoint := fmt.Sprintf("%s/v1.0/datasets/sync/upload-url?%s", baseURL, q.Encode())
 
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, nil)
+	payload, err := json.Marshal(struct {
+		Files map[string]string `json:"files"`
+	}{Files: files})
+	if err != nil {
+		return uploadURLResponse{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(payload))
 	if err != nil {
 		return uploadURLResponse{}, fmt.Errorf("creating upload-url request: %v", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", utils.BearerAPIToken(token))
 	utils.ApplyProjectHeader(req, projectSlug)
 
@@ -416,6 +512,32 @@ func newSchemaBadge(created bool) string {
 	return ""
 }
 
+// writeBundleChecksums hashes every file bound for a push zip and writes
+// the listing, keyed by the flattened in-zip name, to a temporary
+// checksums.sha256 that pull verifies after extraction. The returned
+// cleanup removes the temp file.
+func writeBundleChecksums(files []string) (string, func(), error) {
+	sums := make(map[string]string, len(files))
+	for _, f := range files {
+		sum, err := scenario.FileSHA256(f)
+		if err != nil {
+			return "", nil, err
+		}
+		sums[filepath.Base(f)] = sum
+	}
+	dir, err := os.MkdirTemp("", "seedmancer-sums-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("creating checksum dir: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	path := filepath.Join(dir, scenario.ChecksumsFileName)
+	if err := scenario.WriteChecksumsFile(path, sums); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("writing %s: %w", scenario.ChecksumsFileName, err)
+	}
+	return path, cleanup, nil
+}
+
 func compressFiles(files []string) (*bytes.Buffer, error) {
 	buf := new(bytes.Buffer)
 	zipWriter := zip.NewWriter(buf)
@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -321,3 +323,190 @@ func writeScenarioForPush(t *testing.T, projectRoot, storagePath, scenarioPath,
 		t.Fatalf("write revision manifest %s: %v", scenarioPath, err)
 	}
 }
+
+func TestRemoteChanged(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	writeScenarioForPush(t, dir, ".seedmancer", "alpha", "fp", now)
+	scDir := scenario.ScenarioDir(dir, ".seedmancer", "alpha")
+	cloud := datasetAPI{ID: "cloud-alpha", UpdatedAt: "2026-06-15T12:00:00Z"}
+
+	if !remoteChanged(scDir, cloud) {
+		t.Fatal("a cloud copy never synced with should count as changed")
+	}
+	// r001 was pulled; r002, exported since, carries no stamp.
+	stamp := func(revID, updatedAt string) {
+		revDir := scenario.RevisionDir(dir, ".seedmancer", "alpha", revID)
+		if err := os.MkdirAll(revDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		rm, _ := scenario.ReadRevisionManifest(revDir)
+		rm.Revision, rm.RemoteID, rm.RemoteUpdatedAt = revID, cloud.ID, updatedAt
+		if err := scenario.WriteRevisionManifest(revDir, rm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stamp("r001", cloud.UpdatedAt)
+	if err := os.MkdirAll(scenario.RevisionDir(dir, ".seedmancer", "alpha", "r002"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if remoteChanged(scDir, cloud) {
+		t.Fatal("cloud unchanged since r001 was pulled")
+	}
+	cloud.UpdatedAt = "2026-06-16T09:00:00Z"
+	if !remoteChanged(scDir, cloud) {
+		t.Fatal("cloud updated since r001 was pulled")
+	}
+	// A newer pull (diff) has seen the cloud's change.
+	stamp("r003", cloud.UpdatedAt)
+	if remoteChanged(scDir, cloud) {
+		t.Fatal("r003 mirrors the cloud copy")
+	}
+}
+
+func TestPushCommand_conflict(t *testing.T) {
+	dir := t.TempDir()
+	prev, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Setenv("HOME", dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "seedmancer.yaml"), []byte("storage_path: .seedmancer\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	schemaShort := "deadbeefcafe"
+	schemaDir := filepath.Join(dir, ".seedmancer", "schemas", schemaShort)
+	if err := os.MkdirAll(schemaDir, 0755); err != nil {
+		t.Fatalf("mkdir schema: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(schemaDir, "schema.json"), []byte("{}"), 0600); err != nil {
+		t.Fatalf("write schema.json: %v", err)
+	}
+	writeScenarioForPush(t, dir, ".seedmancer", "alpha", "deadbeefcafebabe0000", time.Now().UTC())
+
+	// r001 was pushed from here; someone has pushed over it since.
+	revDir := scenario.RevisionDir(dir, ".seedmancer", "alpha", "r001")
+	rm, err := scenario.ReadRevisionManifest(revDir)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	rm.RemoteID = "cloud-alpha"
+	rm.RemoteUpdatedAt = "2026-06-15T12:00:00Z"
+	if err := scenario.WriteRevisionManifest(revDir, rm); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	var uploads []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1.0/datasets":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(datasetListResponse{Datasets: []datasetAPI{{
+				ID: "cloud-alpha", Name: "alpha", UpdatedAt: "2026-06-16T09:00:00Z",
+			}}})
+		case r.Method == http.MethodPost && r.URL.Path == "/v1.0/datasets/sync/upload-url":
+			uploads = append(uploads, r.URL.Query().Get("revision"))
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(uploadURLResponse{UploadURL: server.URL + "/blob", Path: "staging/test.zip"})
+		case r.Method == http.MethodPut && r.URL.Path == "/blob":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1.0/datasets/sync/confirm":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(syncUploadResult{ID: "ds_1", Name: "alpha", FingerprintShort: schemaShort, FileCount: 2})
+		default:
+			t.Fatalf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("SEEDMANCER_API_URL", server.URL)
+
+	app := &cli.App{
+		Name:      "seedmancer",
+		Writer:    io.Discard,
+		ErrWriter: io.Discard,
+		Commands:  []*cli.Command{PushCommand()},
+	}
+	err = app.Run([]string{"seedmancer", "push", "--token", "tok_test", "alpha"})
+	if err == nil || !strings.Contains(err.Error(), "--on-conflict") {
+		t.Fatalf("push without a terminal: err = %v, want a pointer to --on-conflict", err)
+	}
+	if len(uploads) != 0 {
+		t.Fatalf("nothing should be pushed on a conflict, got %v", uploads)
+	}
+
+	if err := app.Run([]string{"seedmancer", "push", "--token", "tok_test", "--on-conflict", "rename", "alpha"}); err != nil {
+		t.Fatalf("push --on-conflict rename: %v", err)
+	}
+	if len(uploads) != 1 || uploads[0] != "r002" {
+		t.Fatalf("expected a copy pushed as r002, got %v", uploads)
+	}
+	if _, err := os.Stat(filepath.Join(scenario.RevisionDir(dir, ".seedmancer", "alpha", "r002"), "data", "User.csv")); err != nil {
+		t.Fatalf("r002 copy: %v", err)
+	}
+}
+
+func TestPushCommand_missingSchemaJSON(t *testing.T) {
+	dir := stageRevision(t, "alpha", `{"tables":[]}`, map[string]string{"users": "id\n1\n"})
+	schemaPath := scenario.SchemaJSONPath(dir, ".seedmancer", "abababababab")
+	if err := os.Remove(schemaPath); err != nil {
+		t.Fatal(err)
+	}
+
+	var uploads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/v1.0/datasets" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(datasetListResponse{})
+			return
+		}
+		uploads = append(uploads, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	t.Setenv("SEEDMANCER_API_URL", server.URL)
+
+	app := &cli.App{
+		Name:      "seedmancer",
+		Writer:    io.Discard,
+		ErrWriter: io.Discard,
+		Commands:  []*cli.Command{PushCommand()},
+	}
+	for _, args := range [][]string{{"alpha"}, nil} {
+		err := app.Run(append([]string{"seedmancer", "push", "--token", "tok_test"}, args...))
+		if err == nil || !strings.Contains(err.Error(), "schema.json") {
+			t.Fatalf("push %v = %v, want an error naming the missing schema.json", args, err)
+		}
+	}
+	if _, err := RunSync(context.Background(), SyncInput{Scenario: "alpha", Token: "tok_test"}); err == nil || !strings.Contains(err.Error(), "schema.json") {
+		t.Fatalf("RunSync = %v, want an error naming the missing schema.json", err)
+	}
+	if len(uploads) != 0 {
+		t.Fatalf("nothing should be uploaded, got %v", uploads)
+	}
+}
+
+func TestPushEntries_includesRevisionManifest(t *testing.T) {
+	dir := t.TempDir()
+	schemaDir, revDir := filepath.Join(dir, "schema"), filepath.Join(dir, "r001")
+	writeFile(t, filepath.Join(schemaDir, "schema.json"), "{}")
+	writeFile(t, filepath.Join(revDir, "data", "users.csv"), "id\n1\n")
+	if err := scenario.WriteRevisionManifest(revDir, scenario.RevisionManifest{Scenario: "alpha", Revision: "r001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, cleanup, err := pushEntries(schemaDir, filepath.Join(revDir, "data"))
+	if err != nil {
+		t.Fatalf("pushEntries: %v", err)
+	}
+	defer cleanup()
+	var names []string
+	for _, e := range entries {
+		names = append(names, filepath.Base(e))
+	}
+	if got := strings.Join(names, ","); got != "schema.json,users.csv,manifest.json,"+scenario.ChecksumsFileName {
+		t.Errorf("entries = %s, want the manifest before the checksums listing", got)
+	}
+}
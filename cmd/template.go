@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// seedViaTemplate is `seed --template` for one target: it resets the
+// target from the PostgreSQL template database matching what would be
+// loaded, or, when there is none yet, seeds through load and saves the
+// result as that template for next time. label (the scenario) lets a new
+// template replace the scenario's previous ones.
+func seedViaTemplate(target utils.NamedEnv, mergedDir, label string, opts db.RestoreOptions, load func(target utils.NamedEnv, dir string) seedResult) seedResult {
+	if len(target.Shards) > 0 {
+		return seedShards(target, mergedDir, func(shard utils.NamedEnv, dir string) seedResult {
+			return seedViaTemplate(shard, dir, label, opts, load)
+		})
+	}
+	start := time.Now()
+	fail := func(err error) seedResult {
+		return seedResult{Env: targetDisplay(target), Err: err, Duration: time.Since(start)}
+	}
+
+	restoreDir, cleanupResolved, err := resolveMarkersDir(mergedDir, target.Values, target.Name)
+	if err != nil {
+		return fail(err)
+	}
+	defer cleanupResolved()
+	opts.Role = target.Role
+	key, err := templateKey(restoreDir, opts)
+	if err != nil {
+		return fail(err)
+	}
+	template := db.TemplateDatabaseName(key)
+
+	ctx := context.Background()
+	reset, err := db.ResetFromTemplate(ctx, target.DatabaseURL, template)
+	if err != nil {
+		return fail(fmt.Errorf("resetting from template %s: %w", template, err))
+	}
+	if reset {
+		// Planner statistics come along with the copy; caches don't.
+		if opts.WarmupSQL != "" {
+			if err := execOnTarget(target, opts.WarmupSQL); err != nil {
+				return fail(fmt.Errorf("running warmup SQL: %w", err))
+			}
+		}
+		return seedResult{Env: targetDisplay(target), Duration: time.Since(start), FromTemplate: true}
+	}
+
+	res := load(target, mergedDir)
+	if res.Err != nil || res.Skipped {
+		return res
+	}
+	if err := db.SaveTemplate(ctx, target.DatabaseURL, template, label); err != nil {
+		res.Err = fmt.Errorf("saving template %s: %w", template, err)
+	}
+	res.Duration = time.Since(start)
+	return res
+}
+
+// templateKey names the template for a restore of dir with opts: a hash
+// of every file in dir (symlinks followed, so a staged restore dir hashes
+// like its sources) plus the options that change what ends up in the
+// database. Any change to the fixture therefore gets a fresh template.
+func templateKey(dir string, opts db.RestoreOptions) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("reading restore dir: %v", err)
+	}
+	h := sha256.New()
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		sum, err := scenario.FileSHA256(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s\n", sum, e.Name())
+	}
+	fmt.Fprintf(h, "analyze=%t role=%s\n", opts.Analyze, opts.Role)
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// validateTemplateSeed rejects options a template can't reproduce: a
+// template holds the whole fixture, loaded into a freshly emptied database.
+func validateTemplateSeed(opts db.RestoreOptions) error {
+	switch {
+	case opts.Mode != "" && opts.Mode != db.RestoreReplace:
+		return fmt.Errorf("--template only works with --mode %s", db.RestoreReplace)
+	case len(opts.Tables) > 0:
+		return fmt.Errorf("--template can't be combined with --tables")
+	case isSandboxRestore(opts):
+		return fmt.Errorf("--template can't be combined with --target-schema or --target-database")
+	}
+	return nil
+}
+
+// execOnTarget runs sqlText against target's database.
+func execOnTarget(target utils.NamedEnv, sqlText string) error {
+	manager, normalizedURL, err := db.NewManager(target.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	if err := manager.ConnectWithDSN(normalizedURL); err != nil {
+		return fmt.Errorf("connecting: %v", err)
+	}
+	return manager.ExecSQL(sqlText)
+}
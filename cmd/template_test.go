@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	db "github.com/KazanKK/seedmancer/database"
+)
+
+func TestTemplateKey_followsSymlinksAndTracksContent(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "schema.json"), `{"tables":[]}`)
+	writeFile(t, filepath.Join(src, "users.csv"), "id\n1\n")
+
+	copied := t.TempDir()
+	writeFile(t, filepath.Join(copied, "schema.json"), `{"tables":[]}`)
+	writeFile(t, filepath.Join(copied, "users.csv"), "id\n1\n")
+
+	linked := t.TempDir()
+	for _, name := range []string{"schema.json", "users.csv"} {
+		if err := os.Symlink(filepath.Join(src, name), filepath.Join(linked, name)); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	key := func(dir string, opts db.RestoreOptions) string {
+		t.Helper()
+		k, err := templateKey(dir, opts)
+		if err != nil {
+			t.Fatalf("templateKey: %v", err)
+		}
+		return k
+	}
+	base := key(copied, db.RestoreOptions{})
+	if got := key(linked, db.RestoreOptions{}); got != base {
+		t.Fatalf("symlinked dir key %s != copied dir key %s", got, base)
+	}
+	if key(copied, db.RestoreOptions{Analyze: true}) == base {
+		t.Fatal("Analyze should change the key")
+	}
+	writeFile(t, filepath.Join(copied, "users.csv"), "id\n2\n")
+	if key(copied, db.RestoreOptions{}) == base {
+		t.Fatal("changed CSV should change the key")
+	}
+}
+
+func TestValidateTemplateSeed(t *testing.T) {
+	if err := validateTemplateSeed(db.RestoreOptions{Mode: db.RestoreReplace}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	for name, opts := range map[string]db.RestoreOptions{
+		"upsert": {Mode: db.RestoreUpsert},
+		"tables": {Tables: []string{"users"}},
+		"schema": {TargetSchema: "run_1"},
+	} {
+		if err := validateTemplateSeed(opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/devdb"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// UpCommand starts a disposable database container seeded with a
+// scenario revision and prints its URL.
+//
+//	seedmancer up billing/pro
+//	export DATABASE_URL=$(seedmancer up billing/pro --revision r003)
+func UpCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "up",
+		Usage:     "Start a throwaway database in Docker, seed it, and print its URL",
+		ArgsUsage: "<scenario>",
+		Description: "Starts a database container with the docker CLI, waits for it to\n" +
+			"accept connections, seeds it with the scenario's latest revision (or\n" +
+			"--revision) and prints the connection URL on stdout:\n\n" +
+			"  export DATABASE_URL=$(seedmancer up billing/pro)\n\n" +
+			"The engine defaults to the one the revision was captured from and\n" +
+			"can be set with --engine (postgres, mysql, mariadb, cockroach). The\n" +
+			"server listens on 127.0.0.1 only, on a free port unless --port is\n" +
+			"given. Pass --save-env <name> to also record the URL as an env in\n" +
+			"seedmancer.yaml.\n\n" +
+			"Remove the container again with `seedmancer down`.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "revision",
+				Aliases: []string{"r"},
+				Usage:   "Revision to seed (default: latest)",
+			},
+			&cli.StringFlag{
+				Name:  "engine",
+				Usage: "Database engine: postgres, mysql, mariadb or cockroach (default: the revision's)",
+			},
+			&cli.StringFlag{
+				Name:  "image",
+				Usage: "Container image to run instead of the engine's default",
+			},
+			&cli.StringFlag{
+				Name:  "name",
+				Usage: "Container name (default: seedmancer-<project>-<engine>)",
+			},
+			&cli.StringFlag{
+				Name:  "port",
+				Usage: "Host port to publish the database on (default: any free port)",
+			},
+			&cli.DurationFlag{
+				Name:  "wait",
+				Usage: "How long to wait for the database to start",
+				Value: 90 * time.Second,
+			},
+			&cli.StringFlag{
+				Name:  "save-env",
+				Usage: "Save the container's URL in seedmancer.yaml as this env",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Emit the container as JSON instead of the bare URL",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			if scenarioArg == "" {
+				return usageError(c, "missing required argument: <scenario>")
+			}
+			configPath, err := utils.FindConfigFile()
+			if err != nil {
+				return err
+			}
+			projectRoot := filepath.Dir(configPath)
+			cfg, err := utils.LoadConfig(configPath)
+			if err != nil {
+				return err
+			}
+			saveEnv := strings.TrimSpace(c.String("save-env"))
+			if saveEnv != "" {
+				if err := validateEnvName(saveEnv); err != nil {
+					return err
+				}
+			}
+
+			rev, err := resolveSeedRevision(projectRoot, cfg.StoragePath, scenarioArg, c.String("revision"), false)
+			if err != nil {
+				return err
+			}
+			if err := verifyRevisionChecksum(rev); err != nil {
+				return err
+			}
+			captured := revisionDatabaseType(projectRoot, cfg.StoragePath, rev)
+			engine, err := upEngine(c.String("engine"), captured)
+			if err != nil {
+				return err
+			}
+			if captured != "" && engineFamily(engine.Type) != engineFamily(captured) {
+				return fmt.Errorf("%s @ %s was captured from %s and can't be loaded into %s — drop --engine or pick %s",
+					rev.Scenario, rev.RevID, captured, engine.Type, captured)
+			}
+			name := strings.TrimSpace(c.String("name"))
+			if name == "" {
+				name = devdb.ContainerName(filepath.Base(projectRoot), engine.Type)
+			}
+
+			ctx := context.Background()
+			sp := ui.StartSpinner(fmt.Sprintf("Starting %s container %s...", engine.Type, name))
+			container, err := devdb.Start(ctx, devdb.Spec{
+				Name:     name,
+				Engine:   engine,
+				Project:  projectRoot,
+				Image:    strings.TrimSpace(c.String("image")),
+				HostPort: strings.TrimSpace(c.String("port")),
+			})
+			if err != nil {
+				sp.Stop(false, "Could not start the container")
+				if strings.Contains(err.Error(), "is already in use") {
+					return fmt.Errorf("%v\nremove the old one with `seedmancer down %s`", err, name)
+				}
+				return err
+			}
+			sp.Stop(true, fmt.Sprintf("Started %s", container.Name))
+
+			// From here on a failure would leave a half-seeded container
+			// behind, so take it down again.
+			fail := func(err error) error {
+				if rmErr := devdb.Remove(ctx, container.Name); rmErr != nil {
+					ui.Warn("Could not remove %s: %v", container.Name, rmErr)
+				}
+				return err
+			}
+			target := utils.NamedEnv{Name: container.Name, EnvConfig: utils.EnvConfig{DatabaseURL: container.URL}}
+			sp = ui.StartSpinner("Waiting for the database to accept connections...")
+			if err := db.WaitForReady(ctx, container.URL, c.Duration("wait"), func(reason string) {
+				ui.Debug("%s not ready: %s", container.Name, reason)
+			}); err != nil {
+				sp.Stop(false, "Database did not come up")
+				return fail(err)
+			}
+			sp.Stop(true, "Database is ready")
+
+			schemaDir := scenario.SchemaStoreDir(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+			merged, cleanup, err := materializeRestoreDir(schemaDir, rev.DataDir)
+			if err != nil {
+				return fail(err)
+			}
+			defer cleanup()
+			// The database is empty, so there is no live schema to guard
+			// against; the restore creates it.
+			opts := restoreOptionsFromConfig(cfg)
+			opts.Record = seedRecordFor(rev)
+			if res := seedOneEnv(target, merged, rev.RevID, rev.Scenario, true, opts); res.Err != nil {
+				return fail(res.Err)
+			}
+
+			if saveEnv != "" {
+				if prev, ok := cfg.EffectiveEnvs()[saveEnv]; ok && prev.DatabaseURL != container.URL {
+					ui.Warn("Replacing env %q (was %s)", saveEnv, maskDatabaseURL(prev.DatabaseURL))
+				}
+				cfg.SetEnv(saveEnv, utils.EnvConfig{DatabaseURL: container.URL})
+				if err := utils.SaveConfig(configPath, cfg); err != nil {
+					return err
+				}
+				ui.Success("Saved env %q → %s", saveEnv, container.Name)
+			}
+
+			if c.Bool("json") {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(container)
+			}
+			ui.Info("%s @ %s is up. Stop it with `seedmancer down %s`.", rev.Scenario, rev.RevID, container.Name)
+			fmt.Fprintln(os.Stdout, container.URL)
+			return nil
+		},
+	}
+}
+
+// upEngine picks the engine for `up`: the --engine flag, else the engine
+// the revision was captured from, else PostgreSQL.
+func upEngine(flag string, captured db.DatabaseType) (devdb.Engine, error) {
+	name := strings.TrimSpace(flag)
+	if name == "" {
+		name = string(captured)
+	}
+	if name == "" {
+		name = string(db.Postgres)
+	}
+	return devdb.LookupEngine(name)
+}
+
+// DownCommand removes the containers `seedmancer up` started.
+func DownCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "down",
+		Usage:     "Remove the databases started by `seedmancer up`",
+		ArgsUsage: "[container...]",
+		Description: "Stops and deletes the named containers, or with no arguments every\n" +
+			"container `seedmancer up` started for this project, along with\n" +
+			"their data. --all removes seedmancer's containers of every project.\n" +
+			"Containers seedmancer didn't start are never touched.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "Remove the containers of every project, not just this one",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			project := ""
+			if !c.Bool("all") {
+				configPath, err := utils.FindConfigFile()
+				if err != nil {
+					return err
+				}
+				project = filepath.Dir(configPath)
+			}
+			ctx := context.Background()
+			containers, err := devdb.List(ctx, project)
+			if err != nil {
+				return err
+			}
+			names, err := downTargets(containers, c.Args().Slice())
+			if err != nil {
+				return err
+			}
+			if len(names) == 0 {
+				ui.Info("No seedmancer containers to remove.")
+				return nil
+			}
+			if err := devdb.Remove(ctx, names...); err != nil {
+				return err
+			}
+			for _, n := range names {
+				ui.Success("Removed %s", n)
+			}
+			return nil
+		},
+	}
+}
+
+// downTargets narrows containers to the requested names; every requested
+// name must be one of them, so down can't remove a foreign container.
+func downTargets(containers []devdb.Container, requested []string) ([]string, error) {
+	known := make(map[string]bool, len(containers))
+	var all []string
+	for _, ct := range containers {
+		known[ct.Name] = true
+		all = append(all, ct.Name)
+	}
+	if len(requested) == 0 {
+		return all, nil
+	}
+	for _, name := range requested {
+		if !known[name] {
+			return nil, fmt.Errorf("%s is not a container seedmancer up started here (see `docker ps --filter label=%s`)", name, devdb.LabelManaged)
+		}
+	}
+	return requested, nil
+}
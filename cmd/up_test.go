@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/devdb"
+)
+
+func TestUpEngine(t *testing.T) {
+	for flag, want := range map[string]string{"": "mysql", "MariaDB": "mariadb"} {
+		e, err := upEngine(flag, db.MySQL)
+		if err != nil || string(e.Type) != want {
+			t.Fatalf("upEngine(%q) = %s, %v; want %s", flag, e.Type, err, want)
+		}
+	}
+	if e, err := upEngine("", ""); err != nil || e.Type != "postgres" {
+		t.Fatalf("default engine = %s, %v", e.Type, err)
+	}
+	if _, err := upEngine("oracle", db.MySQL); err == nil {
+		t.Fatal("expected an error for an unknown engine")
+	}
+}
+
+func TestDownTargets(t *testing.T) {
+	containers := []devdb.Container{{Name: "seedmancer-app-postgres"}, {Name: "seedmancer-app-mysql"}}
+	got, err := downTargets(containers, nil)
+	if err != nil || !reflect.DeepEqual(got, []string{"seedmancer-app-postgres", "seedmancer-app-mysql"}) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	if _, err := downTargets(containers, []string{"some-other-db"}); err == nil {
+		t.Fatal("expected an error for a container seedmancer didn't start")
+	}
+}
@@ -0,0 +1,385 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// validateShown caps how many problems the text output lists; --json
+// always carries all of them.
+const validateShown = 50
+
+// ValidateCommand checks a revision's data against its own schema.json
+// offline, so a hand-edited or generated snapshot fails here instead of
+// half way through a seed.
+func ValidateCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "validate",
+		Usage:     "Check scenario revisions' CSVs against their schema, without a database by default",
+		ArgsUsage: "<scenario>",
+		Description: "Reads the revision's schema.json and every table CSV and reports:\n" +
+			"  - a schema.json that does not parse, or that refers to tables,\n" +
+			"    columns or enums it doesn't declare\n" +
+			"  - tables with no CSV (write a header-only CSV for an empty table)\n" +
+			"    and CSVs with no table\n" +
+			"  - header columns the table lacks, and NOT NULL columns without a\n" +
+			"    default that the header leaves out\n" +
+			"  - cells that don't parse as their column type, NULL in NOT NULL\n" +
+			"    columns, values outside an enum or CHECK list, and strings\n" +
+			"    longer than their varchar length\n" +
+			"  - foreign key values with no matching parent row in the revision\n" +
+			"  - data/ no longer matching the checksum in the revision manifest\n\n" +
+			"@env markers and =DEFAULT / \\D / =expr(...) cells are resolved at seed\n" +
+			"time and are not checked. Exits non-zero when anything is found.\n\n" +
+			"With --env or --db-url the live database's schema fingerprint is\n" +
+			"compared too, as seed's guard would. Without them nothing connects\n" +
+			"anywhere; --offline makes that a promise for CI on fixture-only PRs,\n" +
+			"failing instead of connecting if a target is passed. --all checks\n" +
+			"the latest revision of every scenario. --dir needs no seedmancer.yaml,\n" +
+			"even with --db-url.\n\n" +
+			"Examples:\n" +
+			"  seedmancer validate billing/pro\n" +
+			"  seedmancer validate billing/pro --revision r003 --output json\n" +
+			"  seedmancer validate --all --offline\n" +
+			"  seedmancer validate billing/pro --env staging\n" +
+			"  seedmancer validate --dir ./fixtures/billing",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "revision",
+				Aliases: []string{"r"},
+				Usage:   "Revision to validate (defaults to latest)",
+			},
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "Validate a flat directory of schema.json plus <table>.csv files instead of a scenario",
+			},
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "Validate the latest revision of every scenario",
+			},
+			&cli.StringFlag{
+				Name:    "env",
+				Aliases: []string{"e"},
+				Usage:   "Also compare the schema with this named environment's database",
+			},
+			&cli.StringFlag{
+				Name:  "db-url",
+				Usage: "Also compare the schema with this database (takes precedence over env)",
+			},
+			&cli.BoolFlag{
+				Name:  "offline",
+				Usage: "Never connect to a database; fail if --env or --db-url is given",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Same as --output json",
+			},
+			outputFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			scenarioArg := strings.TrimSpace(c.Args().First())
+			dir := strings.TrimSpace(c.String("dir"))
+			sources := 0
+			for _, set := range []bool{scenarioArg != "", dir != "", c.Bool("all")} {
+				if set {
+					sources++
+				}
+			}
+			if sources == 0 {
+				return usageError(c, "missing required argument: <scenario> (or --dir <path>, or --all)")
+			}
+			if sources > 1 {
+				return usageError(c, "pass one of <scenario>, --dir or --all")
+			}
+			if c.Bool("all") && c.IsSet("revision") {
+				return usageError(c, "--revision can't be combined with --all")
+			}
+			if c.Bool("offline") && (c.IsSet("env") || c.IsSet("db-url")) {
+				return usageError(c, "--offline can't be combined with --env or --db-url")
+			}
+			asJSON, err := jsonRequested(c)
+			if err != nil {
+				return err
+			}
+
+			in := ValidateInput{
+				Scenario: scenarioArg,
+				Revision: c.String("revision"),
+				Dir:      dir,
+				Env:      c.String("env"),
+				DBURL:    c.String("db-url"),
+			}
+			var outs []ValidateOutput
+			if c.Bool("all") {
+				all, err := RunValidateAll(c.Context, in)
+				for _, s := range all.Skipped {
+					ui.Warn("Skipping %s: %s", s.Scenario, s.Error)
+				}
+				if err != nil {
+					return err
+				}
+				outs = all.Results
+			} else {
+				out, err := RunValidate(c.Context, in)
+				if err != nil {
+					return err
+				}
+				outs = []ValidateOutput{out}
+			}
+
+			failed := 0
+			for i, out := range outs {
+				if !out.Valid {
+					failed++
+				}
+				if asJSON {
+					continue
+				}
+				if i > 0 {
+					fmt.Println()
+				}
+				renderValidateOutput(out)
+			}
+			if asJSON {
+				var v interface{} = outs
+				if !c.Bool("all") {
+					v = outs[0]
+				}
+				if err := outputJSON(v); err != nil {
+					return err
+				}
+			}
+			switch {
+			case failed == 0:
+				return nil
+			case len(outs) == 1:
+				return fmt.Errorf("%s: %d problem(s) found", outs[0].Target, len(outs[0].Problems))
+			default:
+				return fmt.Errorf("%d of %d scenario(s) have problems", failed, len(outs))
+			}
+		},
+	}
+}
+
+// ValidateInput is the structured input for RunValidate. Dir, when set,
+// replaces Scenario/Revision.
+type ValidateInput struct {
+	Scenario string `json:"scenario,omitempty" jsonschema:"Scenario path"`
+	Revision string `json:"revision,omitempty" jsonschema:"Specific revision id (defaults to latest)"`
+	Dir      string `json:"dir,omitempty" jsonschema:"Flat directory holding schema.json and one CSV per table"`
+	// Env / DBURL name a live database whose schema fingerprint is
+	// compared as well. Both empty keeps validation offline.
+	Env   string `json:"env,omitempty" jsonschema:"Named environment whose schema is compared too (omit to stay offline)"`
+	DBURL string `json:"dbUrl,omitempty" jsonschema:"Database URL whose schema is compared too (omit to stay offline)"`
+}
+
+// ValidateOutput is the structured response for RunValidate.
+type ValidateOutput struct {
+	// Target is "<scenario> @ <revision>" or the validated directory.
+	Target   string `json:"target"`
+	Scenario string `json:"scenario,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	Valid    bool   `json:"valid"`
+	db.ValidationReport
+}
+
+// RunValidate does the heavy lifting for the `validate` command.
+func RunValidate(_ context.Context, in ValidateInput) (ValidateOutput, error) {
+	if dir := strings.TrimSpace(in.Dir); dir != "" {
+		if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+			return ValidateOutput{}, fmt.Errorf("%s is not a directory", dir)
+		}
+		schemaPath := filepath.Join(dir, "schema.json")
+		report, err := db.ValidateFixtureDir(schemaPath, dir)
+		if err != nil {
+			return ValidateOutput{}, err
+		}
+		if (in.Env != "" || in.DBURL != "") && !hasSchemaParseProblem(report) {
+			fp, err := utils.FingerprintSchemaFile(schemaPath)
+			if err != nil {
+				return ValidateOutput{}, err
+			}
+			if err := compareLiveSchema(&report, fp, in.Env, in.DBURL); err != nil {
+				return ValidateOutput{}, err
+			}
+		}
+		return ValidateOutput{Target: dir, Valid: len(report.Problems) == 0, ValidationReport: report}, nil
+	}
+
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return ValidateOutput{}, err
+	}
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return ValidateOutput{}, err
+	}
+	projectRoot := filepath.Dir(configPath)
+	scenarioPath, err := scenario.Normalize(in.Scenario)
+	if err != nil {
+		return ValidateOutput{}, err
+	}
+	rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, scenarioPath, in.Revision)
+	if err != nil {
+		return ValidateOutput{}, err
+	}
+
+	schemaPath := scenario.SchemaJSONPath(projectRoot, cfg.StoragePath, utils.FingerprintShort(rev.Manifest.SchemaFingerprint))
+	if _, err := os.Stat(schemaPath); err != nil {
+		return ValidateOutput{}, fmt.Errorf("%s @ %s: schema.json for fingerprint %s not found at %s",
+			rev.Scenario, rev.RevID, utils.FingerprintShort(rev.Manifest.SchemaFingerprint), schemaPath)
+	}
+	report, err := db.ValidateFixtureDir(schemaPath, rev.DataDir)
+	if err != nil {
+		return ValidateOutput{}, err
+	}
+	if err := verifyRevisionChecksum(rev); err != nil {
+		report.Problems = append(report.Problems, db.Problem{File: "manifest.json", Message: err.Error()})
+	}
+	report.Problems = withoutSkippedTables(report.Problems, rev.Manifest.SkippedTables)
+	if in.Env != "" || in.DBURL != "" {
+		if err := compareLiveSchema(&report, rev.Manifest.SchemaFingerprint, in.Env, in.DBURL); err != nil {
+			return ValidateOutput{}, err
+		}
+	}
+	return ValidateOutput{
+		Target:           fmt.Sprintf("%s @ %s", rev.Scenario, rev.RevID),
+		Scenario:         rev.Scenario,
+		Revision:         rev.RevID,
+		Valid:            len(report.Problems) == 0,
+		ValidationReport: report,
+	}, nil
+}
+
+// ValidateAllOutput is the result of RunValidateAll.
+type ValidateAllOutput struct {
+	Results []ValidateOutput `json:"results"`
+	// Skipped lists the scenarios whose manifest couldn't be read.
+	Skipped []SkippedScenario `json:"skipped,omitempty"`
+}
+
+// SkippedScenario is a scenario RunValidateAll couldn't validate, and why.
+type SkippedScenario struct {
+	Scenario string `json:"scenario"`
+	Error    string `json:"error"`
+}
+
+// RunValidateAll validates the latest revision of every scenario in the
+// project, in path order. Scenarios without revisions are skipped, and
+// those with an unreadable manifest are reported in Skipped.
+func RunValidateAll(ctx context.Context, in ValidateInput) (ValidateAllOutput, error) {
+	configPath, err := utils.FindConfigFile()
+	if err != nil {
+		return ValidateAllOutput{}, err
+	}
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return ValidateAllOutput{}, err
+	}
+	paths, bad, err := scenario.WalkScenarios(filepath.Dir(configPath), cfg.StoragePath)
+	if err != nil {
+		return ValidateAllOutput{}, err
+	}
+	var all ValidateAllOutput
+	for path, err := range bad {
+		all.Skipped = append(all.Skipped, SkippedScenario{Scenario: path, Error: err.Error()})
+	}
+	sort.Slice(all.Skipped, func(i, j int) bool { return all.Skipped[i].Scenario < all.Skipped[j].Scenario })
+	if len(paths) == 0 {
+		return all, fmt.Errorf("no scenarios to validate — run `seedmancer export <scenario>` first")
+	}
+	all.Results = make([]ValidateOutput, 0, len(paths))
+	for _, path := range paths {
+		manifest, err := scenario.ReadManifest(scenario.ScenarioDir(filepath.Dir(configPath), cfg.StoragePath, path))
+		if err != nil || manifest.Latest == "" {
+			continue
+		}
+		one := in
+		one.Scenario, one.Revision, one.Dir = path, "", ""
+		out, err := RunValidate(ctx, one)
+		if err != nil {
+			return all, err
+		}
+		all.Results = append(all.Results, out)
+	}
+	return all, nil
+}
+
+// withoutSkippedTables drops the missing-CSV problems of tables a
+// partial export skipped: the manifest already says they have no data.
+func withoutSkippedTables(problems []db.Problem, skipped []string) []db.Problem {
+	if len(skipped) == 0 {
+		return problems
+	}
+	skip := map[string]bool{}
+	for _, t := range skipped {
+		skip[t+".csv"] = true
+	}
+	kept := problems[:0]
+	for _, p := range problems {
+		if !(skip[p.File] && p.Row == 0 && strings.HasPrefix(p.Message, "missing:")) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// hasSchemaParseProblem reports whether report says schema.json didn't
+// parse, in which case it has no fingerprint to compare.
+func hasSchemaParseProblem(report db.ValidationReport) bool {
+	return report.Tables == 0 && len(report.Problems) == 1 && report.Problems[0].File == "schema.json"
+}
+
+// compareLiveSchema adds a problem to report when the target database's
+// schema fingerprint differs from fingerprint, the check seed's guard
+// makes before loading anything.
+func compareLiveSchema(report *db.ValidationReport, fingerprint, envName, dbURL string) error {
+	_, cfg, err := loadConfigOrAdHoc(dbURL)
+	if err != nil {
+		return err
+	}
+	target, err := pickExportTarget(cfg, envName, dbURL)
+	if err != nil {
+		return err
+	}
+	current, _, err := fingerprintCurrentDB(target)
+	if err != nil {
+		return err
+	}
+	if current != fingerprint {
+		report.Problems = append(report.Problems, db.Problem{File: "schema.json", Message: fmt.Sprintf(
+			"%s has schema %s, not %s — seed would refuse it without --force",
+			targetDisplay(target), utils.FingerprintShort(current), utils.FingerprintShort(fingerprint))})
+	}
+	return nil
+}
+
+func renderValidateOutput(out ValidateOutput) {
+	ui.Title(out.Target)
+	ui.KeyValue("Tables: ", fmt.Sprintf("%d", out.Tables))
+	ui.KeyValue("Rows: ", fmt.Sprintf("%d", out.Rows))
+	if out.Valid {
+		fmt.Println()
+		ui.Success("No problems found — safe to seed.")
+		return
+	}
+	fmt.Println()
+	ui.Error("%d problem(s):", len(out.Problems))
+	for i, p := range out.Problems {
+		if i == validateShown {
+			ui.Info("  … and %d more (use --output json for the full list)", len(out.Problems)-validateShown)
+			break
+		}
+		ui.KeyValue("  ", p.String())
+	}
+}
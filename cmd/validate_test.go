@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+)
+
+const validateSchema = `{"databaseType":"postgres","enums":[],"tables":[
+  {"name":"users","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"email","type":"text"}]}
+]}`
+
+func TestRunValidate_revision(t *testing.T) {
+	dir := stageRevision(t, "billing/pro", validateSchema, map[string]string{
+		"users": "id,email\n1,a@example.com\n",
+	})
+
+	out, err := RunValidate(context.Background(), ValidateInput{Scenario: "billing/pro"})
+	if err != nil {
+		t.Fatalf("RunValidate: %v", err)
+	}
+	if !out.Valid || out.Target != "billing/pro @ r001" || out.Rows != 1 {
+		t.Errorf("out = %+v, want a valid revision with one row", out)
+	}
+
+	revDir := scenario.RevisionDir(dir, ".seedmancer", "billing/pro", "r001")
+	writeFile(t, filepath.Join(revDir, "data", "users.csv"), "id,email\nNULL,b@example.com\n")
+	out, err = RunValidate(context.Background(), ValidateInput{Scenario: "billing/pro"})
+	if err != nil {
+		t.Fatalf("RunValidate: %v", err)
+	}
+	if out.Valid || len(out.Problems) != 1 || !strings.Contains(out.Problems[0].Message, "NOT NULL") {
+		t.Errorf("problems = %+v, want the NULL primary key", out.Problems)
+	}
+}
+
+func TestRunValidate_partialRevision(t *testing.T) {
+	const schema = `{"databaseType":"postgres","enums":[],"tables":[
+	  {"name":"users","columns":[{"name":"id","type":"integer","isPrimary":true}]},
+	  {"name":"payroll","columns":[{"name":"id","type":"integer","isPrimary":true}]}
+	]}`
+	dir := stageRevision(t, "billing/pro", schema, map[string]string{"users": "id\n1\n"})
+	revDir := scenario.RevisionDir(dir, ".seedmancer", "billing/pro", "r001")
+	m, err := scenario.ReadRevisionManifest(revDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := RunValidate(context.Background(), ValidateInput{Scenario: "billing/pro"})
+	if err != nil {
+		t.Fatalf("RunValidate: %v", err)
+	}
+	if out.Valid {
+		t.Fatal("payroll.csv is missing and the revision doesn't say it was skipped")
+	}
+
+	m.SkippedTables = []string{"payroll"}
+	if err := scenario.WriteRevisionManifest(revDir, m); err != nil {
+		t.Fatal(err)
+	}
+	out, err = RunValidate(context.Background(), ValidateInput{Scenario: "billing/pro"})
+	if err != nil {
+		t.Fatalf("RunValidate: %v", err)
+	}
+	if !out.Valid {
+		t.Errorf("problems = %+v, want a skipped table's CSV to be allowed missing", out.Problems)
+	}
+	rev, err := resolveScenarioRevision(dir, ".seedmancer", "billing/pro", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := partialRevisionWarning(rev); !strings.Contains(w, "partial") || !strings.Contains(w, "payroll") {
+		t.Errorf("partialRevisionWarning = %q", w)
+	}
+}
+
+func TestRunValidate_dir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "schema.json"), validateSchema)
+	out, err := RunValidate(context.Background(), ValidateInput{Dir: dir})
+	if err != nil {
+		t.Fatalf("RunValidate: %v", err)
+	}
+	if out.Valid || len(out.Problems) != 1 || out.Problems[0].File != "users.csv" {
+		t.Errorf("problems = %+v, want users.csv missing", out.Problems)
+	}
+}
+
+func TestRunValidateAll(t *testing.T) {
+	dir := stageRevision(t, "billing/pro", validateSchema, map[string]string{
+		"users": "id,email\n1,a@example.com\n",
+	})
+	broken := scenario.RevisionDir(dir, ".seedmancer", "billing/broken", "r001")
+	writeFile(t, filepath.Join(broken, "data", "users.csv"), "id,email\nx,b@example.com\n")
+	copyManifest(t, scenario.RevisionDir(dir, ".seedmancer", "billing/pro", "r001"), broken, "billing/broken")
+
+	writeFile(t, filepath.Join(scenario.ScenarioDir(dir, ".seedmancer", "billing/garbled"), "manifest.json"), "{not json")
+
+	all, err := RunValidateAll(context.Background(), ValidateInput{})
+	if err != nil {
+		t.Fatalf("RunValidateAll: %v", err)
+	}
+	if len(all.Skipped) != 1 || all.Skipped[0].Scenario != "billing/garbled" {
+		t.Errorf("skipped = %+v, want billing/garbled", all.Skipped)
+	}
+	outs := all.Results
+	if len(outs) != 2 {
+		t.Fatalf("outs = %+v, want two scenarios", outs)
+	}
+	if outs[0].Scenario != "billing/broken" || outs[0].Valid || outs[1].Scenario != "billing/pro" || !outs[1].Valid {
+		t.Errorf("outs = %+v, want billing/broken invalid and billing/pro valid", outs)
+	}
+}
+
+// copyManifest gives the revision at dst the manifest of src, renamed to
+// scenarioPath, and makes it its scenario's latest.
+func copyManifest(t *testing.T, src, dst, scenarioPath string) {
+	t.Helper()
+	m, err := scenario.ReadRevisionManifest(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Scenario = scenarioPath
+	if err := scenario.WriteRevisionManifest(dst, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := scenario.WriteManifest(filepath.Dir(filepath.Dir(dst)), scenario.Manifest{Scenario: scenarioPath, Latest: m.Revision}); err != nil {
+		t.Fatal(err)
+	}
+}
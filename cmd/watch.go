@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/ui"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// exportWatch is export --watch: re-export in every --interval until
+// interrupted, keeping a revision only when schema or data changed and,
+// with --push, uploading each kept revision.
+func exportWatch(c *cli.Context, in ExportInput) error {
+	interval := c.Duration("interval")
+	if interval <= 0 {
+		return usageError(c, "--interval must be positive (e.g. 30m, 1h)")
+	}
+	var projectRoot, token, projectSlug string
+	var cfg utils.Config
+	if c.Bool("push") {
+		configPath, err := utils.FindConfigFile()
+		if err != nil {
+			return err
+		}
+		projectRoot = filepath.Dir(configPath)
+		if cfg, err = utils.LoadConfig(configPath); err != nil {
+			return err
+		}
+		// Resolved up front so a missing token fails now, not after the
+		// first export.
+		if token, err = utils.ResolveAPIToken(c.String("token")); err != nil {
+			return err
+		}
+		projectSlug = utils.ResolveProjectSlug(c.String("project"), cfg)
+		utils.SetGlobalProjectSlug(projectSlug)
+	}
+
+	ctx, stop := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	in.SkipUnchanged = true
+	ui.Info("Exporting %s every %s — Ctrl-C to stop", in.Scenario, interval)
+	return watchLoop(ctx, interval, func(ctx context.Context) error {
+		out, err := RunExport(ctx, in)
+		if err != nil {
+			return err
+		}
+		stamp := time.Now().Format("15:04:05")
+		if out.Unchanged {
+			ui.Info("%s  %s unchanged, latest is still %s", stamp, out.Scenario, out.Revision)
+		} else {
+			ui.Success("%s  %s → %s (%d table(s))", stamp, out.Scenario, out.Revision, len(out.Tables))
+			if len(out.DroppedTables) > 0 {
+				ui.Warn("Not carried over from %s (no longer in the database): %s", out.PreviousRevision, strings.Join(out.DroppedTables, ", "))
+			}
+			if len(out.SkippedTables) > 0 {
+				ui.Warn("%s is partial — skipped unreadable table(s): %s", out.Revision, strings.Join(out.SkippedTables, ", "))
+			}
+		}
+		if token == "" {
+			return nil
+		}
+		// An unchanged revision is pushed too when the cloud doesn't have
+		// it yet, so a push that failed last time is retried.
+		rev, err := resolveScenarioRevision(projectRoot, cfg.StoragePath, out.Scenario, "")
+		if err != nil {
+			return err
+		}
+		if out.Unchanged && rev.Manifest.RemoteID != "" {
+			return nil
+		}
+		if err := pushScenario(ctx, projectRoot, cfg, out.Scenario, utils.GetBaseURL(), token, projectSlug, ""); err != nil {
+			return fmt.Errorf("push %s: %w", out.Scenario, err)
+		}
+		return nil
+	})
+}
+
+// watchLoop calls cycle now and then every interval until ctx is done. An
+// error from the first cycle is returned, as it is most likely a setup
+// mistake; later ones are reported and the next cycle tries again.
+func watchLoop(ctx context.Context, interval time.Duration, cycle func(context.Context) error) error {
+	for first := true; ; first = false {
+		if err := cycle(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if first {
+				return err
+			}
+			ui.Error("%v — trying again in %s", err, interval)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/lib/pq"
+)
+
+// additions is the DDL a create-missing-only restore applies: the enums,
+// other user types, sequences and tables the schema declares that the
+// live database lacks, and the columns missing from tables it already
+// has.
+type additions struct {
+	Enums []EnumItem
+	// Types are the CREATE statements of the missing domains and
+	// composite types, in creation order.
+	Types     []string
+	Sequences []Sequence
+	Tables    []Table
+	Columns   []addedColumn
+}
+
+// addedColumn is a column added to an existing table.
+type addedColumn struct {
+	Table  string
+	Column Column
+	// Relaxed is set when the fixture declares the column NOT NULL
+	// without a default: the rows already in the table would have no
+	// value for it, so it is added as nullable instead.
+	Relaxed bool
+}
+
+func (a additions) empty() bool {
+	return len(a.Enums) == 0 && len(a.Types) == 0 && len(a.Sequences) == 0 && len(a.Tables) == 0 && len(a.Columns) == 0
+}
+
+// summary reads like "2 table(s), 1 enum(s), 3 column(s)".
+func (a additions) summary() string {
+	var parts []string
+	for _, p := range []struct {
+		n    int
+		noun string
+	}{{len(a.Tables), "table"}, {len(a.Enums), "enum"}, {len(a.Types), "type"}, {len(a.Sequences), "sequence"}, {len(a.Columns), "column"}} {
+		if p.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s(s)", p.n, p.noun))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// foreignKeyTables lists the tables whose FK constraints are new along
+// with them: created tables in full, existing tables with just their
+// added columns. FKs on existing columns are left as they are.
+func (a additions) foreignKeyTables() []Table {
+	tables := append([]Table(nil), a.Tables...)
+	byTable := map[string]int{}
+	for _, c := range a.Columns {
+		i, ok := byTable[c.Table]
+		if !ok {
+			i = len(tables)
+			byTable[c.Table] = i
+			tables = append(tables, Table{Name: c.Table})
+		}
+		tables[i].Columns = append(tables[i].Columns, c.Column)
+	}
+	return tables
+}
+
+// planAdditions compares schema with the live catalog: existing maps
+// "enum", "type", "sequence" and "table" to the names present, liveColumns maps
+// each existing table to its columns. Nothing present is ever changed.
+func planAdditions(schema *Schema, existing map[string]map[string]bool, liveColumns map[string]map[string]bool) additions {
+	var a additions
+	for _, e := range schema.Enums {
+		if !existing["enum"][e.Name] {
+			a.Enums = append(a.Enums, e)
+		}
+	}
+	a.Types = userTypeStatements(schema, func(name string) bool { return existing["type"][name] })
+	for _, s := range schema.Sequences {
+		if !existing["sequence"][s.Name] {
+			a.Sequences = append(a.Sequences, s)
+		}
+	}
+	for _, t := range schema.Tables {
+		if !existing["table"][t.Name] {
+			a.Tables = append(a.Tables, t)
+			continue
+		}
+		for _, col := range t.Columns {
+			if liveColumns[t.Name][col.Name] {
+				continue
+			}
+			// Keys can't be added to rows that are already there.
+			col.IsPrimary, col.IsUnique = false, false
+			added := addedColumn{Table: t.Name, Column: col}
+			if !col.Nullable && col.Default == nil && col.Identity == "" && !isAutoIncrement(col) {
+				added.Column.Nullable = true
+				added.Relaxed = true
+			}
+			a.Columns = append(a.Columns, added)
+		}
+	}
+	return a
+}
+
+// queryColumns reads (table, column) pairs from q into a set per table.
+func queryColumns(ctx context.Context, q rowQueryer, query string, args ...interface{}) (map[string]map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying existing columns: %v", err)
+	}
+	defer rows.Close()
+	cols := map[string]map[string]bool{}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("scanning existing columns: %v", err)
+		}
+		if cols[table] == nil {
+			cols[table] = map[string]bool{}
+		}
+		cols[table][column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading existing columns: %v", err)
+	}
+	return cols, nil
+}
+
+// reportAdditions prints what a create-missing-only restore did.
+func reportAdditions(a additions) {
+	if a.empty() {
+		ui.Step("Nothing to create: every table, enum and column already exists")
+		return
+	}
+	ui.Step("Created %s", a.summary())
+	for _, c := range a.Columns {
+		if c.Relaxed {
+			ui.Warn("%s.%s was added as nullable: it is NOT NULL without a default in the fixture, and existing rows have no value for it", c.Table, c.Column.Name)
+		}
+	}
+}
+
+// createMissingOnly applies the additions schema needs on conn. PostgreSQL
+// runs DDL transactionally, so a failure leaves the database as it was.
+func (p *PostgresManager) createMissingOnly(ctx context.Context, conn *sql.Conn, schema *Schema, schemaName string, existing map[string]map[string]bool) error {
+	live, err := queryColumns(ctx, conn,
+		`SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = $1::text`, schemaName)
+	if err != nil {
+		return err
+	}
+	a := planAdditions(schema, existing, live)
+	if a.empty() && existing["schema"][schemaName] {
+		reportAdditions(a)
+		return nil
+	}
+
+	standalone := map[string]bool{}
+	for _, seq := range schema.Sequences {
+		standalone[seq.Name] = true
+	}
+	var stmts []string
+	if !existing["schema"][schemaName] {
+		stmts = append(stmts, "CREATE SCHEMA IF NOT EXISTS "+pq.QuoteIdentifier(schemaName)+";")
+	}
+	for _, enum := range a.Enums {
+		stmts = append(stmts, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s);",
+			pq.QuoteIdentifier(enum.Name), joinQuotedStrings(enum.Values)))
+	}
+	for _, stmt := range a.Types {
+		stmts = append(stmts, stmt+";")
+	}
+	for _, seq := range a.Sequences {
+		stmts = append(stmts, createSequenceSQL(seq)+";")
+	}
+	for _, table := range a.Tables {
+		stmts = append(stmts, p.buildCreateTableSQL(table, standalone)+";")
+	}
+	for _, c := range a.Columns {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;",
+			pq.QuoteIdentifier(c.Table), p.columnDefSQL(c.Column, standalone)))
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+	batch := strings.Join(stmts, "\n")
+	p.logSQL("Create Missing Objects", batch)
+	if _, err := tx.ExecContext(ctx, batch); err != nil {
+		return fmt.Errorf("creating missing objects: %v", err)
+	}
+	if err := p.addMissingForeignKeys(ctx, tx, &Schema{Tables: a.foreignKeyTables()}, existing["table"], existing["fk"]); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing: %v", err)
+	}
+	reportAdditions(a)
+	return nil
+}
+
+// createMissingOnly applies the additions schema needs. MySQL commits
+// each DDL statement on its own, so a failure part way keeps what was
+// created before it; running again picks up the rest.
+func (m *MySQLManager) createMissingOnly(schema *Schema, existingTables map[string]bool) error {
+	live, err := queryColumns(context.Background(), m.DB,
+		`SELECT TABLE_NAME, COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE()`)
+	if err != nil {
+		return err
+	}
+	a := planAdditions(schema, map[string]map[string]bool{"table": existingTables}, live)
+	// MySQL enums are inline column types, not objects of their own,
+	// and it has no domains or composite types.
+	a.Enums, a.Types = nil, nil
+
+	for _, table := range a.Tables {
+		if err := m.createTable(table); err != nil {
+			return fmt.Errorf("creating table %s: %v", table.Name, err)
+		}
+	}
+	for _, c := range a.Columns {
+		alterSQL := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(c.Table), m.columnDefSQL(c.Column))
+		m.logSQL("Add Column "+c.Table+"."+c.Column.Name, alterSQL)
+		if _, err := m.DB.Exec(alterSQL); err != nil {
+			return fmt.Errorf("adding column %s.%s: %v", c.Table, c.Column.Name, err)
+		}
+	}
+	for _, table := range a.foreignKeyTables() {
+		if err := m.addForeignKeys(table); err != nil {
+			return fmt.Errorf("adding FKs for %s: %v", table.Name, err)
+		}
+	}
+	reportAdditions(a)
+	return nil
+}
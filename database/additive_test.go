@@ -0,0 +1,80 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanAdditions(t *testing.T) {
+	schema := &Schema{
+		Enums: []EnumItem{{Name: "plan_t", Values: []string{"free", "pro"}}, {Name: "role_t", Values: []string{"admin"}}},
+		Tables: []Table{
+			{Name: "orgs", Columns: []Column{
+				{Name: "id", Type: "integer", IsPrimary: true},
+				{Name: "plan", Type: "enum", Enum: "plan_t", Default: "'free'::plan_t"},
+				{Name: "slug", Type: "text", IsUnique: true},
+				{Name: "owner", Type: "text", Nullable: false},
+				{Name: "note", Type: "text", Nullable: true},
+			}},
+			{Name: "users", Columns: []Column{
+				{Name: "id", Type: "integer", IsPrimary: true},
+				{Name: "org_id", Type: "integer", ForeignKey: &ForeignKey{Table: "orgs", Column: "id"}},
+			}},
+		},
+		Sequences: []Sequence{{Name: "invoice_no"}},
+		Domains:   []Domain{{Name: "email_t", BaseType: "text"}},
+	}
+	existing := map[string]map[string]bool{
+		"enum":     {"plan_t": true},
+		"sequence": {"invoice_no": true},
+		"table":    {"orgs": true},
+	}
+	live := map[string]map[string]bool{"orgs": {"id": true, "note": true}}
+
+	a := planAdditions(schema, existing, live)
+	if len(a.Enums) != 1 || a.Enums[0].Name != "role_t" {
+		t.Errorf("enums = %+v", a.Enums)
+	}
+	if len(a.Types) != 1 || a.Types[0] != `CREATE DOMAIN "email_t" AS text` {
+		t.Errorf("types = %q", a.Types)
+	}
+	if len(a.Sequences) != 0 {
+		t.Errorf("sequences = %+v", a.Sequences)
+	}
+	if len(a.Tables) != 1 || a.Tables[0].Name != "users" {
+		t.Errorf("tables = %+v", a.Tables)
+	}
+	var names []string
+	for _, c := range a.Columns {
+		names = append(names, c.Column.Name)
+		if c.Column.IsPrimary || c.Column.IsUnique {
+			t.Errorf("%s keeps a key constraint", c.Column.Name)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"plan", "slug", "owner"}) {
+		t.Errorf("columns = %v", names)
+	}
+	// NOT NULL without a default can't be added to existing rows.
+	if !a.Columns[2].Relaxed || !a.Columns[2].Column.Nullable {
+		t.Errorf("owner = %+v, want relaxed to nullable", a.Columns[2])
+	}
+	if a.Columns[0].Relaxed {
+		t.Error("plan has a default and should stay NOT NULL")
+	}
+	if got := a.summary(); got != "1 table(s), 1 enum(s), 1 type(s), 3 column(s)" {
+		t.Errorf("summary = %q", got)
+	}
+
+	fk := a.foreignKeyTables()
+	if len(fk) != 2 || fk[0].Name != "users" || fk[1].Name != "orgs" || len(fk[1].Columns) != 3 {
+		t.Errorf("foreignKeyTables = %+v", fk)
+	}
+}
+
+func TestPlanAdditions_nothingMissing(t *testing.T) {
+	schema := &Schema{Tables: []Table{{Name: "orgs", Columns: []Column{{Name: "id", Type: "integer"}}}}}
+	a := planAdditions(schema, map[string]map[string]bool{"table": {"orgs": true}}, map[string]map[string]bool{"orgs": {"id": true}})
+	if !a.empty() {
+		t.Errorf("additions = %+v, want none", a)
+	}
+}
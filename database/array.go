@@ -0,0 +1,80 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// pgElementTypes maps PostgreSQL's internal names of common array
+// element types, as udt_name reports them without the leading
+// underscore, to the names information_schema uses for plain columns.
+var pgElementTypes = map[string]string{
+	"int2":        "smallint",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"bool":        "boolean",
+	"bpchar":      "character",
+	"varchar":     "character varying",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+}
+
+// arrayElementType is the element type of an array column whose udt_name
+// is udtName (_int4, _uuid, _mood …).
+func arrayElementType(udtName string) string {
+	name := strings.TrimPrefix(udtName, "_")
+	if std, ok := pgElementTypes[name]; ok {
+		return std
+	}
+	return name
+}
+
+// plainTypeName matches type names that need no quoting: built-in ones,
+// some spelled with spaces, and lower-case user types.
+var plainTypeName = regexp.MustCompile(`^[a-z_][a-z0-9_ ]*$`)
+
+// arrayTypeSQL is an array column's type as CREATE TABLE takes it. Arrays
+// from schemas exported before the element type was recorded stay text[].
+func arrayTypeSQL(col Column) string {
+	elem := col.ElementType
+	if elem == "" {
+		return "text[]"
+	}
+	if !plainTypeName.MatchString(elem) {
+		elem = pq.QuoteIdentifier(elem)
+	}
+	return elem + "[]"
+}
+
+// arrayElementOf is the element type of col, an array column: its
+// ElementType, or what precedes [] in a hand-written type like integer[],
+// or "" when neither says.
+func arrayElementOf(col Column) string {
+	if col.ElementType != "" {
+		return col.ElementType
+	}
+	elem, ok := strings.CutSuffix(strings.TrimSpace(col.Type), "[]")
+	if !ok {
+		return ""
+	}
+	return elem
+}
+
+// pgArrayLiteral renders elems as a PostgreSQL array literal, quoting the
+// elements that would otherwise be misread.
+func pgArrayLiteral(elems []string) string {
+	quoted := make([]string, len(elems))
+	for i, e := range elems {
+		if e == "" || strings.EqualFold(e, "null") || strings.ContainsAny(e, `{},"\ `) {
+			e = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(e) + `"`
+		}
+		quoted[i] = e
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
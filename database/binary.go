@@ -0,0 +1,56 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// binaryTypes are the column types holding raw bytes. Their cells are
+// written to CSV as \x followed by hex digits — PostgreSQL's own bytea
+// output — so binary data survives the round trip on either engine.
+var binaryTypes = map[string]bool{
+	"bytea":      true,
+	"blob":       true,
+	"tinyblob":   true,
+	"mediumblob": true,
+	"longblob":   true,
+	"binary":     true,
+	"varbinary":  true,
+}
+
+func isBinaryType(t string) bool {
+	return binaryTypes[baseType(t)]
+}
+
+// encodeBinaryCell renders b the way export writes a binary cell.
+func encodeBinaryCell(b []byte) string {
+	return `\x` + hex.EncodeToString(b)
+}
+
+// decodeBinaryCell reverses encodeBinaryCell. ok is false when cell
+// isn't \x-prefixed hex, as in CSVs exported before binary columns were
+// encoded.
+func decodeBinaryCell(cell string) (b []byte, ok bool) {
+	digits, found := strings.CutPrefix(cell, `\x`)
+	if !found {
+		return nil, false
+	}
+	b, err := hex.DecodeString(digits)
+	return b, err == nil
+}
+
+// binaryColumns reports which of rows' columns are binary, for
+// formatCSVCell.
+func binaryColumns(rows *sql.Rows) ([]bool, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("reading column types: %v", err)
+	}
+	binary := make([]bool, len(types))
+	for i, t := range types {
+		binary[i] = isBinaryType(t.DatabaseTypeName())
+	}
+	return binary, nil
+}
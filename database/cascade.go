@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// CascadeError is returned by a PostgreSQL restore whose TRUNCATE …
+// CASCADE would also empty tables the restore doesn't reload: tables
+// outside the revision that reference, directly or through others, a
+// table it truncates. It is returned before anything has changed.
+type CascadeError struct {
+	// Tables lists them, schema-qualified when outside the target schema.
+	Tables []string
+}
+
+func (e *CascadeError) Error() string {
+	return fmt.Sprintf("restoring would empty %s through TRUNCATE … CASCADE, which the revision doesn't reload (nothing was changed); "+
+		"pass --allow-cascade to empty them anyway, or seed with --tables to DELETE only the revision's rows",
+		strings.Join(e.Tables, ", "))
+}
+
+// PreviewCascade reports, without changing anything, the tables a
+// restore of directory with opts would empty through TRUNCATE … CASCADE
+// without reloading them: what its CascadeError would name. Only
+// PostgreSQL truncates with CASCADE, so other engines, CockroachDB and
+// restores that truncate nothing return none.
+func PreviewCascade(dm DatabaseManager, directory string, opts RestoreOptions) ([]string, error) {
+	p, ok := dm.(*PostgresManager)
+	if !ok || p.isCockroach() || opts.CreateMissingOnly {
+		return nil, nil
+	}
+	return p.previewCascade(directory, opts)
+}
+
+// previewCascade works out the restore's TRUNCATE list the way
+// RestoreFromCSVWithOptions does, on a session with the same role and
+// search_path, and asks cascadeTargets where it reaches.
+func (p *PostgresManager) previewCascade(directory string, opts RestoreOptions) ([]string, error) {
+	if p.DB == nil {
+		return nil, errors.New("no database connection")
+	}
+	ctx := context.Background()
+	schema, err := p.ReadSchemaFromFile(filepath.Join(directory, "schema.json"))
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %v", err)
+	}
+	conn, err := p.DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring connection: %v", err)
+	}
+	defer conn.Close()
+	if opts.Role != "" {
+		if _, err := conn.ExecContext(ctx, "SET ROLE "+pq.QuoteIdentifier(opts.Role)); err != nil {
+			return nil, fmt.Errorf("setting role %s: %v", opts.Role, err)
+		}
+		defer conn.ExecContext(context.Background(), "RESET ROLE")
+	}
+	schemaName := opts.pgSchema()
+	if opts.TargetSchema != "" {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET search_path TO %s, public", pq.QuoteIdentifier(schemaName))); err != nil {
+			return nil, fmt.Errorf("setting search_path: %v", err)
+		}
+		defer conn.ExecContext(context.Background(), "RESET search_path")
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = $1::text AND table_type = 'BASE TABLE'
+	`, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("querying existing tables: %v", err)
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning existing tables: %v", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading existing tables: %v", err)
+	}
+
+	unchanged := unchangedStaticTables(ctx, conn, pq.QuoteIdentifier, directory, opts.StaticTables, existing, p.log)
+	reloadCascadedStatic(schema, planRestore(schema, directory, existing, nil, nil, unchanged, opts).TruncateTables, unchanged)
+	truncate := planRestore(schema, directory, existing, nil, nil, unchanged, opts).TruncateTables
+	if len(truncate) == 0 {
+		return nil, nil
+	}
+	return cascadeTargets(ctx, conn, schemaName, truncate)
+}
+
+// cascadeTargets lists the tables TRUNCATE … CASCADE of truncate, in
+// schema, would empty besides truncate itself, skipping those already
+// empty: emptying them again loses nothing.
+func cascadeTargets(ctx context.Context, conn *sql.Conn, schema string, truncate []string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `
+		WITH RECURSIVE doomed(oid) AS (
+			SELECT c.oid FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1::text AND c.relname = ANY($2::text[])
+			UNION
+			SELECT con.conrelid FROM pg_constraint con
+			JOIN doomed d ON con.confrelid = d.oid
+			WHERE con.contype = 'f'
+		)
+		SELECT n.nspname, c.relname FROM doomed d
+		JOIN pg_class c ON c.oid = d.oid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE NOT (n.nspname = $1::text AND c.relname = ANY($2::text[]))
+		ORDER BY n.nspname, c.relname
+	`, schema, pq.Array(truncate))
+	if err != nil {
+		return nil, fmt.Errorf("finding tables TRUNCATE … CASCADE reaches: %v", err)
+	}
+	type table struct{ schema, name string }
+	var reached []table
+	for rows.Next() {
+		var t table
+		if err := rows.Scan(&t.schema, &t.name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("finding tables TRUNCATE … CASCADE reaches: %v", err)
+		}
+		reached = append(reached, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("finding tables TRUNCATE … CASCADE reaches: %v", err)
+	}
+
+	var targets []string
+	for _, t := range reached {
+		var hasRows bool
+		q := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s.%s)", pq.QuoteIdentifier(t.schema), pq.QuoteIdentifier(t.name))
+		if err := conn.QueryRowContext(ctx, q).Scan(&hasRows); err != nil {
+			return nil, fmt.Errorf("checking %s for rows: %v", t.name, err)
+		}
+		if !hasRows {
+			continue
+		}
+		if t.schema == schema {
+			targets = append(targets, t.name)
+		} else {
+			targets = append(targets, t.schema+"."+t.name)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"strings"
+)
+
+// CockroachDB speaks the PostgreSQL wire protocol, so PostgresManager
+// drives it as well. The differences that matter to seedmancer are
+// handled where they arise, behind isCockroach:
+//
+//   - every table has a hidden rowid column in information_schema when
+//     it was created without a primary key; it is left out of the schema.
+//   - pg_sequences doesn't exist; standalone sequences come from
+//     information_schema.sequences instead.
+//   - session_replication_role can't switch off foreign key checks, so
+//     rows are cleared child-first and loaded parent-first.
+//   - TRUNCATE is a schema change, which a transaction can't follow with
+//     writes to the same table; tables are emptied with DELETE instead.
+//
+// Rows still load through COPY: IMPORT INTO would need the CSVs on
+// storage the cluster itself can read.
+
+// isCockroach reports whether p is connected to CockroachDB. It is true
+// up front for cockroach:// URLs; for postgres:// URLs the server is
+// asked once, on first use.
+func (p *PostgresManager) isCockroach() bool {
+	if p.Cockroach || p.probedCockroach || p.DB == nil {
+		return p.Cockroach
+	}
+	p.probedCockroach = true
+	var version string
+	if err := p.DB.QueryRow("SELECT version()").Scan(&version); err == nil {
+		p.Cockroach = isCockroachVersion(version)
+	}
+	if p.Cockroach {
+		p.log("Detected CockroachDB: %s", version)
+	}
+	return p.Cockroach
+}
+
+// isCockroachVersion reports whether a version() string comes from
+// CockroachDB, e.g. "CockroachDB CCL v23.2.4 (x86_64-pc-linux-gnu, …)".
+func isCockroachVersion(version string) bool {
+	return strings.HasPrefix(strings.TrimSpace(version), "CockroachDB")
+}
+
+// tablesInInsertOrder returns schema's tables parents first, along with
+// any caught in a foreign key cycle (see Schema.InsertOrder).
+func tablesInInsertOrder(schema *Schema) ([]Table, []string) {
+	order, cyclic := schema.InsertOrder()
+	tables := make([]Table, 0, len(order))
+	for _, name := range order {
+		tables = append(tables, *schema.TableByName(name))
+	}
+	return tables, cyclic
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsCockroachVersion(t *testing.T) {
+	cases := map[string]bool{
+		"CockroachDB CCL v23.2.4 (x86_64-pc-linux-gnu, built 2024/04/15 20:16:51, go1.21.9 X:nocoverageredesign)": true,
+		"PostgreSQL 16.2 on x86_64-pc-linux-gnu, compiled by gcc (Debian 12.2.0-14) 12.2.0, 64-bit":               false,
+		"": false,
+	}
+	for version, want := range cases {
+		if got := isCockroachVersion(version); got != want {
+			t.Errorf("isCockroachVersion(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestTablesInInsertOrder(t *testing.T) {
+	schema := &Schema{Tables: []Table{
+		{Name: "orders", Columns: []Column{{Name: "user_id", ForeignKey: &ForeignKey{Table: "users", Column: "id"}}}},
+		{Name: "users", Columns: []Column{{Name: "id"}}},
+	}}
+	tables, cyclic := tablesInInsertOrder(schema)
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"users", "orders"}) || len(cyclic) != 0 {
+		t.Fatalf("order = %v, cyclic = %v", names, cyclic)
+	}
+}
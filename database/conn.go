@@ -0,0 +1,150 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ConnOptions tunes the connection pool every manager opens, and how long
+// it waits on the server: --max-open-conns, --max-idle-conns,
+// --conn-timeout and --statement-timeout.
+type ConnOptions struct {
+	// MaxOpenConns caps the connections held at once; 0 means no cap.
+	MaxOpenConns int
+	// MaxIdleConns is how many of them are kept open between statements.
+	MaxIdleConns int
+	// ConnTimeout bounds establishing a connection; 0 waits as long as
+	// the driver does, which for lib/pq is forever.
+	ConnTimeout time.Duration
+	// StatementTimeout aborts a statement running longer; 0 means no
+	// limit. MySQL only bounds SELECTs (max_execution_time), MariaDB every
+	// statement (max_statement_time).
+	StatementTimeout time.Duration
+}
+
+// DefaultConnOptions keeps a run to a handful of connections and gives up
+// on a server that doesn't answer. Statements are left unbounded: the
+// export of a large table is one long SELECT.
+var DefaultConnOptions = ConnOptions{
+	MaxOpenConns: 10,
+	MaxIdleConns: 2,
+	ConnTimeout:  10 * time.Second,
+}
+
+var connOptions = DefaultConnOptions
+
+// SetConnOptions sets the options the managers connect with from then on.
+func SetConnOptions(o ConnOptions) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	connOptions = o
+	return nil
+}
+
+func (o ConnOptions) validate() error {
+	switch {
+	case o.MaxOpenConns < 0:
+		return fmt.Errorf("--max-open-conns must not be negative (got %d)", o.MaxOpenConns)
+	case o.MaxIdleConns < 0:
+		return fmt.Errorf("--max-idle-conns must not be negative (got %d)", o.MaxIdleConns)
+	case o.ConnTimeout < 0:
+		return fmt.Errorf("--conn-timeout must not be negative (got %s)", o.ConnTimeout)
+	case o.StatementTimeout < 0:
+		return fmt.Errorf("--statement-timeout must not be negative (got %s)", o.StatementTimeout)
+	}
+	return nil
+}
+
+// applyPool sizes db's pool.
+func (o ConnOptions) applyPool(db *sql.DB) {
+	db.SetMaxOpenConns(o.MaxOpenConns)
+	db.SetMaxIdleConns(o.MaxIdleConns)
+}
+
+// postgresDSN adds o's timeouts to a lib/pq DSN, URL or key=value form.
+// Settings the DSN already makes are left alone. The statement timeout
+// goes in as a startup option, which CockroachDB honours too.
+func (o ConnOptions) postgresDSN(dsn string) (string, error) {
+	if o.ConnTimeout > 0 {
+		// Whole seconds; lib/pq reads anything under one as no timeout.
+		connect := strconv.Itoa(int((o.ConnTimeout + time.Second - 1) / time.Second))
+		if !strings.Contains(dsn, "://") {
+			if !strings.Contains(dsn, "connect_timeout=") {
+				dsn += " connect_timeout=" + connect
+			}
+		} else {
+			u, err := url.Parse(dsn)
+			if err != nil {
+				return "", fmt.Errorf("parsing DSN: %v", err)
+			}
+			if q := u.Query(); q.Get("connect_timeout") == "" {
+				q.Set("connect_timeout", connect)
+				u.RawQuery = q.Encode()
+			}
+			dsn = u.String()
+		}
+	}
+	if o.StatementTimeout > 0 {
+		return postgresOption(dsn, "statement_timeout", strconv.FormatInt(o.StatementTimeout.Milliseconds(), 10))
+	}
+	return dsn, nil
+}
+
+// postgresOption sets the run-time parameter name to value for every
+// session opened with dsn, through the options startup parameter, unless
+// the DSN sets it already.
+func postgresOption(dsn, name, value string) (string, error) {
+	setting := "-c " + name + "=" + value
+	if !strings.Contains(dsn, "://") {
+		if strings.Contains(dsn, name) {
+			return dsn, nil
+		}
+		if strings.Contains(dsn, "options=") {
+			return "", fmt.Errorf("can't set %s alongside options= in a key=value DSN; add -c %s=%s to it instead", name, name, value)
+		}
+		return dsn + " options='" + setting + "'", nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("parsing DSN: %v", err)
+	}
+	q := u.Query()
+	if opts := q.Get("options"); !strings.Contains(opts, name) {
+		q.Set("options", strings.TrimSpace(opts+" "+setting))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// mysqlDSN adds o's timeouts to a go-sql-driver DSN. Settings the DSN
+// already makes are left alone. The statement timeout is a session
+// variable whose name depends on the engine, hence mariaDB.
+func (o ConnOptions) mysqlDSN(dsn string, mariaDB bool) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("parsing DSN: %v", err)
+	}
+	if o.ConnTimeout > 0 && cfg.Timeout == 0 {
+		cfg.Timeout = o.ConnTimeout
+	}
+	if o.StatementTimeout > 0 {
+		name, value := "max_execution_time", strconv.FormatInt(o.StatementTimeout.Milliseconds(), 10)
+		if mariaDB {
+			name, value = "max_statement_time", strconv.FormatFloat(o.StatementTimeout.Seconds(), 'f', -1, 64)
+		}
+		if _, set := cfg.Params[name]; !set {
+			if cfg.Params == nil {
+				cfg.Params = map[string]string{}
+			}
+			cfg.Params[name] = value
+		}
+	}
+	return cfg.FormatDSN(), nil
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnOptions_postgresDSN(t *testing.T) {
+	o := ConnOptions{ConnTimeout: 1500 * time.Millisecond, StatementTimeout: 30 * time.Second}
+	cases := []struct {
+		name            string
+		in              string
+		wantContains    []string
+		wantNotContains []string
+	}{
+		{
+			name:         "timeouts added to a URL",
+			in:           "postgres://u:p@h:5432/db?sslmode=disable",
+			wantContains: []string{"connect_timeout=2", "options=-c+statement_timeout%3D30000", "sslmode=disable"},
+		},
+		{
+			name:            "settings in the DSN win",
+			in:              "postgres://u:p@h/db?connect_timeout=60&options=-c%20statement_timeout%3D5s",
+			wantContains:    []string{"connect_timeout=60", "statement_timeout%3D5s"},
+			wantNotContains: []string{"connect_timeout=2", "30000"},
+		},
+		{
+			name:         "other startup options kept",
+			in:           "postgres://u:p@h/db?options=-c%20search_path%3Dapp",
+			wantContains: []string{"search_path%3Dapp+-c+statement_timeout%3D30000"},
+		},
+		{
+			name:         "key=value form",
+			in:           "host=h dbname=db",
+			wantContains: []string{"host=h dbname=db connect_timeout=2 options='-c statement_timeout=30000'"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := o.postgresDSN(tc.in)
+			if err != nil {
+				t.Fatalf("postgresDSN: %v", err)
+			}
+			for _, s := range tc.wantContains {
+				if !strings.Contains(got, s) {
+					t.Errorf("%q does not contain %q", got, s)
+				}
+			}
+			for _, s := range tc.wantNotContains {
+				if strings.Contains(got, s) {
+					t.Errorf("%q contains %q", got, s)
+				}
+			}
+		})
+	}
+
+	if got, _ := (ConnOptions{}).postgresDSN("postgres://h/db"); got != "postgres://h/db" {
+		t.Errorf("zero options changed the DSN to %q", got)
+	}
+}
+
+func TestConnOptions_mysqlDSN(t *testing.T) {
+	o := ConnOptions{ConnTimeout: 10 * time.Second, StatementTimeout: 1500 * time.Millisecond}
+	in := "u:p@tcp(h:3306)/db?parseTime=true"
+
+	got, err := o.mysqlDSN(in, false)
+	if err != nil {
+		t.Fatalf("mysqlDSN: %v", err)
+	}
+	for _, s := range []string{"timeout=10s", "max_execution_time=1500", "parseTime=true"} {
+		if !strings.Contains(got, s) {
+			t.Errorf("%q does not contain %q", got, s)
+		}
+	}
+
+	got, err = o.mysqlDSN(in, true)
+	if err != nil {
+		t.Fatalf("mysqlDSN: %v", err)
+	}
+	if !strings.Contains(got, "max_statement_time=1.5") || strings.Contains(got, "max_execution_time") {
+		t.Errorf("MariaDB DSN %q should bound statements with max_statement_time", got)
+	}
+
+	got, err = o.mysqlDSN("u:p@tcp(h:3306)/db?timeout=3s&max_execution_time=0", false)
+	if err != nil {
+		t.Fatalf("mysqlDSN: %v", err)
+	}
+	if !strings.Contains(got, "timeout=3s") || !strings.Contains(got, "max_execution_time=0") {
+		t.Errorf("settings in the DSN were overridden: %q", got)
+	}
+}
+
+func TestSetConnOptions_rejectsNegative(t *testing.T) {
+	defer SetConnOptions(DefaultConnOptions)
+	if err := SetConnOptions(ConnOptions{MaxOpenConns: -1}); err == nil {
+		t.Error("negative --max-open-conns accepted")
+	}
+	if err := SetConnOptions(ConnOptions{StatementTimeout: -time.Second}); err == nil {
+		t.Error("negative --statement-timeout accepted")
+	}
+}
+
+func TestPostgresOption(t *testing.T) {
+	got, err := postgresOption("postgres://u@h/db?options=-c%20search_path%3Dapp", "default_transaction_read_only", "on")
+	if err != nil {
+		t.Fatalf("postgresOption: %v", err)
+	}
+	u, _ := url.Parse(got)
+	if opts := u.Query().Get("options"); opts != "-c search_path=app -c default_transaction_read_only=on" {
+		t.Errorf("options = %q", opts)
+	}
+
+	got, err = postgresOption("host=h dbname=db", "default_transaction_read_only", "on")
+	if err != nil || got != "host=h dbname=db options='-c default_transaction_read_only=on'" {
+		t.Errorf("key=value DSN = %q, %v", got, err)
+	}
+	if _, err := postgresOption("host=h options='-c x=1'", "default_transaction_read_only", "on"); err == nil {
+		t.Error("key=value DSN with options= should be refused")
+	}
+}
+
+func TestMySQLReadOnlyDSN(t *testing.T) {
+	got, err := mysqlReadOnlyDSN("u:p@tcp(h:3306)/db", "tx_read_only")
+	if err != nil || !strings.Contains(got, "tx_read_only=1") {
+		t.Errorf("mysqlReadOnlyDSN = %q, %v", got, err)
+	}
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	for _, s := range []string{"on", "1", "ON"} {
+		if err := checkReadOnly(s); err != nil {
+			t.Errorf("checkReadOnly(%q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{"off", "0"} {
+		if err := checkReadOnly(s); err == nil {
+			t.Errorf("checkReadOnly(%q) accepted", s)
+		}
+	}
+}
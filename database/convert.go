@@ -0,0 +1,296 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A schema captured from one engine family is converted to the other's
+// types when it is read for a restore, so a PostgreSQL snapshot loads
+// into MySQL and back:
+//
+//   - serial, nextval and identity columns ↔ AUTO_INCREMENT
+//   - jsonb ↔ json
+//   - timestamptz and timestamp ↔ datetime (MySQL's timestamp becomes
+//     timestamptz)
+//   - boolean ↔ tinyint(1)
+//   - uuid ↔ char(36)
+//
+// Defaults are carried across where the other engine has an equivalent
+// (literals, the current time, a random uuid) and dropped otherwise.
+// Values need no conversion: each engine's CSV import already takes the
+// other's spelling of booleans and timestamps.
+
+// sameFamily reports whether a schema captured from a can be restored
+// into b without converting its types.
+func sameFamily(a, b DatabaseType) bool {
+	family := func(t DatabaseType) DatabaseType {
+		switch t {
+		case Cockroach:
+			return Postgres
+		case MariaDB:
+			return MySQL
+		}
+		return t
+	}
+	return family(a) == family(b)
+}
+
+// convertToMySQL rewrites a PostgreSQL schema's columns in MySQL's types.
+func convertToMySQL(schema *Schema) {
+	for ti := range schema.Tables {
+		for ci := range schema.Tables[ti].Columns {
+			col := &schema.Tables[ti].Columns[ci]
+			// Type already holds a domain's base type.
+			col.Domain = ""
+			def := columnDefaultString(col.Default)
+			serial := col.Identity != "" || strings.Contains(col.Type, "serial") ||
+				strings.Contains(strings.ToLower(def), "nextval(")
+			col.Type = mysqlTypeFor(col)
+			if serial {
+				col.Identity, col.IsGenerated, col.Default = "", false, nil
+				// MySQL only auto-increments a key column.
+				if col.IsPrimary {
+					col.Default = "AUTO_INCREMENT"
+				}
+				continue
+			}
+			col.Default = mysqlDefaultFor(def, col)
+		}
+	}
+	schema.Domains, schema.CompositeTypes = nil, nil
+	schema.DatabaseType = MySQL
+}
+
+// mysqlTypeFor is the MySQL type for a PostgreSQL column, adjusting its
+// length and precision to go with it.
+func mysqlTypeFor(col *Column) string {
+	t := strings.ToLower(col.Type)
+	length := func(n string) {
+		if col.Varchar == nil {
+			col.Varchar = &n
+		}
+	}
+	switch {
+	case t == "serial", t == "integer", t == "int4":
+		return "int"
+	case t == "bigserial", t == "int8":
+		return "bigint"
+	case t == "smallserial", t == "int2":
+		return "smallint"
+	case t == "boolean", t == "bool":
+		one := 1
+		col.Precision = &one
+		return "tinyint"
+	case t == "jsonb":
+		return "json"
+	case strings.HasPrefix(t, "timestamp"):
+		six := 6
+		col.Precision = &six
+		return "datetime"
+	case strings.HasPrefix(t, "time"):
+		six := 6
+		col.Precision = &six
+		return "time"
+	case t == "uuid":
+		col.Varchar = nil
+		length("36")
+		return "char"
+	case t == "character":
+		return "char"
+	case t == "character varying", t == "citext", t == "inet", t == "cidr", t == "macaddr":
+		// varchar rather than text, so the column can still carry a key
+		length("255")
+		return "varchar"
+	case t == "numeric":
+		return "decimal"
+	case t == "money":
+		p, s := 19, 2
+		col.Precision, col.Scale = &p, &s
+		return "decimal"
+	case t == "real", t == "float4":
+		return "float"
+	case t == "double precision", t == "float8":
+		return "double"
+	case t == "bytea":
+		return "longblob"
+	case strings.HasPrefix(t, "array"), t == "interval", t == "xml", t == "tsvector":
+		return "text"
+	case t == "composite":
+		// Kept in PostgreSQL's row literal form, (a,b).
+		col.Composite = ""
+		return "text"
+	}
+	return col.Type
+}
+
+// pgCastLiteral matches a PostgreSQL default that is a literal with a
+// cast, such as 'active'::character varying or '{}'::jsonb.
+var pgCastLiteral = regexp.MustCompile(`^\(?('(?:[^']|'')*'|-?[0-9.]+|NULL)\)?::[a-zA-Z_ ]+(?:\(\d+(?:,\d+)?\))?(?:\[\])?$`)
+
+// mysqlDefaultFor is col's PostgreSQL default def as MySQL spells it, or
+// nil when MySQL has no equivalent.
+func mysqlDefaultFor(def string, col *Column) interface{} {
+	if def == "" {
+		return nil
+	}
+	if m := pgCastLiteral.FindStringSubmatch(def); m != nil {
+		def = m[1]
+	}
+	lower := strings.ToLower(def)
+	switch {
+	case lower == "null":
+		return nil
+	case lower == "now()", lower == "current_timestamp", lower == "localtimestamp",
+		lower == "transaction_timestamp()", lower == "statement_timestamp()", lower == "clock_timestamp()":
+		if col.Type == "datetime" && col.Precision != nil {
+			return fmt.Sprintf("CURRENT_TIMESTAMP(%d)", *col.Precision)
+		}
+		return "CURRENT_TIMESTAMP"
+	case lower == "current_date":
+		return "(CURRENT_DATE)"
+	case lower == "gen_random_uuid()", lower == "uuid_generate_v4()":
+		return "(uuid())"
+	case lower == "true", lower == "false":
+		if lower == "true" {
+			return "1"
+		}
+		return "0"
+	case strings.Contains(def, "("):
+		// Any other function call is PostgreSQL's own.
+		return nil
+	}
+	// MySQL only takes a literal default on a text, blob or json column
+	// as an expression.
+	switch col.Type {
+	case "text", "longtext", "longblob", "json":
+		if strings.HasPrefix(def, "'") {
+			return "(" + def + ")"
+		}
+	}
+	return def
+}
+
+// convertToPostgres rewrites a MySQL schema's columns in PostgreSQL's
+// types.
+func convertToPostgres(schema *Schema) {
+	for ti := range schema.Tables {
+		table := schema.Tables[ti].Name
+		for ci := range schema.Tables[ti].Columns {
+			col := &schema.Tables[ti].Columns[ci]
+			def := columnDefaultString(col.Default)
+			if isAutoIncrement(*col) {
+				col.Type = postgresTypeFor(col)
+				// A nextval default makes the restore create the column as
+				// serial and move its sequence past the loaded rows.
+				col.Default = fmt.Sprintf("nextval('%s_%s_seq'::regclass)", table, col.Name)
+				continue
+			}
+			col.Type = postgresTypeFor(col)
+			col.Default = postgresDefaultFor(def, col)
+		}
+	}
+	schema.DatabaseType = Postgres
+}
+
+// postgresTypeFor is the PostgreSQL type for a MySQL column, adjusting
+// its length and precision to go with it. An unsigned integer is widened
+// to the next type that holds its whole range.
+func postgresTypeFor(col *Column) string {
+	t := strings.ToLower(col.Type)
+	unsigned := col.Unsigned
+	col.Unsigned = false
+	switch t {
+	case "tinyint":
+		if col.Precision != nil && *col.Precision == 1 {
+			col.Precision = nil
+			return "boolean"
+		}
+		return "smallint"
+	case "smallint":
+		if unsigned {
+			return "integer"
+		}
+		return "smallint"
+	case "mediumint", "int", "integer":
+		if unsigned {
+			return "bigint"
+		}
+		return "integer"
+	case "bigint":
+		if unsigned && !isAutoIncrement(*col) {
+			p, s := 20, 0
+			col.Precision, col.Scale = &p, &s
+			return "numeric"
+		}
+		return "bigint"
+	case "year":
+		return "integer"
+	case "decimal":
+		return "numeric"
+	case "float":
+		return "real"
+	case "double":
+		return "double precision"
+	case "datetime":
+		col.Precision = nil
+		return "timestamp without time zone"
+	case "timestamp":
+		col.Precision = nil
+		return "timestamp with time zone"
+	case "time":
+		col.Precision = nil
+		return "time without time zone"
+	case "char", "varchar":
+		return "character varying"
+	case "tinytext", "mediumtext", "longtext", "set":
+		return "text"
+	case "json":
+		return "jsonb"
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		col.Varchar = nil
+		return "bytea"
+	}
+	return col.Type
+}
+
+// postgresDefaultFor is col's MySQL default def as PostgreSQL spells it,
+// or nil when PostgreSQL has no equivalent. MySQL keeps string literals
+// unquoted, so they are quoted here.
+func postgresDefaultFor(def string, col *Column) interface{} {
+	if def == "" {
+		return nil
+	}
+	lower := strings.ToLower(strings.Trim(def, "()"))
+	switch {
+	case lower == "null":
+		return nil
+	case strings.HasPrefix(lower, "current_timestamp"), strings.HasPrefix(lower, "now"),
+		strings.HasPrefix(lower, "localtimestamp"):
+		return "CURRENT_TIMESTAMP"
+	case lower == "current_date", lower == "curdate":
+		return "CURRENT_DATE"
+	case lower == "uuid":
+		return "gen_random_uuid()"
+	case strings.HasPrefix(def, "("), strings.HasSuffix(def, ")"):
+		// Any other expression is MySQL's own.
+		return nil
+	}
+	if col.Type == "boolean" {
+		switch def {
+		case "0":
+			return "false"
+		case "1":
+			return "true"
+		}
+	}
+	if strings.HasPrefix(def, "'") {
+		return def
+	}
+	if _, err := strconv.ParseFloat(def, 64); err == nil {
+		return def
+	}
+	return "'" + strings.ReplaceAll(def, "'", "''") + "'"
+}
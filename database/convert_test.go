@@ -0,0 +1,85 @@
+package db
+
+import "testing"
+
+func TestConvertToMySQL(t *testing.T) {
+	schema := &Schema{
+		DatabaseType: Postgres,
+		Tables: []Table{{Name: "users", Columns: []Column{
+			{Name: "id", Type: "integer", IsPrimary: true, Default: "nextval('users_id_seq'::regclass)"},
+			{Name: "ref", Type: "uuid", Default: "gen_random_uuid()"},
+			{Name: "active", Type: "boolean", Default: "true"},
+			{Name: "prefs", Type: "jsonb", Default: "'{}'::jsonb"},
+			{Name: "status", Type: "character varying", Default: "'new'::character varying"},
+			{Name: "created_at", Type: "timestamp with time zone", Default: "now()"},
+			{Name: "score", Type: "double precision", Default: "random()"},
+		}}},
+	}
+	convertToMySQL(schema)
+	if schema.DatabaseType != MySQL {
+		t.Errorf("DatabaseType = %s, want mysql", schema.DatabaseType)
+	}
+
+	m := &MySQLManager{}
+	want := map[string]string{
+		"id":         "`id` INT AUTO_INCREMENT NOT NULL",
+		"ref":        "`ref` CHAR(36) NOT NULL DEFAULT (uuid())",
+		"active":     "`active` TINYINT(1) NOT NULL DEFAULT 1",
+		"prefs":      "`prefs` JSON NOT NULL DEFAULT ('{}')",
+		"status":     "`status` VARCHAR(255) NOT NULL DEFAULT 'new'",
+		"created_at": "`created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)",
+		"score":      "`score` DOUBLE NOT NULL",
+	}
+	for _, col := range schema.Tables[0].Columns {
+		if got := m.columnDefSQL(col); got != want[col.Name] {
+			t.Errorf("%s = %q, want %q", col.Name, got, want[col.Name])
+		}
+	}
+}
+
+func TestConvertToPostgres(t *testing.T) {
+	one, forty, uuidLen := 1, "40", "36"
+	schema := &Schema{
+		DatabaseType: MariaDB,
+		Tables: []Table{{Name: "orders", Columns: []Column{
+			{Name: "id", Type: "bigint", IsPrimary: true, Unsigned: true, Default: "AUTO_INCREMENT"},
+			{Name: "paid", Type: "tinyint", Precision: &one, Default: "0"},
+			{Name: "qty", Type: "int", Unsigned: true, Default: "1"},
+			{Name: "note", Type: "varchar", Varchar: &forty, Default: "it's new"},
+			{Name: "meta", Type: "json", Nullable: true},
+			{Name: "placed_at", Type: "datetime", Default: "CURRENT_TIMESTAMP"},
+			{Name: "token", Type: "char", Varchar: &uuidLen, Default: "(uuid())"},
+			{Name: "blob", Type: "longblob", Nullable: true, Default: "NULL"},
+		}}},
+	}
+	convertToPostgres(schema)
+	if schema.DatabaseType != Postgres {
+		t.Errorf("DatabaseType = %s, want postgres", schema.DatabaseType)
+	}
+
+	p := &PostgresManager{}
+	want := map[string]string{
+		"id":        `"id" BIGSERIAL NOT NULL`,
+		"paid":      `"paid" boolean NOT NULL DEFAULT false`,
+		"qty":       `"qty" bigint NOT NULL DEFAULT 1`,
+		"note":      `"note" varchar(40) NOT NULL DEFAULT 'it''s new'`,
+		"meta":      `"meta" jsonb`,
+		"placed_at": `"placed_at" timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+		"token":     `"token" varchar(36) NOT NULL DEFAULT gen_random_uuid()`,
+		"blob":      `"blob" bytea`,
+	}
+	for _, col := range schema.Tables[0].Columns {
+		if got := p.columnDefSQL(col, nil); got != want[col.Name] {
+			t.Errorf("%s = %q, want %q", col.Name, got, want[col.Name])
+		}
+	}
+}
+
+func TestSameFamily(t *testing.T) {
+	if !sameFamily(Cockroach, Postgres) || !sameFamily(MariaDB, MySQL) {
+		t.Error("CockroachDB and MariaDB should share their family's types")
+	}
+	if sameFamily(Postgres, MySQL) {
+		t.Error("PostgreSQL and MySQL should not")
+	}
+}
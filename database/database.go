@@ -1,13 +1,255 @@
 package db
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+)
+
 // DatabaseManager defines the interface for database operations
 type DatabaseManager interface {
 	ConnectWithDSN(dsn string) error
+	// ConnectReadOnly is ConnectWithDSN with every session held to reads
+	// by the server, checked once connected.
+	ConnectReadOnly(dsn string) error
 	ExportSchema(outputPath string) error
 	ExportToCSV(outputDir string) error
+	// ExportToCSVWithOptions is ExportToCSV with per-call tuning. It
+	// returns the tables it skipped (see ExportOptions.SkipUnreadable).
+	ExportToCSVWithOptions(outputDir string, opts ExportOptions) ([]string, error)
 	RestoreFromCSV(inputDir string) error
+	// RestoreFromCSVWithOptions is RestoreFromCSV with per-call tuning.
+	// RestoreFromCSV(dir) is equivalent to passing the zero RestoreOptions.
+	RestoreFromCSVWithOptions(inputDir string, opts RestoreOptions) error
 	// ExecSQL executes one or more SQL statements against the open
 	// connection inside a transaction. On any error the transaction is
 	// rolled back so the database is left in its pre-call state.
 	ExecSQL(sql string) error
+	// CompareWithCSV reports, without changing anything, how each of
+	// tables in the live database differs from its CSV in inputDir.
+	CompareWithCSV(inputDir string, tables []string) ([]TableDrift, error)
+	// GenerateFake inserts made-up rows straight into the live tables,
+	// in one transaction, and reports how many each table got.
+	GenerateFake(opts FakeOptions) ([]FakeTableResult, error)
+	// SeedHistory returns the limit most recent seeds recorded in
+	// HistoryTable, newest first.
+	SeedHistory(limit int) ([]SeedRecord, error)
+}
+
+// RestoreOptions tunes a single restore. The zero value reproduces the
+// historical behaviour: every table in schema.json is truncated and
+// reloaded from its CSV.
+type RestoreOptions struct {
+	// StaticTables names reference tables (countries, currencies, …) that
+	// rarely change. When a static table already exists and its live rows
+	// hash to the same value as the fixture CSV, the restore leaves it
+	// alone instead of truncating and reloading it.
+	StaticTables []string
+
+	// Tables, when non-empty, limits the restore to a subset: only these
+	// tables are cleared and reloaded. Rows are removed with DELETE rather
+	// than TRUNCATE … CASCADE so tables outside the subset keep their data.
+	Tables []string
+
+	// AllowCascade lets a PostgreSQL restore go ahead when its TRUNCATE …
+	// CASCADE would also empty tables outside the revision that hold rows.
+	// Without it the restore stops with a *CascadeError naming them.
+	AllowCascade bool
+
+	// MergeTables are loaded without clearing: each CSV row is inserted
+	// only when its key is not already present, and existing rows are left
+	// untouched. Used for the FK parents of a subset seed.
+	MergeTables []string
+
+	// Role, when set, is assumed with SET ROLE for the whole restore, and
+	// the privilege preflight checks that role rather than the login user.
+	// PostgreSQL only.
+	Role string
+
+	// TargetSchema restores into this PostgreSQL schema instead of public,
+	// creating it when missing. Everything the restore creates lands there
+	// and public is left untouched (sandbox mode).
+	TargetSchema string
+
+	// TargetDatabase restores into this MySQL database instead of the one
+	// in the DSN, creating it when missing (sandbox mode).
+	TargetDatabase string
+
+	// Mode selects how rows already in the target are treated. The zero
+	// value behaves like RestoreReplace.
+	Mode RestoreMode
+
+	// Analyze refreshes planner statistics on every reloaded table once
+	// the load has committed, so the first queries get the plans later
+	// ones would.
+	Analyze bool
+
+	// WarmupSQL runs after the load (and the ANALYZE, when set) to prime
+	// caches and materialized views before the first test does. Its
+	// results are discarded.
+	WarmupSQL string
+
+	// Vacuum, when set, runs after the load and the warmup: VacuumRun
+	// vacuums the reloaded tables, VacuumAdvise prints the space there is
+	// to reclaim and the statements that would reclaim it.
+	Vacuum VacuumMode
+
+	// CreateMissingOnly applies additive DDL and nothing else: enums,
+	// sequences and tables missing from the target are created, and
+	// columns missing from existing tables are added. Existing objects
+	// are left as they are and no rows are loaded or removed.
+	CreateMissingOnly bool
+
+	// Record, when set, is appended to HistoryTable once the rows are
+	// loaded; FinishedAt is stamped by the restore. On PostgreSQL it is
+	// written in the restore transaction, so the history never names a
+	// seed that rolled back. Failing to write it only warns.
+	Record *SeedRecord
+}
+
+// RestoreMode selects how a restore treats rows already in the target.
+type RestoreMode string
+
+const (
+	// RestoreReplace clears every restored table before loading it.
+	RestoreReplace RestoreMode = "replace"
+	// RestoreUpsert keeps existing rows: fixture rows are inserted, and
+	// those whose primary key is already present overwrite that row.
+	RestoreUpsert RestoreMode = "upsert"
+	// RestoreAppend keeps existing rows and inserts the fixture alongside
+	// them, renumbering generated and UUID primary keys (and the foreign
+	// keys that point at them) so the two never collide.
+	RestoreAppend RestoreMode = "append"
+)
+
+// ParseRestoreMode validates a --mode value. Empty means RestoreReplace.
+func ParseRestoreMode(s string) (RestoreMode, error) {
+	switch mode := RestoreMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", RestoreReplace:
+		return RestoreReplace, nil
+	case RestoreUpsert, RestoreAppend:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown restore mode %q (supported: replace, upsert, append)", s)
+	}
+}
+
+// pgSchema is the PostgreSQL schema a restore with o writes to.
+func (o RestoreOptions) pgSchema() string {
+	if o.TargetSchema != "" {
+		return o.TargetSchema
+	}
+	return "public"
+}
+
+// inSubset reports whether a restore with opts touches table at all.
+func (o RestoreOptions) inSubset(table string) bool {
+	if len(o.Tables) == 0 {
+		return true
+	}
+	return containsName(o.Tables, table) || containsName(o.MergeTables, table)
+}
+
+// merges reports whether table is loaded in insert-if-missing mode.
+func (o RestoreOptions) merges(table string) bool {
+	return containsName(o.MergeTables, table)
+}
+
+// upserts reports whether table is loaded with insert-or-update.
+func (o RestoreOptions) upserts(table string) bool {
+	return o.Mode == RestoreUpsert && !o.merges(table)
+}
+
+// appends reports whether table is loaded alongside its existing rows
+// with remapped keys.
+func (o RestoreOptions) appends(table string) bool {
+	return o.Mode == RestoreAppend && !o.merges(table)
+}
+
+// keepsExisting reports whether rows of table whose key is already
+// present are skipped rather than loaded. Appended tables without
+// remapped keys (natural or composite keys) behave like merged ones.
+func (o RestoreOptions) keepsExisting(table string, remapped bool) bool {
+	return o.merges(table) || (o.appends(table) && !remapped)
+}
+
+// clears reports whether an existing table is emptied before loading.
+func (o RestoreOptions) clears(table string) bool {
+	return (o.Mode == "" || o.Mode == RestoreReplace) && !o.merges(table)
+}
+
+// expectedRows is the row count of each table the restore's progress
+// ETAs go by: those of the seed record, which the caller counted.
+func (o RestoreOptions) expectedRows() map[string]int64 {
+	if o.Record == nil {
+		return nil
+	}
+	rows := make(map[string]int64, len(o.Record.RowCounts))
+	for table, n := range o.Record.RowCounts {
+		rows[table] = int64(n)
+	}
+	return rows
+}
+
+// loadTables names the tables a restore with opts loads rows into: those
+// in its subset with a CSV in dataDir, bar the unchanged static ones.
+func loadTables(tables []Table, dataDir string, unchanged map[string]bool, opts RestoreOptions) []string {
+	var names []string
+	for _, table := range tables {
+		if unchanged[table.Name] || !opts.inSubset(table.Name) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dataDir, table.Name+".csv")); err == nil {
+			names = append(names, table.Name)
+		}
+	}
+	return names
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// upsertColumns lists the header columns an upsert overwrites on a key
+// conflict: everything except the primary key and generated columns.
+func upsertColumns(table Table, header []string) []string {
+	skip := map[string]bool{}
+	for _, col := range table.Columns {
+		if col.IsPrimary || col.IsGenerated {
+			skip[col.Name] = true
+		}
+	}
+	var cols []string
+	for _, h := range header {
+		if !skip[h] {
+			cols = append(cols, h)
+		}
+	}
+	return cols
+}
+
+// exprRow is a CSV row holding at least one SQL expression cell, with
+// its 1-based row number for error messages.
+type exprRow struct {
+	n      int
+	record []string
+}
+
+// hasExpr reports whether any cell of record is an SQL expression for the
+// database to evaluate (see package cellexpr).
+func hasExpr(record []string) bool {
+	for _, v := range record {
+		if cellexpr.Is(v) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/ddl")
+
+// checkGolden compares got with testdata/ddl/name, or rewrites the file
+// when the tests run with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "ddl", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("DDL differs from %s (run go test -update if the change is intended):\n%s", path, got)
+	}
+}
+
+func readTestSchema(t *testing.T, engine string) *Schema {
+	t.Helper()
+	schema, err := ReadSchemaFile(filepath.Join("..", "testdata", "databases", "test-data-1", engine, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+// reverseColumns reverses every table's columns, which must not move its
+// constraints around.
+func reverseColumns(schema *Schema) {
+	for i := range schema.Tables {
+		cols := schema.Tables[i].Columns
+		for l, r := 0, len(cols)-1; l < r; l, r = l+1, r-1 {
+			cols[l], cols[r] = cols[r], cols[l]
+		}
+	}
+}
+
+// constraintLines keeps the lines of ddl that declare a constraint.
+func constraintLines(ddl string) string {
+	var out []string
+	for _, line := range strings.Split(ddl, "\n") {
+		if strings.Contains(line, "UNIQUE (") || strings.Contains(line, "FOREIGN KEY") {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func postgresDDL(schema *Schema) string {
+	p := &PostgresManager{}
+	var stmts []string
+	for _, enum := range schema.Enums {
+		stmts = append(stmts, createEnumSQL(enum)+";")
+	}
+	for _, stmt := range userTypeStatements(schema, func(string) bool { return false }) {
+		stmts = append(stmts, stmt+";")
+	}
+	standalone := map[string]bool{}
+	for _, seq := range schema.Sequences {
+		standalone[seq.Name] = true
+		stmts = append(stmts, createSequenceSQL(seq)+";")
+	}
+	for _, table := range schema.Tables {
+		stmts = append(stmts, p.buildCreateTableSQL(table, standalone)+";")
+	}
+	stmts = append(stmts, p.foreignKeySQL(schema, nil, nil)...)
+	return strings.Join(stmts, "\n") + "\n"
+}
+
+func mysqlDDL(schema *Schema) string {
+	m := &MySQLManager{}
+	var stmts []string
+	for _, table := range schema.Tables {
+		stmts = append(stmts, m.createTableSQL(table)+";")
+	}
+	for _, table := range schema.Tables {
+		for _, fk := range foreignKeySQL(table) {
+			stmts = append(stmts, fk.sql+";")
+		}
+	}
+	return strings.Join(stmts, "\n") + "\n"
+}
+
+func TestPostgresDDL_golden(t *testing.T) {
+	ddl := postgresDDL(readTestSchema(t, "postgres"))
+	checkGolden(t, "postgres.sql", ddl)
+
+	reversed := readTestSchema(t, "postgres")
+	reverseColumns(reversed)
+	if got, want := constraintLines(postgresDDL(reversed)), constraintLines(ddl); got != want {
+		t.Errorf("constraints follow column order:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMySQLDDL_golden(t *testing.T) {
+	ddl := mysqlDDL(readTestSchema(t, "mysql"))
+	checkGolden(t, "mysql.sql", ddl)
+
+	reversed := readTestSchema(t, "mysql")
+	reverseColumns(reversed)
+	if got, want := constraintLines(mysqlDDL(reversed)), constraintLines(ddl); got != want {
+		t.Errorf("constraints follow column order:\n%s\nwant\n%s", got, want)
+	}
+}
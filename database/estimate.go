@@ -0,0 +1,68 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SizeEstimator is implemented by managers that can tell, before an
+// export starts, roughly how many bytes its CSVs will take.
+type SizeEstimator interface {
+	// EstimateExportBytes is the on-disk size of the tables an export
+	// with opts reads, per the server's statistics. Tables with a filter
+	// are left out, as are CSVs' differences from the storage format:
+	// it's an order of magnitude, not a promise.
+	EstimateExportBytes(opts ExportOptions) (int64, error)
+}
+
+// EstimateExportBytes implements SizeEstimator with pg_table_size, which
+// counts TOAST but not indexes.
+func (p *PostgresManager) EstimateExportBytes(opts ExportOptions) (int64, error) {
+	if p.DB == nil {
+		return 0, fmt.Errorf("no database connection")
+	}
+	return opts.sizeEstimate(p.DB, `
+		SELECT c.relname, pg_table_size(c.oid)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p')
+		AND c.relname <> '_seedmancer_history'`)
+}
+
+// EstimateExportBytes implements SizeEstimator with DATA_LENGTH, which
+// InnoDB derives from page counts and so runs somewhat high.
+func (m *MySQLManager) EstimateExportBytes(opts ExportOptions) (int64, error) {
+	if m.DB == nil {
+		return 0, fmt.Errorf("no database connection")
+	}
+	return opts.sizeEstimate(m.DB, `
+		SELECT TABLE_NAME, DATA_LENGTH
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		AND TABLE_NAME <> '_seedmancer_history'`)
+}
+
+// sizeEstimate sums the sizes query returns, as (table, bytes) rows, for
+// the tables an export with opts reads in full.
+func (opts ExportOptions) sizeEstimate(db *sql.DB, query string) (int64, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return 0, fmt.Errorf("estimating export size: %v", err)
+	}
+	defer rows.Close()
+	var total int64
+	for rows.Next() {
+		var table string
+		var n sql.NullInt64
+		if err := rows.Scan(&table, &n); err != nil {
+			return 0, fmt.Errorf("estimating export size: %v", err)
+		}
+		if !n.Valid || opts.where(table) != "" || (len(opts.Tables) > 0 && !containsName(opts.Tables, table)) {
+			continue
+		}
+		total += n.Int64
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("estimating export size: %v", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MarkExcludedColumns flags the columns of the schema.json in dir that
+// patterns pick — "table.column", or a bare column name for every table
+// — as excluded, and returns them as "table.column", sorted. An export
+// leaves excluded columns out of the CSVs; a restore lets the database
+// fill them with their default or NULL, and the seed command makes up
+// values for NOT NULL ones without a default. Keys can't be excluded,
+// since the rows referencing them would break, and a pattern that
+// matches no column is an error.
+func MarkExcludedColumns(dir string, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	path := filepath.Join(dir, "schema.json")
+	schema, err := ReadSchemaFile(path)
+	if err != nil {
+		return nil, err
+	}
+	want := make(map[string]bool, len(patterns))
+	for _, p := range patterns {
+		want[p] = true
+	}
+	referenced := map[string]bool{}
+	for _, t := range schema.Tables {
+		for _, c := range t.Columns {
+			if c.ForeignKey != nil {
+				referenced[c.ForeignKey.Table+"."+c.ForeignKey.Column] = true
+			}
+		}
+	}
+
+	used := map[string]bool{}
+	var marked []string
+	for ti := range schema.Tables {
+		t := &schema.Tables[ti]
+		for ci := range t.Columns {
+			c := &t.Columns[ci]
+			key := t.Name + "." + c.Name
+			pattern := key
+			if !want[key] {
+				if pattern = c.Name; !want[pattern] {
+					continue
+				}
+			}
+			used[pattern] = true
+			if c.IsPrimary || c.ForeignKey != nil || referenced[key] {
+				return nil, fmt.Errorf("exclude_columns: %s is a key; the rows referencing it would break without it", key)
+			}
+			c.Excluded = true
+			marked = append(marked, key)
+		}
+	}
+	for _, p := range patterns {
+		if !used[p] {
+			return nil, fmt.Errorf("exclude_columns: %s matches no column", p)
+		}
+	}
+	sort.Strings(marked)
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("converting schema to JSON: %v", err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return nil, fmt.Errorf("writing schema to file: %v", err)
+	}
+	return marked, nil
+}
+
+// ReadSchemaFile reads the schema.json at path.
+func ReadSchemaFile(path string) (Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Schema{}, err
+	}
+	schema, err := parseSchema(data)
+	if err != nil {
+		return Schema{}, fmt.Errorf("parsing %s: %v", filepath.Base(path), err)
+	}
+	return schema, nil
+}
+
+// FillExcluded returns the CSV records of table, header first, with
+// made-up values appended for the excluded columns a restore can't
+// leave to the database: NOT NULL ones without a default. records comes
+// back unchanged when there are none, or no rows to fill.
+func FillExcluded(schema *Schema, table string, records [][]string) ([][]string, error) {
+	t := schema.TableByName(table)
+	if t == nil || len(records) < 2 {
+		return records, nil
+	}
+	inHeader := make(map[string]bool, len(records[0]))
+	for _, name := range records[0] {
+		inHeader[name] = true
+	}
+	var fill []Column
+	for _, c := range t.Columns {
+		if c.Excluded && requiresValue(c) && !inHeader[c.Name] {
+			fill = append(fill, c)
+		}
+	}
+	if len(fill) == 0 {
+		return records, nil
+	}
+
+	generated, err := GenerateFakeDataInMemory(&Schema{
+		DatabaseType: schema.DatabaseType,
+		Enums:        schema.Enums,
+		Tables:       []Table{{Name: t.Name, Columns: fill}},
+	}, FakeOptions{Rows: len(records) - 1, Clean: true})
+	if err != nil {
+		return nil, fmt.Errorf("filling excluded columns of %s: %v", table, err)
+	}
+	rows := generated[0].Rows
+	out := make([][]string, len(records))
+	for i, rec := range records {
+		out[i] = append([]string(nil), rec...)
+		for _, c := range fill {
+			if i == 0 {
+				out[i] = append(out[i], c.Name)
+			} else {
+				out[i] = append(out[i], formatCSVCell(rows[i-1][c.Name], isBinaryType(c.Type)))
+			}
+		}
+	}
+	return out, nil
+}
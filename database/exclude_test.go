@@ -0,0 +1,86 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkExcludedColumns(t *testing.T) {
+	schema := `{"databaseType":"postgres","enums":[],"tables":[
+	  {"name":"users","columns":[
+	    {"name":"id","type":"integer","isPrimary":true},
+	    {"name":"password_hash","type":"text"},
+	    {"name":"avatar","type":"bytea","nullable":true}
+	  ]},
+	  {"name":"docs","columns":[
+	    {"name":"id","type":"integer","isPrimary":true},
+	    {"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}},
+	    {"name":"avatar","type":"bytea","nullable":true}
+	  ]}
+	]}`
+	dir := t.TempDir()
+	writeFixtureFile(t, dir, "schema.json", schema)
+
+	got, err := MarkExcludedColumns(dir, []string{"users.password_hash", "avatar"})
+	if err != nil {
+		t.Fatalf("MarkExcludedColumns: %v", err)
+	}
+	want := []string{"docs.avatar", "users.avatar", "users.password_hash"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("marked = %v, want %v", got, want)
+	}
+	s, err := ReadSchemaFile(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := s.TableByName("users").Columns; c[0].Excluded || !c[1].Excluded || !c[2].Excluded {
+		t.Errorf("users columns excluded = %v %v %v, want false true true", c[0].Excluded, c[1].Excluded, c[2].Excluded)
+	}
+
+	for pattern, msg := range map[string]string{
+		"docs.user_id": "is a key",
+		"users.id":     "is a key",
+		"nope":         "matches no column",
+	} {
+		writeFixtureFile(t, dir, "schema.json", schema)
+		if _, err := MarkExcludedColumns(dir, []string{pattern}); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: err = %v, want it to say %q", pattern, err, msg)
+		}
+	}
+}
+
+func TestFillExcluded(t *testing.T) {
+	schema := &Schema{
+		DatabaseType: Postgres,
+		Tables: []Table{{Name: "users", Columns: []Column{
+			{Name: "id", Type: "integer", IsPrimary: true},
+			{Name: "password_hash", Type: "text", Excluded: true},
+			{Name: "avatar", Type: "bytea", Nullable: true, Excluded: true},
+		}}},
+	}
+	records := [][]string{{"id"}, {"1"}, {"2"}}
+	got, err := FillExcluded(schema, "users", records)
+	if err != nil {
+		t.Fatalf("FillExcluded: %v", err)
+	}
+	// avatar is nullable, so restore leaves it to the database.
+	if !reflect.DeepEqual(got[0], []string{"id", "password_hash"}) {
+		t.Fatalf("header = %v", got[0])
+	}
+	for _, row := range got[1:] {
+		if len(row) != 2 || row[1] == "" || row[1] == "NULL" {
+			t.Errorf("row = %q, want a made-up password_hash", row)
+		}
+	}
+	if records[0][0] != "id" || len(records[0]) != 1 {
+		t.Errorf("records changed: %v", records)
+	}
+
+	// A CSV that already has the column is left alone.
+	full := [][]string{{"id", "password_hash"}, {"1", "x"}}
+	if got, _ := FillExcluded(schema, "users", full); !reflect.DeepEqual(got, full) {
+		t.Errorf("got %v, want %v unchanged", got, full)
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// ExportOptions tunes ExportToCSVWithOptions. The zero value exports
+// every table and fails on the first one that can't be read.
+type ExportOptions struct {
+	// SkipUnreadable leaves out tables the credentials may not SELECT
+	// from, with a warning, instead of aborting the export. Their names
+	// are returned so the caller can record the export as partial.
+	SkipUnreadable bool
+	// Filters maps a table to an SQL predicate its rows must satisfy to
+	// be exported, e.g. "created_at > now() - interval '30 days'". Tables
+	// without one are exported in full. Foreign keys are not followed: a
+	// row whose parent was filtered out is still exported.
+	Filters map[string]string
+	// ExcludedColumns are columns, as "table.column", left out of the
+	// CSVs (see MarkExcludedColumns).
+	ExcludedColumns []string
+	// Tables, when non-empty, limits the export to these tables; the
+	// others get no CSV. Naming a table the database doesn't have fails.
+	Tables []string
+	// ShardRows, when positive, cuts every table with more data rows than
+	// this into ordered shards as it is written (see csvshard).
+	ShardRows int
+}
+
+// createCSV starts the CSV of table in outputDir, sharded per ShardRows.
+func (opts ExportOptions) createCSV(outputDir, table string) (*csvshard.Writer, error) {
+	w, err := csvshard.Create(outputDir, table, opts.ShardRows)
+	if err != nil {
+		return nil, fmt.Errorf("creating CSV file: %v", err)
+	}
+	return w, nil
+}
+
+// selectTables narrows tables, the database's, to opts.Tables.
+func (opts ExportOptions) selectTables(tables []string) ([]string, error) {
+	if len(opts.Tables) == 0 {
+		return tables, nil
+	}
+	known := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		known[t] = true
+	}
+	wanted := make(map[string]bool, len(opts.Tables))
+	for _, t := range opts.Tables {
+		if !known[t] {
+			return nil, fmt.Errorf("table %s: no such table", t)
+		}
+		wanted[t] = true
+	}
+	var selected []string
+	for _, t := range tables {
+		if wanted[t] {
+			selected = append(selected, t)
+		}
+	}
+	return selected, nil
+}
+
+// columns drops the excluded columns of table from columns.
+func (opts ExportOptions) columns(table string, columns []string) []string {
+	if len(opts.ExcludedColumns) == 0 {
+		return columns
+	}
+	excluded := make(map[string]bool, len(opts.ExcludedColumns))
+	for _, c := range opts.ExcludedColumns {
+		excluded[c] = true
+	}
+	kept := columns[:0]
+	for _, c := range columns {
+		if !excluded[table+"."+c] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// checkFilters fails when opts.Filters names a table not in tables or
+// holds more than a single predicate.
+func (opts ExportOptions) checkFilters(tables []string) error {
+	known := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		known[t] = true
+	}
+	for table, pred := range opts.Filters {
+		if !known[table] {
+			return fmt.Errorf("filter for %s: no such table", table)
+		}
+		if strings.TrimSpace(pred) == "" {
+			return fmt.Errorf("filter for %s: empty predicate", table)
+		}
+		if strings.Contains(pred, ";") {
+			return fmt.Errorf("filter for %s: a filter is one predicate and can't contain ';'", table)
+		}
+	}
+	return nil
+}
+
+// where is the WHERE clause exporting table takes, or "".
+func (opts ExportOptions) where(table string) string {
+	if pred := strings.TrimSpace(opts.Filters[table]); pred != "" {
+		return " WHERE (" + pred + ")"
+	}
+	return ""
+}
+
+// rowEstimates reads the planner's row count of each table from query,
+// which yields (table, rows), for the export's progress ETAs. Filtered
+// tables are left out, their estimate counting rows the filter drops, and
+// so are tables without statistics (a negative count). It is best-effort:
+// without estimates the ETA goes by tables.
+func (opts ExportOptions) rowEstimates(db *sql.DB, query string) map[string]int64 {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	estimates := map[string]int64{}
+	for rows.Next() {
+		var table string
+		var n sql.NullInt64
+		if err := rows.Scan(&table, &n); err != nil {
+			return nil
+		}
+		if n.Valid && n.Int64 >= 0 && opts.where(table) == "" {
+			estimates[table] = n.Int64
+		}
+	}
+	if rows.Err() != nil {
+		return nil
+	}
+	return estimates
+}
+
+// isPermissionDenied reports whether err is the server refusing a
+// statement for lack of privileges: SQLSTATE 42501 on PostgreSQL and
+// CockroachDB, ER_TABLEACCESS_DENIED_ERROR / ER_COLUMNACCESS_DENIED_ERROR
+// on MySQL and MariaDB.
+func isPermissionDenied(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "42501"
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1142 || myErr.Number == 1143
+	}
+	return false
+}
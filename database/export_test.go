@@ -0,0 +1,64 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestIsPermissionDenied(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "42501", Message: "permission denied for table payroll"}, true},
+		{fmt.Errorf("querying data: %w", &pq.Error{Code: "42501"}), true},
+		{&pq.Error{Code: "42P01"}, false},
+		{&mysql.MySQLError{Number: 1142, Message: "SELECT command denied"}, true},
+		{&mysql.MySQLError{Number: 1146}, false},
+		{errors.New("permission denied"), false},
+	}
+	for _, c := range cases {
+		if got := isPermissionDenied(c.err); got != c.want {
+			t.Errorf("isPermissionDenied(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestExportOptionsFilters(t *testing.T) {
+	opts := ExportOptions{Filters: map[string]string{"users": "id > 10"}}
+	if err := opts.checkFilters([]string{"users", "orders"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := opts.where("users"); got != " WHERE (id > 10)" {
+		t.Errorf("where(users) = %q", got)
+	}
+	if got := opts.where("orders"); got != "" {
+		t.Errorf("where(orders) = %q, want none", got)
+	}
+	if err := opts.checkFilters([]string{"orders"}); err == nil {
+		t.Error("a filter for a missing table: want an error")
+	}
+	bad := ExportOptions{Filters: map[string]string{"users": "true; DROP TABLE users"}}
+	if err := bad.checkFilters([]string{"users"}); err == nil {
+		t.Error("a filter with two statements: want an error")
+	}
+}
+
+func TestExportOptionsSelectTables(t *testing.T) {
+	all := []string{"orders", "users", "audit_log"}
+	got, err := ExportOptions{}.selectTables(all)
+	if err != nil || fmt.Sprint(got) != "[orders users audit_log]" {
+		t.Fatalf("no selection = %v, %v; want every table", got, err)
+	}
+	got, err = ExportOptions{Tables: []string{"users", "orders"}}.selectTables(all)
+	if err != nil || fmt.Sprint(got) != "[orders users]" {
+		t.Fatalf("selection = %v, %v; want [orders users]", got, err)
+	}
+	if _, err := (ExportOptions{Tables: []string{"payments"}}).selectTables(all); err == nil {
+		t.Error("a missing table: want an error")
+	}
+}
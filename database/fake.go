@@ -0,0 +1,1091 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KazanKK/seedmancer/generators"
+	"github.com/KazanKK/seedmancer/internal/chaos"
+	"github.com/KazanKK/seedmancer/internal/rowtemplate"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"github.com/lib/pq"
+)
+
+// FakeOptions tunes GenerateFake.
+type FakeOptions struct {
+	// Rows is how many rows each table gets.
+	Rows int
+	// Tables limits generation to these tables. Their foreign keys point
+	// at rows already in the other tables. Empty means every table.
+	Tables []string
+	// Seed makes a run repeatable against the same starting data.
+	Seed int64
+	// Pack supplies the names, places and words of one locale; nil
+	// means rowtemplate.Default.
+	Pack *rowtemplate.Pack
+	// Custom returns the custom generator for a column of table, or nil
+	// for the built-in values. Foreign key columns always reference a
+	// parent row. A value a custom generator repeats in a unique column
+	// is drawn again; columns marked UniqueIgnoreCase compare values
+	// case-insensitively.
+	Custom func(table, column string) generators.Func
+	// EnumWeights returns the relative weight of each label of an enum or
+	// CHECK-list column of table, or nil for equal weights. Every label
+	// still appears once before weights apply, as far as the rows go, so
+	// rare ones get a row too; a label without a weight appears only then.
+	EnumWeights func(table, column string) map[string]float64
+	// FKPick returns how a foreign key column of table picks its parent
+	// row, one of the utils.FKPick strategies; nil picks uniformly. A
+	// unique foreign key column is always one-to-one.
+	FKPick func(table, column string) string
+	// EdgeCases is the share of rows, between 0 and 1, that get one
+	// edge-case value in a column that allows it: NULL in a nullable
+	// foreign key, a string at its length limit, an integer at its type's
+	// limits, an empty JSON object, an extreme date. Key, unique, enum
+	// and custom columns are left alone.
+	EdgeCases float64
+	// Clean leaves out the NULLs otherwise drawn at random, so every
+	// nullable foreign key points at a parent row. It overrides EdgeCases.
+	Clean bool
+}
+
+// FakeTableResult is how many rows GenerateFake put into one table.
+type FakeTableResult struct {
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+	// EdgeCases is how many of those rows got an edge-case value.
+	EdgeCases int `json:"edgeCases,omitempty"`
+}
+
+// fakeDialect is what differs between the engines when inserting.
+type fakeDialect struct {
+	quote       func(string) string
+	placeholder func(n int) string
+	// maxParams caps the bind parameters in one INSERT.
+	maxParams int
+}
+
+// fakeBatchRows is the most rows one INSERT carries.
+const fakeBatchRows = 500
+
+// fakeZipfSkew is the exponent of a zipfian foreign key pick: with 100
+// parents the busiest gets about a quarter of the children.
+const fakeZipfSkew = 1.1
+
+// fakeKeySample is how many existing keys are read per referenced column.
+const fakeKeySample = 10000
+
+// generateFake inserts opts.Rows made-up rows into each selected table of
+// schema, parents first, inside one transaction on db. Columns the
+// database fills in itself (serial, identity, AUTO_INCREMENT, generated)
+// are left out; foreign keys get a key read back from the parent table
+// after its own rows went in, so they can point at new and existing rows
+// alike.
+func generateFake(ctx context.Context, db *sql.DB, schema *Schema, opts FakeOptions, d fakeDialect, logSQL func(operation, sql string)) ([]FakeTableResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+	results, err := fakeRun(ctx, schema, opts, &txSink{tx: tx, d: d, logSQL: logSQL})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %v", err)
+	}
+	return results, nil
+}
+
+// FakeTable is one table of GenerateFakeDataInMemory's output.
+type FakeTable struct {
+	Table string `json:"table"`
+	// Columns are the columns the rows set, in table order.
+	Columns []string `json:"columns"`
+	// Rows map a column to its value: a string, an int64 for integer
+	// keys, or nil for NULL.
+	Rows []map[string]any `json:"rows"`
+	// EdgeCases is how many of the rows got an edge-case value.
+	EdgeCases int `json:"edgeCases,omitempty"`
+}
+
+// GenerateFakeDataInMemory makes up opts.Rows rows for each selected table
+// of schema the way GenerateFake does, without a database: nothing is
+// read or written, and the tables come back parents first. Serial,
+// identity and AUTO_INCREMENT keys are numbered from 1 rather than left
+// to the database, so foreign keys have something to point at; they can
+// only point at rows made up in the same run, so a table whose NOT NULL
+// foreign key references a table outside opts.Tables fails.
+func GenerateFakeDataInMemory(schema *Schema, opts FakeOptions) ([]FakeTable, error) {
+	sink := &memSink{}
+	results, err := fakeRun(context.Background(), schema, opts, sink)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]FakeTable, len(results))
+	for i, r := range results {
+		t := sink.tables[r.Table]
+		t.EdgeCases = r.EdgeCases
+		if t.Rows == nil {
+			t.Rows = []map[string]any{}
+		}
+		tables[i] = *t
+	}
+	return tables, nil
+}
+
+// fakeRun makes up opts.Rows rows for each selected table of schema,
+// parents first, and hands them to sink.
+func fakeRun(ctx context.Context, schema *Schema, opts FakeOptions, sink fakeSink) ([]FakeTableResult, error) {
+	if opts.Rows <= 0 {
+		return nil, fmt.Errorf("rows must be positive, got %d", opts.Rows)
+	}
+	selected := map[string]bool{}
+	for _, name := range opts.Tables {
+		if schema.TableByName(name) == nil {
+			return nil, fmt.Errorf("table %q not found in the database", name)
+		}
+		selected[name] = true
+	}
+	enums := make(map[string][]string, len(schema.Enums))
+	for _, e := range schema.Enums {
+		enums[e.Name] = e.Values
+	}
+
+	pack := opts.Pack
+	if pack == nil {
+		pack = rowtemplate.Default
+	}
+	g := &fakeGen{
+		rng:     rand.New(rand.NewSource(opts.Seed)),
+		seed:    opts.Seed,
+		pack:    pack,
+		custom:  opts.Custom,
+		clean:   opts.Clean,
+		weights: opts.EnumWeights,
+		picks:   opts.FKPick,
+		run:     strconv.FormatInt(opts.Seed&0xffffff, 36),
+		enums:   enums,
+		keys:    map[string][]interface{}{},
+	}
+	if !opts.Clean {
+		g.edgeCases = opts.EdgeCases
+	}
+	order, _ := schema.InsertOrder()
+	var results []FakeTableResult
+	for _, name := range order {
+		if len(selected) > 0 && !selected[name] {
+			continue
+		}
+		table := schema.TableByName(name)
+		n, err := g.fillTable(ctx, sink, *table, opts.Rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		results = append(results, FakeTableResult{Table: name, Rows: n, EdgeCases: g.edges})
+	}
+	return results, nil
+}
+
+// fakeSink is where a run reads existing keys from and puts the rows it
+// makes up.
+type fakeSink interface {
+	// keys returns up to fakeKeySample non-NULL values of table.column.
+	keys(ctx context.Context, table, column string) ([]interface{}, error)
+	// maxInt returns the largest value of an integer column, 0 when the
+	// table is empty.
+	maxInt(ctx context.Context, table, column string) (int64, error)
+	// insert stores rows of table, each holding columns' values in order.
+	insert(ctx context.Context, table string, columns []string, rows [][]interface{}) error
+	// fillsKeys reports whether serial, identity and AUTO_INCREMENT
+	// columns get their values from the sink rather than the run.
+	fillsKeys() bool
+}
+
+// txSink inserts into a database inside a transaction.
+type txSink struct {
+	tx     *sql.Tx
+	d      fakeDialect
+	logSQL func(operation, sql string)
+}
+
+func (s *txSink) fillsKeys() bool { return true }
+
+func (s *txSink) keys(ctx context.Context, table, column string) ([]interface{}, error) {
+	d := s.d
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NOT NULL LIMIT %d",
+		d.quote(column), d.quote(table), d.quote(column), fakeKeySample)
+	rows, err := s.tx.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("reading keys of %s.%s: %v", table, column, err)
+	}
+	defer rows.Close()
+	var keys []interface{}
+	for rows.Next() {
+		var v interface{}
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("reading keys of %s.%s: %v", table, column, err)
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		keys = append(keys, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading keys of %s.%s: %v", table, column, err)
+	}
+	return keys, nil
+}
+
+func (s *txSink) maxInt(ctx context.Context, table, column string) (int64, error) {
+	var max sql.NullInt64
+	q := fmt.Sprintf("SELECT MAX(%s) FROM %s", s.d.quote(column), s.d.quote(table))
+	if err := s.tx.QueryRowContext(ctx, q).Scan(&max); err != nil {
+		return 0, fmt.Errorf("reading the largest %s: %v", column, err)
+	}
+	return max.Int64, nil
+}
+
+func (s *txSink) insert(ctx context.Context, table string, columns []string, rows [][]interface{}) error {
+	d := s.d
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = d.quote(c)
+	}
+	batch := len(rows)
+	if d.maxParams > 0 && batch*len(columns) > d.maxParams {
+		batch = d.maxParams / len(columns)
+	}
+	for len(rows) > 0 {
+		chunk := rows
+		if len(chunk) > batch {
+			chunk = rows[:batch]
+		}
+		rows = rows[len(chunk):]
+		tuples := make([]string, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*len(columns))
+		for i, row := range chunk {
+			ph := make([]string, len(row))
+			for j, v := range row {
+				args = append(args, v)
+				ph[j] = d.placeholder(len(args))
+			}
+			tuples[i] = "(" + strings.Join(ph, ", ") + ")"
+		}
+		stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", d.quote(table), strings.Join(header, ", "), strings.Join(tuples, ", "))
+		s.logSQL("Insert "+table, fmt.Sprintf("INSERT INTO %s (%s) VALUES … (%d rows)", d.quote(table), strings.Join(header, ", "), len(chunk)))
+		if _, err := s.tx.ExecContext(ctx, stmt, args...); err != nil {
+			return fmt.Errorf("inserting rows: %v", err)
+		}
+	}
+	return nil
+}
+
+// memSink keeps the rows in memory, for GenerateFakeDataInMemory.
+type memSink struct {
+	tables map[string]*FakeTable
+}
+
+func (s *memSink) fillsKeys() bool { return false }
+
+func (s *memSink) keys(_ context.Context, table, column string) ([]interface{}, error) {
+	var keys []interface{}
+	if t := s.tables[table]; t != nil {
+		for _, row := range t.Rows {
+			if v := row[column]; v != nil && len(keys) < fakeKeySample {
+				keys = append(keys, v)
+			}
+		}
+	}
+	return keys, nil
+}
+
+func (s *memSink) maxInt(context.Context, string, string) (int64, error) { return 0, nil }
+
+func (s *memSink) insert(_ context.Context, table string, columns []string, rows [][]interface{}) error {
+	if s.tables == nil {
+		s.tables = map[string]*FakeTable{}
+	}
+	t := s.tables[table]
+	if t == nil {
+		t = &FakeTable{Table: table, Columns: append([]string(nil), columns...)}
+		s.tables[table] = t
+	}
+	for _, row := range rows {
+		m := make(map[string]any, len(columns))
+		for i, c := range columns {
+			m[c] = row[i]
+		}
+		t.Rows = append(t.Rows, m)
+	}
+	return nil
+}
+
+// fakeGen holds the state shared across the tables of one run.
+type fakeGen struct {
+	rng  *rand.Rand
+	seed int64
+	pack *rowtemplate.Pack
+	// custom picks a column's custom generator; nil when none are set.
+	custom func(table, column string) generators.Func
+	// weights picks a column's label weights; nil when none are set.
+	weights func(table, column string) map[string]float64
+	picks   func(table, column string) string
+	// clean turns off random NULLs; edgeCases is the share of rows that
+	// get an edge-case value, and edges counts them for the current table.
+	clean     bool
+	edgeCases float64
+	edges     int
+	run       string // short token that keeps unique strings apart between runs
+	enums     map[string][]string
+	// keys caches the values read for a "table.column" FK target. Entries
+	// are dropped once that table gets new rows.
+	keys map[string][]interface{}
+}
+
+// fakeColumn is how fillTable produces one column's values.
+type fakeColumn struct {
+	col    Column
+	unique bool
+	// next is the next value of an integer key the database doesn't fill.
+	next int64
+	// refs are the parent keys a foreign key column picks from; perm is a
+	// shuffled order of them when each may be used only once. zipf skews
+	// the picks towards the first parents of hot, a shuffled order too.
+	refs []interface{}
+	perm []int
+	zipf *rand.Zipf
+	hot  []int
+	// custom makes the values of a column with a custom generator,
+	// drawing from its own rng.
+	custom generators.Func
+	rng    *rand.Rand
+	// seen holds the custom values already used in a unique column,
+	// lower-cased when the column ignores case.
+	seen map[string]bool
+	// edge are the values an edge-case row may put in the column; nil
+	// stands for NULL.
+	edge []interface{}
+	// labels are the values of an enum or CHECK-list column. cover holds
+	// those not drawn yet, in the order they will be; once it is empty,
+	// weights (nil for equal ones) picks among labels.
+	labels  []string
+	cover   []string
+	weights []float64
+}
+
+func (g *fakeGen) fillTable(ctx context.Context, sink fakeSink, table Table, rows int) (int, error) {
+	pkCols := 0
+	for _, col := range table.Columns {
+		if col.IsPrimary {
+			pkCols++
+		}
+	}
+	var cols []*fakeColumn
+	var keyIdx []int // columns of a composite key, checked for repeats
+	for _, col := range table.Columns {
+		if col.IsGenerated || (sink.fillsKeys() && filledByDatabase(col)) {
+			continue
+		}
+		fc := &fakeColumn{col: col, unique: col.IsUnique || col.UniqueIgnoreCase || (col.IsPrimary && pkCols == 1)}
+		if fk := col.ForeignKey; fk != nil {
+			refs, err := g.parentKeys(ctx, sink, fk.Table, fk.Column)
+			if err != nil {
+				return 0, err
+			}
+			if len(refs) == 0 && !col.Nullable {
+				return 0, fmt.Errorf("%s references %s.%s, which has no rows; generate that table too or leave --tables off", col.Name, fk.Table, fk.Column)
+			}
+			fc.refs = refs
+			pick := utils.FKPickUniform
+			if g.picks != nil {
+				pick = g.picks(table.Name, col.Name)
+			}
+			if fc.unique || pick == utils.FKPickOneToOne {
+				fc.perm = g.rng.Perm(len(refs))
+				if len(refs) < rows && !col.Nullable {
+					kind := "a unique"
+					if !fc.unique {
+						kind = "a one-to-one"
+					}
+					return 0, fmt.Errorf("%s is %s reference to %s.%s, which has only %d row(s) for %d new row(s)", col.Name, kind, fk.Table, fk.Column, len(refs), rows)
+				}
+			} else if pick == utils.FKPickZipfian && len(refs) > 1 {
+				fc.zipf = rand.NewZipf(g.rng, fakeZipfSkew, 1, uint64(len(refs)-1))
+				fc.hot = g.rng.Perm(len(refs))
+			}
+		} else if g.custom != nil && g.custom(table.Name, col.Name) != nil {
+			fc.custom = g.custom(table.Name, col.Name)
+			fc.rng = rand.New(rand.NewSource(generators.Seed(g.seed, table.Name, col.Name)))
+		} else if _, ok := g.value(table.Name, col, 0, g.pack.New(g.rng.Intn)); !ok {
+			if col.Nullable || col.Default != nil {
+				continue
+			}
+			return 0, fmt.Errorf("don't know how to make up a %s value for NOT NULL column %s", col.Type, col.Name)
+		} else if labels := g.labels(col); len(labels) > 0 && !fc.unique {
+			if err := g.coverLabels(fc, table.Name, labels); err != nil {
+				return 0, err
+			}
+		}
+		if fc.unique && fc.col.ForeignKey == nil && fc.custom == nil && integerTypes[baseType(col.Type)] {
+			max, err := sink.maxInt(ctx, table.Name, col.Name)
+			if err != nil {
+				return 0, err
+			}
+			fc.next = max + 1
+			if _, hi := integerRange(col); fc.next > hi-int64(rows)+1 {
+				return 0, fmt.Errorf("%s is %s: %d new row(s) after %d would pass its maximum of %d", col.Name, integerTypeName(col), rows, max, hi)
+			}
+		}
+		if col.IsPrimary && pkCols > 1 {
+			keyIdx = append(keyIdx, len(cols))
+		}
+		cols = append(cols, fc)
+	}
+	if len(cols) == 0 {
+		return 0, fmt.Errorf("no column can be filled in")
+	}
+
+	names := make([]string, len(cols))
+	index := make(map[string]int, len(cols))
+	for i, fc := range cols {
+		names[i] = fc.col.Name
+		index[fc.col.Name] = i
+	}
+	// ordered maps a column to the one it must not come before in a row.
+	ordered := map[int]int{}
+	for later, earlier := range rowtemplate.OrderedPairs(names) {
+		ordered[index[later]] = index[earlier]
+	}
+	if g.edgeCases > 0 {
+		for _, fc := range cols {
+			fc.edge = edgeValues(fc)
+		}
+		// An extreme date would undo the order between two columns.
+		for later, earlier := range ordered {
+			cols[later].edge, cols[earlier].edge = nil, nil
+		}
+	}
+	g.edges = 0
+
+	seen := map[string]bool{}
+	var pending [][]interface{}
+	inserted := 0
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := sink.insert(ctx, table.Name, names, pending); err != nil {
+			return err
+		}
+		inserted += len(pending)
+		pending = nil
+		return nil
+	}
+
+	for n := 1; n <= rows; n++ {
+		var row []interface{}
+		// A composite key made of references can repeat by chance; a few
+		// redraws are cheap, and a table that runs out of combinations
+		// just ends up with fewer rows.
+		for attempt := 0; attempt < 20; attempt++ {
+			tpl := g.pack.New(g.rng.Intn)
+			row = make([]interface{}, len(cols))
+			for i, fc := range cols {
+				v, err := g.cell(table.Name, fc, n, tpl)
+				if err != nil {
+					return inserted, fmt.Errorf("%s: %v", fc.col.Name, err)
+				}
+				row[i] = v
+			}
+			g.orderRow(row, cols, ordered)
+			if len(keyIdx) == 0 {
+				break
+			}
+			parts := make([]string, len(keyIdx))
+			for i, k := range keyIdx {
+				parts[i] = fmt.Sprint(row[k])
+			}
+			key := strings.Join(parts, "\x00")
+			if !seen[key] {
+				seen[key] = true
+				break
+			}
+			row = nil
+		}
+		if row == nil {
+			break
+		}
+		if g.edgeCases > 0 && g.rng.Float64() < g.edgeCases && g.plantEdge(row, cols) {
+			g.edges++
+		}
+		pending = append(pending, row)
+		if len(pending) >= fakeBatchRows {
+			if err := flush(); err != nil {
+				return inserted, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return inserted, err
+	}
+	for key := range g.keys {
+		if strings.HasPrefix(key, table.Name+".") {
+			delete(g.keys, key)
+		}
+	}
+	return inserted, nil
+}
+
+// parentKeys returns up to fakeKeySample values of table.column from sink.
+func (g *fakeGen) parentKeys(ctx context.Context, sink fakeSink, table, column string) ([]interface{}, error) {
+	cacheKey := table + "." + column
+	if keys, ok := g.keys[cacheKey]; ok {
+		return keys, nil
+	}
+	keys, err := sink.keys(ctx, table, column)
+	if err != nil {
+		return nil, err
+	}
+	g.keys[cacheKey] = keys
+	return keys, nil
+}
+
+// orderRow moves each later date or timestamp in row (end_date,
+// updated_at) to a day to a month after its earlier one.
+func (g *fakeGen) orderRow(row []interface{}, cols []*fakeColumn, ordered map[int]int) {
+	for later, earlier := range ordered {
+		from, ok := row[earlier].(string)
+		if !ok || row[later] == nil || cols[later].col.ForeignKey != nil {
+			continue
+		}
+		offset := 24*time.Hour + time.Duration(g.rng.Int63n(int64(30*24*time.Hour)))
+		if v, ok := rowtemplate.After(from, offset); ok {
+			row[later] = v
+		}
+	}
+}
+
+// cell makes up the value of fc in the table's nth new row, built around
+// tpl. nil is NULL. Only a custom generator can fail.
+func (g *fakeGen) cell(table string, fc *fakeColumn, n int, tpl rowtemplate.Template) (interface{}, error) {
+	col := fc.col
+	if col.ForeignKey != nil {
+		switch {
+		case len(fc.refs) == 0:
+			return nil, nil
+		case fc.perm != nil:
+			if n > len(fc.perm) {
+				return nil, nil
+			}
+			return fc.refs[fc.perm[n-1]], nil
+		case col.Nullable && !g.clean && g.rng.Intn(10) == 0:
+			return nil, nil
+		case fc.zipf != nil:
+			return fc.refs[fc.hot[fc.zipf.Uint64()]], nil
+		}
+		return fc.refs[g.rng.Intn(len(fc.refs))], nil
+	}
+	if fc.custom != nil {
+		req := generators.Request{Table: table, Column: col.Name, Type: col.Type, Row: n, Rand: fc.rng}
+		if !fc.unique {
+			return fc.custom(req)
+		}
+		for attempt := 0; ; attempt++ {
+			v, err := fc.custom(req)
+			if err != nil || fc.claim(v) {
+				return v, err
+			}
+			if attempt == fakeCustomRedraws {
+				if col.UniqueIgnoreCase {
+					return nil, fmt.Errorf("custom generator keeps returning %q, which differs only in case from a value already used", v)
+				}
+				return nil, fmt.Errorf("custom generator keeps returning %q, which is already used", v)
+			}
+		}
+	}
+	if fc.unique && !isBinaryType(col.Type) {
+		if integerTypes[baseType(col.Type)] {
+			v := fc.next
+			fc.next++
+			return v, nil
+		}
+		v, _ := g.value(table, col, n, tpl)
+		if baseType(col.Type) == "uuid" || !isTextType(col.Type) {
+			return v, nil
+		}
+		suffix := "-" + g.run + strconv.Itoa(n)
+		if strings.Contains(v, "@") {
+			at := strings.Index(v, "@")
+			return fitLength(v[:at]+suffix, col, len(v)-at) + v[at:], nil
+		}
+		return fitLength(v, col, len(suffix)) + suffix, nil
+	}
+	if col.Nullable && !g.clean && g.rng.Intn(10) == 0 {
+		return nil, nil
+	}
+	if fc.labels != nil {
+		return g.label(fc), nil
+	}
+	v, _ := g.value(table, col, n, tpl)
+	if isBinaryType(col.Type) {
+		b, _ := decodeBinaryCell(v)
+		return b, nil
+	}
+	return v, nil
+}
+
+// labels returns the values col is limited to by its enum type or CHECK
+// list, or nil.
+func (g *fakeGen) labels(col Column) []string {
+	if values := g.enums[col.Enum]; col.Enum != "" && len(values) > 0 {
+		return values
+	}
+	return col.AllowedValues
+}
+
+// coverLabels sets fc up to draw every one of labels once, in a random
+// order, before drawing by the column's configured weights.
+func (g *fakeGen) coverLabels(fc *fakeColumn, table string, labels []string) error {
+	fc.labels = labels
+	fc.cover = make([]string, len(labels))
+	for i, j := range g.rng.Perm(len(labels)) {
+		fc.cover[i] = labels[j]
+	}
+	if g.weights == nil {
+		return nil
+	}
+	w := g.weights(table, fc.col.Name)
+	if w == nil {
+		return nil
+	}
+	index := make(map[string]int, len(labels))
+	for i, l := range labels {
+		index[l] = i
+	}
+	fc.weights = make([]float64, len(labels))
+	for label, weight := range w {
+		i, ok := index[label]
+		if !ok {
+			return fmt.Errorf("enum_weights for %s: %q is not one of its values (%s)", fc.col.Name, label, strings.Join(labels, ", "))
+		}
+		fc.weights[i] = weight
+	}
+	return nil
+}
+
+// label draws fc's next label: the next one not used yet, then one by
+// weight.
+func (g *fakeGen) label(fc *fakeColumn) string {
+	if len(fc.cover) > 0 {
+		v := fc.cover[0]
+		fc.cover = fc.cover[1:]
+		return v
+	}
+	if fc.weights == nil {
+		return fc.labels[g.rng.Intn(len(fc.labels))]
+	}
+	total := 0.0
+	for _, w := range fc.weights {
+		total += w
+	}
+	if total <= 0 {
+		return fc.labels[g.rng.Intn(len(fc.labels))]
+	}
+	x := g.rng.Float64() * total
+	last := 0
+	for i, w := range fc.weights {
+		if w <= 0 {
+			continue
+		}
+		if x < w {
+			return fc.labels[i]
+		}
+		x -= w
+		last = i
+	}
+	return fc.labels[last]
+}
+
+// edgeValues lists the edge-case values fc may take: NULL for a nullable
+// foreign key, and for a plain column the values chaos plants in its
+// type, plus NULL when it is nullable.
+func edgeValues(fc *fakeColumn) []interface{} {
+	col := fc.col
+	if col.ForeignKey != nil {
+		if col.Nullable && !fc.unique {
+			return []interface{}{nil}
+		}
+		return nil
+	}
+	if fc.unique || col.IsPrimary || fc.custom != nil || col.Enum != "" || len(col.AllowedValues) > 0 {
+		return nil
+	}
+	typ := col.Type
+	if size, ok := varcharLimit(col); ok {
+		typ = fmt.Sprintf("varchar(%d)", size)
+	}
+	if col.Unsigned {
+		typ += " unsigned"
+	}
+	var out []interface{}
+	for _, v := range chaos.EdgeValues(typ) {
+		out = append(out, v)
+	}
+	if len(out) > 0 && col.Nullable {
+		out = append(out, nil)
+	}
+	return out
+}
+
+// plantEdge puts an edge-case value in one column of row that has any,
+// and reports whether it found one.
+func (g *fakeGen) plantEdge(row []interface{}, cols []*fakeColumn) bool {
+	var eligible []int
+	for i, fc := range cols {
+		if len(fc.edge) > 0 {
+			eligible = append(eligible, i)
+		}
+	}
+	if len(eligible) == 0 {
+		return false
+	}
+	i := eligible[g.rng.Intn(len(eligible))]
+	row[i] = cols[i].edge[g.rng.Intn(len(cols[i].edge))]
+	return true
+}
+
+// fakeCustomRedraws is how often a custom generator may repeat a value of
+// a unique column before the run gives up.
+const fakeCustomRedraws = 20
+
+// claim records v as used in fc and reports whether it was still free.
+// A column that ignores case compares values lower-cased, so User1@ and
+// user1@ count as the same.
+func (fc *fakeColumn) claim(v string) bool {
+	key := v
+	if fc.col.UniqueIgnoreCase {
+		key = strings.ToLower(v)
+	}
+	if fc.seen == nil {
+		fc.seen = map[string]bool{}
+	}
+	if fc.seen[key] {
+		return false
+	}
+	fc.seen[key] = true
+	return true
+}
+
+// fitLength cuts s so that s plus reserve more characters fit col's
+// varchar length.
+func fitLength(s string, col Column, reserve int) string {
+	max, ok := varcharLimit(col)
+	if !ok {
+		return s
+	}
+	max -= reserve
+	if max < 0 {
+		max = 0
+	}
+	for utf8.RuneCountInString(s) > max {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s
+}
+
+// filledByDatabase reports whether the database provides col's value:
+// serial, identity and AUTO_INCREMENT keys and generated columns.
+func filledByDatabase(col Column) bool {
+	def := strings.ToLower(columnDefaultString(col.Default))
+	return col.IsGenerated || col.Identity != "" || isAutoIncrement(col) ||
+		strings.Contains(def, "nextval") || strings.Contains(strings.ToLower(col.Type), "serial")
+}
+
+// baseType lowercases t and drops a length or precision suffix.
+func baseType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	if i := strings.Index(t, "("); i > 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+	return t
+}
+
+// integerRange is the range of values col's integer type holds, wider
+// from zero up for an unsigned MySQL column. Unsigned bigint is capped at
+// the largest int64.
+func integerRange(col Column) (lo, hi int64) {
+	switch baseType(col.Type) {
+	case "tinyint":
+		lo, hi = math.MinInt8, math.MaxInt8
+	case "smallint", "smallserial":
+		lo, hi = math.MinInt16, math.MaxInt16
+	case "mediumint":
+		lo, hi = -1<<23, 1<<23-1
+	case "bigint", "bigserial":
+		lo, hi = math.MinInt64, math.MaxInt64
+	default:
+		lo, hi = math.MinInt32, math.MaxInt32
+	}
+	if col.Unsigned {
+		if hi == math.MaxInt64 {
+			return 0, hi
+		}
+		return 0, 2*hi + 1
+	}
+	return lo, hi
+}
+
+// integerTypeName is col's type as error messages name it.
+func integerTypeName(col Column) string {
+	if col.Unsigned {
+		return col.Type + " unsigned"
+	}
+	return col.Type
+}
+
+func isTextType(t string) bool {
+	t = baseType(t)
+	return t == "text" || strings.Contains(t, "char") || t == "citext" || t == "tinytext" || t == "mediumtext" || t == "longtext"
+}
+
+// value makes up a value for col by its type, enum or CHECK list and,
+// for text, its name: columns the row template determines (names, email,
+// address, phone) take its value. ok is false for types it can't produce.
+func (g *fakeGen) value(table string, col Column, n int, tpl rowtemplate.Template) (string, bool) {
+	if values := g.enums[col.Enum]; col.Enum != "" && len(values) > 0 {
+		return values[g.rng.Intn(len(values))], true
+	}
+	if len(col.AllowedValues) > 0 {
+		return col.AllowedValues[g.rng.Intn(len(col.AllowedValues))], true
+	}
+	t := baseType(col.Type)
+	r := g.rng
+	switch {
+	case t == "boolean" || t == "bool" || t == "tinyint" && col.Precision != nil && *col.Precision == 1:
+		return strconv.Itoa(r.Intn(2)), true
+	case t == "year":
+		return strconv.Itoa(1990 + r.Intn(40)), true
+	case integerTypes[t]:
+		// Small positive numbers, kept inside the type's range.
+		n := int64(10000)
+		if t == "tinyint" || t == "smallint" || t == "mediumint" || t == "smallserial" {
+			n = 100
+		}
+		if _, hi := integerRange(col); hi < n {
+			n = hi
+		}
+		return strconv.FormatInt(1+r.Int63n(n), 10), true
+	case (t == "numeric" || t == "decimal") && col.Precision != nil:
+		return fakeDecimal(r, *col.Precision, col.Scale), true
+	case t == "numeric" || t == "decimal" || t == "real" || t == "money" || t == "float" || strings.Contains(t, "double"):
+		return fmt.Sprintf("%d.%02d", r.Intn(1000), r.Intn(100)), true
+	case t == "uuid":
+		b := make([]byte, 16)
+		r.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), true
+	case t == "date":
+		return fakeTime(r).Format("2006-01-02"), true
+	case strings.HasPrefix(t, "timestamp") || t == "datetime":
+		return fakeTime(r).Format("2006-01-02 15:04:05"), true
+	case strings.HasPrefix(t, "time"):
+		return fmt.Sprintf("%02d:%02d:%02d", r.Intn(24), r.Intn(60), r.Intn(60)), true
+	case t == "json" || t == "jsonb":
+		return fmt.Sprintf(`{"n": %d}`, n), true
+	case t == "array" || strings.HasSuffix(t, "[]"):
+		return g.array(table, col, n, tpl)
+	case binaryTypes[t]:
+		// 16 random bytes, fewer when binary(n) or varbinary(n) holds less.
+		size := 16
+		if col.Varchar != nil {
+			if max, err := strconv.Atoi(*col.Varchar); err == nil && max > 0 && max < size {
+				size = max
+			}
+		}
+		b := make([]byte, size)
+		r.Read(b)
+		return encodeBinaryCell(b), true
+	case isSpatialType(t):
+		return fakeGeometry(r, col)
+	case t == "inet" || t == "cidr":
+		return fmt.Sprintf("10.%d.%d.%d", r.Intn(256), r.Intn(256), 1+r.Intn(254)), true
+	case isTextType(t):
+		size, _ := varcharLimit(col)
+		if v, ok := tpl.Text(col.Name, size, n); ok {
+			return fitLength(v, col, 0), true
+		}
+		return fitLength(fakeText(r, tpl, table, strings.ToLower(col.Name), n), col, 0), true
+	}
+	return "", false
+}
+
+// array makes up one to three elements of col's element type, or an
+// empty array when the schema doesn't say what the elements are.
+func (g *fakeGen) array(table string, col Column, n int, tpl rowtemplate.Template) (string, bool) {
+	elem := Column{Name: col.Name, Type: arrayElementOf(col)}
+	if elem.Type == "" {
+		return "{}", true
+	}
+	if _, ok := g.enums[elem.Type]; ok {
+		elem.Enum = elem.Type
+	}
+	values := make([]string, 1+g.rng.Intn(3))
+	for i := range values {
+		v, ok := g.value(table, elem, n, tpl)
+		if !ok {
+			return "", false
+		}
+		values[i] = v
+	}
+	return pgArrayLiteral(values), true
+}
+
+// fakeDecimal is a non-negative number that fits decimal(precision,
+// scale): up to three digits before the point, fewer if the type leaves
+// less room, and up to two after it.
+func fakeDecimal(r *rand.Rand, precision int, scale *int) string {
+	s := 0
+	if scale != nil {
+		s = *scale
+	}
+	digits := func(n, most int) string {
+		if n > most {
+			n = most
+		}
+		if n < 0 {
+			n = 0
+		}
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte('0' + r.Intn(10))
+		}
+		return string(b)
+	}
+	v := strings.TrimLeft(digits(precision-s, 3), "0")
+	if v == "" {
+		v = "0"
+	}
+	if s > 0 {
+		v += "." + digits(s, 2)
+	}
+	return v
+}
+
+// fakeTime is a moment between two years and two months before now, to
+// the second, which leaves room for a later column of the same row to
+// stay in the past.
+func fakeTime(r *rand.Rand) time.Time {
+	const span, margin = 2 * 365 * 24 * time.Hour, 60 * 24 * time.Hour
+	return time.Now().UTC().Add(-margin - time.Duration(r.Int63n(int64(span-margin)))).Truncate(time.Second)
+}
+
+// fakeWords make up identifiers (slugs, URLs, SKUs), which stay ASCII
+// whatever the locale.
+var fakeWords = rowtemplate.Default.Words
+
+// personTables are table names whose "name" column holds a person's name.
+var personTables = []string{"user", "customer", "person", "people", "member", "employee", "author", "contact", "profile", "admin", "student", "patient"}
+
+// fakeText makes up a string that suits a column called name, for the
+// names the row template doesn't cover.
+func fakeText(r *rand.Rand, tpl rowtemplate.Template, table, name string, n int) string {
+	pack := tpl.Pack
+	if pack == nil {
+		pack = rowtemplate.Default
+	}
+	pick := func(list []string) string { return list[r.Intn(len(list))] }
+	words := func(k int) []string {
+		out := make([]string, k)
+		for i := range out {
+			out[i] = pick(pack.Words)
+		}
+		return out
+	}
+	phrase := func(k int) string { return strings.Join(words(k), pack.WordSep) }
+	has := func(subs ...string) bool {
+		for _, s := range subs {
+			if strings.Contains(name, s) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("company", "organization", "organisation", "employer"):
+		return pick(pack.Companies)
+	case has("avatar", "image", "photo", "picture"):
+		return fmt.Sprintf("https://example.com/images/%d.png", n)
+	case has("url", "website", "link", "homepage"):
+		return fmt.Sprintf("https://example.com/%s-%d", pick(fakeWords), n)
+	case has("slug"):
+		return pick(fakeWords) + "-" + pick(fakeWords) + "-" + strconv.Itoa(n)
+	case has("password", "hash", "token", "secret", "api_key"):
+		return fmt.Sprintf("%016x%016x", r.Uint64(), r.Uint64())
+	case has("color", "colour"):
+		return fmt.Sprintf("#%06x", r.Intn(1<<24))
+	case has("status"):
+		return []string{"active", "pending", "inactive"}[r.Intn(3)]
+	case has("sku", "code"):
+		return strings.ToUpper(fmt.Sprintf("%s-%04d", pick(fakeWords)[:3], n))
+	case has("title", "subject", "headline"):
+		return strings.Title(phrase(3))
+	case has("description", "bio", "body", "content", "notes", "comment", "summary", "message", "text"):
+		return pack.Sentence(words(8))
+	case has("nickname"):
+		first, _ := tpl.Latin()
+		return first + strconv.Itoa(n)
+	case has("name"):
+		if name == "name" {
+			for _, p := range personTables {
+				if strings.Contains(strings.ToLower(table), p) {
+					return tpl.FullName()
+				}
+			}
+		}
+		return strings.Title(phrase(2))
+	}
+	return phrase(2)
+}
+
+func (p *PostgresManager) GenerateFake(opts FakeOptions) ([]FakeTableResult, error) {
+	if p.DB == nil {
+		return nil, errors.New("no database connection")
+	}
+	schema, err := p.ExtractSchema()
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %v", err)
+	}
+	d := fakeDialect{
+		quote:       pq.QuoteIdentifier,
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		maxParams:   65535,
+	}
+	return generateFake(context.Background(), p.DB, schema, opts, d, p.logSQL)
+}
+
+func (m *MySQLManager) GenerateFake(opts FakeOptions) ([]FakeTableResult, error) {
+	if m.DB == nil {
+		return nil, errors.New("no database connection")
+	}
+	schema, err := m.ExtractSchema()
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %v", err)
+	}
+	d := fakeDialect{
+		quote:       quoteIdent,
+		placeholder: func(int) string { return "?" },
+		maxParams:   65535,
+	}
+	return generateFake(context.Background(), m.DB, schema, opts, d, m.logSQL)
+}
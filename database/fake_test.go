@@ -0,0 +1,443 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/KazanKK/seedmancer/generators"
+	"github.com/KazanKK/seedmancer/internal/rowtemplate"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func TestFakeGenValue(t *testing.T) {
+	g := &fakeGen{
+		rng:   rand.New(rand.NewSource(1)),
+		run:   "x",
+		enums: map[string][]string{"mood": {"happy", "sad"}},
+	}
+	four := "4"
+	cases := []struct {
+		col   Column
+		check func(string) bool
+	}{
+		{Column{Name: "email", Type: "varchar"}, func(v string) bool { return strings.HasSuffix(v, "@example.com") }},
+		{Column{Name: "code", Type: "varchar", Varchar: &four}, func(v string) bool { return utf8.RuneCountInString(v) <= 4 }},
+		{Column{Name: "mood", Type: "USER-DEFINED", Enum: "mood"}, func(v string) bool { return v == "happy" || v == "sad" }},
+		{Column{Name: "plan", Type: "text", AllowedValues: []string{"free"}}, func(v string) bool { return v == "free" }},
+		{Column{Name: "active", Type: "boolean"}, func(v string) bool { return v == "0" || v == "1" }},
+		{Column{Name: "qty", Type: "integer"}, func(v string) bool { _, err := strconv.Atoi(v); return err == nil }},
+		{Column{Name: "born", Type: "date"}, func(v string) bool { return len(v) == len("2006-01-02") }},
+		{Column{Name: "id", Type: "uuid"}, func(v string) bool { return len(v) == 36 && v[14] == '4' }},
+		{Column{Name: "avatar", Type: "bytea"}, func(v string) bool { b, ok := decodeBinaryCell(v); return ok && len(b) == 16 }},
+		{Column{Name: "token", Type: "binary", Varchar: &four}, func(v string) bool { b, ok := decodeBinaryCell(v); return ok && len(b) == 4 }},
+		{Column{Name: "scores", Type: "ARRAY", ElementType: "integer"}, func(v string) bool {
+			for _, e := range strings.Split(strings.Trim(v, "{}"), ",") {
+				if _, err := strconv.Atoi(e); err != nil {
+					return false
+				}
+			}
+			return strings.HasPrefix(v, "{")
+		}},
+		{Column{Name: "moods", Type: "ARRAY", ElementType: "mood"}, func(v string) bool { return strings.Contains(v, "happy") || strings.Contains(v, "sad") }},
+		{Column{Name: "keys", Type: "uuid[]"}, func(v string) bool { return len(v) >= 38 && v[0] == '{' && v[15] == '4' }},
+		{Column{Name: "tags", Type: "ARRAY"}, func(v string) bool { return v == "{}" }},
+		{Column{Name: "spot", Type: "geometry"}, func(v string) bool { return strings.HasPrefix(v, "POINT(") }},
+		{Column{Name: "area", Type: "geography", GeometryType: "Polygon", SRID: 4326}, func(v string) bool {
+			return strings.HasPrefix(v, "SRID=4326;POLYGON((") && strings.Count(v, ",") == 4
+		}},
+		{Column{Name: "route", Type: "geometry", GeometryType: "LineStringZ"}, func(v string) bool {
+			return strings.HasPrefix(v, "LINESTRING(") && strings.Count(v, " ") == 5
+		}},
+	}
+	for _, c := range cases {
+		v, ok := g.value("users", c.col, 3, rowtemplate.New(g.rng.Intn))
+		if !ok || !c.check(v) {
+			t.Errorf("%s %s: got %q (ok=%v)", c.col.Name, c.col.Type, v, ok)
+		}
+	}
+	if _, ok := g.value("users", Column{Name: "shape", Type: "geometry", GeometryType: "CircularString"}, 1, rowtemplate.Template{}); ok {
+		t.Error("circular string: want ok=false")
+	}
+}
+
+func TestFakeGenCell_uniqueAndReferences(t *testing.T) {
+	g := &fakeGen{rng: rand.New(rand.NewSource(1)), run: "r"}
+
+	id := &fakeColumn{col: Column{Name: "id", Type: "bigint", IsPrimary: true}, unique: true, next: 41}
+	tpl := rowtemplate.New(g.rng.Intn)
+	if a, b := mustCell(t, g, "users", id, 1, tpl), mustCell(t, g, "users", id, 2, tpl); a != int64(41) || b != int64(42) {
+		t.Errorf("integer key: got %v, %v, want 41, 42", a, b)
+	}
+
+	email := &fakeColumn{col: Column{Name: "email", Type: "text", IsUnique: true}, unique: true}
+	a, b := mustCell(t, g, "users", email, 1, tpl).(string), mustCell(t, g, "users", email, 2, tpl).(string)
+	if a == b || !strings.HasSuffix(a, "@example.com") {
+		t.Errorf("unique email: got %q and %q", a, b)
+	}
+
+	refs := []interface{}{int64(1), int64(2), int64(3)}
+	owner := &fakeColumn{col: Column{Name: "user_id", Type: "bigint", IsUnique: true, ForeignKey: &ForeignKey{Table: "users", Column: "id"}}, unique: true, refs: refs, perm: g.rng.Perm(3)}
+	seen := map[interface{}]bool{}
+	for n := 1; n <= 3; n++ {
+		seen[mustCell(t, g, "profiles", owner, n, tpl)] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("unique reference reused a parent: %v", seen)
+	}
+}
+
+func TestFakeGenCell_custom(t *testing.T) {
+	g := &fakeGen{rng: rand.New(rand.NewSource(1)), run: "r"}
+	iban := func(r generators.Request) (string, error) {
+		return fmt.Sprintf("DE%02d%018d", r.Row, r.Rand.Int63n(1e17)), nil
+	}
+	fc := &fakeColumn{col: Column{Name: "iban", Type: "varchar(34)", IsUnique: true}, unique: true, custom: iban, rng: rand.New(rand.NewSource(7))}
+	tpl := rowtemplate.New(g.rng.Intn)
+	if v := mustCell(t, g, "accounts", fc, 3, tpl).(string); !strings.HasPrefix(v, "DE03") || len(v) != 22 {
+		t.Errorf("custom value: got %q", v)
+	}
+
+	fc.custom = func(generators.Request) (string, error) { return "", errors.New("out of numbers") }
+	if _, err := g.cell("accounts", fc, 1, tpl); err == nil || !strings.Contains(err.Error(), "out of numbers") {
+		t.Errorf("want the generator's error, got %v", err)
+	}
+}
+
+func TestEdgeValues(t *testing.T) {
+	twelve := "12"
+	fk := &ForeignKey{Table: "users", Column: "id"}
+	cases := []struct {
+		fc   *fakeColumn
+		want func([]interface{}) bool
+	}{
+		{&fakeColumn{col: Column{Name: "user_id", Type: "bigint", Nullable: true, ForeignKey: fk}}, func(v []interface{}) bool { return len(v) == 1 && v[0] == nil }},
+		{&fakeColumn{col: Column{Name: "user_id", Type: "bigint", ForeignKey: fk}}, func(v []interface{}) bool { return len(v) == 0 }},
+		{&fakeColumn{col: Column{Name: "qty", Type: "smallint"}}, func(v []interface{}) bool { return len(v) == 3 && v[0] == "-32768" && v[2] == "32767" }},
+		{&fakeColumn{col: Column{Name: "qty", Type: "smallint", Unsigned: true}}, func(v []interface{}) bool { return len(v) == 2 && v[0] == "0" && v[1] == "65535" }},
+		{&fakeColumn{col: Column{Name: "meta", Type: "jsonb", Nullable: true}}, func(v []interface{}) bool { return len(v) == 2 && v[0] == "{}" && v[1] == nil }},
+		{&fakeColumn{col: Column{Name: "code", Type: "varchar", Varchar: &twelve}}, func(v []interface{}) bool { return len(v) > 0 && utf8.RuneCountInString(v[0].(string)) == 12 }},
+		{&fakeColumn{col: Column{Name: "email", Type: "text", IsUnique: true}, unique: true}, func(v []interface{}) bool { return len(v) == 0 }},
+		{&fakeColumn{col: Column{Name: "plan", Type: "text", AllowedValues: []string{"free"}}}, func(v []interface{}) bool { return len(v) == 0 }},
+	}
+	for _, c := range cases {
+		if got := edgeValues(c.fc); !c.want(got) {
+			t.Errorf("%s %s: got %v", c.fc.col.Name, c.fc.col.Type, got)
+		}
+	}
+}
+
+func TestFakeGenCell_clean(t *testing.T) {
+	g := &fakeGen{rng: rand.New(rand.NewSource(1)), run: "r", clean: true}
+	refs := []interface{}{int64(1), int64(2)}
+	owner := &fakeColumn{col: Column{Name: "user_id", Type: "bigint", Nullable: true, ForeignKey: &ForeignKey{Table: "users", Column: "id"}}, refs: refs}
+	note := &fakeColumn{col: Column{Name: "note", Type: "text", Nullable: true}}
+	for n := 1; n <= 200; n++ {
+		tpl := rowtemplate.New(g.rng.Intn)
+		if mustCell(t, g, "orders", owner, n, tpl) == nil || mustCell(t, g, "orders", note, n, tpl) == nil {
+			t.Fatalf("row %d: clean generation drew a NULL", n)
+		}
+	}
+}
+
+func TestFakeGenCell_customUniqueIgnoresCase(t *testing.T) {
+	g := &fakeGen{rng: rand.New(rand.NewSource(1)), run: "r"}
+	calls := 0
+	shouting := func(r generators.Request) (string, error) {
+		calls++
+		if calls%2 == 0 {
+			return fmt.Sprintf("USER%d@EXAMPLE.COM", r.Row), nil
+		}
+		return fmt.Sprintf("user%d@example.com", r.Row+calls), nil
+	}
+	col := Column{Name: "email", Type: "citext", UniqueIgnoreCase: true}
+	fc := &fakeColumn{col: col, unique: true, custom: shouting, rng: rand.New(rand.NewSource(7))}
+	tpl := rowtemplate.New(g.rng.Intn)
+	first := mustCell(t, g, "users", fc, 1, tpl).(string) // user2@example.com
+	second := mustCell(t, g, "users", fc, 2, tpl).(string)
+	if strings.EqualFold(first, second) {
+		t.Fatalf("got %q and %q, which differ only in case", first, second)
+	}
+
+	fc = &fakeColumn{col: col, unique: true, rng: rand.New(rand.NewSource(7)),
+		custom: func(r generators.Request) (string, error) { return "Same@example.com", nil }}
+	mustCell(t, g, "users", fc, 1, tpl)
+	fc.custom = func(r generators.Request) (string, error) { return "same@EXAMPLE.com", nil }
+	if _, err := g.cell("users", fc, 2, tpl); err == nil || !strings.Contains(err.Error(), "only in case") {
+		t.Errorf("want a repeated-value error, got %v", err)
+	}
+}
+
+func TestFakeDecimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	two, zero := 2, 0
+	for i := 0; i < 200; i++ {
+		if v := fakeDecimal(r, 4, &two); len(v) > len("99.99") || !strings.Contains(v, ".") {
+			t.Fatalf("decimal(4,2): got %q", v)
+		}
+		if v := fakeDecimal(r, 2, &two); !strings.HasPrefix(v, "0.") {
+			t.Fatalf("decimal(2,2): got %q", v)
+		}
+		if v := fakeDecimal(r, 12, &zero); strings.Contains(v, ".") || len(v) > 3 {
+			t.Fatalf("decimal(12,0): got %q", v)
+		}
+	}
+}
+
+func mustCell(t *testing.T, g *fakeGen, table string, fc *fakeColumn, n int, tpl rowtemplate.Template) interface{} {
+	t.Helper()
+	v, err := g.cell(table, fc, n, tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestFakeGenRow_consistent(t *testing.T) {
+	g := &fakeGen{rng: rand.New(rand.NewSource(2)), run: "r"}
+	two := "2"
+	cols := []*fakeColumn{
+		{col: Column{Name: "first_name", Type: "text"}},
+		{col: Column{Name: "last_name", Type: "text"}},
+		{col: Column{Name: "email", Type: "text"}},
+		{col: Column{Name: "city", Type: "text"}},
+		{col: Column{Name: "country", Type: "varchar", Varchar: &two}},
+		{col: Column{Name: "start_date", Type: "date"}},
+		{col: Column{Name: "end_date", Type: "date"}},
+	}
+	ordered := map[int]int{6: 5}
+	for n := 1; n <= 50; n++ {
+		tpl := rowtemplate.New(g.rng.Intn)
+		row := make([]interface{}, len(cols))
+		for i, fc := range cols {
+			row[i] = mustCell(t, g, "users", fc, n, tpl)
+		}
+		g.orderRow(row, cols, ordered)
+
+		first, last, email := row[0], row[1], row[2]
+		if first != nil && last != nil && email != nil {
+			want := strings.ToLower(first.(string) + "." + last.(string))
+			if !strings.HasPrefix(email.(string), want) {
+				t.Fatalf("row %d: email %q doesn't match %v %v", n, email, first, last)
+			}
+		}
+		if city, country := row[3], row[4]; city != nil && country != nil {
+			if country != tpl.Locale.CountryCode || city != tpl.Locale.City {
+				t.Fatalf("row %d: %v, %v is not %+v", n, city, country, tpl.Locale)
+			}
+		}
+		if start, end := row[5], row[6]; start != nil && end != nil && end.(string) <= start.(string) {
+			t.Fatalf("row %d: end_date %v is not after start_date %v", n, end, start)
+		}
+	}
+}
+
+func TestFakeText_locale(t *testing.T) {
+	de, err := rowtemplate.Lookup("de_DE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(1))
+	tpl := de.New(r.Intn)
+	if got := fakeText(r, tpl, "users", "name", 1); got != tpl.FullName() {
+		t.Errorf("users.name = %q, want %q", got, tpl.FullName())
+	}
+	if got := fakeText(r, tpl, "accounts", "company", 1); !containsString(de.Companies, got) {
+		t.Errorf("company = %q, not one of the de_DE companies", got)
+	}
+	if got := fakeText(r, tpl, "posts", "body", 1); !strings.HasSuffix(got, ".") || !utf8.ValidString(got) {
+		t.Errorf("body = %q, want a sentence", got)
+	}
+	for _, column := range []string{"slug", "sku"} {
+		for _, c := range fakeText(r, tpl, "products", column, 1) {
+			if c > 127 {
+				t.Errorf("%s = %q, want ASCII", column, fakeText(r, tpl, "products", column, 1))
+				break
+			}
+		}
+	}
+}
+
+func TestFilledByDatabase(t *testing.T) {
+	for _, c := range []struct {
+		col  Column
+		want bool
+	}{
+		{Column{Name: "id", Type: "integer", Default: "nextval('users_id_seq'::regclass)"}, true},
+		{Column{Name: "id", Type: "bigint", Identity: "ALWAYS"}, true},
+		{Column{Name: "total", Type: "numeric", IsGenerated: true}, true},
+		{Column{Name: "id", Type: "bigserial"}, true},
+		{Column{Name: "name", Type: "text", Default: "'x'"}, false},
+	} {
+		if got := filledByDatabase(c.col); got != c.want {
+			t.Errorf("%+v: got %v, want %v", c.col, got, c.want)
+		}
+	}
+}
+
+func TestGenerateFakeDataInMemory(t *testing.T) {
+	schema := &Schema{Tables: []Table{
+		{Name: "orders", Columns: []Column{
+			{Name: "id", Type: "bigint", IsPrimary: true, Default: "nextval('orders_id_seq'::regclass)"},
+			{Name: "user_id", Type: "integer", ForeignKey: &ForeignKey{Table: "users", Column: "id"}},
+			{Name: "total", Type: "numeric"},
+			{Name: "label", Type: "text", IsGenerated: true},
+		}},
+		{Name: "users", Columns: []Column{
+			{Name: "id", Type: "serial", IsPrimary: true},
+			{Name: "email", Type: "varchar", IsUnique: true},
+		}},
+	}}
+	opts := FakeOptions{Rows: 5, Seed: 3, Clean: true}
+	tables, err := GenerateFakeDataInMemory(schema, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 || tables[0].Table != "users" || tables[1].Table != "orders" {
+		t.Fatalf("tables = %+v, want users then orders", tables)
+	}
+	users, orders := tables[0], tables[1]
+	if got := strings.Join(orders.Columns, ","); got != "id,user_id,total" {
+		t.Errorf("orders columns = %s; the generated column should be left out", got)
+	}
+	ids := map[interface{}]bool{}
+	for i, row := range users.Rows {
+		if row["id"] != int64(i+1) {
+			t.Errorf("users row %d: id = %v, want %d", i, row["id"], i+1)
+		}
+		ids[row["id"]] = true
+	}
+	for _, row := range orders.Rows {
+		if !ids[row["user_id"]] {
+			t.Errorf("order references missing user %v", row["user_id"])
+		}
+	}
+
+	again, _ := GenerateFakeDataInMemory(schema, opts)
+	if fmt.Sprint(again) != fmt.Sprint(tables) {
+		t.Error("the same seed made different rows")
+	}
+
+	if _, err := GenerateFakeDataInMemory(schema, FakeOptions{Rows: 2, Tables: []string{"orders"}}); err == nil {
+		t.Error("orders without users: want an error, its NOT NULL user_id has nothing to reference")
+	}
+}
+
+func TestGenerateFakeDataInMemory_fkPicks(t *testing.T) {
+	schema := &Schema{Tables: []Table{
+		{Name: "users", Columns: []Column{{Name: "id", Type: "serial", IsPrimary: true}}},
+		{Name: "orders", Columns: []Column{
+			{Name: "id", Type: "serial", IsPrimary: true},
+			{Name: "user_id", Type: "integer", ForeignKey: &ForeignKey{Table: "users", Column: "id"}},
+		}},
+	}}
+	busiest := func(pick string) (top, parents int) {
+		t.Helper()
+		tables, err := GenerateFakeDataInMemory(schema, FakeOptions{
+			Rows: 100, Seed: 5, Clean: true,
+			FKPick: func(string, string) string { return pick },
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", pick, err)
+		}
+		children := map[interface{}]int{}
+		for _, row := range tables[1].Rows {
+			children[row["user_id"]]++
+		}
+		for _, n := range children {
+			top = max(top, n)
+		}
+		return top, len(children)
+	}
+
+	if top, _ := busiest(utils.FKPickUniform); top > 10 {
+		t.Errorf("uniform: busiest user has %d of 100 orders", top)
+	}
+	if top, _ := busiest(utils.FKPickZipfian); top < 15 {
+		t.Errorf("zipfian: busiest user has only %d of 100 orders", top)
+	}
+	if top, parents := busiest(utils.FKPickOneToOne); top != 1 || parents != 100 {
+		t.Errorf("one-to-one: %d users, busiest with %d orders; want 100 with 1 each", parents, top)
+	}
+}
+
+func TestGenerateFake_integerRange(t *testing.T) {
+	one := 1
+	schema := &Schema{Tables: []Table{{Name: "flags", Columns: []Column{
+		{Name: "id", Type: "tinyint", IsPrimary: true},
+		{Name: "level", Type: "smallint"},
+		{Name: "active", Type: "tinyint", Precision: &one},
+	}}}}
+	tables, err := GenerateFakeDataInMemory(schema, FakeOptions{Rows: 127, Seed: 1, Clean: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range tables[0].Rows {
+		if id := row["id"].(int64); id < 1 || id > 127 {
+			t.Fatalf("id %d is outside tinyint", id)
+		}
+		if a := row["active"]; a != "0" && a != "1" {
+			t.Fatalf("tinyint(1) active = %v, want 0 or 1", a)
+		}
+	}
+
+	_, err = GenerateFakeDataInMemory(schema, FakeOptions{Rows: 128, Seed: 1})
+	if err == nil || !strings.Contains(err.Error(), "maximum of 127") {
+		t.Fatalf("128 tinyint keys: err = %v, want the maximum named", err)
+	}
+	schema.Tables[0].Columns[0].Unsigned = true
+	if _, err := GenerateFakeDataInMemory(schema, FakeOptions{Rows: 200, Seed: 1}); err != nil {
+		t.Fatalf("200 tinyint unsigned keys: %v", err)
+	}
+}
+
+func TestIntegerRange(t *testing.T) {
+	cases := []struct {
+		col    Column
+		lo, hi int64
+	}{
+		{Column{Type: "smallint"}, -32768, 32767},
+		{Column{Type: "smallint", Unsigned: true}, 0, 65535},
+		{Column{Type: "mediumint"}, -8388608, 8388607},
+		{Column{Type: "integer"}, -2147483648, 2147483647},
+		{Column{Type: "int", Unsigned: true}, 0, 4294967295},
+		{Column{Type: "bigint", Unsigned: true}, 0, math.MaxInt64},
+	}
+	for _, c := range cases {
+		if lo, hi := integerRange(c.col); lo != c.lo || hi != c.hi {
+			t.Errorf("%s unsigned=%v: got %d..%d, want %d..%d", c.col.Type, c.col.Unsigned, lo, hi, c.lo, c.hi)
+		}
+	}
+}
+
+func TestFakeGenLabel_coversEveryLabelFirst(t *testing.T) {
+	g := &fakeGen{
+		rng:     rand.New(rand.NewSource(2)),
+		weights: func(string, string) map[string]float64 { return map[string]float64{"open": 1} },
+	}
+	fc := &fakeColumn{col: Column{Name: "status", Type: "text"}}
+	if err := g.coverLabels(fc, "tickets", []string{"open", "closed", "escalated"}); err != nil {
+		t.Fatal(err)
+	}
+	first := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		first[g.label(fc)] = true
+	}
+	if len(first) != 3 {
+		t.Errorf("first three draws = %v, want each label once", first)
+	}
+	for i := 0; i < 20; i++ {
+		if v := g.label(fc); v != "open" {
+			t.Fatalf("after the first round, draw %d = %q; only open has a weight", i, v)
+		}
+	}
+
+	if err := g.coverLabels(&fakeColumn{col: Column{Name: "status"}}, "tickets", []string{"closed"}); err == nil {
+		t.Error("a weight for a label the column lacks: want an error")
+	}
+}
@@ -0,0 +1,249 @@
+package db
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Fixture holds the rows of a fixture keyed by table name. Each row maps
+// column name to a value converted according to schema.json:
+//
+//	integer types        int64
+//	numeric/float types  float64
+//	boolean              bool
+//	date/time types      time.Time
+//	json, jsonb          the decoded value (map[string]any, []any, …)
+//	PostgreSQL arrays    []string
+//	bytea, blob, binary  []byte
+//	everything else      string (text, uuid, enums, …)
+//
+// NULL cells are nil. @env: markers and SQL expression cells (=DEFAULT,
+// =expr(…)) are returned as written.
+type Fixture map[string][]map[string]any
+
+// LoadFixture reads a flat fixture directory — schema.json plus one
+// <table>.csv per table, as bundle, embed and the restore staging dir
+// lay it out — into a Fixture.
+func LoadFixture(dir string) (Fixture, error) {
+	return loadFixture(filepath.Join(dir, "schema.json"), dir)
+}
+
+// LoadRevision reads a revision of scenario from the local store of the
+// project rooted at projectRoot into a Fixture. An empty projectRoot
+// searches upwards from the working directory for seedmancer.yaml, and
+// an empty revision means the scenario's latest, so a test inside the
+// project can call
+//
+//	fx, err := db.LoadRevision("", "billing/pro", "")
+func LoadRevision(projectRoot, scenarioName, revision string) (Fixture, error) {
+	configPath := filepath.Join(projectRoot, "seedmancer.yaml")
+	if projectRoot == "" {
+		var err error
+		if configPath, err = utils.FindConfigFile(); err != nil {
+			return nil, err
+		}
+		projectRoot = filepath.Dir(configPath)
+	}
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	scenarioPath, err := scenario.Normalize(scenarioName)
+	if err != nil {
+		return nil, err
+	}
+	if revision == "" {
+		manifest, err := scenario.ReadManifest(scenario.ScenarioDir(projectRoot, cfg.StoragePath, scenarioPath))
+		if err != nil {
+			return nil, fmt.Errorf("scenario %q: %v", scenarioPath, err)
+		}
+		if manifest.Latest == "" {
+			return nil, fmt.Errorf("scenario %q has no revisions yet", scenarioPath)
+		}
+		revision = manifest.Latest
+	}
+	revManifest, err := scenario.ReadRevisionManifest(scenario.RevisionDir(projectRoot, cfg.StoragePath, scenarioPath, revision))
+	if err != nil {
+		return nil, fmt.Errorf("scenario %q revision %s: %v", scenarioPath, revision, err)
+	}
+	schemaPath := scenario.SchemaJSONPath(projectRoot, cfg.StoragePath, utils.FingerprintShort(revManifest.SchemaFingerprint))
+	return loadFixture(schemaPath, scenario.RevisionDataDir(projectRoot, cfg.StoragePath, scenarioPath, revision))
+}
+
+// loadFixture converts every table CSV in dataDir that schemaPath
+// describes. Tables without a CSV are left out.
+func loadFixture(schemaPath, dataDir string) (Fixture, error) {
+	raw, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema.json: %v", err)
+	}
+	schema, err := parseSchema(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing schema.json: %v", err)
+	}
+
+	fx := Fixture{}
+	for _, table := range schema.Tables {
+		rows, err := loadFixtureTable(table, filepath.Join(dataDir, table.Name+".csv"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", table.Name, err)
+		}
+		fx[table.Name] = rows
+	}
+	return fx, nil
+}
+
+func loadFixtureTable(table Table, csvPath string) ([]map[string]any, error) {
+	f, err := csvshard.Open(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	columns := make(map[string]Column, len(table.Columns))
+	for _, col := range table.Columns {
+		columns[col.Name] = col
+	}
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err == io.EOF {
+		return []map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []map[string]any{}
+	for n := 1; ; n++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(header))
+		for i, name := range header {
+			if i >= len(record) {
+				break
+			}
+			v, err := fixtureValue(columns[name], record[i])
+			if err != nil {
+				return nil, fmt.Errorf("row %d, column %s: %v", n, name, err)
+			}
+			row[name] = v
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// fixtureValue converts one CSV cell to the Go type for col. Cells that
+// don't parse as their column type are an error rather than a silent
+// string, so a broken fixture fails the test that loads it.
+func fixtureValue(col Column, cell string) (any, error) {
+	if cell == "NULL" || cell == "null" {
+		return nil, nil
+	}
+	if cellexpr.Is(cell) {
+		return cell, nil
+	}
+	typ := strings.ToLower(strings.TrimSpace(col.Type))
+	if i := strings.Index(typ, "("); i > 0 {
+		typ = strings.TrimSpace(typ[:i])
+	}
+	isText := typ == "" || typ == "text" || typ == "char" || typ == "character" ||
+		strings.Contains(typ, "char") || typ == "enum" || typ == "uuid"
+	if cell == "" && !isText {
+		return nil, nil
+	}
+
+	switch {
+	case isText:
+		return cell, nil
+	case typ == "boolean" || typ == "bool":
+		switch strings.ToLower(cell) {
+		case "true", "t", "yes", "y", "1":
+			return true, nil
+		case "false", "f", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", cell)
+	case integerTypes[typ]:
+		n, err := strconv.ParseInt(cell, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", cell)
+		}
+		return n, nil
+	case typ == "numeric" || typ == "decimal" || typ == "real" || typ == "money" ||
+		strings.Contains(typ, "double") || strings.Contains(typ, "float"):
+		f, err := strconv.ParseFloat(strings.TrimPrefix(cell, "$"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", cell)
+		}
+		return f, nil
+	case typ == "json" || typ == "jsonb":
+		var v any
+		if err := json.Unmarshal([]byte(cell), &v); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return v, nil
+	case typ == "array" || strings.HasSuffix(typ, "[]"):
+		return fixtureArray(cell), nil
+	case binaryTypes[typ]:
+		b, ok := decodeBinaryCell(cell)
+		if !ok {
+			return nil, fmt.Errorf("%q is not \\x followed by hex digits", cell)
+		}
+		return b, nil
+	case strings.HasPrefix(typ, "time") || typ == "date" || typ == "datetime":
+		for _, layout := range []string{
+			time.RFC3339Nano,
+			"2006-01-02 15:04:05.999999999Z07:00",
+			"2006-01-02 15:04:05.999999999Z07",
+			"2006-01-02 15:04:05.999999999 -0700 MST",
+			"2006-01-02 15:04:05.999999999",
+			"2006-01-02T15:04:05.999999999",
+			"2006-01-02",
+			"15:04:05.999999999",
+		} {
+			if t, err := time.Parse(layout, cell); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a recognised date or time", cell)
+	}
+	return cell, nil
+}
+
+// fixtureArray splits a PostgreSQL array literal such as {a,"b c"} into
+// its elements.
+func fixtureArray(cell string) []string {
+	inner := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(cell), "{"), "}")
+	if inner == "" {
+		return []string{}
+	}
+	elems := parseArrayString(inner)
+	for i, e := range elems {
+		if len(e) >= 2 && e[0] == '"' && e[len(e)-1] == '"' {
+			e = strings.ReplaceAll(e[1:len(e)-1], `\"`, `"`)
+		}
+		elems[i] = e
+	}
+	return elems
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/scenario"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func TestLoadFixture_convertsBySchema(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{"databaseType":"postgres","enums":[],"tables":[
+	  {"name":"users","columns":[
+	    {"name":"id","type":"integer","isPrimary":true},
+	    {"name":"email","type":"character varying"},
+	    {"name":"score","type":"numeric"},
+	    {"name":"active","type":"boolean"},
+	    {"name":"created_at","type":"timestamp with time zone"},
+	    {"name":"prefs","type":"jsonb","nullable":true},
+	    {"name":"tags","type":"ARRAY","nullable":true},
+	    {"name":"nickname","type":"text","nullable":true}
+	  ]},
+	  {"name":"absent","columns":[{"name":"id","type":"integer"}]}
+	]}`
+	csv := "id,email,score,active,created_at,prefs,tags,nickname\n" +
+		`1,ada@example.com,4.5,t,2024-01-02 03:04:05+00,"{""theme"":""dark""}","{a,""b c""}",` + "\n" +
+		"2,bob@example.com,NULL,false,2024-01-03,NULL,NULL,NULL\n"
+	writeFixtureFile(t, dir, "schema.json", schema)
+	writeFixtureFile(t, dir, "users.csv", csv)
+
+	fx, err := LoadFixture(dir)
+	if err != nil {
+		t.Fatalf("LoadFixture: %v", err)
+	}
+	if _, ok := fx["absent"]; ok {
+		t.Error("table without a CSV should be left out")
+	}
+	users := fx["users"]
+	if len(users) != 2 {
+		t.Fatalf("users rows = %d, want 2", len(users))
+	}
+	want := map[string]any{
+		"id":         int64(1),
+		"email":      "ada@example.com",
+		"score":      4.5,
+		"active":     true,
+		"created_at": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"prefs":      map[string]any{"theme": "dark"},
+		"tags":       []string{"a", "b c"},
+		"nickname":   "",
+	}
+	for col, w := range want {
+		got := users[0][col]
+		if tm, ok := got.(time.Time); ok {
+			got = tm.UTC()
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("users[0][%s] = %#v, want %#v", col, got, w)
+		}
+	}
+	for _, col := range []string{"score", "prefs", "tags", "nickname"} {
+		if users[1][col] != nil {
+			t.Errorf("users[1][%s] = %#v, want nil", col, users[1][col])
+		}
+	}
+}
+
+func TestLoadFixture_badCellFails(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFile(t, dir, "schema.json", `{"tables":[{"name":"t","columns":[{"name":"n","type":"bigint"}]}]}`)
+	writeFixtureFile(t, dir, "t.csv", "n\nseven\n")
+	if _, err := LoadFixture(dir); err == nil {
+		t.Fatal("a non-integer in a bigint column should fail")
+	}
+}
+
+func TestLoadFixture_joinsShards(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFile(t, dir, "schema.json", `{"tables":[{"name":"t","columns":[{"name":"n","type":"bigint"}]}]}`)
+	writeFixtureFile(t, dir, "t.csv.001", "n\n1\n2\n")
+	writeFixtureFile(t, dir, "t.csv.002", "n\n3\n")
+	fx, err := LoadFixture(dir)
+	if err != nil {
+		t.Fatalf("LoadFixture: %v", err)
+	}
+	if len(fx["t"]) != 3 || fx["t"][2]["n"] != int64(3) {
+		t.Fatalf("t = %v, want the rows of both shards", fx["t"])
+	}
+}
+
+func TestLoadRevision_resolvesLatestFromTheStore(t *testing.T) {
+	root := t.TempDir()
+	writeFixtureFile(t, root, "seedmancer.yaml", "storage_path: .seedmancer\n")
+	fp := "abcdef0123456789abcdef0123456789"
+	schemaDir := scenario.SchemaStoreDir(root, ".seedmancer", utils.FingerprintShort(fp))
+	dataDir := scenario.RevisionDataDir(root, ".seedmancer", "billing/pro", "r002")
+	for _, d := range []string{schemaDir, dataDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFixtureFile(t, schemaDir, "schema.json", `{"tables":[{"name":"plans","columns":[{"name":"id","type":"integer"}]}]}`)
+	writeFixtureFile(t, dataDir, "plans.csv", "id\n7\n")
+	scenarioDir := scenario.ScenarioDir(root, ".seedmancer", "billing/pro")
+	if err := scenario.WriteManifest(scenarioDir, scenario.Manifest{Scenario: "billing/pro", Latest: "r002"}); err != nil {
+		t.Fatal(err)
+	}
+	revDir := scenario.RevisionDir(root, ".seedmancer", "billing/pro", "r002")
+	if err := scenario.WriteRevisionManifest(revDir, scenario.RevisionManifest{Scenario: "billing/pro", Revision: "r002", SchemaFingerprint: fp}); err != nil {
+		t.Fatal(err)
+	}
+
+	fx, err := LoadRevision(root, "billing/pro", "")
+	if err != nil {
+		t.Fatalf("LoadRevision: %v", err)
+	}
+	if got := fx["plans"]; len(got) != 1 || got[0]["id"] != int64(7) {
+		t.Fatalf("plans = %v, want one row with id 7", got)
+	}
+}
+
+func writeFixtureFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFixture_keepsExpressionCells(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFile(t, dir, "schema.json", `{"tables":[{"name":"t","columns":[{"name":"n","type":"bigint"},{"name":"at","type":"timestamp"}]}]}`)
+	writeFixtureFile(t, dir, "t.csv", "n,at\n=DEFAULT,=expr(now() - interval '3 days')\n")
+	fx, err := LoadFixture(dir)
+	if err != nil {
+		t.Fatalf("LoadFixture: %v", err)
+	}
+	if got := fx["t"][0]; got["n"] != "=DEFAULT" || got["at"] != "=expr(now() - interval '3 days')" {
+		t.Errorf("row = %#v, want the expressions as written", got)
+	}
+}
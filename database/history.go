@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HistoryTable is the table where every seed records what it loaded into
+// a database. Unlike the restore journal it lives next to the seeded
+// tables (in the target schema on PostgreSQL, the target database on
+// MySQL), so whoever can read the data can also see which fixture it
+// came from. Export, schema extraction and status skip it.
+const HistoryTable = "_seedmancer_history"
+
+// SeedRecord is one row of HistoryTable: which scenario revision a seed
+// loaded, who ran it, when, and how many rows it loaded per table.
+type SeedRecord struct {
+	Scenario   string         `json:"scenario"`
+	Revision   string         `json:"revision"`
+	Checksum   string         `json:"checksum,omitempty"`
+	Operator   string         `json:"operator,omitempty"`
+	StartedAt  time.Time      `json:"startedAt"`
+	FinishedAt time.Time      `json:"finishedAt"`
+	Rows       int            `json:"rows"`
+	RowCounts  map[string]int `json:"rowCounts,omitempty"`
+}
+
+// historyDialect is what differs between the engines in the history table.
+type historyDialect struct {
+	create string
+	// exists counts HistoryTable in the current schema or database.
+	exists      string
+	placeholder func(n int) string
+}
+
+var postgresHistory = historyDialect{
+	create: `CREATE TABLE IF NOT EXISTS _seedmancer_history (
+  id BIGINT PRIMARY KEY,
+  scenario VARCHAR(255) NOT NULL,
+  revision VARCHAR(64) NOT NULL,
+  checksum VARCHAR(128),
+  operator VARCHAR(255),
+  started_at TIMESTAMP NOT NULL,
+  finished_at TIMESTAMP NOT NULL,
+  row_count BIGINT NOT NULL,
+  row_counts TEXT
+)`,
+	exists:      "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = '_seedmancer_history'",
+	placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+}
+
+var mysqlHistory = historyDialect{
+	create: "CREATE TABLE IF NOT EXISTS `_seedmancer_history` (\n" +
+		"  id BIGINT PRIMARY KEY,\n" +
+		"  scenario VARCHAR(255) NOT NULL,\n" +
+		"  revision VARCHAR(64) NOT NULL,\n" +
+		"  checksum VARCHAR(128),\n" +
+		"  operator VARCHAR(255),\n" +
+		"  started_at DATETIME(3) NOT NULL,\n" +
+		"  finished_at DATETIME(3) NOT NULL,\n" +
+		"  row_count BIGINT NOT NULL,\n" +
+		"  row_counts TEXT\n" +
+		")",
+	exists:      "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = '_seedmancer_history'",
+	placeholder: func(int) string { return "?" },
+}
+
+// recordSeed creates HistoryTable when missing and appends rec to it.
+// FinishedAt is stamped here when unset; times are stored in UTC.
+func recordSeed(ctx context.Context, q sqlExecer, d historyDialect, rec SeedRecord) error {
+	if rec.FinishedAt.IsZero() {
+		rec.FinishedAt = time.Now()
+	}
+	if rec.StartedAt.IsZero() {
+		rec.StartedAt = rec.FinishedAt
+	}
+	if _, err := q.ExecContext(ctx, d.create); err != nil {
+		return fmt.Errorf("creating %s: %v", HistoryTable, err)
+	}
+	var counts interface{}
+	if len(rec.RowCounts) > 0 {
+		raw, err := json.Marshal(rec.RowCounts)
+		if err != nil {
+			return err
+		}
+		counts = string(raw)
+	}
+	ph := d.placeholder
+	insert := fmt.Sprintf(
+		"INSERT INTO _seedmancer_history (id, scenario, revision, checksum, operator, started_at, finished_at, row_count, row_counts) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)",
+		ph(1), ph(2), ph(3), ph(4), ph(5), ph(6), ph(7), ph(8), ph(9))
+	_, err := q.ExecContext(ctx, insert, time.Now().UnixNano(), rec.Scenario, rec.Revision,
+		nullIfEmpty(rec.Checksum), nullIfEmpty(rec.Operator),
+		rec.StartedAt.UTC(), rec.FinishedAt.UTC(), rec.Rows, counts)
+	if err != nil {
+		return fmt.Errorf("recording seed in %s: %v", HistoryTable, err)
+	}
+	return nil
+}
+
+// readSeedHistory returns the limit most recent seeds recorded in the
+// current schema or database, newest first. A database that was never
+// seeded has no history table and returns none.
+func readSeedHistory(ctx context.Context, db *sql.DB, d historyDialect, limit int) ([]SeedRecord, error) {
+	var n int
+	if err := db.QueryRowContext(ctx, d.exists).Scan(&n); err != nil {
+		return nil, fmt.Errorf("looking for %s: %v", HistoryTable, err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT scenario, revision, COALESCE(checksum, ''), COALESCE(operator, ''), started_at, finished_at, row_count, COALESCE(row_counts, '')
+FROM _seedmancer_history ORDER BY id DESC LIMIT %d`, limit))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", HistoryTable, err)
+	}
+	defer rows.Close()
+	var records []SeedRecord
+	for rows.Next() {
+		var rec SeedRecord
+		var counts string
+		if err := rows.Scan(&rec.Scenario, &rec.Revision, &rec.Checksum, &rec.Operator,
+			&rec.StartedAt, &rec.FinishedAt, &rec.Rows, &counts); err != nil {
+			return nil, fmt.Errorf("reading %s: %v", HistoryTable, err)
+		}
+		if counts != "" {
+			if err := json.Unmarshal([]byte(counts), &rec.RowCounts); err != nil {
+				return nil, fmt.Errorf("reading %s: row counts: %v", HistoryTable, err)
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// withoutHistoryTable drops HistoryTable from tables read off a live
+// database, so it never ends up in an exported schema or fixture.
+func withoutHistoryTable(tables []Table) []Table {
+	kept := tables[:0]
+	for _, t := range tables {
+		if t.Name != HistoryTable {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,235 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/ui"
+)
+
+// The restore journal is a small table where every restore records
+// itself while it runs: the schema or database it restores into, the
+// server session doing the work and, on MySQL, the tables it created. A
+// row still marked running when the next restore of the same target
+// starts belongs to a restore that was killed part way, and is recovered
+// before the new one changes anything (see beginRestoreJournal). A restore
+// opens its entry only once its preflight checks have passed, so one
+// refused up front leaves nothing behind.
+//
+// The table lives in its own "seedmancer" schema (PostgreSQL) or database
+// (MySQL), so it never shows up in an export, a fingerprint or a diff.
+// Creating it is best effort: a role without the privilege restores as
+// before, just without recovery.
+const journalTable = "seedmancer.restore_journal"
+
+// journalStaleAfter is how long the session of an unfinished restore has
+// to sit idle before a new restore takes it for abandoned and ends it.
+// A session that is still busy, or idle for less, may be a restore that
+// is running right now.
+const journalStaleAfter = time.Minute
+
+// journalDialect is what differs between the engines in the journal.
+type journalDialect struct {
+	// create makes the journal schema and table if they are missing.
+	create []string
+	// insert records a running restore; its arguments are id, target and
+	// session.
+	insert string
+	// session reports whether the session of journal entry id is still
+	// connected and how long it has been idle.
+	session string
+	// terminate ends a server session.
+	terminate   func(ctx context.Context, db *sql.DB, session int64) error
+	placeholder func(n int) string
+}
+
+var postgresJournal = journalDialect{
+	create: []string{
+		"CREATE SCHEMA IF NOT EXISTS seedmancer",
+		`CREATE TABLE IF NOT EXISTS seedmancer.restore_journal (
+  id BIGINT PRIMARY KEY,
+  target VARCHAR(255) NOT NULL,
+  session_id BIGINT NOT NULL,
+  session_started TIMESTAMPTZ,
+  status VARCHAR(16) NOT NULL,
+  created_tables TEXT,
+  started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP NULL
+)`,
+	},
+	// backend_start tells a reused pid apart from the session that wrote
+	// the entry.
+	insert: `INSERT INTO seedmancer.restore_journal (id, target, session_id, session_started, status)
+SELECT $1, $2, $3, (SELECT backend_start FROM pg_stat_activity WHERE pid = $3::int), 'running'`,
+	session: `SELECT a.state IN ('idle', 'idle in transaction', 'idle in transaction (aborted)'),
+       COALESCE(EXTRACT(EPOCH FROM now() - a.state_change), 0)::bigint
+FROM pg_stat_activity a
+JOIN seedmancer.restore_journal j ON j.session_id = a.pid AND j.session_started = a.backend_start
+WHERE j.id = $1`,
+	terminate: func(ctx context.Context, db *sql.DB, session int64) error {
+		_, err := db.ExecContext(ctx, "SELECT pg_terminate_backend($1)", session)
+		return err
+	},
+	placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+}
+
+var mysqlJournal = journalDialect{
+	create: []string{
+		"CREATE DATABASE IF NOT EXISTS seedmancer",
+		`CREATE TABLE IF NOT EXISTS seedmancer.restore_journal (
+  id BIGINT PRIMARY KEY,
+  target VARCHAR(255) NOT NULL,
+  session_id BIGINT NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  created_tables TEXT,
+  started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP NULL
+)`,
+	},
+	insert: "INSERT INTO seedmancer.restore_journal (id, target, session_id, status) VALUES (?, ?, ?, 'running')",
+	// Connection ids only grow, so a live id is the session that wrote
+	// the entry.
+	session: `SELECT p.COMMAND = 'Sleep', p.TIME
+FROM information_schema.PROCESSLIST p
+JOIN seedmancer.restore_journal j ON j.session_id = p.ID
+WHERE j.id = ?`,
+	terminate: func(ctx context.Context, db *sql.DB, session int64) error {
+		_, err := db.ExecContext(ctx, fmt.Sprintf("KILL %d", session))
+		return err
+	},
+	placeholder: func(int) string { return "?" },
+}
+
+// restoreJournal is the journal entry of the restore in progress. A nil
+// *restoreJournal (no journal on this database) ignores every call.
+type restoreJournal struct {
+	db      *sql.DB
+	d       journalDialect
+	id      int64
+	created []string
+}
+
+// interruptedRestore is a journal entry left running by a restore that
+// never finished.
+type interruptedRestore struct {
+	id      int64
+	session int64
+	// created are the tables it created, oldest first.
+	created []string
+}
+
+// beginRestoreJournal records a restore of target running on session.
+// Any earlier restore of target that never finished is dealt with first:
+// its session is ended if it is still connected and has sat idle for
+// journalStaleAfter (it is refused as a concurrent restore otherwise),
+// then recover is called to undo its partial work and the entry is
+// closed. A journal that can't be created is logged and skipped.
+func beginRestoreJournal(ctx context.Context, db *sql.DB, d journalDialect, target string, session int64, recover func(interruptedRestore) error) (*restoreJournal, error) {
+	for _, stmt := range d.create {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			ui.Debug("restore journal unavailable, interrupted restores won't be recovered: %v", err)
+			return nil, nil
+		}
+	}
+	j := &restoreJournal{db: db, d: d}
+	ph := d.placeholder
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT id, session_id, COALESCE(created_tables, '') FROM %s WHERE target = %s AND status = 'running' ORDER BY id",
+		journalTable, ph(1)), target)
+	if err != nil {
+		return nil, fmt.Errorf("reading restore journal: %v", err)
+	}
+	var pending []interruptedRestore
+	for rows.Next() {
+		var r interruptedRestore
+		var created string
+		if err := rows.Scan(&r.id, &r.session, &created); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("reading restore journal: %v", err)
+		}
+		if created != "" {
+			r.created = strings.Split(created, ",")
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading restore journal: %v", err)
+	}
+
+	for _, r := range pending {
+		var idle bool
+		var idleFor int64
+		err := db.QueryRowContext(ctx, d.session, r.id).Scan(&idle, &idleFor)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return nil, fmt.Errorf("checking session %d of an earlier restore: %v", r.session, err)
+		case !idle || time.Duration(idleFor)*time.Second < journalStaleAfter:
+			return nil, fmt.Errorf("another restore into %s is running on session %d; if it was interrupted, run again once it has been idle for %s", target, r.session, journalStaleAfter)
+		default:
+			ui.Warn("Ending session %d left behind by an interrupted restore into %s", r.session, target)
+			if err := d.terminate(ctx, db, r.session); err != nil {
+				return nil, fmt.Errorf("ending session %d of an interrupted restore: %v", r.session, err)
+			}
+		}
+		ui.Warn("Recovering from an interrupted restore into %s", target)
+		if recover != nil {
+			if err := recover(r); err != nil {
+				return nil, fmt.Errorf("recovering from an interrupted restore: %v", err)
+			}
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(
+			"UPDATE %s SET status = 'recovered', finished_at = CURRENT_TIMESTAMP WHERE id = %s", journalTable, ph(1)), r.id); err != nil {
+			return nil, fmt.Errorf("updating restore journal: %v", err)
+		}
+	}
+
+	// Only unfinished entries matter; drop the history of this target.
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %s WHERE target = %s AND status <> 'running'", journalTable, ph(1)), target); err != nil {
+		return nil, fmt.Errorf("pruning restore journal: %v", err)
+	}
+	j.id = time.Now().UnixNano()
+	if _, err := db.ExecContext(ctx, d.insert, j.id, target, session); err != nil {
+		return nil, fmt.Errorf("writing restore journal: %v", err)
+	}
+	return j, nil
+}
+
+// createdTable records that the restore created table, so an interrupted
+// run can drop it again.
+func (j *restoreJournal) createdTable(table string) error {
+	if j == nil {
+		return nil
+	}
+	j.created = append(j.created, table)
+	_, err := j.db.Exec(fmt.Sprintf("UPDATE %s SET created_tables = %s WHERE id = %s",
+		journalTable, j.d.placeholder(1), j.d.placeholder(2)), strings.Join(j.created, ","), j.id)
+	if err != nil {
+		return fmt.Errorf("updating restore journal: %v", err)
+	}
+	return nil
+}
+
+// finish closes the entry as done, or failed when restoreErr is set.
+// Only a restore killed before getting here leaves its entry running for
+// the next one to recover.
+func (j *restoreJournal) finish(restoreErr error) {
+	if j == nil {
+		return
+	}
+	status := "done"
+	if restoreErr != nil {
+		status = "failed"
+	}
+	_, err := j.db.Exec(fmt.Sprintf("UPDATE %s SET status = %s, finished_at = CURRENT_TIMESTAMP WHERE id = %s",
+		journalTable, j.d.placeholder(1), j.d.placeholder(2)), status, j.id)
+	if err != nil {
+		ui.Debug("updating restore journal: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MariaDB is restored through MySQLManager, which handles where it
+// differs from MySQL behind isMariaDB:
+//
+//   - JSON columns are LONGTEXT with a json_valid CHECK, so
+//     information_schema reports them as longtext; the CHECK tells them
+//     apart and they are captured as json.
+//   - COLUMN_DEFAULT (10.2.7+) quotes string literals, spells a missing
+//     default as NULL and adds () to CURRENT_TIMESTAMP; defaults are
+//     normalized to MySQL's spelling so schema.json reads the same
+//     whichever engine it came from.
+//
+// Restores never read rows back from an INSERT, so MariaDB's RETURNING
+// isn't needed and both engines share the one import path.
+
+// isMariaDB reports whether m is connected to MariaDB. It is true up
+// front for mariadb:// URLs; for mysql:// URLs the server is asked once,
+// on first use.
+func (m *MySQLManager) isMariaDB() bool {
+	if m.MariaDB || m.probedMariaDB || m.DB == nil {
+		return m.MariaDB
+	}
+	m.probedMariaDB = true
+	var version string
+	if err := m.DB.QueryRow("SELECT VERSION()").Scan(&version); err == nil {
+		m.MariaDB = strings.Contains(strings.ToLower(version), "mariadb")
+	}
+	if m.MariaDB {
+		m.log("Detected MariaDB: %s", version)
+	}
+	return m.MariaDB
+}
+
+// mariaDBDefault normalizes a MariaDB COLUMN_DEFAULT to what MySQL would
+// report for the same column. ok is false when the column has no default.
+func mariaDBDefault(def string) (string, bool) {
+	switch {
+	case def == "NULL":
+		return "", false
+	case len(def) >= 2 && def[0] == '\'' && def[len(def)-1] == '\'':
+		return strings.ReplaceAll(def[1:len(def)-1], "''", "'"), true
+	case strings.EqualFold(def, "current_timestamp()"):
+		return "CURRENT_TIMESTAMP", true
+	}
+	return def, true
+}
+
+var jsonValidCheck = regexp.MustCompile("(?i)^\\s*json_valid\\(`([^`]+)`\\)\\s*$")
+
+// jsonCheckColumn returns the column a MariaDB CHECK clause such as
+// json_valid(`payload`) marks as JSON, or "" for any other clause.
+func jsonCheckColumn(clause string) string {
+	if m := jsonValidCheck.FindStringSubmatch(clause); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// mysqlDefaultSQL renders a captured column default for DDL. Both
+// engines end up with string defaults unquoted in schema.json, so
+// anything that isn't NULL, a number, an already quoted literal or an
+// expression is quoted as a string.
+func mysqlDefaultSQL(def string) string {
+	trimmed := strings.TrimSpace(def)
+	upper := strings.ToUpper(trimmed)
+	switch {
+	case upper == "NULL", upper == "TRUE", upper == "FALSE":
+		return upper
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP"), strings.HasPrefix(upper, "CURRENT_DATE"),
+		strings.HasPrefix(upper, "NOW("), strings.HasPrefix(upper, "LOCALTIMESTAMP"):
+		return trimmed
+	case strings.HasPrefix(trimmed, "'"), strings.HasPrefix(trimmed, "("),
+		strings.HasPrefix(upper, "B'"), strings.HasPrefix(upper, "X'"):
+		return trimmed
+	}
+	if _, err := strconv.ParseFloat(trimmed, 64); err == nil {
+		return trimmed
+	}
+	if strings.HasSuffix(trimmed, ")") && strings.Contains(trimmed, "(") {
+		// An expression default such as uuid(); MySQL needs it wrapped.
+		return "(" + trimmed + ")"
+	}
+	return "'" + strings.ReplaceAll(def, "'", "''") + "'"
+}
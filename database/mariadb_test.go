@@ -0,0 +1,54 @@
+package db
+
+import "testing"
+
+func TestMariaDBDefault(t *testing.T) {
+	cases := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"NULL", "", false},
+		{"'active'", "active", true},
+		{"'it''s'", "it's", true},
+		{"'NULL'", "NULL", true},
+		{"0", "0", true},
+		{"current_timestamp()", "CURRENT_TIMESTAMP", true},
+		{"uuid()", "uuid()", true},
+	}
+	for _, c := range cases {
+		got, ok := mariaDBDefault(c.in)
+		if got != c.want || ok != c.wantOK {
+			t.Errorf("mariaDBDefault(%q) = %q, %v; want %q, %v", c.in, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+func TestJSONCheckColumn(t *testing.T) {
+	if got := jsonCheckColumn("json_valid(`payload`)"); got != "payload" {
+		t.Errorf("jsonCheckColumn = %q, want payload", got)
+	}
+	if got := jsonCheckColumn("`qty` > 0"); got != "" {
+		t.Errorf("jsonCheckColumn = %q, want empty for a non-JSON check", got)
+	}
+}
+
+func TestMySQLDefaultSQL(t *testing.T) {
+	cases := map[string]string{
+		"active":               "'active'",
+		"it's":                 "'it''s'",
+		"0":                    "0",
+		"1.5":                  "1.5",
+		"NULL":                 "NULL",
+		"CURRENT_TIMESTAMP":    "CURRENT_TIMESTAMP",
+		"CURRENT_TIMESTAMP(6)": "CURRENT_TIMESTAMP(6)",
+		"uuid()":               "(uuid())",
+		"'quoted'":             "'quoted'",
+		"b'1'":                 "b'1'",
+	}
+	for in, want := range cases {
+		if got := mysqlDefaultSQL(in); got != want {
+			t.Errorf("mysqlDefaultSQL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package db
+
+import "sort"
+
+// TableByName returns the table called name, or nil when the schema has
+// none.
+func (s *Schema) TableByName(name string) *Table {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// Column returns the column called name, or nil. It is safe to call on a
+// nil *Table, so lookups chain: schema.TableByName(t).Column(c).
+func (t *Table) Column(name string) *Column {
+	if t == nil {
+		return nil
+	}
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// PrimaryKey returns the table's primary key columns in schema order, or
+// nil when it has none.
+func (t *Table) PrimaryKey() []string {
+	var pk []string
+	for _, col := range t.Columns {
+		if col.IsPrimary {
+			pk = append(pk, col.Name)
+		}
+	}
+	return pk
+}
+
+// FKEdge is one foreign key column: Table.Column → RefTable.RefColumn.
+type FKEdge struct {
+	Table     string
+	Column    string
+	RefTable  string
+	RefColumn string
+	Nullable  bool
+}
+
+// ForeignKeys returns every foreign key in the schema, in table and then
+// column order.
+func (s *Schema) ForeignKeys() []FKEdge {
+	var edges []FKEdge
+	for _, t := range s.Tables {
+		for _, c := range t.Columns {
+			if c.ForeignKey == nil {
+				continue
+			}
+			edges = append(edges, FKEdge{
+				Table:     t.Name,
+				Column:    c.Name,
+				RefTable:  c.ForeignKey.Table,
+				RefColumn: c.ForeignKey.Column,
+				Nullable:  c.Nullable,
+			})
+		}
+	}
+	return edges
+}
+
+// References returns the tables that table's foreign keys point at,
+// sorted and without table itself.
+func (s *Schema) References(table string) []string {
+	seen := map[string]bool{}
+	for _, e := range s.ForeignKeys() {
+		if e.Table == table && e.RefTable != table {
+			seen[e.RefTable] = true
+		}
+	}
+	return sortedNames(seen)
+}
+
+// ReferencedBy returns the tables with a foreign key pointing at table,
+// sorted and without table itself.
+func (s *Schema) ReferencedBy(table string) []string {
+	seen := map[string]bool{}
+	for _, e := range s.ForeignKeys() {
+		if e.RefTable == table && e.Table != table {
+			seen[e.Table] = true
+		}
+	}
+	return sortedNames(seen)
+}
+
+// Ancestors returns every table table depends on through foreign keys,
+// directly or transitively: what must exist before its rows can load.
+func (s *Schema) Ancestors(table string) []string {
+	return s.reach(table, s.References)
+}
+
+// Descendants returns every table that depends on table through foreign
+// keys, directly or transitively: what a cascading delete would reach.
+func (s *Schema) Descendants(table string) []string {
+	return s.reach(table, s.ReferencedBy)
+}
+
+func (s *Schema) reach(table string, next func(string) []string) []string {
+	seen := map[string]bool{}
+	queue := []string{table}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, n := range next(cur) {
+			if n != table && !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return sortedNames(seen)
+}
+
+// InsertOrder returns every table ordered so that each comes after the
+// tables its foreign keys point at, with ties broken alphabetically.
+// Self references are ignored. Tables caught in a longer FK cycle can't
+// be ordered; they are appended, sorted, at the end and also returned as
+// cyclic so callers can warn. References to tables outside the schema
+// are ignored.
+func (s *Schema) InsertOrder() (order []string, cyclic []string) {
+	pending := map[string]map[string]bool{}
+	for _, t := range s.Tables {
+		pending[t.Name] = map[string]bool{}
+	}
+	children := map[string][]string{}
+	for _, e := range s.ForeignKeys() {
+		if e.RefTable == e.Table || pending[e.RefTable] == nil || pending[e.Table][e.RefTable] {
+			continue
+		}
+		pending[e.Table][e.RefTable] = true
+		children[e.RefTable] = append(children[e.RefTable], e.Table)
+	}
+
+	var ready []string
+	for name, parents := range pending {
+		if len(parents) == 0 {
+			ready = append(ready, name)
+		}
+	}
+	emitted := map[string]bool{}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		cur := ready[0]
+		ready = ready[1:]
+		emitted[cur] = true
+		order = append(order, cur)
+		for _, child := range children[cur] {
+			delete(pending[child], cur)
+			if len(pending[child]) == 0 && !emitted[child] {
+				ready = append(ready, child)
+			}
+		}
+	}
+	for name := range pending {
+		if !emitted[name] {
+			cyclic = append(cyclic, name)
+		}
+	}
+	sort.Strings(cyclic)
+	return append(order, cyclic...), cyclic
+}
+
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func modelSchema() *Schema {
+	fk := func(table, column string) *ForeignKey { return &ForeignKey{Table: table, Column: column} }
+	return &Schema{Tables: []Table{
+		{Name: "order_items", Columns: []Column{
+			{Name: "id", IsPrimary: true},
+			{Name: "order_id", ForeignKey: fk("orders", "id")},
+			{Name: "product_id", ForeignKey: fk("products", "id")},
+		}},
+		{Name: "orders", Columns: []Column{
+			{Name: "id", IsPrimary: true},
+			{Name: "user_id", ForeignKey: fk("users", "id")},
+		}},
+		{Name: "products", Columns: []Column{{Name: "id", IsPrimary: true}}},
+		{Name: "users", Columns: []Column{
+			{Name: "id", IsPrimary: true},
+			{Name: "manager_id", Nullable: true, ForeignKey: fk("users", "id")},
+		}},
+	}}
+}
+
+func TestSchema_lookups(t *testing.T) {
+	s := modelSchema()
+	if tbl := s.TableByName("orders"); tbl == nil || tbl.Name != "orders" {
+		t.Fatalf("TableByName(orders) = %v", tbl)
+	}
+	if s.TableByName("missing") != nil {
+		t.Fatal("TableByName(missing) should be nil")
+	}
+	if s.TableByName("missing").Column("id") != nil {
+		t.Fatal("Column on a missing table should be nil")
+	}
+	col := s.TableByName("users").Column("manager_id")
+	if col == nil || !col.Nullable {
+		t.Fatalf("users.manager_id = %v", col)
+	}
+	col.AllowedValues = []string{"x"}
+	if s.Tables[3].Columns[1].AllowedValues == nil {
+		t.Fatal("Column should point into the schema, not at a copy")
+	}
+	if got := s.TableByName("order_items").PrimaryKey(); !reflect.DeepEqual(got, []string{"id"}) {
+		t.Fatalf("PrimaryKey = %v", got)
+	}
+}
+
+func TestSchema_dependencies(t *testing.T) {
+	s := modelSchema()
+	if got := len(s.ForeignKeys()); got != 4 {
+		t.Fatalf("ForeignKeys = %d edges, want 4", got)
+	}
+	cases := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"References(order_items)", s.References("order_items"), []string{"orders", "products"}},
+		{"References(users)", s.References("users"), []string{}},
+		{"ReferencedBy(users)", s.ReferencedBy("users"), []string{"orders"}},
+		{"Ancestors(order_items)", s.Ancestors("order_items"), []string{"orders", "products", "users"}},
+		{"Descendants(users)", s.Descendants("users"), []string{"order_items", "orders"}},
+	}
+	for _, c := range cases {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	order, cyclic := s.InsertOrder()
+	if want := []string{"products", "users", "orders", "order_items"}; !reflect.DeepEqual(order, want) || len(cyclic) != 0 {
+		t.Fatalf("InsertOrder = %v, %v; want %v, none", order, cyclic, want)
+	}
+}
+
+func TestSchema_insertOrderReportsCycles(t *testing.T) {
+	s := &Schema{Tables: []Table{
+		{Name: "a", Columns: []Column{{Name: "b_id", ForeignKey: &ForeignKey{Table: "b", Column: "id"}}}},
+		{Name: "b", Columns: []Column{{Name: "a_id", ForeignKey: &ForeignKey{Table: "a", Column: "id"}}}},
+		{Name: "c", Columns: []Column{{Name: "id"}}},
+	}}
+	order, cyclic := s.InsertOrder()
+	if !reflect.DeepEqual(order, []string{"c", "a", "b"}) || !reflect.DeepEqual(cyclic, []string{"a", "b"}) {
+		t.Fatalf("InsertOrder = %v, %v", order, cyclic)
+	}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
@@ -9,17 +10,28 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+	"github.com/KazanKK/seedmancer/internal/csvshard"
 	"github.com/KazanKK/seedmancer/internal/ui"
 )
 
 type MySQLManager struct {
 	DB *sql.DB
+	// dsn is kept so a restore can open a second pool on a target
+	// database (sandbox mode).
+	dsn string
+	// MariaDB switches on the MariaDB variations (see mariadb.go).
+	// NewManager sets it for mariadb:// URLs; otherwise it is detected
+	// from the server on first use.
+	MariaDB       bool
+	probedMariaDB bool
 }
 
 func (m *MySQLManager) log(format string, args ...interface{}) {
@@ -37,11 +49,45 @@ func quoteIdent(s string) string {
 }
 
 func (m *MySQLManager) ConnectWithDSN(dsn string) error {
+	return m.connect(dsn, false)
+}
+
+// connect opens the pool on dsn tuned by connOptions, with every session
+// held to reads when readOnly (see ConnectReadOnly).
+func (m *MySQLManager) connect(dsn string, readOnly bool) error {
+	if (connOptions.StatementTimeout > 0 || readOnly) && !m.MariaDB {
+		// The statement timeout's and read-only mode's variables are named
+		// differently on MariaDB, and setting the wrong one fails every
+		// connection, so the server is asked which it is first.
+		probeDSN, err := ConnOptions{ConnTimeout: connOptions.ConnTimeout}.mysqlDSN(dsn, false)
+		if err != nil {
+			return err
+		}
+		probe, err := sql.Open("mysql", probeDSN)
+		if err != nil {
+			return err
+		}
+		m.DB = probe
+		m.isMariaDB()
+		probe.Close()
+		m.DB = nil
+	}
+	dsn, err := connOptions.mysqlDSN(dsn, m.MariaDB)
+	if err != nil {
+		return err
+	}
+	if readOnly {
+		if dsn, err = mysqlReadOnlyDSN(dsn, m.readOnlyVariable()); err != nil {
+			return err
+		}
+	}
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return err
 	}
+	connOptions.applyPool(db)
 	m.DB = db
+	m.dsn = dsn
 	return nil
 }
 
@@ -85,7 +131,11 @@ func (m *MySQLManager) ExtractSchema() (*Schema, error) {
 			COLUMN_DEFAULT,
 			COLUMN_KEY,
 			EXTRA,
-			CHARACTER_MAXIMUM_LENGTH
+			CHARACTER_MAXIMUM_LENGTH,
+			COLLATION_NAME,
+			NUMERIC_PRECISION,
+			NUMERIC_SCALE,
+			DATETIME_PRECISION
 		FROM information_schema.COLUMNS
 		WHERE TABLE_SCHEMA = DATABASE()
 		ORDER BY TABLE_NAME, ORDINAL_POSITION
@@ -105,6 +155,10 @@ func (m *MySQLManager) ExtractSchema() (*Schema, error) {
 		columnKey  string
 		extra      string
 		charMaxLen sql.NullInt64
+		collation  sql.NullString
+		precision  sql.NullInt64
+		scale      sql.NullInt64
+		fsp        sql.NullInt64 // fractional seconds of datetime and time
 	}
 
 	var rawCols []rawColumn
@@ -113,7 +167,8 @@ func (m *MySQLManager) ExtractSchema() (*Schema, error) {
 		if err := colRows.Scan(
 			&rc.tableName, &rc.columnName, &rc.dataType, &rc.columnType,
 			&rc.isNullable, &rc.colDefault, &rc.columnKey, &rc.extra,
-			&rc.charMaxLen,
+			&rc.charMaxLen, &rc.collation,
+			&rc.precision, &rc.scale, &rc.fsp,
 		); err != nil {
 			return nil, fmt.Errorf("scanning column row: %v", err)
 		}
@@ -150,11 +205,39 @@ func (m *MySQLManager) ExtractSchema() (*Schema, error) {
 		fkMap[fkKey{tbl, col}] = &ForeignKey{Table: refTbl, Column: refCol}
 	}
 
+	// ── MariaDB JSON columns ─────────────────────────────────────────────────
+	mariaDB := m.isMariaDB()
+	jsonCols := map[fkKey]bool{}
+	if mariaDB {
+		checkRows, err := m.DB.Query(`
+			SELECT TABLE_NAME, CHECK_CLAUSE
+			FROM information_schema.CHECK_CONSTRAINTS
+			WHERE CONSTRAINT_SCHEMA = DATABASE()
+		`)
+		if err != nil {
+			m.log("Warning: could not query CHECK constraints: %v", err)
+		} else {
+			defer checkRows.Close()
+			for checkRows.Next() {
+				var tbl, clause string
+				if err := checkRows.Scan(&tbl, &clause); err != nil {
+					return nil, fmt.Errorf("scanning CHECK constraint: %v", err)
+				}
+				if col := jsonCheckColumn(clause); col != "" {
+					jsonCols[fkKey{tbl, col}] = true
+				}
+			}
+		}
+	}
+
 	// ── Build Schema ─────────────────────────────────────────────────────────
 	schema := &Schema{
 		DatabaseType: MySQL,
 		Tables:       []Table{},
 	}
+	if mariaDB {
+		schema.DatabaseType = MariaDB
+	}
 
 	var syntheticEnums []EnumItem
 	currentTable := ""
@@ -183,19 +266,53 @@ func (m *MySQLManager) ExtractSchema() (*Schema, error) {
 			IsPrimary: rc.columnKey == "PRI",
 			IsUnique:  rc.columnKey == "UNI",
 		}
-
-		// varchar length
-		if rc.charMaxLen.Valid && (rc.dataType == "varchar" || rc.dataType == "char") {
-			s := strconv.FormatInt(rc.charMaxLen.Int64, 10)
-			col.Varchar = &s
+		// Most MySQL collations (utf8mb4_0900_ai_ci, utf8mb4_general_ci)
+		// compare without regard to case.
+		col.UniqueIgnoreCase = col.IsUnique && strings.HasSuffix(rc.collation.String, "_ci")
+
+		// varchar length, also kept for binary and varbinary
+		switch rc.dataType {
+		case "varchar", "char", "varbinary", "binary":
+			if rc.charMaxLen.Valid {
+				s := strconv.FormatInt(rc.charMaxLen.Int64, 10)
+				col.Varchar = &s
+			}
 		}
+		// decimal digits and fractional seconds; COLUMN_TYPE is the only
+		// place MySQL spells out unsigned
+		switch rc.dataType {
+		case "decimal":
+			if rc.precision.Valid {
+				p, s := int(rc.precision.Int64), int(rc.scale.Int64)
+				col.Precision, col.Scale = &p, &s
+			}
+		case "tinyint":
+			// tinyint(1) is how MySQL spells boolean
+			if strings.HasPrefix(strings.ToLower(rc.columnType), "tinyint(1)") {
+				p := 1
+				col.Precision = &p
+			}
+		case "datetime", "time", "timestamp":
+			if rc.fsp.Valid && rc.fsp.Int64 > 0 {
+				p := int(rc.fsp.Int64)
+				col.Precision = &p
+			}
+		}
+		col.Unsigned = strings.Contains(strings.ToLower(rc.columnType), "unsigned")
 
 		// auto_increment stored as a sentinel default so createTable can detect it
 		if strings.Contains(strings.ToLower(rc.extra), "auto_increment") {
 			col.Default = "AUTO_INCREMENT"
+		} else if rc.colDefault.Valid && mariaDB {
+			if def, ok := mariaDBDefault(rc.colDefault.String); ok {
+				col.Default = def
+			}
 		} else if rc.colDefault.Valid {
 			col.Default = rc.colDefault.String
 		}
+		if jsonCols[fkKey{rc.tableName, rc.columnName}] {
+			col.Type = "json"
+		}
 
 		// generated/virtual columns produce their value inside the DB — no CSV data needed
 		extraLower := strings.ToLower(rc.extra)
@@ -287,6 +404,7 @@ func (m *MySQLManager) ExtractSchema() (*Schema, error) {
 		}
 	}
 
+	schema.Tables = withoutHistoryTable(schema.Tables)
 	return schema, nil
 }
 
@@ -382,10 +500,33 @@ func (m *MySQLManager) ExportSchema(outputDir string) error {
 	return nil
 }
 
+// CompareWithCSV implements DatabaseManager for the connected database.
+func (m *MySQLManager) CompareWithCSV(directory string, tables []string) ([]TableDrift, error) {
+	if m.DB == nil {
+		return nil, errors.New("no database connection")
+	}
+	existing := map[string]bool{}
+	for _, name := range tables {
+		ok, err := m.tableExists(name)
+		if err != nil {
+			return nil, err
+		}
+		existing[name] = ok
+	}
+	return compareTables(context.Background(), m.DB, quoteIdent, directory, tables, existing)
+}
+
 // ExportToCSV exports each table to a CSV file in outputDir.
 func (m *MySQLManager) ExportToCSV(outputDir string) error {
+	_, err := m.ExportToCSVWithOptions(outputDir, ExportOptions{})
+	return err
+}
+
+// ExportToCSVWithOptions writes one CSV per table into outputDir and
+// returns the tables it skipped as unreadable.
+func (m *MySQLManager) ExportToCSVWithOptions(outputDir string, opts ExportOptions) ([]string, error) {
 	if m.DB == nil {
-		return errors.New("no database connection")
+		return nil, errors.New("no database connection")
 	}
 
 	rows, err := m.DB.Query(`
@@ -393,10 +534,11 @@ func (m *MySQLManager) ExportToCSV(outputDir string) error {
 		FROM information_schema.TABLES
 		WHERE TABLE_SCHEMA = DATABASE()
 		AND TABLE_TYPE = 'BASE TABLE'
+		AND TABLE_NAME <> '_seedmancer_history'
 		ORDER BY TABLE_NAME
 	`)
 	if err != nil {
-		return fmt.Errorf("querying tables: %v", err)
+		return nil, fmt.Errorf("querying tables: %v", err)
 	}
 	defer rows.Close()
 
@@ -404,31 +546,53 @@ func (m *MySQLManager) ExportToCSV(outputDir string) error {
 	for rows.Next() {
 		var t string
 		if err := rows.Scan(&t); err != nil {
-			return fmt.Errorf("scanning table name: %v", err)
+			return nil, fmt.Errorf("scanning table name: %v", err)
 		}
 		tables = append(tables, t)
 	}
 
+	if err := opts.checkFilters(tables); err != nil {
+		return nil, err
+	}
+	if tables, err = opts.selectTables(tables); err != nil {
+		return nil, err
+	}
+	// TABLE_ROWS is InnoDB's estimate, which can be off by half.
+	progress := ui.StartTableProgress(ui.PhaseExport, tables, opts.rowEstimates(m.DB, `
+		SELECT TABLE_NAME, TABLE_ROWS
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'`))
+	var skipped []string
 	for _, tbl := range tables {
-		if err := m.exportTableToCSV(tbl, outputDir); err != nil {
-			return fmt.Errorf("exporting table %s: %v", tbl, err)
+		progress.Begin(tbl)
+		if err := m.exportTableToCSV(tbl, outputDir, opts, progress); err != nil {
+			progress.Clear()
+			if opts.SkipUnreadable && isPermissionDenied(err) {
+				csvshard.Remove(filepath.Join(outputDir, tbl+".csv"))
+				ui.Warn("Skipping table %s: %v", tbl, err)
+				skipped = append(skipped, tbl)
+				progress.End()
+				continue
+			}
+			return nil, fmt.Errorf("exporting table %s: %v", tbl, err)
 		}
+		progress.End()
 		ui.Debug("Exported table: %s", tbl)
 	}
-	ui.Success("Exported %d table(s)", len(tables))
-	return nil
+	progress.Clear()
+	ui.Success("Exported %d table(s)", len(tables)-len(skipped))
+	return skipped, nil
 }
 
-func (m *MySQLManager) exportTableToCSV(tableName, outputDir string) error {
-	csvPath := filepath.Join(outputDir, tableName+".csv")
-	file, err := os.Create(csvPath)
+// exportTableToCSV writes tableName's rows to <outputDir>/<tableName>.csv,
+// or its shards, as far as opts' filters and excluded columns let
+// through, counting each on progress.
+func (m *MySQLManager) exportTableToCSV(tableName, outputDir string, opts ExportOptions, progress *ui.TableProgress) error {
+	writer, err := opts.createCSV(outputDir, tableName)
 	if err != nil {
-		return fmt.Errorf("creating CSV file: %v", err)
+		return err
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	defer writer.Close()
 
 	// Column names in ordinal order
 	colRows, err := m.DB.Query(`
@@ -451,6 +615,7 @@ func (m *MySQLManager) exportTableToCSV(tableName, outputDir string) error {
 		columns = append(columns, c)
 	}
 
+	columns = opts.columns(tableName, columns)
 	if err := writer.Write(columns); err != nil {
 		return fmt.Errorf("writing CSV header: %v", err)
 	}
@@ -459,14 +624,18 @@ func (m *MySQLManager) exportTableToCSV(tableName, outputDir string) error {
 	for i, c := range columns {
 		quotedCols[i] = quoteIdent(c)
 	}
-	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quotedCols, ", "), quoteIdent(tableName))
+	query := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(quotedCols, ", "), quoteIdent(tableName), opts.where(tableName))
 	m.logSQL("Export "+tableName, query)
 
 	dataRows, err := m.DB.Query(query)
 	if err != nil {
-		return fmt.Errorf("querying data: %v", err)
+		return fmt.Errorf("querying data: %w", err)
 	}
 	defer dataRows.Close()
+	binary, err := binaryColumns(dataRows)
+	if err != nil {
+		return err
+	}
 
 	vals := make([]interface{}, len(columns))
 	valPtrs := make([]interface{}, len(columns))
@@ -480,53 +649,141 @@ func (m *MySQLManager) exportTableToCSV(tableName, outputDir string) error {
 		}
 		row := make([]string, len(columns))
 		for i, v := range vals {
-			if v == nil {
-				row[i] = "NULL"
-			} else {
-				switch val := v.(type) {
-				case []byte:
-					row[i] = string(val)
-				case time.Time:
-					row[i] = val.Format("2006-01-02 15:04:05.999999 -0700 UTC")
-				default:
-					row[i] = fmt.Sprintf("%v", val)
-				}
-			}
+			row[i] = formatCSVCell(v, binary[i])
 		}
 		if err := writer.Write(row); err != nil {
 			return fmt.Errorf("writing CSV row: %v", err)
 		}
+		progress.Rows(1)
 	}
-	return nil
+	return writer.Close()
 }
 
 // RestoreFromCSV restores the database from schema.json + CSV files in directory.
 func (m *MySQLManager) RestoreFromCSV(directory string) error {
+	return m.RestoreFromCSVWithOptions(directory, RestoreOptions{})
+}
+
+// RestoreFromCSVWithOptions restores schema.json + CSVs from directory,
+// honouring opts (see RestoreOptions).
+func (m *MySQLManager) RestoreFromCSVWithOptions(directory string, opts RestoreOptions) (err error) {
 	if m.DB == nil {
 		return errors.New("no database connection")
 	}
+	if opts.Role != "" {
+		return fmt.Errorf("role %q: restoring as another role is only supported on PostgreSQL", opts.Role)
+	}
+	if opts.TargetSchema != "" {
+		return fmt.Errorf("target schema %q: MySQL restores into a target database instead", opts.TargetSchema)
+	}
+	if opts.TargetDatabase != "" {
+		ui.Step("Restoring into database %s", opts.TargetDatabase)
+		restore, err := m.useTargetDatabase(opts.TargetDatabase)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
 
 	if _, err := m.DB.Exec("SET FOREIGN_KEY_CHECKS = 0"); err != nil {
 		return fmt.Errorf("disabling FK checks: %v", err)
 	}
 	defer m.DB.Exec("SET FOREIGN_KEY_CHECKS = 1")
 
+	// MySQL commits every CREATE TABLE on its own, so a restore killed
+	// part way leaves the tables it created; the journal records them and
+	// the next restore drops them before starting over. It is opened only
+	// once the preflight has passed, so a refused restore leaves no trace;
+	// the tables it drops count as missing from then on.
+	var journal *restoreJournal
+	defer func() { journal.finish(err) }()
+	openJournal := func(existing, unchanged map[string]bool) error {
+		var target string
+		var session int64
+		if err := m.DB.QueryRow("SELECT COALESCE(DATABASE(), ''), CONNECTION_ID()").Scan(&target, &session); err != nil {
+			return fmt.Errorf("reading session id: %v", err)
+		}
+		var err error
+		journal, err = beginRestoreJournal(context.Background(), m.DB, mysqlJournal, target, session, func(r interruptedRestore) error {
+			if err := m.dropCreatedTables(r); err != nil {
+				return err
+			}
+			for _, table := range r.created {
+				existing[table] = false
+				delete(unchanged, table)
+			}
+			return nil
+		})
+		return err
+	}
+
 	schema, err := m.readSchemaFromFile(filepath.Join(directory, "schema.json"))
 	if err != nil {
 		return fmt.Errorf("reading schema: %v", err)
 	}
 
-	ui.Step("Preparing %d table(s)...", len(schema.Tables))
+	existing := map[string]bool{}
 	for _, table := range schema.Tables {
 		exists, err := m.tableExists(table.Name)
 		if err != nil {
 			return err
 		}
-		if !exists {
+		existing[table.Name] = exists
+	}
+	if opts.CreateMissingOnly {
+		if err := openJournal(existing, nil); err != nil {
+			return err
+		}
+		return m.createMissingOnly(schema, existing)
+	}
+
+	// Static tables whose live rows already match the fixture are neither
+	// truncated nor reloaded.
+	unchanged := unchangedStaticTables(context.Background(), m.DB, quoteIdent, directory, opts.StaticTables, existing, m.log)
+	if len(unchanged) > 0 {
+		ui.Step("Keeping %d unchanged static table(s)", len(unchanged))
+	}
+
+	// Append mode loads a rewritten copy of the CSVs whose generated and
+	// UUID keys can't collide with the rows already there.
+	dataDir := directory
+	appendKeys := appendKeyColumns(schema, opts, unchanged)
+	if opts.Mode == RestoreAppend {
+		liveMax, err := liveKeyMax(context.Background(), m.DB, quoteIdent, appendKeys, existing)
+		if err != nil {
+			return err
+		}
+		if dataDir, err = os.MkdirTemp("", "seedmancer-append-*"); err != nil {
+			return fmt.Errorf("creating temp dir: %v", err)
+		}
+		defer os.RemoveAll(dataDir)
+		if err := remapForAppend(schema, appendKeys, directory, dataDir, liveMax); err != nil {
+			return err
+		}
+		ui.Step("Remapping keys of %d table(s) for append", len(appendKeys))
+	}
+
+	plan := planRestore(schema, directory, existing, nil, nil, unchanged, opts)
+	if err := m.preflightMySQL(plan); err != nil {
+		return err
+	}
+	if err := openJournal(existing, unchanged); err != nil {
+		return err
+	}
+
+	ui.Step("Preparing %d table(s)...", len(schema.Tables))
+	for _, table := range schema.Tables {
+		if unchanged[table.Name] || !opts.inSubset(table.Name) {
+			continue
+		}
+		if !existing[table.Name] {
 			if err := m.createTable(table); err != nil {
 				return fmt.Errorf("creating table %s: %v", table.Name, err)
 			}
-		} else {
+			if err := journal.createdTable(table.Name); err != nil {
+				return err
+			}
+		} else if opts.clears(table.Name) {
 			truncSQL := "TRUNCATE TABLE " + quoteIdent(table.Name)
 			m.logSQL("Truncate "+table.Name, truncSQL)
 			if _, err := m.DB.Exec(truncSQL); err != nil {
@@ -637,59 +894,88 @@ func (m *MySQLManager) RestoreFromCSV(directory string) error {
 	}
 
 	ui.Step("Importing data...")
+	progress := ui.StartTableProgress(ui.PhaseImport, loadTables(schema.Tables, dataDir, unchanged, opts), opts.expectedRows())
+	defer progress.Clear()
+	var loaded []string
 	for _, table := range schema.Tables {
-		csvPath := filepath.Join(directory, table.Name+".csv")
+		if unchanged[table.Name] {
+			m.log("Static table %s unchanged; skipping import", table.Name)
+			continue
+		}
+		if !opts.inSubset(table.Name) {
+			continue
+		}
+		csvPath := filepath.Join(dataDir, table.Name+".csv")
 		if _, err := os.Stat(csvPath); err == nil {
-			if err := m.importCSV(table, csvPath); err != nil {
+			progress.Begin(table.Name)
+			_, remapped := appendKeys[table.Name]
+			if err := m.importCSV(table, csvPath, opts.keepsExisting(table.Name, remapped), opts.upserts(table.Name), progress); err != nil {
 				return fmt.Errorf("importing %s: %v", table.Name, err)
 			}
+			progress.End()
+			loaded = append(loaded, table.Name)
 		} else {
 			m.log("No CSV file found for table: %s", table.Name)
 		}
 	}
+	progress.Clear()
+
+	// MySQL has no restore transaction to share; the row is written
+	// once everything is loaded.
+	if opts.Record != nil {
+		if err := recordSeed(context.Background(), m.DB, mysqlHistory, *opts.Record); err != nil {
+			ui.Warn("%v", err)
+		}
+	}
 
+	if err := warmUp(context.Background(), m.DB, "ANALYZE TABLE", quoteIdent, loaded, opts, m.logSQL); err != nil {
+		return err
+	}
+	return m.reclaimMySQL(context.Background(), loaded, opts)
+}
+
+// SeedHistory implements DatabaseManager for the database in the DSN.
+func (m *MySQLManager) SeedHistory(limit int) ([]SeedRecord, error) {
+	if m.DB == nil {
+		return nil, errors.New("no database connection")
+	}
+	return readSeedHistory(context.Background(), m.DB, mysqlHistory, limit)
+}
+
+// dropCreatedTables drops the tables an interrupted restore created, so
+// they are created afresh instead of keeping its partial rows.
+func (m *MySQLManager) dropCreatedTables(r interruptedRestore) error {
+	for _, table := range r.created {
+		dropSQL := "DROP TABLE IF EXISTS " + quoteIdent(table)
+		m.logSQL("Drop "+table, dropSQL)
+		if _, err := m.DB.Exec(dropSQL); err != nil {
+			return fmt.Errorf("dropping table %s: %v", table, err)
+		}
+	}
+	if len(r.created) > 0 {
+		ui.Step("Dropped %d table(s) created by the interrupted restore", len(r.created))
+	}
 	return nil
 }
 
 // createTable builds and executes a CREATE TABLE statement for MySQL.
 func (m *MySQLManager) createTable(table Table) error {
+	createSQL := m.createTableSQL(table)
+	m.logSQL("Create Table "+table.Name, createSQL)
+	_, err := m.DB.Exec(createSQL)
+	return err
+}
+
+// createTableSQL renders the CREATE TABLE statement for a table, columns
+// in schema order and UNIQUE constraints by column name. Foreign keys are
+// added afterwards by addForeignKeys.
+func (m *MySQLManager) createTableSQL(table Table) string {
 	var cols []string
 	var pks []string
 	var uniques []string
 
 	for _, col := range table.Columns {
-		def := quoteIdent(col.Name) + " "
-
-		defaultStr := fmt.Sprintf("%v", col.Default)
-		if col.Default == nil {
-			defaultStr = ""
-		}
-		isAutoInc := strings.ToUpper(strings.TrimSpace(defaultStr)) == "AUTO_INCREMENT"
-
-		if isAutoInc {
-			switch strings.ToLower(col.Type) {
-			case "bigint":
-				def += "BIGINT"
-			case "smallint", "tinyint":
-				def += "SMALLINT"
-			default:
-				def += "INT"
-			}
-			def += " AUTO_INCREMENT"
-			if !col.Nullable {
-				def += " NOT NULL"
-			}
-		} else {
-			def += m.columnTypeDDL(col)
-			if !col.Nullable {
-				def += " NOT NULL"
-			}
-			if defaultStr != "" {
-				def += " DEFAULT " + defaultStr
-			}
-		}
-
-		cols = append(cols, def)
+		cols = append(cols, m.columnDefSQL(col))
 		if col.IsPrimary {
 			pks = append(pks, col.Name)
 		}
@@ -705,19 +991,70 @@ func (m *MySQLManager) createTable(table Table) error {
 		}
 		cols = append(cols, "PRIMARY KEY ("+strings.Join(quotedPKs, ", ")+")")
 	}
+	sort.Strings(uniques)
 	for _, u := range uniques {
 		cols = append(cols, "UNIQUE ("+quoteIdent(u)+")")
 	}
 
-	createSQL := fmt.Sprintf("CREATE TABLE %s (\n  %s\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
 		quoteIdent(table.Name), strings.Join(cols, ",\n  "))
-	m.logSQL("Create Table "+table.Name, createSQL)
-	_, err := m.DB.Exec(createSQL)
-	return err
 }
 
-// columnTypeDDL converts a schema Column type into a MySQL DDL fragment.
+// columnDefSQL is col's definition inside CREATE TABLE or ADD COLUMN,
+// without key constraints.
+func (m *MySQLManager) columnDefSQL(col Column) string {
+	def := quoteIdent(col.Name) + " "
+
+	defaultStr := fmt.Sprintf("%v", col.Default)
+	if col.Default == nil {
+		defaultStr = ""
+	}
+
+	if isAutoIncrement(col) {
+		switch strings.ToLower(col.Type) {
+		case "bigint":
+			def += "BIGINT"
+		case "smallint", "tinyint":
+			def += "SMALLINT"
+		default:
+			def += "INT"
+		}
+		if col.Unsigned {
+			def += " UNSIGNED"
+		}
+		def += " AUTO_INCREMENT"
+		if !col.Nullable {
+			def += " NOT NULL"
+		}
+	} else {
+		def += m.columnTypeDDL(col)
+		if !col.Nullable {
+			def += " NOT NULL"
+		}
+		if defaultStr != "" {
+			def += " DEFAULT " + mysqlDefaultSQL(defaultStr)
+		}
+	}
+	return def
+}
+
+// columnTypeDDL converts a schema Column type into a MySQL DDL fragment,
+// with UNSIGNED appended for an unsigned numeric column.
 func (m *MySQLManager) columnTypeDDL(col Column) string {
+	ddl := m.baseTypeDDL(col)
+	if col.Unsigned && mysqlNumericTypes[strings.ToLower(col.Type)] {
+		ddl += " UNSIGNED"
+	}
+	return ddl
+}
+
+// mysqlNumericTypes are the types MySQL accepts UNSIGNED on.
+var mysqlNumericTypes = map[string]bool{
+	"tinyint": true, "smallint": true, "mediumint": true, "int": true, "integer": true,
+	"bigint": true, "decimal": true, "numeric": true, "float": true, "double": true,
+}
+
+func (m *MySQLManager) baseTypeDDL(col Column) string {
 	t := strings.ToLower(col.Type)
 
 	if col.Type == "enum" && col.Enum != "" {
@@ -741,6 +1078,14 @@ func (m *MySQLManager) columnTypeDDL(col Column) string {
 		return "SMALLINT"
 	case t == "boolean" || t == "bool":
 		return "TINYINT(1)"
+	case t == "tinyint" && col.Precision != nil:
+		return fmt.Sprintf("TINYINT(%d)", *col.Precision)
+	case (t == "numeric" || t == "decimal") && col.Precision != nil:
+		scale := 0
+		if col.Scale != nil {
+			scale = *col.Scale
+		}
+		return fmt.Sprintf("DECIMAL(%d,%d)", *col.Precision, scale)
 	case t == "numeric" || t == "decimal":
 		return "DECIMAL(18,6)"
 	case t == "real" || t == "float4":
@@ -751,8 +1096,12 @@ func (m *MySQLManager) columnTypeDDL(col Column) string {
 		return "DATETIME(6)"
 	case t == "date":
 		return "DATE"
+	case (t == "datetime" || t == "time") && col.Precision != nil:
+		return fmt.Sprintf("%s(%d)", strings.ToUpper(t), *col.Precision)
 	case t == "time":
 		return "TIME"
+	case (t == "varbinary" || t == "binary") && col.Varchar != nil:
+		return fmt.Sprintf("%s(%s)", strings.ToUpper(t), *col.Varchar)
 	case t == "json" || t == "jsonb":
 		return "JSON"
 	case t == "uuid":
@@ -774,34 +1123,51 @@ func (m *MySQLManager) tableExists(name string) (bool, error) {
 }
 
 func (m *MySQLManager) addForeignKeys(table Table) error {
+	for _, fk := range foreignKeySQL(table) {
+		m.logSQL("Add FK "+fk.name, fk.sql)
+		if _, err := m.DB.Exec(fk.sql); err != nil {
+			if isDuplicateConstraint(err) {
+				continue
+			}
+			m.log("Warning: adding FK %s: %v", fk.name, err)
+		}
+	}
+	return nil
+}
+
+// mysqlForeignKey is one ALTER TABLE … ADD CONSTRAINT statement.
+type mysqlForeignKey struct{ name, sql string }
+
+// foreignKeySQL returns the statements adding table's FK constraints,
+// sorted by constraint name.
+func foreignKeySQL(table Table) []mysqlForeignKey {
+	var fks []mysqlForeignKey
 	for _, col := range table.Columns {
 		if col.ForeignKey == nil {
 			continue
 		}
 		constraintName := fmt.Sprintf("%s_%s_fk", table.Name, col.Name)
-		alterSQL := fmt.Sprintf(
+		fks = append(fks, mysqlForeignKey{constraintName, fmt.Sprintf(
 			"ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s(%s)",
 			quoteIdent(table.Name),
 			quoteIdent(constraintName),
 			quoteIdent(col.Name),
 			quoteIdent(col.ForeignKey.Table),
 			quoteIdent(col.ForeignKey.Column),
-		)
-		m.logSQL("Add FK "+constraintName, alterSQL)
-		if _, err := m.DB.Exec(alterSQL); err != nil {
-			if isDuplicateConstraint(err) {
-				continue
-			}
-			m.log("Warning: adding FK %s: %v", constraintName, err)
-		}
+		)})
 	}
-	return nil
+	sort.Slice(fks, func(i, j int) bool { return fks[i].name < fks[j].name })
+	return fks
 }
 
-// importCSV loads CSV data into a table using batched INSERT statements.
+// importCSV loads CSV data into a table using batched INSERT statements,
+// counting each row on progress as it is flushed.
+// With keepExisting set the batches use INSERT IGNORE, so rows whose key
+// already exists are skipped instead of failing the restore; with upsert
+// set they overwrite the existing row instead.
 const mysqlBatchSize = 500
 
-func (m *MySQLManager) importCSV(table Table, csvPath string) error {
+func (m *MySQLManager) importCSV(table Table, csvPath string, keepExisting, upsert bool, progress *ui.TableProgress) error {
 	file, err := os.Open(csvPath)
 	if err != nil {
 		return fmt.Errorf("opening CSV: %v", err)
@@ -825,28 +1191,39 @@ func (m *MySQLManager) importCSV(table Table, csvPath string) error {
 	}
 	placeholders := "(" + strings.Repeat("?,", len(header)-1) + "?)"
 
-	insertPrefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ",
-		quoteIdent(table.Name), strings.Join(quotedHeader, ", "))
+	verb, suffix := "INSERT INTO", ""
+	switch {
+	case keepExisting:
+		verb = "INSERT IGNORE INTO"
+	case upsert:
+		if table.PrimaryKey() == nil {
+			m.log("Warning: table %s has no primary key; upsert only overwrites rows that hit a unique key", table.Name)
+		}
+		verb, suffix = mysqlUpsert(table, header)
+	}
+	insertPrefix := fmt.Sprintf("%s %s (%s) VALUES ",
+		verb, quoteIdent(table.Name), strings.Join(quotedHeader, ", "))
 
 	var batch [][]interface{}
+	var tuples []string
 	rowCount := 0
 
 	flush := func() error {
 		if len(batch) == 0 {
 			return nil
 		}
-		rowPlaceholders := make([]string, len(batch))
 		var flatVals []interface{}
-		for i, row := range batch {
-			rowPlaceholders[i] = placeholders
+		for _, row := range batch {
 			flatVals = append(flatVals, row...)
 		}
-		query := insertPrefix + strings.Join(rowPlaceholders, ", ")
+		rowPlaceholders := tuples
+		query := insertPrefix + strings.Join(rowPlaceholders, ", ") + suffix
 		m.logSQL(fmt.Sprintf("Insert batch %d rows into %s", len(batch), table.Name), query)
 		if _, err := m.DB.Exec(query, flatVals...); err != nil {
 			return fmt.Errorf("batch insert into %s: %v", table.Name, err)
 		}
-		batch = batch[:0]
+		progress.Rows(len(batch))
+		batch, tuples = batch[:0], tuples[:0]
 		return nil
 	}
 
@@ -861,11 +1238,29 @@ func (m *MySQLManager) importCSV(table Table, csvPath string) error {
 		if len(record) != len(header) {
 			return fmt.Errorf("column count mismatch at row %d", rowCount+1)
 		}
-		vals := make([]interface{}, len(record))
-		for i, v := range record {
-			vals[i] = m.processCSVValue(v, colTypeMap[header[i]])
+		tuple := placeholders
+		var vals []interface{}
+		if hasExpr(record) {
+			// Expression cells (see package cellexpr) go into VALUES as
+			// written for MySQL to evaluate; the rest stay bound.
+			cells := make([]string, len(record))
+			for i, v := range record {
+				if expr, ok := cellexpr.Parse(v); ok {
+					cells[i] = expr
+					continue
+				}
+				cells[i] = "?"
+				vals = append(vals, m.processCSVValue(v, colTypeMap[header[i]]))
+			}
+			tuple = "(" + strings.Join(cells, ", ") + ")"
+		} else {
+			vals = make([]interface{}, len(record))
+			for i, v := range record {
+				vals[i] = m.processCSVValue(v, colTypeMap[header[i]])
+			}
 		}
 		batch = append(batch, vals)
+		tuples = append(tuples, tuple)
 		rowCount++
 		if len(batch) >= mysqlBatchSize {
 			if err := flush(); err != nil {
@@ -877,26 +1272,58 @@ func (m *MySQLManager) importCSV(table Table, csvPath string) error {
 		return err
 	}
 
-	// Reset AUTO_INCREMENT
+	m.resetAutoIncrement(table)
+
+	ui.Debug("Imported %d rows into %s", rowCount, table.Name)
+	return nil
+}
+
+// resetAutoIncrement moves the AUTO_INCREMENT counter of table past the
+// highest id now stored, so the application's next insert can't collide
+// with a seeded row. ALTER TABLE only accepts a literal there, hence the
+// separate MAX query. Failures are logged, not fatal — as with sequence
+// resets on PostgreSQL.
+func (m *MySQLManager) resetAutoIncrement(table Table) {
 	for _, col := range table.Columns {
-		defaultStr := fmt.Sprintf("%v", col.Default)
-		if col.Default == nil {
-			defaultStr = ""
+		if !isAutoIncrement(col) {
+			continue
 		}
-		if strings.ToUpper(strings.TrimSpace(defaultStr)) == "AUTO_INCREMENT" {
-			resetSQL := fmt.Sprintf(
-				"ALTER TABLE %s AUTO_INCREMENT = (SELECT IFNULL(MAX(%s), 0) + 1 FROM %s)",
-				quoteIdent(table.Name), quoteIdent(col.Name), quoteIdent(table.Name),
-			)
-			m.logSQL("Reset AUTO_INCREMENT "+table.Name, resetSQL)
-			if _, err := m.DB.Exec(resetSQL); err != nil {
-				m.log("Warning: resetting AUTO_INCREMENT for %s.%s: %v", table.Name, col.Name, err)
-			}
+		var next int64
+		maxSQL := fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) + 1 FROM %s", quoteIdent(col.Name), quoteIdent(table.Name))
+		if err := m.DB.QueryRow(maxSQL).Scan(&next); err != nil {
+			m.log("Warning: reading max %s.%s: %v", table.Name, col.Name, err)
+			continue
+		}
+		resetSQL := fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %d", quoteIdent(table.Name), next)
+		m.logSQL("Reset AUTO_INCREMENT "+table.Name, resetSQL)
+		if _, err := m.DB.Exec(resetSQL); err != nil {
+			m.log("Warning: resetting AUTO_INCREMENT for %s.%s: %v", table.Name, col.Name, err)
 		}
 	}
+}
 
-	ui.Debug("Imported %d rows into %s", rowCount, table.Name)
-	return nil
+// isAutoIncrement reports whether col carries the AUTO_INCREMENT sentinel
+// ExtractSchema stores as its default.
+func isAutoIncrement(col Column) bool {
+	def, _ := col.Default.(string)
+	return strings.EqualFold(strings.TrimSpace(def), "AUTO_INCREMENT")
+}
+
+// mysqlUpsert returns the INSERT verb and trailing clause that overwrite
+// existing rows with the header columns from the CSV. MySQL resolves the
+// conflict on any unique key, primary or not; with no column left to
+// update the row is kept as-is via INSERT IGNORE.
+func mysqlUpsert(table Table, header []string) (verb, suffix string) {
+	cols := upsertColumns(table, header)
+	if len(cols) == 0 {
+		return "INSERT IGNORE INTO", ""
+	}
+	sets := make([]string, len(cols))
+	for i, col := range cols {
+		q := quoteIdent(col)
+		sets[i] = q + " = VALUES(" + q + ")"
+	}
+	return "INSERT INTO", " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
 }
 
 // processCSVValue converts a raw CSV string to a typed Go value for MySQL.
@@ -916,6 +1343,15 @@ func (m *MySQLManager) processCSVValue(value, columnType string) interface{} {
 		return nil
 	}
 
+	// BLOB and BINARY cells are \x hex; anything else is a CSV from
+	// before binary columns were encoded and goes in as written.
+	if isBinaryType(ct) {
+		if b, ok := decodeBinaryCell(value); ok {
+			return b
+		}
+		return value
+	}
+
 	if ct == "json" {
 		var js interface{}
 		if json.Unmarshal([]byte(value), &js) == nil {
@@ -976,12 +1412,13 @@ func (m *MySQLManager) readSchemaFromFile(filename string) (*Schema, error) {
 	if err != nil {
 		return nil, err
 	}
-	var schema Schema
-	if err := json.Unmarshal(data, &schema); err != nil {
+	schema, err := parseSchema(data)
+	if err != nil {
 		return nil, fmt.Errorf("parsing schema.json: %v", err)
 	}
-	if schema.DatabaseType != "" && schema.DatabaseType != MySQL {
-		m.log("Warning: schema was created for %s, restoring into MySQL", schema.DatabaseType)
+	if schema.DatabaseType != "" && !sameFamily(schema.DatabaseType, MySQL) {
+		m.log("Warning: schema was created for %s, converting its types for MySQL", schema.DatabaseType)
+		convertToMySQL(&schema)
 	}
 	return &schema, nil
 }
@@ -158,6 +158,15 @@ INSERT INTO seedmancer_it_books (id, author_id, title, rating) VALUES
 		t.Fatalf("expected NULL rating for book id=11, got %v", rating.Float64)
 	}
 
+	// AUTO_INCREMENT must continue past the restored ids.
+	res, err := raw.Exec(`INSERT INTO seedmancer_it_authors (name) VALUES ('Dave')`)
+	if err != nil {
+		t.Fatalf("insert author: %v", err)
+	}
+	if id, _ := res.LastInsertId(); id != 4 {
+		t.Fatalf("next author id = %d, want 4", id)
+	}
+
 	// FK constraint must be intact — inserting an orphan book should fail.
 	_, fkErr := raw.Exec(`INSERT INTO seedmancer_it_books (author_id, title) VALUES (9999, 'Orphan')`)
 	if fkErr == nil {
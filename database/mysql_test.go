@@ -161,6 +161,20 @@ func TestMySQLProcessCSVValue_timestamps(t *testing.T) {
 	}
 }
 
+func TestMySQLProcessCSVValue_binary(t *testing.T) {
+	m := &MySQLManager{}
+	for _, colType := range []string{"blob", "longblob", "varbinary(16)"} {
+		got, ok := m.processCSVValue(`\x00ff`, colType).([]byte)
+		if !ok || string(got) != "\x00\xff" {
+			t.Errorf("processCSVValue(\\x00ff, %s) = %v, want bytes 00 ff", colType, got)
+		}
+	}
+	// CSVs exported before binary columns were encoded go in as written.
+	if got := m.processCSVValue("plain", "blob"); got != "plain" {
+		t.Errorf("unencoded blob = %v, want it as written", got)
+	}
+}
+
 func TestMySQLProcessCSVValue_json(t *testing.T) {
 	m := &MySQLManager{}
 	in := `{"key":"value"}`
@@ -217,6 +231,36 @@ func TestMySQLColumnTypeDDL(t *testing.T) {
 	}
 }
 
+func TestMySQLColumnTypeDDL_precisionAndUnsigned(t *testing.T) {
+	m := &MySQLManager{}
+	ten, two, three := 10, 2, 3
+	sixteen := "16"
+	cases := []struct {
+		col  Column
+		want string
+	}{
+		{Column{Type: "decimal", Precision: &ten, Scale: &two}, "DECIMAL(10,2)"},
+		{Column{Type: "decimal", Precision: &ten}, "DECIMAL(10,0)"},
+		{Column{Type: "decimal", Precision: &ten, Scale: &two, Unsigned: true}, "DECIMAL(10,2) UNSIGNED"},
+		{Column{Type: "int", Unsigned: true}, "INT UNSIGNED"},
+		{Column{Type: "tinyint", Unsigned: true}, "TINYINT UNSIGNED"},
+		{Column{Type: "varchar", Unsigned: true}, "VARCHAR(255)"},
+		{Column{Type: "datetime", Precision: &three}, "DATETIME(3)"},
+		{Column{Type: "datetime"}, "DATETIME"},
+		{Column{Type: "time", Precision: &three}, "TIME(3)"},
+		{Column{Type: "varbinary", Varchar: &sixteen}, "VARBINARY(16)"},
+	}
+	for _, c := range cases {
+		if got := m.columnTypeDDL(c.col); got != c.want {
+			t.Errorf("columnTypeDDL(%+v) = %q, want %q", c.col, got, c.want)
+		}
+	}
+	id := Column{Name: "id", Type: "bigint", Default: "AUTO_INCREMENT", Unsigned: true}
+	if got := m.columnDefSQL(id); got != "`id` BIGINT UNSIGNED AUTO_INCREMENT NOT NULL" {
+		t.Errorf("columnDefSQL(unsigned auto-increment) = %q", got)
+	}
+}
+
 func TestMySQLColumnTypeDDL_timestamp(t *testing.T) {
 	m := &MySQLManager{}
 	got := m.columnTypeDDL(Column{Type: "timestamp without time zone"})
@@ -224,3 +268,37 @@ func TestMySQLColumnTypeDDL_timestamp(t *testing.T) {
 		t.Errorf("timestamp type should map to DATETIME variant, got %q", got)
 	}
 }
+
+func TestMySQLUpsert(t *testing.T) {
+	table := Table{Name: "memberships", Columns: []Column{
+		{Name: "user_id", IsPrimary: true},
+		{Name: "org_id", IsPrimary: true},
+		{Name: "role"},
+	}}
+	verb, suffix := mysqlUpsert(table, []string{"user_id", "org_id", "role"})
+	if verb != "INSERT INTO" || suffix != " ON DUPLICATE KEY UPDATE `role` = VALUES(`role`)" {
+		t.Errorf("mysqlUpsert = %q, %q", verb, suffix)
+	}
+	if verb, suffix := mysqlUpsert(table, []string{"user_id", "org_id"}); verb != "INSERT IGNORE INTO" || suffix != "" {
+		t.Errorf("key-only header: %q, %q", verb, suffix)
+	}
+}
+
+func TestIsAutoIncrement(t *testing.T) {
+	cases := []struct {
+		def  interface{}
+		want bool
+	}{
+		{"AUTO_INCREMENT", true},
+		{"auto_increment", true},
+		{" AUTO_INCREMENT ", true},
+		{"0", false},
+		{nil, false},
+		{42, false},
+	}
+	for _, c := range cases {
+		if got := isAutoIncrement(Column{Name: "id", Default: c.def}); got != c.want {
+			t.Errorf("isAutoIncrement(%v) = %v, want %v", c.def, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// createDomainSQL is the CREATE DOMAIN statement for d.
+func createDomainSQL(d Domain) string {
+	stmt := fmt.Sprintf("CREATE DOMAIN %s AS %s", pq.QuoteIdentifier(d.Name), d.BaseType)
+	if d.Default != "" {
+		stmt += " DEFAULT " + d.Default
+	}
+	if d.NotNull {
+		stmt += " NOT NULL"
+	}
+	for _, check := range d.Checks {
+		stmt += " " + check
+	}
+	return stmt
+}
+
+// createCompositeSQL is the CREATE TYPE … AS (…) statement for c.
+func createCompositeSQL(c CompositeType) string {
+	attrs := make([]string, len(c.Attributes))
+	for i, a := range c.Attributes {
+		attrs[i] = pq.QuoteIdentifier(a.Name) + " " + a.Type
+	}
+	return fmt.Sprintf("CREATE TYPE %s AS (%s)", pq.QuoteIdentifier(c.Name), strings.Join(attrs, ", "))
+}
+
+// userTypeStatements returns the CREATE statements for the domains and
+// composite types of schema that exists doesn't name, in an order where
+// every type comes after the ones it is built from: a domain over
+// another domain, a composite with a domain or composite attribute.
+func userTypeStatements(schema *Schema, exists func(name string) bool) []string {
+	pending := map[string]string{}
+	uses := map[string][]string{}
+	var names []string
+	add := func(name, stmt string, types ...string) {
+		if exists(name) {
+			return
+		}
+		pending[name] = stmt
+		uses[name] = types
+		names = append(names, name)
+	}
+	for _, d := range schema.Domains {
+		add(d.Name, createDomainSQL(d), d.BaseType)
+	}
+	for _, c := range schema.CompositeTypes {
+		types := make([]string, len(c.Attributes))
+		for i, a := range c.Attributes {
+			types[i] = a.Type
+		}
+		add(c.Name, createCompositeSQL(c), types...)
+	}
+
+	// Types that depend on each other in a cycle can't be created
+	// anyway; they are left to fail in declaration order.
+	var stmts []string
+	for len(names) > 0 {
+		var next []string
+		for _, name := range names {
+			if waitsOn(uses[name], pending, name) {
+				next = append(next, name)
+				continue
+			}
+			stmts = append(stmts, pending[name])
+			delete(pending, name)
+		}
+		if len(next) == len(names) {
+			for _, name := range next {
+				stmts = append(stmts, pending[name])
+			}
+			break
+		}
+		names = next
+	}
+	return stmts
+}
+
+// waitsOn reports whether any of types names a type still pending other
+// than self, with or without quotes and array brackets.
+func waitsOn(types []string, pending map[string]string, self string) bool {
+	for _, t := range types {
+		t = strings.Trim(strings.TrimSuffix(strings.TrimSpace(t), "[]"), `"`)
+		if _, ok := pending[t]; ok && t != self {
+			return true
+		}
+	}
+	return false
+}
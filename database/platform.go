@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// DriverProbe is what ProbeDriver learned from a server.
+type DriverProbe struct {
+	Engine  DatabaseType `json:"engine"`
+	Version string       `json:"version"`
+	// Collation is the database's default collation.
+	Collation string `json:"collation,omitempty"`
+	// StaleCollations are PostgreSQL collations whose recorded version
+	// differs from the one the server's collation library now provides;
+	// indexes on text sorted by them may be corrupt until reindexed.
+	StaleCollations []string `json:"staleCollations,omitempty"`
+}
+
+// ProbeDriver connects to rawDSN with the driver a restore would use and
+// asks the server for its version and collation, to tell driver and
+// platform trouble apart from problems with a fixture.
+func ProbeDriver(ctx context.Context, rawDSN string) (DriverProbe, error) {
+	engine, err := DatabaseTypeOf(rawDSN)
+	if err != nil {
+		return DriverProbe{}, err
+	}
+	normalized, _, err := normalizeDSN(rawDSN)
+	if err != nil {
+		return DriverProbe{}, err
+	}
+	driver := "postgres"
+	if engine == MySQL || engine == MariaDB {
+		driver = "mysql"
+	}
+	conn, err := sql.Open(driver, normalized)
+	if err != nil {
+		return DriverProbe{}, err
+	}
+	defer conn.Close()
+
+	probe := DriverProbe{Engine: engine}
+	if err := conn.QueryRowContext(ctx, "SELECT version()").Scan(&probe.Version); err != nil {
+		return probe, err
+	}
+	switch engine {
+	case Postgres:
+		if isCockroachVersion(probe.Version) {
+			probe.Engine = Cockroach
+			return probe, nil
+		}
+		if err := conn.QueryRowContext(ctx,
+			"SELECT datcollate FROM pg_database WHERE datname = current_database()").Scan(&probe.Collation); err != nil {
+			return probe, fmt.Errorf("reading the database collation: %w", err)
+		}
+		// pg_collation_actual_version fails for a provider the server
+		// can't load; that shows up when the collation is used, so it
+		// isn't reported here.
+		rows, err := conn.QueryContext(ctx, `
+			SELECT collname FROM pg_collation
+			WHERE collversion IS NOT NULL
+			  AND collversion IS DISTINCT FROM pg_collation_actual_version(oid)
+			ORDER BY collname`)
+		if err != nil {
+			return probe, nil
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return probe, err
+			}
+			probe.StaleCollations = append(probe.StaleCollations, name)
+		}
+		return probe, rows.Err()
+	case MySQL, MariaDB:
+		if strings.Contains(strings.ToLower(probe.Version), "mariadb") {
+			probe.Engine = MariaDB
+		}
+		if err := conn.QueryRowContext(ctx, "SELECT @@collation_database").Scan(&probe.Collation); err != nil {
+			return probe, fmt.Errorf("reading the database collation: %w", err)
+		}
+	}
+	return probe, nil
+}
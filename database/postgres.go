@@ -11,10 +11,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+	"github.com/KazanKK/seedmancer/internal/csvshard"
 	"github.com/KazanKK/seedmancer/internal/ui"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
@@ -22,6 +25,11 @@ import (
 
 type PostgresManager struct {
 	DB *sql.DB
+	// Cockroach switches on the CockroachDB variations (see
+	// cockroach.go). NewManager sets it for cockroach:// URLs; otherwise
+	// it is detected from the server on first use.
+	Cockroach       bool
+	probedCockroach bool
 }
 
 func (p *PostgresManager) log(format string, args ...interface{}) {
@@ -33,10 +41,15 @@ func (p *PostgresManager) logSQL(operation, sql string) {
 }
 
 func (p *PostgresManager) ConnectWithDSN(dsn string) error {
+	dsn, err := connOptions.postgresDSN(dsn)
+	if err != nil {
+		return err
+	}
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return err
 	}
+	connOptions.applyPool(db)
 	p.DB = db
 	return nil
 }
@@ -84,6 +97,7 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 		JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
 		WHERE n.nspname = 'public'
 		GROUP BY t.typname
+		ORDER BY t.typname
 	`
 	enumRows, err := p.DB.Query(enumQuery)
 	if err != nil {
@@ -105,7 +119,10 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 		})
 	}
 
-	// Extract user-defined functions (prokind 'f'=function, 'p'=procedure; requires PG11+)
+	// Extract user-defined functions (prokind 'f'=function, 'p'=procedure; requires PG11+).
+	// Functions an extension installed into public (moddatetime, pgcrypto,
+	// …) are left out: CREATE EXTENSION owns them, and replaying their
+	// C-language definitions needs superuser.
 	var functions []Function
 	funcRows, err := p.DB.Query(`
 		SELECT
@@ -115,6 +132,12 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 		JOIN pg_namespace n ON n.oid = p.pronamespace
 		WHERE n.nspname = 'public'
 		AND p.prokind IN ('f', 'p')
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_proc'::regclass
+			AND d.objid = p.oid
+			AND d.deptype = 'e'
+		)
 		ORDER BY p.proname
 	`)
 	if err != nil {
@@ -170,6 +193,203 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 		})
 	}
 
+	// Extract standalone sequences. Sequences owned by a serial ('a') or
+	// identity ('i') column are skipped: recreating the column recreates
+	// them. pg_sequences requires PG10+.
+	var sequences []Sequence
+	seqQuery := `
+		SELECT
+			s.sequencename,
+			s.data_type::text,
+			s.start_value,
+			s.increment_by,
+			s.min_value,
+			s.max_value,
+			s.cycle
+		FROM pg_sequences s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class c ON c.relname = s.sequencename AND c.relnamespace = n.oid
+		WHERE s.schemaname = 'public'
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.deptype IN ('a', 'i')
+		)
+		ORDER BY s.sequencename
+	`
+	if p.isCockroach() {
+		seqQuery = `
+			SELECT
+				sequence_name,
+				data_type,
+				start_value::INT8,
+				increment::INT8,
+				minimum_value::INT8,
+				maximum_value::INT8,
+				cycle_option = 'YES'
+			FROM information_schema.sequences
+			WHERE sequence_schema = 'public'
+			ORDER BY sequence_name
+		`
+	}
+	seqRows, err := p.DB.Query(seqQuery)
+	if err != nil {
+		p.log("Warning: could not query sequences (requires PostgreSQL 10+): %v", err)
+	} else {
+		defer seqRows.Close()
+		for seqRows.Next() {
+			var seq Sequence
+			if err := seqRows.Scan(&seq.Name, &seq.DataType, &seq.Start, &seq.Increment,
+				&seq.MinValue, &seq.MaxValue, &seq.Cycle); err != nil {
+				return nil, fmt.Errorf("scanning sequence info: %v", err)
+			}
+			sequences = append(sequences, seq)
+		}
+	}
+
+	// Extract views and materialized views, skipping those an extension
+	// owns, along with which other views each one reads from.
+	var views []View
+	viewRows, err := p.DB.Query(`
+		SELECT
+			c.relname,
+			c.relkind = 'm' AS materialized,
+			pg_get_viewdef(c.oid, true) AS definition,
+			COALESCE(ARRAY(
+				SELECT DISTINCT dep.relname
+				FROM pg_rewrite rw
+				JOIN pg_depend d ON d.classid = 'pg_rewrite'::regclass
+					AND d.objid = rw.oid
+					AND d.refclassid = 'pg_class'::regclass
+				JOIN pg_class dep ON dep.oid = d.refobjid
+				JOIN pg_namespace dn ON dn.oid = dep.relnamespace
+				WHERE rw.ev_class = c.oid
+				AND dep.oid <> c.oid
+				AND dep.relkind IN ('v', 'm')
+				AND dn.nspname = 'public'
+				ORDER BY dep.relname
+			), '{}') AS depends_on
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public'
+		AND c.relkind IN ('v', 'm')
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend e
+			WHERE e.classid = 'pg_class'::regclass
+			AND e.objid = c.oid
+			AND e.deptype = 'e'
+		)
+		ORDER BY c.relname
+	`)
+	switch {
+	case err != nil && p.isCockroach():
+		// CockroachDB's pg_rewrite and pg_depend are partial.
+		p.log("Warning: could not query views on CockroachDB: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("querying views: %v", err)
+	default:
+		defer viewRows.Close()
+		for viewRows.Next() {
+			var view View
+			var dependsOn pq.StringArray
+			if err := viewRows.Scan(&view.Name, &view.Materialized, &view.Definition, &dependsOn); err != nil {
+				return nil, fmt.Errorf("scanning view info: %v", err)
+			}
+			view.DependsOn = dependsOn
+			views = append(views, view)
+		}
+	}
+
+	// Extract domains and composite types, skipping those an extension
+	// owns. Table row types are composites too; relkind 'c' leaves them
+	// out. CockroachDB has neither domains nor the catalogs to read
+	// composites from.
+	var domains []Domain
+	var composites []CompositeType
+	if !p.isCockroach() {
+		domainRows, err := p.DB.Query(`
+			SELECT
+				t.typname,
+				format_type(t.typbasetype, t.typtypmod),
+				t.typnotnull,
+				COALESCE(t.typdefault, ''),
+				COALESCE(ARRAY(
+					SELECT pg_get_constraintdef(con.oid)
+					FROM pg_constraint con
+					WHERE con.contypid = t.oid AND con.contype = 'c'
+					ORDER BY con.conname
+				), '{}')
+			FROM pg_type t
+			JOIN pg_namespace n ON n.oid = t.typnamespace
+			WHERE n.nspname = 'public'
+			AND t.typtype = 'd'
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_type'::regclass
+				AND d.objid = t.oid
+				AND d.deptype = 'e'
+			)
+			ORDER BY t.typname
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("querying domains: %v", err)
+		}
+		defer domainRows.Close()
+		for domainRows.Next() {
+			var d Domain
+			var checks pq.StringArray
+			if err := domainRows.Scan(&d.Name, &d.BaseType, &d.NotNull, &d.Default, &checks); err != nil {
+				return nil, fmt.Errorf("scanning domain info: %v", err)
+			}
+			d.Checks = checks
+			domains = append(domains, d)
+		}
+
+		compositeRows, err := p.DB.Query(`
+			SELECT t.typname, a.attname, format_type(a.atttypid, a.atttypmod)
+			FROM pg_type t
+			JOIN pg_namespace n ON n.oid = t.typnamespace
+			JOIN pg_class c ON c.oid = t.typrelid
+			JOIN pg_attribute a ON a.attrelid = c.oid
+			WHERE n.nspname = 'public'
+			AND t.typtype = 'c'
+			AND c.relkind = 'c'
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_type'::regclass
+				AND d.objid = t.oid
+				AND d.deptype = 'e'
+			)
+			ORDER BY t.typname, a.attnum
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("querying composite types: %v", err)
+		}
+		defer compositeRows.Close()
+		for compositeRows.Next() {
+			var typeName string
+			var attr CompositeAttribute
+			if err := compositeRows.Scan(&typeName, &attr.Name, &attr.Type); err != nil {
+				return nil, fmt.Errorf("scanning composite type info: %v", err)
+			}
+			if n := len(composites); n == 0 || composites[n-1].Name != typeName {
+				composites = append(composites, CompositeType{Name: typeName})
+			}
+			last := &composites[len(composites)-1]
+			last.Attributes = append(last.Attributes, attr)
+		}
+	}
+
+	// CockroachDB lists the rowid column it adds to tables without a
+	// primary key; it is hidden and recreated on its own.
+	hiddenFilter := ""
+	if p.isCockroach() {
+		hiddenFilter = "AND c.is_hidden = 'NO'"
+	}
+
 	// Updated query to include character_maximum_length for varchar columns
 	rows, err := p.DB.Query(`
 		WITH fk_info AS (
@@ -219,7 +439,8 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 			fk.foreign_column_name,
 			c.character_maximum_length,
 			COALESCE(c.is_generated, 'NEVER') AS is_generated,
-			c.identity_generation
+			c.identity_generation,
+			c.domain_name
 		FROM 
 			information_schema.tables t
 			JOIN information_schema.columns c
@@ -234,6 +455,7 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 		WHERE 
 			t.table_schema = 'public'
 			AND t.table_type = 'BASE TABLE'
+			` + hiddenFilter + `
 		ORDER BY 
 			t.table_name, c.ordinal_position;
 	`)
@@ -242,12 +464,20 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 	}
 	defer rows.Close()
 
+	dbType := Postgres
+	if p.isCockroach() {
+		dbType = Cockroach
+	}
 	schema := &Schema{
-		DatabaseType: "postgres",
-		Enums:        enums,
-		Tables:       make([]Table, 0),
-		Functions:    functions,
-		Triggers:     triggers,
+		DatabaseType:   dbType,
+		Enums:          enums,
+		Tables:         make([]Table, 0),
+		Functions:      functions,
+		Triggers:       triggers,
+		Sequences:      sequences,
+		Views:          views,
+		Domains:        domains,
+		CompositeTypes: composites,
 	}
 
 	// Process tables and columns
@@ -261,7 +491,7 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 		var foreignTable, foreignColumn sql.NullString
 		var charMaxLength sql.NullInt64
 		var isGenerated string
-		var identityGeneration sql.NullString
+		var identityGeneration, domainName sql.NullString
 
 		if err := rows.Scan(
 			&tableName,
@@ -277,6 +507,7 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 			&charMaxLength,
 			&isGenerated,
 			&identityGeneration,
+			&domainName,
 		); err != nil {
 			return nil, err
 		}
@@ -297,6 +528,7 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 			IsPrimary:   isPrimary,
 			IsUnique:    isUnique,
 			IsGenerated: isGenerated == "ALWAYS" || identityGeneration.Valid,
+			Identity:    identityGeneration.String,
 		}
 
 		// Handle varchar length
@@ -314,6 +546,34 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 			}
 		}
 
+		// citext is reported as USER-DEFINED; keep its name so generators
+		// treat it as text and a restore can recreate it.
+		if udtName == "citext" {
+			column.Type = "citext"
+			column.UniqueIgnoreCase = isUnique
+		}
+		// So are PostGIS types; their modifiers are read further down.
+		if isSpatialType(udtName) {
+			column.Type = udtName
+		}
+
+		// Arrays are reported as ARRAY; udt_name is the element type's
+		// internal name with an underscore in front.
+		if dataType == "ARRAY" {
+			column.ElementType = arrayElementType(udtName)
+		}
+
+		// Domain columns report their base type, which Type keeps;
+		// composite ones are USER-DEFINED.
+		column.Domain = domainName.String
+		for _, ct := range composites {
+			if ct.Name == udtName {
+				column.Type = "composite"
+				column.Composite = ct.Name
+				break
+			}
+		}
+
 		// Handle enums
 		for _, enum := range enums {
 			if enum.Name == udtName {
@@ -401,27 +661,106 @@ func (p *PostgresManager) ExtractSchema() (*Schema, error) {
 			if len(vals) == 0 {
 				continue
 			}
-			// Attach to the matching column in schema.Tables
-			for ti := range schema.Tables {
-				if schema.Tables[ti].Name != tblName {
-					continue
-				}
-				for ci := range schema.Tables[ti].Columns {
-					if schema.Tables[ti].Columns[ci].Name == colName {
-						schema.Tables[ti].Columns[ci].AllowedValues = vals
-					}
-				}
+			if col := schema.TableByName(tblName).Column(colName); col != nil {
+				col.AllowedValues = vals
+			}
+		}
+	}
+
+	// ── Unique indexes on lower(col) → UniqueIgnoreCase ──────────────────────
+	// Only whole-table, single-expression indexes count: a partial index
+	// leaves the column free outside its WHERE clause.
+	idxRows, err := p.DB.Query(`
+		SELECT cls.relname, pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class     cls ON cls.oid = i.indrelid
+		JOIN pg_namespace ns  ON ns.oid  = cls.relnamespace
+		WHERE i.indisunique
+		  AND i.indexprs IS NOT NULL
+		  AND i.indpred IS NULL
+		  AND i.indnatts = 1
+		  AND ns.nspname = 'public'
+	`)
+	if err == nil {
+		defer idxRows.Close()
+		for idxRows.Next() {
+			var tblName, def string
+			if err := idxRows.Scan(&tblName, &def); err != nil {
+				continue
+			}
+			if col := schema.TableByName(tblName).Column(foldedIndexColumn(def)); col != nil {
+				col.UniqueIgnoreCase = true
 			}
 		}
 	}
 
+	// ── PostGIS type modifiers → GeometryType, SRID ──────────────────────────
+	// information_schema drops them; format_type spells them out as in
+	// geometry(Point,4326).
+	spatialRows, err := p.DB.Query(`
+		SELECT cls.relname, att.attname, format_type(att.atttypid, att.atttypmod)
+		FROM pg_attribute att
+		JOIN pg_class     cls ON cls.oid = att.attrelid
+		JOIN pg_namespace ns  ON ns.oid  = cls.relnamespace
+		JOIN pg_type      typ ON typ.oid = att.atttypid
+		WHERE typ.typname IN ('geometry', 'geography')
+		  AND att.attnum > 0
+		  AND NOT att.attisdropped
+		  AND ns.nspname = 'public'
+	`)
+	if err == nil {
+		defer spatialRows.Close()
+		for spatialRows.Next() {
+			var tblName, colName, formatted string
+			if err := spatialRows.Scan(&tblName, &colName, &formatted); err != nil {
+				continue
+			}
+			if col := schema.TableByName(tblName).Column(colName); col != nil {
+				col.GeometryType, col.SRID = parseSpatialType(formatted)
+			}
+		}
+	}
+
+	schema.Tables = withoutHistoryTable(schema.Tables)
 	return schema, nil
 }
 
+// foldedIndexRe matches the key of an index definition that is lower() or
+// upper() of one column, as pg_get_indexdef prints it:
+// "… USING btree (lower((email)::text))".
+var foldedIndexRe = regexp.MustCompile(`(?i)\((?:lower|upper)\(\(?(?:"([^"]+)"|(\w+))\)?(?:::[\w ]+)?\)\)$`)
+
+// foldedIndexColumn returns the column an index definition folds the case
+// of, or "" when the index is on anything else.
+func foldedIndexColumn(def string) string {
+	m := foldedIndexRe.FindStringSubmatch(strings.TrimSpace(def))
+	if m == nil {
+		return ""
+	}
+	return m[1] + m[2]
+}
+
+// SeedHistory implements DatabaseManager for the public schema.
+func (p *PostgresManager) SeedHistory(limit int) ([]SeedRecord, error) {
+	if p.DB == nil {
+		return nil, errors.New("no database connection")
+	}
+	return readSeedHistory(context.Background(), p.DB, postgresHistory, limit)
+}
+
 func (p *PostgresManager) RestoreFromCSV(directory string) error {
+	return p.RestoreFromCSVWithOptions(directory, RestoreOptions{})
+}
+
+// RestoreFromCSVWithOptions restores schema.json + CSVs from directory,
+// honouring opts (see RestoreOptions).
+func (p *PostgresManager) RestoreFromCSVWithOptions(directory string, opts RestoreOptions) (err error) {
 	if p.DB == nil {
 		return errors.New("no database connection")
 	}
+	if opts.TargetDatabase != "" {
+		return fmt.Errorf("target database %q: PostgreSQL restores into a target schema instead", opts.TargetDatabase)
+	}
 	ctx := context.Background()
 
 	schema, err := p.ReadSchemaFromFile(filepath.Join(directory, "schema.json"))
@@ -429,41 +768,108 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 		return fmt.Errorf("reading schema: %v", err)
 	}
 
-	// Pin one session for the whole restore. session_replication_role is a
-	// session-level setting, so it must run on the same connection as every
-	// statement that relies on it — p.DB is a pool and gives no such
-	// guarantee. A single session also keeps the number of network round
-	// trips minimal, which dominates restore time against remote databases.
+	// Pin one session for the whole restore. The role, search_path and the
+	// restore transaction are per-session state, so they must live on the
+	// same connection as every statement that relies on them — p.DB is a
+	// pool and gives no such guarantee. A single session also keeps the
+	// number of network round trips minimal, which dominates restore time
+	// against remote databases.
 	conn, err := p.DB.Conn(ctx)
 	if err != nil {
 		return fmt.Errorf("acquiring connection: %v", err)
 	}
 	defer conn.Close()
 
-	// Disable all triggers/constraints temporarily
-	if _, err := conn.ExecContext(ctx, "SET session_replication_role = 'replica';"); err != nil {
-		return fmt.Errorf("disabling constraints: %v", err)
+	if opts.Role != "" {
+		var user string
+		var member bool
+		if err := conn.QueryRowContext(ctx,
+			`SELECT session_user, EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1) AND pg_has_role(session_user, $1, 'MEMBER')`,
+			opts.Role).Scan(&user, &member); err != nil {
+			return fmt.Errorf("checking role %s: %v", opts.Role, err)
+		}
+		if !member {
+			return &MissingPrivilegesError{User: user, Missing: []string{fmt.Sprintf("membership in role %s (for SET ROLE)", opts.Role)}}
+		}
+		if _, err := conn.ExecContext(ctx, "SET ROLE "+pq.QuoteIdentifier(opts.Role)); err != nil {
+			return fmt.Errorf("setting role %s: %v", opts.Role, err)
+		}
+		defer conn.ExecContext(context.Background(), "RESET ROLE")
+	}
+
+	// Sandbox mode: unqualified names resolve to (and are created in) the
+	// target schema first. public stays on the path for extensions and
+	// functions referenced by column defaults.
+	schemaName := opts.pgSchema()
+	if opts.TargetSchema != "" {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET search_path TO %s, public", pq.QuoteIdentifier(schemaName))); err != nil {
+			return fmt.Errorf("setting search_path: %v", err)
+		}
+		defer conn.ExecContext(context.Background(), "RESET search_path")
+	}
+
+	// The restore runs in one transaction, so an interrupted restore
+	// leaves no half-created enums or tables behind, only possibly its
+	// session, still holding the transaction's locks until the server
+	// notices the client is gone. The journal ends such a session. It is
+	// opened only once the preflight and the cascade guard have passed,
+	// so a refused restore leaves no trace, not even a journal entry.
+	// CockroachDB has no pg_stat_activity to check it against.
+	var journal *restoreJournal
+	defer func() { journal.finish(err) }()
+	openJournal := func() error {
+		if p.isCockroach() {
+			return nil
+		}
+		var pid int64
+		if err := conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+			return fmt.Errorf("reading session id: %v", err)
+		}
+		var err error
+		journal, err = beginRestoreJournal(ctx, p.DB, postgresJournal, schemaName, pid, nil)
+		return err
 	}
-	defer conn.ExecContext(context.Background(), "SET session_replication_role = 'origin';")
 
-	// One round trip: fetch existing enums, tables, and FK constraint names
-	// up front instead of issuing per-object EXISTS probes.
-	existing := map[string]map[string]bool{"enum": {}, "table": {}, "fk": {}}
+	// One round trip: fetch existing enums, sequences, tables, views, and
+	// FK constraint names up front instead of issuing per-object EXISTS
+	// probes.
+	existing := map[string]map[string]bool{"schema": {}, "enum": {}, "type": {}, "sequence": {}, "table": {}, "view": {}, "fk": {}}
 	metaRows, err := conn.QueryContext(ctx, `
-		SELECT 'enum' AS kind, t.typname AS name
+		SELECT 'schema' AS kind, nspname AS name
+		FROM pg_namespace
+		WHERE nspname = $1::text
+		UNION ALL
+		SELECT 'enum', t.typname
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE n.nspname = $1::text AND t.typtype = 'e'
+		UNION ALL
+		SELECT 'type', t.typname
 		FROM pg_type t
 		JOIN pg_namespace n ON n.oid = t.typnamespace
-		WHERE n.nspname = 'public' AND t.typtype = 'e'
+		LEFT JOIN pg_class c ON c.oid = t.typrelid
+		WHERE n.nspname = $1::text
+		AND (t.typtype = 'd' OR t.typtype = 'c' AND c.relkind = 'c')
+		UNION ALL
+		SELECT 'sequence', c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1::text AND c.relkind = 'S'
+		UNION ALL
+		SELECT 'view', c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1::text AND c.relkind IN ('v', 'm')
 		UNION ALL
 		SELECT 'table', table_name
 		FROM information_schema.tables
-		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'
+		WHERE table_schema = $1::text AND table_type = 'BASE TABLE'
 		UNION ALL
 		SELECT 'fk', con.conname
 		FROM pg_constraint con
 		JOIN pg_namespace ns ON ns.oid = con.connamespace
-		WHERE con.contype = 'f' AND ns.nspname = 'public'
-	`)
+		WHERE con.contype = 'f' AND ns.nspname = $1::text
+	`, schemaName)
 	if err != nil {
 		return fmt.Errorf("querying existing objects: %v", err)
 	}
@@ -481,48 +887,214 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 	}
 	metaRows.Close()
 
+	if opts.CreateMissingOnly {
+		if err := openJournal(); err != nil {
+			return err
+		}
+		return p.createMissingOnly(ctx, conn, schema, schemaName, existing)
+	}
+
+	// Static tables whose live rows already match the fixture are left
+	// out of the TRUNCATE and the COPY below.
+	unchanged := unchangedStaticTables(ctx, conn, pq.QuoteIdentifier, directory, opts.StaticTables, existing["table"], p.log)
+	// TRUNCATE … CASCADE would still empty one that references a
+	// truncated table, so those are reloaded after all.
+	truncate := planRestore(schema, directory, existing["table"], nil, nil, unchanged, opts).TruncateTables
+	if reload := reloadCascadedStatic(schema, truncate, unchanged); len(reload) > 0 {
+		ui.Step("Reloading static table(s) %s: they reference a truncated table", strings.Join(reload, ", "))
+	}
+	if len(unchanged) > 0 {
+		ui.Step("Keeping %d unchanged static table(s)", len(unchanged))
+	}
+
+	// Append mode loads a rewritten copy of the CSVs whose generated and
+	// UUID keys can't collide with the rows already there.
+	dataDir := directory
+	appendKeys := appendKeyColumns(schema, opts, unchanged)
+	if opts.Mode == RestoreAppend {
+		liveMax, err := liveKeyMax(ctx, conn, pq.QuoteIdentifier, appendKeys, existing["table"])
+		if err != nil {
+			return err
+		}
+		if dataDir, err = os.MkdirTemp("", "seedmancer-append-*"); err != nil {
+			return fmt.Errorf("creating temp dir: %v", err)
+		}
+		defer os.RemoveAll(dataDir)
+		if err := remapForAppend(schema, appendKeys, directory, dataDir, liveMax); err != nil {
+			return err
+		}
+		ui.Step("Remapping keys of %d table(s) for append", len(appendKeys))
+	}
+
+	ui.Step("Preparing %d table(s)...", len(schema.Tables))
+
+	// Check privileges before the first statement that changes anything,
+	// so a restricted role fails with the full list instead of halfway
+	// through with some DDL already applied.
+	plan := planRestore(schema, directory, existing["table"], existing["enum"], existing["fk"], unchanged, opts)
+	plan.Schema = schemaName
+	plan.CreateSchema = !existing["schema"][schemaName]
+	// New sequences and views need the same CREATE on the schema as a
+	// new table.
+	for _, seq := range schema.Sequences {
+		if !existing["sequence"][seq.Name] {
+			plan.CreateTables = true
+		}
+	}
+	for _, view := range schema.Views {
+		if !existing["view"][view.Name] {
+			plan.CreateTables = true
+		}
+	}
+	if len(userTypeStatements(schema, func(name string) bool { return existing["type"][name] })) > 0 {
+		plan.CreateTypes = true
+	}
+	if err := p.preflightPostgres(ctx, conn, plan); err != nil {
+		return err
+	}
+
+	// TRUNCATE … CASCADE follows every foreign key into the target, so a
+	// table the revision doesn't cover would be emptied along with its
+	// parents. Refuse unless asked to, and say which either way.
+	if len(plan.TruncateTables) > 0 && !p.isCockroach() {
+		cascaded, err := cascadeTargets(ctx, conn, schemaName, plan.TruncateTables)
+		if err != nil {
+			return err
+		}
+		if len(cascaded) > 0 {
+			if !opts.AllowCascade {
+				return &CascadeError{Tables: cascaded}
+			}
+			ui.Warn("TRUNCATE … CASCADE also empties %d table(s) the revision doesn't reload: %s", len(cascaded), strings.Join(cascaded, ", "))
+		}
+	}
+
+	if err := openJournal(); err != nil {
+		return err
+	}
+
+	// Everything from here on — DDL included, which PostgreSQL runs
+	// transactionally — happens in one transaction: one BEGIN/COMMIT for
+	// the whole restore, and a failure at any step rolls the database back
+	// to exactly how it was instead of leaving it half seeded.
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning restore transaction: %v", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if plan.CreateSchema {
+		ui.Step("Creating schema %s...", schemaName)
+		createSQL := "CREATE SCHEMA IF NOT EXISTS " + pq.QuoteIdentifier(schemaName)
+		p.logSQL("Create Schema", createSQL)
+		if _, err := tx.ExecContext(ctx, createSQL); err != nil {
+			return fmt.Errorf("creating schema %s: %v", schemaName, err)
+		}
+	}
+
+	// Disable all triggers/constraints until the transaction ends.
+	// CockroachDB has no such switch; it loads in FK order instead.
+	cockroach := p.isCockroach()
+	if !cockroach {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL session_replication_role = 'replica';"); err != nil {
+			return fmt.Errorf("disabling constraints: %v", err)
+		}
+	}
+	tables := schema.Tables
+	if cockroach {
+		var cyclic []string
+		if tables, cyclic = tablesInInsertOrder(schema); len(cyclic) > 0 {
+			ui.Warn("Foreign key cycle between %s: CockroachDB can't defer the checks, so the load may fail", strings.Join(cyclic, ", "))
+		}
+	}
+
 	// Create missing enum types — all in one statement.
 	var enumStmts []string
 	for _, enum := range schema.Enums {
 		if existing["enum"][enum.Name] {
 			continue
 		}
-		enumStmts = append(enumStmts, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s);",
-			pq.QuoteIdentifier(enum.Name), joinQuotedStrings(enum.Values)))
+		enumStmts = append(enumStmts, createEnumSQL(enum)+";")
 	}
 	if len(enumStmts) > 0 {
 		ui.Step("Creating %d enum type(s)...", len(enumStmts))
 		batch := strings.Join(enumStmts, "\n")
 		p.logSQL("Create Enums", batch)
-		if _, err := conn.ExecContext(ctx, batch); err != nil {
+		if _, err := tx.ExecContext(ctx, batch); err != nil {
 			return fmt.Errorf("creating enum types: %v", err)
 		}
 	}
 
-	ui.Step("Preparing %d table(s)...", len(schema.Tables))
+	// Then the domains and composite types, which may be built on them.
+	if typeStmts := userTypeStatements(schema, func(name string) bool { return existing["type"][name] }); len(typeStmts) > 0 {
+		ui.Step("Creating %d domain and composite type(s)...", len(typeStmts))
+		batch := strings.Join(typeStmts, ";\n") + ";"
+		p.logSQL("Create Types", batch)
+		if _, err := tx.ExecContext(ctx, batch); err != nil {
+			return fmt.Errorf("creating domains and composite types: %v", err)
+		}
+	}
+
+	// Create missing standalone sequences before the tables whose
+	// defaults call nextval on them.
+	var seqStmts []string
+	standalone := map[string]bool{}
+	for _, seq := range schema.Sequences {
+		standalone[seq.Name] = true
+		if !existing["sequence"][seq.Name] {
+			seqStmts = append(seqStmts, createSequenceSQL(seq)+";")
+		}
+	}
+	if len(seqStmts) > 0 {
+		ui.Step("Creating %d sequence(s)...", len(seqStmts))
+		batch := strings.Join(seqStmts, "\n")
+		p.logSQL("Create Sequences", batch)
+		if _, err := tx.ExecContext(ctx, batch); err != nil {
+			return fmt.Errorf("creating sequences: %v", err)
+		}
+	}
 
 	// Create missing tables (one statement) and truncate the rest (one
 	// combined TRUNCATE — CASCADE makes the order irrelevant).
 	var createStmts []string
 	var truncateTargets []string
-	for _, table := range schema.Tables {
-		if existing["table"][table.Name] {
+	for _, table := range tables {
+		if unchanged[table.Name] || !opts.inSubset(table.Name) {
+			continue
+		}
+		if !existing["table"][table.Name] {
+			createStmts = append(createStmts, p.buildCreateTableSQL(table, standalone)+";")
+		} else if opts.clears(table.Name) {
 			truncateTargets = append(truncateTargets, pq.QuoteIdentifier(table.Name))
-		} else {
-			createStmts = append(createStmts, p.buildCreateTableSQL(table)+";")
 		}
 	}
 	if len(createStmts) > 0 {
 		batch := strings.Join(createStmts, "\n")
 		p.logSQL("Create Tables", batch)
-		if _, err := conn.ExecContext(ctx, batch); err != nil {
+		if _, err := tx.ExecContext(ctx, batch); err != nil {
 			return fmt.Errorf("creating tables: %v", err)
 		}
 	}
 	if len(truncateTargets) > 0 {
-		truncateSQL := fmt.Sprintf("TRUNCATE TABLE %s CASCADE", strings.Join(truncateTargets, ", "))
-		p.logSQL("Truncate Tables", truncateSQL)
-		if _, err := conn.ExecContext(ctx, truncateSQL); err != nil {
+		clearSQL := fmt.Sprintf("TRUNCATE TABLE %s CASCADE", strings.Join(truncateTargets, ", "))
+		if len(opts.Tables) > 0 || cockroach {
+			// Subset restore: CASCADE would empty every referencing table
+			// outside the subset. DELETE under replica role clears just
+			// the listed tables. On CockroachDB, DELETE runs children
+			// first so the FK checks pass.
+			stmts := make([]string, len(truncateTargets))
+			for i, t := range truncateTargets {
+				stmts[len(stmts)-1-i] = "DELETE FROM " + t + ";"
+			}
+			clearSQL = strings.Join(stmts, "\n")
+		}
+		p.logSQL("Truncate Tables", clearSQL)
+		if _, err := tx.ExecContext(ctx, clearSQL); err != nil {
 			return fmt.Errorf("truncating tables: %v", err)
 		}
 	}
@@ -530,7 +1102,7 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 	// Add missing foreign key constraints — all in one statement. The
 	// constraint-name convention matches what addForeignKeySQL generates, so
 	// the pg_constraint snapshot above tells us which ones already exist.
-	if err := p.addMissingForeignKeys(ctx, conn, schema, existing["table"], existing["fk"]); err != nil {
+	if err := p.addMissingForeignKeys(ctx, tx, schema, existing["table"], existing["fk"]); err != nil {
 		return err
 	}
 
@@ -563,8 +1135,9 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 				return fmt.Errorf("reading function file %s: %v", filepath.Base(sqlPath), err)
 			}
 			fnName := strings.TrimSuffix(filepath.Base(sqlPath), "_func.sql")
-			p.logSQL(fmt.Sprintf("Restore Function %s", fnName), string(content))
-			if _, err := conn.ExecContext(ctx, string(content)); err != nil {
+			definition := retargetPublic(string(content), opts.TargetSchema)
+			p.logSQL(fmt.Sprintf("Restore Function %s", fnName), definition)
+			if _, err := tx.ExecContext(ctx, definition); err != nil {
 				return fmt.Errorf("restoring function %s: %v", fnName, err)
 			}
 			p.log("Restored function: %s", fnName)
@@ -572,8 +1145,9 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 		}
 	} else {
 		for _, fn := range schema.Functions {
-			p.logSQL(fmt.Sprintf("Restore Function %s", fn.Name), fn.Definition)
-			if _, err := conn.ExecContext(ctx, fn.Definition); err != nil {
+			definition := retargetPublic(fn.Definition, opts.TargetSchema)
+			p.logSQL(fmt.Sprintf("Restore Function %s", fn.Name), definition)
+			if _, err := tx.ExecContext(ctx, definition); err != nil {
 				return fmt.Errorf("restoring function %s: %v", fn.Name, err)
 			}
 			p.log("Restored function: %s", fn.Name)
@@ -598,6 +1172,11 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 			if parseErr != nil {
 				return fmt.Errorf("parsing trigger file %s: %v", filepath.Base(sqlPath), parseErr)
 			}
+			if sandboxSkipsTrigger(opts, tableSchema) {
+				p.log("Skipping trigger %s on %s.%s outside the target schema", name, tableSchema, tableName)
+				continue
+			}
+			definition = retargetPublic(definition, opts.TargetSchema)
 			tableRef := pq.QuoteIdentifier(tableName)
 			if tableSchema != "" && tableSchema != "public" {
 				tableRef = pq.QuoteIdentifier(tableSchema) + "." + tableRef
@@ -605,7 +1184,7 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 			dropAndCreate := fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;\n%s",
 				pq.QuoteIdentifier(name), tableRef, definition)
 			p.logSQL(fmt.Sprintf("Restore Trigger %s", name), dropAndCreate)
-			if _, err := conn.ExecContext(ctx, dropAndCreate); err != nil {
+			if _, err := tx.ExecContext(ctx, dropAndCreate); err != nil {
 				return fmt.Errorf("restoring trigger %s on %s: %v", name, tableRef, err)
 			}
 			p.log("Restored trigger: %s on %s", name, tableRef)
@@ -613,14 +1192,18 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 		}
 	} else {
 		for _, trigger := range schema.Triggers {
+			if sandboxSkipsTrigger(opts, trigger.TableSchema) {
+				p.log("Skipping trigger %s on %s.%s outside the target schema", trigger.Name, trigger.TableSchema, trigger.TableName)
+				continue
+			}
 			tableRef := pq.QuoteIdentifier(trigger.TableName)
 			if trigger.TableSchema != "" && trigger.TableSchema != "public" {
 				tableRef = pq.QuoteIdentifier(trigger.TableSchema) + "." + tableRef
 			}
 			dropAndCreate := fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;\n%s",
-				pq.QuoteIdentifier(trigger.Name), tableRef, trigger.Definition)
+				pq.QuoteIdentifier(trigger.Name), tableRef, retargetPublic(trigger.Definition, opts.TargetSchema))
 			p.logSQL(fmt.Sprintf("Restore Trigger %s", trigger.Name), dropAndCreate)
-			if _, err := conn.ExecContext(ctx, dropAndCreate); err != nil {
+			if _, err := tx.ExecContext(ctx, dropAndCreate); err != nil {
 				return fmt.Errorf("restoring trigger %s on %s: %v", trigger.Name, tableRef, err)
 			}
 			p.log("Restored trigger: %s on %s", trigger.Name, tableRef)
@@ -631,40 +1214,84 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 		ui.Step("Restored %d trigger(s)", trigCount)
 	}
 
-	ui.Step("Importing data...")
-
-	// All tables import inside one transaction: one BEGIN/COMMIT for the
-	// whole restore instead of one per table, and the seed becomes atomic —
-	// either every table lands or none do.
-	tx, err := conn.BeginTx(ctx, nil)
+	// Create views once the tables and functions they read exist, in
+	// dependency order. Plain views are replaced so an edited definition
+	// takes effect; an existing materialized view is kept and refreshed
+	// after the load like a new one. A view that fails is reported and
+	// skipped rather than failing the seed.
+	views, err := orderViews(schema.Views)
 	if err != nil {
-		return fmt.Errorf("beginning import transaction: %v", err)
+		return err
 	}
-	committed := false
-	defer func() {
-		if !committed {
-			_ = tx.Rollback()
+	var viewCount int
+	for _, view := range views {
+		if view.Materialized && existing["view"][view.Name] {
+			continue
 		}
-	}()
+		definition := retargetPublic(strings.TrimSuffix(strings.TrimSpace(view.Definition), ";"), opts.TargetSchema)
+		stmt := fmt.Sprintf("CREATE OR REPLACE VIEW %s AS\n%s", pq.QuoteIdentifier(view.Name), definition)
+		if view.Materialized {
+			stmt = fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS\n%s\nWITH NO DATA", pq.QuoteIdentifier(view.Name), definition)
+		}
+		p.logSQL("Restore View "+view.Name, stmt)
+		if err := execSavepoint(ctx, tx, stmt); err != nil {
+			ui.Warn("Could not restore view %s: %v", view.Name, err)
+			continue
+		}
+		viewCount++
+	}
+	if viewCount > 0 {
+		ui.Step("Restored %d view(s)", viewCount)
+	}
+
+	ui.Step("Importing data...")
 
+	progress := ui.StartTableProgress(ui.PhaseImport, loadTables(tables, dataDir, unchanged, opts), opts.expectedRows())
+	defer progress.Clear()
 	var sequenceResets []string
-	for _, table := range schema.Tables {
-		csvPath := filepath.Join(directory, table.Name+".csv")
+	var loaded []string
+	for _, table := range tables {
+		if unchanged[table.Name] {
+			p.log("Static table %s unchanged; skipping import", table.Name)
+			continue
+		}
+		if !opts.inSubset(table.Name) {
+			continue
+		}
+		csvPath := filepath.Join(dataDir, table.Name+".csv")
 		if _, err := os.Stat(csvPath); err != nil {
 			p.log("No CSV file found for table: %s", table.Name)
 			continue
 		}
 		p.log("Importing data for table: %s", table.Name)
-		if err := p.copyCSVIntoTable(tx, table, csvPath); err != nil {
+		progress.Begin(table.Name)
+		_, remapped := appendKeys[table.Name]
+		if opts.keepsExisting(table.Name, remapped) || opts.upserts(table.Name) {
+			if err := p.mergeCSVIntoTable(tx, table, csvPath, opts.upserts(table.Name), progress); err != nil {
+				return fmt.Errorf("merging data for table %s: %v", table.Name, err)
+			}
+		} else if err := p.copyCSVIntoTable(tx, table, csvPath, progress); err != nil {
 			return fmt.Errorf("importing data for table %s: %v", table.Name, err)
 		}
+		progress.End()
 		p.log("Imported data for table: %s", table.Name)
+		loaded = append(loaded, table.Name)
 
 		// Queue sequence resets for serial/identity columns; they all run
 		// in a single statement just before commit.
 		for _, col := range table.Columns {
+			if seq := nextvalSequence(columnDefaultString(col.Default)); standalone[seq] {
+				// A standalone sequence may feed several tables, so it is
+				// only ever moved forward, never back to this table's max.
+				sequenceResets = append(sequenceResets, fmt.Sprintf(
+					"SELECT setval(%s, m) FROM (SELECT MAX(%s) AS m FROM %s) q WHERE m >= (SELECT last_value FROM %s);",
+					pq.QuoteLiteral(pq.QuoteIdentifier(seq)),
+					pq.QuoteIdentifier(col.Name), pq.QuoteIdentifier(table.Name),
+					pq.QuoteIdentifier(seq)))
+				continue
+			}
 			if strings.Contains(fmt.Sprintf("%v", col.Default), "nextval") ||
-				strings.Contains(col.Type, "serial") {
+				strings.Contains(col.Type, "serial") || col.Identity != "" {
 				sequenceResets = append(sequenceResets, fmt.Sprintf(
 					"SELECT setval(seq, COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false) FROM (SELECT pg_get_serial_sequence('%s', '%s') AS seq) q WHERE seq IS NOT NULL;",
 					pq.QuoteIdentifier(col.Name), pq.QuoteIdentifier(table.Name),
@@ -673,73 +1300,66 @@ func (p *PostgresManager) RestoreFromCSV(directory string) error {
 		}
 	}
 
+	progress.Clear()
+
 	if len(sequenceResets) > 0 {
 		batch := strings.Join(sequenceResets, "\n")
 		p.logSQL("Reset Sequences", batch)
-		if _, err := tx.Exec(batch); err != nil {
+		if err := execSavepoint(ctx, tx, batch); err != nil {
 			// Don't fail the restore over sequence bookkeeping; matches the
 			// historical warn-and-continue behaviour.
 			p.log("Warning: failed to reset sequences: %v", err)
 		}
 	}
 
+	// Materialized views were created empty; fill them from the loaded
+	// rows, dependencies first.
+	for _, view := range views {
+		if !view.Materialized {
+			continue
+		}
+		refreshSQL := "REFRESH MATERIALIZED VIEW " + pq.QuoteIdentifier(view.Name)
+		p.logSQL("Refresh "+view.Name, refreshSQL)
+		if err := execSavepoint(ctx, tx, refreshSQL); err != nil {
+			ui.Warn("Could not refresh materialized view %s: %v", view.Name, err)
+		}
+	}
+
+	if opts.Record != nil {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT seedmancer_step"); err != nil {
+			return err
+		}
+		if err := recordSeed(ctx, tx, postgresHistory, *opts.Record); err != nil {
+			ui.Warn("%v", err)
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT seedmancer_step"); err != nil {
+				return err
+			}
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("committing import transaction: %v", err)
+		return fmt.Errorf("committing restore transaction: %v", err)
 	}
 	committed = true
 
-	return nil
+	if err := warmUp(ctx, conn, "ANALYZE", pq.QuoteIdentifier, loaded, opts, p.logSQL); err != nil {
+		return err
+	}
+	return p.reclaimPostgres(ctx, conn, loaded, opts)
 }
 
 // buildCreateTableSQL renders the CREATE TABLE statement for a table.
 // Foreign key constraints are always skipped — they are added afterwards
-// once every referenced table exists.
-func (p *PostgresManager) buildCreateTableSQL(table Table) string {
+// once every referenced table exists. standalone names the schema's
+// standalone sequences: a nextval default on one of those is kept as is
+// rather than turned into a SERIAL with a sequence of its own.
+func (p *PostgresManager) buildCreateTableSQL(table Table, standalone map[string]bool) string {
 	var columnDefs []string
 	var primaryKeys []string
 	var uniqueConstraints []string
 
 	for _, col := range table.Columns {
-		colDef := fmt.Sprintf("%s ", pq.QuoteIdentifier(col.Name))
-
-		defaultStr := columnDefaultString(col.Default)
-		isNextval := strings.Contains(strings.ToLower(defaultStr), "nextval(")
-
-		if isNextval {
-			// Convert integer+nextval → SERIAL, bigint+nextval → BIGSERIAL.
-			// This lets PostgreSQL create the backing sequence automatically.
-			switch strings.ToLower(col.Type) {
-			case "bigint":
-				colDef += "BIGSERIAL"
-			case "smallint":
-				colDef += "SMALLSERIAL"
-			default:
-				colDef += "SERIAL"
-			}
-			if !col.Nullable {
-				colDef += " NOT NULL"
-			}
-		} else {
-			if col.Type == "enum" && col.Enum != "" {
-				colDef += pq.QuoteIdentifier(col.Enum)
-			} else if strings.HasPrefix(col.Type, "ARRAY") {
-				colDef += "text[]"
-			} else if (col.Type == "character varying" || col.Type == "varchar") && col.Varchar != nil {
-				colDef += fmt.Sprintf("varchar(%s)", *col.Varchar)
-			} else {
-				colDef += col.Type
-			}
-
-			if !col.Nullable {
-				colDef += " NOT NULL"
-			}
-
-			if defaultStr != "" {
-				colDef += " DEFAULT " + defaultStr
-			}
-		}
-
-		columnDefs = append(columnDefs, colDef)
+		columnDefs = append(columnDefs, p.columnDefSQL(col, standalone))
 
 		if col.IsPrimary {
 			primaryKeys = append(primaryKeys, col.Name)
@@ -758,7 +1378,9 @@ func (p *PostgresManager) buildCreateTableSQL(table Table) string {
 		columnDefs = append(columnDefs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pkNames, ", ")))
 	}
 
-	// Add unique constraints
+	// Add unique constraints, by column name so the statement doesn't
+	// change when columns are reordered
+	sort.Strings(uniqueConstraints)
 	for _, uniqueCol := range uniqueConstraints {
 		columnDefs = append(columnDefs, fmt.Sprintf("UNIQUE (%s)", pq.QuoteIdentifier(uniqueCol)))
 	}
@@ -768,13 +1390,105 @@ func (p *PostgresManager) buildCreateTableSQL(table Table) string {
 		strings.Join(columnDefs, ",\n  "))
 }
 
+// createEnumSQL renders the CREATE TYPE statement for an enum, its labels
+// in declaration order.
+func createEnumSQL(enum EnumItem) string {
+	return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)",
+		pq.QuoteIdentifier(enum.Name), joinQuotedStrings(enum.Values))
+}
+
+// columnDefSQL is col's definition inside CREATE TABLE or ADD COLUMN,
+// without key constraints.
+func (p *PostgresManager) columnDefSQL(col Column, standalone map[string]bool) string {
+	colDef := fmt.Sprintf("%s ", pq.QuoteIdentifier(col.Name))
+
+	defaultStr := columnDefaultString(col.Default)
+	isNextval := strings.Contains(strings.ToLower(defaultStr), "nextval(") &&
+		!standalone[nextvalSequence(defaultStr)]
+
+	if col.Identity != "" {
+		// Identity columns are NOT NULL implicitly.
+		colDef += fmt.Sprintf("%s GENERATED %s AS IDENTITY", col.Type, col.Identity)
+	} else if isNextval {
+		// Convert integer+nextval → SERIAL, bigint+nextval → BIGSERIAL.
+		// This lets PostgreSQL create the backing sequence automatically.
+		switch strings.ToLower(col.Type) {
+		case "bigint":
+			colDef += "BIGSERIAL"
+		case "smallint":
+			colDef += "SMALLSERIAL"
+		default:
+			colDef += "SERIAL"
+		}
+		if !col.Nullable {
+			colDef += " NOT NULL"
+		}
+	} else {
+		if col.Domain != "" {
+			colDef += pq.QuoteIdentifier(col.Domain)
+		} else if col.Composite != "" {
+			colDef += pq.QuoteIdentifier(col.Composite)
+		} else if col.Type == "enum" && col.Enum != "" {
+			colDef += pq.QuoteIdentifier(col.Enum)
+		} else if strings.HasPrefix(col.Type, "ARRAY") {
+			colDef += arrayTypeSQL(col)
+		} else if (col.Type == "character varying" || col.Type == "varchar") && col.Varchar != nil {
+			colDef += fmt.Sprintf("varchar(%s)", *col.Varchar)
+		} else if isSpatialType(col.Type) {
+			colDef += spatialTypeSQL(col)
+		} else if col.Type == "numeric" && col.Precision != nil {
+			scale := 0
+			if col.Scale != nil {
+				scale = *col.Scale
+			}
+			colDef += fmt.Sprintf("numeric(%d,%d)", *col.Precision, scale)
+		} else {
+			colDef += col.Type
+		}
+
+		if !col.Nullable {
+			colDef += " NOT NULL"
+		}
+
+		if defaultStr != "" {
+			colDef += " DEFAULT " + defaultStr
+		}
+	}
+	return colDef
+}
+
 // addMissingForeignKeys adds every FK constraint from the schema that is
 // not already present, in a single round trip. existingTables is the
 // pre-restore table snapshot; tables in schema.Tables that were missing
 // have just been created, so a referenced table exists when it is in
 // either set. Failures are logged, not fatal — matching the historical
 // warn-and-continue behaviour for constraint setup.
-func (p *PostgresManager) addMissingForeignKeys(ctx context.Context, conn *sql.Conn, schema *Schema, existingTables, existingFKs map[string]bool) error {
+func (p *PostgresManager) addMissingForeignKeys(ctx context.Context, tx *sql.Tx, schema *Schema, existingTables, existingFKs map[string]bool) error {
+	alterStmts := p.foreignKeySQL(schema, existingTables, existingFKs)
+	if len(alterStmts) == 0 {
+		return nil
+	}
+
+	batch := strings.Join(alterStmts, "\n")
+	p.logSQL("Add Foreign Keys", batch)
+	if err := execSavepoint(ctx, tx, batch); err == nil {
+		return nil
+	}
+
+	// The combined statement failed (e.g. one referenced column is gone).
+	// Retry one by one so a single bad constraint doesn't block the rest.
+	for _, stmt := range alterStmts {
+		if err := execSavepoint(ctx, tx, stmt); err != nil &&
+			!strings.Contains(err.Error(), "already exists") {
+			p.log("Warning: Failed to add foreign key (%s): %v", stmt, err)
+		}
+	}
+	return nil
+}
+
+// foreignKeySQL returns the ALTER TABLE statements adding schema's
+// missing FK constraints, sorted by table and constraint name.
+func (p *PostgresManager) foreignKeySQL(schema *Schema, existingTables, existingFKs map[string]bool) []string {
 	schemaTables := map[string]bool{}
 	for _, t := range schema.Tables {
 		schemaTables[t.Name] = true
@@ -804,34 +1518,115 @@ func (p *PostgresManager) addMissingForeignKeys(ctx context.Context, conn *sql.C
 				pq.QuoteIdentifier(col.ForeignKey.Column)))
 		}
 	}
-	if len(alterStmts) == 0 {
-		return nil
-	}
+	sort.Strings(alterStmts)
+	return alterStmts
+}
 
-	batch := strings.Join(alterStmts, "\n")
-	p.logSQL("Add Foreign Keys", batch)
-	if _, err := conn.ExecContext(ctx, batch); err == nil {
-		return nil
+// execSavepoint runs stmt inside a savepoint, so a failure undoes just
+// that statement and leaves tx usable for the rest of the restore.
+func execSavepoint(ctx context.Context, tx *sql.Tx, stmt string) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT seedmancer_step"); err != nil {
+		return err
 	}
-
-	// The combined statement failed (e.g. one referenced column is gone).
-	// Retry one by one so a single bad constraint doesn't block the rest.
-	for _, stmt := range alterStmts {
-		if _, err := conn.ExecContext(ctx, stmt); err != nil &&
-			!strings.Contains(err.Error(), "already exists") {
-			p.log("Warning: Failed to add foreign key (%s): %v", stmt, err)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT seedmancer_step"); rbErr != nil {
+			return rbErr
 		}
+		return err
 	}
-	return nil
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT seedmancer_step")
+	return err
 }
 
 // copyCSVIntoTable streams one CSV file into a table via COPY, inside the
 // caller's transaction. COPY data is pipelined by lib/pq, so the per-table
 // network cost is just the prepare + close round trips.
-func (p *PostgresManager) copyCSVIntoTable(tx *sql.Tx, table Table, csvPath string) error {
+func (p *PostgresManager) copyCSVIntoTable(tx *sql.Tx, table Table, csvPath string, progress *ui.TableProgress) error {
+	_, err := p.copyCSVInto(tx, table, table.Name, csvPath, progress)
+	return err
+}
+
+// mergeCSVIntoTable loads a CSV without disturbing other rows: the data
+// is COPYed into a temp table shaped like the target, then inserted with
+// ON CONFLICT. Rows whose key already exists are skipped, or overwritten
+// when upsert is set.
+func (p *PostgresManager) mergeCSVIntoTable(tx *sql.Tx, table Table, csvPath string, upsert bool, progress *ui.TableProgress) error {
+	staging := "seedmancer_merge_" + table.Name
+	createSQL := fmt.Sprintf("CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP",
+		pq.QuoteIdentifier(staging), pq.QuoteIdentifier(table.Name))
+	p.logSQL("Create Merge Staging "+table.Name, createSQL)
+	if _, err := tx.Exec(createSQL); err != nil {
+		return fmt.Errorf("creating staging table: %v", err)
+	}
+	header, err := p.copyCSVInto(tx, table, staging, csvPath, progress)
+	if err != nil {
+		return err
+	}
+	if len(header) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(header))
+	for i, h := range header {
+		quoted[i] = pq.QuoteIdentifier(h)
+	}
+	cols := strings.Join(quoted, ", ")
+	conflict := "ON CONFLICT DO NOTHING"
+	if upsert {
+		if table.PrimaryKey() == nil {
+			p.log("Warning: table %s has no primary key; upsert only skips rows that hit a unique constraint", table.Name)
+		}
+		conflict = pgUpsertClause(table, header)
+	}
+	// Fixture ids go into GENERATED ALWAYS identity columns as they are,
+	// the way COPY writes them.
+	overriding := ""
+	for _, col := range table.Columns {
+		if col.Identity == "ALWAYS" && indexOf(header, col.Name) >= 0 {
+			overriding = " OVERRIDING SYSTEM VALUE"
+			break
+		}
+	}
+	insertSQL := fmt.Sprintf("INSERT INTO %s (%s)%s SELECT %s FROM %s %s",
+		pq.QuoteIdentifier(table.Name), cols, overriding, cols, pq.QuoteIdentifier(staging), conflict)
+	p.logSQL("Merge "+table.Name, insertSQL)
+	if _, err := tx.Exec(insertSQL); err != nil {
+		return fmt.Errorf("merging rows: %v", err)
+	}
+	return nil
+}
+
+// pgUpsertClause builds the ON CONFLICT clause that overwrites existing
+// rows by primary key with the header columns from the CSV. Tables
+// without a primary key, or whose CSV holds only key columns, fall back
+// to DO NOTHING.
+func pgUpsertClause(table Table, header []string) string {
+	pk := table.PrimaryKey()
+	if len(pk) == 0 {
+		return "ON CONFLICT DO NOTHING"
+	}
+	var sets []string
+	for _, col := range upsertColumns(table, header) {
+		q := pq.QuoteIdentifier(col)
+		sets = append(sets, q+" = EXCLUDED."+q)
+	}
+	quotedPK := make([]string, len(pk))
+	for i, col := range pk {
+		quotedPK[i] = pq.QuoteIdentifier(col)
+	}
+	target := "(" + strings.Join(quotedPK, ", ") + ")"
+	if len(sets) == 0 {
+		return "ON CONFLICT " + target + " DO NOTHING"
+	}
+	return "ON CONFLICT " + target + " DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+// copyCSVInto streams csvPath into target (the table itself or a staging
+// copy of it), counting each row on progress, and returns the CSV header
+// it used.
+func (p *PostgresManager) copyCSVInto(tx *sql.Tx, table Table, target, csvPath string, progress *ui.TableProgress) ([]string, error) {
 	file, err := os.Open(csvPath)
 	if err != nil {
-		return fmt.Errorf("opening CSV file: %v", err)
+		return nil, fmt.Errorf("opening CSV file: %v", err)
 	}
 	defer file.Close()
 
@@ -843,7 +1638,7 @@ func (p *PostgresManager) copyCSVIntoTable(tx *sql.Tx, table Table, csvPath stri
 	reader := csv.NewReader(file)
 	header, err := reader.Read()
 	if err != nil {
-		return fmt.Errorf("reading CSV header: %v", err)
+		return nil, fmt.Errorf("reading CSV header: %v", err)
 	}
 	for _, colName := range header {
 		if _, exists := columnTypeMap[colName]; !exists {
@@ -851,12 +1646,13 @@ func (p *PostgresManager) copyCSVIntoTable(tx *sql.Tx, table Table, csvPath stri
 		}
 	}
 
-	stmt, err := tx.Prepare(pq.CopyIn(table.Name, header...))
+	stmt, err := tx.Prepare(pq.CopyIn(target, header...))
 	if err != nil {
-		return fmt.Errorf("preparing COPY statement: %v", err)
+		return nil, fmt.Errorf("preparing COPY statement: %v", err)
 	}
 
 	rowCount := 0
+	var exprRows []exprRow
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
@@ -864,12 +1660,19 @@ func (p *PostgresManager) copyCSVIntoTable(tx *sql.Tx, table Table, csvPath stri
 		}
 		if err != nil {
 			stmt.Close()
-			return fmt.Errorf("reading CSV record: %v", err)
+			return nil, fmt.Errorf("reading CSV record: %v", err)
 		}
 
 		if len(record) != len(header) {
 			stmt.Close()
-			return fmt.Errorf("column count mismatch: expected %d, got %d in row %d", len(header), len(record), rowCount+1)
+			return nil, fmt.Errorf("column count mismatch: expected %d, got %d in row %d", len(header), len(record), rowCount+1)
+		}
+		if hasExpr(record) {
+			// COPY only carries literals; these rows are inserted below.
+			exprRows = append(exprRows, exprRow{n: rowCount + 1, record: record})
+			rowCount++
+			progress.Rows(1)
+			continue
 		}
 
 		values := make([]interface{}, len(record))
@@ -879,17 +1682,60 @@ func (p *PostgresManager) copyCSVIntoTable(tx *sql.Tx, table Table, csvPath stri
 
 		if _, err := stmt.Exec(values...); err != nil {
 			stmt.Close()
-			return fmt.Errorf("executing COPY for table %s row %d: %v\nValues: %v", table.Name, rowCount+1, err, values)
+			return nil, fmt.Errorf("executing COPY for table %s row %d: %v\nValues: %v", table.Name, rowCount+1, err, values)
 		}
 		rowCount++
+		progress.Rows(1)
 	}
 
 	// Close the prepared statement to complete the COPY operation
 	if err := stmt.Close(); err != nil {
-		return fmt.Errorf("closing COPY statement: %v", err)
+		return nil, fmt.Errorf("closing COPY statement: %v", err)
+	}
+	if err := p.insertExprRows(tx, table, target, header, columnTypeMap, exprRows); err != nil {
+		return nil, err
 	}
 
 	ui.Debug("Imported %d rows into %s", rowCount, table.Name)
+	return header, nil
+}
+
+// insertExprRows inserts the rows holding expression cells (see package
+// cellexpr) with one INSERT each: literal cells are bound as parameters
+// and expressions are written into VALUES for the database to evaluate.
+func (p *PostgresManager) insertExprRows(tx *sql.Tx, table Table, target string, header []string, columnTypeMap map[string]string, rows []exprRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(header))
+	for i, h := range header {
+		quoted[i] = pq.QuoteIdentifier(h)
+	}
+	overriding := ""
+	for _, col := range table.Columns {
+		if col.Identity == "ALWAYS" && indexOf(header, col.Name) >= 0 {
+			overriding = " OVERRIDING SYSTEM VALUE"
+			break
+		}
+	}
+	for _, row := range rows {
+		values := make([]string, len(row.record))
+		var args []interface{}
+		for i, v := range row.record {
+			if expr, ok := cellexpr.Parse(v); ok {
+				values[i] = expr
+				continue
+			}
+			args = append(args, p.processCSVValue(v, columnTypeMap[header[i]]))
+			values[i] = fmt.Sprintf("$%d", len(args))
+		}
+		insertSQL := fmt.Sprintf("INSERT INTO %s (%s)%s VALUES (%s)",
+			pq.QuoteIdentifier(target), strings.Join(quoted, ", "), overriding, strings.Join(values, ", "))
+		p.logSQL(fmt.Sprintf("Insert %s row %d", table.Name, row.n), insertSQL)
+		if _, err := tx.Exec(insertSQL, args...); err != nil {
+			return fmt.Errorf("inserting row %d of table %s with SQL expressions: %v", row.n, table.Name, err)
+		}
+	}
 	return nil
 }
 
@@ -916,6 +1762,15 @@ func (p *PostgresManager) processCSVValue(value string, columnType string) inter
 		return nil
 	}
 
+	// bytea cells are \x hex; anything else is a CSV from before binary
+	// columns were encoded and goes in as written.
+	if isBinaryType(colType) {
+		if b, ok := decodeBinaryCell(value); ok {
+			return b
+		}
+		return value
+	}
+
 	// Handle JSON and JSONB types
 	if colType == "json" || colType == "jsonb" {
 		// Try to parse as JSON
@@ -1120,14 +1975,15 @@ func (p *PostgresManager) ReadSchemaFromFile(filename string) (*Schema, error) {
 		return nil, err
 	}
 
-	var schema Schema
-	if err := json.Unmarshal(data, &schema); err != nil {
+	schema, err := parseSchema(data)
+	if err != nil {
 		return nil, fmt.Errorf("parsing schema file: %v", err)
 	}
 
 	// Validate database type if specified
-	if schema.DatabaseType != "" && schema.DatabaseType != "postgres" {
-		p.log("Warning: Schema was created for %s database, but using with PostgreSQL", schema.DatabaseType)
+	if schema.DatabaseType != "" && !sameFamily(schema.DatabaseType, Postgres) {
+		p.log("Warning: Schema was created for %s database, converting its types for PostgreSQL", schema.DatabaseType)
+		convertToPostgres(&schema)
 	}
 
 	// Enum creation happens in RestoreFromCSV (batched with the other
@@ -1135,6 +1991,89 @@ func (p *PostgresManager) ReadSchemaFromFile(filename string) (*Schema, error) {
 	return &schema, nil
 }
 
+// nextvalArg matches the sequence in a nextval default such as
+// nextval('order_no_seq'::regclass) or nextval('public."Order_No"').
+var nextvalArg = regexp.MustCompile(`(?i)nextval\(\s*'([^']+)'`)
+
+// nextvalSequence returns the unqualified name of the sequence a column
+// default calls nextval on, or "" when it doesn't.
+func nextvalSequence(def string) string {
+	m := nextvalArg.FindStringSubmatch(def)
+	if m == nil {
+		return ""
+	}
+	// Drop a schema qualifier: everything up to the last dot outside quotes.
+	name, start, quoted := m[1], 0, false
+	for i, r := range name {
+		switch r {
+		case '"':
+			quoted = !quoted
+		case '.':
+			if !quoted {
+				start = i + 1
+			}
+		}
+	}
+	name = name[start:]
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	return name
+}
+
+// orderViews sorts views so each comes after the views it depends on,
+// keeping the schema order otherwise. Dependencies on views not in the
+// list are ignored; a cycle is an error.
+func orderViews(views []View) ([]View, error) {
+	byName := make(map[string]View, len(views))
+	for _, v := range views {
+		byName[v.Name] = v
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	ordered := make([]View, 0, len(views))
+	var visit func(v View) error
+	visit = func(v View) error {
+		switch state[v.Name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("views depend on each other in a cycle through %s", v.Name)
+		}
+		state[v.Name] = visiting
+		for _, dep := range v.DependsOn {
+			if d, ok := byName[dep]; ok {
+				if err := visit(d); err != nil {
+					return err
+				}
+			}
+		}
+		state[v.Name] = done
+		ordered = append(ordered, v)
+		return nil
+	}
+	for _, v := range views {
+		if err := visit(v); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// createSequenceSQL renders the CREATE SEQUENCE statement for a
+// standalone sequence.
+func createSequenceSQL(seq Sequence) string {
+	stmt := fmt.Sprintf("CREATE SEQUENCE %s AS %s INCREMENT BY %d MINVALUE %d MAXVALUE %d START WITH %d",
+		pq.QuoteIdentifier(seq.Name), seq.DataType, seq.Increment, seq.MinValue, seq.MaxValue, seq.Start)
+	if seq.Cycle {
+		stmt += " CYCLE"
+	}
+	return stmt
+}
+
 // columnDefaultString extracts the default value as a string, handling the
 // untyped interface{} from JSON unmarshalling.
 func columnDefaultString(v interface{}) string {
@@ -1158,9 +2097,44 @@ func joinQuotedStrings(values []string) string {
 	return strings.Join(quoted, ", ")
 }
 
+// CompareWithCSV implements DatabaseManager for tables in the public schema.
+func (p *PostgresManager) CompareWithCSV(directory string, tables []string) ([]TableDrift, error) {
+	if p.DB == nil {
+		return nil, errors.New("no database connection")
+	}
+	ctx := context.Background()
+	existing := map[string]bool{}
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying existing tables: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning existing tables: %v", err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading existing tables: %v", err)
+	}
+	return compareTables(ctx, p.DB, pq.QuoteIdentifier, directory, tables, existing)
+}
+
 func (p *PostgresManager) ExportToCSV(outputDir string) error {
+	_, err := p.ExportToCSVWithOptions(outputDir, ExportOptions{})
+	return err
+}
+
+// ExportToCSVWithOptions writes one CSV per table into outputDir and
+// returns the tables it skipped as unreadable.
+func (p *PostgresManager) ExportToCSVWithOptions(outputDir string, opts ExportOptions) ([]string, error) {
 	if p.DB == nil {
-		return errors.New("no database connection")
+		return nil, errors.New("no database connection")
 	}
 
 	// Get list of tables
@@ -1169,9 +2143,10 @@ func (p *PostgresManager) ExportToCSV(outputDir string) error {
 		FROM information_schema.tables 
 		WHERE table_schema = 'public' 
 		AND table_type = 'BASE TABLE'
+		AND table_name <> '_seedmancer_history'
 	`)
 	if err != nil {
-		return fmt.Errorf("querying tables: %v", err)
+		return nil, fmt.Errorf("querying tables: %v", err)
 	}
 	defer rows.Close()
 
@@ -1179,37 +2154,58 @@ func (p *PostgresManager) ExportToCSV(outputDir string) error {
 	for rows.Next() {
 		var tableName string
 		if err := rows.Scan(&tableName); err != nil {
-			return fmt.Errorf("scanning table name: %v", err)
+			return nil, fmt.Errorf("scanning table name: %v", err)
 		}
 		tables = append(tables, tableName)
 	}
 
+	if err := opts.checkFilters(tables); err != nil {
+		return nil, err
+	}
+	if tables, err = opts.selectTables(tables); err != nil {
+		return nil, err
+	}
+	// reltuples is -1 for a table never vacuumed or analyzed.
+	progress := ui.StartTableProgress(ui.PhaseExport, tables, opts.rowEstimates(p.DB, `
+		SELECT c.relname, c.reltuples::bigint
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p')`))
+	var skipped []string
 	for _, tableName := range tables {
-		if err := p.exportTableToCSV(tableName, outputDir); err != nil {
-			return fmt.Errorf("exporting table %s: %v", tableName, err)
+		progress.Begin(tableName)
+		if err := p.exportTableToCSV(tableName, outputDir, opts, progress); err != nil {
+			progress.Clear()
+			if opts.SkipUnreadable && isPermissionDenied(err) {
+				csvshard.Remove(filepath.Join(outputDir, tableName+".csv"))
+				ui.Warn("Skipping table %s: %v", tableName, err)
+				skipped = append(skipped, tableName)
+				progress.End()
+				continue
+			}
+			return nil, fmt.Errorf("exporting table %s: %v", tableName, err)
 		}
+		progress.End()
 		ui.Debug("Exported table: %s", tableName)
 	}
-	ui.Success("Exported %d table(s)", len(tables))
+	progress.Clear()
+	ui.Success("Exported %d table(s)", len(tables)-len(skipped))
 
-	return nil
+	return skipped, nil
 }
 
-func (p *PostgresManager) exportTableToCSV(tableName, outputDir string) error {
-	// Create CSV file
-	csvPath := filepath.Join(outputDir, fmt.Sprintf("%s.csv", tableName))
-	file, err := os.Create(csvPath)
+// exportTableToCSV writes tableName's rows to <outputDir>/<tableName>.csv,
+// or its shards, as far as opts' filters and excluded columns let
+// through, counting each on progress.
+func (p *PostgresManager) exportTableToCSV(tableName, outputDir string, opts ExportOptions, progress *ui.TableProgress) error {
+	writer, err := opts.createCSV(outputDir, tableName)
 	if err != nil {
-		return fmt.Errorf("creating CSV file: %v", err)
+		return err
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	defer writer.Close()
 
 	// Get column names
 	rows, err := p.DB.Query(fmt.Sprintf(`
-		SELECT column_name 
+		SELECT column_name, udt_name
 		FROM information_schema.columns 
 		WHERE table_schema = 'public' 
 		AND table_name = '%s' 
@@ -1221,14 +2217,18 @@ func (p *PostgresManager) exportTableToCSV(tableName, outputDir string) error {
 	defer rows.Close()
 
 	var columns []string
+	spatial := map[string]bool{}
 	for rows.Next() {
-		var colName string
-		if err := rows.Scan(&colName); err != nil {
+		var colName, udtName string
+		if err := rows.Scan(&colName, &udtName); err != nil {
 			return fmt.Errorf("scanning column name: %v", err)
 		}
 		columns = append(columns, colName)
+		spatial[colName] = isSpatialType(udtName)
 	}
 
+	columns = opts.columns(tableName, columns)
+
 	// Write header
 	if err := writer.Write(columns); err != nil {
 		return fmt.Errorf("writing CSV header: %v", err)
@@ -1238,18 +2238,27 @@ func (p *PostgresManager) exportTableToCSV(tableName, outputDir string) error {
 	quotedColumns := make([]string, len(columns))
 	for i, col := range columns {
 		quotedColumns[i] = pq.QuoteIdentifier(col)
+		if spatial[col] {
+			// EWKT rather than the default hex EWKB, so the CSV stays
+			// readable and keeps the SRID.
+			quotedColumns[i] = fmt.Sprintf("ST_AsEWKT(%s) AS %s", quotedColumns[i], quotedColumns[i])
+		}
 	}
 
-	query := fmt.Sprintf("SELECT %s FROM %s",
+	query := fmt.Sprintf("SELECT %s FROM %s%s",
 		strings.Join(quotedColumns, ", "),
-		pq.QuoteIdentifier(tableName))
+		pq.QuoteIdentifier(tableName), opts.where(tableName))
 	p.logSQL(fmt.Sprintf("Export Table %s", tableName), query)
 
 	dataRows, err := p.DB.Query(query)
 	if err != nil {
-		return fmt.Errorf("querying data: %v", err)
+		return fmt.Errorf("querying data: %w", err)
 	}
 	defer dataRows.Close()
+	binary, err := binaryColumns(dataRows)
+	if err != nil {
+		return err
+	}
 
 	// Write data rows
 	values := make([]interface{}, len(columns))
@@ -1265,27 +2274,16 @@ func (p *PostgresManager) exportTableToCSV(tableName, outputDir string) error {
 
 		row := make([]string, len(columns))
 		for i, val := range values {
-			if val == nil {
-				row[i] = "NULL"
-			} else {
-				switch v := val.(type) {
-				case []byte:
-					row[i] = string(v)
-				case time.Time:
-					// Format timestamp with correct timezone format
-					row[i] = v.Format("2006-01-02 15:04:05.999999 -0700 UTC")
-				default:
-					row[i] = fmt.Sprintf("%v", v)
-				}
-			}
+			row[i] = formatCSVCell(val, binary[i])
 		}
 
 		if err := writer.Write(row); err != nil {
 			return fmt.Errorf("writing CSV row: %v", err)
 		}
+		progress.Rows(1)
 	}
 
-	return nil
+	return writer.Close()
 }
 
 // ExportSchema exports the database schema to outputDir.
@@ -1345,9 +2343,11 @@ func (p *PostgresManager) ExportSchema(outputDir string) error {
 
 	// --- Write schema.json (tables + enums only, no functions/triggers) ---
 	schemaForJSON := Schema{
-		DatabaseType: schema.DatabaseType,
-		Enums:        schema.Enums,
-		Tables:       schema.Tables,
+		DatabaseType:   schema.DatabaseType,
+		Enums:          schema.Enums,
+		Tables:         schema.Tables,
+		Domains:        schema.Domains,
+		CompositeTypes: schema.CompositeTypes,
 	}
 
 	jsonData, err := json.MarshalIndent(schemaForJSON, "", "  ")
@@ -1,10 +1,15 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -139,6 +144,204 @@ INSERT INTO public.seedmancer_it_books (id, author_id, title, rating) VALUES
 	if name != "Carol's" {
 		t.Fatalf("name round-trip = %q, want %q", name, "Carol's")
 	}
+
+	// A freshly restored table matches its fixture; dropping it shows up
+	// as a table seed would create.
+	drift, err := pg.CompareWithCSV(restoreDir, []string{"seedmancer_it_books"})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	if len(drift) != 1 || !drift[0].Exists || !drift[0].Identical || drift[0].LiveRows != 3 {
+		t.Fatalf("drift after restore = %+v, want identical with 3 rows", drift)
+	}
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("drop: %v", err)
+	}
+	drift, err = pg.CompareWithCSV(restoreDir, []string{"seedmancer_it_books"})
+	if err != nil {
+		t.Fatalf("compare after drop: %v", err)
+	}
+	if drift[0].Exists || drift[0].FixtureRows != 3 {
+		t.Fatalf("drift after drop = %+v, want missing table with 3 fixture rows", drift[0])
+	}
+
+	// A restore that fails on the last table's data rolls back everything,
+	// including the tables it had already created.
+	badBooks := "id,author_id,title,rating\n10,1,First Book\n"
+	if err := os.WriteFile(filepath.Join(restoreDir, "seedmancer_it_books.csv"), []byte(badBooks), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.RestoreFromCSV(restoreDir); err == nil {
+		t.Fatal("restore with a malformed CSV should fail")
+	}
+	var leftover int
+	if err := raw.QueryRow(`SELECT COUNT(*) FROM information_schema.tables WHERE table_name LIKE 'seedmancer_it_%'`).Scan(&leftover); err != nil {
+		t.Fatalf("count tables: %v", err)
+	}
+	if leftover != 0 {
+		t.Fatalf("%d table(s) left behind by the failed restore, want 0", leftover)
+	}
+}
+
+// TestPostgresIntegration_IdentityAndSequences checks that identity
+// columns and standalone sequences survive export → drop → restore, and
+// that both continue past the restored ids. Same gating as above.
+func TestPostgresIntegration_IdentityAndSequences(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+
+	dropAll := `
+DROP TABLE IF EXISTS public.seedmancer_it_orders CASCADE;
+DROP SEQUENCE IF EXISTS public.seedmancer_it_order_no;
+`
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+
+	ddl := `
+CREATE SEQUENCE public.seedmancer_it_order_no START WITH 1000 INCREMENT BY 10;
+
+CREATE TABLE public.seedmancer_it_orders (
+    id        INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    order_no  BIGINT NOT NULL DEFAULT nextval('public.seedmancer_it_order_no'),
+    note      TEXT
+);
+
+INSERT INTO public.seedmancer_it_orders (note) VALUES ('a'), ('b'), ('c');
+`
+	if _, err := raw.Exec(ddl); err != nil {
+		t.Fatalf("ddl: %v", err)
+	}
+
+	pg := &PostgresManager{}
+	if err := pg.ConnectWithDSN(dsn); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	restoreDir := t.TempDir()
+	if err := pg.ExportSchema(restoreDir); err != nil {
+		t.Fatalf("export schema: %v", err)
+	}
+	if err := pg.ExportToCSV(restoreDir); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("mid-clean: %v", err)
+	}
+	if err := pg.RestoreFromCSV(restoreDir); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	var identity string
+	if err := raw.QueryRow(`SELECT identity_generation FROM information_schema.columns
+		WHERE table_name = 'seedmancer_it_orders' AND column_name = 'id'`).Scan(&identity); err != nil {
+		t.Fatalf("identity_generation: %v", err)
+	}
+	if identity != "ALWAYS" {
+		t.Fatalf("id identity = %q, want ALWAYS", identity)
+	}
+
+	var id, orderNo int64
+	if err := raw.QueryRow(`INSERT INTO public.seedmancer_it_orders (note) VALUES ('d') RETURNING id, order_no`).Scan(&id, &orderNo); err != nil {
+		t.Fatalf("insert after restore: %v", err)
+	}
+	if id != 4 || orderNo != 1030 {
+		t.Fatalf("next row = (id %d, order_no %d), want (4, 1030)", id, orderNo)
+	}
+}
+
+// TestPostgresIntegration_TriggersAndFunctions checks that a user-defined
+// trigger function and the trigger using it survive export → drop →
+// restore and fire afterwards. Same gating as above.
+func TestPostgresIntegration_TriggersAndFunctions(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+
+	dropAll := `
+DROP TABLE IF EXISTS public.seedmancer_it_notes CASCADE;
+DROP FUNCTION IF EXISTS public.seedmancer_it_touch();
+`
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+
+	ddl := `
+CREATE TABLE public.seedmancer_it_notes (
+    id          INTEGER PRIMARY KEY,
+    body        TEXT NOT NULL,
+    updated_at  TIMESTAMP NOT NULL
+);
+
+CREATE FUNCTION public.seedmancer_it_touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER seedmancer_it_notes_touch
+BEFORE UPDATE ON public.seedmancer_it_notes
+FOR EACH ROW EXECUTE FUNCTION public.seedmancer_it_touch();
+
+INSERT INTO public.seedmancer_it_notes VALUES (1, 'first', '2000-01-01 00:00:00');
+`
+	if _, err := raw.Exec(ddl); err != nil {
+		t.Fatalf("ddl: %v", err)
+	}
+
+	pg := &PostgresManager{}
+	if err := pg.ConnectWithDSN(dsn); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	restoreDir := t.TempDir()
+	if err := pg.ExportSchema(restoreDir); err != nil {
+		t.Fatalf("export schema: %v", err)
+	}
+	if err := pg.ExportToCSV(restoreDir); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(restoreDir, "seedmancer_it_touch_func.sql")); err != nil {
+		t.Fatalf("function sidecar missing: %v", err)
+	}
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("mid-clean: %v", err)
+	}
+	if err := pg.RestoreFromCSV(restoreDir); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	// The restore itself must not have fired the trigger…
+	var updatedAt time.Time
+	if err := raw.QueryRow(`SELECT updated_at FROM public.seedmancer_it_notes WHERE id = 1`).Scan(&updatedAt); err != nil {
+		t.Fatalf("scan updated_at: %v", err)
+	}
+	if updatedAt.Year() != 2000 {
+		t.Fatalf("updated_at after restore = %v, want the fixture value", updatedAt)
+	}
+	// …but an application update afterwards must.
+	if err := raw.QueryRow(`UPDATE public.seedmancer_it_notes SET body = 'edited' WHERE id = 1 RETURNING updated_at`).Scan(&updatedAt); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updatedAt.Year() == 2000 {
+		t.Fatal("updated_at unchanged after UPDATE; trigger was not restored")
+	}
 }
 
 func mustCopyDir(t *testing.T, src, dst string) {
@@ -160,3 +363,596 @@ func mustCopyDir(t *testing.T, src, dst string) {
 		}
 	}
 }
+
+// TestPostgresIntegration_WaitForReplica points the primary and the
+// replica at the same database, so the counts converge on the first probe.
+func TestPostgresIntegration_WaitForReplica(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+
+	drop := `DROP TABLE IF EXISTS public.seedmancer_it_replica CASCADE;`
+	if _, err := raw.Exec(drop + `
+CREATE TABLE public.seedmancer_it_replica (id INTEGER PRIMARY KEY);
+INSERT INTO public.seedmancer_it_replica VALUES (1), (2);`); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(drop) })
+
+	var probes int
+	if err := WaitForReplica(context.Background(), dsn, dsn, []string{"seedmancer_it_replica"}, 5*time.Second, func(string) { probes++ }); err != nil {
+		t.Fatalf("WaitForReplica: %v", err)
+	}
+	if probes != 0 {
+		t.Fatalf("probes = %d, want convergence on the first check", probes)
+	}
+}
+
+func TestPostgresIntegration_Snapshots(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	// Snapshots drop and recreate the database they restore, so the test
+	// works in a database of its own rather than the one dsn names.
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+	const dbName = "seedmancer_it_snap"
+	dropAll := `DROP DATABASE IF EXISTS seedmancer_it_snap; DROP DATABASE IF EXISTS seedmancer_it_snap__snap_clean;`
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	if _, err := raw.Exec("CREATE DATABASE " + dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + dbName
+	target := u.String()
+	exec := func(q string) {
+		t.Helper()
+		conn, err := sql.Open("postgres", target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	exec(`CREATE TABLE items (id INTEGER PRIMARY KEY); INSERT INTO items VALUES (1), (2);`)
+
+	ctx := context.Background()
+	if err := CreateSnapshot(ctx, target, "clean"); err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if err := CreateSnapshot(ctx, target, "clean"); err == nil {
+		t.Fatal("expected an error for an existing snapshot")
+	}
+	exec(`DELETE FROM items; INSERT INTO items VALUES (3);`)
+
+	if err := RestoreSnapshot(ctx, target, "clean"); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	conn, err := sql.Open("postgres", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var sum int
+	if err := conn.QueryRow("SELECT SUM(id) FROM items").Scan(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 3 {
+		t.Fatalf("SUM(id) = %d after restore, want 3 (rows 1 and 2)", sum)
+	}
+
+	snaps, err := ListSnapshots(ctx, target)
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].Name != "clean" || snaps[0].CreatedAt.IsZero() {
+		t.Fatalf("ListSnapshots = %+v", snaps)
+	}
+	if err := DropSnapshot(ctx, target, "clean"); err != nil {
+		t.Fatalf("DropSnapshot: %v", err)
+	}
+}
+
+func TestPostgresIntegration_TemplateReset(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+	const dbName = "seedmancer_it_tpl"
+	tpl := TemplateDatabaseName("it0000000000000a")
+	dropAll := `DROP DATABASE IF EXISTS seedmancer_it_tpl; DROP DATABASE IF EXISTS ` + tpl + `;`
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	if _, err := raw.Exec("CREATE DATABASE " + dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + dbName
+	target := u.String()
+	exec := func(q string) {
+		t.Helper()
+		conn, err := sql.Open("postgres", target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	ctx := context.Background()
+	if reset, err := ResetFromTemplate(ctx, target, tpl); err != nil || reset {
+		t.Fatalf("ResetFromTemplate before SaveTemplate = %v, %v; want false, nil", reset, err)
+	}
+	exec(`CREATE TABLE items (id INTEGER PRIMARY KEY); INSERT INTO items VALUES (1), (2);`)
+	if err := SaveTemplate(ctx, target, tpl, "it"); err != nil {
+		t.Fatalf("SaveTemplate: %v", err)
+	}
+	exec(`DELETE FROM items;`)
+	if reset, err := ResetFromTemplate(ctx, target, tpl); err != nil || !reset {
+		t.Fatalf("ResetFromTemplate = %v, %v; want true, nil", reset, err)
+	}
+	conn, err := sql.Open("postgres", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var n int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM items").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("%d rows after reset, want 2", n)
+	}
+}
+
+// TestPostgresIntegration_CreateMissingOnly drifts a database away from
+// its export — a dropped table, a dropped column, an extra row — and
+// checks that a create-missing-only restore puts the structures back
+// without touching the rows. Same gating as above.
+func TestPostgresIntegration_CreateMissingOnly(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+
+	dropAll := `
+DROP TABLE IF EXISTS public.seedmancer_it_books   CASCADE;
+DROP TABLE IF EXISTS public.seedmancer_it_authors CASCADE;
+`
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+
+	ddl := `
+CREATE TABLE public.seedmancer_it_authors (
+    id     INTEGER PRIMARY KEY,
+    name   TEXT NOT NULL,
+    email  TEXT NOT NULL
+);
+CREATE TABLE public.seedmancer_it_books (
+    id         INTEGER PRIMARY KEY,
+    author_id  INTEGER REFERENCES public.seedmancer_it_authors(id)
+);
+INSERT INTO public.seedmancer_it_authors VALUES (1, 'Ann', 'ann@example.com');
+INSERT INTO public.seedmancer_it_books VALUES (10, 1);
+`
+	if _, err := raw.Exec(ddl); err != nil {
+		t.Fatalf("ddl: %v", err)
+	}
+
+	pg := &PostgresManager{}
+	if err := pg.ConnectWithDSN(dsn); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	restoreDir := t.TempDir()
+	if err := pg.ExportSchema(restoreDir); err != nil {
+		t.Fatalf("export schema: %v", err)
+	}
+	if err := pg.ExportToCSV(restoreDir); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+
+	drift := `
+DROP TABLE public.seedmancer_it_books;
+ALTER TABLE public.seedmancer_it_authors DROP COLUMN email;
+INSERT INTO public.seedmancer_it_authors VALUES (2, 'Bob');
+`
+	if _, err := raw.Exec(drift); err != nil {
+		t.Fatalf("drift: %v", err)
+	}
+	if err := pg.RestoreFromCSVWithOptions(restoreDir, RestoreOptions{CreateMissingOnly: true}); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	var authors, books int
+	if err := raw.QueryRow(`SELECT (SELECT count(*) FROM public.seedmancer_it_authors), (SELECT count(*) FROM public.seedmancer_it_books)`).Scan(&authors, &books); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if authors != 2 || books != 0 {
+		t.Fatalf("rows = (authors %d, books %d), want (2, 0)", authors, books)
+	}
+	var nullable string
+	if err := raw.QueryRow(`SELECT is_nullable FROM information_schema.columns
+		WHERE table_name = 'seedmancer_it_authors' AND column_name = 'email'`).Scan(&nullable); err != nil {
+		t.Fatalf("email column: %v", err)
+	}
+	if nullable != "YES" {
+		t.Fatalf("email is_nullable = %q, want YES", nullable)
+	}
+
+	// A second run finds nothing left to create.
+	if err := pg.RestoreFromCSVWithOptions(restoreDir, RestoreOptions{CreateMissingOnly: true}); err != nil {
+		t.Fatalf("second restore: %v", err)
+	}
+}
+
+// TestPostgresIntegration_ExpressionCells checks that =DEFAULT and
+// =expr(…) cells are evaluated by the database rather than loaded as
+// text. Same gating as above.
+func TestPostgresIntegration_ExpressionCells(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+
+	dropAll := `DROP TABLE IF EXISTS public.seedmancer_it_events CASCADE;`
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+
+	dir := t.TempDir()
+	schema := `{"databaseType":"postgres","tables":[{"name":"seedmancer_it_events","columns":[
+	  {"name":"id","type":"integer","isPrimary":true},
+	  {"name":"kind","type":"text","default":"'signup'::text"},
+	  {"name":"at","type":"timestamp with time zone","nullable":true}
+	]}]}`
+	csv := "id,kind,at\n" +
+		"1,login,2024-01-02 03:04:05+00\n" +
+		"2,=DEFAULT,=expr(now() - interval '3 days')\n"
+	if err := os.WriteFile(filepath.Join(dir, "schema.json"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "seedmancer_it_events.csv"), []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pg := &PostgresManager{}
+	if err := pg.ConnectWithDSN(dsn); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := pg.RestoreFromCSV(dir); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	var kind string
+	var ageSecs float64
+	if err := raw.QueryRow(`SELECT kind, EXTRACT(EPOCH FROM now() - at) FROM public.seedmancer_it_events WHERE id = 2`).Scan(&kind, &ageSecs); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	age := time.Duration(ageSecs * float64(time.Second))
+	if kind != "signup" {
+		t.Errorf("kind = %q, want the column default", kind)
+	}
+	if age < 71*time.Hour || age > 73*time.Hour {
+		t.Errorf("at is %s ago, want about 3 days", age)
+	}
+}
+
+// TestPostgresIntegration_RestoreJournal checks that a restore closes the
+// entry of an earlier one that never finished, and refuses to run while
+// that restore's session is still busy. Same gating as above.
+func TestPostgresIntegration_RestoreJournal(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+
+	dropAll := `
+DROP TABLE IF EXISTS public.seedmancer_it_authors CASCADE;
+DELETE FROM seedmancer.restore_journal WHERE target = 'public';
+`
+	for _, stmt := range postgresJournal.create {
+		if _, err := raw.Exec(stmt); err != nil {
+			t.Fatalf("journal: %v", err)
+		}
+	}
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+	if _, err := raw.Exec(`CREATE TABLE public.seedmancer_it_authors (id INTEGER PRIMARY KEY);
+INSERT INTO public.seedmancer_it_authors VALUES (1);`); err != nil {
+		t.Fatalf("ddl: %v", err)
+	}
+
+	pg := &PostgresManager{}
+	if err := pg.ConnectWithDSN(dsn); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	restoreDir := t.TempDir()
+	if err := pg.ExportSchema(restoreDir); err != nil {
+		t.Fatalf("export schema: %v", err)
+	}
+	if err := pg.ExportToCSV(restoreDir); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+
+	// A session that is busy right now: the restore must not end it.
+	ctx := context.Background()
+	busy, err := raw.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pid int64
+	if err := busy.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := raw.Exec(postgresJournal.insert, 1, "public", pid); err != nil {
+		t.Fatalf("insert journal: %v", err)
+	}
+	if err := pg.RestoreFromCSV(restoreDir); err == nil || !strings.Contains(err.Error(), "another restore") {
+		t.Fatalf("restore with a live earlier restore: err = %v, want another restore", err)
+	}
+
+	// Once that session is gone the entry is recovered and the restore runs.
+	busy.Close()
+	if _, err := raw.Exec("SELECT pg_terminate_backend($1)", pid); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var alive bool
+		if err := raw.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_stat_activity WHERE pid = $1)", pid).Scan(&alive); err != nil {
+			t.Fatal(err)
+		}
+		if !alive || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := pg.RestoreFromCSV(restoreDir); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	var running, done int
+	if err := raw.QueryRow(`SELECT count(*) FILTER (WHERE status = 'running'), count(*) FILTER (WHERE status = 'done')
+		FROM seedmancer.restore_journal WHERE target = 'public'`).Scan(&running, &done); err != nil {
+		t.Fatalf("journal: %v", err)
+	}
+	if running != 0 || done != 1 {
+		t.Fatalf("journal = (running %d, done %d), want (0, 1)", running, done)
+	}
+}
+
+// TestPostgresIntegration_SeedHistory checks that a restore with a Record
+// appends it to the history table, that SeedHistory reads it back, and
+// that the table stays out of exports. Same gating as above.
+func TestPostgresIntegration_SeedHistory(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+
+	dropAll := `
+DROP TABLE IF EXISTS public.seedmancer_it_authors CASCADE;
+DROP TABLE IF EXISTS public._seedmancer_history;
+`
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+	if _, err := raw.Exec(`CREATE TABLE public.seedmancer_it_authors (id INTEGER PRIMARY KEY);
+INSERT INTO public.seedmancer_it_authors VALUES (1), (2);`); err != nil {
+		t.Fatalf("ddl: %v", err)
+	}
+
+	pg := &PostgresManager{}
+	if err := pg.ConnectWithDSN(dsn); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if history, err := pg.SeedHistory(5); err != nil || len(history) != 0 {
+		t.Fatalf("history before any seed = %v, %v; want none", history, err)
+	}
+	restoreDir := t.TempDir()
+	if err := pg.ExportSchema(restoreDir); err != nil {
+		t.Fatalf("export schema: %v", err)
+	}
+	if err := pg.ExportToCSV(restoreDir); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+
+	for _, rev := range []string{"r001", "r002"} {
+		rec := &SeedRecord{Scenario: "it/history", Revision: rev, Operator: "ci", StartedAt: time.Now(),
+			Rows: 2, RowCounts: map[string]int{"seedmancer_it_authors": 2}}
+		if err := pg.RestoreFromCSVWithOptions(restoreDir, RestoreOptions{Record: rec}); err != nil {
+			t.Fatalf("restore %s: %v", rev, err)
+		}
+	}
+	history, err := pg.SeedHistory(5)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 || history[0].Revision != "r002" || history[1].Revision != "r001" {
+		t.Fatalf("history = %+v, want r002 then r001", history)
+	}
+	if got := history[0]; got.Operator != "ci" || got.Rows != 2 || got.RowCounts["seedmancer_it_authors"] != 2 || got.FinishedAt.Before(got.StartedAt) {
+		t.Fatalf("latest = %+v", got)
+	}
+
+	exportDir := t.TempDir()
+	if err := pg.ExportToCSV(exportDir); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(exportDir, HistoryTable+".csv")); !os.IsNotExist(err) {
+		t.Fatalf("%s was exported (stat err = %v)", HistoryTable, err)
+	}
+	schema, err := pg.ExtractSchema()
+	if err != nil {
+		t.Fatalf("extract schema: %v", err)
+	}
+	if schema.TableByName(HistoryTable) != nil {
+		t.Fatalf("%s is in the extracted schema", HistoryTable)
+	}
+}
+
+// TestPostgresIntegration_CascadeGuard checks that a restore refuses to
+// let TRUNCATE … CASCADE empty a table outside the revision until
+// AllowCascade is set. Same gating as above.
+func TestPostgresIntegration_CascadeGuard(t *testing.T) {
+	dsn := os.Getenv("SEEDMANCER_INTEGRATION_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("SEEDMANCER_INTEGRATION_DATABASE_URL not set; skipping integration test")
+	}
+
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+
+	dropAll := `
+DROP TABLE IF EXISTS public.seedmancer_it_reviews CASCADE;
+DROP TABLE IF EXISTS public.seedmancer_it_books   CASCADE;
+DROP TABLE IF EXISTS public.seedmancer_it_authors CASCADE;
+`
+	if _, err := raw.Exec(dropAll); err != nil {
+		t.Fatalf("pre-clean: %v", err)
+	}
+	t.Cleanup(func() { _, _ = raw.Exec(dropAll) })
+
+	ddl := `
+CREATE TABLE public.seedmancer_it_authors (
+    id    INTEGER PRIMARY KEY,
+    name  TEXT NOT NULL
+);
+CREATE TABLE public.seedmancer_it_books (
+    id         INTEGER PRIMARY KEY,
+    author_id  INTEGER REFERENCES public.seedmancer_it_authors(id)
+);
+INSERT INTO public.seedmancer_it_authors VALUES (1, 'Ann');
+INSERT INTO public.seedmancer_it_books VALUES (10, 1);
+`
+	if _, err := raw.Exec(ddl); err != nil {
+		t.Fatalf("ddl: %v", err)
+	}
+
+	pg := &PostgresManager{}
+	if err := pg.ConnectWithDSN(dsn); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	restoreDir := t.TempDir()
+	if err := pg.ExportSchema(restoreDir); err != nil {
+		t.Fatalf("export schema: %v", err)
+	}
+	if err := pg.ExportToCSV(restoreDir); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+
+	// reviews appears after the export, so the revision doesn't cover it.
+	if _, err := raw.Exec(`
+CREATE TABLE public.seedmancer_it_reviews (
+    id       INTEGER PRIMARY KEY,
+    book_id  INTEGER REFERENCES public.seedmancer_it_books(id)
+);
+INSERT INTO public.seedmancer_it_reviews VALUES (100, 10);
+`); err != nil {
+		t.Fatalf("reviews: %v", err)
+	}
+
+	preview, err := PreviewCascade(pg, restoreDir, RestoreOptions{})
+	if err != nil || len(preview) != 1 || preview[0] != "seedmancer_it_reviews" {
+		t.Fatalf("PreviewCascade = %v, %v; want seedmancer_it_reviews", preview, err)
+	}
+	if preview, err := PreviewCascade(pg, restoreDir, RestoreOptions{Mode: RestoreUpsert}); err != nil || len(preview) != 0 {
+		t.Fatalf("PreviewCascade for upsert = %v, %v; want none", preview, err)
+	}
+
+	err = pg.RestoreFromCSV(restoreDir)
+	var cascade *CascadeError
+	if !errors.As(err, &cascade) || len(cascade.Tables) != 1 || cascade.Tables[0] != "seedmancer_it_reviews" {
+		t.Fatalf("restore err = %v, want a CascadeError naming seedmancer_it_reviews", err)
+	}
+	var reviews int
+	if err := raw.QueryRow(`SELECT count(*) FROM public.seedmancer_it_reviews`).Scan(&reviews); err != nil || reviews != 1 {
+		t.Fatalf("reviews after refusal = %d, %v; want 1", reviews, err)
+	}
+	// The refusal comes before the journal is opened, so it leaves no
+	// entry behind either.
+	var journal sql.NullString
+	if err := raw.QueryRow(`SELECT to_regclass('seedmancer.restore_journal')::text`).Scan(&journal); err != nil {
+		t.Fatalf("looking up the journal: %v", err)
+	}
+	if journal.Valid {
+		var failed int
+		if err := raw.QueryRow(`SELECT count(*) FROM seedmancer.restore_journal WHERE target = 'public' AND status = 'failed'`).Scan(&failed); err != nil || failed != 0 {
+			t.Fatalf("journal entries of the refused restore = %d, %v; want none", failed, err)
+		}
+	}
+
+	if err := pg.RestoreFromCSVWithOptions(restoreDir, RestoreOptions{AllowCascade: true}); err != nil {
+		t.Fatalf("restore with AllowCascade: %v", err)
+	}
+	if err := raw.QueryRow(`SELECT count(*) FROM public.seedmancer_it_reviews`).Scan(&reviews); err != nil || reviews != 0 {
+		t.Fatalf("reviews after cascade = %d, %v; want 0", reviews, err)
+	}
+}
@@ -159,3 +159,207 @@ func TestProcessCSVValue_jsonValid(t *testing.T) {
 		t.Errorf("valid json should round-trip, got %v", got)
 	}
 }
+
+func TestPgUpsertClause(t *testing.T) {
+	table := Table{Name: "users", Columns: []Column{
+		{Name: "id", IsPrimary: true},
+		{Name: "email"},
+		{Name: "search", IsGenerated: true},
+	}}
+	got := pgUpsertClause(table, []string{"id", "email", "search"})
+	want := `ON CONFLICT ("id") DO UPDATE SET "email" = EXCLUDED."email"`
+	if got != want {
+		t.Errorf("pgUpsertClause = %q, want %q", got, want)
+	}
+	if got := pgUpsertClause(table, []string{"id"}); got != `ON CONFLICT ("id") DO NOTHING` {
+		t.Errorf("key-only header: %q", got)
+	}
+	noPK := Table{Name: "log", Columns: []Column{{Name: "line"}}}
+	if got := pgUpsertClause(noPK, []string{"line"}); got != "ON CONFLICT DO NOTHING" {
+		t.Errorf("no primary key: %q", got)
+	}
+}
+
+func TestNextvalSequence(t *testing.T) {
+	cases := map[string]string{
+		"nextval('order_no_seq'::regclass)": "order_no_seq",
+		"nextval('public.order_no_seq')":    "order_no_seq",
+		`nextval('"Order.No"'::regclass)`:   "Order.No",
+		`nextval('public."Order ""No"""')`:  `Order "No"`,
+		"now()":                             "",
+		"":                                  "",
+	}
+	for in, want := range cases {
+		if got := nextvalSequence(in); got != want {
+			t.Errorf("nextvalSequence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildCreateTableSQL_identityAndSequences(t *testing.T) {
+	p := &PostgresManager{}
+	table := Table{Name: "orders", Columns: []Column{
+		{Name: "id", Type: "bigint", IsPrimary: true, IsGenerated: true, Identity: "ALWAYS"},
+		{Name: "number", Type: "integer", Nullable: true, Default: "nextval('order_no_seq'::regclass)"},
+		{Name: "legacy_id", Type: "integer", Nullable: true, Default: "nextval('orders_legacy_id_seq'::regclass)"},
+	}}
+	got := p.buildCreateTableSQL(table, map[string]bool{"order_no_seq": true})
+	for _, want := range []string{
+		`"id" bigint GENERATED ALWAYS AS IDENTITY`,
+		`"number" integer DEFAULT nextval('order_no_seq'::regclass)`,
+		`"legacy_id" SERIAL`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CREATE TABLE missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCreateSequenceSQL(t *testing.T) {
+	got := createSequenceSQL(Sequence{Name: "order_no", DataType: "bigint", Start: 1000, Increment: 5, MinValue: 1, MaxValue: 9999, Cycle: true})
+	want := `CREATE SEQUENCE "order_no" AS bigint INCREMENT BY 5 MINVALUE 1 MAXVALUE 9999 START WITH 1000 CYCLE`
+	if got != want {
+		t.Errorf("createSequenceSQL = %q, want %q", got, want)
+	}
+}
+
+func TestOrderViews(t *testing.T) {
+	views := []View{
+		{Name: "a_report", DependsOn: []string{"m_totals", "z_active"}},
+		{Name: "m_totals", Materialized: true, DependsOn: []string{"z_active"}},
+		{Name: "z_active", DependsOn: []string{"outside_schema"}},
+	}
+	got, err := orderViews(views)
+	if err != nil {
+		t.Fatalf("orderViews: %v", err)
+	}
+	var names []string
+	for _, v := range got {
+		names = append(names, v.Name)
+	}
+	if strings.Join(names, ",") != "z_active,m_totals,a_report" {
+		t.Fatalf("order = %v, want dependencies first", names)
+	}
+
+	cycle := []View{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}}
+	if _, err := orderViews(cycle); err == nil {
+		t.Fatal("a dependency cycle should fail")
+	}
+}
+
+func TestFoldedIndexColumn(t *testing.T) {
+	cases := map[string]string{
+		`CREATE UNIQUE INDEX users_email_lower ON public.users USING btree (lower((email)::text))`: "email",
+		`CREATE UNIQUE INDEX u ON public.users USING btree (lower(handle))`:                        "handle",
+		`CREATE UNIQUE INDEX u ON public.users USING btree (upper(("Code")::text))`:                "Code",
+		`CREATE UNIQUE INDEX u ON public.users USING btree (lower((first || last)))`:               "",
+		`CREATE UNIQUE INDEX u ON public.users USING btree (date_trunc('day'::text, created_at))`:  "",
+	}
+	for def, want := range cases {
+		if got := foldedIndexColumn(def); got != want {
+			t.Errorf("foldedIndexColumn(%q) = %q, want %q", def, got, want)
+		}
+	}
+}
+
+func TestParseSpatialType(t *testing.T) {
+	cases := map[string]struct {
+		geometryType string
+		srid         int
+	}{
+		"geometry(Point,4326)":          {"Point", 4326},
+		"geography(MultiPolygonZ,4326)": {"MultiPolygonZ", 4326},
+		"geometry(LineString)":          {"LineString", 0},
+		"geometry":                      {"", 0},
+	}
+	for formatted, want := range cases {
+		if gt, srid := parseSpatialType(formatted); gt != want.geometryType || srid != want.srid {
+			t.Errorf("parseSpatialType(%q) = %q, %d, want %q, %d", formatted, gt, srid, want.geometryType, want.srid)
+		}
+	}
+}
+
+func TestBuildCreateTableSQL_spatial(t *testing.T) {
+	p := &PostgresManager{}
+	table := Table{Name: "places", Columns: []Column{
+		{Name: "location", Type: "geography", GeometryType: "Point", SRID: 4326},
+		{Name: "outline", Type: "geometry", SRID: 3857, Nullable: true},
+		{Name: "anything", Type: "geometry", Nullable: true},
+	}}
+	got := p.buildCreateTableSQL(table, nil)
+	for _, want := range []string{
+		`"location" geography(Point,4326) NOT NULL`,
+		`"outline" geometry(Geometry,3857)`,
+		`"anything" geometry`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CREATE TABLE missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestBuildCreateTableSQL_arrays(t *testing.T) {
+	p := &PostgresManager{}
+	table := Table{Name: "posts", Columns: []Column{
+		{Name: "scores", Type: "ARRAY", ElementType: arrayElementType("_int4")},
+		{Name: "owners", Type: "ARRAY", ElementType: arrayElementType("_uuid")},
+		{Name: "moods", Type: "ARRAY", ElementType: "Mood"},
+		{Name: "legacy", Type: "ARRAY"},
+	}}
+	got := p.buildCreateTableSQL(table, nil)
+	for _, want := range []string{
+		`"scores" integer[] NOT NULL`,
+		`"owners" uuid[] NOT NULL`,
+		`"moods" "Mood"[] NOT NULL`,
+		`"legacy" text[] NOT NULL`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CREATE TABLE missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPgArrayLiteral(t *testing.T) {
+	got := pgArrayLiteral([]string{"a", "b c", `say "hi"`, "", "NULL", `C:\tmp`})
+	want := `{a,"b c","say \"hi\"","","NULL","C:\\tmp"}`
+	if got != want {
+		t.Errorf("pgArrayLiteral = %s, want %s", got, want)
+	}
+}
+
+func TestUserTypeStatements(t *testing.T) {
+	schema := &Schema{
+		Domains: []Domain{
+			{Name: "amount", BaseType: "money_base", Checks: []string{"CHECK ((VALUE >= (0)::numeric))"}},
+			{Name: "money_base", BaseType: "numeric(12,2)", NotNull: true, Default: "0"},
+			{Name: "email", BaseType: "text"},
+		},
+		CompositeTypes: []CompositeType{{Name: "price", Attributes: []CompositeAttribute{
+			{Name: "value", Type: "amount"},
+			{Name: "currency", Type: "character(3)"},
+		}}},
+	}
+	got := userTypeStatements(schema, func(name string) bool { return name == "email" })
+	want := []string{
+		`CREATE DOMAIN "money_base" AS numeric(12,2) DEFAULT 0 NOT NULL`,
+		`CREATE DOMAIN "amount" AS money_base CHECK ((VALUE >= (0)::numeric))`,
+		`CREATE TYPE "price" AS ("value" amount, "currency" character(3))`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("userTypeStatements:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildCreateTableSQL_userTypes(t *testing.T) {
+	p := &PostgresManager{}
+	table := Table{Name: "products", Columns: []Column{
+		{Name: "contact", Type: "text", Domain: "email", Nullable: true},
+		{Name: "price", Type: "composite", Composite: "price"},
+	}}
+	got := p.buildCreateTableSQL(table, nil)
+	for _, want := range []string{`"contact" "email"`, `"price" "price" NOT NULL`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CREATE TABLE missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MissingPrivilegesError is returned by a restore whose preflight found
+// privileges the connection lacks. It is returned before any statement
+// that changes the database has run, so nothing needs cleaning up.
+type MissingPrivilegesError struct {
+	// User is the role/user the restore runs as.
+	User string
+	// Missing lists each absent privilege, e.g. "TRUNCATE on users".
+	Missing []string
+}
+
+func (e *MissingPrivilegesError) Error() string {
+	return fmt.Sprintf("%s lacks privileges needed to restore (nothing was changed): %s",
+		e.User, strings.Join(e.Missing, "; "))
+}
+
+// privilegeNeed is one privilege a restore requires. Object is a table
+// name unless the privilege is database- or schema-wide.
+type privilegeNeed struct {
+	Privilege string
+	Object    string
+}
+
+func (n privilegeNeed) String() string {
+	switch n.Privilege {
+	case "OWNER":
+		return fmt.Sprintf("ALTER TABLE on %s (must own the table)", n.Object)
+	case "SET":
+		return fmt.Sprintf("SET %s (superuser, or GRANT SET ON PARAMETER on PostgreSQL 15+)", n.Object)
+	}
+	return n.Privilege + " on " + n.Object
+}
+
+// restorePlan is what a restore is about to do, worked out from the
+// schema, the live catalog, the sidecars in the restore dir and opts
+// before anything runs. It drives the privilege preflight.
+type restorePlan struct {
+	// Schema is the PostgreSQL schema restored into; CreateSchema is set
+	// when it doesn't exist yet.
+	Schema         string
+	CreateSchema   bool
+	CreateTables   bool
+	CreateTypes    bool
+	Functions      bool
+	TriggerTables  []string
+	InsertTables   []string
+	TruncateTables []string
+	DeleteTables   []string
+	MergeTables    []string
+	UpsertTables   []string
+	// AlterTables are existing tables that receive new FK constraints.
+	AlterTables []string
+}
+
+// planRestore mirrors the decisions RestoreFromCSVWithOptions makes.
+// existingFKs may be nil for engines that only add FKs to new tables.
+func planRestore(schema *Schema, directory string, existingTables, existingEnums, existingFKs, unchanged map[string]bool, opts RestoreOptions) restorePlan {
+	var plan restorePlan
+	for _, e := range schema.Enums {
+		if !existingEnums[e.Name] {
+			plan.CreateTypes = true
+		}
+	}
+	for _, t := range schema.Tables {
+		if unchanged[t.Name] || !opts.inSubset(t.Name) {
+			continue
+		}
+		if !existingTables[t.Name] {
+			plan.CreateTables = true
+			continue
+		}
+		plan.InsertTables = append(plan.InsertTables, t.Name)
+		switch {
+		case opts.merges(t.Name), opts.appends(t.Name):
+			plan.MergeTables = append(plan.MergeTables, t.Name)
+		case opts.upserts(t.Name):
+			plan.UpsertTables = append(plan.UpsertTables, t.Name)
+		case len(opts.Tables) > 0:
+			plan.DeleteTables = append(plan.DeleteTables, t.Name)
+		default:
+			plan.TruncateTables = append(plan.TruncateTables, t.Name)
+		}
+	}
+	if existingFKs != nil {
+		for _, t := range schema.Tables {
+			if !existingTables[t.Name] {
+				continue
+			}
+			for _, c := range t.Columns {
+				if c.ForeignKey != nil && !existingFKs[fmt.Sprintf("%s_%s_fkey", t.Name, c.Name)] {
+					plan.AlterTables = append(plan.AlterTables, t.Name)
+					break
+				}
+			}
+		}
+	}
+
+	plan.Functions = len(schema.Functions) > 0
+	triggerTables := map[string]bool{}
+	for _, tr := range schema.Triggers {
+		triggerTables[tr.TableName] = true
+	}
+	if entries, err := os.ReadDir(directory); err == nil {
+		for _, e := range entries {
+			name := e.Name()
+			switch {
+			case strings.HasSuffix(name, "_func.sql"):
+				plan.Functions = true
+			case strings.HasSuffix(name, "_trigger.sql"):
+				if content, err := os.ReadFile(filepath.Join(directory, name)); err == nil {
+					if _, _, table, _, err := parseTriggerSQL(string(content)); err == nil {
+						triggerTables[table] = true
+					}
+				}
+			}
+		}
+	}
+	for t := range triggerTables {
+		if existingTables[t] {
+			plan.TriggerTables = append(plan.TriggerTables, t)
+		}
+	}
+	sort.Strings(plan.TriggerTables)
+	return plan
+}
+
+func (plan restorePlan) schemaName() string {
+	if plan.Schema == "" {
+		return "public"
+	}
+	return plan.Schema
+}
+
+// postgresNeeds lists the privileges plan requires on PostgreSQL.
+func postgresNeeds(plan restorePlan) []privilegeNeed {
+	needs := []privilegeNeed{{"SET", "session_replication_role"}}
+	if plan.CreateSchema {
+		// The new schema is owned by the restoring role, which covers
+		// everything created inside it.
+		needs = append(needs, privilegeNeed{"CREATE", "the database"})
+	} else if plan.CreateTables || plan.CreateTypes || plan.Functions {
+		needs = append(needs, privilegeNeed{"CREATE", "schema " + plan.schemaName()})
+	}
+	if len(plan.MergeTables) > 0 || len(plan.UpsertTables) > 0 {
+		needs = append(needs, privilegeNeed{"TEMPORARY", "the database"})
+	}
+	for _, t := range plan.InsertTables {
+		needs = append(needs, privilegeNeed{"INSERT", t})
+	}
+	for _, t := range plan.UpsertTables {
+		needs = append(needs, privilegeNeed{"UPDATE", t})
+	}
+	for _, t := range plan.TruncateTables {
+		needs = append(needs, privilegeNeed{"TRUNCATE", t})
+	}
+	for _, t := range plan.DeleteTables {
+		needs = append(needs, privilegeNeed{"DELETE", t})
+	}
+	owners := map[string]bool{}
+	for _, t := range append(append([]string{}, plan.AlterTables...), plan.TriggerTables...) {
+		if !owners[t] {
+			owners[t] = true
+			needs = append(needs, privilegeNeed{"OWNER", t})
+		}
+	}
+	return needs
+}
+
+// preflightPostgres checks every privilege plan needs on conn and returns
+// a *MissingPrivilegesError listing the absent ones. Superusers pass
+// without further checks.
+func (p *PostgresManager) preflightPostgres(ctx context.Context, conn *sql.Conn, plan restorePlan) error {
+	var user string
+	var super, canCreate, canCreateSchema, canTemp bool
+	if err := conn.QueryRowContext(ctx, `
+		SELECT current_user, r.rolsuper,
+		       CASE WHEN EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1::text)
+			            THEN has_schema_privilege($1::text, 'CREATE') ELSE false END,
+		       has_database_privilege(current_database(), 'CREATE'),
+		       has_database_privilege(current_database(), 'TEMPORARY')
+		FROM pg_roles r WHERE r.rolname = current_user
+	`, plan.schemaName()).Scan(&user, &super, &canCreate, &canCreateSchema, &canTemp); err != nil {
+		return fmt.Errorf("checking privileges: %v", err)
+	}
+	if super {
+		return nil
+	}
+
+	// has_parameter_privilege exists from PostgreSQL 15; on older servers
+	// only superusers may change session_replication_role.
+	var canSetRole bool
+	if err := conn.QueryRowContext(ctx,
+		`SELECT has_parameter_privilege('session_replication_role', 'SET')`).Scan(&canSetRole); err != nil {
+		canSetRole = false
+	}
+
+	tables := map[string]bool{}
+	for _, t := range [][]string{plan.InsertTables, plan.AlterTables, plan.TriggerTables} {
+		for _, name := range t {
+			tables[name] = true
+		}
+	}
+	names := make([]string, 0, len(tables))
+	for t := range tables {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+
+	held := map[privilegeNeed]bool{
+		{"SET", "session_replication_role"}:       canSetRole,
+		{"CREATE", "schema " + plan.schemaName()}: canCreate,
+		{"CREATE", "the database"}:                canCreateSchema,
+		{"TEMPORARY", "the database"}:             canTemp,
+	}
+	if len(names) > 0 {
+		rows, err := conn.QueryContext(ctx, `
+			SELECT t.name,
+			       has_table_privilege(c.oid, 'INSERT'),
+			       has_table_privilege(c.oid, 'UPDATE'),
+			       has_table_privilege(c.oid, 'TRUNCATE'),
+			       has_table_privilege(c.oid, 'DELETE'),
+			       pg_has_role(c.relowner, 'USAGE')
+			FROM unnest($1::text[]) AS t(name)
+			JOIN pg_class c ON c.relname = t.name
+			JOIN pg_namespace n ON n.oid = c.relnamespace AND n.nspname = $2::text
+		`, pq.Array(names), plan.schemaName())
+		if err != nil {
+			return fmt.Errorf("checking table privileges: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			var ins, upd, trunc, del, owner bool
+			if err := rows.Scan(&name, &ins, &upd, &trunc, &del, &owner); err != nil {
+				return fmt.Errorf("checking table privileges: %v", err)
+			}
+			held[privilegeNeed{"INSERT", name}] = ins
+			held[privilegeNeed{"UPDATE", name}] = upd
+			held[privilegeNeed{"TRUNCATE", name}] = trunc
+			held[privilegeNeed{"DELETE", name}] = del
+			held[privilegeNeed{"OWNER", name}] = owner
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("checking table privileges: %v", err)
+		}
+	}
+	return missingPrivileges(user, postgresNeeds(plan), held)
+}
+
+// missingPrivileges returns a *MissingPrivilegesError for every need not
+// marked held, or nil when all are.
+func missingPrivileges(user string, needs []privilegeNeed, held map[privilegeNeed]bool) error {
+	var missing []string
+	for _, n := range needs {
+		if !held[n] {
+			missing = append(missing, n.String())
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &MissingPrivilegesError{User: user, Missing: missing}
+}
+
+// mysqlNeeds lists the privileges plan requires on MySQL. TRUNCATE needs
+// DROP there, and FKs are only added to tables the restore creates.
+func mysqlNeeds(plan restorePlan) []privilegeNeed {
+	var needs []privilegeNeed
+	if plan.CreateTables {
+		needs = append(needs, privilegeNeed{"CREATE", "the database"})
+	}
+	if plan.Functions {
+		needs = append(needs, privilegeNeed{"CREATE ROUTINE", "the database"}, privilegeNeed{"ALTER ROUTINE", "the database"})
+	}
+	for _, t := range plan.InsertTables {
+		needs = append(needs, privilegeNeed{"INSERT", t})
+	}
+	for _, t := range plan.UpsertTables {
+		needs = append(needs, privilegeNeed{"UPDATE", t})
+	}
+	for _, t := range plan.TruncateTables {
+		needs = append(needs, privilegeNeed{"DROP", t})
+	}
+	for _, t := range plan.DeleteTables {
+		needs = append(needs, privilegeNeed{"DROP", t})
+	}
+	for _, t := range plan.TriggerTables {
+		needs = append(needs, privilegeNeed{"TRIGGER", t})
+	}
+	return needs
+}
+
+// preflightMySQL checks plan against the grants information_schema shows
+// for CURRENT_USER(). Privileges that arrive through MySQL 8 roles are
+// not listed there, so the check is skipped while a role is active.
+func (m *MySQLManager) preflightMySQL(plan restorePlan) error {
+	var activeRole string
+	if err := m.DB.QueryRow("SELECT CURRENT_ROLE()").Scan(&activeRole); err == nil && activeRole != "NONE" {
+		m.log("Skipping privilege preflight: role %s is active", activeRole)
+		return nil
+	}
+
+	var user string
+	if err := m.DB.QueryRow("SELECT CURRENT_USER()").Scan(&user); err != nil {
+		return fmt.Errorf("checking privileges: %v", err)
+	}
+	const grantee = `CONCAT('''', SUBSTRING_INDEX(CURRENT_USER(), '@', 1), '''@''', SUBSTRING_INDEX(CURRENT_USER(), '@', -1), '''')`
+	rows, err := m.DB.Query(`
+		SELECT '' AS tbl, PRIVILEGE_TYPE FROM information_schema.USER_PRIVILEGES WHERE GRANTEE = ` + grantee + `
+		UNION ALL
+		SELECT '', PRIVILEGE_TYPE FROM information_schema.SCHEMA_PRIVILEGES
+		WHERE GRANTEE = ` + grantee + ` AND TABLE_SCHEMA = DATABASE()
+		UNION ALL
+		SELECT TABLE_NAME, PRIVILEGE_TYPE FROM information_schema.TABLE_PRIVILEGES
+		WHERE GRANTEE = ` + grantee + ` AND TABLE_SCHEMA = DATABASE()
+	`)
+	if err != nil {
+		return fmt.Errorf("checking privileges: %v", err)
+	}
+	defer rows.Close()
+	dbWide := map[string]bool{}
+	perTable := map[string]map[string]bool{}
+	any := false
+	for rows.Next() {
+		var table, priv string
+		if err := rows.Scan(&table, &priv); err != nil {
+			return fmt.Errorf("checking privileges: %v", err)
+		}
+		any = true
+		if table == "" {
+			dbWide[priv] = true
+			continue
+		}
+		if perTable[table] == nil {
+			perTable[table] = map[string]bool{}
+		}
+		perTable[table][priv] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("checking privileges: %v", err)
+	}
+	if !any {
+		m.log("Skipping privilege preflight: no grants visible for %s", user)
+		return nil
+	}
+
+	needs := mysqlNeeds(plan)
+	held := map[privilegeNeed]bool{}
+	for _, n := range needs {
+		held[n] = dbWide[n.Privilege] || perTable[n.Object][n.Privilege]
+	}
+	return missingPrivileges(user, needs, held)
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func preflightSchema() *Schema {
+	return &Schema{
+		Enums: []EnumItem{{Name: "status", Values: []string{"a", "b"}}},
+		Tables: []Table{
+			{Name: "users", Columns: []Column{{Name: "id", Type: "integer"}}},
+			{Name: "orders", Columns: []Column{
+				{Name: "id", Type: "integer"},
+				{Name: "user_id", Type: "integer", ForeignKey: &ForeignKey{Table: "users", Column: "id"}},
+			}},
+			{Name: "audit", Columns: []Column{{Name: "id", Type: "integer"}}},
+		},
+	}
+}
+
+func TestPostgresNeeds_fullRestore(t *testing.T) {
+	existing := map[string]bool{"users": true, "orders": true}
+	plan := planRestore(preflightSchema(), t.TempDir(), existing, map[string]bool{"status": true}, map[string]bool{}, nil, RestoreOptions{})
+
+	var got []string
+	for _, n := range postgresNeeds(plan) {
+		got = append(got, n.String())
+	}
+	want := []string{
+		"SET session_replication_role (superuser, or GRANT SET ON PARAMETER on PostgreSQL 15+)",
+		"CREATE on schema public",
+		"INSERT on users",
+		"INSERT on orders",
+		"TRUNCATE on users",
+		"TRUNCATE on orders",
+		"ALTER TABLE on orders (must own the table)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("needs =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestPostgresNeeds_subsetDeletesAndMerges(t *testing.T) {
+	existing := map[string]bool{"users": true, "orders": true, "audit": true}
+	fks := map[string]bool{"orders_user_id_fkey": true}
+	plan := planRestore(preflightSchema(), t.TempDir(), existing, map[string]bool{"status": true}, fks, nil,
+		RestoreOptions{Tables: []string{"orders"}, MergeTables: []string{"users"}})
+
+	held := map[privilegeNeed]bool{
+		{"SET", "session_replication_role"}: true,
+		{"TEMPORARY", "the database"}:       true,
+		{"INSERT", "users"}:                 true,
+		{"INSERT", "orders"}:                true,
+	}
+	err := missingPrivileges("app", postgresNeeds(plan), held)
+	var mp *MissingPrivilegesError
+	if !errors.As(err, &mp) {
+		t.Fatalf("err = %v, want *MissingPrivilegesError", err)
+	}
+	if !reflect.DeepEqual(mp.Missing, []string{"DELETE on orders"}) {
+		t.Fatalf("missing = %q", mp.Missing)
+	}
+	if !strings.Contains(err.Error(), "app lacks privileges") || !strings.Contains(err.Error(), "nothing was changed") {
+		t.Fatalf("error = %q", err.Error())
+	}
+
+	held[privilegeNeed{"DELETE", "orders"}] = true
+	if err := missingPrivileges("app", postgresNeeds(plan), held); err != nil {
+		t.Fatalf("all held: %v", err)
+	}
+}
+
+func TestPostgresNeeds_upsertNeedsUpdateNotTruncate(t *testing.T) {
+	existing := map[string]bool{"users": true, "orders": true, "audit": true}
+	fks := map[string]bool{"orders_user_id_fkey": true}
+	plan := planRestore(preflightSchema(), t.TempDir(), existing, map[string]bool{"status": true}, fks, nil,
+		RestoreOptions{Mode: RestoreUpsert})
+
+	var got []string
+	for _, n := range postgresNeeds(plan) {
+		got = append(got, n.String())
+	}
+	want := []string{
+		"SET session_replication_role (superuser, or GRANT SET ON PARAMETER on PostgreSQL 15+)",
+		"TEMPORARY on the database",
+		"INSERT on users",
+		"INSERT on orders",
+		"INSERT on audit",
+		"UPDATE on users",
+		"UPDATE on orders",
+		"UPDATE on audit",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("needs =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ConnectReadOnly is ConnectWithDSN with every session held to reads by
+// the server itself (export --read-only): default_transaction_read_only
+// on PostgreSQL and CockroachDB, set as a startup option. Once connected
+// the session is asked whether it really is read-only, so a pooler that
+// drops startup options is refused rather than trusted.
+func (p *PostgresManager) ConnectReadOnly(dsn string) error {
+	dsn, err := postgresOption(dsn, "default_transaction_read_only", "on")
+	if err != nil {
+		return err
+	}
+	if err := p.ConnectWithDSN(dsn); err != nil {
+		return err
+	}
+	var readOnly string
+	if err := p.DB.QueryRow("SHOW transaction_read_only").Scan(&readOnly); err != nil {
+		return fmt.Errorf("checking the session is read-only: %v", err)
+	}
+	return checkReadOnly(readOnly)
+}
+
+// ConnectReadOnly is ConnectWithDSN with every session held to reads by
+// the server itself (export --read-only): transaction_read_only, or
+// tx_read_only on MariaDB, is set as each connection opens. Once connected
+// the session is asked whether it really is read-only.
+func (m *MySQLManager) ConnectReadOnly(dsn string) error {
+	if err := m.connect(dsn, true); err != nil {
+		return err
+	}
+	var readOnly string
+	if err := m.DB.QueryRow("SELECT @@SESSION." + m.readOnlyVariable()).Scan(&readOnly); err != nil {
+		return fmt.Errorf("checking the session is read-only: %v", err)
+	}
+	return checkReadOnly(readOnly)
+}
+
+// readOnlyVariable is the session variable holding transactions to reads.
+// MariaDB only took MySQL's name in 11.1; the old one works throughout.
+func (m *MySQLManager) readOnlyVariable() string {
+	if m.isMariaDB() {
+		return "tx_read_only"
+	}
+	return "transaction_read_only"
+}
+
+// mysqlReadOnlyDSN sets variable, read-only mode, on every connection
+// opened with dsn, unless the DSN sets it already.
+func mysqlReadOnlyDSN(dsn, variable string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("parsing DSN: %v", err)
+	}
+	if _, set := cfg.Params[variable]; !set {
+		if cfg.Params == nil {
+			cfg.Params = map[string]string{}
+		}
+		cfg.Params[variable] = "1"
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// checkReadOnly turns the session's read-only setting, as the server
+// reports it, into an error unless it is on.
+func checkReadOnly(setting string) error {
+	switch strings.ToLower(strings.TrimSpace(setting)) {
+	case "on", "1", "true":
+		return nil
+	}
+	return fmt.Errorf("the session is not read-only (transaction_read_only = %s); a connection pooler may be dropping startup options, or the DSN turns it off", setting)
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// appendKey is the primary key column an append restore renumbers.
+type appendKey struct {
+	Column string
+	UUID   bool // fresh random UUIDs; otherwise integers shifted past the live maximum
+}
+
+// integerTypes are the column types a serial key can have on either engine.
+var integerTypes = map[string]bool{
+	"integer": true, "bigint": true, "smallint": true,
+	"int": true, "tinyint": true, "mediumint": true,
+	"serial": true, "bigserial": true, "smallserial": true,
+}
+
+// appendKeyColumns picks the tables whose keys an append restore remaps:
+// those with a single-column primary key that the database generates
+// (serial, identity, AUTO_INCREMENT) or that holds UUIDs. Tables left
+// out of the restore, merged parents and unchanged static tables keep
+// their keys, so rows pointing at them keep theirs too.
+func appendKeyColumns(schema *Schema, opts RestoreOptions, unchanged map[string]bool) map[string]appendKey {
+	keys := map[string]appendKey{}
+	for _, table := range schema.Tables {
+		if unchanged[table.Name] || !opts.inSubset(table.Name) || !opts.appends(table.Name) {
+			continue
+		}
+		var pk []Column
+		for _, col := range table.Columns {
+			if col.IsPrimary {
+				pk = append(pk, col)
+			}
+		}
+		if len(pk) != 1 {
+			continue
+		}
+		col := pk[0]
+		def := strings.ToLower(fmt.Sprintf("%v", col.Default))
+		switch {
+		case col.Type == "uuid" || strings.Contains(def, "uuid"):
+			keys[table.Name] = appendKey{Column: col.Name, UUID: true}
+		case integerTypes[strings.ToLower(col.Type)] &&
+			(strings.Contains(def, "nextval") || def == "auto_increment" || col.IsGenerated || strings.Contains(col.Type, "serial")):
+			keys[table.Name] = appendKey{Column: col.Name}
+		}
+	}
+	return keys
+}
+
+// liveKeyMax returns MAX(key) for every integer-keyed table in keys that
+// already exists. New keys are numbered from just above it.
+func liveKeyMax(ctx context.Context, q rowQueryer, quote func(string) string, keys map[string]appendKey, existing map[string]bool) (map[string]int64, error) {
+	out := map[string]int64{}
+	for table, key := range keys {
+		if key.UUID || !existing[table] {
+			continue
+		}
+		rows, err := q.QueryContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) FROM %s", quote(key.Column), quote(table)))
+		if err != nil {
+			return nil, fmt.Errorf("reading max %s.%s: %v", table, key.Column, err)
+		}
+		var top int64
+		if rows.Next() {
+			err = rows.Scan(&top)
+		}
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading max %s.%s: %v", table, key.Column, err)
+		}
+		out[table] = top
+	}
+	return out, nil
+}
+
+// remapForAppend copies every table CSV from srcDir to dstDir with the
+// keys in keys renumbered and every foreign key pointing at them
+// rewritten to match. Integer keys keep their relative order and start
+// right after liveMax[table]; UUID keys are replaced with new random
+// ones. Foreign key values with no match in the fixture (rows that
+// reference data already in the database) are left as they are.
+func remapForAppend(schema *Schema, keys map[string]appendKey, srcDir, dstDir string, liveMax map[string]int64) error {
+	mapping := map[string]map[string]string{}
+	for _, table := range schema.Tables {
+		key, ok := keys[table.Name]
+		if !ok {
+			continue
+		}
+		records, err := utils.ReadCSVRecords(filepath.Join(srcDir, table.Name+".csv"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			continue
+		}
+		if err := checkRowWidths(table.Name, records); err != nil {
+			return err
+		}
+		idx := indexOf(records[0], key.Column)
+		if idx < 0 {
+			continue
+		}
+		m, err := newKeys(records[1:], idx, key, liveMax[table.Name])
+		if err != nil {
+			return fmt.Errorf("remapping %s.%s: %v", table.Name, key.Column, err)
+		}
+		mapping[table.Name] = m
+	}
+
+	for _, table := range schema.Tables {
+		src := filepath.Join(srcDir, table.Name+".csv")
+		records, err := utils.ReadCSVRecords(src)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkRowWidths(table.Name, records); err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			for i, name := range records[0] {
+				m := columnMapping(table, name, keys, mapping)
+				if m == nil {
+					continue
+				}
+				for _, row := range records[1:] {
+					if newVal, ok := m[row[i]]; ok {
+						row[i] = newVal
+					}
+				}
+			}
+		}
+		if err := utils.WriteCSVRecords(filepath.Join(dstDir, table.Name+".csv"), records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRowWidths reports a row of table's CSV records with more or fewer
+// cells than the header, which remapping would index past.
+func checkRowWidths(table string, records [][]string) error {
+	for i, row := range records {
+		if len(row) != len(records[0]) {
+			return fmt.Errorf("%s.csv: record %d has %d field(s), the header %d", table, i+1, len(row), len(records[0]))
+		}
+	}
+	return nil
+}
+
+// columnMapping returns the old→new key map that applies to column name
+// of table: its own remapped key, or the key a foreign key points at.
+func columnMapping(table Table, name string, keys map[string]appendKey, mapping map[string]map[string]string) map[string]string {
+	if keys[table.Name].Column == name {
+		return mapping[table.Name]
+	}
+	col := table.Column(name)
+	if col == nil || col.ForeignKey == nil {
+		return nil
+	}
+	if key, ok := keys[col.ForeignKey.Table]; ok && key.Column == col.ForeignKey.Column {
+		return mapping[col.ForeignKey.Table]
+	}
+	return nil
+}
+
+// newKeys assigns a replacement for every distinct key in column idx.
+func newKeys(rows [][]string, idx int, key appendKey, liveMax int64) (map[string]string, error) {
+	m := map[string]string{}
+	if key.UUID {
+		for _, row := range rows {
+			if v := row[idx]; !isNullCell(v) && m[v] == "" {
+				id, err := randomUUID()
+				if err != nil {
+					return nil, err
+				}
+				m[v] = id
+			}
+		}
+		return m, nil
+	}
+
+	ids := make(map[string]int64, len(rows))
+	var lowest int64
+	for i, row := range rows {
+		v := row[idx]
+		if isNullCell(v) {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %q is not an integer", i+1, v)
+		}
+		if len(ids) == 0 || n < lowest {
+			lowest = n
+		}
+		ids[v] = n
+	}
+	for v, n := range ids {
+		m[v] = strconv.FormatInt(n-lowest+liveMax+1, 10)
+	}
+	return m, nil
+}
+
+func isNullCell(v string) bool {
+	return v == "" || v == "NULL" || v == "null"
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// randomUUID returns a new version 4 UUID.
+func randomUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func TestRemapForAppend_cascadesThroughForeignKeys(t *testing.T) {
+	schema := &Schema{Tables: []Table{
+		{Name: "users", Columns: []Column{
+			{Name: "id", Type: "integer", IsPrimary: true, Default: "nextval('users_id_seq'::regclass)"},
+			{Name: "manager_id", Type: "integer", ForeignKey: &ForeignKey{Table: "users", Column: "id"}},
+		}},
+		{Name: "orders", Columns: []Column{
+			{Name: "id", Type: "uuid", IsPrimary: true},
+			{Name: "user_id", Type: "integer", ForeignKey: &ForeignKey{Table: "users", Column: "id"}},
+		}},
+		{Name: "countries", Columns: []Column{{Name: "code", Type: "text", IsPrimary: true}}},
+	}}
+	src, dst := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		"users.csv":     "id,manager_id\n5,NULL\n6,5\n",
+		"orders.csv":    "id,user_id\nb4a7e9f0-0000-4000-8000-000000000001,6\nb4a7e9f0-0000-4000-8000-000000000002,42\n",
+		"countries.csv": "code\nNZ\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := appendKeyColumns(schema, RestoreOptions{Mode: RestoreAppend}, nil)
+	want := map[string]appendKey{"users": {Column: "id"}, "orders": {Column: "id", UUID: true}}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %+v, want %+v", keys, want)
+	}
+	if err := remapForAppend(schema, keys, src, dst, map[string]int64{"users": 100}); err != nil {
+		t.Fatalf("remapForAppend: %v", err)
+	}
+
+	users := readRows(t, filepath.Join(dst, "users.csv"))
+	if !reflect.DeepEqual(users, [][]string{{"101", "NULL"}, {"102", "101"}}) {
+		t.Errorf("users = %q", users)
+	}
+	orders := readRows(t, filepath.Join(dst, "orders.csv"))
+	if orders[0][0] == "b4a7e9f0-0000-4000-8000-000000000001" || len(orders[0][0]) != 36 {
+		t.Errorf("order id not remapped: %q", orders[0][0])
+	}
+	if orders[0][1] != "102" || orders[1][1] != "42" {
+		t.Errorf("order user_ids = %q, %q; want 102 and the untouched 42", orders[0][1], orders[1][1])
+	}
+	countries := readRows(t, filepath.Join(dst, "countries.csv"))
+	if !reflect.DeepEqual(countries, [][]string{{"NZ"}}) {
+		t.Errorf("countries = %q", countries)
+	}
+}
+
+// readRows returns the rows of the CSV at path, header left out.
+func readRows(t *testing.T, path string) [][]string {
+	t.Helper()
+	records, err := utils.ReadCSVRecords(path)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("reading %s: %v", filepath.Base(path), err)
+	}
+	return records[1:]
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WaitForReplica polls replicaDSN until every one of tables holds as many
+// rows as it does on primaryDSN, or timeout elapses. Call it after a seed
+// has committed on the primary so readers of the replica don't race the
+// replication lag. onWait, when non-nil, receives the reason after every
+// probe that hasn't converged yet.
+func WaitForReplica(ctx context.Context, primaryDSN, replicaDSN string, tables []string, timeout time.Duration, onWait func(reason string)) error {
+	primary, quote, err := openForCount(primaryDSN)
+	if err != nil {
+		return err
+	}
+	defer primary.Close()
+	replica, _, err := openForCount(replicaDSN)
+	if err != nil {
+		return err
+	}
+	defer replica.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	want, err := countRows(ctx, primary, quote, tables)
+	if err != nil {
+		return fmt.Errorf("counting rows on primary: %w", err)
+	}
+	for {
+		reason := replicaLag(ctx, replica, quote, tables, want)
+		if reason == "" {
+			return nil
+		}
+		if onWait != nil {
+			onWait(reason)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("replica not caught up after %s: %s", timeout, reason)
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// replicaLag returns why replica doesn't match the primary's row counts
+// yet, or "" when it does.
+func replicaLag(ctx context.Context, replica *sql.DB, quote func(string) string, tables []string, want map[string]int64) string {
+	probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	got, err := countRows(probeCtx, replica, quote, tables)
+	if err != nil {
+		return err.Error()
+	}
+	for _, table := range tables {
+		if got[table] != want[table] {
+			return fmt.Sprintf("%s has %d of %d rows", table, got[table], want[table])
+		}
+	}
+	return ""
+}
+
+func countRows(ctx context.Context, q *sql.DB, quote func(string) string, tables []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		var n int64
+		if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quote(table)).Scan(&n); err != nil {
+			return nil, fmt.Errorf("counting %s: %v", table, err)
+		}
+		counts[table] = n
+	}
+	return counts, nil
+}
+
+// openForCount opens rawDSN with the driver its scheme needs, along with
+// that engine's identifier quoting.
+func openForCount(rawDSN string) (*sql.DB, func(string) string, error) {
+	normalized, scheme, err := normalizeDSN(rawDSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch scheme {
+	case "postgres", "cockroach":
+		conn, err := sql.Open("postgres", normalized)
+		return conn, pq.QuoteIdentifier, err
+	case "mysql", "mariadb":
+		conn, err := sql.Open("mysql", normalized)
+		return conn, quoteIdent, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported database scheme %q (supported: postgres, mysql, cockroach, mariadb)", scheme)
+	}
+}
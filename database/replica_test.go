@@ -0,0 +1,21 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForReplica_primaryUnreachable(t *testing.T) {
+	err := WaitForReplica(context.Background(), "postgres://u:p@127.0.0.1:1/app", "postgres://u:p@127.0.0.1:1/app", []string{"users"}, time.Second, nil)
+	if err == nil || !strings.Contains(err.Error(), "counting rows on primary") {
+		t.Fatalf("err = %v, want a primary count error", err)
+	}
+}
+
+func TestWaitForReplica_rejectsUnknownScheme(t *testing.T) {
+	if err := WaitForReplica(context.Background(), "sqlite://x", "sqlite://y", nil, time.Second, nil); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// publicQualifier matches a "public." schema qualifier that isn't part of
+// a longer identifier or a quoted name.
+var publicQualifier = regexp.MustCompile(`(^|[^\w."])public\.`)
+
+// retargetPublic rewrites public-qualified names in a function or trigger
+// definition to schema, so sandbox restores never touch objects in public.
+// An empty schema returns sqlText unchanged.
+func retargetPublic(sqlText, schema string) string {
+	if schema == "" || schema == "public" {
+		return sqlText
+	}
+	return publicQualifier.ReplaceAllString(sqlText, "${1}"+pq.QuoteIdentifier(schema)+".")
+}
+
+// sandboxSkipsTrigger reports whether a trigger on tableSchema must be
+// left out of a sandbox restore: triggers on tables outside public (e.g.
+// auth.users) would attach to shared tables rather than the sandbox.
+func sandboxSkipsTrigger(opts RestoreOptions, tableSchema string) bool {
+	return opts.TargetSchema != "" && tableSchema != "" && tableSchema != "public"
+}
+
+// useTargetDatabase creates name when missing and points m.DB at it for
+// the rest of the restore. The returned func closes the sandbox pool and
+// restores the original connection.
+func (m *MySQLManager) useTargetDatabase(name string) (func(), error) {
+	if m.dsn == "" {
+		return nil, fmt.Errorf("target database %q: connection was not opened from a DSN", name)
+	}
+	cfg, err := mysql.ParseDSN(m.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parsing DSN: %v", err)
+	}
+	createSQL := "CREATE DATABASE IF NOT EXISTS " + quoteIdent(name)
+	m.logSQL("Create Database", createSQL)
+	if _, err := m.DB.Exec(createSQL); err != nil {
+		return nil, fmt.Errorf("creating database %s: %v", name, err)
+	}
+	cfg.DBName = name
+	sandbox, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	orig := m.DB
+	m.DB = sandbox
+	return func() {
+		sandbox.Close()
+		m.DB = orig
+	}, nil
+}
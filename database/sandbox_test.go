@@ -0,0 +1,36 @@
+package db
+
+import "testing"
+
+func TestRetargetPublic(t *testing.T) {
+	cases := []struct {
+		in, schema, want string
+	}{
+		{
+			in:     "CREATE OR REPLACE FUNCTION public.touch() RETURNS trigger",
+			schema: "run_42",
+			want:   `CREATE OR REPLACE FUNCTION "run_42".touch() RETURNS trigger`,
+		},
+		{
+			in:     "CREATE TRIGGER t BEFORE UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION public.touch()",
+			schema: "run_42",
+			want:   `CREATE TRIGGER t BEFORE UPDATE ON "run_42".users FOR EACH ROW EXECUTE FUNCTION "run_42".touch()`,
+		},
+		{
+			// Identifiers that merely end in "public" are left alone.
+			in:     "SELECT notpublic.x, \"public\".y",
+			schema: "run_42",
+			want:   "SELECT notpublic.x, \"public\".y",
+		},
+		{
+			in:     "CREATE FUNCTION public.f()",
+			schema: "",
+			want:   "CREATE FUNCTION public.f()",
+		},
+	}
+	for _, c := range cases {
+		if got := retargetPublic(c.in, c.schema); got != c.want {
+			t.Errorf("retargetPublic(%q, %q) = %q, want %q", c.in, c.schema, got, c.want)
+		}
+	}
+}
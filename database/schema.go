@@ -1,5 +1,11 @@
 package db
 
+import (
+	"encoding/json"
+
+	"github.com/KazanKK/seedmancer/internal/schemaspec"
+)
+
 type Column struct {
 	Name          string      `json:"name"`
 	Type          string      `json:"type"` // Database type (e.g. text, integer, timestamp)
@@ -10,8 +16,39 @@ type Column struct {
 	IsUnique      bool        `json:"isUnique"`
 	ForeignKey    *ForeignKey `json:"foreignKey,omitempty"`
 	Enum          string      `json:"enum,omitempty"`
-	IsGenerated   bool        `json:"isGenerated,omitempty"` // true for computed/generated/identity columns
+	IsGenerated   bool        `json:"isGenerated,omitempty"`   // true for computed/generated/identity columns
 	AllowedValues []string    `json:"allowedValues,omitempty"` // values extracted from IN-based CHECK constraints
+	Identity      string      `json:"identity,omitempty"`      // PostgreSQL identity kind: ALWAYS or BY DEFAULT
+	// UniqueIgnoreCase is set when no two rows may hold values that differ
+	// only in case: a unique citext column, a unique column with a
+	// case-insensitive MySQL collation, or one covered by a unique index on
+	// lower(column) or upper(column).
+	UniqueIgnoreCase bool `json:"uniqueIgnoreCase,omitempty"`
+	// Precision is a decimal column's total digits, or the fractional
+	// second digits of a datetime or time column, or 1 for MySQL's
+	// tinyint(1), the usual spelling of boolean; Scale is a decimal
+	// column's digits after the point. Unsigned marks a MySQL numeric
+	// column that only takes values from zero up.
+	Precision *int `json:"precision,omitempty"`
+	Scale     *int `json:"scale,omitempty"`
+	Unsigned  bool `json:"unsigned,omitempty"`
+	// Excluded marks a column export left out of the CSVs (see
+	// MarkExcludedColumns).
+	Excluded bool `json:"excluded,omitempty"`
+	// GeometryType and SRID are a PostGIS geometry or geography column's
+	// type modifiers, as in geometry(Point,4326); both are empty for an
+	// unconstrained column.
+	GeometryType string `json:"geometryType,omitempty"`
+	SRID         int    `json:"srid,omitempty"`
+	// ElementType is an array column's element type, such as integer or
+	// uuid; Type is ARRAY.
+	ElementType string `json:"elementType,omitempty"`
+	// Domain names the PostgreSQL domain a column is declared as; Type is
+	// the domain's base type, which generators and validation go by.
+	// Composite names the composite type of a column whose Type is
+	// "composite".
+	Domain    string `json:"domain,omitempty"`
+	Composite string `json:"composite,omitempty"`
 }
 
 type ForeignKey struct {
@@ -41,11 +78,58 @@ type Trigger struct {
 	Definition  string `json:"definition"`
 }
 
+// Sequence is a standalone PostgreSQL sequence: one not created by a
+// serial or identity column, since those come back with their table.
+type Sequence struct {
+	Name      string `json:"name"`
+	DataType  string `json:"dataType"`
+	Start     int64  `json:"start"`
+	Increment int64  `json:"increment"`
+	MinValue  int64  `json:"minValue"`
+	MaxValue  int64  `json:"maxValue"`
+	Cycle     bool   `json:"cycle,omitempty"`
+}
+
+// Domain is a PostgreSQL domain: a base type with its own default, NOT
+// NULL and CHECK constraints, created before the tables using it.
+type Domain struct {
+	Name     string `json:"name"`
+	BaseType string `json:"baseType"` // e.g. character varying(255)
+	NotNull  bool   `json:"notNull,omitempty"`
+	Default  string `json:"default,omitempty"`
+	// Checks are the constraint definitions, such as CHECK ((VALUE > 0)).
+	Checks []string `json:"checks,omitempty"`
+}
+
+// CompositeType is a PostgreSQL composite type (CREATE TYPE … AS (…)).
+type CompositeType struct {
+	Name       string               `json:"name"`
+	Attributes []CompositeAttribute `json:"attributes"`
+}
+
+type CompositeAttribute struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// View is a PostgreSQL view or materialized view. DependsOn lists the
+// other views its definition reads from, which must be created first.
+type View struct {
+	Name         string   `json:"name"`
+	Materialized bool     `json:"materialized,omitempty"`
+	Definition   string   `json:"definition"`
+	DependsOn    []string `json:"dependsOn,omitempty"`
+}
+
 type DatabaseType string
 
 const (
 	Postgres DatabaseType = "postgres"
 	MySQL    DatabaseType = "mysql"
+	// Cockroach is CockroachDB, restored through PostgresManager.
+	Cockroach DatabaseType = "cockroach"
+	// MariaDB is restored through MySQLManager.
+	MariaDB DatabaseType = "mariadb"
 )
 
 type Schema struct {
@@ -54,6 +138,25 @@ type Schema struct {
 	Tables       []Table      `json:"tables,omitempty"`
 	Functions    []Function   `json:"functions,omitempty"`
 	Triggers     []Trigger    `json:"triggers,omitempty"`
+	Sequences    []Sequence   `json:"sequences,omitempty"`
+	Views        []View       `json:"views,omitempty"`
+	// Domains and CompositeTypes are PostgreSQL's user-defined types
+	// other than enums.
+	Domains        []Domain        `json:"domains,omitempty"`
+	CompositeTypes []CompositeType `json:"compositeTypes,omitempty"`
+}
+
+// parseSchema decodes a schema.json, naming the offending value (e.g.
+// tables[3].columns[7].type) when decoding fails.
+func parseSchema(data []byte) (Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		if precise := schemaspec.Explain(data); precise != nil {
+			return Schema{}, precise
+		}
+		return Schema{}, err
+	}
+	return schema, nil
 }
 
 type SchemaExtractor interface {
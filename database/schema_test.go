@@ -0,0 +1,93 @@
+package db
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/schemaspec"
+)
+
+// TestJSONSchemaCoversSchema keeps the published JSON Schema in step with
+// the structs export writes: every JSON field must be described, or
+// `schema validate` would reject files seedmancer itself produced.
+func TestJSONSchemaCoversSchema(t *testing.T) {
+	var doc struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(schemaspec.JSONSchema, &doc); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		def   string
+		props map[string]json.RawMessage
+		typ   interface{}
+	}{
+		{"(root)", doc.Properties, Schema{}},
+		{"enum", doc.Defs["enum"].Properties, EnumItem{}},
+		{"table", doc.Defs["table"].Properties, Table{}},
+		{"column", doc.Defs["column"].Properties, Column{}},
+		{"foreignKey", doc.Defs["foreignKey"].Properties, ForeignKey{}},
+		{"function", doc.Defs["function"].Properties, Function{}},
+		{"trigger", doc.Defs["trigger"].Properties, Trigger{}},
+		{"sequence", doc.Defs["sequence"].Properties, Sequence{}},
+		{"view", doc.Defs["view"].Properties, View{}},
+		{"domain", doc.Defs["domain"].Properties, Domain{}},
+		{"compositeType", doc.Defs["compositeType"].Properties, CompositeType{}},
+		{"compositeAttribute", doc.Defs["compositeAttribute"].Properties, CompositeAttribute{}},
+	}
+	for _, tc := range cases {
+		rt := reflect.TypeOf(tc.typ)
+		var missing []string
+		for i := 0; i < rt.NumField(); i++ {
+			name := strings.Split(rt.Field(i).Tag.Get("json"), ",")[0]
+			if _, ok := tc.props[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		sort.Strings(missing)
+		if len(missing) > 0 {
+			t.Errorf("%s: JSON Schema lacks %v", tc.def, missing)
+		}
+	}
+}
+
+func TestParseSchemaNamesOffendingValue(t *testing.T) {
+	_, err := parseSchema([]byte(`{"tables":[{"name":"a","columns":[{"name":"id","type":"int","isPrimary":"yes"}]}]}`))
+	if err == nil || err.Error() != "tables[0].columns[0].isPrimary: expected boolean, got string" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestExportedSchemaValidates(t *testing.T) {
+	varchar := "255"
+	schema := Schema{
+		DatabaseType: Postgres,
+		Enums:        []EnumItem{{Name: "plan_t", Values: []string{"free"}}},
+		Tables: []Table{
+			{Name: "orgs", Columns: []Column{{Name: "id", Type: "integer", IsPrimary: true}}},
+			{Name: "users", Columns: []Column{
+				{Name: "id", Type: "bigint", IsPrimary: true, IsGenerated: true, Identity: "ALWAYS"},
+				{Name: "org_id", Type: "integer", ForeignKey: &ForeignKey{Table: "orgs", Column: "id"}},
+				{Name: "plan", Type: "USER-DEFINED", Enum: "plan_t", Default: "'free'::plan_t"},
+				{Name: "email", Type: "varchar", Varchar: &varchar, AllowedValues: []string{"a"}},
+			}},
+		},
+		Functions: []Function{{Name: "f", Definition: "CREATE FUNCTION f()"}},
+		Triggers:  []Trigger{{Name: "t", TableName: "users", TableSchema: "public", Definition: "CREATE TRIGGER t"}},
+		Sequences: []Sequence{{Name: "s", DataType: "bigint", Start: 1, Increment: 1, MinValue: 1, MaxValue: 1 << 62}},
+		Views:     []View{{Name: "v", Definition: "SELECT 1", DependsOn: []string{"w"}}},
+	}
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := schemaspec.Validate(raw); issues != nil {
+		t.Fatalf("issues = %v", issues)
+	}
+}
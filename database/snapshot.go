@@ -0,0 +1,685 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// Snapshots are whole-database copies kept on the same server as the
+// database they were taken from, so restoring one doesn't re-import any
+// CSVs:
+//
+//   - PostgreSQL copies the database with CREATE DATABASE … TEMPLATE.
+//     Other sessions connected to the database are terminated first,
+//     since Postgres refuses to copy (or drop) a database in use.
+//   - MySQL and MariaDB dump every base table into a sibling database with
+//     SHOW CREATE TABLE plus INSERT … SELECT, then copy the triggers.
+//     Views and stored routines are left where they are.
+//   - CockroachDB has no template databases and isn't supported.
+//
+// A snapshot named "clean" of database "app" lives in "app__snap_clean".
+// Restoring copies it into a staging database first and only swaps that
+// in for "app" once the copy is complete, so a restore that fails leaves
+// "app" as it was.
+
+// snapshotSeparator joins a database name and a snapshot name.
+const snapshotSeparator = "__snap_"
+
+// maxIdentifierLen is the shorter of PostgreSQL's (63) and MySQL's (64)
+// identifier limits.
+const maxIdentifierLen = 63
+
+var snapshotNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Snapshot describes one snapshot of a database.
+type Snapshot struct {
+	Name     string `json:"name"`
+	Database string `json:"database"`
+	// CreatedAt is zero when the server doesn't record it.
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	SizeBytes int64     `json:"sizeBytes"`
+}
+
+// SnapshotDatabaseName is the database that holds snapshot name of dbName.
+func SnapshotDatabaseName(dbName, name string) string {
+	return dbName + snapshotSeparator + name
+}
+
+// validateSnapshotName accepts lowercase letters, digits and underscores,
+// and rejects names that would push the snapshot database past the
+// identifier limit.
+func validateSnapshotName(dbName, name string) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use lowercase letters, digits and underscores", name)
+	}
+	if n := len(SnapshotDatabaseName(dbName, name)); n > maxIdentifierLen {
+		return fmt.Errorf("snapshot name %q is too long: %q would be %d characters (max %d)",
+			name, SnapshotDatabaseName(dbName, name), n, maxIdentifierLen)
+	}
+	return nil
+}
+
+// CreateSnapshot copies the database rawDSN points at into snapshot name.
+// An existing snapshot of that name is an error; drop it first.
+func CreateSnapshot(ctx context.Context, rawDSN, name string) error {
+	return withSnapshotAdmin(ctx, rawDSN, func(a snapshotAdmin) error {
+		if err := validateSnapshotName(a.database, name); err != nil {
+			return err
+		}
+		snap := SnapshotDatabaseName(a.database, name)
+		exists, err := a.exists(ctx, snap)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("snapshot %q of %s already exists — drop it first", name, a.database)
+		}
+		if err := a.copyDatabase(ctx, a.database, snap); err != nil {
+			_ = a.dropDatabase(ctx, snap)
+			return err
+		}
+		return nil
+	})
+}
+
+// RestoreSnapshot replaces the database rawDSN points at with a copy of
+// snapshot name. The snapshot itself is kept, so it can be restored again.
+func RestoreSnapshot(ctx context.Context, rawDSN, name string) error {
+	return withSnapshotAdmin(ctx, rawDSN, func(a snapshotAdmin) error {
+		return restoreSnapshot(ctx, a, name)
+	})
+}
+
+// restoreSnapshot copies snapshot name into a staging database and swaps
+// it in for a.database. Until the swap the database is untouched, so a
+// copy that fails — the snapshot in use, a lock timeout, a full disk —
+// only costs the staging database.
+func restoreSnapshot(ctx context.Context, a snapshotAdmin, name string) error {
+	if err := validateSnapshotName(a.database, name); err != nil {
+		return err
+	}
+	snap := SnapshotDatabaseName(a.database, name)
+	exists, err := a.exists(ctx, snap)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("no snapshot %q of %s", name, a.database)
+	}
+	staged := scratchDatabaseName(a.database, "__restoring")
+	// Left behind by a restore that was killed mid-copy.
+	if err := a.dropDatabase(ctx, staged); err != nil {
+		return err
+	}
+	if err := a.copyDatabase(ctx, snap, staged); err != nil {
+		_ = a.dropDatabase(ctx, staged)
+		return fmt.Errorf("%w (%s was left unchanged)", err, a.database)
+	}
+	if err := a.replaceDatabase(ctx, staged, a.database); err != nil {
+		_ = a.dropDatabase(ctx, staged)
+		return err
+	}
+	return a.dropDatabase(ctx, staged)
+}
+
+// scratchDatabaseName is dbName with suffix, cutting dbName short when
+// the whole would pass the identifier limit.
+func scratchDatabaseName(dbName, suffix string) string {
+	if n := maxIdentifierLen - len(suffix); len(dbName) > n {
+		dbName = dbName[:n]
+	}
+	return dbName + suffix
+}
+
+// DropSnapshot deletes snapshot name of the database rawDSN points at.
+func DropSnapshot(ctx context.Context, rawDSN, name string) error {
+	return withSnapshotAdmin(ctx, rawDSN, func(a snapshotAdmin) error {
+		if err := validateSnapshotName(a.database, name); err != nil {
+			return err
+		}
+		snap := SnapshotDatabaseName(a.database, name)
+		exists, err := a.exists(ctx, snap)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("no snapshot %q of %s", name, a.database)
+		}
+		return a.dropDatabase(ctx, snap)
+	})
+}
+
+// ListSnapshots returns the snapshots of the database rawDSN points at,
+// sorted by name.
+func ListSnapshots(ctx context.Context, rawDSN string) ([]Snapshot, error) {
+	var out []Snapshot
+	err := withSnapshotAdmin(ctx, rawDSN, func(a snapshotAdmin) error {
+		var err error
+		out, err = a.list(ctx, a.database)
+		return err
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, err
+}
+
+// snapshotAdmin is an engine's set of database-level operations, run from
+// a connection that isn't inside the database being copied or dropped.
+type snapshotAdmin struct {
+	database     string
+	exists       func(ctx context.Context, dbName string) (bool, error)
+	copyDatabase func(ctx context.Context, src, dst string) error
+	// replaceDatabase puts the contents of staged in dbName's place. It
+	// either completes or leaves dbName as it was; staged is left for
+	// the caller to drop.
+	replaceDatabase func(ctx context.Context, staged, dbName string) error
+	dropDatabase    func(ctx context.Context, dbName string) error
+	list            func(ctx context.Context, dbName string) ([]Snapshot, error)
+}
+
+// withSnapshotAdmin opens the admin connection for rawDSN's engine and
+// hands it to fn.
+func withSnapshotAdmin(ctx context.Context, rawDSN string, fn func(snapshotAdmin) error) error {
+	normalized, scheme, err := normalizeDSN(rawDSN)
+	if err != nil {
+		return err
+	}
+	switch scheme {
+	case "postgres":
+		return withPostgresSnapshotAdmin(normalized, fn)
+	case "mysql", "mariadb":
+		return withMySQLSnapshotAdmin(ctx, normalized, fn)
+	case "cockroach":
+		return fmt.Errorf("snapshots are not supported on CockroachDB (it has no template databases)")
+	default:
+		return fmt.Errorf("unsupported database scheme %q (supported: postgres, mysql, cockroach, mariadb)", scheme)
+	}
+}
+
+// ─── PostgreSQL ─────────────────────────────────────────────────────────────
+
+// postgresAdminDSN points dsn at the maintenance database, returning it
+// along with the database dsn named. When that database is postgres
+// itself, template1 is used instead so it can be dropped and recreated.
+func postgresAdminDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing database URL: %v", err)
+	}
+	dbName := strings.TrimPrefix(u.Path, "/")
+	if dbName == "" {
+		return "", "", fmt.Errorf("database URL names no database")
+	}
+	admin := "postgres"
+	if dbName == admin {
+		admin = "template1"
+	}
+	u.Path = "/" + admin
+	return u.String(), dbName, nil
+}
+
+func withPostgresSnapshotAdmin(dsn string, fn func(snapshotAdmin) error) error {
+	adminDSN, dbName, err := postgresAdminDSN(dsn)
+	if err != nil {
+		return err
+	}
+	conn, err := sql.Open("postgres", adminDSN)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return fn(snapshotAdmin{
+		database: dbName,
+		exists: func(ctx context.Context, name string) (bool, error) {
+			return postgresDatabaseExists(ctx, conn, name)
+		},
+		copyDatabase: func(ctx context.Context, src, dst string) error {
+			return cloneFromTemplate(ctx, conn, src, dst, snapshotCommentPrefix+time.Now().UTC().Format(time.RFC3339))
+		},
+		replaceDatabase: func(ctx context.Context, staged, name string) error {
+			return replacePostgresDatabase(ctx, conn, staged, name)
+		},
+		dropDatabase: func(ctx context.Context, name string) error {
+			return dropPostgresDatabase(ctx, conn, name)
+		},
+		list: func(ctx context.Context, name string) ([]Snapshot, error) {
+			rows, err := conn.QueryContext(ctx, `
+				SELECT datname, pg_database_size(oid), COALESCE(shobj_description(oid, 'pg_database'), '')
+				FROM pg_database`)
+			if err != nil {
+				return nil, fmt.Errorf("listing databases: %v", err)
+			}
+			defer rows.Close()
+			prefix := name + snapshotSeparator
+			var out []Snapshot
+			for rows.Next() {
+				var s Snapshot
+				var comment string
+				if err := rows.Scan(&s.Database, &s.SizeBytes, &comment); err != nil {
+					return nil, fmt.Errorf("scanning database: %v", err)
+				}
+				if !strings.HasPrefix(s.Database, prefix) {
+					continue
+				}
+				s.Name = strings.TrimPrefix(s.Database, prefix)
+				s.CreatedAt, _ = time.Parse(time.RFC3339, strings.TrimPrefix(comment, snapshotCommentPrefix))
+				out = append(out, s)
+			}
+			return out, rows.Err()
+		},
+	})
+}
+
+// snapshotCommentPrefix precedes the creation time in the COMMENT on
+// each snapshot database.
+const snapshotCommentPrefix = "seedmancer snapshot "
+
+// terminateSessions disconnects every other session on dbName, which
+// CREATE DATABASE … TEMPLATE and DROP DATABASE both require.
+func terminateSessions(ctx context.Context, conn *sql.DB, dbName string) error {
+	_, err := conn.ExecContext(ctx,
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()", dbName)
+	if err != nil {
+		return fmt.Errorf("disconnecting sessions on %s: %v", dbName, err)
+	}
+	return nil
+}
+
+// cloneFromTemplate creates dst as a copy of src and, when comment is
+// set, puts it on dst.
+func cloneFromTemplate(ctx context.Context, conn *sql.DB, src, dst, comment string) error {
+	if err := terminateSessions(ctx, conn, src); err != nil {
+		return err
+	}
+	createSQL := fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", pq.QuoteIdentifier(dst), pq.QuoteIdentifier(src))
+	if _, err := conn.ExecContext(ctx, createSQL); err != nil {
+		return fmt.Errorf("copying %s to %s: %v", src, dst, err)
+	}
+	if comment == "" {
+		return nil
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("COMMENT ON DATABASE %s IS %s", pq.QuoteIdentifier(dst), pq.QuoteLiteral(comment))); err != nil {
+		return fmt.Errorf("labelling %s: %v", dst, err)
+	}
+	return nil
+}
+
+// replacePostgresDatabase renames dbName out of the way, renames staged
+// to dbName and only then drops the old database, renaming it back if
+// staged can't take its place.
+func replacePostgresDatabase(ctx context.Context, conn *sql.DB, staged, dbName string) error {
+	old := scratchDatabaseName(dbName, "__replaced")
+	if err := dropPostgresDatabase(ctx, conn, old); err != nil {
+		return err
+	}
+	rename := func(from, to string) error {
+		if err := terminateSessions(ctx, conn, from); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", pq.QuoteIdentifier(from), pq.QuoteIdentifier(to))); err != nil {
+			return fmt.Errorf("renaming %s to %s: %v", from, to, err)
+		}
+		return nil
+	}
+	if err := rename(dbName, old); err != nil {
+		return err
+	}
+	if err := rename(staged, dbName); err != nil {
+		if rerr := rename(old, dbName); rerr != nil {
+			return fmt.Errorf("%v; %s is kept as %s: %v", err, dbName, old, rerr)
+		}
+		return err
+	}
+	return dropPostgresDatabase(ctx, conn, old)
+}
+
+func dropPostgresDatabase(ctx context.Context, conn *sql.DB, dbName string) error {
+	if err := terminateSessions(ctx, conn, dbName); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "DROP DATABASE IF EXISTS "+pq.QuoteIdentifier(dbName)); err != nil {
+		return fmt.Errorf("dropping %s: %v", dbName, err)
+	}
+	return nil
+}
+
+// ─── MySQL / MariaDB ────────────────────────────────────────────────────────
+
+func withMySQLSnapshotAdmin(ctx context.Context, dsn string, fn func(snapshotAdmin) error) error {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return fmt.Errorf("parsing DSN: %v", err)
+	}
+	if cfg.DBName == "" {
+		return fmt.Errorf("database URL names no database")
+	}
+	pool, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	// One pinned session: FOREIGN_KEY_CHECKS and USE are per session.
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+
+	return fn(snapshotAdmin{
+		database: cfg.DBName,
+		exists: func(ctx context.Context, name string) (bool, error) {
+			var n int
+			err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", name).Scan(&n)
+			if err != nil {
+				return false, fmt.Errorf("looking up database %s: %v", name, err)
+			}
+			return n > 0, nil
+		},
+		copyDatabase: func(ctx context.Context, src, dst string) error {
+			if _, err := conn.ExecContext(ctx, "CREATE DATABASE IF NOT EXISTS "+quoteIdent(dst)); err != nil {
+				return fmt.Errorf("creating database %s: %v", dst, err)
+			}
+			return copyMySQLDatabase(ctx, conn, src, dst)
+		},
+		replaceDatabase: func(ctx context.Context, staged, name string) error {
+			return replaceMySQLDatabase(ctx, conn, staged, name)
+		},
+		dropDatabase: func(ctx context.Context, name string) error {
+			if _, err := conn.ExecContext(ctx, "DROP DATABASE IF EXISTS "+quoteIdent(name)); err != nil {
+				return fmt.Errorf("dropping %s: %v", name, err)
+			}
+			return nil
+		},
+		list: func(ctx context.Context, name string) ([]Snapshot, error) {
+			rows, err := conn.QueryContext(ctx, `
+				SELECT s.SCHEMA_NAME,
+				       COALESCE(SUM(t.DATA_LENGTH + t.INDEX_LENGTH), 0),
+				       MIN(t.CREATE_TIME)
+				FROM information_schema.SCHEMATA s
+				LEFT JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = s.SCHEMA_NAME
+				GROUP BY s.SCHEMA_NAME`)
+			if err != nil {
+				return nil, fmt.Errorf("listing databases: %v", err)
+			}
+			defer rows.Close()
+			prefix := name + snapshotSeparator
+			var out []Snapshot
+			for rows.Next() {
+				var s Snapshot
+				var created sql.NullTime
+				if err := rows.Scan(&s.Database, &s.SizeBytes, &created); err != nil {
+					return nil, fmt.Errorf("scanning database: %v", err)
+				}
+				if !strings.HasPrefix(s.Database, prefix) {
+					continue
+				}
+				s.Name = strings.TrimPrefix(s.Database, prefix)
+				if created.Valid {
+					s.CreatedAt = created.Time
+				}
+				out = append(out, s)
+			}
+			return out, rows.Err()
+		},
+	})
+}
+
+// mysqlBaseTables lists the tables (not views) of dbName.
+func mysqlBaseTables(ctx context.Context, conn *sql.Conn, dbName string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME`, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables of %s: %v", dbName, err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+// copyMySQLDatabase recreates every base table of src in dst, rows and
+// foreign keys included, followed by src's triggers. conn must have
+// FOREIGN_KEY_CHECKS off so tables can be created and filled in any order.
+func copyMySQLDatabase(ctx context.Context, conn *sql.Conn, src, dst string) error {
+	tables, err := mysqlBaseTables(ctx, conn, src)
+	if err != nil {
+		return err
+	}
+	// SHOW CREATE output names tables unqualified, so it runs with dst as
+	// the default database and foreign keys end up pointing inside dst.
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdent(dst)); err != nil {
+		return fmt.Errorf("switching to %s: %v", dst, err)
+	}
+	for _, t := range tables {
+		var name, ddl string
+		if err := conn.QueryRowContext(ctx, "SHOW CREATE TABLE "+quoteIdent(src)+"."+quoteIdent(t)).Scan(&name, &ddl); err != nil {
+			return fmt.Errorf("reading definition of %s: %v", t, err)
+		}
+		if _, err := conn.ExecContext(ctx, ddl); err != nil {
+			return fmt.Errorf("creating %s.%s: %v", dst, t, err)
+		}
+		cols, err := mysqlStoredColumns(ctx, conn, src, t)
+		if err != nil {
+			return err
+		}
+		insertSQL := fmt.Sprintf("INSERT INTO %s.%s (%s) SELECT %s FROM %s.%s",
+			quoteIdent(dst), quoteIdent(t), strings.Join(cols, ", "),
+			strings.Join(cols, ", "), quoteIdent(src), quoteIdent(t))
+		if _, err := conn.ExecContext(ctx, insertSQL); err != nil {
+			return fmt.Errorf("copying rows of %s: %v", t, err)
+		}
+	}
+	return copyMySQLTriggers(ctx, conn, src)
+}
+
+// mysqlStoredColumns returns the quoted columns of table that hold data,
+// i.e. all but generated ones, which can't be inserted into.
+func mysqlStoredColumns(ctx context.Context, conn *sql.Conn, dbName, table string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT COLUMN_NAME, EXTRA FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, dbName, table)
+	if err != nil {
+		return nil, fmt.Errorf("listing columns of %s: %v", table, err)
+	}
+	defer rows.Close()
+	var cols []string
+	for rows.Next() {
+		var name, extra string
+		if err := rows.Scan(&name, &extra); err != nil {
+			return nil, err
+		}
+		extra = strings.ToLower(extra)
+		if strings.Contains(extra, "virtual generated") || strings.Contains(extra, "stored generated") {
+			continue
+		}
+		cols = append(cols, quoteIdent(name))
+	}
+	return cols, rows.Err()
+}
+
+// copyMySQLTriggers recreates src's triggers in the connection's current
+// database.
+func copyMySQLTriggers(ctx context.Context, conn *sql.Conn, src string) error {
+	triggers, err := mysqlTriggers(ctx, conn, src)
+	if err != nil {
+		return err
+	}
+	_, err = createMySQLTriggers(ctx, conn, triggers)
+	return err
+}
+
+// mysqlTrigger is a trigger's name and its CREATE TRIGGER statement, which
+// names the table unqualified.
+type mysqlTrigger struct {
+	name, def string
+}
+
+// mysqlTriggers reads the triggers of dbName in the order they fire.
+func mysqlTriggers(ctx context.Context, conn *sql.Conn, dbName string) ([]mysqlTrigger, error) {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT TRIGGER_NAME FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ? ORDER BY EVENT_OBJECT_TABLE, ACTION_ORDER", dbName)
+	if err != nil {
+		return nil, fmt.Errorf("listing triggers of %s: %v", dbName, err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	triggers := make([]mysqlTrigger, 0, len(names))
+	for _, name := range names {
+		// SHOW CREATE TRIGGER returns: Trigger, sql_mode, SQL Original Statement, character_set_client, collation_connection, Database Collation, Created
+		var trig, mode, def, cs, cc, dc, created sql.NullString
+		row := conn.QueryRowContext(ctx, "SHOW CREATE TRIGGER "+quoteIdent(dbName)+"."+quoteIdent(name))
+		if err := row.Scan(&trig, &mode, &def, &cs, &cc, &dc, &created); err != nil {
+			return nil, fmt.Errorf("reading trigger %s: %v", name, err)
+		}
+		triggers = append(triggers, mysqlTrigger{name: name, def: def.String})
+	}
+	return triggers, nil
+}
+
+// createMySQLTriggers creates triggers in the connection's current
+// database, returning how many it created before any error.
+func createMySQLTriggers(ctx context.Context, conn *sql.Conn, triggers []mysqlTrigger) (int, error) {
+	for i, t := range triggers {
+		if _, err := conn.ExecContext(ctx, t.def); err != nil {
+			return i, fmt.Errorf("creating trigger %s: %v", t.name, err)
+		}
+	}
+	return len(triggers), nil
+}
+
+// dropMySQLTriggers drops triggers from dbName, returning how many it
+// dropped before any error.
+func dropMySQLTriggers(ctx context.Context, conn *sql.Conn, dbName string, triggers []mysqlTrigger) (int, error) {
+	for i, t := range triggers {
+		if _, err := conn.ExecContext(ctx, "DROP TRIGGER "+quoteIdent(dbName)+"."+quoteIdent(t.name)); err != nil {
+			return i, fmt.Errorf("dropping trigger %s: %v", t.name, err)
+		}
+	}
+	return len(triggers), nil
+}
+
+// replaceMySQLDatabase moves dbName's base tables into a side database and
+// staged's into dbName with one RENAME TABLE, which MySQL applies
+// atomically, then drops the side database. RENAME TABLE won't move a
+// table with triggers to another database, so the triggers on both sides
+// are dropped first and staged's are recreated in dbName afterwards; a
+// step that fails puts dbName's own tables and triggers back.
+func replaceMySQLDatabase(ctx context.Context, conn *sql.Conn, staged, dbName string) error {
+	old := scratchDatabaseName(dbName, "__replaced")
+	for _, stmt := range []string{"DROP DATABASE IF EXISTS " + quoteIdent(old), "CREATE DATABASE " + quoteIdent(old)} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("preparing %s: %v", old, err)
+		}
+	}
+	dropOld := func() { _, _ = conn.ExecContext(ctx, "DROP DATABASE IF EXISTS "+quoteIdent(old)) }
+
+	current, err := mysqlTriggers(ctx, conn, dbName)
+	if err != nil {
+		dropOld()
+		return err
+	}
+	incoming, err := mysqlTriggers(ctx, conn, staged)
+	if err != nil {
+		dropOld()
+		return err
+	}
+	currentTables, err := mysqlBaseTables(ctx, conn, dbName)
+	if err != nil {
+		dropOld()
+		return err
+	}
+	stagedTables, err := mysqlBaseTables(ctx, conn, staged)
+	if err != nil {
+		dropOld()
+		return err
+	}
+	// putBack recreates the first n of dbName's own triggers.
+	putBack := func(n int) {
+		if _, err := conn.ExecContext(ctx, "USE "+quoteIdent(dbName)); err == nil {
+			_, _ = createMySQLTriggers(ctx, conn, current[:n])
+		}
+	}
+
+	if _, err := dropMySQLTriggers(ctx, conn, staged, incoming); err != nil {
+		dropOld()
+		return err
+	}
+	if n, err := dropMySQLTriggers(ctx, conn, dbName, current); err != nil {
+		putBack(n)
+		dropOld()
+		return err
+	}
+
+	swap := append(mysqlMoves(currentTables, dbName, old), mysqlMoves(stagedTables, staged, dbName)...)
+	if err := renameMySQLTables(ctx, conn, swap); err != nil {
+		putBack(len(current))
+		dropOld()
+		return fmt.Errorf("swapping in the restored tables: %v", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdent(dbName)); err != nil {
+		return fmt.Errorf("switching to %s: %v (%s's previous tables are in %s)", dbName, err, dbName, old)
+	}
+	if n, err := createMySQLTriggers(ctx, conn, incoming); err != nil {
+		// Undo the swap: the restored tables go back to staged (without
+		// the triggers just created on them) and dbName's come home.
+		_, _ = dropMySQLTriggers(ctx, conn, dbName, incoming[:n])
+		back := append(mysqlMoves(stagedTables, dbName, staged), mysqlMoves(currentTables, old, dbName)...)
+		if rerr := renameMySQLTables(ctx, conn, back); rerr != nil {
+			return fmt.Errorf("%v; %s's previous tables are kept in %s: %v", err, dbName, old, rerr)
+		}
+		putBack(len(current))
+		dropOld()
+		return err
+	}
+	dropOld()
+	return nil
+}
+
+// mysqlMoves renders the RENAME TABLE clauses moving tables from database
+// from to database to.
+func mysqlMoves(tables []string, from, to string) []string {
+	moves := make([]string, len(tables))
+	for i, t := range tables {
+		moves[i] = fmt.Sprintf("%s.%s TO %s.%s", quoteIdent(from), quoteIdent(t), quoteIdent(to), quoteIdent(t))
+	}
+	return moves
+}
+
+// renameMySQLTables runs moves as a single, atomic RENAME TABLE.
+func renameMySQLTables(ctx context.Context, conn *sql.Conn, moves []string) error {
+	if len(moves) == 0 {
+		return nil
+	}
+	_, err := conn.ExecContext(ctx, "RENAME TABLE "+strings.Join(moves, ", "))
+	return err
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateSnapshotName(t *testing.T) {
+	for _, name := range []string{"clean", "after_login", "v2"} {
+		if err := validateSnapshotName("app", name); err != nil {
+			t.Errorf("%q: unexpected error %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "Clean", "with-dash", "a.b", strings.Repeat("x", 60)} {
+		if err := validateSnapshotName("app", name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
+
+func TestPostgresAdminDSN(t *testing.T) {
+	admin, dbName, err := postgresAdminDSN("postgres://u:p@localhost:5432/app?sslmode=disable")
+	if err != nil || dbName != "app" || admin != "postgres://u:p@localhost:5432/postgres?sslmode=disable" {
+		t.Fatalf("got %q, %q, %v", admin, dbName, err)
+	}
+	admin, dbName, err = postgresAdminDSN("postgres://u:p@localhost:5432/postgres")
+	if err != nil || dbName != "postgres" || !strings.HasSuffix(admin, "/template1") {
+		t.Fatalf("got %q, %q, %v", admin, dbName, err)
+	}
+	if _, _, err := postgresAdminDSN("postgres://u:p@localhost:5432"); err == nil {
+		t.Fatal("expected an error for a URL without a database")
+	}
+}
+
+func TestSnapshotsUnsupportedOnCockroach(t *testing.T) {
+	err := CreateSnapshot(context.Background(), "cockroach://root@localhost:26257/app", "clean")
+	if err == nil || !strings.Contains(err.Error(), "CockroachDB") {
+		t.Fatalf("err = %v, want CockroachDB unsupported", err)
+	}
+}
+
+// fakeSnapshotAdmin records the operations restoreSnapshot runs, with
+// databases as a set of names.
+func fakeSnapshotAdmin(dbs map[string]bool, copyErr error, ops *[]string) snapshotAdmin {
+	return snapshotAdmin{
+		database: "app",
+		exists:   func(_ context.Context, name string) (bool, error) { return dbs[name], nil },
+		copyDatabase: func(_ context.Context, src, dst string) error {
+			*ops = append(*ops, "copy "+src+" "+dst)
+			if copyErr != nil {
+				return copyErr
+			}
+			dbs[dst] = true
+			return nil
+		},
+		replaceDatabase: func(_ context.Context, staged, name string) error {
+			*ops = append(*ops, "replace "+staged+" "+name)
+			delete(dbs, staged)
+			return nil
+		},
+		dropDatabase: func(_ context.Context, name string) error {
+			*ops = append(*ops, "drop "+name)
+			delete(dbs, name)
+			return nil
+		},
+	}
+}
+
+func TestRestoreSnapshot_copyFailureLeavesDatabase(t *testing.T) {
+	dbs := map[string]bool{"app": true, "app__snap_clean": true}
+	var ops []string
+	err := restoreSnapshot(context.Background(), fakeSnapshotAdmin(dbs, errors.New("source database is being accessed by other users"), &ops), "clean")
+	if err == nil || !strings.Contains(err.Error(), "app was left unchanged") {
+		t.Fatalf("err = %v, want the copy error", err)
+	}
+	if !dbs["app"] || !dbs["app__snap_clean"] || dbs["app__restoring"] {
+		t.Fatalf("databases after a failed copy = %v", dbs)
+	}
+	for _, op := range ops {
+		if strings.HasPrefix(op, "replace") || op == "drop app" {
+			t.Fatalf("ops = %v: the database was touched before the copy succeeded", ops)
+		}
+	}
+}
+
+func TestRestoreSnapshot_swapsInStagedCopy(t *testing.T) {
+	dbs := map[string]bool{"app": true, "app__snap_clean": true}
+	var ops []string
+	if err := restoreSnapshot(context.Background(), fakeSnapshotAdmin(dbs, nil, &ops), "clean"); err != nil {
+		t.Fatal(err)
+	}
+	want := "drop app__restoring,copy app__snap_clean app__restoring,replace app__restoring app,drop app__restoring"
+	if got := strings.Join(ops, ","); got != want {
+		t.Fatalf("ops = %s, want %s", got, want)
+	}
+}
+
+func TestScratchDatabaseName(t *testing.T) {
+	if got := scratchDatabaseName("app", "__restoring"); got != "app__restoring" {
+		t.Fatalf("got %q", got)
+	}
+	if got := scratchDatabaseName(strings.Repeat("x", 63), "__restoring"); len(got) != maxIdentifierLen || !strings.HasSuffix(got, "__restoring") {
+		t.Fatalf("got %q (%d chars)", got, len(got))
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// isSpatialType reports whether t is a PostGIS geometry or geography
+// type. Export writes their cells as EWKT (SRID=4326;POINT(1 2)), which
+// the types' input functions parse the same way ST_GeomFromEWKT does, so
+// COPY restores them as written.
+func isSpatialType(t string) bool {
+	t = baseType(t)
+	return t == "geometry" || t == "geography"
+}
+
+// spatialTypeRe matches format_type's spelling of a spatial column with
+// type modifiers: geometry(Point,4326), geography(PolygonZ,4326) or
+// geometry(LineString).
+var spatialTypeRe = regexp.MustCompile(`(?i)^(geometry|geography)\((\w+)(?:,\s*(\d+))?\)$`)
+
+// parseSpatialType splits format_type's spelling of a spatial column into
+// its geometry type and SRID. Both are zero for an unconstrained column.
+func parseSpatialType(formatted string) (geometryType string, srid int) {
+	m := spatialTypeRe.FindStringSubmatch(strings.TrimSpace(formatted))
+	if m == nil {
+		return "", 0
+	}
+	if m[3] != "" {
+		srid, _ = strconv.Atoi(m[3])
+	}
+	return m[2], srid
+}
+
+// spatialTypeSQL is col's type with its modifiers, as CREATE TABLE
+// takes it.
+func spatialTypeSQL(col Column) string {
+	switch {
+	case col.SRID != 0:
+		geometryType := col.GeometryType
+		if geometryType == "" {
+			geometryType = "Geometry"
+		}
+		return fmt.Sprintf("%s(%s,%d)", col.Type, geometryType, col.SRID)
+	case col.GeometryType != "":
+		return fmt.Sprintf("%s(%s)", col.Type, col.GeometryType)
+	}
+	return col.Type
+}
+
+// fakeGeometry makes up an EWKT value of col's geometry type: a point
+// somewhere on the globe, or a line or a small square starting there.
+// Unconstrained columns get points. ok is false for geometry types it
+// can't produce, such as curves.
+func fakeGeometry(r *rand.Rand, col Column) (string, bool) {
+	kind := strings.ToUpper(col.GeometryType)
+	// A Z or M suffix adds a coordinate; EWKT infers Z from the count but
+	// spells M out.
+	extra, tag := "", ""
+	switch {
+	case strings.HasSuffix(kind, "ZM"):
+		kind, extra = strings.TrimSuffix(kind, "ZM"), " 0 0"
+	case strings.HasSuffix(kind, "Z"):
+		kind, extra = strings.TrimSuffix(kind, "Z"), " 0"
+	case strings.HasSuffix(kind, "M"):
+		kind, extra, tag = strings.TrimSuffix(kind, "M"), " 0", "M"
+	}
+
+	lon := -180 + r.Float64()*359.99
+	lat := -85 + r.Float64()*170
+	at := func(dx, dy float64) string {
+		return strconv.FormatFloat(lon+dx, 'f', 6, 64) + " " + strconv.FormatFloat(lat+dy, 'f', 6, 64) + extra
+	}
+	point := at(0, 0)
+	line := point + ", " + at(0.01, 0.01)
+	ring := "(" + point + ", " + at(0.01, 0) + ", " + at(0.01, 0.01) + ", " + at(0, 0.01) + ", " + point + ")"
+
+	var wkt string
+	switch kind {
+	case "", "GEOMETRY", "POINT":
+		wkt = "POINT" + tag + "(" + point + ")"
+	case "LINESTRING":
+		wkt = "LINESTRING" + tag + "(" + line + ")"
+	case "POLYGON":
+		wkt = "POLYGON" + tag + "(" + ring + ")"
+	case "MULTIPOINT":
+		wkt = "MULTIPOINT" + tag + "((" + point + "))"
+	case "MULTILINESTRING":
+		wkt = "MULTILINESTRING" + tag + "((" + line + "))"
+	case "MULTIPOLYGON":
+		wkt = "MULTIPOLYGON" + tag + "((" + ring + "))"
+	case "GEOMETRYCOLLECTION":
+		wkt = "GEOMETRYCOLLECTION" + tag + "(POINT" + tag + "(" + point + "))"
+	default:
+		return "", false
+	}
+	if col.SRID != 0 {
+		wkt = fmt.Sprintf("SRID=%d;%s", col.SRID, wkt)
+	}
+	return wkt, true
+}
@@ -0,0 +1,272 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rowQueryer is the subset of *sql.DB / *sql.Conn / *sql.Tx the hashing
+// helpers need. Accepting the interface lets Postgres hash on the pinned
+// restore session while MySQL hashes straight off the pool.
+type rowQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// formatCSVCell renders one scanned driver value the way ExportToCSV writes
+// it; binary says the column holds raw bytes (see binaryColumns). Keeping
+// a single renderer means a freshly exported CSV and the live table it
+// came from hash to the same value.
+func formatCSVCell(val interface{}, binary bool) string {
+	if val == nil {
+		return "NULL"
+	}
+	switch v := val.(type) {
+	case []byte:
+		if binary {
+			return encodeBinaryCell(v)
+		}
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999 -0700 UTC")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// hashRows returns the content hash of a header plus data rows. Rows are
+// sorted first so physical row order (which neither Postgres nor MySQL
+// guarantees without ORDER BY) never changes the result.
+func hashRows(header []string, rows [][]string) string {
+	encoded := make([]string, len(rows))
+	for i, r := range rows {
+		encoded[i] = encodeCSVRecord(r)
+	}
+	sort.Strings(encoded)
+
+	h := sha256.New()
+	io.WriteString(h, encodeCSVRecord(header))
+	for _, line := range encoded {
+		io.WriteString(h, line)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// encodeCSVRecord renders a record with encoding/csv so cells containing
+// commas or newlines can't collide with neighbouring cells in the hash.
+func encodeCSVRecord(record []string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	_ = w.Write(record)
+	w.Flush()
+	return b.String()
+}
+
+// HashCSVFile returns the content hash of a fixture CSV along with its
+// header. The hash matches what a live table with identical rows yields
+// from tableContentHash.
+func HashCSVFile(path string) (hash string, header []string, err error) {
+	hash, header, _, err = hashCSVFileRows(path)
+	return hash, header, err
+}
+
+// hashCSVFileRows is HashCSVFile that also reports the number of data rows.
+func hashCSVFileRows(path string) (hash string, header []string, rowCount int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReader(f))
+	header, err = r.Read()
+	if err == io.EOF {
+		return hashRows(nil, nil), nil, 0, nil
+	}
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("reading CSV header: %v", err)
+	}
+	var rows [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, 0, fmt.Errorf("reading CSV record: %v", err)
+		}
+		rows = append(rows, rec)
+	}
+	return hashRows(header, rows), header, len(rows), nil
+}
+
+// tableContentHash selects columns (in the given order) from table and
+// hashes the rows with the same rendering ExportToCSV uses. quote is the
+// dialect's identifier quoting function.
+func tableContentHash(ctx context.Context, q rowQueryer, quote func(string) string, table string, columns []string) (string, error) {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quote(c)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), quote(table))
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return "", fmt.Errorf("querying %s: %v", table, err)
+	}
+	defer rows.Close()
+	binary, err := binaryColumns(rows)
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var out [][]string
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", fmt.Errorf("scanning %s: %v", table, err)
+		}
+		rec := make([]string, len(columns))
+		for i, v := range values {
+			rec[i] = formatCSVCell(v, binary[i])
+		}
+		out = append(out, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("reading %s: %v", table, err)
+	}
+	return hashRows(columns, out), nil
+}
+
+// unchangedStaticTables returns the subset of static tables whose live
+// contents already match their fixture CSV. Tables missing from the
+// database or the restore dir are never reported as unchanged, and any
+// error while hashing is treated as "changed" so the caller falls back to
+// a normal reload.
+func unchangedStaticTables(ctx context.Context, q rowQueryer, quote func(string) string, directory string, static []string, existing map[string]bool, logf func(string, ...interface{})) map[string]bool {
+	skip := map[string]bool{}
+	for _, name := range static {
+		if !existing[name] {
+			continue
+		}
+		csvPath := filepath.Join(directory, name+".csv")
+		fixtureHash, header, err := HashCSVFile(csvPath)
+		if err != nil || len(header) == 0 {
+			continue
+		}
+		liveHash, err := tableContentHash(ctx, q, quote, name, header)
+		if err != nil {
+			logf("Warning: hashing static table %s: %v", name, err)
+			continue
+		}
+		if liveHash == fixtureHash {
+			skip[name] = true
+		}
+	}
+	return skip
+}
+
+// reloadCascadedStatic removes from unchanged every table that references
+// one of truncate, directly or through other tables of schema, and
+// returns their names: TRUNCATE … CASCADE empties such a table, so it
+// has to be truncated and reloaded along with the rest.
+func reloadCascadedStatic(schema *Schema, truncate []string, unchanged map[string]bool) []string {
+	reached := make(map[string]bool, len(truncate))
+	for _, name := range truncate {
+		reached[name] = true
+	}
+	for grew := true; grew; {
+		grew = false
+		for _, t := range schema.Tables {
+			if reached[t.Name] {
+				continue
+			}
+			for _, c := range t.Columns {
+				if c.ForeignKey != nil && reached[c.ForeignKey.Table] {
+					reached[t.Name], grew = true, true
+					break
+				}
+			}
+		}
+	}
+	var reload []string
+	for _, t := range schema.Tables {
+		if unchanged[t.Name] && reached[t.Name] {
+			delete(unchanged, t.Name)
+			reload = append(reload, t.Name)
+		}
+	}
+	return reload
+}
+
+// TableDrift describes how one live table compares with its fixture CSV:
+// what a seed of that fixture would change about the table.
+type TableDrift struct {
+	Table string `json:"table"`
+	// Exists is false when the table is missing from the live database
+	// (seed would create it).
+	Exists      bool `json:"exists"`
+	LiveRows    int  `json:"liveRows"`
+	FixtureRows int  `json:"fixtureRows"`
+	// Identical is true when the live rows hash to the same value as the
+	// fixture CSV, so seeding would leave the table's contents as they are.
+	Identical bool `json:"identical"`
+}
+
+// compareTables builds a TableDrift for each of tables. A table without a
+// CSV in directory counts as an empty fixture, since restore truncates it.
+// Nothing is written; live rows are only counted and hashed.
+func compareTables(ctx context.Context, q rowQueryer, quote func(string) string, directory string, tables []string, existing map[string]bool) ([]TableDrift, error) {
+	out := make([]TableDrift, 0, len(tables))
+	for _, name := range tables {
+		d := TableDrift{Table: name, Exists: existing[name]}
+		fixtureHash, header, n, err := hashCSVFileRows(filepath.Join(directory, name+".csv"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("reading %s.csv: %v", name, err)
+		}
+		d.FixtureRows = n
+		if !d.Exists {
+			out = append(out, d)
+			continue
+		}
+		rows, err := q.QueryContext(ctx, "SELECT COUNT(*) FROM "+quote(name))
+		if err != nil {
+			return nil, fmt.Errorf("counting %s: %v", name, err)
+		}
+		if rows.Next() {
+			err = rows.Scan(&d.LiveRows)
+		}
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("counting %s: %v", name, err)
+		}
+		switch {
+		case d.LiveRows != d.FixtureRows:
+		case d.FixtureRows == 0:
+			d.Identical = true
+		case len(header) > 0:
+			liveHash, err := tableContentHash(ctx, q, quote, name, header)
+			if err != nil {
+				// The fixture has columns the live table lacks; the
+				// schema diff reports that, the rows simply differ.
+				break
+			}
+			d.Identical = liveHash == fixtureHash
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashCSVFile_ignoresRowOrder(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.csv")
+	b := filepath.Join(dir, "b.csv")
+	if err := os.WriteFile(a, []byte("code,name\nDE,Germany\nFR,France\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("code,name\nFR,France\nDE,Germany\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ha, header, err := HashCSVFile(a)
+	if err != nil {
+		t.Fatalf("hash a: %v", err)
+	}
+	hb, _, err := HashCSVFile(b)
+	if err != nil {
+		t.Fatalf("hash b: %v", err)
+	}
+	if ha != hb {
+		t.Fatalf("row order changed hash: %s vs %s", ha, hb)
+	}
+	if len(header) != 2 || header[0] != "code" {
+		t.Fatalf("header = %v", header)
+	}
+}
+
+func TestHashRows_matchesLiveRendering(t *testing.T) {
+	// A live row scanned as driver values must hash the same as the CSV
+	// ExportToCSV would have written for it.
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	live := [][]string{{formatCSVCell(int64(1), false), formatCSVCell([]byte("x"), false), formatCSVCell(nil, false), formatCSVCell(ts, false), formatCSVCell([]byte{0, 0xff}, true)}}
+	fixture := [][]string{{"1", "x", "NULL", "2024-01-02 03:04:05 +0000 UTC", `\x00ff`}}
+	header := []string{"id", "name", "note", "created_at", "avatar"}
+	if hashRows(header, live) != hashRows(header, fixture) {
+		t.Fatalf("live %v and fixture %v hash differently", live, fixture)
+	}
+}
+
+func TestHashRows_cellBoundariesMatter(t *testing.T) {
+	header := []string{"a", "b"}
+	if hashRows(header, [][]string{{"x,y", "z"}}) == hashRows(header, [][]string{{"x", "y,z"}}) {
+		t.Fatal("cells with embedded commas must not collide")
+	}
+}
+
+func TestReloadCascadedStatic(t *testing.T) {
+	fk := func(table string) *ForeignKey { return &ForeignKey{Table: table, Column: "id"} }
+	schema := &Schema{Tables: []Table{
+		{Name: "users", Columns: []Column{{Name: "id"}}},
+		{Name: "plans", Columns: []Column{{Name: "id"}, {Name: "owner_id", ForeignKey: fk("users")}}},
+		{Name: "plan_tiers", Columns: []Column{{Name: "id"}, {Name: "plan_id", ForeignKey: fk("plans")}}},
+		{Name: "countries", Columns: []Column{{Name: "id"}}},
+	}}
+	// plan_tiers reaches users through plans, which isn't static.
+	unchanged := map[string]bool{"plan_tiers": true, "countries": true}
+	reload := reloadCascadedStatic(schema, []string{"users"}, unchanged)
+	if len(reload) != 1 || reload[0] != "plan_tiers" {
+		t.Errorf("reload = %v, want [plan_tiers]", reload)
+	}
+	if unchanged["plan_tiers"] || !unchanged["countries"] {
+		t.Errorf("unchanged = %v, want only countries", unchanged)
+	}
+}
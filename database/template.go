@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Template databases make repeat seeds of the same fixture nearly free on
+// PostgreSQL: the first seed loads the CSVs as usual and then copies the
+// result into a template database; every later seed replaces the target
+// with CREATE DATABASE … TEMPLATE instead of re-importing anything.
+//
+// Templates are keyed by the caller (a hash of everything that goes into
+// the restore), so a changed fixture simply misses and builds a new one.
+// Each template is labelled with the scenario it holds, and saving a new
+// template drops the older ones with the same label.
+
+// templatePrefix starts the name of every template database.
+const templatePrefix = "seedmancer_tpl_"
+
+// templateCommentPrefix precedes the label in a template's COMMENT.
+const templateCommentPrefix = "seedmancer template "
+
+// TemplateDatabaseName is the template database for key.
+func TemplateDatabaseName(key string) string {
+	return templatePrefix + key
+}
+
+// ResetFromTemplate replaces the database rawDSN points at with a copy of
+// template. It reports false, changing nothing, when template doesn't
+// exist yet. PostgreSQL only.
+func ResetFromTemplate(ctx context.Context, rawDSN, template string) (bool, error) {
+	var found bool
+	err := withTemplateAdmin(rawDSN, func(conn *sql.DB, dbName string) error {
+		exists, err := postgresDatabaseExists(ctx, conn, template)
+		if err != nil || !exists {
+			return err
+		}
+		found = true
+		if err := dropPostgresDatabase(ctx, conn, dbName); err != nil {
+			return err
+		}
+		return cloneFromTemplate(ctx, conn, template, dbName, "")
+	})
+	return found, err
+}
+
+// SaveTemplate copies the database rawDSN points at into template,
+// labelled label, and drops other templates carrying the same label.
+// PostgreSQL only.
+func SaveTemplate(ctx context.Context, rawDSN, template, label string) error {
+	return withTemplateAdmin(rawDSN, func(conn *sql.DB, dbName string) error {
+		if err := dropPostgresDatabase(ctx, conn, template); err != nil {
+			return err
+		}
+		if err := cloneFromTemplate(ctx, conn, dbName, template, templateCommentPrefix+label); err != nil {
+			return err
+		}
+		rows, err := conn.QueryContext(ctx, `
+			SELECT datname FROM pg_database
+			WHERE shobj_description(oid, 'pg_database') = $1 AND datname <> $2`,
+			templateCommentPrefix+label, template)
+		if err != nil {
+			return fmt.Errorf("listing stale templates: %v", err)
+		}
+		var stale []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return err
+			}
+			stale = append(stale, name)
+		}
+		rows.Close()
+		for _, name := range stale {
+			if err := dropPostgresDatabase(ctx, conn, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// withTemplateAdmin opens the maintenance connection for rawDSN, which
+// must be a PostgreSQL URL, and hands it to fn with rawDSN's database.
+func withTemplateAdmin(rawDSN string, fn func(conn *sql.DB, dbName string) error) error {
+	normalized, scheme, err := normalizeDSN(rawDSN)
+	if err != nil {
+		return err
+	}
+	if scheme != "postgres" {
+		return fmt.Errorf("template databases need PostgreSQL (got %s)", scheme)
+	}
+	adminDSN, dbName, err := postgresAdminDSN(normalized)
+	if err != nil {
+		return err
+	}
+	conn, err := sql.Open("postgres", adminDSN)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return fn(conn, dbName)
+}
+
+func postgresDatabaseExists(ctx context.Context, conn *sql.DB, name string) (bool, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("looking up database %s: %v", name, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,169 @@
+CREATE TABLE `Address` (
+  `id` VARCHAR(255) NOT NULL,
+  `street` TEXT NOT NULL,
+  `city` TEXT NOT NULL,
+  `state` TEXT,
+  `country` TEXT NOT NULL,
+  `zipCode` TEXT NOT NULL,
+  `profileId` VARCHAR(255) NOT NULL,
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE (`profileId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Attachment` (
+  `id` VARCHAR(255) NOT NULL,
+  `filename` TEXT NOT NULL,
+  `url` TEXT NOT NULL,
+  `size` INT NOT NULL,
+  `mimeType` TEXT NOT NULL,
+  `postId` VARCHAR(255) NOT NULL,
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Category` (
+  `id` VARCHAR(255) NOT NULL,
+  `name` VARCHAR(255) NOT NULL,
+  `description` TEXT,
+  `parentId` VARCHAR(255),
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Comment` (
+  `id` VARCHAR(255) NOT NULL,
+  `content` TEXT NOT NULL,
+  `postId` VARCHAR(255) NOT NULL,
+  `authorId` VARCHAR(255) NOT NULL,
+  `parentId` VARCHAR(255),
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `MenuItem` (
+  `id` VARCHAR(255) NOT NULL,
+  `name` TEXT NOT NULL,
+  `path` TEXT,
+  `icon` TEXT,
+  `order` INT NOT NULL,
+  `parentId` VARCHAR(255),
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Notification` (
+  `id` VARCHAR(255) NOT NULL,
+  `type` NotificationType NOT NULL,
+  `title` TEXT NOT NULL,
+  `content` TEXT NOT NULL,
+  `read` TINYINT(1) NOT NULL,
+  `userId` VARCHAR(255) NOT NULL,
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Organization` (
+  `id` VARCHAR(255) NOT NULL,
+  `name` TEXT NOT NULL,
+  `description` TEXT,
+  `logo` TEXT,
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `OrganizationMember` (
+  `id` VARCHAR(255) NOT NULL,
+  `role` OrgRole NOT NULL,
+  `userId` VARCHAR(255) NOT NULL,
+  `organizationId` VARCHAR(255) NOT NULL,
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Profile` (
+  `id` VARCHAR(255) NOT NULL,
+  `bio` TEXT,
+  `avatar` TEXT,
+  `phoneNumber` TEXT,
+  `dateOfBirth` DATETIME,
+  `address` TEXT,
+  `socialLinks` JSON,
+  `userId` VARCHAR(255) NOT NULL,
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE (`userId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Project` (
+  `id` VARCHAR(255) NOT NULL,
+  `name` TEXT NOT NULL,
+  `description` TEXT,
+  `status` ProjectStatus NOT NULL,
+  `organizationId` VARCHAR(255) NOT NULL,
+  `teamId` VARCHAR(255),
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Reaction` (
+  `id` VARCHAR(255) NOT NULL,
+  `type` ReactionType NOT NULL,
+  `userId` VARCHAR(255) NOT NULL,
+  `postId` VARCHAR(255),
+  `commentId` VARCHAR(255),
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `Tag` (
+  `id` VARCHAR(255) NOT NULL,
+  `name` TEXT,
+  `color` TEXT,
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `TeamMember` (
+  `id` VARCHAR(255) NOT NULL,
+  `role` TeamRole NOT NULL,
+  `userId` VARCHAR(255) NOT NULL,
+  `teamId` VARCHAR(255) NOT NULL,
+  `replacedById` VARCHAR(255),
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE (`replacedById`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE `User` (
+  `id` VARCHAR(255) NOT NULL,
+  `email` VARCHAR(255) NOT NULL,
+  `name` TEXT,
+  `password` TEXT NOT NULL,
+  `role` Role NOT NULL,
+  `status` UserStatus NOT NULL,
+  `preferences` JSON,
+  `lastLoginAt` DATETIME,
+  `createdAt` DATETIME NOT NULL,
+  `updatedAt` DATETIME NOT NULL,
+  `managerId` VARCHAR(255),
+  `mentorId` VARCHAR(255),
+  PRIMARY KEY (`id`),
+  UNIQUE (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+ALTER TABLE `Address` ADD CONSTRAINT `Address_profileId_fk` FOREIGN KEY (`profileId`) REFERENCES `Profile`(`id`);
+ALTER TABLE `Attachment` ADD CONSTRAINT `Attachment_postId_fk` FOREIGN KEY (`postId`) REFERENCES `Post`(`id`);
+ALTER TABLE `Comment` ADD CONSTRAINT `Comment_authorId_fk` FOREIGN KEY (`authorId`) REFERENCES `User`(`id`);
+ALTER TABLE `Comment` ADD CONSTRAINT `Comment_postId_fk` FOREIGN KEY (`postId`) REFERENCES `Post`(`id`);
+ALTER TABLE `Notification` ADD CONSTRAINT `Notification_userId_fk` FOREIGN KEY (`userId`) REFERENCES `User`(`id`);
+ALTER TABLE `OrganizationMember` ADD CONSTRAINT `OrganizationMember_organizationId_fk` FOREIGN KEY (`organizationId`) REFERENCES `Organization`(`id`);
+ALTER TABLE `OrganizationMember` ADD CONSTRAINT `OrganizationMember_userId_fk` FOREIGN KEY (`userId`) REFERENCES `User`(`id`);
+ALTER TABLE `Profile` ADD CONSTRAINT `Profile_userId_fk` FOREIGN KEY (`userId`) REFERENCES `User`(`id`);
+ALTER TABLE `Project` ADD CONSTRAINT `Project_organizationId_fk` FOREIGN KEY (`organizationId`) REFERENCES `Organization`(`id`);
+ALTER TABLE `Project` ADD CONSTRAINT `Project_teamId_fk` FOREIGN KEY (`teamId`) REFERENCES `Team`(`id`);
+ALTER TABLE `Reaction` ADD CONSTRAINT `Reaction_commentId_fk` FOREIGN KEY (`commentId`) REFERENCES `Comment`(`id`);
+ALTER TABLE `Reaction` ADD CONSTRAINT `Reaction_postId_fk` FOREIGN KEY (`postId`) REFERENCES `Post`(`id`);
+ALTER TABLE `Reaction` ADD CONSTRAINT `Reaction_userId_fk` FOREIGN KEY (`userId`) REFERENCES `User`(`id`);
+ALTER TABLE `TeamMember` ADD CONSTRAINT `TeamMember_teamId_fk` FOREIGN KEY (`teamId`) REFERENCES `Team`(`id`);
+ALTER TABLE `TeamMember` ADD CONSTRAINT `TeamMember_userId_fk` FOREIGN KEY (`userId`) REFERENCES `User`(`id`);
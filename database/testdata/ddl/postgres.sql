@@ -0,0 +1,202 @@
+CREATE TYPE "NotificationType" AS ENUM ('MENTION', 'COMMENT', 'REACTION', 'TEAM_INVITE', 'PROJECT_UPDATE');
+CREATE TYPE "OrgRole" AS ENUM ('OWNER', 'ADMIN', 'MEMBER');
+CREATE TYPE "ProjectStatus" AS ENUM ('ACTIVE', 'ARCHIVED', 'COMPLETED');
+CREATE TYPE "ReactionType" AS ENUM ('LIKE', 'LOVE', 'HAHA', 'WOW', 'SAD', 'ANGRY');
+CREATE TYPE "Role" AS ENUM ('USER', 'ADMIN');
+CREATE TYPE "TeamRole" AS ENUM ('LEADER', 'MEMBER');
+CREATE TYPE "UserStatus" AS ENUM ('ACTIVE', 'INACTIVE', 'SUSPENDED');
+CREATE TABLE "Address" (
+  "id" text NOT NULL,
+  "street" text NOT NULL,
+  "city" text NOT NULL,
+  "state" text,
+  "country" text NOT NULL,
+  "zipCode" text NOT NULL,
+  "profileId" text NOT NULL,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id"),
+  UNIQUE ("profileId")
+);
+CREATE TABLE "Attachment" (
+  "id" text NOT NULL,
+  "filename" text NOT NULL,
+  "url" text NOT NULL,
+  "size" integer NOT NULL,
+  "mimeType" text NOT NULL,
+  "postId" text NOT NULL,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "Category" (
+  "id" text NOT NULL,
+  "name" text NOT NULL,
+  "description" text,
+  "parentId" text,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id"),
+  UNIQUE ("name")
+);
+CREATE TABLE "Comment" (
+  "id" text NOT NULL,
+  "content" text NOT NULL,
+  "postId" text NOT NULL,
+  "authorId" text NOT NULL,
+  "parentId" text,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "MenuItem" (
+  "id" text NOT NULL,
+  "name" text NOT NULL,
+  "path" text,
+  "icon" text,
+  "order" integer NOT NULL,
+  "parentId" text,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "Notification" (
+  "id" text NOT NULL,
+  "type" "NotificationType" NOT NULL,
+  "title" text NOT NULL,
+  "content" text NOT NULL,
+  "read" boolean NOT NULL,
+  "userId" text NOT NULL,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "Organization" (
+  "id" text NOT NULL,
+  "name" text NOT NULL,
+  "description" text,
+  "logo" text,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id"),
+  UNIQUE ("name")
+);
+CREATE TABLE "OrganizationMember" (
+  "id" text NOT NULL,
+  "role" "OrgRole" NOT NULL,
+  "userId" text NOT NULL,
+  "organizationId" text NOT NULL,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "Post" (
+  "id" text NOT NULL,
+  "title" text NOT NULL,
+  "content" text NOT NULL,
+  "published" boolean NOT NULL,
+  "viewCount" integer NOT NULL,
+  "authorId" text NOT NULL,
+  "projectId" text NOT NULL,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "Profile" (
+  "id" text NOT NULL,
+  "bio" text,
+  "avatar" text,
+  "phoneNumber" text,
+  "dateOfBirth" timestamp without time zone,
+  "address" text,
+  "socialLinks" json,
+  "userId" text NOT NULL,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id"),
+  UNIQUE ("userId")
+);
+CREATE TABLE "Project" (
+  "id" text NOT NULL,
+  "name" text NOT NULL,
+  "description" text,
+  "status" "ProjectStatus" NOT NULL,
+  "organizationId" text NOT NULL,
+  "teamId" text,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "Reaction" (
+  "id" text NOT NULL,
+  "type" "ReactionType" NOT NULL,
+  "userId" text NOT NULL,
+  "postId" text,
+  "commentId" text,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "Tag" (
+  "id" text NOT NULL,
+  "name" text NOT NULL,
+  "color" text,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id"),
+  UNIQUE ("name")
+);
+CREATE TABLE "Team" (
+  "id" text NOT NULL,
+  "name" text NOT NULL,
+  "description" text,
+  "organizationId" text NOT NULL,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE TABLE "TeamMember" (
+  "id" text NOT NULL,
+  "role" "TeamRole" NOT NULL,
+  "userId" text NOT NULL,
+  "teamId" text NOT NULL,
+  "replacedById" text,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  PRIMARY KEY ("id"),
+  UNIQUE ("replacedById")
+);
+CREATE TABLE "User" (
+  "id" text NOT NULL,
+  "email" text NOT NULL,
+  "name" text,
+  "password" text NOT NULL,
+  "role" "Role" NOT NULL,
+  "status" "UserStatus" NOT NULL,
+  "preferences" json,
+  "lastLoginAt" timestamp without time zone,
+  "createdAt" timestamp without time zone NOT NULL,
+  "updatedAt" timestamp without time zone NOT NULL,
+  "managerId" text,
+  "mentorId" text,
+  PRIMARY KEY ("id"),
+  UNIQUE ("email")
+);
+ALTER TABLE "Address" ADD CONSTRAINT "Address_profileId_fkey" FOREIGN KEY ("profileId") REFERENCES "Profile"("id");
+ALTER TABLE "Attachment" ADD CONSTRAINT "Attachment_postId_fkey" FOREIGN KEY ("postId") REFERENCES "Post"("id");
+ALTER TABLE "Comment" ADD CONSTRAINT "Comment_authorId_fkey" FOREIGN KEY ("authorId") REFERENCES "User"("id");
+ALTER TABLE "Comment" ADD CONSTRAINT "Comment_postId_fkey" FOREIGN KEY ("postId") REFERENCES "Post"("id");
+ALTER TABLE "Notification" ADD CONSTRAINT "Notification_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User"("id");
+ALTER TABLE "OrganizationMember" ADD CONSTRAINT "OrganizationMember_organizationId_fkey" FOREIGN KEY ("organizationId") REFERENCES "Organization"("id");
+ALTER TABLE "OrganizationMember" ADD CONSTRAINT "OrganizationMember_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User"("id");
+ALTER TABLE "Post" ADD CONSTRAINT "Post_authorId_fkey" FOREIGN KEY ("authorId") REFERENCES "User"("id");
+ALTER TABLE "Post" ADD CONSTRAINT "Post_projectId_fkey" FOREIGN KEY ("projectId") REFERENCES "Project"("id");
+ALTER TABLE "Profile" ADD CONSTRAINT "Profile_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User"("id");
+ALTER TABLE "Project" ADD CONSTRAINT "Project_organizationId_fkey" FOREIGN KEY ("organizationId") REFERENCES "Organization"("id");
+ALTER TABLE "Project" ADD CONSTRAINT "Project_teamId_fkey" FOREIGN KEY ("teamId") REFERENCES "Team"("id");
+ALTER TABLE "Reaction" ADD CONSTRAINT "Reaction_commentId_fkey" FOREIGN KEY ("commentId") REFERENCES "Comment"("id");
+ALTER TABLE "Reaction" ADD CONSTRAINT "Reaction_postId_fkey" FOREIGN KEY ("postId") REFERENCES "Post"("id");
+ALTER TABLE "Reaction" ADD CONSTRAINT "Reaction_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User"("id");
+ALTER TABLE "Team" ADD CONSTRAINT "Team_organizationId_fkey" FOREIGN KEY ("organizationId") REFERENCES "Organization"("id");
+ALTER TABLE "TeamMember" ADD CONSTRAINT "TeamMember_teamId_fkey" FOREIGN KEY ("teamId") REFERENCES "Team"("id");
+ALTER TABLE "TeamMember" ADD CONSTRAINT "TeamMember_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User"("id");
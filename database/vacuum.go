@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/ui"
+	"github.com/lib/pq"
+)
+
+// VacuumMode selects what a restore does, once it has committed, about
+// the space reload cycles leave behind on long-lived databases.
+type VacuumMode string
+
+const (
+	// VacuumOff leaves it to autovacuum.
+	VacuumOff VacuumMode = ""
+	// VacuumRun vacuums the restored tables (OPTIMIZE TABLE on MySQL).
+	VacuumRun VacuumMode = "run"
+	// VacuumAdvise measures the reclaimable space and prints what to run,
+	// changing nothing.
+	VacuumAdvise VacuumMode = "advise"
+)
+
+// ParseVacuumMode validates a --vacuum value. Empty means VacuumOff.
+func ParseVacuumMode(s string) (VacuumMode, error) {
+	switch mode := VacuumMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case VacuumOff, VacuumRun, VacuumAdvise:
+		return mode, nil
+	case "off":
+		return VacuumOff, nil
+	default:
+		return "", fmt.Errorf("unknown vacuum mode %q (supported: run, advise)", s)
+	}
+}
+
+// tableBloat is the reclaimable space of one table after a restore.
+type tableBloat struct {
+	Table string
+	// Live and Dead are PostgreSQL's row estimates; Dead rows are the
+	// ones DELETE-based reloads (--tables, upsert) leave until vacuumed.
+	Live, Dead int64
+	// Size is the table's size on disk, indexes included, and Free the
+	// bytes allocated to it but unused (MySQL's DATA_FREE).
+	Size, Free int64
+	// Catalog marks a system catalog, which repeated CREATE and DROP of
+	// tables and sequences bloats rather than the reload itself.
+	Catalog bool
+}
+
+// sqlQueryer is the subset of *sql.DB / *sql.Conn the post-restore
+// reclaim step needs.
+type sqlQueryer interface {
+	sqlExecer
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// pgBloatCatalogs are the system catalogs every restore that creates
+// tables writes to.
+var pgBloatCatalogs = []string{"pg_catalog.pg_class", "pg_catalog.pg_attribute", "pg_catalog.pg_type", "pg_catalog.pg_depend"}
+
+// vacuumAdvice is the statement worth running for b, and why, or "" when
+// b has nothing to reclaim. Dead rows outnumbering live ones are only
+// given back to the operating system by VACUUM FULL, which locks the
+// table while it rewrites it; a plain VACUUM makes the rest reusable.
+func vacuumAdvice(b tableBloat, mysql bool) (stmt, why string) {
+	if mysql {
+		if b.Free == 0 || b.Free*10 < b.Size {
+			return "", ""
+		}
+		return "OPTIMIZE TABLE " + b.Table, fmt.Sprintf("%s free of %s", formatBytes(b.Free), formatBytes(b.Size))
+	}
+	if b.Dead == 0 {
+		return "", ""
+	}
+	why = fmt.Sprintf("%d dead row(s), %d live, %s on disk", b.Dead, b.Live, formatBytes(b.Size))
+	if b.Dead > b.Live && !b.Catalog {
+		return "VACUUM FULL " + b.Table, why + "; rewrites the table under an exclusive lock"
+	}
+	return "VACUUM " + b.Table, why
+}
+
+// formatBytes renders n in the largest binary unit that keeps it ≥ 1.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// reclaimPostgres handles opts.Vacuum after a PostgreSQL restore of
+// tables: VACUUM them, or measure their dead rows and those of the system
+// catalogs and print what to run.
+func (p *PostgresManager) reclaimPostgres(ctx context.Context, q sqlQueryer, tables []string, opts RestoreOptions) error {
+	if opts.Vacuum == VacuumOff || len(tables) == 0 {
+		return nil
+	}
+	if p.isCockroach() {
+		// MVCC garbage is collected on CockroachDB's own schedule
+		// (gc.ttlseconds); there is no VACUUM to run.
+		ui.Info("CockroachDB reclaims space itself; --vacuum has nothing to do")
+		return nil
+	}
+	quoted := make([]string, len(tables))
+	for i, t := range tables {
+		quoted[i] = pq.QuoteIdentifier(opts.pgSchema()) + "." + pq.QuoteIdentifier(t)
+	}
+	if opts.Vacuum == VacuumRun {
+		stmt := "VACUUM " + strings.Join(quoted, ", ")
+		p.logSQL("Vacuum", stmt)
+		ui.Step("Vacuuming %d table(s)...", len(tables))
+		if _, err := q.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("vacuuming restored tables: %v", err)
+		}
+		return nil
+	}
+
+	// The statistics are whatever the collector last reported, so rows
+	// the restore itself deleted may not be counted yet.
+	rows, err := q.QueryContext(ctx, `
+		SELECT s.relid::regclass::text, s.n_live_tup, s.n_dead_tup,
+		       pg_total_relation_size(s.relid), s.schemaname = 'pg_catalog'
+		FROM pg_stat_all_tables s
+		WHERE s.relid = ANY($1::regclass[])
+		ORDER BY s.n_dead_tup DESC`, pq.Array(append(quoted, pgBloatCatalogs...)))
+	if err != nil {
+		return fmt.Errorf("measuring dead rows: %v", err)
+	}
+	defer rows.Close()
+	var bloat []tableBloat
+	for rows.Next() {
+		var b tableBloat
+		if err := rows.Scan(&b.Table, &b.Live, &b.Dead, &b.Size, &b.Catalog); err != nil {
+			return err
+		}
+		bloat = append(bloat, b)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	printVacuumAdvice(bloat, false)
+	return nil
+}
+
+// reclaimMySQL handles opts.Vacuum after a MySQL restore of tables:
+// OPTIMIZE TABLE them, or measure their free space and print what to run.
+func (m *MySQLManager) reclaimMySQL(ctx context.Context, tables []string, opts RestoreOptions) error {
+	if opts.Vacuum == VacuumOff || len(tables) == 0 {
+		return nil
+	}
+	if opts.Vacuum == VacuumRun {
+		quoted := make([]string, len(tables))
+		for i, t := range tables {
+			quoted[i] = quoteIdent(t)
+		}
+		stmt := "OPTIMIZE TABLE " + strings.Join(quoted, ", ")
+		m.logSQL("Optimize", stmt)
+		ui.Step("Optimizing %d table(s)...", len(tables))
+		// OPTIMIZE TABLE reports per-table failures as result rows, so
+		// they are drained rather than executed away.
+		rows, err := m.DB.QueryContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("optimizing restored tables: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var table, op, msgType, msg string
+			if err := rows.Scan(&table, &op, &msgType, &msg); err != nil {
+				return err
+			}
+			if strings.EqualFold(msgType, "error") {
+				ui.Warn("%s: %s", table, msg)
+			}
+		}
+		return rows.Err()
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tables)), ",")
+	args := make([]interface{}, len(tables))
+	for i, t := range tables {
+		args[i] = t
+	}
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT TABLE_NAME, COALESCE(DATA_LENGTH + INDEX_LENGTH, 0), COALESCE(DATA_FREE, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME IN (`+placeholders+`)
+		ORDER BY DATA_FREE DESC`, args...)
+	if err != nil {
+		return fmt.Errorf("measuring free space: %v", err)
+	}
+	defer rows.Close()
+	var bloat []tableBloat
+	for rows.Next() {
+		var b tableBloat
+		if err := rows.Scan(&b.Table, &b.Size, &b.Free); err != nil {
+			return err
+		}
+		b.Table = quoteIdent(b.Table)
+		bloat = append(bloat, b)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	printVacuumAdvice(bloat, true)
+	return nil
+}
+
+// printVacuumAdvice prints a statement per table in bloat worth
+// reclaiming. Names are printed as the database reported them.
+func printVacuumAdvice(bloat []tableBloat, mysql bool) {
+	shown, catalogs := 0, false
+	for _, b := range bloat {
+		stmt, why := vacuumAdvice(b, mysql)
+		if stmt == "" {
+			continue
+		}
+		if shown == 0 {
+			ui.Info("Space to reclaim after the restore:")
+		}
+		shown++
+		catalogs = catalogs || b.Catalog
+		ui.Info("  %s;  -- %s", stmt, why)
+	}
+	if shown == 0 {
+		ui.Info("Nothing to reclaim after the restore")
+		return
+	}
+	if catalogs {
+		ui.Info("Vacuuming pg_catalog tables takes a superuser or the database owner.")
+	}
+}
@@ -0,0 +1,45 @@
+package db
+
+import "testing"
+
+func TestVacuumAdvice(t *testing.T) {
+	cases := []struct {
+		name  string
+		b     tableBloat
+		mysql bool
+		want  string
+	}{
+		{"clean", tableBloat{Table: "users", Live: 100}, false, ""},
+		{"some dead rows", tableBloat{Table: "users", Live: 100, Dead: 40}, false, "VACUUM users"},
+		{"mostly dead", tableBloat{Table: "users", Live: 10, Dead: 400}, false, "VACUUM FULL users"},
+		// Catalogs are never rewritten: VACUUM FULL on them blocks every
+		// session of the database.
+		{"catalog", tableBloat{Table: "pg_class", Live: 10, Dead: 400, Catalog: true}, false, "VACUUM pg_class"},
+		{"mysql little free", tableBloat{Table: "`users`", Size: 1 << 20, Free: 1 << 10}, true, ""},
+		{"mysql fragmented", tableBloat{Table: "`users`", Size: 1 << 20, Free: 1 << 19}, true, "OPTIMIZE TABLE `users`"},
+	}
+	for _, c := range cases {
+		if stmt, _ := vacuumAdvice(c.b, c.mysql); stmt != c.want {
+			t.Errorf("%s: stmt = %q, want %q", c.name, stmt, c.want)
+		}
+	}
+}
+
+func TestParseVacuumMode(t *testing.T) {
+	for in, want := range map[string]VacuumMode{"": VacuumOff, "off": VacuumOff, "RUN": VacuumRun, " advise ": VacuumAdvise} {
+		if got, err := ParseVacuumMode(in); err != nil || got != want {
+			t.Errorf("ParseVacuumMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseVacuumMode("full"); err == nil {
+		t.Error("want an error for an unknown mode")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 3 << 30: "3.0 GiB"} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package db
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+	"github.com/KazanKK/seedmancer/internal/envmarker"
+)
+
+// Problem is one integrity issue ValidateFixtureDir found. Row is the
+// 1-based data row (the header is row 0) and is zero, like Column, for
+// problems with a whole file.
+type Problem struct {
+	File    string `json:"file"`
+	Row     int    `json:"row,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	loc := p.File
+	if p.Row > 0 {
+		loc += fmt.Sprintf(":%d", p.Row)
+	}
+	if p.Column != "" {
+		loc += " " + p.Column
+	}
+	return loc + ": " + p.Message
+}
+
+// ValidationReport is the result of ValidateFixtureDir.
+type ValidationReport struct {
+	Tables   int       `json:"tables"`
+	Rows     int       `json:"rows"`
+	Problems []Problem `json:"problems"`
+}
+
+// fkCell is a foreign key value waiting for its parent table to be read.
+type fkCell struct {
+	file   string
+	row    int
+	column string
+	ref    ForeignKey
+	value  string
+}
+
+// ValidateFixtureDir checks a revision's data against the schema.json at
+// schemaPath without touching a database: the schema parses and is
+// consistent (see checkSchemaConsistency), every table
+// has a CSV (a header-only one when it is meant to be empty), headers
+// name the table's columns and include every required one, and each cell
+// parses as its column type, is a legal enum or CHECK value, fits its
+// varchar length, is not NULL in a NOT NULL column, and references an
+// existing parent key. Only unreadable files are returned as an error.
+//
+// NULL, @env markers and SQL expression cells are resolved at seed time,
+// so they are skipped by the per-value checks. Foreign keys into tables
+// with no CSV in dataDir are not checked.
+func ValidateFixtureDir(schemaPath, dataDir string) (ValidationReport, error) {
+	report := ValidationReport{Problems: []Problem{}}
+	raw, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return report, fmt.Errorf("reading schema.json: %v", err)
+	}
+	schema, err := parseSchema(raw)
+	if err != nil {
+		report.Problems = append(report.Problems, Problem{File: "schema.json", Message: err.Error()})
+		return report, nil
+	}
+
+	report.Problems = append(report.Problems, checkSchemaConsistency(schema)...)
+	enums := make(map[string][]string, len(schema.Enums))
+	for _, e := range schema.Enums {
+		enums[e.Name] = e.Values
+	}
+	// Parent columns referenced by some FK, and the keys read for them.
+	referenced := map[string]map[string]bool{}
+	for _, t := range schema.Tables {
+		for _, col := range t.Columns {
+			if fk := col.ForeignKey; fk != nil {
+				if referenced[fk.Table] == nil {
+					referenced[fk.Table] = map[string]bool{}
+				}
+				referenced[fk.Table][fk.Column] = true
+			}
+		}
+	}
+	keys := map[string]map[string]map[string]bool{}
+	var pending []fkCell
+
+	known := map[string]bool{}
+	for _, table := range schema.Tables {
+		file := table.Name + ".csv"
+		known[file] = true
+		f, err := csvshard.Open(filepath.Join(dataDir, file))
+		if os.IsNotExist(err) {
+			report.Problems = append(report.Problems, Problem{File: file,
+				Message: "missing: every table needs a CSV, a header-only one if it should be empty"})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Tables++
+		n, cells, problems := validateTableCSV(f, file, table, enums, referenced[table.Name], keys)
+		f.Close()
+		report.Rows += n
+		report.Problems = append(report.Problems, problems...)
+		pending = append(pending, cells...)
+	}
+
+	for _, c := range pending {
+		parent, ok := keys[c.ref.Table]
+		if !ok {
+			continue
+		}
+		if !parent[c.ref.Column][c.value] {
+			report.Problems = append(report.Problems, Problem{File: c.file, Row: c.row, Column: c.column,
+				Message: fmt.Sprintf("%q has no matching %s.%s", c.value, c.ref.Table, c.ref.Column)})
+		}
+	}
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return report, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if table, _, ok := csvshard.Parse(name); ok {
+			name = table + ".csv"
+		}
+		if !e.IsDir() && strings.HasSuffix(name, ".csv") && !known[name] {
+			report.Problems = append(report.Problems, Problem{File: e.Name(), Message: "no table of that name in schema.json"})
+		}
+	}
+
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		a, b := report.Problems[i], report.Problems[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Row < b.Row
+	})
+	return report, nil
+}
+
+// checkSchemaConsistency reports schema.json entries that refer to
+// things it doesn't define: duplicate table or column names, foreign keys
+// to unknown tables or columns, columns of an undeclared enum, domain or
+// composite type, and varchar lengths that aren't a positive number.
+func checkSchemaConsistency(schema Schema) []Problem {
+	var problems []Problem
+	add := func(column, format string, args ...interface{}) {
+		problems = append(problems, Problem{File: "schema.json", Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+	enums := map[string]bool{}
+	for _, e := range schema.Enums {
+		if enums[e.Name] {
+			add("", "enum %s is declared twice", e.Name)
+		}
+		enums[e.Name] = true
+	}
+	types := map[string]bool{}
+	for _, d := range schema.Domains {
+		types[d.Name] = true
+	}
+	for _, c := range schema.CompositeTypes {
+		types[c.Name] = true
+	}
+	columns := map[string]map[string]bool{}
+	for _, t := range schema.Tables {
+		if columns[t.Name] != nil {
+			add("", "table %s is declared twice", t.Name)
+			continue
+		}
+		columns[t.Name] = map[string]bool{}
+		for _, col := range t.Columns {
+			if columns[t.Name][col.Name] {
+				add(t.Name+"."+col.Name, "column is declared twice")
+			}
+			columns[t.Name][col.Name] = true
+		}
+	}
+	for _, t := range schema.Tables {
+		for _, col := range t.Columns {
+			name := t.Name + "." + col.Name
+			if fk := col.ForeignKey; fk != nil {
+				switch {
+				case columns[fk.Table] == nil:
+					add(name, "foreign key references unknown table %s", fk.Table)
+				case !columns[fk.Table][fk.Column]:
+					add(name, "foreign key references unknown column %s.%s", fk.Table, fk.Column)
+				}
+			}
+			if col.Enum != "" && !enums[col.Enum] {
+				add(name, "enum %s is not declared in enums", col.Enum)
+			}
+			if col.Domain != "" && !types[col.Domain] {
+				add(name, "domain %s is not declared in domains", col.Domain)
+			}
+			if col.Composite != "" && !types[col.Composite] {
+				add(name, "composite type %s is not declared in compositeTypes", col.Composite)
+			}
+			if col.Varchar != nil {
+				if n, err := strconv.Atoi(strings.TrimSpace(*col.Varchar)); err != nil || n <= 0 {
+					add(name, "varchar length %q is not a positive number", *col.Varchar)
+				}
+			}
+			if col.Precision != nil && *col.Precision < 0 {
+				add(name, "precision %d is negative", *col.Precision)
+			}
+			if col.Scale != nil && (*col.Scale < 0 || col.Precision != nil && *col.Scale > *col.Precision) {
+				add(name, "scale %d is outside 0 to the precision", *col.Scale)
+			}
+		}
+	}
+	return problems
+}
+
+// validateTableCSV checks one table's CSV. Values of the columns in
+// referenced are recorded in keys for the FK pass; the table's own FK
+// cells are returned for it.
+func validateTableCSV(r io.Reader, file string, table Table, enums map[string][]string, referenced map[string]bool, keys map[string]map[string]map[string]bool) (int, []fkCell, []Problem) {
+	var problems []Problem
+	add := func(row int, column, format string, args ...interface{}) {
+		problems = append(problems, Problem{File: file, Row: row, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		add(0, "", "empty file: expected at least a header row")
+		return 0, nil, problems
+	}
+	if err != nil {
+		add(0, "", "%v", err)
+		return 0, nil, problems
+	}
+
+	columns := make(map[string]Column, len(table.Columns))
+	for _, col := range table.Columns {
+		columns[col.Name] = col
+	}
+	cols := make([]*Column, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		if seen[name] {
+			add(0, name, "column appears twice in the header")
+		}
+		seen[name] = true
+		col, ok := columns[name]
+		if !ok {
+			add(0, name, "not a column of %s in schema.json", table.Name)
+			continue
+		}
+		cols[i] = &col
+	}
+	for _, col := range table.Columns {
+		if !seen[col.Name] && requiresValue(col) && !col.Excluded {
+			add(0, col.Name, "NOT NULL column without a default is missing from the header")
+		}
+	}
+
+	if keys[table.Name] == nil && len(referenced) > 0 {
+		keys[table.Name] = map[string]map[string]bool{}
+		for c := range referenced {
+			keys[table.Name][c] = map[string]bool{}
+		}
+	}
+
+	var pending []fkCell
+	n := 0
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		n++
+		if err != nil {
+			add(n, "", "%v", err)
+			break
+		}
+		for i, cell := range record {
+			col := cols[i]
+			if col == nil {
+				continue
+			}
+			if cellexpr.Is(cell) || envmarker.IsMarker(cell) {
+				continue
+			}
+			if cell == "NULL" || cell == "null" {
+				if requiresValue(*col) {
+					add(n, col.Name, "NULL in a NOT NULL column")
+				}
+				continue
+			}
+			if referenced[col.Name] {
+				keys[table.Name][col.Name][cell] = true
+			}
+			v, err := fixtureValue(*col, cell)
+			if err != nil {
+				add(n, col.Name, "%v", err)
+				continue
+			}
+			if v == nil {
+				continue
+			}
+			if msg := checkAllowedValue(*col, cell, enums); msg != "" {
+				add(n, col.Name, "%s", msg)
+			}
+			if max, ok := varcharLimit(*col); ok && utf8.RuneCountInString(cell) > max {
+				add(n, col.Name, "%d characters exceed %s(%d)", utf8.RuneCountInString(cell), col.Type, max)
+			}
+			if col.ForeignKey != nil {
+				pending = append(pending, fkCell{file: file, row: n, column: col.Name, ref: *col.ForeignKey, value: cell})
+			}
+		}
+	}
+	return n, pending, problems
+}
+
+// requiresValue reports whether col must get a value from the CSV: NOT
+// NULL with nothing in the database to fill it in.
+func requiresValue(col Column) bool {
+	return !col.Nullable && col.Default == nil && !col.IsGenerated && col.Identity == "" && !isAutoIncrement(col)
+}
+
+// checkAllowedValue describes why cell is not a legal value for col's
+// enum or IN-list CHECK constraint, or returns "".
+func checkAllowedValue(col Column, cell string, enums map[string][]string) string {
+	if col.Enum != "" {
+		if values, ok := enums[col.Enum]; ok && !containsString(values, cell) {
+			return fmt.Sprintf("%q is not a value of enum %s (%s)", cell, col.Enum, strings.Join(values, ", "))
+		}
+	}
+	if len(col.AllowedValues) > 0 && !containsString(col.AllowedValues, cell) {
+		return fmt.Sprintf("%q is not allowed by the CHECK constraint (%s)", cell, strings.Join(col.AllowedValues, ", "))
+	}
+	return ""
+}
+
+// varcharLimit returns the declared length of a varchar or char column.
+func varcharLimit(col Column) (int, bool) {
+	if col.Varchar == nil {
+		return 0, false
+	}
+	switch strings.ToLower(col.Type) {
+	case "varchar", "character varying", "char", "character":
+	default:
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(*col.Varchar))
+	return n, err == nil && n > 0
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
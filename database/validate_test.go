@@ -0,0 +1,113 @@
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFixtureDir(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{"databaseType":"postgres","enums":[{"name":"plan","values":["free","pro"]}],"tables":[
+	  {"name":"orgs","columns":[
+	    {"name":"id","type":"integer","isPrimary":true},
+	    {"name":"name","type":"character varying","varchar":"5"},
+	    {"name":"plan","type":"enum","enum":"plan"}
+	  ]},
+	  {"name":"users","columns":[
+	    {"name":"id","type":"integer","isPrimary":true},
+	    {"name":"org_id","type":"integer","nullable":true,"foreignKey":{"table":"orgs","column":"id"}},
+	    {"name":"status","type":"text","allowedValues":["active","banned"]},
+	    {"name":"created_at","type":"timestamp","default":"now()"}
+	  ]},
+	  {"name":"audit","columns":[{"name":"id","type":"integer"}]},
+	  {"name":"tags","columns":[{"name":"id","type":"integer"}]}
+	]}`
+	writeFixtureFile(t, dir, "schema.json", schema)
+	writeFixtureFile(t, dir, "orgs.csv", "id,name,plan\n1,acme,pro\n2,toolong,gold\n")
+	writeFixtureFile(t, dir, "users.csv", "id,org_id,status,nickname\n"+
+		"1,1,active,x\n"+
+		"2,9,active,x\n"+
+		"x,NULL,deleted,x\n"+
+		"NULL,@env:ORG_ID,=DEFAULT,x\n")
+	writeFixtureFile(t, dir, "tags.csv", "id\n")
+	writeFixtureFile(t, dir, "stale.csv", "id\n1\n")
+
+	report, err := ValidateFixtureDir(filepath.Join(dir, "schema.json"), dir)
+	if err != nil {
+		t.Fatalf("ValidateFixtureDir: %v", err)
+	}
+	if report.Tables != 3 || report.Rows != 6 {
+		t.Errorf("tables, rows = %d, %d; want 3, 6", report.Tables, report.Rows)
+	}
+	var got []string
+	for _, p := range report.Problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"audit.csv: missing",
+		`orgs.csv:2 name: 7 characters exceed character varying(5)`,
+		`orgs.csv:2 plan: "gold" is not a value of enum plan`,
+		"stale.csv: no table of that name",
+		"users.csv nickname: not a column of users",
+		`users.csv:2 org_id: "9" has no matching orgs.id`,
+		`users.csv:3 id: "x" is not an integer`,
+		`users.csv:3 status: "deleted" is not allowed by the CHECK constraint`,
+		"users.csv:4 id: NULL in a NOT NULL column",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("problems:\n%s", strings.Join(got, "\n"))
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(got[i], w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, got[i], w)
+		}
+	}
+}
+
+func TestValidateFixtureDir_badSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFile(t, dir, "schema.json", `{"tables":[{"name":"users","columns":[{"name":"id","type":7}]}]}`)
+	report, err := ValidateFixtureDir(filepath.Join(dir, "schema.json"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].File != "schema.json" {
+		t.Errorf("problems = %+v, want one schema.json problem", report.Problems)
+	}
+}
+
+func TestValidateFixtureDir_schemaConsistency(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFile(t, dir, "schema.json", `{"databaseType":"postgres","enums":[],"tables":[
+	  {"name":"posts","columns":[
+	    {"name":"id","type":"integer"},
+	    {"name":"id","type":"integer"},
+	    {"name":"author_id","type":"integer","foreignKey":{"table":"users","column":"id"}},
+	    {"name":"org_id","type":"integer","foreignKey":{"table":"posts","column":"org"}},
+	    {"name":"state","type":"enum","enum":"post_state"},
+	    {"name":"slug","type":"varchar","varchar":"-1"},
+	    {"name":"score","type":"integer","domain":"positive_int"}
+	  ]}
+	]}`)
+	writeFixtureFile(t, dir, "posts.csv", "id,author_id,org_id,state,slug,score\n")
+	report, err := ValidateFixtureDir(filepath.Join(dir, "schema.json"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range report.Problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"schema.json posts.id: column is declared twice",
+		"schema.json posts.author_id: foreign key references unknown table users",
+		"schema.json posts.org_id: foreign key references unknown column posts.org",
+		"schema.json posts.state: enum post_state is not declared in enums",
+		`schema.json posts.slug: varchar length "-1" is not a positive number`,
+		"schema.json posts.score: domain positive_int is not declared in domains",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
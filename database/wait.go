@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// waitPollInterval is the pause between readiness probes.
+const waitPollInterval = time.Second
+
+// WaitForReady polls rawDSN until the server accepts connections and can
+// take writes — out of recovery on PostgreSQL, not read-only on MySQL,
+// answering at all on CockroachDB — or timeout elapses. onWait, when non-nil, receives the reason after
+// every failed probe so callers can show progress.
+func WaitForReady(ctx context.Context, rawDSN string, timeout time.Duration, onWait func(reason string)) error {
+	normalized, scheme, err := normalizeDSN(rawDSN)
+	if err != nil {
+		return err
+	}
+	var driver, readyQuery string
+	switch scheme {
+	case "postgres":
+		driver, readyQuery = "postgres", "SELECT pg_is_in_recovery()"
+	case "cockroach":
+		driver, readyQuery = "postgres", "SELECT false"
+	case "mysql", "mariadb":
+		driver, readyQuery = "mysql", "SELECT @@global.read_only = 1"
+	default:
+		return fmt.Errorf("unsupported database scheme %q (supported: postgres, mysql, cockroach, mariadb)", scheme)
+	}
+	conn, err := sql.Open(driver, normalized)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		reason := probeReady(ctx, conn, scheme, readyQuery)
+		if reason == "" {
+			return nil
+		}
+		if onWait != nil {
+			onWait(reason)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not ready after %s: %s", timeout, reason)
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// probeReady runs one readiness check and returns why the server isn't
+// ready yet, or "" when it is.
+func probeReady(ctx context.Context, conn *sql.DB, scheme, readyQuery string) string {
+	probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var busy bool
+	if err := conn.QueryRowContext(probeCtx, readyQuery).Scan(&busy); err != nil {
+		return err.Error()
+	}
+	if busy {
+		if scheme == "postgres" {
+			return "server is still in recovery"
+		}
+		return "server is read-only"
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForReady_timesOutWithLastReason(t *testing.T) {
+	var probes int
+	err := WaitForReady(context.Background(), "postgres://u:p@127.0.0.1:1/app", 1500*time.Millisecond, func(string) { probes++ })
+	if err == nil || !strings.Contains(err.Error(), "database not ready after 1.5s") {
+		t.Fatalf("err = %v, want a not-ready timeout", err)
+	}
+	if probes < 2 {
+		t.Fatalf("probes = %d, want the wait to retry", probes)
+	}
+}
+
+func TestWaitForReady_rejectsUnknownScheme(t *testing.T) {
+	if err := WaitForReady(context.Background(), "sqlite://x", time.Second, nil); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/ui"
+)
+
+// sqlExecer is the subset of *sql.DB / *sql.Conn warmUp needs.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// warmUp runs once a restore has committed, when opts asks for it:
+// analyze (ANALYZE on PostgreSQL, ANALYZE TABLE on MySQL) over tables so
+// the planner has statistics for the new rows, then opts.WarmupSQL to
+// prime caches and materialized views.
+func warmUp(ctx context.Context, q sqlExecer, analyze string, quote func(string) string, tables []string, opts RestoreOptions, logSQL func(operation, sql string)) error {
+	if opts.Analyze && len(tables) > 0 {
+		quoted := make([]string, len(tables))
+		for i, t := range tables {
+			quoted[i] = quote(t)
+		}
+		stmt := analyze + " " + strings.Join(quoted, ", ")
+		logSQL("Analyze", stmt)
+		if _, err := q.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("analyzing restored tables: %v", err)
+		}
+	}
+	if strings.TrimSpace(opts.WarmupSQL) == "" {
+		return nil
+	}
+	ui.Step("Running warmup queries...")
+	logSQL("Warmup", opts.WarmupSQL)
+	if _, err := q.ExecContext(ctx, opts.WarmupSQL); err != nil {
+		return fmt.Errorf("running warmup SQL: %v", err)
+	}
+	return nil
+}
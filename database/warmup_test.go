@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+type recordingExecer struct{ stmts []string }
+
+func (r *recordingExecer) ExecContext(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+	r.stmts = append(r.stmts, query)
+	return nil, nil
+}
+
+func TestWarmUp(t *testing.T) {
+	noLog := func(string, string) {}
+	cases := []struct {
+		name string
+		opts RestoreOptions
+		want []string
+	}{
+		{"off", RestoreOptions{}, nil},
+		{"analyze only", RestoreOptions{Analyze: true}, []string{`ANALYZE "users", "orders"`}},
+		{"analyze then warmup", RestoreOptions{Analyze: true, WarmupSQL: "SELECT 1;"}, []string{`ANALYZE "users", "orders"`, "SELECT 1;"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var rec recordingExecer
+			if err := warmUp(context.Background(), &rec, "ANALYZE", pq.QuoteIdentifier, []string{"users", "orders"}, c.opts, noLog); err != nil {
+				t.Fatalf("warmUp: %v", err)
+			}
+			if !reflect.DeepEqual(rec.stmts, c.want) {
+				t.Fatalf("statements = %q, want %q", rec.stmts, c.want)
+			}
+		})
+	}
+}
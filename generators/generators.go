@@ -0,0 +1,133 @@
+// Package generators is the extension point for custom value generators:
+// realistic IBANs, an internal SKU format, anything the built-in fake
+// data doesn't know. A generator is registered under a name and picked
+// per column in seedmancer.yaml:
+//
+//	generators:
+//	  accounts.iban: iban     # one column of one table
+//	  sku: internal_sku       # a column of that name in every table
+//
+// Programs that drive seedmancer as a library call Register before
+// generating. The CLI can't see those, so it also loads Go plugins listed
+// under generator_plugins (see LoadPlugin); a plugin registers its
+// generators from an init function the same way.
+//
+// Every generator sees the same Request for the same run, table, column
+// and row, so a deterministic generator keeps repeatable output
+// repeatable.
+package generators
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Request describes the cell a generator fills in.
+type Request struct {
+	Table  string
+	Column string
+	// Type is the column's database type as declared, e.g. "varchar(34)".
+	Type string
+	// Row is the 1-based number of the row within the table in this run.
+	Row int
+	// Rand is the random source to draw from; it is seeded from the run,
+	// so values drawn from it are as repeatable as the rest of the run.
+	Rand *rand.Rand
+}
+
+// Func makes up the value of one cell, as the text that goes into the
+// database or CSV. An error stops generation.
+type Func func(Request) (string, error)
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Func{}
+	// loaded are the plugin files LoadPlugin opened.
+	loaded = map[string]bool{}
+)
+
+// Register makes fn available under name. Names are case-insensitive;
+// registering a name twice panics, like database/sql.Register, since two
+// plugins fighting over a name is a setup error.
+func Register(name string, fn Func) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		panic("generators: Register with an empty name")
+	}
+	if fn == nil {
+		panic("generators: Register " + name + " with a nil Func")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := registry[key]; dup {
+		panic("generators: Register called twice for " + name)
+	}
+	registry[key] = fn
+}
+
+// Lookup returns the generator registered under name.
+func Lookup(name string) (Func, error) {
+	mu.RLock()
+	fn, ok := registry[strings.ToLower(strings.TrimSpace(name))]
+	mu.RUnlock()
+	if !ok {
+		if names := Names(); len(names) > 0 {
+			return nil, fmt.Errorf("no generator named %q (registered: %s)", name, strings.Join(names, ", "))
+		}
+		return nil, fmt.Errorf("no generator named %q (none registered — list plugins under generator_plugins)", name)
+	}
+	return fn, nil
+}
+
+// Names returns the registered generator names, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForColumns resolves a column → generator name map as written in
+// seedmancer.yaml ("table.column" or a bare column name, the former
+// winning) into a lookup for the generators. It fails on the first name
+// that isn't registered, so a typo shows up before any row is made. The
+// returned function gives nil for columns without a custom generator.
+func ForColumns(columns map[string]string) (func(table, column string) Func, error) {
+	resolved := make(map[string]Func, len(columns))
+	keys := make([]string, 0, len(columns))
+	for key := range columns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fn, err := Lookup(columns[key])
+		if err != nil {
+			return nil, fmt.Errorf("generators.%s: %v", key, err)
+		}
+		resolved[key] = fn
+	}
+	return func(table, column string) Func {
+		if fn, ok := resolved[table+"."+column]; ok {
+			return fn
+		}
+		return resolved[column]
+	}, nil
+}
+
+// Seed returns the seed of the random source a generator gets for one
+// column of a run, so each column draws its own repeatable sequence.
+func Seed(run int64, table, column string) int64 {
+	h := uint64(run) ^ 14695981039346656037
+	for _, b := range []byte(table + "." + column) {
+		h ^= uint64(b)
+		h *= 1099511628211
+	}
+	return int64(h)
+}
@@ -0,0 +1,65 @@
+package generators
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRegisterAndForColumns(t *testing.T) {
+	Register("Test_SKU", func(r Request) (string, error) { return "SKU-" + strconv.Itoa(r.Row), nil })
+	Register("test_iban", func(Request) (string, error) { return "DE00", nil })
+
+	lookup, err := ForColumns(map[string]string{
+		"sku":              "test_sku",
+		"legacy_items.sku": "TEST_IBAN",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := lookup("items", "sku")(Request{Row: 3}); v != "SKU-3" {
+		t.Errorf("bare column: got %q", v)
+	}
+	if v, _ := lookup("legacy_items", "sku")(Request{}); v != "DE00" {
+		t.Errorf("table.column should win over the bare column: got %q", v)
+	}
+	if lookup("items", "name") != nil {
+		t.Error("a column without a generator should get nil")
+	}
+
+	_, err = ForColumns(map[string]string{"accounts.iban": "ibn"})
+	if err == nil || !strings.Contains(err.Error(), "generators.accounts.iban") || !strings.Contains(err.Error(), "test_iban") {
+		t.Errorf("unknown generator: got %v", err)
+	}
+}
+
+func TestRegister_duplicatePanics(t *testing.T) {
+	Register("test_dup", func(Request) (string, error) { return "", nil })
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice should panic")
+		}
+	}()
+	Register("TEST_DUP", func(Request) (string, error) { return "", nil })
+}
+
+func TestSeed_perColumn(t *testing.T) {
+	if Seed(1, "a", "b") != Seed(1, "a", "b") {
+		t.Error("Seed should be stable")
+	}
+	if Seed(1, "a", "b") == Seed(1, "a", "c") || Seed(1, "a", "b") == Seed(2, "a", "b") {
+		t.Error("Seed should differ per column and run")
+	}
+}
+
+func TestLoadPlugin_refusesNonPlugins(t *testing.T) {
+	if err := LoadPlugin("iban.wasm"); err == nil || !strings.Contains(err.Error(), "WebAssembly") {
+		t.Errorf("wasm: got %v", err)
+	}
+	if err := LoadPlugin("iban.js"); err == nil {
+		t.Error("a .js file should be refused")
+	}
+	if err := LoadPlugin("/nonexistent/iban.so"); err == nil {
+		t.Error("a missing plugin should fail")
+	}
+}
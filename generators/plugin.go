@@ -0,0 +1,48 @@
+package generators
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+	"strings"
+)
+
+// LoadPlugin opens a Go plugin (a package main built with
+// `go build -buildmode=plugin`), whose init functions call Register.
+// The plugin must be built with the same Go version and the same version
+// of this module as the seedmancer binary, and Go plugins only load on
+// Linux, macOS and FreeBSD in binaries built with cgo; anything else
+// fails here with the loader's reason.
+//
+// WebAssembly modules are refused: the CLI doesn't ship a WASM runtime.
+func LoadPlugin(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".so":
+	case ".wasm":
+		return fmt.Errorf("%s: WebAssembly generators aren't supported; build a Go plugin (.so) or register the generator from Go", path)
+	default:
+		return fmt.Errorf("%s: not a Go plugin (.so)", path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	// Opening a plugin again doesn't run its init functions again.
+	mu.RLock()
+	done := loaded[abs]
+	mu.RUnlock()
+	if done {
+		return nil
+	}
+	before := len(Names())
+	if _, err := plugin.Open(abs); err != nil {
+		return fmt.Errorf("loading generator plugin: %v", err)
+	}
+	if len(Names()) == before {
+		return fmt.Errorf("%s registered no generators; call generators.Register from an init function", path)
+	}
+	mu.Lock()
+	loaded[abs] = true
+	mu.Unlock()
+	return nil
+}
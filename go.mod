@@ -8,6 +8,7 @@ require (
 	github.com/modelcontextprotocol/go-sdk v1.0.0
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/urfave/cli/v2 v2.25.7
+	golang.org/x/sys v0.29.0
 	golang.org/x/term v0.28.0
 )
 
@@ -16,7 +17,6 @@ require (
 	github.com/google/jsonschema-go v0.3.0 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
-	golang.org/x/sys v0.29.0 // indirect
 )
 
 require (
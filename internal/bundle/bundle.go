@@ -0,0 +1,177 @@
+// Package bundle turns a copy of the seedmancer executable into a
+// standalone seeder for one scenario revision.
+//
+// The revision is appended to the executable rather than compiled in
+// with go:embed, so bundling needs no Go toolchain or module download.
+// The layout is:
+//
+//	<executable> <zip archive> <manifest JSON> <trailer>
+//
+// where the trailer is the archive length and the manifest length
+// (big-endian uint64 each) followed by a 16-byte magic string. Executable
+// formats ignore trailing bytes, so the result still runs as-is, and at
+// startup the binary checks its own tail for the magic to decide whether
+// to behave as the full CLI or as the bundled seeder.
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// magic ends every bundled executable. Bumping the trailing digits marks
+// an incompatible layout change.
+const magic = "SEEDMANCERBNDL01"
+
+const trailerSize = 8 + 8 + len(magic)
+
+// Manifest describes the revision a bundle carries.
+type Manifest struct {
+	Scenario          string   `json:"scenario"`
+	Revision          string   `json:"revision"`
+	SchemaFingerprint string   `json:"schemaFingerprint"`
+	DatabaseType      string   `json:"databaseType,omitempty"`
+	Tables            []string `json:"tables"`
+	SeedmancerVersion string   `json:"seedmancerVersion,omitempty"`
+}
+
+// Write copies the executable at exePath to outPath and appends archive
+// (a zip of the restore files) and m. outPath is made executable.
+func Write(exePath, outPath string, archive []byte, m Manifest) error {
+	if _, err := Open(exePath); err == nil {
+		return fmt.Errorf("%s is itself a bundle; run bundle from the seedmancer CLI", exePath)
+	} else if !errors.Is(err, ErrNotBundle) {
+		return err
+	}
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(exePath)
+	if err != nil {
+		return fmt.Errorf("opening executable: %w", err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(outPath), "."+filepath.Base(outPath)+"-*")
+	if err != nil {
+		return fmt.Errorf("creating output: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	trailer := make([]byte, 16, trailerSize)
+	binary.BigEndian.PutUint64(trailer[0:8], uint64(len(archive)))
+	binary.BigEndian.PutUint64(trailer[8:16], uint64(len(manifest)))
+	trailer = append(trailer, magic...)
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("copying executable: %w", err)
+	}
+	for _, part := range [][]byte{archive, manifest, trailer} {
+		if _, err := tmp.Write(part); err != nil {
+			tmp.Close()
+			return fmt.Errorf("writing bundle: %w", err)
+		}
+	}
+	if err := tmp.Chmod(0755); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), outPath)
+}
+
+// ErrNotBundle is returned by Open for executables without a bundle.
+var ErrNotBundle = errors.New("not a seedmancer bundle")
+
+// Bundle is an opened bundled executable.
+type Bundle struct {
+	Manifest Manifest
+	archive  *zip.Reader
+}
+
+// Open reads the bundle appended to the executable at path. It returns
+// ErrNotBundle when the file doesn't end in a bundle trailer.
+func Open(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < int64(trailerSize) {
+		return nil, ErrNotBundle
+	}
+	trailer := make([]byte, trailerSize)
+	if _, err := f.ReadAt(trailer, size-int64(trailerSize)); err != nil {
+		return nil, err
+	}
+	if string(trailer[16:]) != magic {
+		return nil, ErrNotBundle
+	}
+	archiveLen := int64(binary.BigEndian.Uint64(trailer[0:8]))
+	manifestLen := int64(binary.BigEndian.Uint64(trailer[8:16]))
+	start := size - int64(trailerSize) - manifestLen - archiveLen
+	if archiveLen < 0 || manifestLen < 0 || start < 0 {
+		return nil, fmt.Errorf("corrupt bundle trailer in %s", path)
+	}
+
+	payload := make([]byte, archiveLen+manifestLen)
+	if _, err := f.ReadAt(payload, start); err != nil {
+		return nil, fmt.Errorf("reading bundle: %w", err)
+	}
+	var b Bundle
+	if err := json.Unmarshal(payload[archiveLen:], &b.Manifest); err != nil {
+		return nil, fmt.Errorf("reading bundle manifest: %w", err)
+	}
+	b.archive, err = zip.NewReader(bytes.NewReader(payload[:archiveLen]), archiveLen)
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle archive: %w", err)
+	}
+	return &b, nil
+}
+
+// Extract writes the bundled files into dir (flat, as they were added).
+func (b *Bundle) Extract(dir string) error {
+	for _, file := range b.archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if err := extractFile(file, filepath.Join(dir, filepath.Base(file.Name))); err != nil {
+			return fmt.Errorf("extracting %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+func extractFile(file *zip.File, dest string) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
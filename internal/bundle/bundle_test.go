@@ -0,0 +1,61 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteOpen_roundTrip(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "seedmancer")
+	if err := os.WriteFile(exe, []byte("\x7fELF not really an executable"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(exe); !errors.Is(err, ErrNotBundle) {
+		t.Fatalf("Open(plain exe) err = %v, want ErrNotBundle", err)
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, err := zw.Create("users.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("id,name\n1,Ada\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "seeder")
+	m := Manifest{Scenario: "billing/pro", Revision: "r003", Tables: []string{"users"}}
+	if err := Write(exe, out, archive.Bytes(), m); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if info, err := os.Stat(out); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Fatalf("seeder not executable: %v %v", info, err)
+	}
+
+	b, err := Open(out)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if b.Manifest.Scenario != "billing/pro" || b.Manifest.Revision != "r003" {
+		t.Fatalf("manifest = %+v", b.Manifest)
+	}
+	extractDir := t.TempDir()
+	if err := b.Extract(extractDir); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(extractDir, "users.csv"))
+	if err != nil || string(got) != "id,name\n1,Ada\n" {
+		t.Fatalf("users.csv = %q, %v", got, err)
+	}
+
+	if err := Write(out, filepath.Join(dir, "again"), archive.Bytes(), m); err == nil {
+		t.Fatal("bundling a bundle should fail")
+	}
+}
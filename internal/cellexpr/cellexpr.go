@@ -0,0 +1,63 @@
+// Package cellexpr recognises CSV cells that hold an SQL expression for
+// the database to evaluate at insert time instead of a literal value, so
+// a fixture can carry dynamic values without post-processing SQL.
+//
+// Syntax:
+//
+//	=DEFAULT                           the column's default
+//	\D                                 the same, spelled like COPY's \N
+//	=expr(now() - interval '3 days')   any SQL expression
+//
+// Rules:
+//   - Must be the entire cell value; "=DEFAULT" is case-insensitive, "\D"
+//     is not.
+//   - Everything else, including other cells starting with "=", is a
+//     literal. The literal text "=DEFAULT" is written =expr('=DEFAULT'),
+//     and "\D" likewise.
+//   - The expression is passed to the database verbatim, so it must be
+//     valid for the engine being seeded.
+package cellexpr
+
+import "strings"
+
+// Parse returns the SQL a cell stands for — "DEFAULT" or the text inside
+// =expr(…) — and whether the cell is an expression at all.
+func Parse(cell string) (string, bool) {
+	if strings.EqualFold(cell, "=DEFAULT") || cell == `\D` {
+		return "DEFAULT", true
+	}
+	if len(cell) < len("=expr()") || !strings.EqualFold(cell[:len("=expr(")], "=expr(") || !strings.HasSuffix(cell, ")") {
+		return "", false
+	}
+	inner := strings.TrimSpace(cell[len("=expr(") : len(cell)-1])
+	if inner == "" || !balanced(inner) {
+		return "", false
+	}
+	return inner, true
+}
+
+// balanced reports whether the parentheses in s outside single-quoted
+// strings pair up, so "=expr(now()" isn't taken for an expression.
+func balanced(s string) bool {
+	depth, quoted := 0, false
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth--; depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0 && !quoted
+}
+
+// Is reports whether cell is an expression cell.
+func Is(cell string) bool {
+	_, ok := Parse(cell)
+	return ok
+}
@@ -0,0 +1,33 @@
+package cellexpr
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		cell, want string
+		ok         bool
+	}{
+		{"=DEFAULT", "DEFAULT", true},
+		{"=default", "DEFAULT", true},
+		{`\D`, "DEFAULT", true},
+		{`\d`, "", false},
+		{`\D\D`, "", false},
+		{"=expr(now() - interval '3 days')", "now() - interval '3 days'", true},
+		{"=EXPR( gen_random_uuid() )", "gen_random_uuid()", true},
+		{"=expr('=DEFAULT')", "'=DEFAULT'", true},
+		{"=expr()", "", false},
+		{"=expr(now()", "", false},
+		{"=expr(')')", "')'", true},
+		{"=expr(a) or (b)", "", false},
+		{"=now()", "", false},
+		{"DEFAULT", "", false},
+		{"a=DEFAULT", "", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := Parse(tc.cell)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("Parse(%q) = %q, %v; want %q, %v", tc.cell, got, ok, tc.want, tc.ok)
+		}
+	}
+}
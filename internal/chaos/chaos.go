@@ -0,0 +1,306 @@
+// Package chaos rewrites a share of a fixture's rows with deliberately
+// awkward values — NULLs where a column allows them, strings at their
+// maximum length, dates at the edges of what the engines store, integers
+// at their limits, tricky unicode, empty JSON objects — so an application can be tested
+// against messy data on purpose rather than by accident.
+//
+// Only values the database accepts are produced: NOT NULL columns never
+// get a NULL, varchar(n) never gets more than n characters, and primary
+// key, unique, foreign key and enum columns are never touched, so a
+// fixture with faults still loads cleanly. Every change is returned as a
+// Fault so the caller can report exactly what was planted where.
+//
+// Like subset and timeshift this is pure file logic.
+package chaos
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Kinds of fault.
+const (
+	KindNull      = "null"
+	KindMaxLength = "max-length"
+	KindUnicode   = "unicode"
+	KindDate      = "extreme-date"
+	KindNumber    = "boundary-number"
+	KindEmptyJSON = "empty-json"
+)
+
+// Fault is one planted value.
+type Fault struct {
+	Table  string `json:"table"`
+	Row    int    `json:"row"` // 1-based data row, header excluded
+	Column string `json:"column"`
+	Kind   string `json:"kind"`
+	Was    string `json:"was"`
+	Value  string `json:"value"`
+}
+
+// ParseRate parses a share of rows such as "5%", "5" or "0.5%" into a
+// fraction in (0, 1].
+func ParseRate(s string) (float64, error) {
+	raw := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	pct, err := strconv.ParseFloat(raw, 64)
+	if err != nil || pct <= 0 || pct > 100 {
+		return 0, fmt.Errorf("invalid rate %q (use a percentage between 0 and 100, e.g. 5%%)", s)
+	}
+	return pct / 100, nil
+}
+
+// unicodeSamples are strings that commonly break rendering, truncation,
+// sorting or escaping: combining marks, a ZWJ emoji sequence, RTL text, a
+// right-to-left override, zero-width and non-breaking spaces, an astral
+// plane character, and quoting/markup characters.
+var unicodeSamples = []string{
+	"Zalgo: Z̤͔ͧ̑̓ä͖̭̈̇lͮ̒ͫǧ̗͚̚o̙̔ͮ̇͐̇",
+	"👩‍👩‍👧‍👦",
+	"مرحبا بالعالم",
+	"\u202Etxt.exe",
+	"zero\u200Bwidth\u00A0space",
+	"𝕳𝖊𝖑𝖑𝖔",
+	`O'Brien "quoted" <b>&amp;</b> \ ; --`,
+	"Ǆǅǆ ß ẞ İ ı",
+	"   leading and trailing   ",
+}
+
+var lengthRe = regexp.MustCompile(`\((\d+)\)`)
+
+// column is what Corrupt needs to know about one CSV column.
+type column struct {
+	name     string
+	index    int
+	nullable bool
+	typ      string
+}
+
+// eligibleColumns returns the columns of header that may receive a fault.
+func eligibleColumns(header []string, t utils.SchemaTable) []column {
+	byName := make(map[string]utils.SchemaColumn, len(t.Columns))
+	for _, c := range t.Columns {
+		byName[c.Name] = c
+	}
+	var out []column
+	for i, name := range header {
+		c, ok := byName[name]
+		if !ok || isTrue(c.IsPrimary) || isTrue(c.IsUnique) || c.ForeignKey != nil || hasEnum(c) {
+			continue
+		}
+		col := column{name: name, index: i, nullable: isTrue(c.Nullable), typ: strings.ToLower(strings.TrimSpace(c.Type))}
+		if len(faultsFor(col, "")) == 0 {
+			continue
+		}
+		out = append(out, col)
+	}
+	return out
+}
+
+func isTrue(b *bool) bool { return b != nil && *b }
+
+func hasEnum(c utils.SchemaColumn) bool {
+	raw := strings.TrimSpace(string(c.Enum))
+	return raw != "" && raw != "null"
+}
+
+// candidate is one value a column could take, with its kind.
+type candidate struct {
+	kind  string
+	value string
+}
+
+// faultsFor lists the faults that fit col. current is the cell being
+// replaced; candidates equal to it are dropped so every fault changes
+// something.
+func faultsFor(col column, current string) []candidate {
+	var out []candidate
+	if col.nullable {
+		out = append(out, candidate{KindNull, "NULL"})
+	}
+	t := col.typ
+	switch {
+	case isStringType(t):
+		max := 0
+		if m := lengthRe.FindStringSubmatch(t); m != nil {
+			max, _ = strconv.Atoi(m[1])
+		}
+		if max > 0 && max <= 65535 {
+			out = append(out, candidate{KindMaxLength, maxLengthString(max)})
+		}
+		for _, s := range unicodeSamples {
+			if max == 0 || utf8.RuneCountInString(s) <= max {
+				out = append(out, candidate{KindUnicode, s})
+			}
+		}
+	case t == "date":
+		for _, v := range []string{"1000-01-01", "9999-12-31", "2024-02-29"} {
+			out = append(out, candidate{KindDate, v})
+		}
+	case strings.HasPrefix(t, "datetime"):
+		for _, v := range []string{"1000-01-01 00:00:00", "9999-12-31 23:59:59", "2024-02-29 23:59:59"} {
+			out = append(out, candidate{KindDate, v})
+		}
+	case strings.HasPrefix(t, "timestamp"):
+		// MySQL's TIMESTAMP range, which PostgreSQL also accepts.
+		for _, v := range []string{"1970-01-01 00:00:01", "2038-01-19 03:14:07", "2024-02-29 23:59:59"} {
+			out = append(out, candidate{KindDate, v})
+		}
+	case t == "json" || t == "jsonb":
+		out = append(out, candidate{KindEmptyJSON, "{}"})
+	default:
+		for _, v := range integerBounds(t) {
+			out = append(out, candidate{KindNumber, v})
+		}
+	}
+	kept := out[:0]
+	for _, c := range out {
+		if c.value != current {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// EdgeValues returns the non-NULL values Corrupt could plant in a column
+// of type t ("varchar(40)", "bigint", "date", "jsonb", …), for generators
+// that make up edge cases themselves. It is empty for types chaos has no
+// values for.
+func EdgeValues(t string) []string {
+	var out []string
+	for _, c := range faultsFor(column{typ: strings.ToLower(strings.TrimSpace(t))}, "") {
+		out = append(out, c.value)
+	}
+	return out
+}
+
+func isStringType(t string) bool {
+	switch {
+	case t == "text", t == "tinytext", t == "mediumtext", t == "longtext", t == "citext", t == "string":
+		return true
+	case strings.HasPrefix(t, "varchar"), strings.HasPrefix(t, "character varying"), strings.HasPrefix(t, "nvarchar"):
+		return true
+	}
+	return false
+}
+
+// maxLengthString is n characters, mixing ASCII with a multi-byte
+// character so byte-length and character-length checks disagree.
+func maxLengthString(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i%10 == 9 {
+			b.WriteRune('é')
+		} else {
+			b.WriteByte('W')
+		}
+	}
+	return b.String()
+}
+
+// integerBounds returns the limits of an integer type, or nil for any
+// other type. Unsigned MySQL types only get 0 and their maximum.
+func integerBounds(t string) []string {
+	unsigned := strings.Contains(t, "unsigned")
+	base := t
+	if i := strings.IndexAny(base, "( "); i >= 0 {
+		base = base[:i]
+	}
+	var min, max, umax string
+	switch base {
+	case "tinyint":
+		if strings.HasPrefix(t, "tinyint(1)") {
+			return nil // MySQL's boolean
+		}
+		min, max, umax = "-128", "127", "255"
+	case "smallint", "int2":
+		min, max, umax = "-32768", "32767", "65535"
+	case "mediumint":
+		min, max, umax = "-8388608", "8388607", "16777215"
+	case "int", "integer", "int4":
+		min, max, umax = "-2147483648", "2147483647", "4294967295"
+	case "bigint", "int8":
+		min, max, umax = "-9223372036854775808", "9223372036854775807", "18446744073709551615"
+	default:
+		return nil
+	}
+	if unsigned {
+		return []string{"0", umax}
+	}
+	return []string{min, "0", max}
+}
+
+// Corrupt plants faults in records (row 0 is the header) for table t:
+// each data row is picked with probability rate, and a picked row gets
+// one fault in one of its eligible columns. records is not modified.
+func Corrupt(records [][]string, t utils.SchemaTable, rate float64, rng *rand.Rand) ([][]string, []Fault) {
+	if len(records) < 2 {
+		return records, nil
+	}
+	cols := eligibleColumns(records[0], t)
+	if len(cols) == 0 {
+		return records, nil
+	}
+	out := make([][]string, len(records))
+	out[0] = records[0]
+	var faults []Fault
+	for r := 1; r < len(records); r++ {
+		out[r] = records[r]
+		if rng.Float64() >= rate {
+			continue
+		}
+		col := cols[rng.Intn(len(cols))]
+		if col.index >= len(records[r]) || strings.HasPrefix(records[r][col.index], "@env:") || cellexpr.Is(records[r][col.index]) {
+			continue
+		}
+		cands := faultsFor(col, records[r][col.index])
+		if len(cands) == 0 {
+			continue
+		}
+		pick := cands[rng.Intn(len(cands))]
+		row := append([]string(nil), records[r]...)
+		row[col.index] = pick.value
+		out[r] = row
+		faults = append(faults, Fault{
+			Table: t.Name, Row: r, Column: col.name, Kind: pick.kind,
+			Was: records[r][col.index], Value: pick.value,
+		})
+	}
+	return out, faults
+}
+
+// Apply writes a copy of every table CSV in srcDir that schema describes
+// into dstDir with faults planted at rate, seeded by seed so a run can be
+// reproduced. It returns the faults sorted by table and row. Files other
+// than table CSVs are not copied.
+func Apply(srcDir, dstDir string, schema utils.SchemaJSON, rate float64, seed int64) ([]Fault, error) {
+	tables := append([]utils.SchemaTable(nil), schema.Tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	rng := rand.New(rand.NewSource(seed))
+	var faults []Fault
+	for _, t := range tables {
+		src := filepath.Join(srcDir, t.Name+".csv")
+		records, err := utils.ReadCSVRecords(src)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out, f := Corrupt(records, t, rate, rng)
+		if err := utils.WriteCSVRecords(filepath.Join(dstDir, t.Name+".csv"), out); err != nil {
+			return nil, fmt.Errorf("writing %s.csv: %w", t.Name, err)
+		}
+		faults = append(faults, f...)
+	}
+	return faults, nil
+}
@@ -0,0 +1,157 @@
+package chaos
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"unicode/utf8"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+var usersTable = utils.SchemaTable{
+	Name: "users",
+	Columns: []utils.SchemaColumn{
+		{Name: "id", Type: "integer", IsPrimary: boolPtr(true)},
+		{Name: "email", Type: "varchar(255)", IsUnique: boolPtr(true)},
+		{Name: "name", Type: "varchar(8)"},
+		{Name: "bio", Type: "text", Nullable: boolPtr(true)},
+		{Name: "born", Type: "date"},
+		{Name: "karma", Type: "smallint"},
+		{Name: "org_id", Type: "integer", ForeignKey: &utils.SchemaForeignKey{Table: "orgs", Column: "id"}},
+		{Name: "plan", Type: "plan_t", Enum: json.RawMessage(`"plan_t"`)},
+		{Name: "active", Type: "boolean"},
+	},
+}
+
+func usersRecords(n int) [][]string {
+	records := [][]string{{"id", "email", "name", "bio", "born", "karma", "org_id", "plan", "active"}}
+	for i := 1; i <= n; i++ {
+		id := strconv.Itoa(i)
+		records = append(records, []string{id, "u" + id + "@x.io", "ann", "hi", "1990-05-01", "3", "1", "free", "true"})
+	}
+	return records
+}
+
+func TestParseRate(t *testing.T) {
+	for in, want := range map[string]float64{"5%": 0.05, "5": 0.05, " 0.5% ": 0.005, "100%": 1} {
+		got, err := ParseRate(in)
+		if err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0", "-1%", "101%", "lots"} {
+		if _, err := ParseRate(in); err == nil {
+			t.Errorf("ParseRate(%q): expected an error", in)
+		}
+	}
+}
+
+func TestCorrupt_onlyEligibleColumnsAndValidValues(t *testing.T) {
+	records := usersRecords(200)
+	out, faults := Corrupt(records, usersTable, 1, rand.New(rand.NewSource(1)))
+	if len(faults) != 200 {
+		t.Fatalf("got %d faults at rate 1, want one per row", len(faults))
+	}
+	if records[1][2] != "ann" {
+		t.Fatal("Corrupt modified its input")
+	}
+	allowed := map[string]bool{"name": true, "bio": true, "born": true, "karma": true}
+	kinds := map[string]bool{}
+	for _, f := range faults {
+		if !allowed[f.Column] {
+			t.Fatalf("fault in ineligible column %s: %+v", f.Column, f)
+		}
+		if got := out[f.Row][indexOf(records[0], f.Column)]; got != f.Value || f.Value == f.Was {
+			t.Fatalf("fault %+v not applied (cell %q)", f, got)
+		}
+		if f.Kind == KindNull && f.Column != "bio" {
+			t.Fatalf("NULL planted in NOT NULL column: %+v", f)
+		}
+		if f.Column == "name" && utf8.RuneCountInString(f.Value) > 8 {
+			t.Fatalf("value longer than varchar(8): %+v", f)
+		}
+		kinds[f.Kind] = true
+	}
+	for _, k := range []string{KindNull, KindMaxLength, KindUnicode, KindDate, KindNumber} {
+		if !kinds[k] {
+			t.Errorf("no %s fault in 200 rows", k)
+		}
+	}
+}
+
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestIntegerBounds(t *testing.T) {
+	cases := map[string][]string{
+		"smallint":         {"-32768", "0", "32767"},
+		"int(11) unsigned": {"0", "4294967295"},
+		"tinyint(1)":       nil,
+		"numeric(10,2)":    nil,
+	}
+	for typ, want := range cases {
+		if got := integerBounds(typ); !reflect.DeepEqual(got, want) {
+			t.Errorf("integerBounds(%q) = %v, want %v", typ, got, want)
+		}
+	}
+}
+
+func TestApply_isReproducible(t *testing.T) {
+	src := t.TempDir()
+	if err := utils.WriteCSVRecords(filepath.Join(src, "users.csv"), usersRecords(50)); err != nil {
+		t.Fatal(err)
+	}
+	schema := utils.SchemaJSON{Tables: []utils.SchemaTable{usersTable, {Name: "orgs"}}}
+
+	run := func() ([]Fault, string) {
+		dst := t.TempDir()
+		faults, err := Apply(src, dst, schema, 0.2, 42)
+		if err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		raw, err := os.ReadFile(filepath.Join(dst, "users.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return faults, string(raw)
+	}
+	f1, csv1 := run()
+	f2, csv2 := run()
+	if len(f1) == 0 || !reflect.DeepEqual(f1, f2) || csv1 != csv2 {
+		t.Fatalf("same seed gave different results: %d vs %d faults", len(f1), len(f2))
+	}
+}
+
+func TestEdgeValues(t *testing.T) {
+	if got := EdgeValues("jsonb"); !reflect.DeepEqual(got, []string{"{}"}) {
+		t.Errorf("EdgeValues(jsonb) = %v, want [{}]", got)
+	}
+	if got := EdgeValues("SMALLINT"); !reflect.DeepEqual(got, []string{"-32768", "0", "32767"}) {
+		t.Errorf("EdgeValues(SMALLINT) = %v", got)
+	}
+	got := EdgeValues("varchar(12)")
+	if len(got) == 0 || got[0] != maxLengthString(12) {
+		t.Errorf("EdgeValues(varchar(12)) = %v, want the 12-character string first", got)
+	}
+	for _, v := range got {
+		if v == "NULL" || utf8.RuneCountInString(v) > 12 {
+			t.Errorf("EdgeValues(varchar(12)) has %q", v)
+		}
+	}
+	if got := EdgeValues("bytea"); len(got) != 0 {
+		t.Errorf("EdgeValues(bytea) = %v, want none", got)
+	}
+}
@@ -0,0 +1,328 @@
+// Package csvshard writes a table's CSV as ordered shards —
+// orders.csv.001, orders.csv.002, … — for tables too big to keep in one
+// file, and reads them back as the one CSV they make up.
+//
+// Every shard starts with the table's header, so each is a valid CSV on
+// its own. Read in order with the repeated headers dropped, the shards
+// are the table's CSV byte for byte: records are read back raw, never
+// re-encoded, so quoting (and with it NULL versus empty) survives.
+package csvshard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Name is the file name of shard n (counting from 1) of table.
+func Name(table string, n int) string {
+	return fmt.Sprintf("%s.csv.%03d", table, n)
+}
+
+var shardName = regexp.MustCompile(`^(.+)\.csv\.(\d{3,})$`)
+
+// Parse splits a shard file name into its table and shard number. ok is
+// false for anything else, plain CSVs included.
+func Parse(name string) (table string, n int, ok bool) {
+	m := shardName.FindStringSubmatch(name)
+	if m == nil {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil || n < 1 {
+		return "", 0, false
+	}
+	return m[1], n, true
+}
+
+// Tables returns the sharded tables in dir, each with its shard file
+// names in load order.
+func Tables(dir string) (map[string][]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	numbered := map[string]map[int]string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		table, n, ok := Parse(e.Name())
+		if !ok {
+			continue
+		}
+		if numbered[table] == nil {
+			numbered[table] = map[int]string{}
+		}
+		numbered[table][n] = e.Name()
+	}
+	tables := make(map[string][]string, len(numbered))
+	for table, byN := range numbered {
+		names := make([]string, 0, len(byN))
+		for n := 1; n <= len(byN); n++ {
+			name, ok := byN[n]
+			if !ok {
+				// A gap would silently drop the missing shard's rows.
+				return nil, fmt.Errorf("%s is missing shard %s", table, Name(table, n))
+			}
+			names = append(names, name)
+		}
+		tables[table] = names
+	}
+	return tables, nil
+}
+
+// Files returns the paths of the shards standing in for the CSV at path
+// (…/orders.csv), in load order, or nil when the table isn't sharded.
+func Files(path string) ([]string, error) {
+	dir := filepath.Dir(path)
+	tables, err := Tables(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := tables[strings.TrimSuffix(filepath.Base(path), ".csv")]
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name)
+	}
+	return paths, nil
+}
+
+// Open opens the CSV at path, or when it doesn't exist the shards in its
+// place, read as one CSV with a single header. With neither, the error is
+// Open's own, so os.IsNotExist still reports it.
+func Open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err == nil {
+		return f, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	shards, ferr := Files(path)
+	if ferr != nil {
+		return nil, ferr
+	}
+	if len(shards) == 0 {
+		return nil, err
+	}
+	return &reader{paths: shards}, nil
+}
+
+// Exists reports whether path or shards in its place exist.
+func Exists(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	shards, err := Files(path)
+	return err == nil && len(shards) > 0
+}
+
+// Join writes the table read through Open(path) to dst as one CSV.
+func Join(path, dst string) error {
+	src, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// reader reads shards back to back, skipping the header of every shard
+// after the first.
+type reader struct {
+	paths []string
+	next  int
+	f     *os.File
+	r     io.Reader
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for {
+		if r.r == nil {
+			if r.next == len(r.paths) {
+				return 0, io.EOF
+			}
+			f, err := os.Open(r.paths[r.next])
+			if err != nil {
+				return 0, err
+			}
+			r.f, r.r = f, f
+			if r.next > 0 {
+				br := bufio.NewReader(f)
+				if _, err := readRecord(br); err != nil && err != io.EOF {
+					return 0, err
+				}
+				r.r = br
+			}
+			r.next++
+		}
+		n, err := r.r.Read(p)
+		if err == io.EOF {
+			r.f.Close()
+			r.f, r.r = nil, nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *reader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
+
+// Writer writes a table's CSV a record at a time, the header first, and
+// cuts it into shards as it goes: once more than rows data rows have been
+// written, <table>.csv is renamed to the first shard and every rows rows
+// after that start the next. A table that never outgrows rows stays one
+// <table>.csv, and nothing is written twice.
+type Writer struct {
+	dir, table string
+	rows       int
+	header     []string
+	started    bool
+	f          *os.File
+	w          *csv.Writer
+	n          int // data rows in the current file
+	shards     []string
+}
+
+// Create starts <dir>/<table>.csv. rows < 1 never shards.
+func Create(dir, table string, rows int) (*Writer, error) {
+	f, err := os.Create(filepath.Join(dir, table+".csv"))
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{dir: dir, table: table, rows: rows, f: f, w: csv.NewWriter(f)}, nil
+}
+
+// Write writes one record; the first is the header every shard repeats.
+func (w *Writer) Write(record []string) error {
+	if !w.started {
+		w.started = true
+		w.header = append([]string(nil), record...)
+		return w.w.Write(record)
+	}
+	if w.rows > 0 && w.n == w.rows {
+		if err := w.nextShard(); err != nil {
+			return err
+		}
+	}
+	w.n++
+	return w.w.Write(record)
+}
+
+// nextShard closes the current file and starts the next shard with the
+// header, renaming <table>.csv to the first shard the first time.
+func (w *Writer) nextShard() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	if len(w.shards) == 0 {
+		first := Name(w.table, 1)
+		if err := os.Rename(filepath.Join(w.dir, w.table+".csv"), filepath.Join(w.dir, first)); err != nil {
+			return err
+		}
+		w.shards = []string{first}
+	}
+	name := Name(w.table, len(w.shards)+1)
+	f, err := os.Create(filepath.Join(w.dir, name))
+	if err != nil {
+		return err
+	}
+	w.f, w.w, w.n = f, csv.NewWriter(f), 0
+	w.shards = append(w.shards, name)
+	return w.w.Write(w.header)
+}
+
+func (w *Writer) closeFile() error {
+	w.w.Flush()
+	err := w.w.Error()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	w.f = nil
+	return err
+}
+
+// Close flushes and closes the file being written. It is safe to call
+// more than once.
+func (w *Writer) Close() error {
+	if w.f == nil {
+		return nil
+	}
+	return w.closeFile()
+}
+
+// Shards returns the shard file names written, in order, or nil when the
+// table stayed one CSV.
+func (w *Writer) Shards() []string { return w.shards }
+
+// Remove removes the CSV at path and any shards in its place.
+func Remove(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	shards, ferr := Files(path)
+	if ferr != nil {
+		return ferr
+	}
+	for _, shard := range shards {
+		if err := os.Remove(shard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRecord reads one CSV record, raw and with its line ending. A
+// newline only ends the record when the quotes before it are balanced,
+// so quoted cells spanning lines stay whole.
+func readRecord(br *bufio.Reader) ([]byte, error) {
+	var rec []byte
+	quotes := 0
+	for {
+		line, err := br.ReadBytes('\n')
+		rec = append(rec, line...)
+		quotes += bytes.Count(line, []byte{'"'})
+		if err == io.EOF {
+			if len(rec) == 0 {
+				return nil, io.EOF
+			}
+			if rec[len(rec)-1] != '\n' {
+				// The last record of a file without a trailing newline:
+				// terminate it so a record written after it stays apart.
+				rec = append(rec, '\n')
+			}
+			return rec, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if quotes%2 == 0 {
+			return rec, nil
+		}
+	}
+}
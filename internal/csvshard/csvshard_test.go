@@ -0,0 +1,127 @@
+package csvshard
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// orders has five data rows, one of them a quoted cell spanning lines and
+// one an empty cell.
+var orders = [][]string{
+	{"id", "note"},
+	{"1", "a"}, {"2", "two\nlines"}, {"3", ""}, {"4", "d"}, {"5", "e"},
+}
+
+const ordersCSV = "id,note\n1,a\n2,\"two\nlines\"\n3,\n4,d\n5,e\n"
+
+// writeOrders writes orders into a temp dir through a Writer cutting
+// shards of rows rows and returns the path of orders.csv.
+func writeOrders(t *testing.T, rows int) (string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := Create(dir, "orders", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range orders {
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "orders.csv"), w.Shards()
+}
+
+func TestWriter(t *testing.T) {
+	path, names := writeOrders(t, 2)
+	if strings.Join(names, ",") != "orders.csv.001,orders.csv.002,orders.csv.003" {
+		t.Fatalf("names = %v", names)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("orders.csv still there: %v", err)
+	}
+	second, _ := os.ReadFile(filepath.Join(filepath.Dir(path), "orders.csv.002"))
+	if string(second) != "id,note\n3,\n4,d\n" {
+		t.Fatalf("shard 2 = %q", second)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	joined, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(joined) != ordersCSV {
+		t.Fatalf("joined = %q, want %q", joined, ordersCSV)
+	}
+}
+
+func TestWriter_smallTableStaysWhole(t *testing.T) {
+	path, names := writeOrders(t, 5)
+	if names != nil {
+		t.Fatalf("Shards = %v, want nothing split", names)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != ordersCSV {
+		t.Fatalf("orders.csv = %q, %v", data, err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	path, _ := writeOrders(t, 2)
+	if err := Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if Exists(path) {
+		t.Fatal("shards left behind")
+	}
+}
+
+func TestTables_gap(t *testing.T) {
+	path, _ := writeOrders(t, 2)
+	dir := filepath.Dir(path)
+	tables, err := Tables(dir)
+	if err != nil || len(tables["orders"]) != 3 {
+		t.Fatalf("Tables = %v, %v", tables, err)
+	}
+	if err := os.Remove(filepath.Join(dir, "orders.csv.002")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "missing shard orders.csv.002") {
+		t.Fatalf("Open err = %v, want a missing shard", err)
+	}
+}
+
+func TestOpen_notExist(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "users.csv")); !os.IsNotExist(err) {
+		t.Fatalf("err = %v, want not-exist", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for name, want := range map[string]string{
+		"orders.csv.001":     "orders 1",
+		"line_items.csv.012": "line_items 12",
+		"orders.csv":         "",
+		"orders.csv.000":     "",
+		"orders.csv.tmp":     "",
+	} {
+		table, n, ok := Parse(name)
+		got := ""
+		if ok {
+			got = fmt.Sprintf("%s %d", table, n)
+		}
+		if got != want {
+			t.Errorf("Parse(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package devdb runs disposable database containers for `seedmancer up`
+// and `seedmancer down`. It drives the docker CLI rather than the Docker
+// API so it works with whatever docker (or a docker-compatible CLI such
+// as podman's) is on PATH, with the user's own contexts and credentials.
+//
+// Every container carries labels naming seedmancer and the project it was
+// started for, so `down` only ever removes containers seedmancer created.
+package devdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+
+	db "github.com/KazanKK/seedmancer/database"
+)
+
+// Labels set on every container.
+const (
+	LabelManaged = "dev.seedmancer"
+	LabelProject = "dev.seedmancer.project"
+	LabelEngine  = "dev.seedmancer.engine"
+)
+
+// Database, user and password of every container. They are throwaway
+// local databases bound to 127.0.0.1, so the credentials are fixed.
+const (
+	dbName   = "seedmancer"
+	password = "seedmancer"
+)
+
+// Engine is how to run one database engine in a container.
+type Engine struct {
+	Type  db.DatabaseType
+	Image string
+	// Port is the server's port inside the container.
+	Port string
+	Env  []string
+	Args []string
+	// URL builds the connection URL for the server published on hostPort.
+	URL func(hostPort string) string
+}
+
+// Engines are the engines `up` can start, by type.
+var Engines = map[db.DatabaseType]Engine{
+	db.Postgres: {
+		Type:  db.Postgres,
+		Image: "postgres:16",
+		Port:  "5432",
+		Env:   []string{"POSTGRES_PASSWORD=" + password, "POSTGRES_DB=" + dbName},
+		URL: func(hostPort string) string {
+			return fmt.Sprintf("postgres://postgres:%s@%s/%s?sslmode=disable", password, hostPort, dbName)
+		},
+	},
+	db.MySQL: {
+		Type:  db.MySQL,
+		Image: "mysql:8.4",
+		Port:  "3306",
+		Env:   []string{"MYSQL_ROOT_PASSWORD=" + password, "MYSQL_DATABASE=" + dbName},
+		URL: func(hostPort string) string {
+			return fmt.Sprintf("mysql://root:%s@%s/%s", password, hostPort, dbName)
+		},
+	},
+	db.MariaDB: {
+		Type:  db.MariaDB,
+		Image: "mariadb:11",
+		Port:  "3306",
+		Env:   []string{"MARIADB_ROOT_PASSWORD=" + password, "MARIADB_DATABASE=" + dbName},
+		URL: func(hostPort string) string {
+			return fmt.Sprintf("mariadb://root:%s@%s/%s", password, hostPort, dbName)
+		},
+	},
+	db.Cockroach: {
+		Type:  db.Cockroach,
+		Image: "cockroachdb/cockroach:latest-v24.1",
+		Port:  "26257",
+		Args:  []string{"start-single-node", "--insecure"},
+		URL: func(hostPort string) string {
+			return fmt.Sprintf("cockroach://root@%s/defaultdb?sslmode=disable", hostPort)
+		},
+	},
+}
+
+// LookupEngine returns the engine called name.
+func LookupEngine(name string) (Engine, error) {
+	e, ok := Engines[db.DatabaseType(strings.ToLower(strings.TrimSpace(name)))]
+	if !ok {
+		names := make([]string, 0, len(Engines))
+		for t := range Engines {
+			names = append(names, string(t))
+		}
+		sort.Strings(names)
+		return Engine{}, fmt.Errorf("unknown engine %q (supported: %s)", name, strings.Join(names, ", "))
+	}
+	return e, nil
+}
+
+// Spec describes a container to start.
+type Spec struct {
+	Name    string
+	Engine  Engine
+	Project string
+	// Image overrides Engine.Image.
+	Image string
+	// HostPort publishes the server on this port; empty picks a free one.
+	HostPort string
+}
+
+// Container is a running (or stopped) seedmancer container.
+type Container struct {
+	Name   string `json:"name"`
+	Engine string `json:"engine"`
+	Status string `json:"status"`
+	// URL is only known for containers this process started.
+	URL string `json:"url,omitempty"`
+}
+
+// docker runs the docker CLI and returns its trimmed stdout. Tests
+// replace it.
+var docker = func(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("docker %s: %s", args[0], msg)
+		}
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			return "", fmt.Errorf("docker not found on PATH — install Docker to use seedmancer up")
+		}
+		return "", fmt.Errorf("docker %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Start runs spec's container in the background, published on 127.0.0.1
+// only, and returns it with its connection URL. The server may still be
+// starting up; wait for it with db.WaitForReady.
+func Start(ctx context.Context, spec Spec) (Container, error) {
+	image := spec.Image
+	if image == "" {
+		image = spec.Engine.Image
+	}
+	args := []string{"run", "--detach",
+		"--name", spec.Name,
+		"--label", LabelManaged + "=true",
+		"--label", LabelProject + "=" + spec.Project,
+		"--label", LabelEngine + "=" + string(spec.Engine.Type),
+		"--publish", "127.0.0.1:" + spec.HostPort + ":" + spec.Engine.Port,
+	}
+	for _, e := range spec.Engine.Env {
+		args = append(args, "--env", e)
+	}
+	args = append(args, image)
+	args = append(args, spec.Engine.Args...)
+	if _, err := docker(ctx, args...); err != nil {
+		return Container{}, err
+	}
+
+	out, err := docker(ctx, "port", spec.Name, spec.Engine.Port+"/tcp")
+	if err != nil {
+		_, _ = docker(ctx, "rm", "--force", "--volumes", spec.Name)
+		return Container{}, err
+	}
+	hostPort, err := parsePublishedPort(out)
+	if err != nil {
+		_, _ = docker(ctx, "rm", "--force", "--volumes", spec.Name)
+		return Container{}, err
+	}
+	return Container{
+		Name:   spec.Name,
+		Engine: string(spec.Engine.Type),
+		Status: "running",
+		URL:    spec.Engine.URL(hostPort),
+	}, nil
+}
+
+// parsePublishedPort picks the IPv4 binding out of `docker port` output,
+// which lists one "host:port" per line.
+func parsePublishedPort(out string) (string, error) {
+	for _, line := range strings.Split(out, "\n") {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(line))
+		if err == nil && net.ParseIP(host).To4() != nil {
+			return net.JoinHostPort(host, port), nil
+		}
+	}
+	return "", fmt.Errorf("no published port in docker output %q", out)
+}
+
+// List returns seedmancer's containers, running or not: those of project,
+// or every one when project is empty.
+func List(ctx context.Context, project string) ([]Container, error) {
+	filter := LabelManaged
+	if project != "" {
+		filter = LabelProject + "=" + project
+	}
+	out, err := docker(ctx, "ps", "--all",
+		"--filter", "label="+filter,
+		"--format", `{{.Names}}\t{{.Label "`+LabelEngine+`"}}\t{{.Status}}`)
+	if err != nil {
+		return nil, err
+	}
+	var containers []Container
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		containers = append(containers, Container{Name: fields[0], Engine: fields[1], Status: fields[2]})
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Name < containers[j].Name })
+	return containers, nil
+}
+
+// Remove stops and deletes the named containers with their volumes.
+func Remove(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	_, err := docker(ctx, append([]string{"rm", "--force", "--volumes"}, names...)...)
+	return err
+}
+
+// ContainerName is the default container name for project and engine:
+// seedmancer-<project dir>-<engine>, lowercased, with anything docker
+// rejects in a name replaced by "-".
+func ContainerName(projectDir string, engine db.DatabaseType) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(projectDir) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	base := strings.Trim(b.String(), "-.")
+	if base == "" {
+		base = "project"
+	}
+	return "seedmancer-" + base + "-" + string(engine)
+}
@@ -0,0 +1,85 @@
+// Package diskspace checks, before a long operation starts, that the disk
+// it writes to has room for what it's about to write, so a large export
+// or pull fails up front instead of an hour in with a half-written file.
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var disabled bool
+
+// SetDisabled turns Check off for the process (--skip-disk-check), for
+// when an estimate is known to overshoot.
+func SetDisabled(d bool) { disabled = d }
+
+// ShortError is returned by Check when the disk lacks the room.
+type ShortError struct {
+	// Dir is the directory the operation writes to.
+	Dir string
+	// What names the operation, e.g. "downloading billing/pro".
+	What string
+	// Need is the estimated size of what it writes; Free what the disk
+	// has available to the current user.
+	Need, Free uint64
+}
+
+func (e *ShortError) Error() string {
+	return fmt.Sprintf("not enough disk space for %s: it needs about %s in %s, which has %s free "+
+		"(free some space, or pass --skip-disk-check if the estimate is off)",
+		e.What, formatBytes(e.Need), e.Dir, formatBytes(e.Free))
+}
+
+// Check returns a *ShortError when the disk holding dir has less than
+// need bytes available. dir need not exist yet: its nearest existing
+// ancestor is measured. A disk whose free space can't be read passes.
+func Check(dir string, need int64, what string) error {
+	if disabled || need <= 0 {
+		return nil
+	}
+	free, err := Free(dir)
+	if err != nil {
+		return nil
+	}
+	if free < uint64(need) {
+		return &ShortError{Dir: dir, What: what, Need: uint64(need), Free: free}
+	}
+	return nil
+}
+
+// Free returns the bytes available to the current user on the disk
+// holding dir, or its nearest existing ancestor.
+func Free(dir string) (uint64, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return free(dir)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return 0, fmt.Errorf("no existing directory above %s", dir)
+		}
+		dir = parent
+	}
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for n2 := n / unit; n2 >= unit; n2 /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,31 @@
+package diskspace
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "not", "created", "yet")
+	free, err := Free(dir)
+	if err != nil || free == 0 {
+		t.Fatalf("Free(%s) = %d, %v", dir, free, err)
+	}
+	if err := Check(dir, 1, "a tiny write"); err != nil {
+		t.Errorf("Check(1 byte) = %v", err)
+	}
+
+	err = Check(dir, math.MaxInt64, "a huge export")
+	var short *ShortError
+	if !errors.As(err, &short) || short.Need != math.MaxInt64 || short.Free == 0 {
+		t.Fatalf("Check(huge) = %v, want a ShortError", err)
+	}
+
+	SetDisabled(true)
+	defer SetDisabled(false)
+	if err := Check(dir, math.MaxInt64, "a huge export"); err != nil {
+		t.Errorf("Check with --skip-disk-check = %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package diskspace
+
+import "errors"
+
+func free(string) (uint64, error) {
+	return 0, errors.New("free disk space is not available on this platform")
+}
@@ -0,0 +1,105 @@
+// Package embedgen renders the Go file `seedmancer embed` writes next to a
+// copy of a revision's files. The file go:embeds those files and exposes a
+// Seed(*sql.DB) helper, so a service's test binary carries its own fixture
+// and seeds without seedmancer.yaml, local storage or network access.
+package embedgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"text/template"
+)
+
+// DataDir is the directory, next to FileName, that holds the embedded files.
+const DataDir = "seedmancer_data"
+
+// FileName is the generated Go file.
+const FileName = "seedmancer_embed.go"
+
+// Params describe the revision being embedded.
+type Params struct {
+	Package           string
+	Scenario          string
+	Revision          string
+	SchemaFingerprint string
+	DatabaseType      string
+	Tables            []string
+	SeedmancerVersion string
+}
+
+// Render returns the gofmt'd source of FileName for p.
+func Render(p Params) ([]byte, error) {
+	if !token.IsIdentifier(p.Package) || token.IsKeyword(p.Package) {
+		return nil, fmt.Errorf("invalid Go package name %q", p.Package)
+	}
+	if p.DatabaseType != "mysql" {
+		p.DatabaseType = "postgres"
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct {
+		Params
+		DataDir string
+	}{p, DataDir}); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var tmpl = template.Must(template.New(FileName).Parse(`// Code generated by seedmancer embed{{with .SeedmancerVersion}} {{.}}{{end}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"database/sql"
+	"embed"
+	"os"
+	"path/filepath"
+
+	db "github.com/KazanKK/seedmancer/database"
+)
+
+// Scenario, Revision and SchemaFingerprint identify the embedded fixture.
+const (
+	Scenario          = {{printf "%q" .Scenario}}
+	Revision          = {{printf "%q" .Revision}}
+	SchemaFingerprint = {{printf "%q" .SchemaFingerprint}}
+)
+
+// Tables lists the tables the fixture holds data for.
+var Tables = []string{ {{- range $i, $t := .Tables}}{{if $i}}, {{end}}{{printf "%q" $t}}{{end -}} }
+
+//go:embed {{.DataDir}}
+var seedmancerFiles embed.FS
+
+// Seed restores the embedded fixture into conn, a {{if eq .DatabaseType "mysql"}}MySQL{{else}}PostgreSQL{{end}} database:
+// missing tables are created and the fixture's tables are emptied and
+// reloaded. The files are staged in a temp dir that is removed afterwards.
+func Seed(conn *sql.DB) error {
+	dir, err := os.MkdirTemp("", "seedmancer-embed-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	entries, err := seedmancerFiles.ReadDir({{printf "%q" .DataDir}})
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		data, err := seedmancerFiles.ReadFile({{printf "%q" .DataDir}} + "/" + e.Name())
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), data, 0o644); err != nil {
+			return err
+		}
+	}
+{{- if eq .DatabaseType "mysql"}}
+	return (&db.MySQLManager{DB: conn}).RestoreFromCSV(dir)
+{{- else}}
+	return (&db.PostgresManager{DB: conn}).RestoreFromCSV(dir)
+{{- end}}
+}
+`))
@@ -0,0 +1,39 @@
+package embedgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	src, err := Render(Params{
+		Package:           "fixtures",
+		Scenario:          "billing/pro",
+		Revision:          "r003",
+		SchemaFingerprint: "abc123",
+		DatabaseType:      "mysql",
+		Tables:            []string{"users", "orders"},
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, want := range []string{
+		"package fixtures\n",
+		`Revision          = "r003"`,
+		`var Tables = []string{"users", "orders"}`,
+		"//go:embed seedmancer_data\n",
+		"(&db.MySQLManager{DB: conn}).RestoreFromCSV(dir)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source missing %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestRender_rejectsBadPackageName(t *testing.T) {
+	for _, name := range []string{"", "my-fixtures", "func"} {
+		if _, err := Render(Params{Package: name}); err == nil {
+			t.Errorf("Render(%q) should fail", name)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package explain looks up one row of a CSV fixture together with the
+// rows it references and the rows referencing it, so a reviewer can see
+// what an entity in a revision is made of without seeding it anywhere.
+//
+// Only immediate neighbours are followed: the parent rows named by the
+// row's own foreign keys, and the child rows whose foreign keys name it.
+// Tables without a CSV are skipped, and NULLs, @env markers and SQL
+// expression cells reference nothing.
+package explain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+	"github.com/KazanKK/seedmancer/internal/envmarker"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Match selects a row by the values of some of its columns.
+type Match map[string]string
+
+// ParseMatch parses a --row value: "id=42", or "org_id=1,id=42" for a
+// composite key.
+func ParseMatch(s string) (Match, error) {
+	m := Match{}
+	for _, part := range strings.Split(s, ",") {
+		col, value, ok := strings.Cut(part, "=")
+		col = strings.TrimSpace(col)
+		if !ok || col == "" {
+			return nil, fmt.Errorf("invalid row selector %q (want column=value, e.g. id=42)", part)
+		}
+		m[col] = strings.TrimSpace(value)
+	}
+	return m, nil
+}
+
+func (m Match) String() string {
+	parts := make([]string, 0, len(m))
+	for col, value := range m {
+		parts = append(parts, col+"="+value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Related is the rows of one table linked to the explained row through
+// one foreign key.
+type Related struct {
+	Table string `json:"table"`
+	// Via is the foreign key, as "child.column → parent.column".
+	Via    string     `json:"via"`
+	Header []string   `json:"header"`
+	Rows   [][]string `json:"rows"`
+	// Value is the key the rows were matched on. For a parent, no Rows
+	// means the reference is orphaned.
+	Value string `json:"value"`
+}
+
+// Entity is one row and its immediate neighbours.
+type Entity struct {
+	Table    string    `json:"table"`
+	Header   []string  `json:"header"`
+	Row      []string  `json:"row"`
+	Parents  []Related `json:"parents"`
+	Children []Related `json:"children"`
+}
+
+// Explain finds the single row of table that match selects and collects
+// its parents and children. tables maps a table name to its CSV records,
+// header first.
+func Explain(schema utils.SchemaJSON, tables map[string][][]string, table string, match Match) (Entity, error) {
+	records := tables[table]
+	if len(records) == 0 {
+		return Entity{}, fmt.Errorf("no CSV for table %s", table)
+	}
+	header := records[0]
+	for col := range match {
+		if indexOf(header, col) < 0 {
+			return Entity{}, fmt.Errorf("%s has no column %s", table, col)
+		}
+	}
+	var found [][]string
+	for _, rec := range records[1:] {
+		if matches(header, rec, match) {
+			found = append(found, rec)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Entity{}, fmt.Errorf("no row of %s has %s", table, match)
+	case 1:
+	default:
+		return Entity{}, fmt.Errorf("%d rows of %s have %s; add columns to narrow it to one", len(found), table, match)
+	}
+
+	e := Entity{Table: table, Header: header, Row: found[0], Parents: []Related{}, Children: []Related{}}
+	for _, t := range schema.Tables {
+		for _, c := range t.Columns {
+			fk := c.ForeignKey
+			if fk == nil {
+				continue
+			}
+			via := t.Name + "." + c.Name + " → " + fk.Table + "." + fk.Column
+			if t.Name == table {
+				if value, ok := reference(header, e.Row, c.Name); ok {
+					if rel, ok := related(tables, fk.Table, fk.Column, value, via); ok {
+						e.Parents = append(e.Parents, rel)
+					}
+				}
+			}
+			if fk.Table == table {
+				if value, ok := reference(header, e.Row, fk.Column); ok {
+					if rel, ok := related(tables, t.Name, c.Name, value, via); ok && len(rel.Rows) > 0 {
+						e.Children = append(e.Children, rel)
+					}
+				}
+			}
+		}
+	}
+	return e, nil
+}
+
+// related collects the rows of table whose column holds value. ok is
+// false when table has no CSV or no such column.
+func related(tables map[string][][]string, table, column, value, via string) (Related, bool) {
+	records := tables[table]
+	if len(records) == 0 {
+		return Related{}, false
+	}
+	i := indexOf(records[0], column)
+	if i < 0 {
+		return Related{}, false
+	}
+	rel := Related{Table: table, Via: via, Header: records[0], Rows: [][]string{}, Value: value}
+	for _, rec := range records[1:] {
+		if i < len(rec) && rec[i] == value {
+			rel.Rows = append(rel.Rows, rec)
+		}
+	}
+	return rel, true
+}
+
+// reference returns rec's value in column when it can name another row.
+func reference(header, rec []string, column string) (string, bool) {
+	i := indexOf(header, column)
+	if i < 0 || i >= len(rec) {
+		return "", false
+	}
+	cell := rec[i]
+	if cell == "" || cell == "NULL" || cell == "null" || envmarker.IsMarker(cell) || cellexpr.Is(cell) {
+		return "", false
+	}
+	return cell, true
+}
+
+func matches(header, rec []string, match Match) bool {
+	for col, value := range match {
+		i := indexOf(header, col)
+		if i >= len(rec) || rec[i] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
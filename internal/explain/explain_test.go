@@ -0,0 +1,98 @@
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+const schemaJSON = `{"tables":[
+  {"name":"users","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"org_id","type":"integer","foreignKey":{"table":"orgs","column":"id"}},
+    {"name":"team_id","type":"integer","nullable":true,"foreignKey":{"table":"teams","column":"id"}}
+  ]},
+  {"name":"orgs","columns":[{"name":"id","type":"integer","isPrimary":true}]},
+  {"name":"orders","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}}
+  ]}
+]}`
+
+func fixture(t *testing.T) (utils.SchemaJSON, map[string][][]string) {
+	t.Helper()
+	var s utils.SchemaJSON
+	if err := json.Unmarshal([]byte(schemaJSON), &s); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return s, map[string][][]string{
+		"users":  {{"id", "org_id", "team_id"}, {"41", "1", "NULL"}, {"42", "1", "7"}, {"43", "9", "NULL"}},
+		"orgs":   {{"id"}, {"1"}},
+		"orders": {{"id", "user_id"}, {"10", "42"}, {"11", "41"}, {"12", "42"}},
+	}
+}
+
+func TestExplain(t *testing.T) {
+	schema, tables := fixture(t)
+	e, err := Explain(schema, tables, "users", Match{"id": "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(e.Row) != "[42 1 7]" {
+		t.Fatalf("Row = %v", e.Row)
+	}
+	// team_id isn't followed: teams has no CSV.
+	if len(e.Parents) != 1 || e.Parents[0].Via != "users.org_id → orgs.id" || fmt.Sprint(e.Parents[0].Rows) != "[[1]]" {
+		t.Fatalf("Parents = %+v", e.Parents)
+	}
+	if len(e.Children) != 1 || e.Children[0].Table != "orders" || fmt.Sprint(e.Children[0].Rows) != "[[10 42] [12 42]]" {
+		t.Fatalf("Children = %+v", e.Children)
+	}
+}
+
+func TestExplain_missingParent(t *testing.T) {
+	schema, tables := fixture(t)
+	e, err := Explain(schema, tables, "users", Match{"id": "43"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Parents) != 1 || len(e.Parents[0].Rows) != 0 || e.Parents[0].Value != "9" {
+		t.Fatalf("Parents = %+v, want an orphaned org 9", e.Parents)
+	}
+	if len(e.Children) != 0 {
+		t.Fatalf("Children = %+v, want none", e.Children)
+	}
+}
+
+func TestExplain_selectsOneRow(t *testing.T) {
+	schema, tables := fixture(t)
+	for match, want := range map[string]string{
+		"org_id=1": "2 rows of users",
+		"id=99":    "no row of users",
+		"email=x":  "no column email",
+	} {
+		m, err := ParseMatch(match)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Explain(schema, tables, "users", m); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", match, err, want)
+		}
+	}
+	if _, err := Explain(schema, tables, "teams", Match{"id": "7"}); err == nil {
+		t.Error("want an error for a table without a CSV")
+	}
+}
+
+func TestParseMatch(t *testing.T) {
+	m, err := ParseMatch("org_id=1, id=42")
+	if err != nil || m.String() != "id=42,org_id=1" {
+		t.Fatalf("ParseMatch = %v, %v", m, err)
+	}
+	if _, err := ParseMatch("42"); err == nil {
+		t.Fatal("want an error without =")
+	}
+}
@@ -0,0 +1,40 @@
+package lockfile
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteLoad_roundTrip(t *testing.T) {
+	dir := t.TempDir()
+	want := Lock{Scenario: "billing/pro", Revision: "r003", Checksum: "abc123", SchemaFingerprint: "fp"}
+	if err := Write(dir, want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, ok, err := Load(dir)
+	if err != nil || !ok {
+		t.Fatalf("Load: ok=%v err=%v", ok, err)
+	}
+	if *got != want {
+		t.Fatalf("got %+v, want %+v", *got, want)
+	}
+}
+
+func TestLoad_missingIsNotAnError(t *testing.T) {
+	_, ok, err := Load(t.TempDir())
+	if err != nil || ok {
+		t.Fatalf("ok=%v err=%v, want false, nil", ok, err)
+	}
+}
+
+func TestLoad_rejectsIncompleteLock(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(Path(dir), []byte("scenario: billing/pro\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := Load(dir)
+	if err == nil || !strings.Contains(err.Error(), "revision, checksum") {
+		t.Fatalf("err = %v, want missing revision, checksum", err)
+	}
+}
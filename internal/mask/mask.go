@@ -0,0 +1,218 @@
+// Package mask replaces sensitive column values in CSV fixtures with
+// stand-ins, so a fixture holding realistic data can be kept on a
+// machine that shouldn't hold the real thing. Columns are picked in
+// seedmancer.yaml like generators, by "table.column" or by a bare column
+// name that applies in every table:
+//
+//	pull_masking:
+//	  users.full_name: name
+//	  email: email
+//
+// Every strategy but null derives its stand-in from a keyed hash of the
+// original, so equal values stay equal — a unique column stays unique
+// and two tables holding the same email still agree — while the key,
+// drawn fresh for each run, keeps the originals from being looked up.
+package mask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Strategies, by the name they are picked with.
+const (
+	// Hash is 16 hex digits.
+	Hash = "hash"
+	// Email is user-<12 hex digits>@example.com.
+	Email = "email"
+	// Name is "Person <8 hex digits>".
+	Name = "name"
+	// Phone is +1 and 10 digits.
+	Phone = "phone"
+	// Null is NULL, for nullable columns.
+	Null = "null"
+)
+
+var strategies = map[string]bool{Hash: true, Email: true, Name: true, Phone: true, Null: true}
+
+// Strategies returns the strategy names, sorted.
+func Strategies() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Masker masks the CSVs of one fixture.
+type Masker struct {
+	key []byte
+	// columns maps table → column → strategy.
+	columns map[string]map[string]string
+}
+
+// New resolves columns, as written in seedmancer.yaml, against schema:
+// "table.column" wins over a bare column name. It fails when a key
+// matches no column, names an unknown strategy, or picks a column
+// masking would break: a primary key, a foreign key or a column one
+// references, anything but text for a strategy other than null, and a
+// NOT NULL column for null. key seeds the hash.
+func New(columns map[string]string, schema utils.SchemaJSON, key []byte) (*Masker, error) {
+	keys := make([]string, 0, len(columns))
+	for k, strategy := range columns {
+		if !strategies[strings.ToLower(strategy)] {
+			return nil, fmt.Errorf("pull_masking.%s: unknown strategy %q (want one of %s)", k, strategy, strings.Join(Strategies(), ", "))
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	referenced := map[string]bool{}
+	for _, t := range schema.Tables {
+		for _, c := range t.Columns {
+			if c.ForeignKey != nil {
+				referenced[c.ForeignKey.Table+"."+c.ForeignKey.Column] = true
+			}
+		}
+	}
+
+	m := &Masker{key: key, columns: map[string]map[string]string{}}
+	used := map[string]bool{}
+	for _, t := range schema.Tables {
+		for _, c := range t.Columns {
+			k := t.Name + "." + c.Name
+			strategy, ok := columns[k]
+			if !ok {
+				if strategy, ok = columns[c.Name]; ok {
+					k = c.Name
+				}
+			}
+			if !ok {
+				continue
+			}
+			used[k] = true
+			strategy = strings.ToLower(strategy)
+			switch {
+			case isTrue(c.IsPrimary) || c.ForeignKey != nil || referenced[t.Name+"."+c.Name]:
+				return nil, fmt.Errorf("pull_masking.%s: %s.%s is a key; masking it would break the rows that reference it", k, t.Name, c.Name)
+			case strategy == Null && !isTrue(c.Nullable):
+				return nil, fmt.Errorf("pull_masking.%s: %s.%s is NOT NULL", k, t.Name, c.Name)
+			case strategy != Null && !isText(c.Type):
+				return nil, fmt.Errorf("pull_masking.%s: %s.%s is %s; %s only masks text columns", k, t.Name, c.Name, c.Type, strategy)
+			}
+			if m.columns[t.Name] == nil {
+				m.columns[t.Name] = map[string]string{}
+			}
+			m.columns[t.Name][c.Name] = strategy
+		}
+	}
+	for _, k := range keys {
+		if !used[k] {
+			return nil, fmt.Errorf("pull_masking.%s: no such column in schema.json", k)
+		}
+	}
+	return m, nil
+}
+
+// Columns returns the masked columns as "table.column", sorted.
+func (m *Masker) Columns() []string {
+	var out []string
+	for table, cols := range m.columns {
+		for col := range cols {
+			out = append(out, table+"."+col)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Masks reports whether table has a masked column.
+func (m *Masker) Masks(table string) bool {
+	return len(m.columns[table]) > 0
+}
+
+// MaskCSV copies table's CSV from r to w with its masked columns
+// replaced, and returns how many cells it replaced. NULL and empty cells
+// are kept as they are.
+func (m *Masker) MaskCSV(table string, r io.Reader, w io.Writer) (int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cw := csv.NewWriter(w)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading %s.csv: %w", table, err)
+	}
+	strategy := make([]string, len(header))
+	for i, name := range header {
+		strategy[i] = m.columns[table][name]
+	}
+	if err := cw.Write(header); err != nil {
+		return 0, err
+	}
+	masked := 0
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return masked, fmt.Errorf("reading %s.csv: %w", table, err)
+		}
+		for i, v := range rec {
+			if i >= len(strategy) || strategy[i] == "" || v == "" || v == "NULL" {
+				continue
+			}
+			rec[i] = m.value(strategy[i], v)
+			masked++
+		}
+		if err := cw.Write(rec); err != nil {
+			return masked, err
+		}
+	}
+	cw.Flush()
+	return masked, cw.Error()
+}
+
+// value is v masked with strategy.
+func (m *Masker) value(strategy, v string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(v))
+	sum := mac.Sum(nil)
+	digest := hex.EncodeToString(sum)
+	switch strategy {
+	case Null:
+		return "NULL"
+	case Email:
+		return "user-" + digest[:12] + "@example.com"
+	case Name:
+		return "Person " + digest[:8]
+	case Phone:
+		var b strings.Builder
+		b.WriteString("+1")
+		for _, c := range sum[:10] {
+			b.WriteByte('0' + c%10)
+		}
+		return b.String()
+	}
+	return digest[:16]
+}
+
+func isTrue(b *bool) bool { return b != nil && *b }
+
+// isText reports whether a column of type t holds free text.
+func isText(t string) bool {
+	t = strings.ToLower(t)
+	return strings.Contains(t, "char") || strings.Contains(t, "text")
+}
@@ -0,0 +1,84 @@
+package mask
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+const schemaJSON = `{"tables":[
+  {"name":"users","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"email","type":"character varying","isUnique":true},
+    {"name":"full_name","type":"text"},
+    {"name":"phone","type":"text","nullable":true},
+    {"name":"age","type":"integer","nullable":true}
+  ]},
+  {"name":"invites","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}},
+    {"name":"email","type":"text"}
+  ]}
+]}`
+
+func loadSchema(t *testing.T) utils.SchemaJSON {
+	t.Helper()
+	var s utils.SchemaJSON
+	if err := json.Unmarshal([]byte(schemaJSON), &s); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return s
+}
+
+func TestMaskCSV(t *testing.T) {
+	m, err := New(map[string]string{"email": "email", "users.full_name": "name", "users.phone": "null"}, loadSchema(t), []byte("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(m.Columns(), ","); got != "invites.email,users.email,users.full_name,users.phone" {
+		t.Fatalf("Columns = %s", got)
+	}
+
+	var users, invites strings.Builder
+	n, err := m.MaskCSV("users", strings.NewReader("id,email,full_name,phone,age\n1,ada@corp.io,Ada Lovelace,555-0100,36\n2,bob@corp.io,Bob,NULL,\n"), &users)
+	if err != nil || n != 5 {
+		t.Fatalf("MaskCSV users = %d, %v; want 5 cells", n, err)
+	}
+	if _, err := m.MaskCSV("invites", strings.NewReader("id,user_id,email\n1,1,ada@corp.io\n"), &invites); err != nil {
+		t.Fatal(err)
+	}
+
+	out := users.String()
+	for _, raw := range []string{"ada@corp.io", "Ada Lovelace", "555-0100"} {
+		if strings.Contains(out, raw) {
+			t.Errorf("masked CSV still holds %q:\n%s", raw, out)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	row := strings.Split(lines[1], ",")
+	if row[0] != "1" || !strings.HasPrefix(row[1], "user-") || !strings.HasPrefix(row[2], "Person ") || row[3] != "NULL" || row[4] != "36" {
+		t.Errorf("row 1 = %v", row)
+	}
+	if !strings.Contains(invites.String(), row[1]) {
+		t.Errorf("the same email masked differently across tables: %s vs %s", row[1], invites.String())
+	}
+}
+
+func TestNew_refuses(t *testing.T) {
+	cases := map[string]map[string]string{
+		"unknown strategy":   {"users.email": "scramble"},
+		"no such column":     {"users.nickname": "name"},
+		"primary key":        {"users.id": "hash"},
+		"foreign key":        {"invites.user_id": "hash"},
+		"NOT NULL":           {"users.full_name": "null"},
+		"text columns":       {"users.age": "hash"},
+		"unmatched bare key": {"nickname": "name"},
+	}
+	for want, columns := range cases {
+		if _, err := New(columns, loadSchema(t), nil); err == nil {
+			t.Errorf("%v: want an error (%s)", columns, want)
+		}
+	}
+}
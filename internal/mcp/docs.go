@@ -69,6 +69,14 @@ environments:
 
 Read ` + "`seedmancer://docs/env-markers`" + ` for the full reference.
 
+## SQL expressions in cells
+
+A CSV cell of ` + "`=DEFAULT`" + ` (or ` + "`\\D`" + `) inserts the column's default, and
+` + "`=expr(now() - interval '3 days')`" + ` inserts whatever the expression evaluates to
+at seed time — handy for timestamps relative to "now" without a post-seed
+SQL step. The expression goes to the database verbatim, so it must suit the
+engine. Any other cell starting with ` + "`=`" + ` is a literal.
+
 ## Pinning for CI
 
 Seedmancer always uses the latest revision. Use ` + "`--revision rNNN`" + ` to lock onto
@@ -270,6 +278,30 @@ variety genuinely helps, run ` + "`SELECT setseed(0.42);`" + ` immediately after
 TRUNCATE — that makes ` + "`random()`" + ` reproducible. For uuid columns derive
 deterministic literals: ` + "`('00000000-0000-0000-0000-' || lpad(i::text, 12, '0'))::uuid`" + `.
 
+## Foreign keys — order and cardinality
+
+` + "`describe_schema`" + ` returns ` + "`insertOrder`" + `: every table, parents first. Write
+the INSERT blocks in exactly that order. Tables listed in ` + "`cyclicTables`" + ` sit
+in an FK cycle — insert them with the cyclic FK set to NULL, then fill it in
+with an UPDATE once both sides exist.
+
+Don't pick parent keys with a bare ` + "`i % n`" + ` — it hands every parent the same
+number of children in lockstep, which real data never does. Pick a strategy
+per relationship instead (` + "`n`" + ` = parent row count, parent ids ` + "`1..n`" + `):
+
+- **uniform** — every parent equally likely, but not in lockstep. Hash the
+  index: ` + "`1 + abs(hashtext('orders.user_id:' || i)) % n`" + `.
+- **zipfian** — a few parents own most children (top customers, hot
+  products): ` + "`1 + floor(n * power((abs(hashtext('orders.user_id:' || i)) % 10000) / 10000.0, 3))::int`" + `.
+  Raise the exponent for a steeper skew.
+- **one-to-one** — each parent gets exactly one child (profiles, settings):
+  use ` + "`i`" + ` itself and generate exactly ` + "`n`" + ` child rows. A UNIQUE FK column
+  always needs this.
+
+All three are deterministic, so the replay contract still holds. They are
+the same strategies ` + "`seedmancer generate --rows N`" + ` applies to the columns
+listed under ` + "`fk_picks`" + ` in seedmancer.yaml.
+
 ## Guidance on what Seedmancer should NOT be
 
 - **Production-shape anonymisation pipelines.** Use a dedicated masking tool.
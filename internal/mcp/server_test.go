@@ -236,3 +236,22 @@ func setupFakeProject(t *testing.T) string {
 	}
 	return dir
 }
+
+// TestRedactToolErrors checks that a tool error quoting a DSN reaches
+// the agent with its password masked.
+func TestRedactToolErrors(t *testing.T) {
+	failing := func(context.Context, string, sdk.Request) (sdk.Result, error) {
+		return &sdk.CallToolResult{
+			IsError: true,
+			Content: []sdk.Content{&sdk.TextContent{Text: "connecting to postgres://app:s3cret@db/app: refused"}},
+		}, nil
+	}
+	res, err := redactToolErrors(failing)(context.Background(), "tools/call", nil)
+	if err != nil {
+		t.Fatalf("middleware: %v", err)
+	}
+	text := res.(*sdk.CallToolResult).Content[0].(*sdk.TextContent).Text
+	if strings.Contains(text, "s3cret") || !strings.Contains(text, "postgres://app:****@db/app") {
+		t.Errorf("tool error = %q, want the password masked", text)
+	}
+}
@@ -2,8 +2,10 @@ package mcp
 
 import (
 	"context"
+	"errors"
 
 	"github.com/KazanKK/seedmancer/cmd"
+	"github.com/KazanKK/seedmancer/internal/redact"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -29,6 +31,8 @@ func registerTools(s *mcp.Server) {
 	falsePtr := func() *bool { b := false; return &b }
 	truePtr := func() *bool { b := true; return &b }
 
+	s.AddReceivingMiddleware(redactToolErrors)
+
 	// ── Introspection (read-only) ─────────────────────────────────────
 	mcp.AddTool(s, &mcp.Tool{
 		Name:  "list_datasets",
@@ -73,6 +77,18 @@ func registerTools(s *mcp.Server) {
 		return nil, out, err
 	})
 
+	mcp.AddTool(s, &mcp.Tool{
+		Name:  "explain_row",
+		Title: "Explain a fixture row",
+		Description: "Return one row of a scenario revision (selected by table and column=value pairs) " +
+			"together with the parent rows its foreign keys reference and the child rows " +
+			"referencing it, read from the CSVs. Use to understand a fixture entity without seeding it.",
+		Annotations: readOnly,
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.ExplainInput) (*mcp.CallToolResult, cmd.ExplainOutput, error) {
+		out, err := cmd.RunExplain(ctx, in)
+		return nil, out, err
+	})
+
 	mcp.AddTool(s, &mcp.Tool{
 		Name:        "list_schemas",
 		Title:       "List schemas",
@@ -86,7 +102,7 @@ func registerTools(s *mcp.Server) {
 	mcp.AddTool(s, &mcp.Tool{
 		Name:        "describe_schema",
 		Title:       "Describe schema",
-		Description: "Return tables + columns for a local schema (fingerprint prefix or full fingerprint).",
+		Description: "Return tables + columns for a local schema (fingerprint prefix or full fingerprint), plus insertOrder: tables sorted parents-first for FK-safe INSERTs.",
 		Annotations: readOnly,
 	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.DescribeSchemaInput) (*mcp.CallToolResult, cmd.DescribeSchemaOutput, error) {
 		out, err := cmd.RunDescribeSchema(ctx, in)
@@ -96,7 +112,7 @@ func registerTools(s *mcp.Server) {
 	mcp.AddTool(s, &mcp.Tool{
 		Name:        "get_status",
 		Title:       "Get Seedmancer status",
-		Description: "Project layout, configured environments, auth state, and optional API reachability probe.",
+		Description: "Project layout, configured environments, auth state, and optional API reachability probe. Given a scenario, also reports how its revision differs from the live database and what seed would change.",
 		Annotations: readOnly,
 	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.StatusInput) (*mcp.CallToolResult, cmd.StatusOutput, error) {
 		out, err := cmd.RunStatus(ctx, in)
@@ -113,6 +129,16 @@ func registerTools(s *mcp.Server) {
 		return nil, out, err
 	})
 
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "introspect",
+		Title:       "Introspect project",
+		Description: "Describe the project from local storage in one call: environments (engine + masked URL), every scenario revision with its tables, row counts and CSV folder, and every local schema's tables, columns and enums.",
+		Annotations: readOnly,
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.IntrospectInput) (*mcp.CallToolResult, cmd.IntrospectOutput, error) {
+		out, err := cmd.RunIntrospect(ctx, in)
+		return nil, out, err
+	})
+
 	// ── Config mutation (non-destructive to data) ─────────────────────
 	mcp.AddTool(s, &mcp.Tool{
 		Name:        "add_env",
@@ -174,7 +200,9 @@ func registerTools(s *mcp.Server) {
 		Description: "Truncate the target env(s) and reload a scenario revision into them. " +
 			"Defaults to the scenario's latest revision; pass `revision: \"rNNN\"` for a specific one. " +
 			"Refuses to seed when the database schema fingerprint differs from the revision's, " +
-			"unless `force: true` is set. This overwrites existing data — intended for test/dev resets.",
+			"unless `force: true` is set, and when PostgreSQL's TRUNCATE … CASCADE would empty tables outside the revision, " +
+			"unless `allowCascade: true` is set; `dryRun: true` lists those tables per target (results[].cascade) without seeding. " +
+			"This overwrites existing data — intended for test/dev resets.",
 		Annotations: &mcp.ToolAnnotations{DestructiveHint: truePtr(), IdempotentHint: true},
 	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.SeedInput) (*mcp.CallToolResult, cmd.SeedOutput, error) {
 		if !in.Yes {
@@ -223,6 +251,69 @@ func registerTools(s *mcp.Server) {
 		return nil, out, err
 	})
 
+	mcp.AddTool(s, &mcp.Tool{
+		Name:  "age_dataset",
+		Title: "Age scenario dates",
+		Description: "Shift every date/timestamp column of a scenario revision by an interval " +
+			"(e.g. 90d, 2w, -36h) and save the result as a new revision. Relative ordering " +
+			"between values is preserved. Use when calendar-sensitive fixtures (trials, " +
+			"expiries) have drifted into the past.",
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: falsePtr(), IdempotentHint: false},
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.AgeInput) (*mcp.CallToolResult, cmd.AgeOutput, error) {
+		out, err := cmd.RunAge(ctx, in)
+		return nil, out, err
+	})
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name:  "save_dataset",
+		Title: "Save revision under another scenario",
+		Description: "Copy a revision (latest by default) of one scenario into a new revision of " +
+			"another, named scenario — e.g. promote a scratch export to billing/pro. Refuses to " +
+			"add to a scenario that already has revisions unless force=true.",
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: falsePtr(), IdempotentHint: false},
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.SaveInput) (*mcp.CallToolResult, cmd.SaveOutput, error) {
+		out, err := cmd.RunSave(ctx, in)
+		return nil, out, err
+	})
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name:  "rewrite_dataset",
+		Title: "Batch-edit scenario values",
+		Description: "Apply set rules (table.column=value) and map rules (table.column:from=to) to a " +
+			"scenario revision and save the result as a new revision. Rules are type-checked " +
+			"against schema.json before anything is written. Prefer this over regenerating when " +
+			"only a few column values need to change.",
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: falsePtr(), IdempotentHint: false},
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.RewriteInput) (*mcp.CallToolResult, cmd.RewriteOutput, error) {
+		out, err := cmd.RunRewrite(ctx, in)
+		return nil, out, err
+	})
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name:  "repair_dataset",
+		Title: "Fix orphaned foreign keys",
+		Description: "Find foreign key cells in a scenario revision that name a parent row its CSVs " +
+			"don't have. With strategy delete, nullify or synthesize-parent, fix them and save " +
+			"the result as a new revision; without one, only list them. Use when seed fails on " +
+			"a foreign key constraint after fixtures were edited by hand.",
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: falsePtr(), IdempotentHint: false},
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.RepairInput) (*mcp.CallToolResult, cmd.RepairOutput, error) {
+		out, err := cmd.RunRepair(ctx, in)
+		return nil, out, err
+	})
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name:  "prune_dataset",
+		Title: "Derive a slim scenario",
+		Description: "Write a new revision of the `as` scenario containing only the `keep` tables of " +
+			"a source revision, plus the FK parent rows they reference. Use to maintain a " +
+			"lightweight variant of a full export.",
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: falsePtr(), IdempotentHint: false},
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in cmd.PruneInput) (*mcp.CallToolResult, cmd.PruneOutput, error) {
+		out, err := cmd.RunPrune(ctx, in)
+		return nil, out, err
+	})
+
 
 	mcp.AddTool(s, &mcp.Tool{
 		Name:        "push_dataset",
@@ -360,3 +451,23 @@ func registerTools(s *mcp.Server) {
 		return nil, out, err
 	})
 }
+
+// redactToolErrors masks database credentials in the errors tools
+// return: a runner's error often quotes the DSN it failed on, and the
+// agent's transcript is no place for the password.
+func redactToolErrors(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		res, err := next(ctx, method, req)
+		if err != nil {
+			return res, errors.New(redact.String(err.Error()))
+		}
+		if r, ok := res.(*mcp.CallToolResult); ok && r.IsError {
+			for _, c := range r.Content {
+				if text, ok := c.(*mcp.TextContent); ok {
+					text.Text = redact.String(text.Text)
+				}
+			}
+		}
+		return res, nil
+	}
+}
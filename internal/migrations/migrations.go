@@ -0,0 +1,313 @@
+// Package migrations reads migrations.yaml, the file a project keeps next
+// to its seedmancer.yaml to describe how columns moved since older
+// revisions were exported, and applies it to a staged restore directory
+// so those revisions still seed into the current schema:
+//
+//	tables:
+//	  users:
+//	    rename:
+//	      full_name: display_name   # revision column: live column
+//	    drop:
+//	      - legacy_flag
+//	    defaults:
+//	      timezone: UTC
+//	      updated_at: =expr(now())
+//
+// Every rule only fires when the revision still has the old shape: a
+// rename when the CSV has the old column and not the new one, a drop
+// when the column is there, a default when the column is missing. A
+// revision exported after the migration passes through unchanged, so one
+// file covers old and new revisions alike.
+//
+// Like chaos and timeshift this is pure file logic: it rewrites CSVs and
+// schema.json and never talks to a database.
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the on-disk name of the mapping file.
+const FileName = "migrations.yaml"
+
+// File is the parsed migrations.yaml.
+type File struct {
+	Tables map[string]TableRules `yaml:"tables"`
+}
+
+// TableRules are the column changes for one table. Defaults values are
+// written into every row as CSV cells, so "NULL", "=DEFAULT" and
+// "=expr(...)" work as they do in fixtures.
+type TableRules struct {
+	Rename   map[string]string `yaml:"rename,omitempty"`
+	Drop     []string          `yaml:"drop,omitempty"`
+	Defaults map[string]string `yaml:"defaults,omitempty"`
+}
+
+// Change is one rule that fired. To is the new name for a rename and the
+// value for a default.
+type Change struct {
+	Table  string `json:"table"`
+	Kind   string `json:"kind"` // rename, drop or default
+	Column string `json:"column"`
+	To     string `json:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case "rename":
+		return fmt.Sprintf("%s.%s → %s", c.Table, c.Column, c.To)
+	case "drop":
+		return fmt.Sprintf("%s.%s dropped", c.Table, c.Column)
+	}
+	return fmt.Sprintf("%s.%s = %s", c.Table, c.Column, c.To)
+}
+
+// Path returns the migrations.yaml path for a project root.
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, FileName)
+}
+
+// Load reads the mapping at path. The bool return is false (with a nil
+// error) when there is no file.
+func Load(path string) (*File, bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var f File
+	if err := yaml.Unmarshal(raw, &f); err != nil {
+		return nil, false, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, true, nil
+}
+
+// validate rejects rules that contradict each other, since which one
+// wins would otherwise depend on the order they are applied in.
+func (f File) validate() error {
+	for table, r := range f.Tables {
+		targets := map[string]string{}
+		for from, to := range r.Rename {
+			from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+			if from == "" || to == "" {
+				return fmt.Errorf("tables.%s.rename: column names can't be empty", table)
+			}
+			if prev, ok := targets[to]; ok {
+				return fmt.Errorf("tables.%s.rename: %s and %s are both renamed to %s", table, prev, from, to)
+			}
+			targets[to] = from
+		}
+		for _, col := range r.Drop {
+			if _, ok := r.Rename[col]; ok {
+				return fmt.Errorf("tables.%s: %s is both renamed and dropped", table, col)
+			}
+		}
+		for col := range r.Defaults {
+			if _, ok := targets[col]; ok {
+				return fmt.Errorf("tables.%s: %s is both a rename target and has a default", table, col)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply rewrites the CSVs and schema.json in srcDir that f touches into
+// dstDir and returns the rules that fired, sorted by table. Files it
+// doesn't change are left for the caller to link through. Columns added
+// by a default go into schema.json as nullable text: the live table
+// already has them, and the value is converted by the database.
+func Apply(srcDir, dstDir string, f File) ([]Change, error) {
+	var changes []Change
+	renamed := map[string]map[string]string{}
+	dropped := map[string]map[string]bool{}
+	added := map[string][]string{}
+
+	tables := make([]string, 0, len(f.Tables))
+	for t := range f.Tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		records, err := utils.ReadCSVRecords(filepath.Join(srcDir, table+".csv"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			continue
+		}
+		out, tc := applyTable(table, records, f.Tables[table])
+		if len(tc) == 0 {
+			continue
+		}
+		for _, c := range tc {
+			switch c.Kind {
+			case "rename":
+				if renamed[table] == nil {
+					renamed[table] = map[string]string{}
+				}
+				renamed[table][c.Column] = c.To
+			case "drop":
+				if dropped[table] == nil {
+					dropped[table] = map[string]bool{}
+				}
+				dropped[table][c.Column] = true
+			case "default":
+				added[table] = append(added[table], c.Column)
+			}
+		}
+		if err := utils.WriteCSVRecords(filepath.Join(dstDir, table+".csv"), out); err != nil {
+			return nil, fmt.Errorf("writing %s.csv: %w", table, err)
+		}
+		changes = append(changes, tc...)
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := rewriteSchema(filepath.Join(srcDir, "schema.json"), filepath.Join(dstDir, "schema.json"), renamed, dropped, added); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// applyTable applies r to one table's records (header first).
+func applyTable(table string, records [][]string, r TableRules) ([][]string, []Change) {
+	header := append([]string(nil), records[0]...)
+	index := map[string]int{}
+	for i, name := range header {
+		index[name] = i
+	}
+	var changes []Change
+
+	froms := make([]string, 0, len(r.Rename))
+	for from := range r.Rename {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		to := r.Rename[from]
+		i, ok := index[from]
+		if _, clash := index[to]; !ok || clash {
+			continue
+		}
+		header[i] = to
+		delete(index, from)
+		index[to] = i
+		changes = append(changes, Change{Table: table, Kind: "rename", Column: from, To: to})
+	}
+
+	drop := map[int]bool{}
+	for _, col := range r.Drop {
+		if i, ok := index[col]; ok && !drop[i] {
+			drop[i] = true
+			changes = append(changes, Change{Table: table, Kind: "drop", Column: col})
+		}
+	}
+
+	defaults := make([]string, 0, len(r.Defaults))
+	for col := range r.Defaults {
+		if _, ok := index[col]; !ok {
+			defaults = append(defaults, col)
+		}
+	}
+	sort.Strings(defaults)
+	for _, col := range defaults {
+		changes = append(changes, Change{Table: table, Kind: "default", Column: col, To: r.Defaults[col]})
+	}
+	if len(changes) == 0 {
+		return records, nil
+	}
+
+	out := make([][]string, 0, len(records))
+	for n, rec := range records {
+		if n == 0 {
+			rec = header
+		}
+		row := make([]string, 0, len(rec)+len(defaults))
+		for i, cell := range rec {
+			if !drop[i] {
+				row = append(row, cell)
+			}
+		}
+		for _, col := range defaults {
+			if n == 0 {
+				row = append(row, col)
+			} else {
+				row = append(row, r.Defaults[col])
+			}
+		}
+		out = append(out, row)
+	}
+	return out, changes
+}
+
+// rewriteSchema mirrors the CSV changes in schema.json, keeping every
+// field it doesn't know about. Foreign keys pointing at a renamed column
+// follow it; those pointing at a dropped one are removed.
+func rewriteSchema(src, dst string, renamed map[string]map[string]string, dropped map[string]map[string]bool, added map[string][]string) error {
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("reading schema.json: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("parsing schema.json: %v", err)
+	}
+	tables, _ := doc["tables"].([]any)
+	for _, t := range tables {
+		table, _ := t.(map[string]any)
+		name, _ := table["name"].(string)
+		cols, _ := table["columns"].([]any)
+		kept := make([]any, 0, len(cols)+len(added[name]))
+		present := map[string]bool{}
+		for _, c := range cols {
+			col, _ := c.(map[string]any)
+			colName, _ := col["name"].(string)
+			if dropped[name][colName] {
+				continue
+			}
+			if to, ok := renamed[name][colName]; ok {
+				col["name"] = to
+				colName = to
+			}
+			if fk, ok := col["foreignKey"].(map[string]any); ok {
+				refTable, _ := fk["table"].(string)
+				refCol, _ := fk["column"].(string)
+				if dropped[refTable][refCol] {
+					delete(col, "foreignKey")
+				} else if to, ok := renamed[refTable][refCol]; ok {
+					fk["column"] = to
+				}
+			}
+			present[colName] = true
+			kept = append(kept, col)
+		}
+		for _, colName := range added[name] {
+			if !present[colName] {
+				kept = append(kept, map[string]any{"name": colName, "type": "text", "nullable": true})
+			}
+		}
+		table["columns"] = kept
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, out, 0o644)
+}
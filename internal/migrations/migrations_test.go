@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok, err := Load(Path(dir)); ok || err != nil {
+		t.Fatalf("missing file: ok=%v err=%v", ok, err)
+	}
+	write(t, Path(dir), "tables:\n  users:\n    rename: {full_name: name}\n    drop: [full_name]\n")
+	if _, _, err := Load(Path(dir)); err == nil || !strings.Contains(err.Error(), "both renamed and dropped") {
+		t.Errorf("err = %v, want a conflict", err)
+	}
+	write(t, Path(dir), "tables:\n  users:\n    rename: {a: c, b: c}\n")
+	if _, _, err := Load(Path(dir)); err == nil || !strings.Contains(err.Error(), "renamed to c") {
+		t.Errorf("err = %v, want a duplicate target", err)
+	}
+	write(t, Path(dir), "tables:\n  users:\n    defaults: {tier: 3, active: true}\n")
+	f, ok, err := Load(Path(dir))
+	if err != nil || !ok {
+		t.Fatalf("Load: ok=%v err=%v", ok, err)
+	}
+	if got := f.Tables["users"].Defaults; got["tier"] != "3" || got["active"] != "true" {
+		t.Errorf("defaults = %v", got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	write(t, filepath.Join(src, "schema.json"), `{"databaseType":"postgres","tables":[
+	  {"name":"users","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"full_name","type":"text"},{"name":"legacy","type":"boolean"}]},
+	  {"name":"posts","columns":[{"name":"id","type":"integer"},{"name":"author","type":"text","foreignKey":{"table":"users","column":"full_name"}}]}
+	]}`)
+	write(t, filepath.Join(src, "users.csv"), "id,full_name,legacy\n1,Ada,t\n2,\"Bob, Jr\",f\n")
+	write(t, filepath.Join(src, "posts.csv"), "id,author\n1,Ada\n")
+
+	f := File{Tables: map[string]TableRules{
+		"users": {
+			Rename:   map[string]string{"full_name": "name"},
+			Drop:     []string{"legacy"},
+			Defaults: map[string]string{"timezone": "UTC", "id": "0"},
+		},
+		"posts": {Rename: map[string]string{"title": "headline"}},
+	}}
+	changes, err := Apply(src, dst, f)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	want := []Change{
+		{Table: "users", Kind: "rename", Column: "full_name", To: "name"},
+		{Table: "users", Kind: "drop", Column: "legacy"},
+		{Table: "users", Kind: "default", Column: "timezone", To: "UTC"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+
+	users, _ := os.ReadFile(filepath.Join(dst, "users.csv"))
+	if got := string(users); got != "id,name,timezone\n1,Ada,UTC\n2,\"Bob, Jr\",UTC\n" {
+		t.Errorf("users.csv = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "posts.csv")); !os.IsNotExist(err) {
+		t.Error("posts.csv had no rule fire and should not be written")
+	}
+	schema, _ := os.ReadFile(filepath.Join(dst, "schema.json"))
+	for _, w := range []string{`"name": "name"`, `"name": "timezone"`, `"column": "name"`} {
+		if !strings.Contains(string(schema), w) {
+			t.Errorf("schema.json lacks %s:\n%s", w, schema)
+		}
+	}
+	if strings.Contains(string(schema), "legacy") {
+		t.Errorf("schema.json still has the dropped column:\n%s", schema)
+	}
+
+	// The rewritten revision no longer has the old shape: nothing fires.
+	again, err := Apply(dst, t.TempDir(), f)
+	if err != nil || len(again) != 0 {
+		t.Errorf("second Apply = %+v, %v; want no changes", again, err)
+	}
+}
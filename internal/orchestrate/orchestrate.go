@@ -0,0 +1,154 @@
+// Package orchestrate finds the seedmancer projects (services) in a
+// monorepo and puts them in seeding order. A service is any directory
+// holding a seedmancer.yaml; its depends_on entries name the services
+// whose fixtures its own rows reference, which must be seeded first.
+//
+// The package only reads files. Seeding itself is done by
+// `seedmancer orchestrate` in cmd/, one service at a time in Order.
+package orchestrate
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/lockfile"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// configFileName is the per-service config Discover looks for.
+const configFileName = "seedmancer.yaml"
+
+// Service is one seedmancer project inside the repository.
+type Service struct {
+	// Name is the service directory relative to the discovery root,
+	// slash-separated ("." for the root itself).
+	Name   string
+	Dir    string
+	Config utils.Config
+	// Lock is the service's seedmancer.lock, nil when it has none.
+	Lock *lockfile.Lock
+	// DependsOn holds the Names of the services in Config.DependsOn.
+	DependsOn []string
+}
+
+// Discover walks root for services and returns them sorted by Name.
+// Hidden directories (which include the storage dirs), node_modules and
+// vendor are not descended into. A depends_on entry that doesn't lead to
+// another discovered service is an error.
+func Discover(root string) ([]Service, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	var services []Service
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != configFileName {
+			return nil
+		}
+		svc, err := loadService(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		services = append(services, svc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	byDir := make(map[string]string, len(services))
+	for _, s := range services {
+		byDir[s.Dir] = s.Name
+	}
+	for i, s := range services {
+		for _, dep := range s.Config.DependsOn {
+			name, ok := byDir[filepath.Clean(filepath.Join(s.Dir, dep))]
+			if !ok {
+				return nil, fmt.Errorf("%s: depends_on %q is not a directory with a %s under %s", s.Name, dep, configFileName, root)
+			}
+			if name == s.Name {
+				return nil, fmt.Errorf("%s: depends_on %q points at itself", s.Name, dep)
+			}
+			services[i].DependsOn = append(services[i].DependsOn, name)
+		}
+	}
+	return services, nil
+}
+
+func loadService(root, dir string) (Service, error) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return Service{}, err
+	}
+	cfg, err := utils.LoadConfig(filepath.Join(dir, configFileName))
+	if err != nil {
+		return Service{}, fmt.Errorf("%s: %w", filepath.ToSlash(rel), err)
+	}
+	lock, _, err := lockfile.Load(dir)
+	if err != nil {
+		return Service{}, fmt.Errorf("%s: %w", filepath.ToSlash(rel), err)
+	}
+	return Service{Name: filepath.ToSlash(rel), Dir: dir, Config: cfg, Lock: lock}, nil
+}
+
+// Order returns services with every service after the ones it depends
+// on. Among services whose dependencies are all placed, the one with the
+// smallest Name goes first, so the order is stable. A dependency cycle
+// is an error naming the services caught in it.
+func Order(services []Service) ([]Service, error) {
+	byName := make(map[string]Service, len(services))
+	pending := make(map[string]int, len(services))
+	dependents := map[string][]string{}
+	for _, s := range services {
+		byName[s.Name] = s
+		pending[s.Name] = len(s.DependsOn)
+		for _, dep := range s.DependsOn {
+			dependents[dep] = append(dependents[dep], s.Name)
+		}
+	}
+
+	var ready []string
+	for _, s := range services {
+		if pending[s.Name] == 0 {
+			ready = append(ready, s.Name)
+		}
+	}
+	out := make([]Service, 0, len(services))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		out = append(out, byName[name])
+		for _, d := range dependents[name] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if len(out) < len(services) {
+		var cyclic []string
+		for name, n := range pending {
+			if n > 0 {
+				cyclic = append(cyclic, name)
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("depends_on cycle between %s", strings.Join(cyclic, ", "))
+	}
+	return out, nil
+}
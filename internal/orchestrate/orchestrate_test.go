@@ -0,0 +1,90 @@
+package orchestrate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KazanKK/seedmancer/internal/lockfile"
+)
+
+func writeService(t *testing.T, root, rel, yaml string) {
+	t.Helper()
+	dir := filepath.Join(root, rel)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, configFileName), []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(services []Service) []string {
+	out := make([]string, len(services))
+	for i, s := range services {
+		out[i] = s.Name
+	}
+	return out
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	writeService(t, root, "services/users", "storage_path: .seedmancer\n")
+	writeService(t, root, "services/orders", "storage_path: .seedmancer\ndepends_on:\n  - ../users\n")
+	writeService(t, root, "node_modules/pkg", "storage_path: .seedmancer\n")
+	writeService(t, root, ".git/x", "storage_path: .seedmancer\n")
+	if err := lockfile.Write(filepath.Join(root, "services/users"), lockfile.Lock{Scenario: "base", Revision: "r001", Checksum: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	services, err := Discover(root)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if got, want := names(services), []string{"services/orders", "services/users"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("services = %v, want %v", got, want)
+	}
+	if got := services[0].DependsOn; !reflect.DeepEqual(got, []string{"services/users"}) {
+		t.Fatalf("orders.DependsOn = %v", got)
+	}
+	if services[0].Lock != nil || services[1].Lock == nil || services[1].Lock.Revision != "r001" {
+		t.Fatalf("locks: orders=%v users=%v", services[0].Lock, services[1].Lock)
+	}
+}
+
+func TestDiscover_unknownDependency(t *testing.T) {
+	root := t.TempDir()
+	writeService(t, root, "orders", "depends_on:\n  - ../users\n")
+	if _, err := Discover(root); err == nil || !strings.Contains(err.Error(), `"../users"`) {
+		t.Fatalf("err = %v, want unknown depends_on", err)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	services := []Service{
+		{Name: "billing", DependsOn: []string{"orders", "users"}},
+		{Name: "catalog"},
+		{Name: "orders", DependsOn: []string{"users", "catalog"}},
+		{Name: "users"},
+	}
+	ordered, err := Order(services)
+	if err != nil {
+		t.Fatalf("Order: %v", err)
+	}
+	if got, want := names(ordered), []string{"catalog", "users", "orders", "billing"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
+
+func TestOrder_cycle(t *testing.T) {
+	services := []Service{
+		{Name: "a", DependsOn: []string{"b"}},
+		{Name: "b", DependsOn: []string{"a"}},
+		{Name: "c"},
+	}
+	if _, err := Order(services); err == nil || !strings.Contains(err.Error(), "a, b") {
+		t.Fatalf("err = %v, want cycle between a, b", err)
+	}
+}
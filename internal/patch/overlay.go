@@ -0,0 +1,93 @@
+package patch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Overlay stacks the table CSVs of layerDir, a whole fixture rather than
+// a recorded patch, on top of those in dataDir: a layer row whose key
+// (primary key, or the whole row without one) is already there replaces
+// it in place, every other row is appended, and a table dataDir lacks is
+// taken as is. Tables are taken from schema; columns are matched by name,
+// so a layer may list them in another order but not add or leave any
+// out. Like Apply it replaces files rather than writing through them.
+func Overlay(layerDir, dataDir string, schema utils.SchemaJSON) ([]TableChange, error) {
+	tables := append([]utils.SchemaTable(nil), schema.Tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	var changes []TableChange
+	for _, t := range tables {
+		layer, err := readTable(filepath.Join(layerDir, t.Name+".csv"))
+		if err != nil {
+			return nil, err
+		}
+		if layer.header == nil {
+			continue
+		}
+		path := filepath.Join(dataDir, t.Name+".csv")
+		base, err := readTable(path)
+		if err != nil {
+			return nil, err
+		}
+		header := base.header
+		if header == nil {
+			header = layer.header
+		}
+		order, err := indexes(layer.header, header)
+		if err != nil || len(layer.header) != len(header) {
+			return nil, fmt.Errorf("%s: layer columns (%s) don't match the fixture's (%s)",
+				t.Name, strings.Join(layer.header, ","), strings.Join(header, ","))
+		}
+		key := keyColumns(t)
+		if len(key) == 0 {
+			key = header
+		}
+		keyIdx, err := indexes(header, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+
+		change := TableChange{Table: t.Name, Key: key}
+		replaced := map[string][]string{}
+		var added []string
+		for _, row := range layer.rows {
+			row = pick(row, order)
+			k := rowKey(row, keyIdx)
+			if _, seen := replaced[k]; !seen {
+				added = append(added, k)
+			}
+			replaced[k] = row
+		}
+		out := [][]string{header}
+		for _, row := range base.rows {
+			k := rowKey(row, keyIdx)
+			if repl, ok := replaced[k]; ok {
+				out = append(out, repl)
+				delete(replaced, k)
+				change.Updated++
+				continue
+			}
+			out = append(out, row)
+		}
+		for _, k := range added {
+			if row, ok := replaced[k]; ok {
+				out = append(out, row)
+				change.Inserted++
+			}
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err := utils.WriteCSVRecords(path, out); err != nil {
+			return nil, fmt.Errorf("writing %s.csv: %w", t.Name, err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,346 @@
+// Package patch turns the writes an application made to a database into
+// a named fixture patch, and applies such a patch on top of a revision's
+// CSVs. Overlay stacks a whole fixture on another the same way, which is
+// how scenarios are layered.
+//
+// A patch is the difference between two exports of the same database,
+// taken before and after a manual test session, keyed by primary key
+// (or by the whole row for tables without one). On disk:
+//
+//	<storagePath>/patches/<name>/
+//	  patch.json          Manifest
+//	  <table>.upsert.csv  rows to insert or overwrite, every column
+//	  <table>.delete.csv  key columns of rows to remove
+//
+// While a session is being recorded, the "before" export lives in
+// <storagePath>/recordings/<name>/.
+//
+// Like subset and chaos this is pure file logic; exporting is the
+// caller's job.
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// ManifestFile is the name of a patch's manifest.
+const ManifestFile = "patch.json"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateName rejects patch names that aren't a single safe path segment.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid patch name %q: use letters, digits, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// Dir is where the patch called name is stored.
+func Dir(projectRoot, storagePath, name string) string {
+	return filepath.Join(projectRoot, storagePath, "patches", name)
+}
+
+// RecordingDir is where the "before" export of a recording of name is kept.
+func RecordingDir(projectRoot, storagePath, name string) string {
+	return filepath.Join(projectRoot, storagePath, "recordings", name)
+}
+
+// TableChange summarises a patch's effect on one table.
+type TableChange struct {
+	Table string `json:"table"`
+	// Key is the columns rows are matched on.
+	Key []string `json:"key"`
+	// Ignored are the columns Diff left out of the comparison.
+	Ignored  []string `json:"ignored,omitempty"`
+	Inserted int      `json:"inserted"`
+	Updated  int      `json:"updated"`
+	Deleted  int      `json:"deleted"`
+}
+
+// Manifest is a patch's patch.json.
+type Manifest struct {
+	Name string `json:"name"`
+	// Scenario and Revision are what the database held when recording
+	// started, when the user said so. Informational only: a patch can be
+	// applied on top of any revision with the same schema.
+	Scenario          string        `json:"scenario,omitempty"`
+	Revision          string        `json:"revision,omitempty"`
+	SchemaFingerprint string        `json:"schemaFingerprint"`
+	CreatedAt         time.Time     `json:"createdAt"`
+	Description       string        `json:"description,omitempty"`
+	Tables            []TableChange `json:"tables"`
+}
+
+// ReadManifest loads the manifest of the patch in dir.
+func ReadManifest(dir string) (Manifest, error) {
+	var m Manifest
+	raw, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return m, fmt.Errorf("parsing %s: %v", ManifestFile, err)
+	}
+	return m, nil
+}
+
+// WriteManifest writes m to dir/patch.json.
+func WriteManifest(dir string, m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0644)
+}
+
+// keyColumns returns t's primary key columns, or nil when it has none.
+func keyColumns(t utils.SchemaTable) []string {
+	var key []string
+	for _, c := range t.Columns {
+		if c.IsPrimary != nil && *c.IsPrimary {
+			key = append(key, c.Name)
+		}
+	}
+	return key
+}
+
+// Diff compares the table CSVs of beforeDir and afterDir and writes the
+// difference into outDir as upsert and delete files. Tables are taken
+// from schema; a table whose CSVs are identical contributes nothing.
+//
+// Columns ignore reports (nil for none) don't count as a change: a row
+// whose other columns are the same isn't updated, and rows are matched
+// without them, on the rest of the table when the whole primary key is
+// ignored. Upserted rows still carry every column.
+func Diff(beforeDir, afterDir, outDir string, schema utils.SchemaJSON, ignore func(table, column string) bool) ([]TableChange, error) {
+	tables := append([]utils.SchemaTable(nil), schema.Tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	var changes []TableChange
+	for _, t := range tables {
+		before, err := readTable(filepath.Join(beforeDir, t.Name+".csv"))
+		if err != nil {
+			return nil, err
+		}
+		after, err := readTable(filepath.Join(afterDir, t.Name+".csv"))
+		if err != nil {
+			return nil, err
+		}
+		if before.header == nil && after.header == nil {
+			continue
+		}
+		header := after.header
+		if header == nil {
+			header = before.header
+		}
+		if before.header != nil && after.header != nil && strings.Join(before.header, ",") != strings.Join(after.header, ",") {
+			return nil, fmt.Errorf("%s: columns changed during the recording (%s → %s)",
+				t.Name, strings.Join(before.header, ","), strings.Join(after.header, ","))
+		}
+		var ignored, compared []string
+		skip := map[string]bool{}
+		for _, c := range header {
+			if ignore != nil && ignore(t.Name, c) {
+				ignored = append(ignored, c)
+				skip[c] = true
+			} else {
+				compared = append(compared, c)
+			}
+		}
+		var key []string
+		for _, c := range keyColumns(t) {
+			if !skip[c] {
+				key = append(key, c)
+			}
+		}
+		if len(key) == 0 {
+			key = compared
+		}
+		keyIdx, err := indexes(header, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+		comparedIdx, err := indexes(header, compared)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+
+		beforeRows := map[string][]string{}
+		for _, row := range before.rows {
+			beforeRows[rowKey(row, keyIdx)] = row
+		}
+		afterKeys := map[string]bool{}
+		change := TableChange{Table: t.Name, Key: key, Ignored: ignored}
+		var upserts, deletes [][]string
+		for _, row := range after.rows {
+			k := rowKey(row, keyIdx)
+			afterKeys[k] = true
+			prev, existed := beforeRows[k]
+			switch {
+			case !existed:
+				change.Inserted++
+				upserts = append(upserts, row)
+			case rowKey(prev, comparedIdx) != rowKey(row, comparedIdx):
+				change.Updated++
+				upserts = append(upserts, row)
+			}
+		}
+		for _, row := range before.rows {
+			if !afterKeys[rowKey(row, keyIdx)] {
+				change.Deleted++
+				deletes = append(deletes, pick(row, keyIdx))
+			}
+		}
+		if len(upserts) > 0 {
+			if err := utils.WriteCSVRecords(filepath.Join(outDir, t.Name+".upsert.csv"), append([][]string{header}, upserts...)); err != nil {
+				return nil, err
+			}
+		}
+		if len(deletes) > 0 {
+			if err := utils.WriteCSVRecords(filepath.Join(outDir, t.Name+".delete.csv"), append([][]string{key}, deletes...)); err != nil {
+				return nil, err
+			}
+		}
+		if change.Inserted+change.Updated+change.Deleted > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// Apply rewrites the table CSVs in dataDir with the patch in patchDir
+// applied: deleted rows removed, updated rows replaced in place, inserted
+// rows appended. Files are replaced rather than written through, so
+// dataDir may hold symlinks into a revision.
+func Apply(patchDir string, m Manifest, dataDir string) error {
+	for _, change := range m.Tables {
+		path := filepath.Join(dataDir, change.Table+".csv")
+		base, err := readTable(path)
+		if err != nil {
+			return err
+		}
+		upserts, err := readTable(filepath.Join(patchDir, change.Table+".upsert.csv"))
+		if err != nil {
+			return err
+		}
+		deletes, err := readTable(filepath.Join(patchDir, change.Table+".delete.csv"))
+		if err != nil {
+			return err
+		}
+
+		header := base.header
+		if header == nil {
+			header = upserts.header
+		}
+		if header == nil {
+			continue // only deletes, on a table the base doesn't have
+		}
+		if upserts.header != nil && strings.Join(upserts.header, ",") != strings.Join(header, ",") {
+			return fmt.Errorf("patch %s: %s columns (%s) don't match the fixture's (%s)",
+				m.Name, change.Table, strings.Join(upserts.header, ","), strings.Join(header, ","))
+		}
+		keyIdx, err := indexes(header, change.Key)
+		if err != nil {
+			return fmt.Errorf("patch %s: %s: %w", m.Name, change.Table, err)
+		}
+
+		removed := map[string]bool{}
+		for _, row := range deletes.rows {
+			removed[strings.Join(row, "\x00")] = true
+		}
+		replaced := map[string][]string{}
+		var order []string
+		for _, row := range upserts.rows {
+			k := rowKey(row, keyIdx)
+			if _, seen := replaced[k]; !seen {
+				order = append(order, k)
+			}
+			replaced[k] = row
+		}
+
+		out := [][]string{header}
+		for _, row := range base.rows {
+			k := rowKey(row, keyIdx)
+			if removed[k] {
+				continue
+			}
+			if repl, ok := replaced[k]; ok {
+				out = append(out, repl)
+				delete(replaced, k)
+				continue
+			}
+			out = append(out, row)
+		}
+		for _, k := range order {
+			if row, ok := replaced[k]; ok {
+				out = append(out, row)
+			}
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := utils.WriteCSVRecords(path, out); err != nil {
+			return fmt.Errorf("writing %s.csv: %w", change.Table, err)
+		}
+	}
+	return nil
+}
+
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// readTable reads a CSV; a missing file is an empty table.
+func readTable(path string) (table, error) {
+	records, err := utils.ReadCSVRecords(path)
+	switch {
+	case os.IsNotExist(err):
+		return table{}, nil
+	case err != nil:
+		return table{}, err
+	case len(records) == 0:
+		return table{}, nil
+	}
+	return table{header: records[0], rows: records[1:]}, nil
+}
+
+func indexes(header, cols []string) ([]int, error) {
+	pos := make(map[string]int, len(header))
+	for i, h := range header {
+		pos[h] = i
+	}
+	out := make([]int, len(cols))
+	for i, c := range cols {
+		p, ok := pos[c]
+		if !ok {
+			return nil, fmt.Errorf("key column %s not in CSV header", c)
+		}
+		out[i] = p
+	}
+	return out, nil
+}
+
+func pick(row []string, idx []int) []string {
+	out := make([]string, len(idx))
+	for i, p := range idx {
+		if p < len(row) {
+			out[i] = row[p]
+		}
+	}
+	return out
+}
+
+func rowKey(row []string, idx []int) string {
+	return strings.Join(pick(row, idx), "\x00")
+}
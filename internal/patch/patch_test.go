@@ -0,0 +1,199 @@
+package patch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+var testSchema = utils.SchemaJSON{Tables: []utils.SchemaTable{
+	{Name: "users", Columns: []utils.SchemaColumn{
+		{Name: "id", Type: "integer", IsPrimary: boolPtr(true)},
+		{Name: "name", Type: "text"},
+	}},
+	{Name: "tags", Columns: []utils.SchemaColumn{
+		{Name: "label", Type: "text"},
+	}},
+}}
+
+func write(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+func TestDiffAndApply(t *testing.T) {
+	before, after, out := t.TempDir(), t.TempDir(), t.TempDir()
+	write(t, before, "users.csv", "id,name\n1,ann\n2,bob\n3,cy\n")
+	write(t, after, "users.csv", "id,name\n1,ann\n2,bobby\n4,dee\n")
+	write(t, before, "tags.csv", "label\nred\n")
+	write(t, after, "tags.csv", "label\nred\nblue\n")
+
+	changes, err := Diff(before, after, out, testSchema, nil)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	want := []TableChange{
+		{Table: "tags", Key: []string{"label"}, Inserted: 1},
+		{Table: "users", Key: []string{"id"}, Inserted: 1, Updated: 1, Deleted: 1},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	if got := read(t, filepath.Join(out, "users.delete.csv")); got != "id\n3\n" {
+		t.Errorf("users.delete.csv = %q", got)
+	}
+
+	// Applying to the "before" data reproduces the "after" data; a base
+	// with an extra row keeps it.
+	data := t.TempDir()
+	write(t, data, "users.csv", "id,name\n1,ann\n2,bob\n3,cy\n5,eve\n")
+	write(t, data, "tags.csv", "label\nred\n")
+	if err := Apply(out, Manifest{Name: "p", Tables: changes}, data); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got := read(t, filepath.Join(data, "users.csv")); got != "id,name\n1,ann\n2,bobby\n5,eve\n4,dee\n" {
+		t.Errorf("users.csv = %q", got)
+	}
+	if got := read(t, filepath.Join(data, "tags.csv")); got != "label\nred\nblue\n" {
+		t.Errorf("tags.csv = %q", got)
+	}
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	before, after, out := t.TempDir(), t.TempDir(), t.TempDir()
+	write(t, before, "users.csv", "id,name\n1,ann\n")
+	write(t, after, "users.csv", "id,name\n1,ann\n")
+	changes, err := Diff(before, after, out, testSchema, nil)
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("Diff = %+v, %v; want no changes", changes, err)
+	}
+	entries, _ := os.ReadDir(out)
+	if len(entries) != 0 {
+		t.Errorf("wrote %d files for an unchanged database", len(entries))
+	}
+}
+
+func TestDiffIgnoresColumns(t *testing.T) {
+	schema := utils.SchemaJSON{Tables: []utils.SchemaTable{
+		{Name: "users", Columns: []utils.SchemaColumn{
+			{Name: "id", Type: "integer", IsPrimary: boolPtr(true)},
+			{Name: "name", Type: "text"},
+			{Name: "updated_at", Type: "timestamp"},
+		}},
+		{Name: "events", Columns: []utils.SchemaColumn{
+			{Name: "id", Type: "integer", IsPrimary: boolPtr(true)},
+			{Name: "kind", Type: "text"},
+		}},
+	}}
+	before, after, out := t.TempDir(), t.TempDir(), t.TempDir()
+	write(t, before, "users.csv", "id,name,updated_at\n1,ann,t1\n2,bob,t1\n")
+	write(t, after, "users.csv", "id,name,updated_at\n1,ann,t2\n2,bobby,t2\n")
+	// The same event got a new auto ID; it isn't a delete plus an insert.
+	write(t, before, "events.csv", "id,kind\n7,login\n")
+	write(t, after, "events.csv", "id,kind\n8,login\n9,logout\n")
+
+	ignore := func(table, column string) bool {
+		return column == "updated_at" || table+"."+column == "events.id"
+	}
+	changes, err := Diff(before, after, out, schema, ignore)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	want := []TableChange{
+		{Table: "events", Key: []string{"kind"}, Ignored: []string{"id"}, Inserted: 1},
+		{Table: "users", Key: []string{"id"}, Ignored: []string{"updated_at"}, Updated: 1},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	if got := read(t, filepath.Join(out, "users.upsert.csv")); got != "id,name,updated_at\n2,bobby,t2\n" {
+		t.Errorf("users.upsert.csv = %q", got)
+	}
+}
+
+func TestDiffRejectsColumnChange(t *testing.T) {
+	before, after := t.TempDir(), t.TempDir()
+	write(t, before, "users.csv", "id,name\n1,ann\n")
+	write(t, after, "users.csv", "id,name,age\n1,ann,3\n")
+	if _, err := Diff(before, after, t.TempDir(), testSchema, nil); err == nil {
+		t.Fatal("expected an error when columns change")
+	}
+}
+
+func TestApplyReplacesSymlink(t *testing.T) {
+	rev, patchDir, data := t.TempDir(), t.TempDir(), t.TempDir()
+	write(t, rev, "users.csv", "id,name\n1,ann\n")
+	if err := os.Symlink(filepath.Join(rev, "users.csv"), filepath.Join(data, "users.csv")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	write(t, patchDir, "users.upsert.csv", "id,name\n1,zed\n")
+	m := Manifest{Name: "p", Tables: []TableChange{{Table: "users", Key: []string{"id"}, Updated: 1}}}
+	if err := Apply(patchDir, m, data); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got := read(t, filepath.Join(rev, "users.csv")); got != "id,name\n1,ann\n" {
+		t.Errorf("revision file written through: %q", got)
+	}
+	if got := read(t, filepath.Join(data, "users.csv")); got != "id,name\n1,zed\n" {
+		t.Errorf("users.csv = %q", got)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, ok := range []string{"refund-flow", "v1.2", "a_b"} {
+		if err := ValidateName(ok); err != nil {
+			t.Errorf("ValidateName(%q) = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "../x", "a/b", ".hidden"} {
+		if err := ValidateName(bad); err == nil {
+			t.Errorf("ValidateName(%q) accepted", bad)
+		}
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	base, layer := t.TempDir(), t.TempDir()
+	write(t, base, "users.csv", "id,name\n1,ann\n2,bob\n")
+	write(t, layer, "users.csv", "name,id\nbobby,2\ndee,4\n")
+	write(t, layer, "tags.csv", "label\nbilling\n")
+
+	changes, err := Overlay(layer, base, testSchema)
+	if err != nil {
+		t.Fatalf("Overlay: %v", err)
+	}
+	want := []TableChange{
+		{Table: "tags", Key: []string{"label"}, Inserted: 1},
+		{Table: "users", Key: []string{"id"}, Inserted: 1, Updated: 1},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	if got := read(t, filepath.Join(base, "users.csv")); got != "id,name\n1,ann\n2,bobby\n4,dee\n" {
+		t.Errorf("users.csv = %q", got)
+	}
+	if got := read(t, filepath.Join(base, "tags.csv")); got != "label\nbilling\n" {
+		t.Errorf("tags.csv = %q", got)
+	}
+
+	write(t, layer, "users.csv", "id,name,email\n5,eve,eve@example.com\n")
+	if _, err := Overlay(layer, base, testSchema); err == nil {
+		t.Error("a layer with an extra column should fail")
+	}
+}
@@ -0,0 +1,385 @@
+// Package repair fixes orphaned foreign key references in CSV fixtures:
+// cells that name a parent row the fixture doesn't have. Hand-edited
+// fixtures drift into that state easily, and otherwise it only shows up
+// as a constraint error halfway through a seed.
+//
+// Three strategies exist:
+//
+//	delete              drop the rows holding an orphan, and then the rows
+//	                    that referenced those, until none are left
+//	nullify             set the orphaned cells to NULL
+//	synthesize-parent   add the missing parent rows, with made-up values
+//	                    for their other columns
+//
+// A reference is only checked when the parent table has a CSV: a
+// partial revision leaves some parents to the database. NULLs, @env
+// markers and SQL expression cells are never orphans.
+package repair
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+	"github.com/KazanKK/seedmancer/internal/envmarker"
+	"github.com/KazanKK/seedmancer/internal/starter"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Strategy is how Repair fixes an orphan.
+type Strategy string
+
+const (
+	Delete           Strategy = "delete"
+	Nullify          Strategy = "nullify"
+	SynthesizeParent Strategy = "synthesize-parent"
+)
+
+// ParseStrategy validates a --strategy value.
+func ParseStrategy(s string) (Strategy, error) {
+	switch st := Strategy(strings.TrimSpace(s)); st {
+	case Delete, Nullify, SynthesizeParent:
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown strategy %q (want delete, nullify or synthesize-parent)", s)
+}
+
+// Orphans counts the orphaned cells of one foreign key column.
+type Orphans struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	// Parent is the referenced "table.column".
+	Parent string `json:"parent"`
+	Count  int    `json:"count"`
+}
+
+// Report is what Repair found and changed.
+type Report struct {
+	// Orphans are the references that were broken before the repair,
+	// sorted by table and column. Rows removed by delete's cascade are
+	// counted under Deleted, not here.
+	Orphans []Orphans `json:"orphans"`
+	// Deleted is the number of rows delete removed per table, and Added
+	// the parent rows synthesize-parent made up.
+	Deleted map[string]int `json:"deleted,omitempty"`
+	Added   map[string]int `json:"added,omitempty"`
+}
+
+// Find returns the orphaned references in tables (CSV records keyed by
+// table name, header first).
+func Find(schema utils.SchemaJSON, tables map[string][][]string) []Orphans {
+	return find(schema, tables, keySets(schema, tables))
+}
+
+// Repair fixes the orphaned references in tables in place with strategy.
+// tables maps a table name to its CSV records, header first.
+func Repair(schema utils.SchemaJSON, tables map[string][][]string, strategy Strategy) (Report, error) {
+	report := Report{Orphans: Find(schema, tables)}
+	if len(report.Orphans) == 0 {
+		return report, nil
+	}
+	switch strategy {
+	case Delete:
+		report.Deleted = deleteOrphans(schema, tables)
+	case Nullify:
+		if err := nullify(schema, tables); err != nil {
+			return Report{}, err
+		}
+	case SynthesizeParent:
+		added, err := synthesize(schema, tables)
+		if err != nil {
+			return Report{}, err
+		}
+		report.Added = added
+	default:
+		return Report{}, fmt.Errorf("unknown strategy %q", strategy)
+	}
+	return report, nil
+}
+
+// reference is one foreign key column of a table with a CSV whose
+// parent table has one too.
+type reference struct {
+	table, column string
+	col           utils.SchemaColumn
+	index         int // of column in the CSV header
+	parent        utils.SchemaForeignKey
+}
+
+func references(schema utils.SchemaJSON, tables map[string][][]string) []reference {
+	var refs []reference
+	for _, t := range schema.Tables {
+		records := tables[t.Name]
+		if len(records) == 0 {
+			continue
+		}
+		for _, c := range t.Columns {
+			if c.ForeignKey == nil || len(tables[c.ForeignKey.Table]) == 0 {
+				continue
+			}
+			if i := indexOf(records[0], c.Name); i >= 0 {
+				refs = append(refs, reference{table: t.Name, column: c.Name, col: c, index: i, parent: *c.ForeignKey})
+			}
+		}
+	}
+	return refs
+}
+
+// keySets returns the values of every referenced column, keyed by
+// "table.column".
+func keySets(schema utils.SchemaJSON, tables map[string][][]string) map[string]map[string]bool {
+	keys := map[string]map[string]bool{}
+	for _, ref := range references(schema, tables) {
+		name := ref.parent.Table + "." + ref.parent.Column
+		if keys[name] != nil {
+			continue
+		}
+		set := map[string]bool{}
+		records := tables[ref.parent.Table]
+		if i := indexOf(records[0], ref.parent.Column); i >= 0 {
+			for _, rec := range records[1:] {
+				if i < len(rec) {
+					set[rec[i]] = true
+				}
+			}
+		}
+		keys[name] = set
+	}
+	return keys
+}
+
+func find(schema utils.SchemaJSON, tables map[string][][]string, keys map[string]map[string]bool) []Orphans {
+	var out []Orphans
+	for _, ref := range references(schema, tables) {
+		parent := ref.parent.Table + "." + ref.parent.Column
+		n := 0
+		for _, rec := range tables[ref.table][1:] {
+			if isOrphan(rec, ref.index, keys[parent]) {
+				n++
+			}
+		}
+		if n > 0 {
+			out = append(out, Orphans{Table: ref.table, Column: ref.column, Parent: parent, Count: n})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Table != out[j].Table {
+			return out[i].Table < out[j].Table
+		}
+		return out[i].Column < out[j].Column
+	})
+	return out
+}
+
+func isOrphan(rec []string, i int, keys map[string]bool) bool {
+	if i >= len(rec) {
+		return false
+	}
+	cell := rec[i]
+	if cell == "" || cell == "NULL" || cell == "null" || envmarker.IsMarker(cell) || cellexpr.Is(cell) {
+		return false
+	}
+	return !keys[cell]
+}
+
+// deleteOrphans drops the rows holding an orphan until none are left,
+// since a dropped parent row orphans its children in turn.
+func deleteOrphans(schema utils.SchemaJSON, tables map[string][][]string) map[string]int {
+	deleted := map[string]int{}
+	for {
+		keys := keySets(schema, tables)
+		removed := false
+		for _, ref := range references(schema, tables) {
+			records := tables[ref.table]
+			kept := records[:1]
+			for _, rec := range records[1:] {
+				if isOrphan(rec, ref.index, keys[ref.parent.Table+"."+ref.parent.Column]) {
+					deleted[ref.table]++
+					removed = true
+					continue
+				}
+				kept = append(kept, rec)
+			}
+			tables[ref.table] = kept
+		}
+		if !removed {
+			return deleted
+		}
+	}
+}
+
+func nullify(schema utils.SchemaJSON, tables map[string][][]string) error {
+	keys := keySets(schema, tables)
+	refs := references(schema, tables)
+	for _, ref := range refs {
+		if ref.col.Nullable != nil && *ref.col.Nullable {
+			continue
+		}
+		for _, rec := range tables[ref.table][1:] {
+			if isOrphan(rec, ref.index, keys[ref.parent.Table+"."+ref.parent.Column]) {
+				return fmt.Errorf("%s.%s is NOT NULL and can't be nullified; use --strategy delete or synthesize-parent", ref.table, ref.column)
+			}
+		}
+	}
+	for _, ref := range refs {
+		for _, rec := range tables[ref.table][1:] {
+			if isOrphan(rec, ref.index, keys[ref.parent.Table+"."+ref.parent.Column]) {
+				rec[ref.index] = "NULL"
+			}
+		}
+	}
+	return nil
+}
+
+// synthesize appends a row to each parent table for every key its
+// children reference but it lacks. The key column gets the referenced
+// value; the other columns get starter values, with foreign keys pointing
+// at the first row of their own parent and unique values that are taken
+// already suffixed with the key.
+func synthesize(schema utils.SchemaJSON, tables map[string][][]string) (map[string]int, error) {
+	keys := keySets(schema, tables)
+	missing := map[string][]string{} // parent table → keys, in order found
+	seen := map[string]bool{}
+	for _, ref := range references(schema, tables) {
+		for _, rec := range tables[ref.table][1:] {
+			if !isOrphan(rec, ref.index, keys[ref.parent.Table+"."+ref.parent.Column]) {
+				continue
+			}
+			if ref.parent.Table == ref.table {
+				return nil, fmt.Errorf("%s.%s references its own table; use --strategy delete or nullify", ref.table, ref.column)
+			}
+			if pk := primaryKey(schemaTable(schema, ref.parent.Table)); len(pk) != 1 || pk[0] != ref.parent.Column {
+				return nil, fmt.Errorf("%s.%s references %s.%s, which is not the primary key of %s; use --strategy delete or nullify",
+					ref.table, ref.column, ref.parent.Table, ref.parent.Column, ref.parent.Table)
+			}
+			if id := ref.parent.Table + "\x00" + rec[ref.index]; !seen[id] {
+				seen[id] = true
+				missing[ref.parent.Table] = append(missing[ref.parent.Table], rec[ref.index])
+			}
+		}
+	}
+
+	added := map[string]int{}
+	for _, name := range sortedKeys(missing) {
+		values := missing[name]
+		parent := schemaTable(schema, name)
+		rows, err := madeUpRows(schema, parent, tables, len(values))
+		if err != nil {
+			return nil, err
+		}
+		records := tables[name]
+		header := records[0]
+		pk := primaryKey(parent)[0]
+		for i, key := range values {
+			rec := make([]string, len(header))
+			for j, column := range header {
+				col, ok := schemaColumn(parent, column)
+				switch {
+				case !ok:
+					rec[j] = "NULL"
+				case column == pk:
+					rec[j] = key
+				case col.ForeignKey != nil:
+					rec[j] = firstValue(tables, *col.ForeignKey)
+					if rec[j] == "" {
+						if col.Nullable == nil || !*col.Nullable {
+							return nil, fmt.Errorf("can't make up a %s row: %s.%s needs a %s.%s row to reference", name, name, column, col.ForeignKey.Table, col.ForeignKey.Column)
+						}
+						rec[j] = "NULL"
+					}
+				default:
+					rec[j] = rows[name][1+i][indexOf(rows[name][0], column)]
+					if col.IsUnique != nil && *col.IsUnique && columnHas(records, j, rec[j]) {
+						rec[j] += "-" + key
+					}
+				}
+			}
+			records = append(records, rec)
+		}
+		tables[name] = records
+		added[name] = len(values)
+	}
+	return added, nil
+}
+
+// madeUpRows returns n starter rows for table, generated on its own with
+// its foreign keys dropped: synthesize fills those from real rows.
+func madeUpRows(schema utils.SchemaJSON, table utils.SchemaTable, tables map[string][][]string, n int) (map[string][][]string, error) {
+	solo := utils.SchemaTable{Name: table.Name, Columns: make([]utils.SchemaColumn, len(table.Columns))}
+	for i, c := range table.Columns {
+		c.ForeignKey = nil
+		solo.Columns[i] = c
+	}
+	rows, err := starter.Generate(utils.SchemaJSON{DatabaseType: schema.DatabaseType, Enums: schema.Enums, Tables: []utils.SchemaTable{solo}}, n, starter.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("making up %s rows: %w", table.Name, err)
+	}
+	return rows, nil
+}
+
+func firstValue(tables map[string][][]string, fk utils.SchemaForeignKey) string {
+	records := tables[fk.Table]
+	if len(records) < 2 {
+		return ""
+	}
+	if i := indexOf(records[0], fk.Column); i >= 0 && i < len(records[1]) {
+		return records[1][i]
+	}
+	return ""
+}
+
+func columnHas(records [][]string, i int, value string) bool {
+	for _, rec := range records[1:] {
+		if i < len(rec) && rec[i] == value {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaTable(schema utils.SchemaJSON, name string) utils.SchemaTable {
+	for _, t := range schema.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return utils.SchemaTable{Name: name}
+}
+
+func schemaColumn(t utils.SchemaTable, name string) (utils.SchemaColumn, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return utils.SchemaColumn{}, false
+}
+
+func primaryKey(t utils.SchemaTable) []string {
+	var pk []string
+	for _, c := range t.Columns {
+		if c.IsPrimary != nil && *c.IsPrimary {
+			pk = append(pk, c.Name)
+		}
+	}
+	return pk
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
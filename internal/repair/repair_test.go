@@ -0,0 +1,118 @@
+package repair
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+const schemaJSON = `{"tables":[
+  {"name":"users","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"email","type":"text","isUnique":true}
+  ]},
+  {"name":"orders","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}},
+    {"name":"coupon_id","type":"integer","nullable":true,"foreignKey":{"table":"coupons","column":"id"}}
+  ]},
+  {"name":"items","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"order_id","type":"integer","foreignKey":{"table":"orders","column":"id"}}
+  ]}
+]}`
+
+func fixture(t *testing.T) (utils.SchemaJSON, map[string][][]string) {
+	t.Helper()
+	var s utils.SchemaJSON
+	if err := json.Unmarshal([]byte(schemaJSON), &s); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return s, map[string][][]string{
+		"users":  {{"id", "email"}, {"1", "a@example.com"}},
+		"orders": {{"id", "user_id", "coupon_id"}, {"10", "1", "7"}, {"11", "2", "NULL"}, {"12", "@env:USER_ID", "NULL"}},
+		"items":  {{"id", "order_id"}, {"100", "10"}, {"101", "11"}, {"102", "99"}},
+	}
+}
+
+func TestFind(t *testing.T) {
+	schema, tables := fixture(t)
+	got := fmt.Sprint(Find(schema, tables))
+	// coupon_id isn't checked: coupons has no CSV.
+	if want := "[{items order_id orders.id 1} {orders user_id users.id 1}]"; got != want {
+		t.Fatalf("Find = %s, want %s", got, want)
+	}
+}
+
+func TestRepair_deleteCascades(t *testing.T) {
+	schema, tables := fixture(t)
+	report, err := Repair(schema, tables, Delete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Order 11 goes for its missing user, and item 101 with it.
+	if fmt.Sprint(report.Deleted) != "map[items:2 orders:1]" {
+		t.Fatalf("Deleted = %v", report.Deleted)
+	}
+	if len(tables["orders"]) != 3 || len(tables["items"]) != 2 || tables["items"][1][0] != "100" {
+		t.Fatalf("left orders %v, items %v", tables["orders"], tables["items"])
+	}
+	if len(Find(schema, tables)) != 0 {
+		t.Fatal("orphans left after delete")
+	}
+}
+
+func TestRepair_nullify(t *testing.T) {
+	schema, tables := fixture(t)
+	if _, err := Repair(schema, tables, Nullify); err == nil || !strings.Contains(err.Error(), "NOT NULL") {
+		t.Fatalf("err = %v, want NOT NULL columns refused", err)
+	}
+
+	for _, tb := range schema.Tables {
+		for i := range tb.Columns {
+			yes := true
+			tb.Columns[i].Nullable = &yes
+		}
+	}
+	if _, err := Repair(schema, tables, Nullify); err != nil {
+		t.Fatal(err)
+	}
+	if tables["orders"][2][1] != "NULL" || tables["items"][3][1] != "NULL" || tables["orders"][1][1] != "1" {
+		t.Fatalf("orders %v, items %v", tables["orders"], tables["items"])
+	}
+}
+
+func TestRepair_synthesizeParent(t *testing.T) {
+	schema, tables := fixture(t)
+	tables["users"] = append(tables["users"], []string{"3", "ada1@example.com"})
+	report, err := Repair(schema, tables, SynthesizeParent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(report.Added) != "map[orders:1 users:1]" {
+		t.Fatalf("Added = %v", report.Added)
+	}
+	users := tables["users"]
+	if got := users[len(users)-1]; got[0] != "2" || got[1] == "" || got[1] == "ada1@example.com" {
+		t.Fatalf("made-up user = %v, want id 2 and an unused email", got)
+	}
+	orders := tables["orders"]
+	if got := orders[len(orders)-1]; got[0] != "99" || got[1] != "1" {
+		t.Fatalf("made-up order = %v, want id 99 referencing user 1", got)
+	}
+	if len(Find(schema, tables)) != 0 {
+		t.Fatal("orphans left after synthesize-parent")
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	if s, err := ParseStrategy("synthesize-parent"); err != nil || s != SynthesizeParent {
+		t.Fatalf("ParseStrategy = %q, %v", s, err)
+	}
+	if _, err := ParseStrategy("drop"); err == nil {
+		t.Fatal("want an error for an unknown strategy")
+	}
+}
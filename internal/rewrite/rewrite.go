@@ -0,0 +1,238 @@
+// Package rewrite batch-edits column values in CSV fixtures. Rules are
+// checked against schema.json before anything is touched, so a typo in a
+// column name or a value that the column type can't hold fails up front
+// instead of surfacing as a COPY error at seed time.
+//
+// Two rule kinds exist:
+//
+//	--set users.environment=staging      every value in the column
+//	--map orders.currency:USD=EUR        only cells equal to USD
+//
+// The literal NULL stands for SQL NULL, matching the export format.
+package rewrite
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Rule is one parsed --set or --map. For a set rule every value in
+// Table.Column becomes To; for a map rule only cells equal to From do.
+type Rule struct {
+	Table  string
+	Column string
+	Set    bool
+	From   string
+	To     string
+}
+
+// String renders the rule back in flag syntax for messages.
+func (r Rule) String() string {
+	if r.Set {
+		return fmt.Sprintf("%s.%s=%s", r.Table, r.Column, r.To)
+	}
+	return fmt.Sprintf("%s.%s:%s=%s", r.Table, r.Column, r.From, r.To)
+}
+
+// splitTarget parses "table.column". The table part may itself contain
+// dots (schema-qualified names), so the column is taken after the last one.
+func splitTarget(s string) (string, string, error) {
+	i := strings.LastIndex(s, ".")
+	if i <= 0 || i == len(s)-1 {
+		return "", "", fmt.Errorf("expected <table>.<column>, got %q", s)
+	}
+	return s[:i], s[i+1:], nil
+}
+
+// ParseSet parses "table.column=value".
+func ParseSet(s string) (Rule, error) {
+	target, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid --set %q (want table.column=value)", s)
+	}
+	table, column, err := splitTarget(strings.TrimSpace(target))
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid --set %q: %w", s, err)
+	}
+	return Rule{Table: table, Column: column, Set: true, To: value}, nil
+}
+
+// ParseMap parses "table.column:from=to".
+func ParseMap(s string) (Rule, error) {
+	target, pair, ok := strings.Cut(s, ":")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid --map %q (want table.column:from=to)", s)
+	}
+	from, to, ok := strings.Cut(pair, "=")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid --map %q (want table.column:from=to)", s)
+	}
+	table, column, err := splitTarget(strings.TrimSpace(target))
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid --map %q: %w", s, err)
+	}
+	return Rule{Table: table, Column: column, From: from, To: to}, nil
+}
+
+// Check validates every rule against schema: the column must exist, the
+// new value must fit its type (and enum, if any), NULL is only allowed on
+// nullable columns, and a --set may not target a primary key or unique
+// column since it would give every row the same value.
+func Check(schema utils.SchemaJSON, rules []Rule) error {
+	enums := map[string][]string{}
+	for _, e := range schema.Enums {
+		enums[e.Name] = e.Values
+	}
+	tables := map[string]utils.SchemaTable{}
+	for _, t := range schema.Tables {
+		tables[t.Name] = t
+	}
+
+	for _, r := range rules {
+		t, ok := tables[r.Table]
+		if !ok {
+			return fmt.Errorf("%s: table %q is not in schema.json", r, r.Table)
+		}
+		var col *utils.SchemaColumn
+		for i := range t.Columns {
+			if t.Columns[i].Name == r.Column {
+				col = &t.Columns[i]
+				break
+			}
+		}
+		if col == nil {
+			return fmt.Errorf("%s: table %q has no column %q", r, r.Table, r.Column)
+		}
+		if r.Set && (isTrue(col.IsPrimary) || isTrue(col.IsUnique)) {
+			return fmt.Errorf("%s: %s.%s is a primary key / unique column; --set would duplicate it across rows", r, r.Table, r.Column)
+		}
+		if r.To == "NULL" {
+			if !isTrue(col.Nullable) {
+				return fmt.Errorf("%s: %s.%s is NOT NULL", r, r.Table, r.Column)
+			}
+			continue
+		}
+		if strings.HasPrefix(r.To, "@env:") {
+			continue // resolved per environment at seed time
+		}
+		if err := checkValue(r.To, col.Type); err != nil {
+			return fmt.Errorf("%s: %w", r, err)
+		}
+		if name := enumName(col.Enum); name != "" {
+			if values, ok := enums[name]; ok && !contains(values, r.To) {
+				return fmt.Errorf("%s: %q is not a value of enum %s (%s)", r, r.To, name, strings.Join(values, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Apply runs the rules for table over a parsed CSV (row 0 is the header)
+// and returns a new record set plus the number of cells changed per rule,
+// in rule order. Rules for other tables are ignored.
+func Apply(records [][]string, table string, rules []Rule) ([][]string, []int, error) {
+	counts := make([]int, len(rules))
+	if len(records) == 0 {
+		return records, counts, nil
+	}
+	header := records[0]
+	index := map[string]int{}
+	for i, h := range header {
+		index[h] = i
+	}
+	out := make([][]string, len(records))
+	out[0] = header
+	for r := 1; r < len(records); r++ {
+		out[r] = append([]string(nil), records[r]...)
+	}
+	for ri, rule := range rules {
+		if rule.Table != table {
+			continue
+		}
+		ci, ok := index[rule.Column]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: column %q is not in %s.csv", rule, rule.Column, table)
+		}
+		for r := 1; r < len(out); r++ {
+			if ci >= len(out[r]) {
+				continue
+			}
+			cell := out[r][ci]
+			if !rule.Set && cell != rule.From {
+				continue
+			}
+			if cell != rule.To {
+				out[r][ci] = rule.To
+				counts[ri]++
+			}
+		}
+	}
+	return out, counts, nil
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// checkValue reports whether v can be stored in a column of colType. Types
+// it doesn't recognise accept anything — the database is the final judge.
+func checkValue(v, colType string) error {
+	t := strings.ToLower(strings.TrimSpace(colType))
+	if i := strings.Index(t, "("); i > 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+	switch t {
+	case "smallint", "integer", "int", "int2", "int4", "int8", "bigint",
+		"serial", "bigserial", "smallserial", "tinyint", "mediumint":
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("%q is not an integer (column type %s)", v, colType)
+		}
+	case "numeric", "decimal", "real", "double precision", "double", "float", "float4", "float8":
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("%q is not a number (column type %s)", v, colType)
+		}
+	case "boolean", "bool":
+		switch strings.ToLower(v) {
+		case "t", "f", "true", "false", "1", "0", "yes", "no":
+		default:
+			return fmt.Errorf("%q is not a boolean (column type %s)", v, colType)
+		}
+	case "uuid":
+		if !uuidRe.MatchString(v) {
+			return fmt.Errorf("%q is not a UUID", v)
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return fmt.Errorf("%q is not a date (want YYYY-MM-DD)", v)
+		}
+	case "json", "jsonb":
+		if !json.Valid([]byte(v)) {
+			return fmt.Errorf("%q is not valid JSON", v)
+		}
+	}
+	return nil
+}
+
+// enumName extracts the enum type name from the lenient raw "enum" field.
+func enumName(raw json.RawMessage) string {
+	var s string
+	if len(raw) == 0 || json.Unmarshal(raw, &s) != nil {
+		return ""
+	}
+	return s
+}
+
+func isTrue(b *bool) bool { return b != nil && *b }
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+const schemaJSON = `{"enums":[{"name":"env_kind","values":["local","staging","prod"]}],"tables":[
+  {"name":"users","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"environment","type":"USER-DEFINED","enum":"env_kind"},
+    {"name":"age","type":"integer","nullable":true},
+    {"name":"email","type":"text","isUnique":true}
+  ]},
+  {"name":"orders","columns":[
+    {"name":"id","type":"integer","isPrimary":true},
+    {"name":"currency","type":"text"}
+  ]}
+]}`
+
+func loadSchema(t *testing.T) utils.SchemaJSON {
+	t.Helper()
+	var s utils.SchemaJSON
+	if err := json.Unmarshal([]byte(schemaJSON), &s); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	r, err := ParseSet("users.environment=staging")
+	if err != nil || r.Table != "users" || r.Column != "environment" || !r.Set || r.To != "staging" {
+		t.Fatalf("ParseSet = %+v, %v", r, err)
+	}
+	r, err = ParseMap("public.orders.currency:USD=EUR")
+	if err != nil || r.Table != "public.orders" || r.Column != "currency" || r.From != "USD" || r.To != "EUR" {
+		t.Fatalf("ParseMap = %+v, %v", r, err)
+	}
+	for _, bad := range []string{"users=1", "users.=1", "nodot"} {
+		if _, err := ParseSet(bad); err == nil {
+			t.Errorf("ParseSet(%q) should fail", bad)
+		}
+	}
+	if _, err := ParseMap("orders.currency=EUR"); err == nil {
+		t.Error("ParseMap without from should fail")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := loadSchema(t)
+	ok := []string{"users.environment=staging", "users.age=NULL", "users.age=42", "orders.currency=EUR", "users.environment=@env:ENV_KIND"}
+	for _, in := range ok {
+		r, _ := ParseSet(in)
+		if err := Check(s, []Rule{r}); err != nil {
+			t.Errorf("Check(%s): %v", in, err)
+		}
+	}
+	bad := map[string]string{
+		"users.environment=qa": "not a value of enum",
+		"users.age=old":        "not an integer",
+		"users.email=x@y.z":    "unique",
+		"orders.currency=NULL": "NOT NULL",
+		"users.nope=1":         "no column",
+		"ghosts.id=1":          "not in schema.json",
+	}
+	for in, want := range bad {
+		r, _ := ParseSet(in)
+		err := Check(s, []Rule{r})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Check(%s) = %v, want error containing %q", in, err, want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	records := [][]string{
+		{"id", "currency"},
+		{"1", "USD"},
+		{"2", "GBP"},
+		{"3", "USD"},
+	}
+	m, _ := ParseMap("orders.currency:USD=EUR")
+	other, _ := ParseSet("users.environment=staging")
+	out, counts, err := Apply(records, "orders", []Rule{m, other})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if counts[0] != 2 || counts[1] != 0 {
+		t.Fatalf("counts = %v, want [2 0]", counts)
+	}
+	var got []string
+	for _, row := range out[1:] {
+		got = append(got, row[1])
+	}
+	if strings.Join(got, ",") != "EUR,GBP,EUR" {
+		t.Fatalf("currencies = %v", got)
+	}
+	if records[1][1] != "USD" {
+		t.Fatal("input records were mutated")
+	}
+}
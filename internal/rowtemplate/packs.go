@@ -0,0 +1,264 @@
+package rowtemplate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pack is the people, places and words generated data is drawn from.
+type Pack struct {
+	// Tag is the locale the pack is picked by, e.g. "de_DE"; empty for
+	// Default.
+	Tag        string
+	FirstNames []string
+	LastNames  []string
+	// Latin spells the names that aren't ASCII in ASCII, for email
+	// addresses and usernames.
+	Latin map[string]string
+	// FamilyFirst writes the family name first in a full name; NameSep
+	// goes between the two.
+	FamilyFirst bool
+	NameSep     string
+	Locales     []Locale
+	// Words make up titles, descriptions and other free text. WordSep
+	// goes between them and FullStop ends a sentence.
+	Words     []string
+	WordSep   string
+	FullStop  string
+	Companies []string
+}
+
+// New picks a template from the pack. pick(n) returns an index in
+// [0, n), like for the package-level New.
+func (p *Pack) New(pick func(n int) int) Template {
+	return Template{
+		First:  p.FirstNames[pick(len(p.FirstNames))],
+		Last:   p.LastNames[pick(len(p.LastNames))],
+		Locale: p.Locales[pick(len(p.Locales))],
+		Pack:   p,
+	}
+}
+
+// Sentence joins words the way the pack's language writes a sentence,
+// capitalizing the first letter where the script has one.
+func (p *Pack) Sentence(words []string) string {
+	s := strings.Join(words, p.WordSep)
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:] + p.FullStop
+}
+
+// Default is the pack used without a locale: English names and words,
+// set in cities around the world.
+var Default = &Pack{
+	FirstNames: FirstNames,
+	LastNames:  LastNames,
+	NameSep:    " ",
+	Locales:    Locales,
+	Words:      []string{"alpha", "bright", "copper", "delta", "ember", "forest", "granite", "harbor", "island", "juniper", "kestrel", "lantern", "meadow", "nimbus", "orbit", "prairie"},
+	WordSep:    " ",
+	FullStop:   ".",
+	Companies:  []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Stark Industries", "Wayne Enterprises", "Soylent"},
+}
+
+// packs are the locales Lookup knows, by tag.
+var packs = map[string]*Pack{
+	"en_US": {
+		Tag:        "en_US",
+		FirstNames: []string{"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth"},
+		LastNames:  []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez"},
+		NameSep:    " ",
+		Locales: []Locale{
+			{"Austin", "Texas", "United States", "US", "78701", "+1 512", "USD", "America/Chicago", "%d Congress Avenue"},
+			{"New York", "New York", "United States", "US", "10001", "+1 212", "USD", "America/New_York", "%d Broadway"},
+			{"Chicago", "Illinois", "United States", "US", "60601", "+1 312", "USD", "America/Chicago", "%d N Michigan Avenue"},
+			{"Seattle", "Washington", "United States", "US", "98101", "+1 206", "USD", "America/Los_Angeles", "%d Pike Street"},
+			{"Denver", "Colorado", "United States", "US", "80202", "+1 303", "USD", "America/Denver", "%d Larimer Street"},
+		},
+		Words:     Default.Words,
+		WordSep:   " ",
+		FullStop:  ".",
+		Companies: Default.Companies,
+	},
+	"en_GB": {
+		Tag:        "en_GB",
+		FirstNames: []string{"Oliver", "Amelia", "George", "Isla", "Harry", "Ava", "Jack", "Emily", "Charlie", "Sophie"},
+		LastNames:  []string{"Smith", "Jones", "Taylor", "Brown", "Williams", "Wilson", "Johnson", "Davies", "Robinson", "Wright"},
+		NameSep:    " ",
+		Locales: []Locale{
+			{"London", "England", "United Kingdom", "GB", "EC1A 1BB", "+44 20", "GBP", "Europe/London", "%d Baker Street"},
+			{"Manchester", "England", "United Kingdom", "GB", "M1 1AE", "+44 161", "GBP", "Europe/London", "%d Deansgate"},
+			{"Edinburgh", "Scotland", "United Kingdom", "GB", "EH1 1YZ", "+44 131", "GBP", "Europe/London", "%d Princes Street"},
+			{"Cardiff", "Wales", "United Kingdom", "GB", "CF10 1EP", "+44 29", "GBP", "Europe/London", "%d Queen Street"},
+		},
+		Words:     Default.Words,
+		WordSep:   " ",
+		FullStop:  ".",
+		Companies: []string{"Acme Ltd", "Globex plc", "Initech Ltd", "Umbrella plc"},
+	},
+	"de_DE": {
+		Tag:        "de_DE",
+		FirstNames: []string{"Jürgen", "Renate", "Günter", "Ursula", "Jörg", "Käthe", "Matthias", "Sabine", "Björn", "Lena"},
+		LastNames:  []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weiß", "Köhler", "Schäfer", "Groß", "Becker", "Wagner"},
+		Latin: map[string]string{
+			"Jürgen": "Juergen", "Günter": "Guenter", "Jörg": "Joerg", "Käthe": "Kaethe", "Björn": "Bjoern",
+			"Müller": "Mueller", "Weiß": "Weiss", "Köhler": "Koehler", "Schäfer": "Schaefer", "Groß": "Gross",
+		},
+		NameSep: " ",
+		Locales: []Locale{
+			{"Berlin", "Berlin", "Deutschland", "DE", "10117", "+49 30", "EUR", "Europe/Berlin", "Friedrichstraße %d"},
+			{"München", "Bayern", "Deutschland", "DE", "80331", "+49 89", "EUR", "Europe/Berlin", "Maximilianstraße %d"},
+			{"Hamburg", "Hamburg", "Deutschland", "DE", "20095", "+49 40", "EUR", "Europe/Berlin", "Mönckebergstraße %d"},
+			{"Köln", "Nordrhein-Westfalen", "Deutschland", "DE", "50667", "+49 221", "EUR", "Europe/Berlin", "Hohe Straße %d"},
+		},
+		Words:     []string{"apfel", "brücke", "frühling", "größe", "häuser", "kühlschrank", "löwe", "märchen", "straße", "übung", "wald", "zeitung"},
+		WordSep:   " ",
+		FullStop:  ".",
+		Companies: []string{"Müller & Söhne GmbH", "Bäckerei Schäfer", "Großhandel Köhler AG", "Nordlicht KG"},
+	},
+	"fr_FR": {
+		Tag:        "fr_FR",
+		FirstNames: []string{"Jérôme", "Hélène", "François", "Amélie", "Léa", "Chloé", "Benoît", "Gaëlle", "Noël", "Élodie"},
+		LastNames:  []string{"Lefèvre", "Dubois", "Martin", "Bernard", "Moreau", "Lemaître", "Girard", "Rousseau", "Fontaine", "Mercier"},
+		Latin: map[string]string{
+			"Jérôme": "Jerome", "Hélène": "Helene", "François": "Francois", "Amélie": "Amelie", "Léa": "Lea",
+			"Chloé": "Chloe", "Benoît": "Benoit", "Gaëlle": "Gaelle", "Noël": "Noel", "Élodie": "Elodie",
+			"Lefèvre": "Lefevre", "Lemaître": "Lemaitre",
+		},
+		NameSep: " ",
+		Locales: []Locale{
+			{"Paris", "Île-de-France", "France", "FR", "75001", "+33 1", "EUR", "Europe/Paris", "%d rue de Rivoli"},
+			{"Lyon", "Auvergne-Rhône-Alpes", "France", "FR", "69001", "+33 4", "EUR", "Europe/Paris", "%d rue de la République"},
+			{"Marseille", "Provence-Alpes-Côte d'Azur", "France", "FR", "13001", "+33 4", "EUR", "Europe/Paris", "%d La Canebière"},
+			{"Lille", "Hauts-de-France", "France", "FR", "59000", "+33 3", "EUR", "Europe/Paris", "%d rue Nationale"},
+		},
+		Words:     []string{"été", "forêt", "château", "crème", "fenêtre", "hôpital", "île", "garçon", "élève", "théâtre", "rivière", "cœur"},
+		WordSep:   " ",
+		FullStop:  ".",
+		Companies: []string{"Boulangerie Lefèvre", "Château & Fils", "Éditions Rivière", "Forêt Noire SARL"},
+	},
+	"es_ES": {
+		Tag:        "es_ES",
+		FirstNames: []string{"José", "María", "Jesús", "Lucía", "Íñigo", "Begoña", "Ramón", "Inés", "Óscar", "Nuria"},
+		LastNames:  []string{"García", "Fernández", "González", "Rodríguez", "López", "Martínez", "Sánchez", "Pérez", "Gómez", "Muñoz"},
+		Latin: map[string]string{
+			"José": "Jose", "María": "Maria", "Jesús": "Jesus", "Lucía": "Lucia", "Íñigo": "Inigo",
+			"Begoña": "Begona", "Ramón": "Ramon", "Inés": "Ines", "Óscar": "Oscar",
+			"García": "Garcia", "Fernández": "Fernandez", "González": "Gonzalez", "Rodríguez": "Rodriguez",
+			"López": "Lopez", "Martínez": "Martinez", "Sánchez": "Sanchez", "Pérez": "Perez", "Gómez": "Gomez", "Muñoz": "Munoz",
+		},
+		NameSep: " ",
+		Locales: []Locale{
+			{"Madrid", "Comunidad de Madrid", "España", "ES", "28013", "+34 91", "EUR", "Europe/Madrid", "Calle Mayor %d"},
+			{"Barcelona", "Cataluña", "España", "ES", "08002", "+34 93", "EUR", "Europe/Madrid", "Passeig de Gràcia %d"},
+			{"Sevilla", "Andalucía", "España", "ES", "41004", "+34 95", "EUR", "Europe/Madrid", "Calle Sierpes %d"},
+			{"Valencia", "Comunidad Valenciana", "España", "ES", "46002", "+34 96", "EUR", "Europe/Madrid", "Calle de Colón %d"},
+		},
+		Words:     []string{"año", "niño", "mañana", "corazón", "canción", "jardín", "montaña", "árbol", "café", "pequeño", "ciudad", "señal"},
+		WordSep:   " ",
+		FullStop:  ".",
+		Companies: []string{"Panadería Muñoz", "Construcciones Pérez S.L.", "Café del Jardín", "Montaña Azul S.A."},
+	},
+	"pt_BR": {
+		Tag:        "pt_BR",
+		FirstNames: []string{"João", "Maria", "José", "Ana", "Antônio", "Conceição", "Sebastião", "Luíza", "Fábio", "Débora"},
+		LastNames:  []string{"Silva", "Santos", "Oliveira", "Souza", "Gonçalves", "Araújo", "Pereira", "Lima", "Magalhães", "Brandão"},
+		Latin: map[string]string{
+			"João": "Joao", "José": "Jose", "Antônio": "Antonio", "Conceição": "Conceicao", "Sebastião": "Sebastiao",
+			"Luíza": "Luiza", "Fábio": "Fabio", "Débora": "Debora",
+			"Gonçalves": "Goncalves", "Araújo": "Araujo", "Magalhães": "Magalhaes", "Brandão": "Brandao",
+		},
+		NameSep: " ",
+		Locales: []Locale{
+			{"São Paulo", "São Paulo", "Brasil", "BR", "01310-100", "+55 11", "BRL", "America/Sao_Paulo", "Avenida Paulista, %d"},
+			{"Rio de Janeiro", "Rio de Janeiro", "Brasil", "BR", "20040-002", "+55 21", "BRL", "America/Sao_Paulo", "Rua do Ouvidor, %d"},
+			{"Belo Horizonte", "Minas Gerais", "Brasil", "BR", "30130-010", "+55 31", "BRL", "America/Sao_Paulo", "Avenida Afonso Pena, %d"},
+			{"Salvador", "Bahia", "Brasil", "BR", "40020-000", "+55 71", "BRL", "America/Bahia", "Rua Chile, %d"},
+		},
+		Words:     []string{"coração", "pão", "maçã", "açúcar", "informação", "avião", "irmã", "ônibus", "café", "canção", "manhã", "lição"},
+		WordSep:   " ",
+		FullStop:  ".",
+		Companies: []string{"Padaria Conceição", "Magalhães & Filhos Ltda.", "Açúcar Doce S.A.", "Avião Azul Ltda."},
+	},
+	"ja_JP": {
+		Tag:        "ja_JP",
+		FirstNames: []string{"太郎", "花子", "翔太", "美咲", "健一", "陽菜", "大輔", "結衣", "拓也", "さくら"},
+		LastNames:  []string{"佐藤", "鈴木", "高橋", "田中", "渡辺", "伊藤", "山本", "中村", "小林", "加藤"},
+		Latin: map[string]string{
+			"太郎": "Taro", "花子": "Hanako", "翔太": "Shota", "美咲": "Misaki", "健一": "Kenichi",
+			"陽菜": "Hina", "大輔": "Daisuke", "結衣": "Yui", "拓也": "Takuya", "さくら": "Sakura",
+			"佐藤": "Sato", "鈴木": "Suzuki", "高橋": "Takahashi", "田中": "Tanaka", "渡辺": "Watanabe",
+			"伊藤": "Ito", "山本": "Yamamoto", "中村": "Nakamura", "小林": "Kobayashi", "加藤": "Kato",
+		},
+		FamilyFirst: true,
+		Locales: []Locale{
+			{"東京", "東京都", "日本", "JP", "100-0005", "+81 3", "JPY", "Asia/Tokyo", "千代田区丸の内%d-1"},
+			{"大阪", "大阪府", "日本", "JP", "530-0001", "+81 6", "JPY", "Asia/Tokyo", "北区梅田%d-1"},
+			{"京都", "京都府", "日本", "JP", "600-8216", "+81 75", "JPY", "Asia/Tokyo", "下京区烏丸通%d"},
+			{"札幌", "北海道", "日本", "JP", "060-0001", "+81 11", "JPY", "Asia/Tokyo", "中央区北1条西%d"},
+		},
+		Words:     []string{"桜", "富士山", "電車", "図書館", "天気", "会議", "季節", "料理", "写真", "旅行", "音楽", "時間"},
+		FullStop:  "。",
+		Companies: []string{"株式会社さくら", "山田商事株式会社", "富士電機工業", "東海物産"},
+	},
+	"zh_CN": {
+		Tag:        "zh_CN",
+		FirstNames: []string{"伟", "芳", "娜", "敏", "静", "磊", "洋", "艳", "勇", "杰"},
+		LastNames:  []string{"王", "李", "张", "刘", "陈", "杨", "黄", "赵", "吴", "周"},
+		Latin: map[string]string{
+			"伟": "Wei", "芳": "Fang", "娜": "Na", "敏": "Min", "静": "Jing",
+			"磊": "Lei", "洋": "Yang", "艳": "Yan", "勇": "Yong", "杰": "Jie",
+			"王": "Wang", "李": "Li", "张": "Zhang", "刘": "Liu", "陈": "Chen",
+			"杨": "Yang", "黄": "Huang", "赵": "Zhao", "吴": "Wu", "周": "Zhou",
+		},
+		FamilyFirst: true,
+		Locales: []Locale{
+			{"北京", "北京市", "中国", "CN", "100006", "+86 10", "CNY", "Asia/Shanghai", "东城区王府井大街%d号"},
+			{"上海", "上海市", "中国", "CN", "200001", "+86 21", "CNY", "Asia/Shanghai", "黄浦区南京东路%d号"},
+			{"广州", "广东省", "中国", "CN", "510030", "+86 20", "CNY", "Asia/Shanghai", "越秀区北京路%d号"},
+			{"成都", "四川省", "中国", "CN", "610016", "+86 28", "CNY", "Asia/Shanghai", "锦江区春熙路%d号"},
+		},
+		Words:     []string{"春天", "图书馆", "火车", "天气", "会议", "音乐", "旅行", "照片", "城市", "朋友", "时间", "文化"},
+		FullStop:  "。",
+		Companies: []string{"华夏科技有限公司", "长城贸易有限公司", "东方食品公司", "星河网络科技"},
+	},
+}
+
+// Tags lists the locales Lookup knows, sorted.
+func Tags() []string {
+	tags := make([]string, 0, len(packs))
+	for tag := range packs {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Lookup returns the pack for a locale tag. The tag is read loosely:
+// "de_DE", "de-DE" and "de_de" are the same, and a bare language ("ja")
+// picks that language's pack. An empty tag returns Default.
+func Lookup(tag string) (*Pack, error) {
+	tag = strings.TrimSpace(strings.ReplaceAll(tag, "-", "_"))
+	if tag == "" {
+		return Default, nil
+	}
+	lang, region, _ := strings.Cut(tag, "_")
+	lang = strings.ToLower(lang)
+	if region != "" {
+		if p, ok := packs[lang+"_"+strings.ToUpper(region)]; ok {
+			return p, nil
+		}
+	} else {
+		for _, t := range Tags() {
+			if strings.HasPrefix(t, lang+"_") {
+				return packs[t], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown locale %q (supported: %s)", tag, strings.Join(Tags(), ", "))
+}
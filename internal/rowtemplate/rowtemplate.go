@@ -0,0 +1,225 @@
+// Package rowtemplate makes up the record behind one generated row, so the
+// columns derived from it agree with each other instead of being drawn
+// one by one: first_name, last_name, full_name and email name the same
+// person, and city, region, country, postal_code, phone, currency and
+// timezone come from the same place.
+//
+// A Pack holds the names, places and words of one locale (de_DE, ja_JP,
+// …), so generated data can look like it came from there, non-ASCII
+// characters included; Default mixes places from around the world.
+//
+// It also knows which columns of a table have to be in order within a
+// row (start_date before end_date, created_at before updated_at), so a
+// generator can move the later value after the earlier one.
+//
+// Like starter and chaos this is pure value logic with no database or
+// file access; the generators decide how a template's values are picked
+// (deterministically or at random).
+package rowtemplate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Locale is one place a row can be set in. Address is a street address
+// there, with %d for the house number.
+type Locale struct {
+	City        string
+	Region      string
+	Country     string
+	CountryCode string
+	PostalCode  string
+	PhonePrefix string
+	Currency    string
+	Timezone    string
+	Address     string
+}
+
+// FirstNames and LastNames are the people a Default template can be about.
+var (
+	FirstNames = []string{"Ada", "Grace", "Alan", "Linus", "Barbara", "Edsger", "Margaret", "Dennis", "Frances", "Ken"}
+	LastNames  = []string{"Lovelace", "Hopper", "Turing", "Torvalds", "Liskov", "Dijkstra", "Hamilton", "Ritchie", "Allen", "Thompson"}
+)
+
+// Locales are the places a Default template can be set in. Each entry is
+// a real city with a postal code, phone prefix, currency and timezone
+// that belong to it.
+var Locales = []Locale{
+	{"Austin", "Texas", "United States", "US", "78701", "+1 512", "USD", "America/Chicago", "%d Congress Avenue"},
+	{"Toronto", "Ontario", "Canada", "CA", "M5H 2N2", "+1 416", "CAD", "America/Toronto", "%d Bay Street"},
+	{"Lisbon", "Lisboa", "Portugal", "PT", "1100-148", "+351 21", "EUR", "Europe/Lisbon", "Rua Augusta %d"},
+	{"Berlin", "Berlin", "Germany", "DE", "10115", "+49 30", "EUR", "Europe/Berlin", "Friedrichstraße %d"},
+	{"London", "England", "United Kingdom", "GB", "EC1A 1BB", "+44 20", "GBP", "Europe/London", "%d Baker Street"},
+	{"Osaka", "Osaka", "Japan", "JP", "530-0001", "+81 6", "JPY", "Asia/Tokyo", "%d-1 Umeda, Kita-ku"},
+	{"Melbourne", "Victoria", "Australia", "AU", "3000", "+61 3", "AUD", "Australia/Melbourne", "%d Collins Street"},
+	{"Nairobi", "Nairobi", "Kenya", "KE", "00100", "+254 20", "KES", "Africa/Nairobi", "%d Kenyatta Avenue"},
+	{"Bogotá", "Bogotá", "Colombia", "CO", "110111", "+57 601", "COP", "America/Bogota", "Carrera 7 # %d-20"},
+	{"Mumbai", "Maharashtra", "India", "IN", "400001", "+91 22", "INR", "Asia/Kolkata", "%d Marine Drive"},
+}
+
+// Template is the person and place one row is built around.
+type Template struct {
+	First  string
+	Last   string
+	Locale Locale
+	// Pack is the pack the template was drawn from; nil means Default.
+	Pack *Pack
+}
+
+// New picks a Default template. pick(n) returns an index in [0, n): a
+// random source for random data, or a function of the row number for
+// deterministic output.
+func New(pick func(n int) int) Template {
+	return Default.New(pick)
+}
+
+// FullName is the person's name written the way the pack's locale
+// writes it.
+func (t Template) FullName() string {
+	p := t.pack()
+	if p.FamilyFirst {
+		return t.Last + p.NameSep + t.First
+	}
+	return t.First + p.NameSep + t.Last
+}
+
+// Latin returns the person's names spelled in ASCII, lower-cased, for
+// email addresses, usernames and other identifiers.
+func (t Template) Latin() (first, last string) {
+	p := t.pack()
+	latin := func(name string) string {
+		if v, ok := p.Latin[name]; ok {
+			return strings.ToLower(v)
+		}
+		return strings.ToLower(name)
+	}
+	return latin(t.First), latin(t.Last)
+}
+
+func (t Template) pack() *Pack {
+	if t.Pack == nil {
+		return Default
+	}
+	return t.Pack
+}
+
+// Text returns the template's value for a text column, or false when the
+// column isn't one the template determines. size is the column's length
+// limit (0 for none): a country column too short for the name gets the
+// ISO code. n is the row number, kept in values that are often unique.
+func (t Template) Text(column string, size, n int) (string, bool) {
+	col := strings.ToLower(column)
+	first, last := t.Latin()
+	l := t.Locale
+	has := func(subs ...string) bool {
+		for _, s := range subs {
+			if strings.Contains(col, s) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("email"):
+		return fmt.Sprintf("%s.%s%d@example.com", first, last, n), true
+	case col == "first_name" || col == "firstname" || col == "given_name":
+		return t.First, true
+	case col == "last_name" || col == "lastname" || col == "surname" || col == "family_name":
+		return t.Last, true
+	case col == "full_name" || col == "fullname" || col == "display_name" || col == "contact_name":
+		return t.FullName(), true
+	case col == "initials":
+		return strings.ToUpper(first[:1] + last[:1]), true
+	case has("username") || col == "login" || col == "handle":
+		return fmt.Sprintf("%s.%s%d", first, last, n), true
+	case has("phone", "mobile"):
+		return fmt.Sprintf("%s %07d", l.PhonePrefix, n), true
+	case has("timezone", "time_zone", "tz_name"):
+		return l.Timezone, true
+	case has("currency"):
+		return l.Currency, true
+	case has("country"):
+		if has("code", "iso") || (size > 0 && size < utf8.RuneCountInString(l.Country)) {
+			return l.CountryCode, true
+		}
+		return l.Country, true
+	case has("city", "town"):
+		return l.City, true
+	case has("region", "province", "county") || strings.HasSuffix(col, "_state"):
+		// A bare "state" is as often a workflow status as a place.
+		return l.Region, true
+	case has("postal", "postcode", "zip"):
+		return l.PostalCode, true
+	case has("address", "street") && !has("ip_", "_ip", "mac_", "url"):
+		return fmt.Sprintf(l.Address, 1+n%999), true
+	}
+	return "", false
+}
+
+// orderedWords are name parts whose columns come in order: a column with
+// the first word in its name must not be later than the column named the
+// same with the second word.
+var orderedWords = [][2]string{
+	{"start", "end"}, {"starts", "ends"}, {"started", "ended"},
+	{"started", "finished"}, {"started", "completed"},
+	{"begin", "end"}, {"begins", "ends"},
+	{"from", "to"}, {"from", "until"},
+	{"in", "out"},
+	{"opened", "closed"}, {"opens", "closes"},
+	{"issued", "expires"}, {"issued", "due"},
+	{"created", "updated"}, {"created", "modified"}, {"created", "deleted"},
+	{"birth", "death"},
+}
+
+// OrderedPairs returns, for each column of columns that must not come
+// before another, that earlier column: end_date → start_date,
+// updated_at → created_at, valid_until → valid_from. Names are split on
+// underscores and compared case-insensitively.
+func OrderedPairs(columns []string) map[string]string {
+	byName := make(map[string]string, len(columns))
+	for _, c := range columns {
+		byName[strings.ToLower(c)] = c
+	}
+	pairs := map[string]string{}
+	for _, c := range columns {
+		parts := strings.Split(strings.ToLower(c), "_")
+		for i, part := range parts {
+			for _, w := range orderedWords {
+				if part != w[0] {
+					continue
+				}
+				later := append([]string(nil), parts...)
+				later[i] = w[1]
+				if name, ok := byName[strings.Join(later, "_")]; ok && name != c {
+					if _, taken := pairs[name]; !taken {
+						pairs[name] = c
+					}
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// timeLayouts are the date and timestamp layouts After reads.
+var timeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05Z07:00", "2006-01-02"}
+
+// After returns the date or timestamp earlier moved forward by offset, in
+// the layout earlier was written in; a plain date moves by at least a day.
+// ok is false when earlier is not a date or timestamp.
+func After(earlier string, offset time.Duration) (string, bool) {
+	for _, layout := range timeLayouts {
+		t, err := time.Parse(layout, earlier)
+		if err != nil {
+			continue
+		}
+		if layout == "2006-01-02" && offset < 24*time.Hour {
+			offset = 24 * time.Hour
+		}
+		return t.Add(offset).Format(layout), true
+	}
+	return "", false
+}
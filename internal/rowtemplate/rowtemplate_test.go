@@ -0,0 +1,123 @@
+package rowtemplate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateText(t *testing.T) {
+	tpl := New(func(int) int { return 1 }) // Grace Hopper in Toronto
+	cases := []struct {
+		column string
+		size   int
+		want   string
+	}{
+		{"email", 0, "grace.hopper7@example.com"},
+		{"first_name", 0, "Grace"},
+		{"full_name", 0, "Grace Hopper"},
+		{"city", 0, "Toronto"},
+		{"billing_state", 0, "Ontario"},
+		{"country", 0, "Canada"},
+		{"country", 2, "CA"},
+		{"country_code", 0, "CA"},
+		{"postal_code", 0, "M5H 2N2"},
+		{"currency", 0, "CAD"},
+		{"phone", 0, "+1 416 0000007"},
+	}
+	for _, c := range cases {
+		got, ok := tpl.Text(c.column, c.size, 7)
+		if !ok || got != c.want {
+			t.Errorf("Text(%q, %d) = %q, %v; want %q", c.column, c.size, got, ok, c.want)
+		}
+	}
+	for _, column := range []string{"state", "status", "title"} {
+		if got, ok := tpl.Text(column, 0, 7); ok {
+			t.Errorf("Text(%q) = %q, want no template value", column, got)
+		}
+	}
+}
+
+func TestOrderedPairs(t *testing.T) {
+	got := OrderedPairs([]string{"id", "start_date", "end_date", "valid_from", "valid_until", "created_at", "updated_at", "check_in", "check_out", "title"})
+	want := map[string]string{
+		"end_date":    "start_date",
+		"valid_until": "valid_from",
+		"updated_at":  "created_at",
+		"check_out":   "check_in",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("OrderedPairs = %v, want %v", got, want)
+	}
+}
+
+func TestAfter(t *testing.T) {
+	if got, _ := After("2024-03-01", time.Hour); got != "2024-03-02" {
+		t.Errorf("date: got %q, want a day later", got)
+	}
+	if got, _ := After("2024-03-01 10:00:00", 90*time.Minute); got != "2024-03-01 11:30:00" {
+		t.Errorf("timestamp: got %q", got)
+	}
+	if _, ok := After("42", time.Hour); ok {
+		t.Error("number: want ok=false")
+	}
+	if got, _ := After("2024-03-01T10:00:00Z", time.Hour); !strings.HasPrefix(got, "2024-03-01T11:00:00") {
+		t.Errorf("RFC 3339: got %q", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for tag, want := range map[string]string{"": "", "de_DE": "de_DE", "ja-jp": "ja_JP", "pt": "pt_BR", " fr_fr ": "fr_FR"} {
+		p, err := Lookup(tag)
+		if err != nil || p.Tag != want {
+			t.Errorf("Lookup(%q) = %v, %v; want %q", tag, p, err, want)
+		}
+	}
+	if _, err := Lookup("xx_YY"); err == nil || !strings.Contains(err.Error(), "de_DE") {
+		t.Errorf("Lookup(xx_YY) err = %v, want the supported list", err)
+	}
+}
+
+func TestPackTemplate(t *testing.T) {
+	ja, _ := Lookup("ja_JP")
+	tpl := ja.New(func(int) int { return 0 }) // 佐藤 太郎 in 東京
+	cases := map[string]string{
+		"full_name":  "佐藤太郎",
+		"first_name": "太郎",
+		"email":      "taro.sato3@example.com",
+		"username":   "taro.sato3",
+		"initials":   "TS",
+		"city":       "東京",
+		"country":    "日本",
+		"address":    "千代田区丸の内4-1",
+		"phone":      "+81 3 0000003",
+	}
+	for column, want := range cases {
+		if got, _ := tpl.Text(column, 0, 3); got != want {
+			t.Errorf("Text(%q) = %q, want %q", column, got, want)
+		}
+	}
+	if got := ja.Sentence([]string{"桜", "電車"}); got != "桜電車。" {
+		t.Errorf("Sentence = %q", got)
+	}
+	de, _ := Lookup("de_DE")
+	if got := de.Sentence([]string{"übung", "wald"}); got != "Übung wald." {
+		t.Errorf("Sentence = %q", got)
+	}
+	all := []*Pack{Default}
+	for _, tag := range Tags() {
+		all = append(all, packs[tag])
+	}
+	for _, p := range all {
+		for _, name := range append(append([]string(nil), p.FirstNames...), p.LastNames...) {
+			first, _ := Template{First: name, Last: "x", Pack: p}.Latin()
+			for _, r := range first {
+				if r > 127 {
+					t.Errorf("%s: %q has no ASCII spelling", p.Tag, name)
+					break
+				}
+			}
+		}
+	}
+}
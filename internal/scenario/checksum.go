@@ -0,0 +1,173 @@
+package scenario
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChecksumsFileName is the sha256sum-style listing bundled into pushed
+// zips so pull can verify every extracted file before it becomes a
+// revision.
+const ChecksumsFileName = "checksums.sha256"
+
+// FileChecksums returns the hex SHA-256 of every regular file in dir,
+// keyed by file name.
+func FileChecksums(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sums := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		sum, err := FileSHA256(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sums[e.Name()] = sum
+	}
+	return sums, nil
+}
+
+// DataChecksum returns a SHA-256 over the regular files in dataDir: each
+// file's name and the hash of its content, in name order. It is recorded in
+// the revision manifest when the revision is written, so a later mismatch
+// means the CSVs were edited (or truncated) after the fact.
+func DataChecksum(dataDir string) (string, error) {
+	sums, err := FileChecksums(dataDir)
+	if err != nil {
+		return "", err
+	}
+	return combineChecksums(sums), nil
+}
+
+func combineChecksums(sums map[string]string) string {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s %s\n", sums[name], name)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyChecksums compares the regular files in dir against want. Every
+// listed file must exist with the same content and no unlisted file may
+// be present; the error names each offending file.
+func VerifyChecksums(dir string, want map[string]string) error {
+	got, err := FileChecksums(dir)
+	if err != nil {
+		return err
+	}
+	return CompareChecksums(got, want)
+}
+
+// CompareChecksums is VerifyChecksums for files already hashed into got,
+// keyed by name.
+func CompareChecksums(got, want map[string]string) error {
+	var changed, missing, extra []string
+	for name, sum := range want {
+		g, ok := got[name]
+		switch {
+		case !ok:
+			missing = append(missing, name)
+		case g != sum:
+			changed = append(changed, name)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	if len(changed)+len(missing)+len(extra) == 0 {
+		return nil
+	}
+	var parts []string
+	for _, g := range []struct {
+		label string
+		names []string
+	}{{"modified", changed}, {"missing", missing}, {"unexpected", extra}} {
+		if len(g.names) > 0 {
+			sort.Strings(g.names)
+			parts = append(parts, g.label+": "+strings.Join(g.names, ", "))
+		}
+	}
+	return fmt.Errorf("checksum mismatch (%s)", strings.Join(parts, "; "))
+}
+
+// WriteChecksumsFile writes sums to path in `sha256sum` format, one
+// "<hex>  <name>" line per file in name order.
+func WriteChecksumsFile(path string, sums map[string]string) error {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", sums[name], name)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// ReadChecksumsFile parses a file written by WriteChecksumsFile.
+func ReadChecksumsFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sums, err := ParseChecksums(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return sums, nil
+}
+
+// ParseChecksums parses a listing in the format WriteChecksumsFile writes.
+func ParseChecksums(r io.Reader) (map[string]string, error) {
+	sums := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		sums[name] = sum
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// FileSHA256 returns the hex SHA-256 of the file at path.
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", filepath.Base(path), err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,96 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDataChecksum_stableAndContentSensitive(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("users.csv", "id,name\n1,ada\n")
+	write("orders.csv", "id,user_id\n1,1\n")
+
+	first, err := DataChecksum(dir)
+	if err != nil {
+		t.Fatalf("DataChecksum: %v", err)
+	}
+	if !strings.HasPrefix(first, "sha256:") {
+		t.Fatalf("checksum %q lacks sha256: prefix", first)
+	}
+	again, _ := DataChecksum(dir)
+	if again != first {
+		t.Fatalf("checksum not stable: %s vs %s", first, again)
+	}
+
+	write("users.csv", "id,name\n1,bob\n")
+	edited, _ := DataChecksum(dir)
+	if edited == first {
+		t.Fatal("checksum did not change after editing a file")
+	}
+
+	write("users.csv", "id,name\n1,ada\n")
+	if err := os.Rename(filepath.Join(dir, "orders.csv"), filepath.Join(dir, "payments.csv")); err != nil {
+		t.Fatal(err)
+	}
+	renamed, _ := DataChecksum(dir)
+	if renamed == first {
+		t.Fatal("checksum did not change after renaming a file")
+	}
+}
+
+func TestVerifyChecksums_namesOffendingFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{"users.csv": "id\n1\n", "orders.csv": "id\n1\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := FileChecksums(dir)
+	if err != nil {
+		t.Fatalf("FileChecksums: %v", err)
+	}
+	if err := VerifyChecksums(dir, want); err != nil {
+		t.Fatalf("VerifyChecksums on untouched dir: %v", err)
+	}
+
+	// Truncate one file, drop another, add a stray one.
+	_ = os.WriteFile(filepath.Join(dir, "users.csv"), []byte("id\n"), 0644)
+	_ = os.Remove(filepath.Join(dir, "orders.csv"))
+	_ = os.WriteFile(filepath.Join(dir, "extra.csv"), []byte("x\n"), 0644)
+
+	err = VerifyChecksums(dir, want)
+	if err == nil {
+		t.Fatal("expected mismatch error")
+	}
+	for _, s := range []string{"modified: users.csv", "missing: orders.csv", "unexpected: extra.csv"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q lacks %q", err, s)
+		}
+	}
+}
+
+func TestChecksumsFile_roundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ChecksumsFileName)
+	sums := map[string]string{
+		"users.csv":   strings.Repeat("a", 64),
+		"schema.json": strings.Repeat("b", 64),
+	}
+	if err := WriteChecksumsFile(path, sums); err != nil {
+		t.Fatalf("WriteChecksumsFile: %v", err)
+	}
+	got, err := ReadChecksumsFile(path)
+	if err != nil {
+		t.Fatalf("ReadChecksumsFile: %v", err)
+	}
+	if len(got) != 2 || got["users.csv"] != sums["users.csv"] || got["schema.json"] != sums["schema.json"] {
+		t.Fatalf("round trip = %v, want %v", got, sums)
+	}
+}
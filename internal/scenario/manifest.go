@@ -46,6 +46,37 @@ type RevisionManifest struct {
 	// revision and skips the download when nothing changed.
 	RemoteID        string `json:"remoteId,omitempty"`
 	RemoteUpdatedAt string `json:"remoteUpdatedAt,omitempty"`
+	// SourceURL is the database the data was read from, password masked.
+	// Empty for revisions that did not come from a live database (pull,
+	// age, rewrite, …). DatabaseType is that database's engine and is
+	// carried over by commands that derive one revision from another.
+	SourceURL    string `json:"sourceUrl,omitempty"`
+	DatabaseType string `json:"databaseType,omitempty"`
+	// SeedmancerVersion is the CLI release that wrote the revision.
+	SeedmancerVersion string `json:"seedmancerVersion,omitempty"`
+	// Checksum is DataChecksum of data/ at write time and Files the
+	// per-file SHA-256 it was built from. Seed and push refuse a revision
+	// whose data no longer matches; revisions written before checksums
+	// existed leave both empty and are never flagged.
+	Checksum string            `json:"checksum,omitempty"`
+	Files    map[string]string `json:"files,omitempty"`
+	// SkippedTables are tables export left out because the credentials
+	// couldn't read them (export --skip-unreadable). A revision listing
+	// any is partial: seed loads no rows into those tables.
+	SkippedTables []string `json:"skippedTables,omitempty"`
+	// Masked are the columns, as "table.column", that pull masked under
+	// pull_masking before writing the revision.
+	Masked []string `json:"masked,omitempty"`
+	// Filters are the per-table predicates export kept rows by (export
+	// --filter); tables without one were exported in full.
+	Filters map[string]string `json:"filters,omitempty"`
+	// Excluded are the columns, as "table.column", export left out of the
+	// CSVs under exclude_columns.
+	Excluded []string `json:"excluded,omitempty"`
+	// Shards lists, per table export split under csv_shard_rows, its
+	// shard files in load order. Their checksums are in Files like any
+	// other file's.
+	Shards map[string][]string `json:"shards,omitempty"`
 }
 
 // manifestName / revisionManifestName / pointersName are kept private so
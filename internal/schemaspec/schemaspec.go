@@ -0,0 +1,441 @@
+// Package schemaspec publishes the JSON Schema for schema.json and checks
+// documents against it.
+//
+// The JSON Schema (seedmancer-schema.v1.json, embedded as JSONSchema) is
+// the source of truth: Validate interprets it directly rather than
+// mirroring it in Go, supporting the subset of draft 2020-12 the document
+// uses — type, enum, required, properties, additionalProperties, items,
+// minItems, minLength, oneOf and local $refs. On top of that Validate
+// checks what JSON Schema can't express: unique table and column names,
+// and foreign keys and enum references that point at something that
+// exists.
+//
+// Every Issue carries a path such as tables[3].columns[7].type, so a
+// hand-written or generated file can be fixed without guessing.
+package schemaspec
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONSchema is the published JSON Schema of schema.json.
+//
+//go:embed seedmancer-schema.v1.json
+var JSONSchema []byte
+
+// Issue is one problem found in a document.
+type Issue struct {
+	// Path locates the offending value, e.g. tables[3].columns[7].type;
+	// empty for the document itself.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+// node is one (sub)schema of the JSON Schema document.
+type node struct {
+	Type                 json.RawMessage  `json:"type"`
+	Enum                 []string         `json:"enum"`
+	Required             []string         `json:"required"`
+	Properties           map[string]*node `json:"properties"`
+	AdditionalProperties *bool            `json:"additionalProperties"`
+	Items                *node            `json:"items"`
+	MinItems             int              `json:"minItems"`
+	MinLength            int              `json:"minLength"`
+	OneOf                []*node          `json:"oneOf"`
+	Ref                  string           `json:"$ref"`
+	Defs                 map[string]*node `json:"$defs"`
+}
+
+func (n *node) types() []string {
+	if len(n.Type) == 0 {
+		return nil
+	}
+	var one string
+	if err := json.Unmarshal(n.Type, &one); err == nil {
+		return []string{one}
+	}
+	var many []string
+	_ = json.Unmarshal(n.Type, &many)
+	return many
+}
+
+var root = mustParse(JSONSchema)
+
+func mustParse(raw []byte) *node {
+	var n node
+	if err := json.Unmarshal(raw, &n); err != nil {
+		panic(fmt.Sprintf("schemaspec: embedded JSON Schema is invalid: %v", err))
+	}
+	return &n
+}
+
+// Validate checks raw against the JSON Schema and the cross-references
+// between tables, columns and enums. It returns nil for a valid document.
+func Validate(raw []byte) []Issue {
+	doc, err := decode(raw)
+	if err != nil {
+		return []Issue{{Message: err.Error()}}
+	}
+	w := walker{strict: true}
+	w.check(root, doc, "")
+	if len(w.issues) == 0 {
+		w.issues = references(doc)
+	}
+	return w.issues
+}
+
+// Explain locates the value that made json.Unmarshal of raw into a
+// schema struct fail, returning an error of the form
+// "tables[3].columns[7].type: expected string, got number". Only JSON
+// types are checked — missing fields and unknown keys are tolerated, as
+// the loaders tolerate them. It returns nil when it finds nothing.
+func Explain(raw []byte) error {
+	doc, err := decode(raw)
+	if err != nil {
+		return err
+	}
+	w := walker{}
+	w.check(root, doc, "")
+	if len(w.issues) == 0 {
+		return nil
+	}
+	return errors.New(w.issues[0].String())
+}
+
+// decode parses raw keeping numbers exact, and turns syntax errors into
+// line:column positions.
+func decode(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		var syn *json.SyntaxError
+		if errors.As(err, &syn) {
+			line, col := position(raw, syn.Offset)
+			return nil, fmt.Errorf("invalid JSON at line %d, column %d: %v", line, col, err)
+		}
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if dec.More() {
+		return nil, errors.New("invalid JSON: unexpected data after the top-level object")
+	}
+	return doc, nil
+}
+
+// position turns a SyntaxError offset, which points just past the
+// offending byte, into a 1-based line and column.
+func position(raw []byte, offset int64) (line, col int) {
+	pos := int(offset) - 1
+	if pos > len(raw) {
+		pos = len(raw)
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	line = 1 + bytes.Count(raw[:pos], []byte("\n"))
+	col = pos - bytes.LastIndexByte(raw[:pos], '\n')
+	return line, col
+}
+
+type walker struct {
+	// strict enables every keyword; otherwise only JSON types are
+	// checked and null is accepted anywhere, as encoding/json does.
+	strict bool
+	issues []Issue
+}
+
+func (w *walker) add(path, format string, args ...interface{}) {
+	w.issues = append(w.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (w *walker) check(n *node, v interface{}, path string) {
+	if n.Ref != "" {
+		n = resolve(n.Ref)
+	}
+	if len(n.OneOf) > 0 {
+		w.checkOneOf(n.OneOf, v, path)
+		return
+	}
+	if types := n.types(); len(types) > 0 && !(v == nil && !w.strict) && !matchesAny(types, v) {
+		w.add(path, "expected %s, got %s", strings.Join(types, " or "), jsonType(v))
+		return
+	}
+	switch val := v.(type) {
+	case string:
+		if !w.strict {
+			return
+		}
+		if n.MinLength > 0 && len(val) < n.MinLength {
+			w.add(path, "must not be empty")
+		}
+		if len(n.Enum) > 0 && !contains(n.Enum, val) {
+			w.add(path, "%q is not one of %s", val, quoteList(n.Enum))
+		}
+	case []interface{}:
+		if w.strict && len(val) < n.MinItems {
+			w.add(path, "needs at least %d item(s)", n.MinItems)
+		}
+		if n.Items != nil {
+			for i, item := range val {
+				w.check(n.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case map[string]interface{}:
+		if w.strict {
+			for _, req := range n.Required {
+				if _, ok := val[req]; !ok {
+					w.add(path, "missing required property %q", req)
+				}
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub, ok := n.Properties[k]
+			if !ok {
+				if w.strict && n.AdditionalProperties != nil && !*n.AdditionalProperties {
+					w.add(join(path, k), "unknown property%s", suggest(k, n.Properties))
+				}
+				continue
+			}
+			w.check(sub, val[k], join(path, k))
+		}
+	}
+}
+
+// checkOneOf accepts v when one of the branches does; otherwise it reports
+// the issues of the branch whose type matched, if any.
+func (w *walker) checkOneOf(branches []*node, v interface{}, path string) {
+	var typed []Issue
+	for _, b := range branches {
+		sub := walker{strict: w.strict}
+		sub.check(b, v, path)
+		if len(sub.issues) == 0 {
+			return
+		}
+		if matchesAny(resolveNode(b).types(), v) {
+			typed = sub.issues
+		}
+	}
+	if typed != nil {
+		w.issues = append(w.issues, typed...)
+		return
+	}
+	var want []string
+	for _, b := range branches {
+		want = append(want, resolveNode(b).types()...)
+	}
+	w.add(path, "expected %s, got %s", strings.Join(want, " or "), jsonType(v))
+}
+
+func resolveNode(n *node) *node {
+	if n.Ref != "" {
+		return resolve(n.Ref)
+	}
+	return n
+}
+
+func resolve(ref string) *node {
+	name := strings.TrimPrefix(ref, "#/$defs/")
+	if d, ok := root.Defs[name]; ok {
+		return d
+	}
+	panic(fmt.Sprintf("schemaspec: unresolved $ref %q", ref))
+}
+
+func matchesAny(types []string, v interface{}) bool {
+	for _, t := range types {
+		if matches(t, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(t string, v interface{}) bool {
+	switch t {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := strconv.ParseInt(n.String(), 10, 64)
+		return err == nil
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+func jsonType(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := strconv.ParseInt(val.String(), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// references checks the cross-references JSON Schema can't express. It
+// runs only on documents that already match the JSON Schema, so the type
+// assertions hold.
+func references(doc interface{}) []Issue {
+	var issues []Issue
+	top := doc.(map[string]interface{})
+
+	enums := map[string]bool{}
+	if list, ok := top["enums"].([]interface{}); ok {
+		for i, e := range list {
+			name := e.(map[string]interface{})["name"].(string)
+			if enums[name] {
+				issues = append(issues, Issue{Path: fmt.Sprintf("enums[%d].name", i), Message: fmt.Sprintf("duplicate enum %q", name)})
+			}
+			enums[name] = true
+		}
+	}
+
+	tables := top["tables"].([]interface{})
+	columns := map[string]map[string]bool{}
+	for i, t := range tables {
+		tbl := t.(map[string]interface{})
+		name := tbl["name"].(string)
+		if columns[name] != nil {
+			issues = append(issues, Issue{Path: fmt.Sprintf("tables[%d].name", i), Message: fmt.Sprintf("duplicate table %q", name)})
+			continue
+		}
+		cols := map[string]bool{}
+		for j, c := range tbl["columns"].([]interface{}) {
+			col := c.(map[string]interface{})["name"].(string)
+			if cols[col] {
+				issues = append(issues, Issue{Path: fmt.Sprintf("tables[%d].columns[%d].name", i, j), Message: fmt.Sprintf("duplicate column %q", col)})
+			}
+			cols[col] = true
+		}
+		columns[name] = cols
+	}
+
+	for i, t := range tables {
+		tbl := t.(map[string]interface{})
+		for j, c := range tbl["columns"].([]interface{}) {
+			col := c.(map[string]interface{})
+			path := fmt.Sprintf("tables[%d].columns[%d]", i, j)
+			if fk, ok := col["foreignKey"].(map[string]interface{}); ok {
+				ft, fc := fk["table"].(string), fk["column"].(string)
+				switch {
+				case columns[ft] == nil:
+					issues = append(issues, Issue{Path: path + ".foreignKey.table", Message: fmt.Sprintf("references unknown table %q", ft)})
+				case !columns[ft][fc]:
+					issues = append(issues, Issue{Path: path + ".foreignKey.column", Message: fmt.Sprintf("references unknown column %s.%s", ft, fc)})
+				}
+			}
+			if e, ok := col["enum"].(string); ok && e != "" && !enums[e] {
+				issues = append(issues, Issue{Path: path + ".enum", Message: fmt.Sprintf("references enum %q, which is not in enums", e)})
+			}
+		}
+	}
+	return issues
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteList(list []string) string {
+	q := make([]string, len(list))
+	for i, s := range list {
+		q[i] = strconv.Quote(s)
+	}
+	return strings.Join(q, ", ")
+}
+
+// suggest returns ` (did you mean "x"?)` when key is a case or
+// one-letter slip of a known property.
+func suggest(key string, props map[string]*node) string {
+	names := make([]string, 0, len(props))
+	for p := range props {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	for _, p := range names {
+		if strings.EqualFold(p, key) || editDistance(strings.ToLower(p), strings.ToLower(key)) <= 1 {
+			return fmt.Sprintf(" (did you mean %q?)", p)
+		}
+	}
+	return ""
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
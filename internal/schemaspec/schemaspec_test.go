@@ -0,0 +1,114 @@
+package schemaspec
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validDoc = `{
+  "databaseType": "postgres",
+  "enums": [{"name": "plan_t", "values": ["free", "pro"]}],
+  "tables": [
+    {"name": "orgs", "columns": [
+      {"name": "id", "type": "integer", "nullable": false, "isPrimary": true, "isUnique": true}
+    ]},
+    {"name": "users", "columns": [
+      {"name": "id", "type": "bigint", "isPrimary": true, "isGenerated": true, "identity": "ALWAYS"},
+      {"name": "org_id", "type": "integer", "foreignKey": {"table": "orgs", "column": "id"}},
+      {"name": "plan", "type": "USER-DEFINED", "enum": "plan_t", "default": "'free'::plan_t"},
+      {"name": "email", "type": "varchar(255)", "varchar": null, "foreignKey": null}
+    ]}
+  ],
+  "sequences": [{"name": "invoice_no", "dataType": "bigint", "start": 1, "increment": 1, "minValue": 1, "maxValue": 9223372036854775807}]
+}`
+
+func TestValidateAcceptsValidDocument(t *testing.T) {
+	if issues := Validate([]byte(validDoc)); issues != nil {
+		t.Fatalf("issues = %v", issues)
+	}
+}
+
+func TestValidateReportsPaths(t *testing.T) {
+	cases := []struct {
+		name, doc, want string
+	}{
+		{"wrong type", `{"tables":[{"name":"a","columns":[{"name":"id","type":7}]}]}`,
+			"tables[0].columns[0].type: expected string, got integer"},
+		{"missing required", `{"tables":[{"name":"a","columns":[{"name":"id"}]}]}`,
+			`tables[0].columns[0]: missing required property "type"`},
+		{"unknown property", `{"tables":[{"name":"a","columns":[{"name":"id","type":"int","isPrimay":true}]}]}`,
+			`tables[0].columns[0].isPrimay: unknown property (did you mean "isPrimary"?)`},
+		{"enum value", `{"databaseType":"sqlite","tables":[{"name":"a","columns":[{"name":"id","type":"int"}]}]}`,
+			`databaseType: "sqlite" is not one of "postgres", "mysql", "mariadb", "cockroach"`},
+		{"empty tables", `{"tables":[]}`, "tables: needs at least 1 item(s)"},
+		{"bad foreign key", `{"tables":[{"name":"a","columns":[{"name":"id","type":"int","foreignKey":{"table":"a","column":1}}]}]}`,
+			"tables[0].columns[0].foreignKey.column: expected string, got integer"},
+		{"fractional integer", `{"tables":[{"name":"a","columns":[{"name":"id","type":"int"}]}],"sequences":[{"name":"s","start":1.5}]}`,
+			"sequences[0].start: expected integer, got number"},
+		{"dangling foreign key", `{"tables":[{"name":"a","columns":[{"name":"b_id","type":"int","foreignKey":{"table":"b","column":"id"}}]}]}`,
+			`tables[0].columns[0].foreignKey.table: references unknown table "b"`},
+		{"unknown enum", `{"tables":[{"name":"a","columns":[{"name":"p","type":"enum","enum":"p_t"}]}]}`,
+			`tables[0].columns[0].enum: references enum "p_t", which is not in enums`},
+		{"duplicate column", `{"tables":[{"name":"a","columns":[{"name":"id","type":"int"},{"name":"id","type":"int"}]}]}`,
+			`tables[0].columns[1].name: duplicate column "id"`},
+		{"syntax error", "{\n  \"tables\": [,]\n}", "invalid JSON at line 2, column 14"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			issues := Validate([]byte(tc.doc))
+			if len(issues) == 0 {
+				t.Fatal("no issues")
+			}
+			if got := issues[0].String(); !strings.HasPrefix(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExplainIsLenient(t *testing.T) {
+	// Unknown keys, missing fields and nulls are fine for the loaders.
+	if err := Explain([]byte(`{"tables":[{"name":"a","columns":[{"name":null,"extra":1}]}]}`)); err != nil {
+		t.Fatalf("Explain = %v", err)
+	}
+	err := Explain([]byte(`{"tables":[{"name":"a","columns":[{"name":"x","type":"int"},{"name":"y","type":"int","nullable":"no"}]}]}`))
+	if err == nil || err.Error() != "tables[0].columns[1].nullable: expected boolean, got string" {
+		t.Fatalf("Explain = %v", err)
+	}
+}
+
+func TestJSONSchemaIsWellFormed(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(JSONSchema, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["$schema"] != "https://json-schema.org/draft/2020-12/schema" {
+		t.Errorf("$schema = %v", doc["$schema"])
+	}
+	// Every $ref resolves.
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n == nil {
+			return
+		}
+		if n.Ref != "" {
+			resolve(n.Ref)
+		}
+		for _, p := range n.Properties {
+			walk(p)
+		}
+		for _, b := range n.OneOf {
+			walk(b)
+		}
+		walk(n.Items)
+	}
+	walk(root)
+	for _, d := range root.Defs {
+		walk(d)
+	}
+	if !reflect.DeepEqual(root.Required, []string{"tables"}) {
+		t.Errorf("required = %v", root.Required)
+	}
+}
@@ -0,0 +1,472 @@
+// Package starter synthesizes a small, deterministic fixture straight from
+// schema.json, so a fresh project has something seedable before anyone has
+// written generation SQL or exported real data.
+//
+// Values are plain and predictable rather than realistic: ids count up from
+// 1, emails look like grace2@example.com, dates walk forward a day per row
+// (audit columns such as created_at/updated_at stay in order and in the
+// past).
+// Every table gets the same number of rows and foreign keys point at the
+// parent row with the same index, which keeps unique and one-to-one FK
+// columns valid without any bookkeeping. Columns of one row agree with
+// each other (see rowtemplate): names and email belong to one person,
+// address and phone columns to one place, and end_date falls after
+// start_date.
+package starter
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KazanKK/seedmancer/generators"
+	"github.com/KazanKK/seedmancer/internal/rowtemplate"
+	"github.com/KazanKK/seedmancer/internal/subset"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Null is the CSV cell that stands for SQL NULL, matching the export format.
+const Null = "NULL"
+
+var baseDate = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// Options tunes Generate. The zero value only writes NULL where a column
+// has no other usable value.
+type Options struct {
+	// NullRatio returns the share of rows (0–1) that get NULL in a
+	// nullable column of table. Primary key and unique columns are never
+	// nulled. The NULLs are spread evenly, so the output stays
+	// deterministic.
+	NullRatio func(table, column string) float64
+	// Pack supplies the names, places and words of one locale; nil
+	// means rowtemplate.Default.
+	Pack *rowtemplate.Pack
+	// Custom returns the custom generator for a column of table, or nil
+	// for the built-in values. Foreign key columns always copy their
+	// parent's value.
+	Custom func(table, column string) generators.Func
+	// EnumWeights returns the relative weight of each label of an enum
+	// column of table, or nil for equal weights. Either way every label
+	// gets a row first, as far as the rows go.
+	EnumWeights func(table, column string) map[string]float64
+}
+
+// Generate returns rows rows for every table in schema, keyed by table
+// name. Each value is a CSV record set whose first record is the header
+// (schema column order). Tables are filled parents-first so foreign keys
+// can reference already-generated rows.
+func Generate(schema utils.SchemaJSON, rows int, opts Options) (map[string][][]string, error) {
+	if rows < 1 {
+		return nil, fmt.Errorf("rows must be at least 1")
+	}
+	pack := opts.Pack
+	if pack == nil {
+		pack = rowtemplate.Default
+	}
+	enums := map[string][]string{}
+	for _, e := range schema.Enums {
+		enums[e.Name] = e.Values
+	}
+	tables := map[string]utils.SchemaTable{}
+	for _, t := range schema.Tables {
+		tables[t.Name] = t
+	}
+
+	order, _ := subset.InsertOrder(schema)
+	out := make(map[string][][]string, len(order))
+	for _, name := range order {
+		t := tables[name]
+		header := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			header[i] = c.Name
+		}
+		// ordered maps a column to the one it must not come before.
+		// Audit columns are already ordered by timeValue.
+		index := make(map[string]int, len(header))
+		for i, h := range header {
+			index[h] = i
+		}
+		ordered := map[int]int{}
+		for later, earlier := range rowtemplate.OrderedPairs(header) {
+			if !updatedColumns[strings.ToLower(later)] {
+				ordered[index[later]] = index[earlier]
+			}
+		}
+		ratios := make([]float64, len(t.Columns))
+		for i, c := range t.Columns {
+			if opts.NullRatio != nil && isTrue(c.Nullable) && !isTrue(c.IsPrimary) && !isTrue(c.IsUnique) {
+				ratios[i] = opts.NullRatio(t.Name, c.Name)
+			}
+		}
+		custom := make([]generators.Func, len(t.Columns))
+		rngs := make([]*rand.Rand, len(t.Columns))
+		for i, c := range t.Columns {
+			if opts.Custom != nil && c.ForeignKey == nil {
+				if custom[i] = opts.Custom(t.Name, c.Name); custom[i] != nil {
+					rngs[i] = rand.New(rand.NewSource(generators.Seed(0, t.Name, c.Name)))
+				}
+			}
+		}
+		// labels are the values of the table's enum columns, weights
+		// their configured weights and drawn how many rows got one.
+		labels := make([][]string, len(t.Columns))
+		weights := make([][]float64, len(t.Columns))
+		drawn := make([]int, len(t.Columns))
+		for i, c := range t.Columns {
+			if c.ForeignKey != nil || custom[i] != nil {
+				continue
+			}
+			labels[i] = enums[enumName(c.Enum)]
+			if len(labels[i]) > 0 && opts.EnumWeights != nil {
+				w, err := labelWeights(labels[i], opts.EnumWeights(t.Name, c.Name))
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", t.Name, c.Name, err)
+				}
+				weights[i] = w
+			}
+		}
+		records := [][]string{header}
+		for r := 0; r < rows; r++ {
+			record := make([]string, len(t.Columns))
+			for ci, c := range t.Columns {
+				if nullAt(r, ratios[ci]) {
+					record[ci] = Null
+					continue
+				}
+				if custom[ci] != nil {
+					v, err := custom[ci](generators.Request{Table: t.Name, Column: c.Name, Type: c.Type, Row: r + 1, Rand: rngs[ci]})
+					if err != nil {
+						return nil, fmt.Errorf("%s.%s: %w", t.Name, c.Name, err)
+					}
+					record[ci] = v
+					continue
+				}
+				if len(labels[ci]) > 0 {
+					record[ci] = enumLabel(labels[ci], weights[ci], drawn[ci])
+					drawn[ci]++
+					continue
+				}
+				v, err := cellValue(t, c, r, pack, enums, out)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", t.Name, c.Name, err)
+				}
+				record[ci] = v
+			}
+			for later, earlier := range ordered {
+				if record[later] == Null || record[earlier] == Null || t.Columns[later].ForeignKey != nil {
+					continue
+				}
+				if v, ok := rowtemplate.After(record[earlier], time.Duration(1+r%14)*24*time.Hour); ok {
+					record[later] = v
+				}
+			}
+			records = append(records, record)
+		}
+		out[name] = records
+	}
+	return out, nil
+}
+
+// labelWeights lines weights up with labels; nil weights stay nil.
+func labelWeights(labels []string, weights map[string]float64) ([]float64, error) {
+	if weights == nil {
+		return nil, nil
+	}
+	index := make(map[string]int, len(labels))
+	for i, l := range labels {
+		index[l] = i
+	}
+	out := make([]float64, len(labels))
+	for label, w := range weights {
+		i, ok := index[label]
+		if !ok {
+			return nil, fmt.Errorf("enum_weights: %q is not one of its values (%s)", label, strings.Join(labels, ", "))
+		}
+		out[i] = w
+	}
+	return out, nil
+}
+
+// enumLabel is the label of the kth (0-based) non-NULL value of an enum
+// column: each label once, in order, then by weights, spread evenly with
+// a golden-ratio sequence so the output stays deterministic. Without
+// weights the labels keep cycling.
+func enumLabel(labels []string, weights []float64, k int) string {
+	if k < len(labels) || weights == nil {
+		return labels[k%len(labels)]
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		return labels[k%len(labels)]
+	}
+	x := float64(k-len(labels)+1) * 0.6180339887498949
+	x = (x - float64(int(x))) * total
+	last := 0
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if x < w {
+			return labels[i]
+		}
+		x -= w
+		last = i
+	}
+	return labels[last]
+}
+
+// nullAt reports whether row r (0-based) is one of the NULL rows of a
+// column with the given ratio: every row where the running count of
+// rows × ratio ticks over a whole number.
+func nullAt(r int, ratio float64) bool {
+	return ratio > 0 && int(float64(r+1)*ratio) > int(float64(r)*ratio)
+}
+
+// estimateSampleRows is how many rows EstimateBytes actually generates.
+const estimateSampleRows = 50
+
+// EstimateBytes approximates the CSV size Generate(schema, rows) would
+// write, without building it: a small sample is generated and its average
+// row width scaled up to rows. Values that widen with the row number, such
+// as ids, make the estimate run somewhat low for huge row counts.
+func EstimateBytes(schema utils.SchemaJSON, rows int, opts Options) (int64, error) {
+	sample := rows
+	if sample > estimateSampleRows {
+		sample = estimateSampleRows
+	}
+	fixture, err := Generate(schema, sample, opts)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, records := range fixture {
+		var body int64
+		for _, r := range records[1:] {
+			body += csvLineBytes(r)
+		}
+		total += csvLineBytes(records[0]) + body*int64(rows)/int64(sample)
+	}
+	return total, nil
+}
+
+// csvLineBytes is the length of record as one unquoted CSV line.
+func csvLineBytes(record []string) int64 {
+	n := int64(len(record)) // separators plus the newline
+	for _, v := range record {
+		n += int64(len(v))
+	}
+	return n
+}
+
+// cellValue picks the value for column c in row r (0-based) of table t.
+func cellValue(t utils.SchemaTable, c utils.SchemaColumn, r int, pack *rowtemplate.Pack, enums map[string][]string, done map[string][][]string) (string, error) {
+	if fk := c.ForeignKey; fk != nil {
+		if fk.Table == t.Name {
+			// Self reference: the first row is a root, every other row
+			// points at the one before it.
+			if r == 0 && isTrue(c.Nullable) {
+				return Null, nil
+			}
+			if fk.Column == c.Name {
+				return "", fmt.Errorf("column references itself")
+			}
+			ref := r - 1
+			if ref < 0 {
+				ref = 0
+			}
+			for _, pc := range t.Columns {
+				if pc.Name == fk.Column {
+					return cellValue(t, pc, ref, pack, enums, done)
+				}
+			}
+			return "", fmt.Errorf("foreign key to unknown column %s.%s", fk.Table, fk.Column)
+		}
+		parent, ok := done[fk.Table]
+		if !ok || len(parent) < 2 {
+			// Parent is missing from the schema or part of an FK cycle
+			// and hasn't been filled yet.
+			if isTrue(c.Nullable) {
+				return Null, nil
+			}
+			return "", fmt.Errorf("non-nullable foreign key to %s, which has no generated rows (missing or in an FK cycle)", fk.Table)
+		}
+		idx := -1
+		for i, h := range parent[0] {
+			if h == fk.Column {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return "", fmt.Errorf("foreign key to unknown column %s.%s", fk.Table, fk.Column)
+		}
+		return parent[1+r%(len(parent)-1)][idx], nil
+	}
+
+	n := r + 1
+	if values := enums[enumName(c.Enum)]; len(values) > 0 {
+		return values[r%len(values)], nil
+	}
+	typ, size := baseType(c.Type)
+	switch typ {
+	case "smallint", "integer", "int", "int2", "int4", "int8", "bigint",
+		"serial", "bigserial", "smallserial", "tinyint", "mediumint":
+		if typ == "tinyint" && size == 1 {
+			return strconv.Itoa(r % 2), nil
+		}
+		if isTrue(c.IsPrimary) || isTrue(c.IsUnique) {
+			return strconv.Itoa(n), nil
+		}
+		return strconv.Itoa(n * 10), nil
+	case "numeric", "decimal", "real", "double precision", "double", "float", "float4", "float8", "money":
+		return fmt.Sprintf("%d.99", n*10-1), nil
+	case "boolean", "bool":
+		return strconv.FormatBool(r%2 == 0), nil
+	case "uuid":
+		return deterministicUUID(t.Name, c.Name, r), nil
+	case "date":
+		return timeValue(c, r).Format("2006-01-02"), nil
+	case "time", "time without time zone", "time with time zone":
+		return baseDate.Add(time.Duration(r) * time.Minute).Format("15:04:05"), nil
+	case "timestamp", "timestamptz", "datetime",
+		"timestamp without time zone", "timestamp with time zone":
+		return timeValue(c, r).Format("2006-01-02 15:04:05"), nil
+	case "json", "jsonb":
+		if strings.HasSuffix(c.Name, "s") {
+			return "[]", nil
+		}
+		return "{}", nil
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		// \x hex, as export writes binary cells.
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s.%s#%d", t.Name, c.Name, r)))
+		length := 16
+		if size > 0 && size < length {
+			length = size
+		}
+		return fmt.Sprintf(`\x%x`, sum[:length]), nil
+	case "tsvector", "inet", "cidr", "interval", "point":
+		if isTrue(c.Nullable) {
+			return Null, nil
+		}
+		return "", fmt.Errorf("no starter value for type %s", c.Type)
+	}
+	return clip(textValue(pack, c.Name, size, n), size, n), nil
+}
+
+// auditSpanDays bounds how far past baseDate audit timestamps go, so they
+// stay in the past however many rows are generated.
+const auditSpanDays = 365
+
+// createdColumns and updatedColumns are the lowercased names of common
+// audit columns.
+var (
+	createdColumns = map[string]bool{
+		"created_at": true, "createdat": true, "created": true, "created_on": true,
+		"inserted_at": true, "insertedat": true, "date_created": true, "creation_date": true,
+	}
+	updatedColumns = map[string]bool{
+		"updated_at": true, "updatedat": true, "updated": true, "updated_on": true,
+		"modified_at": true, "modifiedat": true, "modified": true,
+		"last_modified": true, "date_modified": true,
+	}
+)
+
+// timeValue is the date or timestamp for column c in row r. Other date
+// columns walk forward a day per row; audit columns cycle through the
+// year after baseDate, and an updated column lands 0–71 hours after the
+// created column of the same row, so updated_at >= created_at always.
+func timeValue(c utils.SchemaColumn, r int) time.Time {
+	name := strings.ToLower(c.Name)
+	created := baseDate.AddDate(0, 0, r%auditSpanDays)
+	switch {
+	case createdColumns[name]:
+		return created
+	case updatedColumns[name]:
+		return created.Add(time.Duration(r%72) * time.Hour)
+	}
+	return baseDate.AddDate(0, 0, r)
+}
+
+// textValue picks a string that reads sensibly for common column names.
+// Person and place columns come from the row's template, the nth of each
+// of pack's lists; everything else embeds n so unique text columns stay
+// unique.
+func textValue(pack *rowtemplate.Pack, column string, size, n int) string {
+	tpl := pack.New(func(k int) int { return (n - 1) % k })
+	first, _ := tpl.Latin()
+	col := strings.ToLower(column)
+	switch {
+	case strings.Contains(col, "email"):
+		return fmt.Sprintf("%s%d@example.com", first, n)
+	case col == "first_name" || col == "firstname" || col == "given_name":
+		return tpl.First
+	case col == "last_name" || col == "lastname" || col == "surname" || col == "family_name":
+		return tpl.Last
+	case col == "name" || col == "full_name" || col == "display_name":
+		return fmt.Sprintf("%s %d", tpl.FullName(), n)
+	case strings.Contains(col, "username") || col == "login" || col == "handle":
+		return fmt.Sprintf("%s%d", first, n)
+	case strings.Contains(col, "url") || strings.Contains(col, "website"):
+		return fmt.Sprintf("https://example.com/%d", n)
+	case strings.Contains(col, "slug"):
+		return fmt.Sprintf("%s-%d", strings.ReplaceAll(col, "_", "-"), n)
+	}
+	if v, ok := tpl.Text(column, size, n); ok {
+		return v
+	}
+	return fmt.Sprintf("%s %d", column, n)
+}
+
+// clip shortens v to fit varchar(size) characters, falling back to the
+// bare row number when the label wouldn't survive truncation.
+func clip(v string, size, n int) string {
+	if size <= 0 || utf8.RuneCountInString(v) <= size {
+		return v
+	}
+	if s := strconv.Itoa(n); len(s) <= size {
+		return s
+	}
+	return string([]rune(v)[:size])
+}
+
+// baseType lowercases colType and splits off a "(n)" size suffix.
+func baseType(colType string) (string, int) {
+	t := strings.ToLower(strings.TrimSpace(colType))
+	size := 0
+	if i := strings.Index(t, "("); i > 0 {
+		if j := strings.Index(t[i:], ")"); j > 0 {
+			size, _ = strconv.Atoi(strings.TrimSpace(strings.Split(t[i+1:i+j], ",")[0]))
+			t = strings.TrimSpace(t[:i] + t[i+j+1:])
+		} else {
+			t = strings.TrimSpace(t[:i])
+		}
+	}
+	return t, size
+}
+
+// deterministicUUID derives a stable version-4-shaped UUID from the cell's
+// position so re-running quickstart produces identical files.
+func deterministicUUID(table, column string, r int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s.%s#%d", table, column, r)))
+	sum[6] = (sum[6] & 0x0f) | 0x40
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// enumName extracts the enum type name from the lenient raw "enum" field.
+func enumName(raw json.RawMessage) string {
+	var s string
+	if len(raw) == 0 || json.Unmarshal(raw, &s) != nil {
+		return ""
+	}
+	return s
+}
+
+func isTrue(b *bool) bool { return b != nil && *b }
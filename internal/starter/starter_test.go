@@ -0,0 +1,309 @@
+package starter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KazanKK/seedmancer/generators"
+	"github.com/KazanKK/seedmancer/internal/rowtemplate"
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+func mustSchema(t *testing.T, raw string) utils.SchemaJSON {
+	t.Helper()
+	var s utils.SchemaJSON
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return s
+}
+
+func TestGenerate_fillsTablesWithValidForeignKeys(t *testing.T) {
+	schema := mustSchema(t, `{
+	  "enums":[{"name":"order_status","values":["pending","paid"]}],
+	  "tables":[
+	    {"name":"orders","columns":[
+	      {"name":"id","type":"uuid","isPrimary":true},
+	      {"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}},
+	      {"name":"status","type":"order_status","enum":"order_status"},
+	      {"name":"total","type":"numeric(10,2)"},
+	      {"name":"placed_at","type":"timestamp with time zone"}
+	    ]},
+	    {"name":"users","columns":[
+	      {"name":"id","type":"integer","isPrimary":true},
+	      {"name":"email","type":"varchar(255)","isUnique":true},
+	      {"name":"manager_id","type":"integer","nullable":true,"foreignKey":{"table":"users","column":"id"}},
+	      {"name":"active","type":"boolean"}
+	    ]}
+	  ]}`)
+
+	got, err := Generate(schema, 3, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	users, orders := got["users"], got["orders"]
+	if len(users) != 4 || len(orders) != 4 {
+		t.Fatalf("row counts = users %d, orders %d; want 3 data rows each", len(users)-1, len(orders)-1)
+	}
+	if strings.Join(users[1], ",") != "1,ada1@example.com,NULL,true" {
+		t.Fatalf("users row 1 = %v", users[1])
+	}
+	if users[2][2] != "1" || users[3][2] != "2" {
+		t.Fatalf("self-referencing manager_id = %q, %q; want 1, 2", users[2][2], users[3][2])
+	}
+	userIDs := map[string]bool{"1": true, "2": true, "3": true}
+	for _, rec := range orders[1:] {
+		if !userIDs[rec[1]] {
+			t.Fatalf("orders.user_id %q does not reference a generated user", rec[1])
+		}
+		if rec[2] != "pending" && rec[2] != "paid" {
+			t.Fatalf("orders.status %q is not an enum value", rec[2])
+		}
+	}
+	if orders[1][0] == orders[2][0] || len(orders[1][0]) != 36 {
+		t.Fatalf("uuids not unique or malformed: %q %q", orders[1][0], orders[2][0])
+	}
+
+	again, _ := Generate(schema, 3, Options{})
+	if strings.Join(again["orders"][1], ",") != strings.Join(orders[1], ",") {
+		t.Fatal("Generate is not deterministic")
+	}
+}
+
+func TestGenerate_binaryColumns(t *testing.T) {
+	schema := mustSchema(t, `{"tables":[{"name":"files","columns":[
+	  {"name":"id","type":"integer","isPrimary":true},
+	  {"name":"body","type":"bytea"},
+	  {"name":"tag","type":"varbinary(4)"}
+	]}]}`)
+	got, err := Generate(schema, 2, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, rec := range got["files"][1:] {
+		if !strings.HasPrefix(rec[1], `\x`) || len(rec[1]) != 2+32 || len(rec[2]) != 2+8 {
+			t.Fatalf("binary cells = %q, %q; want \\x and 16 and 4 bytes of hex", rec[1], rec[2])
+		}
+	}
+	if got["files"][1][1] == got["files"][2][1] {
+		t.Fatal("binary cells repeat across rows")
+	}
+}
+
+func TestGenerate_rejectsNonNullableCycle(t *testing.T) {
+	schema := mustSchema(t, `{"tables":[
+	  {"name":"a","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"b_id","type":"integer","foreignKey":{"table":"b","column":"id"}}]},
+	  {"name":"b","columns":[{"name":"id","type":"integer","isPrimary":true},{"name":"a_id","type":"integer","foreignKey":{"table":"a","column":"id"}}]}
+	]}`)
+	if _, err := Generate(schema, 2, Options{}); err == nil {
+		t.Fatal("expected error for a non-nullable FK cycle")
+	}
+}
+
+func TestEstimateBytes_matchesSmallFixturesAndScales(t *testing.T) {
+	schema := mustSchema(t, `{"tables":[{"name":"users","columns":[
+	  {"name":"id","type":"integer","isPrimary":true},
+	  {"name":"email","type":"text"}
+	]}]}`)
+
+	fixture, err := Generate(schema, 10, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exact int64
+	for _, r := range fixture["users"] {
+		exact += csvLineBytes(r)
+	}
+	if got, err := EstimateBytes(schema, 10, Options{}); err != nil || got != exact {
+		t.Fatalf("EstimateBytes(10) = %d, %v; want exact %d", got, err, exact)
+	}
+
+	big, err := EstimateBytes(schema, 10_000_000, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if big < 100_000_000 {
+		t.Fatalf("EstimateBytes(10M) = %d, want at least 100MB", big)
+	}
+}
+
+func TestGenerate_nullRatio(t *testing.T) {
+	schema := mustSchema(t, `{"tables":[{"name":"users","columns":[
+	  {"name":"id","type":"integer","isPrimary":true},
+	  {"name":"email","type":"text","nullable":true,"isUnique":true},
+	  {"name":"middle_name","type":"text","nullable":true},
+	  {"name":"deleted_at","type":"timestamp","nullable":true}
+	]}]}`)
+
+	ratios := map[string]float64{"middle_name": 0.6, "deleted_at": 0.95, "email": 1}
+	got, err := Generate(schema, 20, Options{NullRatio: func(_, column string) float64 { return ratios[column] }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nulls := make([]int, 4)
+	for _, rec := range got["users"][1:] {
+		for i, v := range rec {
+			if v == Null {
+				nulls[i]++
+			}
+		}
+	}
+	if want := []int{0, 0, 12, 19}; fmt.Sprint(nulls) != fmt.Sprint(want) {
+		t.Fatalf("NULLs per column = %v, want %v (key and unique columns never nulled)", nulls, want)
+	}
+}
+
+func TestGenerate_auditColumnsStayOrderedAndInThePast(t *testing.T) {
+	schema := mustSchema(t, `{"tables":[{"name":"posts","columns":[
+	  {"name":"id","type":"integer","isPrimary":true},
+	  {"name":"updatedAt","type":"timestamp"},
+	  {"name":"created_at","type":"timestamp with time zone"}
+	]}]}`)
+
+	got, err := Generate(schema, 1000, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, rec := range got["posts"][1:] {
+		updated, err1 := time.Parse("2006-01-02 15:04:05", rec[1])
+		created, err2 := time.Parse("2006-01-02 15:04:05", rec[2])
+		if err1 != nil || err2 != nil {
+			t.Fatalf("unparseable timestamps %v", rec)
+		}
+		if updated.Before(created) {
+			t.Fatalf("row %s: updatedAt %s before created_at %s", rec[0], rec[1], rec[2])
+		}
+		if !updated.Before(now) {
+			t.Fatalf("row %s: updatedAt %s is in the future", rec[0], rec[1])
+		}
+	}
+}
+
+func TestGenerate_rowColumnsAgree(t *testing.T) {
+	schema := mustSchema(t, `{"tables":[{"name":"bookings","columns":[
+	  {"name":"id","type":"integer","isPrimary":true},
+	  {"name":"first_name","type":"text"},
+	  {"name":"city","type":"text"},
+	  {"name":"country","type":"varchar(2)"},
+	  {"name":"start_date","type":"date"},
+	  {"name":"end_date","type":"date"}
+	]}]}`)
+
+	got, err := Generate(schema, 30, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	countries := map[string]string{"Austin": "US", "Toronto": "CA", "Lisbon": "PT", "Berlin": "DE"}
+	for _, rec := range got["bookings"][1:] {
+		if want, ok := countries[rec[2]]; ok && rec[3] != want {
+			t.Fatalf("row %s: %s is not in %s", rec[0], rec[2], rec[3])
+		}
+		if rec[5] <= rec[4] {
+			t.Fatalf("row %s: end_date %s is not after start_date %s", rec[0], rec[5], rec[4])
+		}
+	}
+}
+
+func TestGenerate_localePack(t *testing.T) {
+	schema := mustSchema(t, `{"tables":[{"name":"customers","columns":[
+	  {"name":"id","type":"integer","isPrimary":true},
+	  {"name":"name","type":"varchar(8)"},
+	  {"name":"email","type":"text"},
+	  {"name":"city","type":"text"},
+	  {"name":"address","type":"varchar(4)"}
+	]}]}`)
+	ja, err := rowtemplate.Lookup("ja_JP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Generate(schema, 3, Options{Pack: ja})
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := got["customers"][1]
+	if row[1] != "佐藤太郎 1" || row[2] != "taro1@example.com" || row[3] != "東京" {
+		t.Fatalf("row = %q, want a Japanese person in Tokyo with an ASCII email", row)
+	}
+	for _, rec := range got["customers"][1:] {
+		if !utf8.ValidString(rec[4]) || utf8.RuneCountInString(rec[4]) > 4 {
+			t.Fatalf("address %q doesn't fit varchar(4) as valid UTF-8", rec[4])
+		}
+	}
+}
+
+func TestGenerate_customGenerator(t *testing.T) {
+	schema := mustSchema(t, `{"tables":[
+	  {"name":"products","columns":[
+	    {"name":"sku","type":"varchar(12)","isPrimary":true},
+	    {"name":"name","type":"text"}]},
+	  {"name":"stock","columns":[
+	    {"name":"product_sku","type":"varchar(12)","foreignKey":{"table":"products","column":"sku"}}]}
+	]}`)
+	sku := func(r generators.Request) (string, error) {
+		return fmt.Sprintf("ACME-%04d", r.Row), nil
+	}
+	opts := Options{Custom: func(table, column string) generators.Func {
+		if column == "sku" || column == "product_sku" {
+			return sku
+		}
+		return nil
+	}}
+	got, err := Generate(schema, 2, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := got["products"]; p[1][0] != "ACME-0001" || p[2][0] != "ACME-0002" {
+		t.Fatalf("products = %q", p)
+	}
+	// A foreign key copies its parent rather than generating its own.
+	if s := got["stock"]; s[1][0] != "ACME-0001" {
+		t.Fatalf("stock = %q", s)
+	}
+
+	opts.Custom = func(string, string) generators.Func {
+		return func(generators.Request) (string, error) { return "", fmt.Errorf("boom") }
+	}
+	if _, err := Generate(schema, 1, opts); err == nil || !strings.Contains(err.Error(), "products.sku: boom") {
+		t.Fatalf("want the generator's error with its column, got %v", err)
+	}
+}
+
+func TestGenerate_enumCoverageAndWeights(t *testing.T) {
+	schema := mustSchema(t, `{
+	  "enums":[{"name":"status","values":["active","suspended","banned","deleted"]}],
+	  "tables":[{"name":"users","columns":[
+	    {"name":"id","type":"integer","isPrimary":true},
+	    {"name":"status","type":"status","enum":"status","nullable":true}
+	  ]}]
+	}`)
+	opts := Options{
+		NullRatio:   func(string, string) float64 { return 0.3 },
+		EnumWeights: func(string, string) map[string]float64 { return map[string]float64{"active": 9, "banned": 1} },
+	}
+	out, err := Generate(schema, 40, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, rec := range out["users"][1:] {
+		counts[rec[1]]++
+	}
+	for _, label := range []string{"active", "suspended", "banned", "deleted"} {
+		if counts[label] == 0 {
+			t.Errorf("%s never appears: %v", label, counts)
+		}
+	}
+	if counts["suspended"] != 1 || counts["deleted"] != 1 || counts["active"] <= counts["banned"] {
+		t.Errorf("weights not applied after the first of each label: %v", counts)
+	}
+
+	opts.EnumWeights = func(string, string) map[string]float64 { return map[string]float64{"gone": 1} }
+	if _, err := Generate(schema, 5, opts); err == nil {
+		t.Error("a weight for a label the enum lacks: want an error")
+	}
+}
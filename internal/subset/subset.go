@@ -0,0 +1,323 @@
+// Package subset computes foreign-key closures over a schema.json and
+// writes the matching slice of a revision's CSVs. It is pure file logic —
+// no database access — so seed, prune, and friends can share it.
+//
+// A subset has two kinds of tables:
+//
+//   - Selected tables are copied in full.
+//   - Parent tables are reached by following foreign keys out of the
+//     selected tables (transitively). Only the rows that are actually
+//     referenced are kept, so loading the subset never produces orphans.
+package subset
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+// Plan is the result of Closure: which tables are loaded in full and which
+// only contribute the rows the selected tables point at.
+type Plan struct {
+	Selected []string
+	Parents  []string
+}
+
+// All returns Selected followed by Parents.
+func (p Plan) All() []string {
+	return append(append([]string{}, p.Selected...), p.Parents...)
+}
+
+// fkEdge is one foreign key column: child.column → parent.parentColumn.
+type fkEdge struct {
+	column       string
+	parent       string
+	parentColumn string
+}
+
+// edgesByTable indexes every FK in the schema by its child table.
+func edgesByTable(schema utils.SchemaJSON) map[string][]fkEdge {
+	out := map[string][]fkEdge{}
+	for _, t := range schema.Tables {
+		for _, c := range t.Columns {
+			if c.ForeignKey == nil || c.ForeignKey.Table == "" {
+				continue
+			}
+			out[t.Name] = append(out[t.Name], fkEdge{
+				column:       c.Name,
+				parent:       c.ForeignKey.Table,
+				parentColumn: c.ForeignKey.Column,
+			})
+		}
+	}
+	return out
+}
+
+// Closure resolves tables against schema and follows foreign keys upward
+// until every referenced parent is included. Unknown table names are an
+// error so a typo in --tables never silently seeds nothing. Self
+// references are ignored for the purpose of adding parents.
+func Closure(schema utils.SchemaJSON, tables []string) (Plan, error) {
+	known := map[string]bool{}
+	for _, t := range schema.Tables {
+		known[t.Name] = true
+	}
+
+	selected := map[string]bool{}
+	var plan Plan
+	for _, raw := range tables {
+		name := strings.TrimSpace(raw)
+		if name == "" || selected[name] {
+			continue
+		}
+		if !known[name] {
+			return Plan{}, fmt.Errorf("table %q is not in schema.json", name)
+		}
+		selected[name] = true
+		plan.Selected = append(plan.Selected, name)
+	}
+	if len(plan.Selected) == 0 {
+		return Plan{}, fmt.Errorf("no tables selected")
+	}
+
+	edges := edgesByTable(schema)
+	seen := map[string]bool{}
+	for n := range selected {
+		seen[n] = true
+	}
+	queue := append([]string{}, plan.Selected...)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, e := range edges[cur] {
+			if seen[e.parent] || !known[e.parent] {
+				continue
+			}
+			seen[e.parent] = true
+			plan.Parents = append(plan.Parents, e.parent)
+			queue = append(queue, e.parent)
+		}
+	}
+	sort.Strings(plan.Selected)
+	sort.Strings(plan.Parents)
+	return plan, nil
+}
+
+// table is an in-memory CSV: header plus rows, with a column index.
+type table struct {
+	header []string
+	index  map[string]int
+	rows   [][]string
+}
+
+func readTable(path string) (*table, error) {
+	records, err := utils.ReadCSVRecords(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &table{index: map[string]int{}}
+	if len(records) == 0 {
+		return t, nil
+	}
+	t.header, t.rows = records[0], records[1:]
+	for i, h := range t.header {
+		t.index[h] = i
+	}
+	return t, nil
+}
+
+func writeTable(path string, t *table, keep []bool) (int, error) {
+	var records [][]string
+	if len(t.header) > 0 {
+		records = append(records, t.header)
+	}
+	n := 0
+	for i, row := range t.rows {
+		if keep == nil || keep[i] {
+			records = append(records, row)
+			n++
+		}
+	}
+	if err := utils.WriteCSVRecords(path, records); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// IsNullCell reports whether a CSV cell stands for SQL NULL in the export
+// format ("NULL", "null", or empty).
+func IsNullCell(v string) bool {
+	return v == "" || v == "NULL" || v == "null"
+}
+
+// WriteSubset reads <srcDir>/<table>.csv, or its shards, for every table
+// in plan and writes the subset to dstDir: selected tables verbatim,
+// parent tables filtered to the rows referenced (directly or transitively)
+// by the selected rows. Tables without a CSV in srcDir are skipped. Returns the
+// number of rows written per table.
+func WriteSubset(srcDir, dstDir string, schema utils.SchemaJSON, plan Plan) (map[string]int, error) {
+	tables := map[string]*table{}
+	for _, name := range plan.All() {
+		t, err := readTable(filepath.Join(srcDir, name+".csv"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tables[name] = t
+	}
+
+	keep := keepReferenced(schema, tables, plan)
+
+	counts := map[string]int{}
+	for _, name := range plan.All() {
+		t, ok := tables[name]
+		if !ok {
+			continue
+		}
+		n, err := writeTable(filepath.Join(dstDir, name+".csv"), t, keep[name])
+		if err != nil {
+			return nil, fmt.Errorf("writing %s.csv: %w", name, err)
+		}
+		counts[name] = n
+	}
+	return counts, nil
+}
+
+// keepReferenced returns, for each parent table in plan, a per-row mask
+// of the rows that some kept row references. Selected tables are absent
+// from the result (every row is kept). The walk repeats until no mask
+// changes so chains and diamonds of parents resolve fully.
+func keepReferenced(schema utils.SchemaJSON, tables map[string]*table, plan Plan) map[string][]bool {
+	edges := edgesByTable(schema)
+	isParent := map[string]bool{}
+	keep := map[string][]bool{}
+	for _, p := range plan.Parents {
+		isParent[p] = true
+		if t, ok := tables[p]; ok {
+			keep[p] = make([]bool, len(t.rows))
+		}
+	}
+
+	kept := func(name string, i int) bool {
+		if !isParent[name] {
+			return true
+		}
+		return keep[name][i]
+	}
+
+	for changed := true; changed; {
+		changed = false
+		// needed[parent][column] = set of referenced values
+		needed := map[string]map[string]map[string]bool{}
+		for _, name := range plan.All() {
+			t, ok := tables[name]
+			if !ok {
+				continue
+			}
+			for _, e := range edges[name] {
+				if !isParent[e.parent] {
+					continue
+				}
+				ci, ok := t.index[e.column]
+				if !ok {
+					continue
+				}
+				for i, row := range t.rows {
+					if !kept(name, i) || IsNullCell(row[ci]) {
+						continue
+					}
+					if needed[e.parent] == nil {
+						needed[e.parent] = map[string]map[string]bool{}
+					}
+					if needed[e.parent][e.parentColumn] == nil {
+						needed[e.parent][e.parentColumn] = map[string]bool{}
+					}
+					needed[e.parent][e.parentColumn][row[ci]] = true
+				}
+			}
+		}
+		for parent, cols := range needed {
+			t, ok := tables[parent]
+			if !ok {
+				continue
+			}
+			for col, values := range cols {
+				ci, ok := t.index[col]
+				if !ok {
+					continue
+				}
+				for i, row := range t.rows {
+					if !keep[parent][i] && values[row[ci]] {
+						keep[parent][i] = true
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	return keep
+}
+
+// InsertOrder returns every table in schema ordered so that each table
+// comes after the tables its foreign keys point at — the order to INSERT
+// in when FK checks are enforced. Ties are broken alphabetically so the
+// result is stable across runs. Self references are ignored; tables caught
+// in a longer FK cycle can't be ordered and are appended (sorted) at the
+// end, which is why cyclic is returned separately so callers can warn.
+func InsertOrder(schema utils.SchemaJSON) (order []string, cyclic []string) {
+	known := map[string]bool{}
+	for _, t := range schema.Tables {
+		known[t.Name] = true
+	}
+
+	// pending[child] = set of distinct parents not yet emitted.
+	pending := map[string]map[string]bool{}
+	children := map[string][]string{}
+	for name := range known {
+		pending[name] = map[string]bool{}
+	}
+	for child, edges := range edgesByTable(schema) {
+		for _, e := range edges {
+			if e.parent == child || !known[e.parent] || pending[child][e.parent] {
+				continue
+			}
+			pending[child][e.parent] = true
+			children[e.parent] = append(children[e.parent], child)
+		}
+	}
+
+	var ready []string
+	for name, parents := range pending {
+		if len(parents) == 0 {
+			ready = append(ready, name)
+		}
+	}
+	emitted := map[string]bool{}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		cur := ready[0]
+		ready = ready[1:]
+		emitted[cur] = true
+		order = append(order, cur)
+		for _, child := range children[cur] {
+			delete(pending[child], cur)
+			if len(pending[child]) == 0 && !emitted[child] {
+				ready = append(ready, child)
+			}
+		}
+	}
+
+	for name := range known {
+		if !emitted[name] {
+			cyclic = append(cyclic, name)
+		}
+	}
+	sort.Strings(cyclic)
+	return append(order, cyclic...), cyclic
+}
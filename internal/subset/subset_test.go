@@ -0,0 +1,115 @@
+package subset
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	utils "github.com/KazanKK/seedmancer/internal/utils"
+)
+
+const shopSchema = `{"tables":[
+  {"name":"countries","columns":[{"name":"code","type":"text"}]},
+  {"name":"users","columns":[{"name":"id","type":"integer"},{"name":"country","type":"text","foreignKey":{"table":"countries","column":"code"}}]},
+  {"name":"orders","columns":[{"name":"id","type":"integer"},{"name":"user_id","type":"integer","foreignKey":{"table":"users","column":"id"}}]},
+  {"name":"order_items","columns":[{"name":"id","type":"integer"},{"name":"order_id","type":"integer","foreignKey":{"table":"orders","column":"id"}}]}
+]}`
+
+func loadSchema(t *testing.T) utils.SchemaJSON {
+	t.Helper()
+	var s utils.SchemaJSON
+	if err := json.Unmarshal([]byte(shopSchema), &s); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return s
+}
+
+func TestClosure_followsParentsTransitively(t *testing.T) {
+	plan, err := Closure(loadSchema(t), []string{"orders"})
+	if err != nil {
+		t.Fatalf("Closure: %v", err)
+	}
+	if strings.Join(plan.Selected, ",") != "orders" {
+		t.Fatalf("Selected = %v", plan.Selected)
+	}
+	if strings.Join(plan.Parents, ",") != "countries,users" {
+		t.Fatalf("Parents = %v, want [countries users]", plan.Parents)
+	}
+}
+
+func TestClosure_unknownTableErrors(t *testing.T) {
+	if _, err := Closure(loadSchema(t), []string{"ordres"}); err == nil {
+		t.Fatal("expected error for unknown table")
+	}
+}
+
+func TestWriteSubset_keepsOnlyReferencedParentRows(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	files := map[string]string{
+		"countries.csv":   "code\nDE\nFR\nJP\n",
+		"users.csv":       "id,country\n1,DE\n2,FR\n3,JP\n",
+		"orders.csv":      "id,user_id\n10,1\n11,1\n12,NULL\n",
+		"order_items.csv": "id,order_id\n100,10\n",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schema := loadSchema(t)
+	plan, err := Closure(schema, []string{"orders"})
+	if err != nil {
+		t.Fatalf("Closure: %v", err)
+	}
+	counts, err := WriteSubset(src, dst, schema, plan)
+	if err != nil {
+		t.Fatalf("WriteSubset: %v", err)
+	}
+	if counts["orders"] != 3 || counts["users"] != 1 || counts["countries"] != 1 {
+		t.Fatalf("counts = %v", counts)
+	}
+	users, _ := os.ReadFile(filepath.Join(dst, "users.csv"))
+	if string(users) != "id,country\n1,DE\n" {
+		t.Fatalf("users.csv = %q", users)
+	}
+	countries, _ := os.ReadFile(filepath.Join(dst, "countries.csv"))
+	if string(countries) != "code\nDE\n" {
+		t.Fatalf("countries.csv = %q", countries)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "order_items.csv")); !os.IsNotExist(err) {
+		t.Fatal("child tables outside the subset must not be written")
+	}
+}
+
+func TestInsertOrder_parentsBeforeChildren(t *testing.T) {
+	order, cyclic := InsertOrder(loadSchema(t))
+	if len(cyclic) != 0 {
+		t.Fatalf("cyclic = %v, want none", cyclic)
+	}
+	if got := strings.Join(order, ","); got != "countries,users,orders,order_items" {
+		t.Fatalf("order = %s", got)
+	}
+}
+
+func TestInsertOrder_cyclesAppendedLast(t *testing.T) {
+	var s utils.SchemaJSON
+	raw := `{"tables":[
+	  {"name":"a","columns":[{"name":"b_id","type":"integer","foreignKey":{"table":"b","column":"id"}}]},
+	  {"name":"b","columns":[{"name":"a_id","type":"integer","foreignKey":{"table":"a","column":"id"}}]},
+	  {"name":"tree","columns":[{"name":"parent_id","type":"integer","foreignKey":{"table":"tree","column":"id"}}]}
+	]}`
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	order, cyclic := InsertOrder(s)
+	if got := strings.Join(order, ","); got != "tree,a,b" {
+		t.Fatalf("order = %s, want tree,a,b", got)
+	}
+	if got := strings.Join(cyclic, ","); got != "a,b" {
+		t.Fatalf("cyclic = %s, want a,b", got)
+	}
+}
@@ -0,0 +1,141 @@
+// Package timeshift moves date and timestamp values in CSV fixtures by a
+// fixed interval. Every temporal cell moves by the same amount, so the
+// relative ordering of events (signup before first order, trial before
+// expiry) is preserved while the whole dataset slides forward in time.
+//
+// Cells keep the layout they were written in: an exported
+// "2024-01-02 03:04:05 +0000 UTC" stays in that form, a hand-written
+// "2024-01-02" stays a plain date.
+package timeshift
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KazanKK/seedmancer/internal/cellexpr"
+)
+
+// ParseShift parses an interval such as "90d", "2w", "-36h" or "1h30m".
+// Days and weeks are accepted on top of everything time.ParseDuration
+// understands; months and years are not, because they are not a fixed
+// length and would break the "every cell moves by the same amount" rule.
+func ParseShift(s string) (time.Duration, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return 0, fmt.Errorf("shift cannot be empty")
+	}
+	body := strings.TrimPrefix(strings.TrimPrefix(raw, "+"), "-")
+	sign := time.Duration(1)
+	if strings.HasPrefix(raw, "-") {
+		sign = -1
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(body, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid shift %q (use e.g. 90d, 2w, -36h)", s)
+			}
+			return sign * time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid shift %q (use e.g. 90d, 2w, -36h)", s)
+	}
+	return d, nil
+}
+
+// IsTemporalType reports whether a schema.json column type holds a date or
+// timestamp. time-of-day types are excluded: shifting them by whole days
+// is a no-op and by hours would wrap around midnight.
+func IsTemporalType(colType string) bool {
+	t := strings.ToLower(strings.TrimSpace(colType))
+	switch {
+	case t == "date", t == "datetime", t == "timestamptz":
+		return true
+	case strings.HasPrefix(t, "timestamp"), strings.HasPrefix(t, "datetime("):
+		return true
+	}
+	return false
+}
+
+// layouts are tried in order. The first is what ExportToCSV writes for
+// every time.Time value; the rest cover hand-written and driver-text
+// forms.
+var layouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 UTC",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+const dateOnly = "2006-01-02"
+
+// ShiftCell moves one cell by d. NULL / empty cells, @env markers, SQL
+// expression cells and the Postgres infinity literals are returned
+// unchanged. A plain date can only move by whole days; anything else is
+// an error rather than a silent truncation.
+func ShiftCell(cell string, d time.Duration) (string, error) {
+	switch cell {
+	case "", "NULL", "null", "infinity", "-infinity":
+		return cell, nil
+	}
+	if strings.HasPrefix(cell, "@env:") || cellexpr.Is(cell) {
+		return cell, nil
+	}
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, cell)
+		if err != nil {
+			continue
+		}
+		if layout == dateOnly && d%(24*time.Hour) != 0 {
+			return "", fmt.Errorf("date %q can only be shifted by whole days", cell)
+		}
+		return t.Add(d).Format(layout), nil
+	}
+	return "", fmt.Errorf("unrecognised date/time value %q", cell)
+}
+
+// ShiftRecords shifts every temporal column of a parsed CSV (row 0 is the
+// header) and returns a new record set plus the number of cells changed.
+// types maps column name → schema.json type; columns missing from it are
+// left alone.
+func ShiftRecords(records [][]string, types map[string]string, d time.Duration) ([][]string, int, error) {
+	if len(records) == 0 {
+		return records, 0, nil
+	}
+	header := records[0]
+	var cols []int
+	for i, name := range header {
+		if IsTemporalType(types[name]) {
+			cols = append(cols, i)
+		}
+	}
+	out := make([][]string, len(records))
+	out[0] = header
+	changed := 0
+	for r := 1; r < len(records); r++ {
+		row := append([]string(nil), records[r]...)
+		for _, ci := range cols {
+			if ci >= len(row) {
+				continue
+			}
+			shifted, err := ShiftCell(row[ci], d)
+			if err != nil {
+				return nil, 0, fmt.Errorf("row %d, column %s: %w", r, header[ci], err)
+			}
+			if shifted != row[ci] {
+				row[ci] = shifted
+				changed++
+			}
+		}
+		out[r] = row
+	}
+	return out, changed, nil
+}
@@ -0,0 +1,95 @@
+package timeshift
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseShift(t *testing.T) {
+	cases := map[string]time.Duration{
+		"90d":   90 * 24 * time.Hour,
+		"+2w":   14 * 24 * time.Hour,
+		"-3d":   -3 * 24 * time.Hour,
+		"-36h":  -36 * time.Hour,
+		"1h30m": 90 * time.Minute,
+	}
+	for in, want := range cases {
+		got, err := ParseShift(in)
+		if err != nil {
+			t.Fatalf("ParseShift(%q): %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("ParseShift(%q) = %v, want %v", in, got, want)
+		}
+	}
+	for _, bad := range []string{"", "3mo", "d", "1y"} {
+		if _, err := ParseShift(bad); err == nil {
+			t.Fatalf("ParseShift(%q) should fail", bad)
+		}
+	}
+}
+
+func TestShiftCell_keepsLayout(t *testing.T) {
+	day := 24 * time.Hour
+	cases := []struct{ in, want string }{
+		{"2024-01-30 10:00:00 +0000 UTC", "2024-02-09 10:00:00 +0000 UTC"},
+		{"2024-01-30 10:00:00.25 +0000 UTC", "2024-02-09 10:00:00.25 +0000 UTC"},
+		{"2024-01-30", "2024-02-09"},
+		{"2024-01-30T10:00:00Z", "2024-02-09T10:00:00Z"},
+		{"2024-01-30 10:00:00", "2024-02-09 10:00:00"},
+		{"NULL", "NULL"},
+		{"", ""},
+		{"infinity", "infinity"},
+		{"@env:START_AT", "@env:START_AT"},
+		{"=expr(now() - interval '3 days')", "=expr(now() - interval '3 days')"},
+	}
+	for _, c := range cases {
+		got, err := ShiftCell(c.in, 10*day)
+		if err != nil {
+			t.Fatalf("ShiftCell(%q): %v", c.in, err)
+		}
+		if got != c.want {
+			t.Fatalf("ShiftCell(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestShiftCell_rejectsSubDayDateAndGarbage(t *testing.T) {
+	if _, err := ShiftCell("2024-01-30", 36*time.Hour); err == nil {
+		t.Fatal("expected error shifting a date by 36h")
+	}
+	if _, err := ShiftCell("next tuesday", time.Hour); err == nil {
+		t.Fatal("expected error for unparseable value")
+	}
+}
+
+func TestShiftRecords_onlyTemporalColumns(t *testing.T) {
+	records := [][]string{
+		{"id", "note", "starts_on", "expires_at"},
+		{"1", "2024-01-01", "2024-01-01", "2024-03-01 00:00:00 +0000 UTC"},
+		{"2", "x", "NULL", "2024-04-01 00:00:00 +0000 UTC"},
+	}
+	types := map[string]string{
+		"id":         "integer",
+		"note":       "text",
+		"starts_on":  "date",
+		"expires_at": "timestamp with time zone",
+	}
+	out, changed, err := ShiftRecords(records, types, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("ShiftRecords: %v", err)
+	}
+	if changed != 3 {
+		t.Fatalf("changed = %d, want 3", changed)
+	}
+	if out[1][1] != "2024-01-01" {
+		t.Fatalf("text column was shifted: %q", out[1][1])
+	}
+	if got := strings.Join(out[1], ","); got != "1,2024-01-01,2024-01-02,2024-03-02 00:00:00 +0000 UTC" {
+		t.Fatalf("row 1 = %s", got)
+	}
+	if records[1][2] != "2024-01-01" {
+		t.Fatal("input records were mutated")
+	}
+}
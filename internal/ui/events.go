@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Phases a ProgressEvent reports on.
+const (
+	PhaseExport   = "export"
+	PhaseImport   = "import"
+	PhaseDownload = "download"
+)
+
+// ProgressEvent kinds, in the order a phase emits them.
+const (
+	EventStart      = "start"
+	EventTableStart = "table_start"
+	EventProgress   = "progress"
+	EventTableEnd   = "table_end"
+	EventEnd        = "end"
+)
+
+// progressEventVersion is ProgressEvent's "v". Fields are only ever added
+// under it; a change that breaks readers bumps it.
+const progressEventVersion = 1
+
+// progressEventEvery is the least time between two "progress" events of
+// a phase; the other kinds are always emitted.
+const progressEventEvery = 250 * time.Millisecond
+
+// ProgressEvent is one line of the --progress-json stream on stderr, for
+// wrappers (IDE plugins, dashboards) to render progress from. Fields that
+// don't apply to the phase, or are zero, are left out.
+type ProgressEvent struct {
+	V     int    `json:"v"`
+	Time  string `json:"time"`
+	Event string `json:"event"`
+	Phase string `json:"phase"`
+	// Table is the table in flight, for table_start, progress and
+	// table_end.
+	Table       string `json:"table,omitempty"`
+	TablesDone  int    `json:"tablesDone,omitempty"`
+	TablesTotal int    `json:"tablesTotal,omitempty"`
+	// Rows counts the rows of the phase so far, TableRows those of Table;
+	// TableRowsTotal is how many Table is thought to have.
+	Rows           int64 `json:"rows,omitempty"`
+	TableRows      int64 `json:"tableRows,omitempty"`
+	TableRowsTotal int64 `json:"tableRowsTotal,omitempty"`
+	// Bytes and BytesTotal are a download's.
+	Bytes      int64 `json:"bytes,omitempty"`
+	BytesTotal int64 `json:"bytesTotal,omitempty"`
+	// Percent is how far through the phase it is, 0 to 100, when known.
+	Percent *float64 `json:"percent,omitempty"`
+	// ETASeconds is the time the phase is estimated to still take.
+	ETASeconds *float64 `json:"etaSeconds,omitempty"`
+}
+
+var (
+	progressJSON bool
+	eventOut     io.Writer = os.Stderr
+	eventMu      sync.Mutex
+)
+
+// SetProgressJSON switches progress reporting from the redrawn status
+// lines to ProgressEvent lines (--progress-json). Other messages still go
+// to stderr as they are; every event line is a JSON object.
+func SetProgressJSON(enabled bool) { progressJSON = enabled }
+
+// ProgressJSON reports whether --progress-json is on.
+func ProgressJSON() bool { return progressJSON }
+
+// emit writes e as one line, stamped with the version and time.
+func emit(e ProgressEvent) {
+	e.V = progressEventVersion
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	eventOut.Write(append(line, '\n'))
+}
+
+// percent is done of total as a ProgressEvent percentage, or nil when
+// total is unknown.
+func percent(done, total int64) *float64 {
+	if total <= 0 {
+		return nil
+	}
+	p := min(100, float64(done)*100/float64(total))
+	return &p
+}
+
+// seconds is d as a ProgressEvent's ETASeconds, or nil when !ok.
+func seconds(d time.Duration, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	s := d.Round(time.Second).Seconds()
+	return &s
+}
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureEvents turns --progress-json on for the test and returns the
+// events emitted by the time it's called back.
+func captureEvents(t *testing.T) func() []ProgressEvent {
+	t.Helper()
+	var buf bytes.Buffer
+	SetProgressJSON(true)
+	eventOut = &buf
+	t.Cleanup(func() {
+		SetProgressJSON(false)
+		eventOut = os.Stderr
+	})
+	return func() []ProgressEvent {
+		var events []ProgressEvent
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var e ProgressEvent
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatalf("event line %q: %v", line, err)
+			}
+			events = append(events, e)
+		}
+		return events
+	}
+}
+
+func TestTableProgressEvents(t *testing.T) {
+	events := captureEvents(t)
+	p := StartTableProgress(PhaseExport, []string{"users", "orders"}, map[string]int64{"users": 1, "orders": 3})
+	p.Begin("users")
+	p.Rows(1)
+	p.End()
+	p.Begin("orders")
+	p.Rows(3)
+	p.End()
+
+	got := events()
+	var kinds []string
+	for _, e := range got {
+		kinds = append(kinds, e.Event)
+		if e.V != 1 || e.Phase != PhaseExport || e.Time == "" {
+			t.Errorf("event %+v lacks its version, phase or time", e)
+		}
+	}
+	want := "start table_start table_end table_start table_end end"
+	if strings.Join(kinds, " ") != want {
+		t.Fatalf("events = %v, want %s", kinds, want)
+	}
+
+	users := got[2]
+	if users.Table != "users" || users.TableRows != 1 || users.TableRowsTotal != 1 || users.TablesDone != 1 || users.TablesTotal != 2 {
+		t.Errorf("users table_end = %+v", users)
+	}
+	if users.Percent == nil || *users.Percent != 25 {
+		t.Errorf("users table_end percent = %v, want 25 (1 of 4 rows)", users.Percent)
+	}
+	end := got[len(got)-1]
+	if end.Rows != 4 || end.TablesDone != 2 || end.Percent == nil || *end.Percent != 100 {
+		t.Errorf("end = %+v", end)
+	}
+}
+
+func TestTableProgressEvents_noTables(t *testing.T) {
+	events := captureEvents(t)
+	StartTableProgress(PhaseImport, nil, nil)
+	got := events()
+	if len(got) != 2 || got[0].Event != EventStart || got[1].Event != EventEnd {
+		t.Errorf("events = %+v, want start and end", got)
+	}
+}
+
+func TestProgressEvents(t *testing.T) {
+	events := captureEvents(t)
+	p := StartProgress(PhaseDownload, "Downloading")
+	p.Update(50, 200)
+	p.Update(200, 200)
+	p.Stop(true, "Downloaded")
+
+	got := events()
+	if len(got) != 4 {
+		t.Fatalf("events = %+v, want start, 2 progress and end", got)
+	}
+	if got[1].Bytes != 50 || got[1].BytesTotal != 200 || got[1].Percent == nil || *got[1].Percent != 25 {
+		t.Errorf("progress = %+v", got[1])
+	}
+	if end := got[3]; end.Event != EventEnd || end.Bytes != 200 {
+		t.Errorf("end = %+v", end)
+	}
+}
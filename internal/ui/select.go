@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrCanceled is returned by MultiSelect when the user backs out with q,
+// Esc or Ctrl-C.
+var ErrCanceled = errors.New("canceled")
+
+// multiSelectRows is how many options MultiSelect shows at once; longer
+// lists scroll with the cursor.
+const multiSelectRows = 15
+
+// CanPrompt reports whether stdin and stderr are both terminals, which an
+// interactive prompt like MultiSelect needs.
+func CanPrompt() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// MultiSelect shows options as a checkbox list on stderr and returns the
+// ones checked when the user presses Enter, in options order. checked
+// names the options that start out checked. ↑/↓ (or k/j) move, Space
+// toggles, a toggles all. It needs a terminal: see CanPrompt.
+func MultiSelect(prompt string, options, checked []string) ([]string, error) {
+	s := newSelection(options, checked)
+	if err := s.run(prompt); err != nil {
+		return nil, err
+	}
+	return s.selected(), nil
+}
+
+// Select shows options as a list on stderr and returns the index of the
+// one under the cursor when the user presses Enter. ↑/↓ (or k/j) move.
+// It needs a terminal: see CanPrompt.
+func Select(prompt string, options []string) (int, error) {
+	s := newSelection(options, nil)
+	s.single = true
+	if err := s.run(prompt); err != nil {
+		return -1, err
+	}
+	return s.cursor, nil
+}
+
+type selectOutcome int
+
+const (
+	selectContinue selectOutcome = iota
+	selectDone
+	selectCanceled
+)
+
+// selection is MultiSelect's state, kept apart from the terminal so the
+// key handling can be exercised without one.
+type selection struct {
+	options []string
+	checked []bool
+	cursor  int
+	offset  int  // first option shown
+	single  bool // Select: no checkboxes, Enter picks the cursor's option
+}
+
+func newSelection(options, checked []string) *selection {
+	s := &selection{options: options, checked: make([]bool, len(options))}
+	on := make(map[string]bool, len(checked))
+	for _, c := range checked {
+		on[c] = true
+	}
+	for i, o := range options {
+		s.checked[i] = on[o]
+	}
+	return s
+}
+
+// run reads key presses from the terminal until the user confirms or
+// backs out, redrawing after each.
+func (s *selection) run(prompt string) error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("switching the terminal to raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	lines := 0
+	buf := make([]byte, 8)
+	for {
+		lines = s.render(os.Stderr, prompt, lines)
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		switch s.handle(string(buf[:n])) {
+		case selectDone:
+			return nil
+		case selectCanceled:
+			return ErrCanceled
+		}
+	}
+}
+
+// handle applies one key press, as the bytes the terminal sent for it.
+func (s *selection) handle(key string) selectOutcome {
+	switch key {
+	case "\r", "\n":
+		return selectDone
+	case "q", "\x1b", "\x03":
+		return selectCanceled
+	case "\x1b[A", "\x1bOA", "k":
+		if s.cursor > 0 {
+			s.cursor--
+		}
+	case "\x1b[B", "\x1bOB", "j":
+		if s.cursor < len(s.options)-1 {
+			s.cursor++
+		}
+	case " ":
+		if len(s.options) > 0 && !s.single {
+			s.checked[s.cursor] = !s.checked[s.cursor]
+		}
+	case "a":
+		if s.single {
+			break
+		}
+		all := true
+		for _, c := range s.checked {
+			all = all && c
+		}
+		for i := range s.checked {
+			s.checked[i] = !all
+		}
+	}
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+multiSelectRows {
+		s.offset = s.cursor - multiSelectRows + 1
+	}
+	return selectContinue
+}
+
+func (s *selection) selected() []string {
+	var out []string
+	for i, o := range s.options {
+		if s.checked[i] {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// render draws the list over the prev lines drawn last time and returns
+// how many it drew. Raw mode needs explicit carriage returns.
+func (s *selection) render(w io.Writer, prompt string, prev int) int {
+	var b strings.Builder
+	if prev > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", prev)
+	}
+	b.WriteString("\r\x1b[J")
+	hint := "(↑/↓ move, enter picks, q cancels)"
+	if !s.single {
+		hint = fmt.Sprintf("(%d/%d selected; ↑/↓ move, space toggles, a all, enter confirms, q cancels)", len(s.selected()), len(s.options))
+	}
+	fmt.Fprintf(&b, "%s %s %s\r\n", color(yellow, "?"), prompt, color(dim, hint))
+	lines := 1
+	end := min(s.offset+multiSelectRows, len(s.options))
+	for i := s.offset; i < end; i++ {
+		cursor, box := "  ", "[ ]"
+		if i == s.cursor {
+			cursor = color(cyan, "❯ ")
+		}
+		if s.checked[i] {
+			box = color(green, "[x]")
+		}
+		if s.single {
+			fmt.Fprintf(&b, "%s%s\r\n", cursor, s.options[i])
+		} else {
+			fmt.Fprintf(&b, "%s%s %s\r\n", cursor, box, s.options[i])
+		}
+		lines++
+	}
+	if end < len(s.options) || s.offset > 0 {
+		fmt.Fprintf(&b, "%s\r\n", color(dim, fmt.Sprintf("  … %d more", len(s.options)-(end-s.offset))))
+		lines++
+	}
+	fmt.Fprint(w, b.String())
+	return lines
+}
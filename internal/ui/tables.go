@@ -0,0 +1,261 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// tableProgressLogEvery is how often TableProgress prints a line when
+// stderr's output isn't redrawn in place (CI logs, pipes).
+const tableProgressLogEvery = 10 * time.Second
+
+// statusOut is where TableProgress writes its status; redrawStderr
+// reports whether it may redraw the line in place. That depends on
+// stderr being a terminal, whatever stdout is.
+var (
+	statusOut    io.Writer = os.Stderr
+	redrawStderr           = term.IsTerminal(int(os.Stderr.Fd()))
+)
+
+// TableProgress reports a run through a list of tables, an export or a
+// restore: tables completed, rows processed, throughput and an ETA, for
+// the whole run and for the table in flight. When stderr is a terminal it
+// is a status line redrawn in place at most every 100ms; elsewhere it
+// prints a line every 10s, so a long CI job still shows it is moving. With
+// --progress-json it emits ProgressEvents instead, ending with an "end"
+// event once every table is done.
+//
+// A nil *TableProgress is valid and reports nothing.
+type TableProgress struct {
+	phase    string
+	verb     string
+	tables   int
+	expected map[string]int64
+	// expectedAll is the sum of expected, when every table has a count.
+	expectedAll int64
+	start       time.Time
+	done        int
+	rows        int64
+	table       string
+	tableRows   int64
+	tableStart  time.Time
+	lastDraw    time.Time
+	drawn       bool
+	mu          sync.Mutex
+}
+
+// phaseVerbs is how the status line names a phase.
+var phaseVerbs = map[string]string{PhaseExport: "Exporting", PhaseImport: "Importing"}
+
+// StartTableProgress starts reporting phase (PhaseExport, PhaseImport)
+// over tables. expected holds the rows each table is thought to have,
+// which the ETAs are worked out from; tables missing from it make the
+// overall ETA fall back to the average time per table.
+func StartTableProgress(phase string, tables []string, expected map[string]int64) *TableProgress {
+	p := &TableProgress{phase: phase, verb: phaseVerbs[phase], tables: len(tables), expected: expected, start: time.Now(), lastDraw: time.Now()}
+	for _, t := range tables {
+		n, ok := expected[t]
+		if !ok {
+			p.expectedAll = 0
+			break
+		}
+		p.expectedAll += n
+	}
+	if progressJSON {
+		emit(p.event(EventStart, p.start))
+		if p.tables == 0 {
+			emit(p.event(EventEnd, p.start))
+		}
+	}
+	return p
+}
+
+// Begin marks table as the one now being processed.
+func (p *TableProgress) Begin(table string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.table, p.tableRows, p.tableStart = table, 0, time.Now()
+	if progressJSON {
+		emit(p.event(EventTableStart, p.tableStart))
+		return
+	}
+	p.draw()
+}
+
+// Rows counts n more rows of the table in flight.
+func (p *TableProgress) Rows(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rows += int64(n)
+	p.tableRows += int64(n)
+	p.draw()
+}
+
+// End marks the table in flight as completed.
+func (p *TableProgress) End() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if progressJSON {
+		now := time.Now()
+		emit(p.event(EventTableEnd, now))
+		p.table = ""
+		if p.done == p.tables {
+			emit(p.event(EventEnd, now))
+		}
+		return
+	}
+	p.table = ""
+	p.draw()
+}
+
+// Clear erases the status line, so a message can be printed or the run
+// is over; the next update draws it again.
+func (p *TableProgress) Clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.drawn && redrawStderr {
+		fmt.Fprintf(statusOut, "\r\033[2K")
+	}
+	p.drawn = false
+}
+
+// draw prints the status when it is due, or with --progress-json emits
+// it. Called with mu held.
+func (p *TableProgress) draw() {
+	now := time.Now()
+	if progressJSON {
+		if now.Sub(p.lastDraw) < progressEventEvery {
+			return
+		}
+		p.lastDraw = now
+		emit(p.event(EventProgress, now))
+		return
+	}
+	if !redrawStderr {
+		if now.Sub(p.lastDraw) < tableProgressLogEvery {
+			return
+		}
+		p.lastDraw = now
+		fmt.Fprintf(statusOut, "→ %s\n", p.status(now))
+		return
+	}
+	if p.drawn && now.Sub(p.lastDraw) < 100*time.Millisecond {
+		return
+	}
+	p.lastDraw, p.drawn = now, true
+	fmt.Fprintf(statusOut, "\r\033[2K%s", p.status(now))
+}
+
+// status is the progress line as of now.
+func (p *TableProgress) status(now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d/%d tables", p.verb, p.done, p.tables)
+	if p.table != "" {
+		fmt.Fprintf(&b, " · %s %s", p.table, formatCount(p.tableRows))
+		if n, ok := p.expected[p.table]; ok && n > 0 {
+			fmt.Fprintf(&b, "/%s", formatCount(n))
+		}
+		b.WriteString(" rows")
+		if eta, ok := estimate(now.Sub(p.tableStart), p.tableRows, p.expected[p.table]); ok {
+			fmt.Fprintf(&b, " (%s left)", formatETA(eta))
+		}
+	}
+	elapsed := now.Sub(p.start)
+	fmt.Fprintf(&b, " · %s rows", formatCount(p.rows))
+	if elapsed >= time.Second {
+		fmt.Fprintf(&b, " · %s rows/s", formatCount(int64(float64(p.rows)/elapsed.Seconds())))
+	}
+	if eta, ok := p.eta(elapsed); ok {
+		fmt.Fprintf(&b, " · ETA %s", formatETA(eta))
+	}
+	return b.String()
+}
+
+// eta is the time the whole run is estimated to still take, elapsed into
+// it: by rows when every table's are expected, else by tables.
+func (p *TableProgress) eta(elapsed time.Duration) (time.Duration, bool) {
+	if p.expectedAll > 0 {
+		return estimate(elapsed, p.rows, p.expectedAll)
+	}
+	return estimate(elapsed, int64(p.done), int64(p.tables))
+}
+
+// event is the ProgressEvent of kind as of now.
+func (p *TableProgress) event(kind string, now time.Time) ProgressEvent {
+	e := ProgressEvent{
+		Event:       kind,
+		Phase:       p.phase,
+		Table:       p.table,
+		TablesDone:  p.done,
+		TablesTotal: p.tables,
+		Rows:        p.rows,
+		Percent:     percent(int64(p.done), int64(p.tables)),
+		ETASeconds:  seconds(p.eta(now.Sub(p.start))),
+	}
+	if p.expectedAll > 0 {
+		e.Percent = percent(p.rows, p.expectedAll)
+	}
+	if p.table != "" {
+		e.TableRows, e.TableRowsTotal = p.tableRows, p.expected[p.table]
+	}
+	if kind == EventEnd {
+		e.Percent, e.ETASeconds = percent(1, 1), nil
+	}
+	return e
+}
+
+// estimate is the time left to get from done to total at the rate done
+// took elapsed. ok is false until there is a rate to go by, and once done
+// has passed total, the estimate having been wrong.
+func estimate(elapsed time.Duration, done, total int64) (time.Duration, bool) {
+	if done <= 0 || total <= 0 || done > total || elapsed < time.Second {
+		return 0, false
+	}
+	return time.Duration(float64(elapsed) * float64(total-done) / float64(done)), true
+}
+
+// formatETA rounds d to what's worth reading: seconds under a minute,
+// minutes and seconds under an hour, hours and minutes beyond.
+func formatETA(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Round(time.Second).Seconds()))
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%dm%02ds", int(d/time.Minute), int(d%time.Minute/time.Second))
+	default:
+		d = d.Round(time.Minute)
+		return fmt.Sprintf("%dh%02dm", int(d/time.Hour), int(d%time.Hour/time.Minute))
+	}
+}
+
+// formatCount writes n with thousands separators.
+func formatCount(n int64) string {
+	s := fmt.Sprintf("%d", n)
+	if n < 0 {
+		return s
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
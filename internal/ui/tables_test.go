@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimate(t *testing.T) {
+	if eta, ok := estimate(10*time.Second, 250, 1000); !ok || eta != 30*time.Second {
+		t.Fatalf("estimate = %v, %v; want 30s", eta, ok)
+	}
+	for name, c := range map[string]struct {
+		elapsed     time.Duration
+		done, total int64
+	}{
+		"nothing done yet":  {10 * time.Second, 0, 1000},
+		"under a second in": {500 * time.Millisecond, 10, 1000},
+		"total unknown":     {10 * time.Second, 10, 0},
+		"past the estimate": {10 * time.Second, 1200, 1000},
+	} {
+		if _, ok := estimate(c.elapsed, c.done, c.total); ok {
+			t.Errorf("%s: want no estimate", name)
+		}
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		12 * time.Second:                      "12s",
+		90*time.Second + 400*time.Millisecond: "1m30s",
+		2*time.Hour + 5*time.Minute:           "2h05m",
+	} {
+		if got := formatETA(d); got != want {
+			t.Errorf("formatETA(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	for n, want := range map[int64]string{0: "0", 999: "999", 1000: "1,000", 1234567: "1,234,567"} {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestTableProgressStatus(t *testing.T) {
+	p := StartTableProgress(PhaseImport, []string{"users", "orders"}, map[string]int64{"users": 100, "orders": 300})
+	p.start = p.start.Add(-10 * time.Second)
+	p.done, p.rows = 1, 200
+	p.table, p.tableRows, p.tableStart = "orders", 100, p.start.Add(5*time.Second)
+	got := p.status(p.start.Add(10 * time.Second))
+	for _, want := range []string{"Importing 1/2 tables", "orders 100/300 rows (10s left)", "200 rows", "20 rows/s", "ETA 10s"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("status = %q, missing %q", got, want)
+		}
+	}
+
+	// Without row counts for every table the ETA goes by tables.
+	p.expectedAll = 0
+	if got := p.status(p.start.Add(10 * time.Second)); !strings.Contains(got, "ETA 10s") {
+		t.Errorf("status = %q, want the per-table ETA", got)
+	}
+}
+
+func TestTableProgress_redrawsOnlyOnTerminalStderr(t *testing.T) {
+	var buf bytes.Buffer
+	statusOut = &buf
+	redraw := redrawStderr
+	t.Cleanup(func() {
+		statusOut = os.Stderr
+		redrawStderr = redraw
+	})
+
+	redrawStderr = false
+	p := StartTableProgress(PhaseExport, []string{"users"}, nil)
+	p.lastDraw = p.lastDraw.Add(-tableProgressLogEvery)
+	p.Begin("users")
+	p.Clear()
+	if got := buf.String(); !strings.HasPrefix(got, "→ Exporting 0/1 tables") || strings.Contains(got, "\r") {
+		t.Errorf("stderr not a terminal: wrote %q, want a plain log line", got)
+	}
+
+	buf.Reset()
+	redrawStderr = true
+	p = StartTableProgress(PhaseExport, []string{"users"}, nil)
+	p.Begin("users")
+	p.Clear()
+	if got := buf.String(); !strings.HasPrefix(got, "\r\033[2KExporting 0/1 tables") || !strings.HasSuffix(got, "\r\033[2K") {
+		t.Errorf("stderr a terminal: wrote %q, want the line drawn and cleared in place", got)
+	}
+}
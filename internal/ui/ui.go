@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/KazanKK/seedmancer/internal/redact"
 	"golang.org/x/term"
 )
 
@@ -50,28 +51,36 @@ func SetDebug(enabled bool) { debugMode = enabled }
 // work (e.g. diagnostic queries) that only feeds Debug logs.
 func DebugEnabled() bool { return debugMode }
 
+// message formats a status line with any database credentials in it
+// masked: errors and debug logs routinely quote the DSN they failed on.
+// KeyValue is left alone, since callers that print a secret on purpose
+// (env show --show-secret) go through it.
+func message(format string, args ...interface{}) string {
+	return redact.String(fmt.Sprintf(format, args...))
+}
+
 func Step(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
+	msg := message(format, args...)
 	fmt.Fprintf(os.Stderr, "%s %s\n", color(cyan, "→"), msg)
 }
 
 func Success(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
+	msg := message(format, args...)
 	fmt.Fprintf(os.Stderr, "%s %s\n", color(green, "✓"), msg)
 }
 
 func Warn(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
+	msg := message(format, args...)
 	fmt.Fprintf(os.Stderr, "%s %s\n", color(yellow, "⚠"), msg)
 }
 
 func Error(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
+	msg := message(format, args...)
 	fmt.Fprintf(os.Stderr, "%s %s\n", color(red, "✗"), msg)
 }
 
 func Info(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
+	msg := message(format, args...)
 	fmt.Fprintf(os.Stderr, "  %s\n", msg)
 }
 
@@ -79,7 +88,7 @@ func Debug(format string, args ...interface{}) {
 	if !debugMode {
 		return
 	}
-	msg := fmt.Sprintf(format, args...)
+	msg := message(format, args...)
 	fmt.Fprintf(os.Stderr, "  %s\n", color(gray, msg))
 }
 
@@ -250,3 +259,116 @@ func (s *Spinner) Stop(success bool, message string) {
 		fmt.Fprintf(os.Stderr, "%s %s\n", color(red, "✗"), message)
 	}
 }
+
+// Progress draws a byte-count progress bar on stderr. On a terminal the
+// bar is redrawn in place at most every 100ms; elsewhere only the start
+// and the final line are printed, like Spinner. With --progress-json it
+// emits ProgressEvents for phase as well, the bar aside.
+type Progress struct {
+	phase     string
+	label     string
+	start     time.Time
+	startDone int64
+	lastDraw  time.Time
+	drawn     bool
+	// done and total are the last Update's, for the "end" event.
+	done, total int64
+	mu          sync.Mutex
+}
+
+func StartProgress(phase, label string) *Progress {
+	if progressJSON {
+		emit(ProgressEvent{Event: EventStart, Phase: phase})
+	} else if noColor {
+		fmt.Fprintf(os.Stderr, "%s %s...\n", color(cyan, "→"), label)
+	}
+	return &Progress{phase: phase, label: label, start: time.Now(), startDone: -1}
+}
+
+// Update reports done of total bytes; total is -1 when unknown.
+func (p *Progress) Update(done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.startDone < 0 {
+		p.startDone = done
+	}
+	p.done, p.total = done, total
+	if progressJSON {
+		if time.Since(p.lastDraw) < progressEventEvery && done != total {
+			return
+		}
+		p.lastDraw = time.Now()
+		e := ProgressEvent{Event: EventProgress, Phase: p.phase, Bytes: done, Percent: percent(done, total)}
+		if total > 0 {
+			e.BytesTotal = total
+			// Bytes resumed from an earlier run don't count towards the rate.
+			e.ETASeconds = seconds(estimate(time.Since(p.start), done-p.startDone, total-p.startDone))
+		}
+		emit(e)
+		return
+	}
+	if noColor || (p.drawn && time.Since(p.lastDraw) < 100*time.Millisecond && done != total) {
+		return
+	}
+	p.lastDraw = time.Now()
+	p.drawn = true
+
+	var line strings.Builder
+	line.WriteString(p.label)
+	if total > 0 {
+		const width = 24
+		filled := int(float64(width) * float64(done) / float64(total))
+		if filled > width {
+			filled = width
+		}
+		fmt.Fprintf(&line, "  %s%s %3d%%  %s / %s",
+			color(cyan, strings.Repeat("█", filled)), color(gray, strings.Repeat("░", width-filled)),
+			done*100/total, formatBytes(done), formatBytes(total))
+	} else {
+		fmt.Fprintf(&line, "  %s", formatBytes(done))
+	}
+	if elapsed := time.Since(p.start).Seconds(); elapsed >= 1 && done > p.startDone {
+		fmt.Fprintf(&line, "  %s/s", formatBytes(int64(float64(done-p.startDone)/elapsed)))
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[2K%s", line.String())
+}
+
+// Clear erases the bar so a message can be printed; the next Update
+// draws it again.
+func (p *Progress) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.drawn && !noColor {
+		fmt.Fprintf(os.Stderr, "\r\033[2K")
+	}
+	p.drawn = false
+}
+
+// Stop replaces the bar with a final ✓ or ✗ line. With --progress-json a
+// success also ends the phase's events.
+func (p *Progress) Stop(success bool, message string) {
+	p.Clear()
+	if progressJSON && success {
+		p.mu.Lock()
+		emit(ProgressEvent{Event: EventEnd, Phase: p.phase, Bytes: p.done, BytesTotal: max(p.total, 0), Percent: percent(1, 1)})
+		p.mu.Unlock()
+	}
+	if success {
+		fmt.Fprintf(os.Stderr, "%s %s\n", color(green, "✓"), message)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s %s\n", color(red, "✗"), message)
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for n2 := n / unit; n2 >= unit; n2 /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
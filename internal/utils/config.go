@@ -2,8 +2,13 @@ package utils
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -50,16 +55,333 @@ type Config struct {
 	// generate and refresh operations. Use this for framework-managed system
 	// tables (e.g. _prisma_migrations) that should never be seeded with fake data.
 	ExcludeTables []string `yaml:"exclude_tables,omitempty"`
+
+	// Tables holds per-table settings keyed by table name. Tables that are
+	// not listed use the defaults.
+	Tables map[string]TableConfig `yaml:"tables,omitempty"`
+
+	// GenerateBudget caps the estimated size of a synthesized fixture
+	// (e.g. "500MB", "2GB"). Generation over the budget is refused unless
+	// explicitly allowed. Empty means DefaultGenerateBudget.
+	GenerateBudget string `yaml:"generate_budget,omitempty"`
+
+	// NullRatio is the share of rows (0–1) that get NULL in a nullable
+	// column of a synthesized fixture. Unset means DefaultNullRatio.
+	NullRatio *float64 `yaml:"null_ratio,omitempty"`
+
+	// NullRatios overrides NullRatio per column, keyed by "table.column"
+	// or by a bare column name that applies in every table
+	// (e.g. deleted_at: 0.95).
+	NullRatios map[string]float64 `yaml:"null_ratios,omitempty"`
+
+	// Generators picks a custom value generator (see package generators)
+	// for a column, keyed like NullRatios: "table.column" or a bare
+	// column name. The value is the name it was registered under.
+	Generators map[string]string `yaml:"generators,omitempty"`
+
+	// EnumWeights sets how often each label of an enum or CHECK-list
+	// column is drawn once every label has appeared, keyed like
+	// NullRatios (e.g. orders.status: {paid: 80, pending: 15,
+	// refunded: 5}). Labels left out appear only once.
+	EnumWeights map[string]map[string]float64 `yaml:"enum_weights,omitempty"`
+
+	// FKPicks sets how a foreign key column of a generated row picks its
+	// parent, keyed like NullRatios (e.g. orders.user_id: zipfian). See
+	// FKPickUniform and its siblings; unset columns pick uniformly.
+	FKPicks map[string]string `yaml:"fk_picks,omitempty"`
+
+	// GeneratorPlugins lists Go plugins (.so files, relative to this
+	// config) that register custom generators. They are loaded before
+	// any rows are generated.
+	GeneratorPlugins []string `yaml:"generator_plugins,omitempty"`
+
+	// PullMasking masks columns of every scenario pulled onto a machine
+	// that isn't CI, before its rows are written, so realistic data in the
+	// cloud never lands on a developer's disk. Keys are like Generators;
+	// the value is a strategy from package mask (hash, email, name, phone
+	// or null).
+	PullMasking map[string]string `yaml:"pull_masking,omitempty"`
+
+	// ExcludeColumns lists columns export leaves out of the CSVs — large
+	// blobs, secrets — as "table.column" or a bare column name for every
+	// table. schema.json marks them excluded: restore leaves them to the
+	// database default or NULL, and makes up values for NOT NULL ones
+	// without a default. Primary and foreign key columns can't be listed.
+	ExcludeColumns []string `yaml:"exclude_columns,omitempty"`
+
+	// CSVShardRows splits every table export writes with more data rows
+	// than this into ordered shards — orders.csv.001, orders.csv.002, … —
+	// each with the header and at most this many rows. Zero keeps one CSV
+	// per table.
+	CSVShardRows int `yaml:"csv_shard_rows,omitempty"`
+
+	// DiffIgnoreColumns lists volatile columns — updated_at, etags, auto
+	// IDs — that `record stop` leaves out when comparing the database with
+	// the recording's start, as "table.column" or a bare column name for
+	// every table. A row whose other columns didn't change isn't in the
+	// patch.
+	DiffIgnoreColumns []string `yaml:"diff_ignore_columns,omitempty"`
+
+	// DependsOn lists other projects in the same repository (directories
+	// holding a seedmancer.yaml, relative to this one) whose fixtures this
+	// project's rows reference. `seedmancer orchestrate` seeds them first.
+	DependsOn []string `yaml:"depends_on,omitempty"`
+
+	// ProtectedHosts lists database hosts seed refuses to write to, such
+	// as production primaries: a host name, a pattern like
+	// *.prod.example.com, or either with :port to match that port only.
+	// Matching is case-insensitive, against each target's URL and shards.
+	ProtectedHosts []string `yaml:"protected_hosts,omitempty"`
+
+	// TmpDir is where temporary files — staged restores, downloaded
+	// archives — are written instead of the OS temp dir, for machines
+	// whose /tmp is small. Relative to this config; --tmp-dir overrides it.
+	TmpDir string `yaml:"tmp_dir,omitempty"`
+
+	// Profiles holds named API/target defaults selected with --profile
+	// (see Profile); DefaultProfile is used when --profile is omitted.
+	Profiles       map[string]Profile `yaml:"profiles,omitempty"`
+	DefaultProfile string             `yaml:"default_profile,omitempty"`
+}
+
+// DefaultNullRatio applies when null_ratio is not set.
+const DefaultNullRatio = 0.1
+
+// NullRatioFunc returns the null ratio for each column: a "table.column"
+// entry in null_ratios wins over a bare column entry, which wins over
+// null_ratio. Values outside 0–1 are an error.
+func (c Config) NullRatioFunc() (func(table, column string) float64, error) {
+	global := DefaultNullRatio
+	if c.NullRatio != nil {
+		global = *c.NullRatio
+	}
+	if global < 0 || global > 1 {
+		return nil, fmt.Errorf("null_ratio: %v is not between 0 and 1", global)
+	}
+	for key, ratio := range c.NullRatios {
+		if ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("null_ratios.%s: %v is not between 0 and 1", key, ratio)
+		}
+	}
+	return func(table, column string) float64 {
+		if ratio, ok := c.NullRatios[table+"."+column]; ok {
+			return ratio
+		}
+		if ratio, ok := c.NullRatios[column]; ok {
+			return ratio
+		}
+		return global
+	}, nil
+}
+
+// EnumWeightsFunc returns the label weights of each column, or nil for
+// a column without any: a "table.column" entry in enum_weights wins over
+// a bare column entry. Negative weights, or none above zero, are an
+// error.
+func (c Config) EnumWeightsFunc() (func(table, column string) map[string]float64, error) {
+	if len(c.EnumWeights) == 0 {
+		return nil, nil
+	}
+	for key, weights := range c.EnumWeights {
+		total := 0.0
+		for label, w := range weights {
+			if w < 0 {
+				return nil, fmt.Errorf("enum_weights.%s.%s: %v is negative", key, label, w)
+			}
+			total += w
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("enum_weights.%s: no label has a weight above 0", key)
+		}
+	}
+	return func(table, column string) map[string]float64 {
+		if weights, ok := c.EnumWeights[table+"."+column]; ok {
+			return weights
+		}
+		return c.EnumWeights[column]
+	}, nil
+}
+
+// Foreign key pick strategies, the values of fk_picks.
+const (
+	// FKPickUniform draws every parent equally often.
+	FKPickUniform = "uniform"
+	// FKPickZipfian lets a few parents own most of the children, the way
+	// top customers own most orders.
+	FKPickZipfian = "zipfian"
+	// FKPickOneToOne gives each parent at most one child (profiles,
+	// settings).
+	FKPickOneToOne = "one-to-one"
+)
+
+// FKPickFunc returns the pick strategy of each foreign key column: a
+// "table.column" entry in fk_picks wins over a bare column entry, and
+// columns without one get FKPickUniform. Unknown strategies are an error.
+func (c Config) FKPickFunc() (func(table, column string) string, error) {
+	for key, pick := range c.FKPicks {
+		switch pick {
+		case FKPickUniform, FKPickZipfian, FKPickOneToOne:
+		default:
+			return nil, fmt.Errorf("fk_picks.%s: unknown strategy %q (want %s, %s or %s)", key, pick, FKPickUniform, FKPickZipfian, FKPickOneToOne)
+		}
+	}
+	return func(table, column string) string {
+		if pick, ok := c.FKPicks[table+"."+column]; ok {
+			return pick
+		}
+		if pick, ok := c.FKPicks[column]; ok {
+			return pick
+		}
+		return FKPickUniform
+	}, nil
+}
+
+// DiffIgnoreFunc reports whether diff_ignore_columns lists a column of
+// table, by "table.column" or by its bare name.
+func (c Config) DiffIgnoreFunc() func(table, column string) bool {
+	ignored := make(map[string]bool, len(c.DiffIgnoreColumns))
+	for _, name := range c.DiffIgnoreColumns {
+		ignored[strings.TrimSpace(name)] = true
+	}
+	return func(table, column string) bool {
+		return ignored[table+"."+column] || ignored[column]
+	}
+}
+
+// DefaultGenerateBudget applies when generate_budget is not set.
+const DefaultGenerateBudget int64 = 256 << 20
+
+// GenerateBudgetBytes returns generate_budget in bytes.
+func (c Config) GenerateBudgetBytes() (int64, error) {
+	if strings.TrimSpace(c.GenerateBudget) == "" {
+		return DefaultGenerateBudget, nil
+	}
+	n, err := ParseByteSize(c.GenerateBudget)
+	if err != nil {
+		return 0, fmt.Errorf("generate_budget: %v", err)
+	}
+	return n, nil
+}
+
+// ParseByteSize parses sizes like "512", "64KB", "500MB" or "2 GB". Units
+// are binary (1KB = 1024 bytes), matching how sizes are printed.
+func ParseByteSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	num := strings.TrimRight(t, "KMGTIB ")
+	unit := strings.TrimSpace(t[len(num):])
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	mult := map[string]float64{
+		"": 1, "B": 1,
+		"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+		"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+		"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+		"T": 1 << 40, "TB": 1 << 40, "TIB": 1 << 40,
+	}[unit]
+	if mult == 0 {
+		return 0, fmt.Errorf("invalid size %q (units: B, KB, MB, GB, TB)", s)
+	}
+	return int64(n * mult), nil
+}
+
+// TableConfig is one entry under `tables:`.
+type TableConfig struct {
+	// Static marks a reference table (countries, currencies, …). It is
+	// always kept in full: export refuses a --filter on it and prune keeps
+	// every row whatever --keep names. Seed skips the truncate + reload
+	// when the live rows already hash to the same value as the fixture CSV,
+	// unless the table references one that is truncated (PostgreSQL's
+	// TRUNCATE … CASCADE would empty it).
+	Static bool `yaml:"static,omitempty"`
+}
+
+// StaticTables returns the names of every table marked `static: true`,
+// sorted so restore logs and tests are deterministic.
+func (c Config) StaticTables() []string {
+	var names []string
+	for name, t := range c.Tables {
+		if t.Static {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProtectedHost returns the protected_hosts entry the host of dsn, a
+// database URL or a key=value DSN, matches, and whether one does.
+func (c Config) ProtectedHost(dsn string) (string, bool) {
+	host, port := dsnHost(dsn)
+	if host == "" {
+		return "", false
+	}
+	for _, entry := range c.ProtectedHosts {
+		pattern := strings.ToLower(strings.TrimSpace(entry))
+		if h, p, err := net.SplitHostPort(pattern); err == nil {
+			if p != port {
+				continue
+			}
+			pattern = h
+		}
+		if ok, err := path.Match(pattern, host); (err == nil && ok) || pattern == host {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// dsnHost is the lower-cased host and the port of dsn, "" when it names
+// none.
+func dsnHost(dsn string) (host, port string) {
+	if !strings.Contains(dsn, "://") {
+		for _, field := range strings.Fields(dsn) {
+			if k, v, ok := strings.Cut(field, "="); ok {
+				switch k {
+				case "host":
+					host = v
+				case "port":
+					port = v
+				}
+			}
+		}
+		return strings.ToLower(strings.Trim(host, "'")), strings.Trim(port, "'")
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", ""
+	}
+	return strings.ToLower(u.Hostname()), u.Port()
 }
 
 // EnvConfig is one named target inside `environments:`.
 type EnvConfig struct {
+	// DatabaseURL, like ReplicaURL and Shards, may reference environment
+	// variables as ${NAME} or ${NAME:-fallback}; they are expanded when the
+	// env is resolved, so credentials needn't be written to the file.
 	DatabaseURL string `yaml:"database_url"`
 	// Values holds environment-specific substitution values for @env:KEY markers
 	// found in CSV data during seeding. Keys must be uppercase letters, digits,
 	// and underscores. If a key is absent here, Seedmancer falls back to
 	// os.Getenv(KEY) before failing with a clear error.
 	Values map[string]string `yaml:"values,omitempty"`
+	// Role, when set, makes seed run SET ROLE <role> before restoring
+	// (PostgreSQL only), for setups where the login user only holds the
+	// privileges through a group role.
+	Role string `yaml:"role,omitempty"`
+	// ReplicaURL is a read replica of DatabaseURL. `seed --wait-for-replica`
+	// polls it after seeding until its row counts match the primary's.
+	ReplicaURL string `yaml:"replica_url,omitempty"`
+	// Shards lists the DSNs of a sharded cluster whose shards all share
+	// one schema. seed splits each table listed in ShardKeys across them
+	// and copies every other table to all shards. When DatabaseURL is
+	// empty it defaults to the first shard, which is what single-database
+	// commands such as export and status then read.
+	Shards []string `yaml:"shards,omitempty"`
+	// ShardKeys maps table name to the column whose value picks a row's
+	// shard.
+	ShardKeys map[string]string `yaml:"shard_keys,omitempty"`
 }
 
 // NamedEnv pairs a resolved env with its name so callers can render banners
@@ -129,6 +451,9 @@ func (c Config) EffectiveEnvs() map[string]EnvConfig {
 // default_env:, we synthesize LegacyEnvName so every command can render
 // "using env: default" uniformly.
 func (c Config) ActiveEnvName() string {
+	if _, p, ok := ActiveProfile(); ok && strings.TrimSpace(p.DefaultEnv) != "" {
+		return strings.TrimSpace(p.DefaultEnv)
+	}
 	if n := strings.TrimSpace(c.DefaultEnv); n != "" {
 		return n
 	}
@@ -183,12 +508,70 @@ func (c Config) ResolveEnv(name string) (NamedEnv, error) {
 			name, strings.Join(c.SortedEnvNames(), ", "),
 		)
 	}
+	env, err := env.interpolate()
+	if err != nil {
+		return NamedEnv{}, fmt.Errorf("environment %q: %v", name, err)
+	}
+	if strings.TrimSpace(env.DatabaseURL) == "" && len(env.Shards) > 0 {
+		env.DatabaseURL = env.Shards[0]
+	}
 	if strings.TrimSpace(env.DatabaseURL) == "" {
 		return NamedEnv{}, fmt.Errorf("environment %q has no database_url set", name)
 	}
 	return NamedEnv{Name: name, EnvConfig: env}, nil
 }
 
+// envRefPattern matches ${NAME} and ${NAME:-fallback} in connection URLs.
+// A bare $NAME is left alone: passwords contain dollar signs.
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// interpolate returns env with ${NAME} references in its connection URLs
+// replaced from the process environment, so seedmancer.yaml can be
+// committed without credentials. Only resolved envs are expanded; the file
+// itself keeps the references.
+func (env EnvConfig) interpolate() (EnvConfig, error) {
+	var err error
+	if env.DatabaseURL, err = expandEnvRefs(env.DatabaseURL); err != nil {
+		return env, fmt.Errorf("database_url: %v", err)
+	}
+	if env.ReplicaURL, err = expandEnvRefs(env.ReplicaURL); err != nil {
+		return env, fmt.Errorf("replica_url: %v", err)
+	}
+	if len(env.Shards) > 0 {
+		shards := make([]string, len(env.Shards))
+		for i, shard := range env.Shards {
+			if shards[i], err = expandEnvRefs(shard); err != nil {
+				return env, fmt.Errorf("shards[%d]: %v", i, err)
+			}
+		}
+		env.Shards = shards
+	}
+	return env, nil
+}
+
+// expandEnvRefs replaces each ${NAME} in s with the value of the
+// environment variable NAME, and ${NAME:-fallback} with fallback when NAME
+// is unset or empty. A variable that is unset with no fallback is an error
+// naming it, rather than a URL with a hole in it.
+func expandEnvRefs(s string) (string, error) {
+	var missing []string
+	out := envRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envRefPattern.FindStringSubmatch(ref)
+		if v := os.Getenv(m[1]); v != "" {
+			return v
+		}
+		if strings.Contains(ref, ":-") {
+			return m[2]
+		}
+		missing = append(missing, m[1])
+		return ref
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
 // ResolveEnvs parses a comma-separated list of env names and resolves each
 // one. Order is preserved (so `--env local,staging` always seeds local
 // before staging). Duplicates are dropped with a friendly error rather than
@@ -183,3 +183,164 @@ func TestResolveAPIToken_flagBeatsEverything(t *testing.T) {
 		t.Fatalf("flag did not win: got %q", got)
 	}
 }
+
+func TestConfig_StaticTables(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "seedmancer.yaml")
+	writeFile(t, cfgPath, "storage_path: .seed\ntables:\n  currencies:\n    static: true\n  users: {}\n  countries:\n    static: true\n")
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	got := cfg.StaticTables()
+	if len(got) != 2 || got[0] != "countries" || got[1] != "currencies" {
+		t.Fatalf("StaticTables() = %v, want [countries currencies]", got)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]int64{
+		"512":    512,
+		"64KB":   64 << 10,
+		"500MB":  500 << 20,
+		"2 GB":   2 << 30,
+		"1.5gib": 3 << 29,
+	}
+	for in, want := range cases {
+		got, err := ParseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "MB", "ten MB", "5 parsecs", "-1KB"} {
+		if _, err := ParseByteSize(bad); err == nil {
+			t.Errorf("ParseByteSize(%q) should fail", bad)
+		}
+	}
+}
+
+func TestConfig_NullRatioFunc(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "seedmancer.yaml")
+	writeFile(t, cfgPath, "storage_path: .seed\nnull_ratio: 0.2\nnull_ratios:\n  deleted_at: 0.95\n  users.middle_name: 0.6\n")
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ratio, err := cfg.NullRatioFunc()
+	if err != nil {
+		t.Fatalf("NullRatioFunc: %v", err)
+	}
+	cases := []struct {
+		table, column string
+		want          float64
+	}{
+		{"users", "middle_name", 0.6},
+		{"authors", "middle_name", 0.2},
+		{"orders", "deleted_at", 0.95},
+		{"orders", "note", 0.2},
+	}
+	for _, c := range cases {
+		if got := ratio(c.table, c.column); got != c.want {
+			t.Errorf("ratio(%s.%s) = %v, want %v", c.table, c.column, got, c.want)
+		}
+	}
+
+	if ratio, _ := (Config{}).NullRatioFunc(); ratio("t", "c") != DefaultNullRatio {
+		t.Errorf("unset null_ratio = %v, want %v", ratio("t", "c"), DefaultNullRatio)
+	}
+	if _, err := (Config{NullRatios: map[string]float64{"x": 1.5}}).NullRatioFunc(); err == nil {
+		t.Error("null_ratios above 1 should fail")
+	}
+}
+
+func TestConfig_EnumWeightsFunc(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "seedmancer.yaml")
+	writeFile(t, cfgPath, "storage_path: .seed\nenum_weights:\n  status: {active: 9, banned: 1}\n  orders.status: {paid: 80, refunded: 5}\n")
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	weights, err := cfg.EnumWeightsFunc()
+	if err != nil {
+		t.Fatalf("EnumWeightsFunc: %v", err)
+	}
+	if got := weights("orders", "status")["paid"]; got != 80 {
+		t.Errorf("orders.status paid = %v, want 80", got)
+	}
+	if got := weights("users", "status")["active"]; got != 9 {
+		t.Errorf("users.status active = %v, want 9", got)
+	}
+	if got := weights("users", "role"); got != nil {
+		t.Errorf("users.role = %v, want nil", got)
+	}
+
+	if w, err := (Config{}).EnumWeightsFunc(); w != nil || err != nil {
+		t.Errorf("unset enum_weights = %v, %v", w != nil, err)
+	}
+	for _, bad := range []map[string]float64{{"a": -1}, {"a": 0}} {
+		if _, err := (Config{EnumWeights: map[string]map[string]float64{"x": bad}}).EnumWeightsFunc(); err == nil {
+			t.Errorf("enum_weights %v should fail", bad)
+		}
+	}
+}
+
+func TestConfig_FKPickFunc(t *testing.T) {
+	pick, err := Config{FKPicks: map[string]string{"user_id": FKPickZipfian, "profiles.user_id": FKPickOneToOne}}.FKPickFunc()
+	if err != nil {
+		t.Fatalf("FKPickFunc: %v", err)
+	}
+	for _, tc := range []struct{ table, column, want string }{
+		{"profiles", "user_id", FKPickOneToOne},
+		{"orders", "user_id", FKPickZipfian},
+		{"orders", "product_id", FKPickUniform},
+	} {
+		if got := pick(tc.table, tc.column); got != tc.want {
+			t.Errorf("%s.%s = %q, want %q", tc.table, tc.column, got, tc.want)
+		}
+	}
+
+	if _, err := (Config{FKPicks: map[string]string{"user_id": "pareto"}}).FKPickFunc(); err == nil {
+		t.Error("unknown strategy should fail")
+	}
+}
+
+func TestConfig_DiffIgnoreFunc(t *testing.T) {
+	ignore := Config{DiffIgnoreColumns: []string{"updated_at", "audit_log.id"}}.DiffIgnoreFunc()
+	for _, tc := range []struct {
+		table, column string
+		want          bool
+	}{
+		{"users", "updated_at", true},
+		{"audit_log", "id", true},
+		{"users", "id", false},
+	} {
+		if got := ignore(tc.table, tc.column); got != tc.want {
+			t.Errorf("ignore(%s.%s) = %v, want %v", tc.table, tc.column, got, tc.want)
+		}
+	}
+}
+
+func TestConfig_ProtectedHost(t *testing.T) {
+	cfg := Config{ProtectedHosts: []string{"*.prod.example.com", "db.internal:5433", "Primary"}}
+	for _, tc := range []struct {
+		dsn, want string
+	}{
+		{"postgres://app@eu.prod.example.com:5432/app", "*.prod.example.com"},
+		{"postgres://app@EU.PROD.example.com/app", "*.prod.example.com"},
+		{"postgres://app@db.internal:5433/app", "db.internal:5433"},
+		{"postgres://app@db.internal:5432/app", ""},
+		{"host=primary port=5432 dbname=app", "Primary"},
+		{"postgres://app@localhost:5432/app", ""},
+		{"mysql://root@primary:3306/app", "Primary"},
+	} {
+		got, ok := cfg.ProtectedHost(tc.dsn)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("ProtectedHost(%q) = %q, %v; want %q", tc.dsn, got, ok, tc.want)
+		}
+	}
+}
@@ -16,13 +16,18 @@ import (
 // separate file credentials.local is used so prod and local tokens never
 // overwrite each other. Switching between environments is as simple as
 // setting or unsetting SEEDMANCER_API_URL.
+//
+// With a profile active the token lives in credentials-<profile>, so each
+// organization keeps its own sign-in.
 func CredentialsPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("getting home directory: %v", err)
 	}
 	filename := "credentials"
-	if apiURL := GetBaseURL(); !strings.Contains(apiURL, "api.seedmancer.dev") {
+	if name, _, ok := ActiveProfile(); ok {
+		filename = "credentials-" + name
+	} else if apiURL := GetBaseURL(); !strings.Contains(apiURL, "api.seedmancer.dev") {
 		filename = "credentials.local"
 	}
 	return filepath.Join(homeDir, ".seedmancer", filename), nil
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KazanKK/seedmancer/internal/csvshard"
+)
+
+// ReadCSVRecords reads every record of the CSV at path, header included,
+// or of the shards standing in for it when the table was split. Records
+// may differ in length. A missing file is returned as os.Open's error.
+func ReadCSVRecords(path string) ([][]string, error) {
+	f, err := csvshard.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filepath.Base(path), err)
+	}
+	return records, nil
+}
+
+// WriteCSVRecords writes records to path. They go to a temporary file
+// renamed over path, so a path that is a symlink (a staged restore dir
+// links the CSVs of another revision) is replaced, not written through.
+func WriteCSVRecords(path string, records [][]string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := csv.NewWriter(f).WriteAll(records); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", filepath.Base(path), err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCSVRecords_replacesSymlink(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "base", "users.csv")
+	writeFile(t, src, "id,name\n1,Ann\n")
+	staged := filepath.Join(dir, "staged", "users.csv")
+	if err := os.MkdirAll(filepath.Dir(staged), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(src, staged); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	want := [][]string{{"id", "name"}, {"2", "Bo, Jr."}}
+	if err := WriteCSVRecords(staged, want); err != nil {
+		t.Fatalf("WriteCSVRecords: %v", err)
+	}
+	if got, err := ReadCSVRecords(staged); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("staged = %v, %v; want %v", got, err, want)
+	}
+	if got, _ := os.ReadFile(src); string(got) != "id,name\n1,Ann\n" {
+		t.Errorf("the symlink's target was written through: %q", got)
+	}
+}
+
+func TestReadCSVRecords_joinsShards(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "orders.csv.001"), "id\n1\n")
+	writeFile(t, filepath.Join(dir, "orders.csv.002"), "id\n2\n")
+	got, err := ReadCSVRecords(filepath.Join(dir, "orders.csv"))
+	if want := [][]string{{"id"}, {"1"}, {"2"}}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCSVRecords = %v, %v; want %v", got, err, want)
+	}
+	if _, err := ReadCSVRecords(filepath.Join(dir, "users.csv")); !os.IsNotExist(err) {
+		t.Errorf("missing table: err = %v, want not-exist", err)
+	}
+}
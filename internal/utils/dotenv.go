@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dotEnvLoaded is the file LoadDotEnv read and the variables it set, for
+// --debug output.
+var dotEnvLoaded struct {
+	path  string
+	names []string
+}
+
+var dotEnvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// LoadDotEnv sets the variables in a .env file — SEEDMANCER_API_TOKEN,
+// SEEDMANCER_DATABASE_URL and the like — in the process environment,
+// before any flag reads them. path is --env-file, which must exist; when
+// it is empty the project's .env, next to seedmancer.yaml (or in the
+// working directory outside a project), is read if there is one.
+// Variables already set in the environment win over the file, so a value
+// exported in the shell or by CI is never overridden.
+func LoadDotEnv(path string) error {
+	explicit := strings.TrimSpace(path) != ""
+	if !explicit {
+		path = projectDotEnvPath()
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading env file: %v", err)
+	}
+	defer f.Close()
+
+	vars, err := parseDotEnv(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	var names []string
+	for _, kv := range vars {
+		if _, set := os.LookupEnv(kv[0]); set {
+			continue
+		}
+		if err := os.Setenv(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("setting %s: %v", kv[0], err)
+		}
+		names = append(names, kv[0])
+	}
+	dotEnvLoaded.path, dotEnvLoaded.names = path, names
+	return nil
+}
+
+// DotEnvLoaded is the file LoadDotEnv read and the variables it set, or ""
+// when none was read.
+func DotEnvLoaded() (string, []string) { return dotEnvLoaded.path, dotEnvLoaded.names }
+
+// projectDotEnvPath is the .env beside the project's seedmancer.yaml, or
+// in the working directory when there's no project. The global
+// ~/.seedmancer/config.yaml doesn't count: a .env in ~/.seedmancer would
+// apply to every project.
+func projectDotEnvPath() string {
+	dir, _ := os.Getwd()
+	if path, err := FindConfigFile(); err == nil && filepath.Base(path) == "seedmancer.yaml" {
+		dir = filepath.Dir(path)
+	}
+	return filepath.Join(dir, ".env")
+}
+
+// parseDotEnv reads KEY=value lines, in order. Blank lines and # comments
+// are skipped and a leading `export ` is allowed, so the file can be
+// sourced by a shell too. Values may be single-quoted (taken literally),
+// double-quoted (with Go escapes such as \n) or bare, where a " #" starts
+// a comment.
+func parseDotEnv(r io.Reader) ([][2]string, error) {
+	var vars [][2]string
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !dotEnvKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote in %s", n, key)
+			}
+			v, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %v", n, key, err)
+			}
+			value = v
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote in %s", n, key)
+			}
+			value = value[1 : end+1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// closingQuote is the index of the " that closes the double-quoted string
+// s starts with, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
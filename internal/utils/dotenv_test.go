@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	in := `# local secrets
+export SEEDMANCER_API_TOKEN=tok_123
+SEEDMANCER_DATABASE_URL = postgres://app:pw@localhost/app # dev db
+QUOTED="line one\nline two"
+LITERAL='a # not a comment \n'
+
+EMPTY=
+`
+	got, err := parseDotEnv(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{
+		{"SEEDMANCER_API_TOKEN", "tok_123"},
+		{"SEEDMANCER_DATABASE_URL", "postgres://app:pw@localhost/app"},
+		{"QUOTED", "line one\nline two"},
+		{"LITERAL", `a # not a comment \n`},
+		{"EMPTY", ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("var %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"NO_EQUALS", "1BAD=x", `OPEN="unterminated`} {
+		if _, err := parseDotEnv(strings.NewReader(bad)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("parseDotEnv(%q) err = %v, want a line 1 error", bad, err)
+		}
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	profileSandbox(t, "storage_path: .seedmancer\n", "")
+	// Registered so the test restores them; LoadDotEnv only sets unset ones.
+	t.Setenv("SEEDMANCER_DOTENV_A", "")
+	os.Unsetenv("SEEDMANCER_DOTENV_A")
+	t.Setenv("SEEDMANCER_DOTENV_B", "from shell")
+
+	if err := LoadDotEnv(""); err != nil {
+		t.Fatalf("no .env: %v", err)
+	}
+	if err := os.WriteFile(".env", []byte("SEEDMANCER_DOTENV_A=a\nSEEDMANCER_DOTENV_B=b\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join("nested", "dir")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDotEnv(""); err != nil {
+		t.Fatal(err)
+	}
+	if v := os.Getenv("SEEDMANCER_DOTENV_A"); v != "a" {
+		t.Errorf("A = %q, want it from the project .env", v)
+	}
+	if v := os.Getenv("SEEDMANCER_DOTENV_B"); v != "from shell" {
+		t.Errorf("B = %q, the shell's value should win", v)
+	}
+	if _, names := DotEnvLoaded(); len(names) != 1 || names[0] != "SEEDMANCER_DOTENV_A" {
+		t.Errorf("DotEnvLoaded names = %v", names)
+	}
+
+	if err := LoadDotEnv("missing.env"); err == nil {
+		t.Error("a missing --env-file should be an error")
+	}
+}
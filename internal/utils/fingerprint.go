@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"os"
 	"sort"
+
+	"github.com/KazanKK/seedmancer/internal/schemaspec"
 )
 
 // ErrNoTables is returned when a schema file contains no tables.
@@ -94,8 +96,11 @@ type SchemaEnum struct {
 // SchemaJSON is the lenient shape we accept for fingerprinting. It tolerates
 // missing/extra fields so a user-hand-edited schema.json still works.
 type SchemaJSON struct {
-	Enums  []SchemaEnum  `json:"enums"`
-	Tables []SchemaTable `json:"tables"`
+	// DatabaseType is the engine the schema was exported from. It is not
+	// part of the fingerprint.
+	DatabaseType string        `json:"databaseType,omitempty"`
+	Enums        []SchemaEnum  `json:"enums"`
+	Tables       []SchemaTable `json:"tables"`
 }
 
 // CanonicalSchemaJSON returns the bytes that feed the SHA-256 hasher. Kept
@@ -193,6 +198,33 @@ func FingerprintSchema(schema SchemaJSON) (string, error) {
 	return hex.EncodeToString(sum[:]), nil
 }
 
+// ParseSchemaJSON decodes a schema.json. When decoding fails the error
+// names the offending value (tables[3].columns[7].type: expected string,
+// got number) instead of encoding/json's struct-field message.
+func ParseSchemaJSON(data []byte) (SchemaJSON, error) {
+	var parsed SchemaJSON
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		if precise := schemaspec.Explain(data); precise != nil {
+			return SchemaJSON{}, precise
+		}
+		return SchemaJSON{}, err
+	}
+	return parsed, nil
+}
+
+// ReadSchemaJSON reads and decodes the schema.json at path.
+func ReadSchemaJSON(path string) (SchemaJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SchemaJSON{}, fmt.Errorf("reading schema.json: %v", err)
+	}
+	schema, err := ParseSchemaJSON(data)
+	if err != nil {
+		return SchemaJSON{}, fmt.Errorf("parsing schema.json: %v", err)
+	}
+	return schema, nil
+}
+
 // FingerprintSchemaFile reads a schema.json from disk and returns its
 // fingerprint. Returns a friendlier error on missing or malformed files.
 func FingerprintSchemaFile(path string) (string, error) {
@@ -200,8 +232,8 @@ func FingerprintSchemaFile(path string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("reading schema %q: %w", path, err)
 	}
-	var parsed SchemaJSON
-	if err := json.Unmarshal(data, &parsed); err != nil {
+	parsed, err := ParseSchemaJSON(data)
+	if err != nil {
 		return "", fmt.Errorf("parsing %q: %w", path, err)
 	}
 	if len(parsed.Tables) == 0 {
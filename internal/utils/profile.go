@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile is a named set of defaults under `profiles:` in seedmancer.yaml
+// or ~/.seedmancer/config.yaml, for people who work across several
+// projects or organizations:
+//
+//	default_profile: acme
+//	profiles:
+//	  acme:
+//	    api_url: https://seedmancer.acme.internal
+//	    dashboard_url: https://seedmancer.acme.internal
+//	    token_env: ACME_SEEDMANCER_TOKEN
+//	    default_project: checkout
+//	    default_env: staging
+//	  personal:
+//	    database_url: postgres://localhost:5432/side_project
+//
+// Every field is optional; an unset one falls back to the usual source.
+// Tokens are never stored in a profile — token_env names the variable
+// that holds one, and `seedmancer --profile <name> login` saves a token
+// to a credentials file of the profile's own.
+type Profile struct {
+	// APIURL is the Seedmancer API origin. SEEDMANCER_API_URL still wins.
+	APIURL string `yaml:"api_url,omitempty"`
+	// DashboardURL is the web app origin `login` opens and scenario links
+	// point at, for self-hosted deployments whose dashboard is not served
+	// from the API origin.
+	DashboardURL string `yaml:"dashboard_url,omitempty"`
+	// TokenEnv names the environment variable holding the API token. It
+	// is consulted after --token and SEEDMANCER_API_TOKEN and before the
+	// profile's credentials file.
+	TokenEnv string `yaml:"token_env,omitempty"`
+	// DefaultProject is the cloud project slug used when --project is
+	// omitted; it overrides default_project.
+	DefaultProject string `yaml:"default_project,omitempty"`
+	// DefaultEnv is the environment used when --env is omitted; it
+	// overrides default_env.
+	DefaultEnv string `yaml:"default_env,omitempty"`
+	// DatabaseURL is the target used when neither --env nor --db-url is
+	// given, ahead of the default environment.
+	DatabaseURL string `yaml:"database_url,omitempty"`
+}
+
+// activeProfile is the profile picked for this process by
+// SelectProfile. The CLI runs one command per process, so a package-level
+// var is safe, as with globalProjectSlug.
+var activeProfile struct {
+	name    string
+	profile Profile
+}
+
+// ActiveProfile returns the profile selected for this process, if any.
+func ActiveProfile() (string, Profile, bool) {
+	return activeProfile.name, activeProfile.profile, activeProfile.name != ""
+}
+
+// SetActiveProfile makes p the active profile under name. An empty name
+// clears it.
+func SetActiveProfile(name string, p Profile) {
+	activeProfile.name = strings.TrimSpace(name)
+	activeProfile.profile = p
+	if activeProfile.name == "" {
+		activeProfile.profile = Profile{}
+	}
+}
+
+// SelectProfile resolves and activates the profile for this process:
+// name (from --profile or SEEDMANCER_PROFILE) or else default_profile.
+// The project's seedmancer.yaml is searched first, then
+// ~/.seedmancer/config.yaml, so personal profiles can live in the global
+// file and still be used from any project. Asking for a profile that
+// doesn't exist is an error; no name and no default_profile means no
+// profile.
+func SelectProfile(name string) error {
+	name = strings.TrimSpace(name)
+	configs := profileConfigs()
+	if name == "" {
+		for _, cfg := range configs {
+			if n := strings.TrimSpace(cfg.DefaultProfile); n != "" {
+				name = n
+				break
+			}
+		}
+		if name == "" {
+			SetActiveProfile("", Profile{})
+			return nil
+		}
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	var known []string
+	seen := map[string]bool{}
+	for _, cfg := range configs {
+		if p, ok := cfg.Profiles[name]; ok {
+			SetActiveProfile(name, p)
+			return nil
+		}
+		for n := range cfg.Profiles {
+			if !seen[n] {
+				seen[n] = true
+				known = append(known, n)
+			}
+		}
+	}
+	sort.Strings(known)
+	if len(known) == 0 {
+		return fmt.Errorf("unknown profile %q: no profiles are defined in seedmancer.yaml or ~/.seedmancer/config.yaml", name)
+	}
+	return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(known, ", "))
+}
+
+// ProfileEntry is one profile as listed by ListProfiles.
+type ProfileEntry struct {
+	Name    string
+	Profile Profile
+	// Source is the config file the profile is read from.
+	Source string
+}
+
+// ListProfiles returns every profile SelectProfile can pick, sorted by
+// name, along with the default_profile in effect. A name defined in both
+// the project and the global config is listed once, from the project
+// file, matching the lookup order.
+func ListProfiles() ([]ProfileEntry, string) {
+	var entries []ProfileEntry
+	defaultName := ""
+	seen := map[string]bool{}
+	for _, pc := range profileConfigPaths() {
+		cfg, err := LoadConfig(pc)
+		if err != nil {
+			continue
+		}
+		if defaultName == "" {
+			defaultName = strings.TrimSpace(cfg.DefaultProfile)
+		}
+		for name, p := range cfg.Profiles {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			entries = append(entries, ProfileEntry{Name: name, Profile: p, Source: pc})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, defaultName
+}
+
+// ValidateProfileName checks that name is usable as a profile and in a
+// credentials file name.
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name is empty")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("invalid profile name %q: use letters, digits, - and _", name)
+		}
+	}
+	return nil
+}
+
+// profileConfigs loads the configs profiles are looked up in, project
+// first. Unreadable files are skipped: a broken config surfaces through
+// the command that loads it.
+func profileConfigs() []Config {
+	var configs []Config
+	for _, path := range profileConfigPaths() {
+		if cfg, err := LoadConfig(path); err == nil {
+			configs = append(configs, cfg)
+		}
+	}
+	return configs
+}
+
+// profileConfigPaths lists the config files profiles are looked up in,
+// project first.
+func profileConfigPaths() []string {
+	var paths []string
+	if path, err := FindConfigFile(); err == nil {
+		paths = append(paths, path)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		global := filepath.Join(home, ".seedmancer", "config.yaml")
+		if len(paths) == 0 || paths[0] != global {
+			paths = append(paths, global)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// profileSandbox points HOME and the working directory at fresh temp
+// dirs, writes the given project and global configs (empty means none)
+// and clears the active profile afterwards.
+func profileSandbox(t *testing.T, project, global string) string {
+	t.Helper()
+	home, work := t.TempDir(), t.TempDir()
+	prev, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("SEEDMANCER_API_URL", "")
+	t.Setenv("SEEDMANCER_API_TOKEN", "")
+	t.Cleanup(func() { SetActiveProfile("", Profile{}) })
+	if project != "" {
+		if err := os.WriteFile(filepath.Join(work, "seedmancer.yaml"), []byte(project), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if global != "" {
+		if err := os.MkdirAll(filepath.Join(home, ".seedmancer"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(home, ".seedmancer", "config.yaml"), []byte(global), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+func TestSelectProfile_projectThenGlobal(t *testing.T) {
+	profileSandbox(t,
+		"storage_path: .seedmancer\nprofiles:\n  acme:\n    api_url: https://seedmancer.acme.test/\n    default_project: checkout\n",
+		"profiles:\n  acme:\n    api_url: https://ignored.test\n  personal:\n    database_url: postgres://localhost/side\n")
+
+	if err := SelectProfile("acme"); err != nil {
+		t.Fatalf("SelectProfile(acme): %v", err)
+	}
+	if got := GetBaseURL(); got != "https://seedmancer.acme.test" {
+		t.Errorf("GetBaseURL = %q, want the project profile's api_url", got)
+	}
+	if got := ResolveProjectSlug("", Config{DefaultProject: "other"}); got != "checkout" {
+		t.Errorf("ResolveProjectSlug = %q, want checkout", got)
+	}
+	if got := ResolveProjectSlug("flag", Config{}); got != "flag" {
+		t.Errorf("ResolveProjectSlug(flag) = %q", got)
+	}
+
+	if err := SelectProfile("personal"); err != nil {
+		t.Fatalf("SelectProfile(personal): %v", err)
+	}
+	if _, p, _ := ActiveProfile(); p.DatabaseURL != "postgres://localhost/side" {
+		t.Errorf("personal profile = %+v", p)
+	}
+
+	err := SelectProfile("nope")
+	if err == nil || !strings.Contains(err.Error(), "(available: acme, personal)") {
+		t.Errorf("SelectProfile(nope) = %v", err)
+	}
+}
+
+func TestSelectProfile_defaultProfile(t *testing.T) {
+	profileSandbox(t, "default_profile: work\nprofiles:\n  work:\n    default_env: staging\n", "")
+	if err := SelectProfile(""); err != nil {
+		t.Fatal(err)
+	}
+	name, _, ok := ActiveProfile()
+	if !ok || name != "work" {
+		t.Fatalf("active profile = %q, %v; want work", name, ok)
+	}
+	cfg := Config{DefaultEnv: "local", Environments: map[string]EnvConfig{"local": {}, "staging": {}}}
+	if got := cfg.ActiveEnvName(); got != "staging" {
+		t.Errorf("ActiveEnvName = %q, want the profile's default_env", got)
+	}
+}
+
+func TestSelectProfile_noneConfigured(t *testing.T) {
+	profileSandbox(t, "storage_path: .seedmancer\n", "")
+	if err := SelectProfile(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := ActiveProfile(); ok {
+		t.Error("no profile should be active")
+	}
+	if err := SelectProfile("acme"); err == nil {
+		t.Error("expected an error for an undefined profile")
+	}
+	if err := SelectProfile("../x"); err == nil {
+		t.Error("expected an error for an invalid profile name")
+	}
+}
+
+func TestProfileToken(t *testing.T) {
+	home := profileSandbox(t, "profiles:\n  acme:\n    token_env: ACME_TOKEN\n", "")
+	if err := SelectProfile("acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := CredentialsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".seedmancer", "credentials-acme"); path != want {
+		t.Errorf("CredentialsPath = %q, want %q", path, want)
+	}
+	if err := SaveAPICredentials("saved"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ACME_TOKEN", "from-profile-env")
+	tok, src, err := ResolveAPITokenSource("")
+	if err != nil || tok != "from-profile-env" || !strings.Contains(src, "ACME_TOKEN") {
+		t.Errorf("token = %q from %q, %v; want the profile's token_env", tok, src, err)
+	}
+	t.Setenv("SEEDMANCER_API_TOKEN", "global-env")
+	if tok, _ := ResolveAPIToken(""); tok != "global-env" {
+		t.Errorf("token = %q; SEEDMANCER_API_TOKEN should still win", tok)
+	}
+	t.Setenv("SEEDMANCER_API_TOKEN", "")
+	t.Setenv("ACME_TOKEN", "")
+	if tok, _ := ResolveAPIToken(""); tok != "saved" {
+		t.Errorf("token = %q, want the profile's credentials file", tok)
+	}
+}
+
+func TestListProfiles(t *testing.T) {
+	profileSandbox(t,
+		"default_profile: prod-api\nprofiles:\n  prod-api:\n    api_url: https://seedmancer.internal.test\n    dashboard_url: https://app.internal.test\n",
+		"default_profile: staging-api\nprofiles:\n  prod-api:\n    api_url: https://ignored.test\n  staging-api:\n    token_env: STAGING_TOKEN\n")
+
+	entries, defaultName := ListProfiles()
+	if defaultName != "prod-api" {
+		t.Errorf("default = %q, want the project's default_profile", defaultName)
+	}
+	if len(entries) != 2 || entries[0].Name != "prod-api" || entries[1].Name != "staging-api" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if entries[0].Profile.APIURL != "https://seedmancer.internal.test" || entries[0].Profile.DashboardURL != "https://app.internal.test" {
+		t.Errorf("prod-api = %+v, want the project definition", entries[0].Profile)
+	}
+	if !strings.HasSuffix(entries[1].Source, filepath.Join(".seedmancer", "config.yaml")) {
+		t.Errorf("staging-api source = %q, want the global config", entries[1].Source)
+	}
+}
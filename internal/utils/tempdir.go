@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempDirOverride is the directory SelectTempDir chose, "" for the OS
+// default.
+var tempDirOverride string
+
+// SelectTempDir points the process's temporary files at dir (--tmp-dir),
+// or, when dir is empty, at the tmp_dir of the first config that sets one,
+// project first. The directory is created if needed. TMPDIR (TMP and TEMP
+// on Windows) is set to it, so every os.MkdirTemp("", …) and os.TempDir
+// in the process, drivers included, follows without being told.
+func SelectTempDir(dir string) error {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		for _, path := range profileConfigPaths() {
+			cfg, err := LoadConfig(path)
+			if err != nil || strings.TrimSpace(cfg.TmpDir) == "" {
+				continue
+			}
+			dir = strings.TrimSpace(cfg.TmpDir)
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(filepath.Dir(path), dir)
+			}
+			break
+		}
+	}
+	if dir == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolving temp dir %s: %v", dir, err)
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return fmt.Errorf("creating temp dir: %v", err)
+	}
+	for _, name := range []string{"TMPDIR", "TMP", "TEMP"} {
+		if err := os.Setenv(name, abs); err != nil {
+			return fmt.Errorf("setting %s: %v", name, err)
+		}
+	}
+	tempDirOverride = abs
+	return nil
+}
+
+// TempDirOverride is the directory SelectTempDir chose, or "" when
+// temporary files go to the OS default.
+func TempDirOverride() string { return tempDirOverride }
@@ -9,6 +9,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/KazanKK/seedmancer/internal/csvshard"
 )
 
 // ErrMissingAPIToken is returned when no API token could be resolved from the
@@ -444,8 +446,9 @@ func IsSchemaSidecarName(name string) bool {
 	return strings.HasSuffix(name, "_func.sql") || strings.HasSuffix(name, "_trigger.sql")
 }
 
-// DatasetFiles returns the CSV/JSON payload files inside a dataset folder.
-// Subdirectories are skipped; the caller doesn't care about them.
+// DatasetFiles returns the CSV/JSON payload files inside a dataset folder,
+// CSV shards (orders.csv.001, …) included. Subdirectories are skipped;
+// the caller doesn't care about them.
 func DatasetFiles(datasetDir string) ([]string, error) {
 	entries, err := os.ReadDir(datasetDir)
 	if err != nil {
@@ -458,7 +461,8 @@ func DatasetFiles(datasetDir string) ([]string, error) {
 		}
 		name := e.Name()
 		lower := strings.ToLower(name)
-		if strings.HasSuffix(lower, ".csv") || strings.HasSuffix(lower, ".json") {
+		_, _, shard := csvshard.Parse(name)
+		if shard || strings.HasSuffix(lower, ".csv") || strings.HasSuffix(lower, ".json") {
 			files = append(files, filepath.Join(datasetDir, name))
 		}
 	}
@@ -496,13 +500,17 @@ func formatSchemaList(schemas []LocalSchema) string {
 //
 // Priority:
 //  1. SEEDMANCER_API_URL — internal test override; not documented for users.
-//  2. https://api.seedmancer.dev
+//  2. api_url of the active profile (see Profile)
+//  3. https://api.seedmancer.dev
 //
-// The api_url YAML key is not supported.
+// A top-level api_url YAML key is not supported.
 func GetBaseURL() string {
 	if v := os.Getenv("SEEDMANCER_API_URL"); v != "" {
 		return strings.TrimRight(strings.TrimSpace(v), "/")
 	}
+	if _, p, ok := ActiveProfile(); ok && strings.TrimSpace(p.APIURL) != "" {
+		return strings.TrimRight(strings.TrimSpace(p.APIURL), "/")
+	}
 	return "https://api.seedmancer.dev"
 }
 
@@ -522,12 +530,32 @@ func SetGlobalProjectSlug(slug string) {
 	globalProjectSlug = strings.TrimSpace(slug)
 }
 
+// cliVersion is the release tag of the running binary, set once from main
+// so revision manifests can record which seedmancer wrote them.
+var cliVersion = "dev"
+
+// SetCLIVersion stores the binary's release tag for the current process.
+func SetCLIVersion(v string) {
+	if v = strings.TrimSpace(v); v != "" {
+		cliVersion = v
+	}
+}
+
+// CLIVersion returns the tag stored by SetCLIVersion ("dev" when unset).
+func CLIVersion() string {
+	return cliVersion
+}
+
 // ResolveProjectSlug returns the project slug to use for cloud API calls.
-// Priority: flagValue (--project flag) > cfg.DefaultProject > "" (server falls back to Default project).
+// Priority: flagValue (--project flag) > the active profile's default_project >
+// cfg.DefaultProject > "" (server falls back to Default project).
 func ResolveProjectSlug(flagValue string, cfg Config) string {
 	if v := strings.TrimSpace(flagValue); v != "" {
 		return v
 	}
+	if _, p, ok := ActiveProfile(); ok && strings.TrimSpace(p.DefaultProject) != "" {
+		return strings.TrimSpace(p.DefaultProject)
+	}
 	return strings.TrimSpace(cfg.DefaultProject)
 }
 
@@ -575,7 +603,9 @@ func ResolveAPIToken(flagValue string) (string, error) {
 // Resolution order (highest priority first):
 //  1. explicit --token CLI flag          (always wins)
 //  2. SEEDMANCER_API_TOKEN env var       (explicit runtime override; CI-friendly)
-//  3. ~/.seedmancer/credentials          (saved default from `seedmancer login`)
+//  3. the active profile's token_env var (see Profile)
+//  4. ~/.seedmancer/credentials          (saved default from `seedmancer login`;
+//     a profile has its own file, see CredentialsPath)
 //
 // Env var beats credentials file — the standard convention across CLI tools
 // (aws, gh, docker, heroku). An explicit env var means the caller wants that
@@ -597,6 +627,13 @@ func ResolveAPITokenSource(flagValue string) (string, string, error) {
 		return tok, lastTokenSource, nil
 	}
 
+	if name, p, ok := ActiveProfile(); ok && strings.TrimSpace(p.TokenEnv) != "" {
+		if tok := strings.TrimSpace(os.Getenv(strings.TrimSpace(p.TokenEnv))); tok != "" {
+			lastTokenSource = fmt.Sprintf("%s env var (profile %s)", strings.TrimSpace(p.TokenEnv), name)
+			return tok, lastTokenSource, nil
+		}
+	}
+
 	if tok, err := LoadAPICredentials(); err == nil && tok != "" {
 		lastTokenSource = TokenSourceCredentials
 		return tok, lastTokenSource, nil
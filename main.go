@@ -7,6 +7,9 @@ import (
 	"strings"
 
 	"github.com/KazanKK/seedmancer/cmd"
+	db "github.com/KazanKK/seedmancer/database"
+	"github.com/KazanKK/seedmancer/internal/bundle"
+	"github.com/KazanKK/seedmancer/internal/diskspace"
 	"github.com/KazanKK/seedmancer/internal/mcpcmd"
 	"github.com/KazanKK/seedmancer/internal/ui"
 	"github.com/KazanKK/seedmancer/internal/updatecheck"
@@ -44,6 +47,20 @@ OPTIONS:{{template "visibleFlagTemplate" .}}{{end}}
 `
 
 func main() {
+	utils.SetCLIVersion(Version)
+
+	// A binary written by `seedmancer bundle` carries a revision appended
+	// to itself and only knows how to seed it.
+	if exe, err := os.Executable(); err == nil {
+		if b, err := bundle.Open(exe); err == nil {
+			if err := cmd.BundledSeederApp(b).Run(os.Args); err != nil {
+				ui.Error("%v", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Strip CATEGORY: from every subcommand's --help output.
 	cli.CommandHelpTemplate = commandHelpTemplate
 
@@ -61,6 +78,12 @@ func main() {
 	statusCmd.Category = "Get started"
 	envCmd := cmd.EnvCommand()
 	envCmd.Category = "Get started"
+	profileCmd := cmd.ProfileCommand()
+	profileCmd.Category = "Get started"
+	quickstartCmd := cmd.QuickstartCommand()
+	quickstartCmd.Category = "Get started"
+	platformCheckCmd := cmd.PlatformCheckCommand()
+	platformCheckCmd.Category = "Get started"
 
 	exportCmd := cmd.ExportCommand()
 	exportCmd.Category = "Local"
@@ -77,8 +100,42 @@ func main() {
 	historyCmd.Category = "Local"
 	checkCmd := cmd.CheckCommand()
 	checkCmd.Category = "Local"
+	validateCmd := cmd.ValidateCommand()
+	validateCmd.Category = "Local"
 	refreshCmd := cmd.RefreshCommand()
 	refreshCmd.Category = "Local"
+	ageCmd := cmd.AgeCommand()
+	ageCmd.Category = "Local"
+	saveCmd := cmd.SaveCommand()
+	saveCmd.Category = "Local"
+	rewriteCmd := cmd.RewriteCommand()
+	rewriteCmd.Category = "Local"
+	repairCmd := cmd.RepairCommand()
+	repairCmd.Category = "Local"
+	explainCmd := cmd.ExplainCommand()
+	explainCmd.Category = "Local"
+	pruneCmd := cmd.PruneCommand()
+	pruneCmd.Category = "Local"
+	bundleCmd := cmd.BundleCommand()
+	bundleCmd.Category = "Local"
+	embedCmd := cmd.EmbedCommand()
+	embedCmd.Category = "Local"
+	lockCmd := cmd.LockCommand()
+	lockCmd.Category = "Local"
+	verifyLockCmd := cmd.VerifyLockCommand()
+	verifyLockCmd.Category = "Local"
+	snapshotCmd := cmd.SnapshotCommand()
+	snapshotCmd.Category = "Local"
+	orchestrateCmd := cmd.OrchestrateCommand()
+	orchestrateCmd.Category = "Local"
+	upCmd := cmd.UpCommand()
+	upCmd.Category = "Local"
+	downCmd := cmd.DownCommand()
+	downCmd.Category = "Local"
+	recordCmd := cmd.RecordCommand()
+	recordCmd.Category = "Local"
+	introspectCmd := cmd.IntrospectCommand()
+	introspectCmd.Category = "Local"
 
 	pushCmd := cmd.PushCommand()
 	pushCmd.Category = "Remote"
@@ -123,13 +180,88 @@ func main() {
 				Usage:   "Cloud project slug (falls back to default_project in seedmancer.yaml, then server Default)",
 				EnvVars: []string{"SEEDMANCER_PROJECT"},
 			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "Named profile from seedmancer.yaml or ~/.seedmancer/config.yaml (API URL, token, default project, env and db-url)",
+				EnvVars: []string{"SEEDMANCER_PROFILE"},
+			},
+			&cli.IntFlag{
+				Name:    "max-open-conns",
+				Usage:   "Most database connections open at once (0 = no limit)",
+				Value:   db.DefaultConnOptions.MaxOpenConns,
+				EnvVars: []string{"SEEDMANCER_MAX_OPEN_CONNS"},
+			},
+			&cli.IntFlag{
+				Name:    "max-idle-conns",
+				Usage:   "Database connections kept open between statements",
+				Value:   db.DefaultConnOptions.MaxIdleConns,
+				EnvVars: []string{"SEEDMANCER_MAX_IDLE_CONNS"},
+			},
+			&cli.DurationFlag{
+				Name:    "conn-timeout",
+				Usage:   "Give up connecting to the database after this long (0 = wait forever)",
+				Value:   db.DefaultConnOptions.ConnTimeout,
+				EnvVars: []string{"SEEDMANCER_CONN_TIMEOUT"},
+			},
+			&cli.DurationFlag{
+				Name:    "statement-timeout",
+				Usage:   "Abort any database statement running longer than this, e.g. 5m (0 = no limit; MySQL only bounds SELECTs)",
+				EnvVars: []string{"SEEDMANCER_STATEMENT_TIMEOUT"},
+			},
+			&cli.BoolFlag{
+				Name:    "progress-json",
+				Usage:   "Report progress as line-delimited JSON events on stderr (phase, table, rows, percent) instead of status lines",
+				EnvVars: []string{"SEEDMANCER_PROGRESS_JSON"},
+			},
+			&cli.StringFlag{
+				Name:    "tmp-dir",
+				Usage:   "Write temporary files (staged restores, downloaded archives) here instead of the OS temp dir; overrides tmp_dir in seedmancer.yaml",
+				EnvVars: []string{"SEEDMANCER_TMP_DIR"},
+			},
+			&cli.BoolFlag{
+				Name:    "skip-disk-check",
+				Usage:   "Don't check for free disk space before exports, pulls and seeds",
+				EnvVars: []string{"SEEDMANCER_SKIP_DISK_CHECK"},
+			},
+			&cli.StringFlag{
+				Name:    "env-file",
+				Usage:   "Load environment variables from this file instead of the project's .env; variables already set win",
+				EnvVars: []string{"SEEDMANCER_ENV_FILE"},
+			},
 		},
 		Before: func(c *cli.Context) error {
 			ui.SetDebug(c.Bool("debug"))
+			ui.SetProgressJSON(c.Bool("progress-json"))
+			if err := db.SetConnOptions(db.ConnOptions{
+				MaxOpenConns:     c.Int("max-open-conns"),
+				MaxIdleConns:     c.Int("max-idle-conns"),
+				ConnTimeout:      c.Duration("conn-timeout"),
+				StatementTimeout: c.Duration("statement-timeout"),
+			}); err != nil {
+				return err
+			}
+			// The profile comes first: it can supply the API URL, token and
+			// project slug every later step resolves.
+			if err := utils.SelectProfile(c.String("profile")); err != nil {
+				return err
+			}
+			if name, _, ok := utils.ActiveProfile(); ok {
+				ui.Debug("Using profile %s", name)
+			}
+			if err := utils.SelectTempDir(c.String("tmp-dir")); err != nil {
+				return err
+			}
+			if dir := utils.TempDirOverride(); dir != "" {
+				ui.Debug("Temporary files go to %s", dir)
+			}
+			diskspace.SetDisabled(c.Bool("skip-disk-check"))
+			if path, names := utils.DotEnvLoaded(); path != "" {
+				ui.Debug("Loaded %s from %s", strings.Join(names, ", "), path)
+			}
 			// Resolve and cache the active project slug for all cloud calls.
 			// Commands that load config themselves will refine this with
 			// ResolveProjectSlug; this handles the global-flag-only case.
-			if slug := strings.TrimSpace(c.String("project")); slug != "" {
+			if slug := utils.ResolveProjectSlug(c.String("project"), utils.Config{}); slug != "" {
 				utils.SetGlobalProjectSlug(slug)
 			}
 			return nil
@@ -139,6 +271,9 @@ func main() {
 			loginCmd,
 			statusCmd,
 			envCmd,
+			profileCmd,
+			quickstartCmd,
+			platformCheckCmd,
 			exportCmd,
 			generateLocalCmd,
 			generateCmd,
@@ -146,7 +281,24 @@ func main() {
 		listCmd,
 		historyCmd,
 		checkCmd,
+		validateCmd,
 			refreshCmd,
+			ageCmd,
+			saveCmd,
+			rewriteCmd,
+			repairCmd,
+			explainCmd,
+			pruneCmd,
+			bundleCmd,
+			embedCmd,
+			lockCmd,
+			verifyLockCmd,
+			snapshotCmd,
+			orchestrateCmd,
+			upCmd,
+			downCmd,
+			recordCmd,
+			introspectCmd,
 		pushCmd,
 		pullCmd,
 		schemasCmd,
@@ -155,6 +307,20 @@ func main() {
 		},
 	}
 
+	cmd.AddDBURLFileFlags(app.Commands)
+
+	// The .env has to be in the environment before app.Run parses flags,
+	// which is where EnvVars are read, so --env-file is picked out of argv
+	// by hand; the flag above only documents it.
+	envFile := envFileArg(os.Args)
+	if envFile == "" {
+		envFile = os.Getenv("SEEDMANCER_ENV_FILE")
+	}
+	if err := utils.LoadDotEnv(envFile); err != nil {
+		ui.Error("%v", err)
+		os.Exit(1)
+	}
+
 	// Kick off the (non-blocking) update check before the command
 	// runs so the goroutine has the entire command's runtime to do
 	// its work. `finishUpdateCheck` is called below for both the
@@ -182,6 +348,27 @@ func main() {
 	finishUpdateCheck()
 }
 
+// envFileArg returns the value of --env-file in argv, or "".
+func envFileArg(argv []string) string {
+	for i := 1; i < len(argv); i++ {
+		tok := argv[i]
+		if tok == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(tok, "-"), "=")
+		if !strings.HasPrefix(tok, "-") || name != "env-file" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(argv) {
+			return argv[i+1]
+		}
+	}
+	return ""
+}
+
 // firstSubcommand returns the first non-flag token from argv past the
 // program name. Used to suppress the update-check banner when the user
 // invoked `seedmancer mcp`, whose stdout/stderr is owned by the MCP
@@ -100,3 +100,17 @@ func TestReorderArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvFileArg(t *testing.T) {
+	cases := map[string][]string{
+		"ci.env": {"seedmancer", "--env-file", "ci.env", "seed", "billing"},
+		"x.env":  {"seedmancer", "--env-file=x.env", "list"},
+		"":       {"seedmancer", "seed", "--", "--env-file", "no.env"},
+		"single": {"seedmancer", "-env-file", "single", "list"},
+	}
+	for want, argv := range cases {
+		if got := envFileArg(argv); got != want {
+			t.Errorf("envFileArg(%q) = %q, want %q", argv, got, want)
+		}
+	}
+}